@@ -20,6 +20,8 @@ type Opts struct {
 var integrationBinaries = []string{
 	"azure",
 	"bitbucket",
+	"bitbucket-hosted",
+	"gitea",
 	"github",
 	"gitlab",
 	"jira-cloud",
@@ -4,6 +4,7 @@ import (
 	"context"
 	"encoding/json"
 	"fmt"
+	"io/ioutil"
 	"os"
 	"path/filepath"
 	"strings"
@@ -81,11 +82,12 @@ var cmdCloneRepo = &cobra.Command{
 		logger := defaultLogger()
 
 		ctx := context.Background()
-		url, _ := cmd.Flags().GetString("url")
 		cacheDir, _ := cmd.Flags().GetString("cache-dir")
-		res, err := gitclone.CloneWithCache(ctx, logger, gitclone.AccessDetails{
-			URL: url,
-		}, gitclone.Dirs{
+		access, err := accessFromFlags(cmd)
+		if err != nil {
+			exitWithErr(logger, err)
+		}
+		res, err := gitclone.CloneWithCache(ctx, logger, access, gitclone.Dirs{
 			CacheRoot: cacheDir,
 		}, "1", "main-repo")
 		fmt.Println("res", res)
@@ -98,9 +100,50 @@ var cmdCloneRepo = &cobra.Command{
 func init() {
 	cmdCloneRepo.Flags().String("url", "", "repo url")
 	cmdCloneRepo.Flags().String("cache-dir", "", "cache-dir for repos")
+	accessFlags(cmdCloneRepo)
 	cmdRoot.AddCommand(cmdCloneRepo)
 }
 
+func accessFlags(cmd *cobra.Command) {
+	cmd.Flags().String("access-mode", "", "git access mode: url, askpass, header or ssh")
+	cmd.Flags().String("username", "", "username for askpass and header access modes")
+	cmd.Flags().String("password", "", "password or token for askpass and header access modes")
+	cmd.Flags().String("ssh-key", "", "file with private key for ssh access mode")
+	cmd.Flags().String("ssh-known-hosts", "", "known_hosts file for ssh access mode")
+}
+
+func accessFromFlags(cmd *cobra.Command) (res gitclone.AccessDetails, rerr error) {
+	res.URL, _ = cmd.Flags().GetString("url")
+	mode, _ := cmd.Flags().GetString("access-mode")
+	var err error
+	res.Mode, err = gitclone.ParseAccessMode(mode)
+	if err != nil {
+		rerr = err
+		return
+	}
+	res.Username, _ = cmd.Flags().GetString("username")
+	res.Password, _ = cmd.Flags().GetString("password")
+	readFile := func(flag string) (string, error) {
+		loc, _ := cmd.Flags().GetString(flag)
+		if loc == "" {
+			return "", nil
+		}
+		b, err := ioutil.ReadFile(loc)
+		return string(b), err
+	}
+	res.SSHKey, err = readFile("ssh-key")
+	if err != nil {
+		rerr = err
+		return
+	}
+	res.SSHKnownHosts, err = readFile("ssh-known-hosts")
+	if err != nil {
+		rerr = err
+		return
+	}
+	return
+}
+
 var cmdExportRepo = &cobra.Command{
 	Use:   "export-repo",
 	Short: "Clone the repo and run ripsrc and write the output",
@@ -143,10 +186,19 @@ var cmdExportRepo = &cobra.Command{
 		repoID, _ := cmd.Flags().GetString("repo-id")
 		customerID, _ := cmd.Flags().GetString("customer-id")
 		localRepo, _ := cmd.Flags().GetString("local-repo")
+		commitFiles, _ := cmd.Flags().GetBool("commit-files")
+		tags, _ := cmd.Flags().GetBool("tags")
+		noMailmap, _ := cmd.Flags().GetBool("no-mailmap")
+		aliasFile, _ := cmd.Flags().GetString("alias-file")
+		ownership, _ := cmd.Flags().GetBool("ownership")
+		access, err := accessFromFlags(cmd)
+		if err != nil {
+			exitWithErr(logger, err)
+		}
 
 		opts := exportrepo.Opts{
 			Logger:            logger,
-			RepoAccess:        gitclone.AccessDetails{URL: url},
+			RepoAccess:        access,
 			LocalRepo:         localRepo,
 			Sessions:          sessions,
 			RepoID:            repoID,
@@ -157,6 +209,11 @@ var cmdExportRepo = &cobra.Command{
 			BranchURLTemplate: commiturl.BranchURLTemplate(dummyRepo, url),
 			RefType:           reftype,
 			CommitUsers:       commitUsers,
+			CommitFiles:       commitFiles,
+			Tags:              tags,
+			Mailmap:           !noMailmap,
+			AliasFile:         aliasFile,
+			Ownership:         ownership,
 		}
 
 		exp := exportrepo.New(opts, locs)
@@ -184,6 +241,12 @@ func init() {
 	cmdExportRepo.Flags().String("repo-name", "", "repo-name")
 	cmdExportRepo.Flags().String("repo-id", "repo1", "repo-id")
 	cmdExportRepo.Flags().String("customer-id", "cus1", "customer-id")
+	cmdExportRepo.Flags().Bool("commit-files", false, "export per file stats for commits")
+	cmdExportRepo.Flags().Bool("tags", false, "export tags and releases")
+	cmdExportRepo.Flags().Bool("no-mailmap", false, "do not use .mailmap from repo")
+	cmdExportRepo.Flags().String("alias-file", "", "file in .mailmap format with author aliases")
+	cmdExportRepo.Flags().Bool("ownership", false, "export code ownership snapshot, created on every run")
+	accessFlags(cmdExportRepo)
 	cmdRoot.AddCommand(cmdExportRepo)
 }
 
@@ -0,0 +1,411 @@
+// Package cmdbundle creates bundles from export results on air-gapped hosts, uploads them from a connected machine and imports upload acks back.
+package cmdbundle
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"regexp"
+	"time"
+
+	"github.com/hashicorp/go-hclog"
+	"github.com/pinpt/agent/cmd/cmdupload"
+	"github.com/pinpt/agent/pkg/archive"
+	"github.com/pinpt/agent/pkg/bundle"
+	"github.com/pinpt/agent/pkg/fs"
+	"github.com/pinpt/agent/pkg/fsconf"
+	"github.com/pinpt/go-common/v10/fileutil"
+	pjson "github.com/pinpt/go-common/v10/json"
+)
+
+const (
+	uploadsDir             = "uploads"
+	lastProcessedDeltaFile = "last_processed_delta.json"
+	resultsFile            = "results.json"
+)
+
+// ExportOpts are options for Export
+type ExportOpts struct {
+	Logger hclog.Logger
+	Locs   fsconf.Locs
+	Conf   bundle.Config
+
+	CustomerID   string
+	DeviceID     string
+	AgentVersion string
+	// Integrations are versions of installed integrations by name
+	Integrations map[string]string
+	JobID        string
+	UploadURL    string
+	// Results is the export result, optional
+	Results interface{}
+	// LogFile is included in bundle if set, the same as in cmdupload
+	LogFile string
+}
+
+// Export creates bundle from files in uploads dir and adds it to pending bundles. The export state is saved to snapshot, next exports continue from it, so that each bundle only contains new data. Export state stays uncommitted until ack of all pending bundles is imported.
+func Export(opts ExportOpts) (_ bundle.Manifest, rerr error) {
+	logger := opts.Logger
+	locs := opts.Locs
+	if opts.Conf.Dir == "" || opts.Conf.Key == "" {
+		rerr = errors.New("bundle dir and key are required")
+		return
+	}
+
+	files, err := fileutil.FindFiles(locs.Uploads, regexp.MustCompile("\\.gz$"))
+	if err != nil {
+		rerr = err
+		return
+	}
+	if len(files) == 0 {
+		rerr = cmdupload.ErrNoFilesFound
+		return
+	}
+
+	err = os.MkdirAll(locs.Temp, 0777)
+	if err != nil {
+		rerr = err
+		return
+	}
+	staging, err := ioutil.TempDir(locs.Temp, "bundle")
+	if err != nil {
+		rerr = err
+		return
+	}
+	defer os.RemoveAll(staging)
+
+	for _, f := range files {
+		rel, err := filepath.Rel(locs.Uploads, f)
+		if err != nil {
+			rerr = err
+			return
+		}
+		err = copyFile(f, filepath.Join(staging, uploadsDir, rel))
+		if err != nil {
+			rerr = err
+			return
+		}
+	}
+	if opts.LogFile != "" {
+		err = copyFile(opts.LogFile, filepath.Join(staging, uploadsDir, "export.log"))
+		if err != nil {
+			rerr = err
+			return
+		}
+	}
+
+	delta, err := lastProcessedDelta(locs)
+	if err != nil {
+		rerr = fmt.Errorf("could not get last processed changes: %v", err)
+		return
+	}
+	err = writeJSON(filepath.Join(staging, lastProcessedDeltaFile), delta)
+	if err != nil {
+		rerr = err
+		return
+	}
+	if opts.Results != nil {
+		err = writeJSON(filepath.Join(staging, resultsFile), opts.Results)
+		if err != nil {
+			rerr = err
+			return
+		}
+	}
+
+	manifest := bundle.Manifest{}
+	manifest.Created = time.Now()
+	manifest.ID = bundle.NewID(manifest.Created)
+	manifest.CustomerID = opts.CustomerID
+	manifest.DeviceID = opts.DeviceID
+	manifest.AgentVersion = opts.AgentVersion
+	manifest.Integrations = opts.Integrations
+	if opts.JobID != "" {
+		manifest.JobIDs = []string{opts.JobID}
+	}
+	manifest.UploadURL = opts.UploadURL
+
+	loc := filepath.Join(opts.Conf.Dir, manifest.ID+bundle.FileExt)
+	manifest, err = bundle.Create(loc, opts.Conf.Key, manifest, staging)
+	if err != nil {
+		rerr = err
+		return
+	}
+
+	pending, err := bundle.ReadPending(locs.BundlePendingFile)
+	if err != nil {
+		rerr = err
+		return
+	}
+	pending = append(pending, bundle.PendingBundle{
+		ID:      manifest.ID,
+		File:    loc,
+		Created: manifest.Created,
+		JobIDs:  manifest.JobIDs,
+	})
+	err = bundle.WritePending(locs.BundlePendingFile, pending)
+	if err != nil {
+		rerr = err
+		return
+	}
+	// saved after pending list, if interrupted, next export starts from the previous snapshot and bundle data is exported again
+	err = saveSnapshot(locs)
+	if err != nil {
+		rerr = fmt.Errorf("could not save export state for bundle: %v", err)
+		return
+	}
+
+	logger.Info("created export bundle, upload it using upload-bundle command on a connected machine", "file", loc, "files", len(manifest.Files), "pending_bundles", len(pending))
+	return manifest, nil
+}
+
+// lastProcessedDelta returns keys of last processed state changed since the previous bundle, or since the last committed state if there are no pending bundles
+func lastProcessedDelta(locs fsconf.Locs) (map[string]interface{}, error) {
+	current := map[string]interface{}{}
+	err := readJSONIfExists(locs.LastProcessedFile, &current)
+	if err != nil {
+		return nil, err
+	}
+	prevLoc := locs.LastProcessedFileBackup
+	snapshotExists, err := fs.Exists(locs.BundleSnapshot)
+	if err != nil {
+		return nil, err
+	}
+	if snapshotExists {
+		prevLoc = locs.LastProcessedFileBundleSnapshot
+	}
+	prev := map[string]interface{}{}
+	err = readJSONIfExists(prevLoc, &prev)
+	if err != nil {
+		return nil, err
+	}
+	res := map[string]interface{}{}
+	for k, v := range current {
+		if p, ok := prev[k]; ok && jsonEqual(p, v) {
+			continue
+		}
+		res[k] = v
+	}
+	return res, nil
+}
+
+// saveSnapshot replaces bundle snapshot with the current export state
+func saveSnapshot(locs fsconf.Locs) error {
+	tmp := locs.BundleSnapshot + ".tmp"
+	err := os.RemoveAll(tmp)
+	if err != nil {
+		return err
+	}
+	err = os.MkdirAll(tmp, 0755)
+	if err != nil {
+		return err
+	}
+	rel := func(loc string) string {
+		r, err := filepath.Rel(locs.BundleSnapshot, loc)
+		if err != nil {
+			panic(err)
+		}
+		return filepath.Join(tmp, r)
+	}
+	err = copyFile(locs.LastProcessedFile, rel(locs.LastProcessedFileBundleSnapshot))
+	if err != nil && !os.IsNotExist(err) {
+		return err
+	}
+	err = fs.CopyDir(locs.RipsrcCheckpoints, rel(locs.RipsrcCheckpointsBundleSnapshot))
+	if err != nil && !os.IsNotExist(err) {
+		return err
+	}
+	err = os.RemoveAll(locs.BundleSnapshot)
+	if err != nil {
+		return err
+	}
+	return os.Rename(tmp, locs.BundleSnapshot)
+}
+
+// commitSnapshot makes the state of the last bundle the committed state, restored on the next export
+func commitSnapshot(locs fsconf.Locs) error {
+	snapshotExists, err := fs.Exists(locs.BundleSnapshot)
+	if err != nil {
+		return err
+	}
+	err = os.RemoveAll(locs.Backup)
+	if err != nil {
+		return fmt.Errorf("error deleting export backup dir: %v", err)
+	}
+	if !snapshotExists {
+		return nil
+	}
+	return os.Rename(locs.BundleSnapshot, locs.Backup)
+}
+
+func jsonEqual(a, b interface{}) bool {
+	ab, err := json.Marshal(a)
+	if err != nil {
+		return false
+	}
+	bb, err := json.Marshal(b)
+	if err != nil {
+		return false
+	}
+	return string(ab) == string(bb)
+}
+
+func readJSONIfExists(loc string, res interface{}) error {
+	ok, err := fs.Exists(loc)
+	if err != nil || !ok {
+		return err
+	}
+	return pjson.ReadFile(loc, res)
+}
+
+func writeJSON(loc string, obj interface{}) error {
+	b, err := json.Marshal(obj)
+	if err != nil {
+		return err
+	}
+	return ioutil.WriteFile(loc, b, 0644)
+}
+
+func copyFile(src, dst string) error {
+	err := os.MkdirAll(filepath.Dir(dst), 0777)
+	if err != nil {
+		return err
+	}
+	return fs.CopyFile(src, dst)
+}
+
+// UploadOpts are options for Upload
+type UploadOpts struct {
+	Logger hclog.Logger
+	// File is the bundle file
+	File string
+	Key  string
+	// APIKey is the agent api key used for upload
+	APIKey string
+	// UploadURL overrides the upload url from bundle manifest
+	UploadURL string
+	// AckFile is where to write ack, defaults to bundle file with .ack extension
+	AckFile string
+}
+
+// Upload verifies bundle and uploads export files in it, the same way as cmdupload does. Writes ack file that should be imported on the host where bundle was created.
+func Upload(opts UploadOpts) (_ bundle.Ack, rerr error) {
+	logger := opts.Logger
+
+	dir, err := ioutil.TempDir("", "pinpoint-bundle")
+	if err != nil {
+		rerr = err
+		return
+	}
+	defer os.RemoveAll(dir)
+
+	manifest, err := bundle.Open(opts.File, opts.Key, dir)
+	if err != nil {
+		rerr = fmt.Errorf("could not verify bundle: %v", err)
+		return
+	}
+	logger.Info("bundle verified", "id", manifest.ID, "created", manifest.Created, "job_ids", manifest.JobIDs, "files", len(manifest.Files), "integrations", manifest.Integrations)
+
+	uploadURL := opts.UploadURL
+	if uploadURL == "" {
+		uploadURL = manifest.UploadURL
+	}
+	if uploadURL == "" {
+		rerr = errors.New("bundle does not have upload url, pass it using upload-url flag")
+		return
+	}
+
+	zipPath := filepath.Join(dir, manifest.ID+".zip")
+	err = archive.ZipDir(zipPath, filepath.Join(dir, uploadsDir))
+	if err != nil {
+		rerr = err
+		return
+	}
+	logger.Info("uploading bundle", "upload_url", uploadURL)
+	_, size, err := cmdupload.UploadZip(logger, zipPath, uploadURL, opts.APIKey)
+	if err != nil {
+		rerr = err
+		return
+	}
+	logger.Info("bundle uploaded", "size_kb", size/1024)
+
+	ack := bundle.NewAck(manifest, time.Now(), opts.Key)
+	ackFile := opts.AckFile
+	if ackFile == "" {
+		ackFile = opts.File + bundle.AckFileExt
+	}
+	err = bundle.WriteAck(ackFile, ack)
+	if err != nil {
+		rerr = err
+		return
+	}
+	logger.Info("ack file written, import it on the host where bundle was created using import-bundle-ack command", "file", ackFile)
+	return ack, nil
+}
+
+// ImportAck removes acknowledged bundle from pending list. When all pending bundles are acknowledged, the state saved with the last bundle replaces the backup dir and becomes the committed state.
+// Export restores the state from backup dir if it exists, so it should not be running.
+func ImportAck(logger hclog.Logger, locs fsconf.Locs, key string, ackFile string) error {
+	ack, err := bundle.ReadAck(ackFile, key)
+	if err != nil {
+		return err
+	}
+	pending, err := bundle.ReadPending(locs.BundlePendingFile)
+	if err != nil {
+		return err
+	}
+	var acked bundle.PendingBundle
+	for _, b := range pending {
+		if b.ID == ack.BundleID {
+			acked = b
+		}
+	}
+	pending, err = bundle.RemovePending(pending, ack)
+	if err != nil {
+		return err
+	}
+	err = bundle.WritePending(locs.BundlePendingFile, pending)
+	if err != nil {
+		return err
+	}
+	if err := os.Remove(acked.File); err != nil && !os.IsNotExist(err) {
+		logger.Warn("could not remove uploaded bundle", "file", acked.File, "err", err)
+	}
+	if len(pending) != 0 {
+		logger.Info("bundle ack imported, waiting for acks of other bundles before committing export state", "id", ack.BundleID, "pending_bundles", len(pending))
+		return nil
+	}
+	err = commitSnapshot(locs)
+	if err != nil {
+		return err
+	}
+	logger.Info("bundle ack imported, export state committed", "id", ack.BundleID, "uploaded", ack.Uploaded)
+	return nil
+}
+
+// ImportAcks imports all ack files in bundle dir. Invalid or already imported acks are logged and skipped.
+func ImportAcks(logger hclog.Logger, locs fsconf.Locs, conf bundle.Config) error {
+	files, err := filepath.Glob(filepath.Join(conf.Dir, "*"+bundle.AckFileExt))
+	if err != nil {
+		return err
+	}
+	for _, f := range files {
+		err := ImportAck(logger, locs, conf.Key, f)
+		if err == bundle.ErrInvalidSignature {
+			logger.Warn("skipping bundle ack with invalid signature", "file", f)
+			continue
+		}
+		if err == bundle.ErrNotPending {
+			logger.Warn("removing ack of bundle that is not pending", "file", f)
+		} else if err != nil {
+			return fmt.Errorf("could not import bundle ack %v: %v", f, err)
+		}
+		err = os.Remove(f)
+		if err != nil {
+			return err
+		}
+	}
+	return nil
+}
@@ -0,0 +1,77 @@
+package cmdbundle
+
+import (
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/pinpt/agent/pkg/fsconf"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestSnapshot(t *testing.T) {
+	assert := assert.New(t)
+	tmp, err := ioutil.TempDir("", "cmdbundle-test")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(tmp)
+	locs := fsconf.New(tmp)
+
+	write := func(loc string, data string) {
+		t.Helper()
+		err := os.MkdirAll(filepath.Dir(loc), 0777)
+		if err != nil {
+			t.Fatal(err)
+		}
+		err = ioutil.WriteFile(loc, []byte(data), 0644)
+		if err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	// committed state
+	write(locs.LastProcessedFileBackup, `{"a":1}`)
+	// first bundle
+	write(locs.LastProcessedFile, `{"a":2,"b":1}`)
+	write(filepath.Join(locs.RipsrcCheckpoints, "repo1"), "c1")
+	delta, err := lastProcessedDelta(locs)
+	if err != nil {
+		t.Fatal(err)
+	}
+	assert.Equal(map[string]interface{}{"a": 2.0, "b": 1.0}, delta)
+	err = saveSnapshot(locs)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	// second bundle only contains changes since the first one
+	write(locs.LastProcessedFile, `{"a":2,"b":2}`)
+	delta, err = lastProcessedDelta(locs)
+	if err != nil {
+		t.Fatal(err)
+	}
+	assert.Equal(map[string]interface{}{"b": 2.0}, delta)
+	err = saveSnapshot(locs)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	err = commitSnapshot(locs)
+	if err != nil {
+		t.Fatal(err)
+	}
+	b, err := ioutil.ReadFile(locs.LastProcessedFileBackup)
+	if err != nil {
+		t.Fatal(err)
+	}
+	assert.Equal(`{"a":2,"b":2}`, string(b))
+	b, err = ioutil.ReadFile(filepath.Join(locs.RipsrcCheckpointsBackup, "repo1"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	assert.Equal("c1", string(b))
+	_, err = os.Stat(locs.BundleSnapshot)
+	assert.True(os.IsNotExist(err))
+}
@@ -0,0 +1,50 @@
+package cmdexport
+
+import (
+	"os"
+
+	"github.com/pinpt/agent/pkg/expsessions"
+)
+
+// resumeFromCheckpoints sets last processed for child sessions completed in previous interrupted export, so integrations could skip them. Opens checkpoints file for the current export.
+func (s *export) resumeFromCheckpoints() error {
+	loc := s.Locs.ExportCheckpointsFile
+	checkpoints, err := expsessions.ReadCheckpoints(loc)
+	if err != nil {
+		return err
+	}
+	if len(checkpoints) != 0 {
+		for root, ids := range expsessions.CheckpointObjects(checkpoints) {
+			s.Logger.Info("Previous export was interrupted, will continue using checkpoints", "session", root, "objects_with_completed_sessions", len(ids))
+		}
+		err = expsessions.ApplyCheckpoints(s.lastProcessed, checkpoints)
+		if err != nil {
+			return err
+		}
+	}
+
+	// sessions in progress when export was interrupted leave temp files, remove them so that only the files from completed sessions are kept
+	tempFiles, err := s.tempFilesInUploads()
+	if err != nil {
+		return err
+	}
+	for _, f := range tempFiles {
+		s.Logger.Info("Removing temp file from interrupted export", "f", f)
+		err := os.Remove(f)
+		if err != nil {
+			return err
+		}
+	}
+
+	s.checkpoints, err = expsessions.NewCheckpointFile(loc)
+	return err
+}
+
+// removeCheckpoints is called after last processed state is saved for complete export
+func (s *export) removeCheckpoints() error {
+	err := s.checkpoints.Close()
+	if err != nil {
+		return err
+	}
+	return os.Remove(s.Locs.ExportCheckpointsFile)
+}
@@ -0,0 +1,130 @@
+package cmdexport
+
+import (
+	"errors"
+	"io/ioutil"
+	"os"
+	"strconv"
+	"testing"
+
+	"github.com/hashicorp/go-hclog"
+	"github.com/pinpt/agent/cmd/cmdintegration"
+	"github.com/pinpt/agent/cmd/cmdrunnorestarts/inconfig"
+	"github.com/pinpt/agent/pkg/expin"
+	"github.com/pinpt/agent/pkg/fs"
+	"github.com/pinpt/agent/pkg/fsconf"
+	"github.com/pinpt/agent/pkg/jsonstore"
+	"github.com/pinpt/agent/rpcdef"
+	"github.com/stretchr/testify/assert"
+)
+
+var errKilled = errors.New("export killed")
+
+var testIn = expin.Export{IntegrationID: "1", IntegrationDef: inconfig.IntegrationDef{Name: "mock"}}
+
+// newTestExport prepares export state the same way as Run, without loading integrations
+func newTestExport(t *testing.T, root string) *export {
+	s := &export{}
+	s.Command = &cmdintegration.Command{}
+	s.Logger = hclog.NewNullLogger()
+	s.Locs = fsconf.New(root)
+	var err error
+	s.lastProcessed, err = jsonstore.New(s.Locs.LastProcessedFile)
+	if err != nil {
+		t.Fatal(err)
+	}
+	err = s.resumeFromCheckpoints()
+	if err != nil {
+		t.Fatal(err)
+	}
+	s.sessions, err = newSessions(s.Logger, s, false)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return s
+}
+
+// exportRepos exports 4 repos with pull requests in child sessions, skipping pull requests of repos with last processed set. If killAfter is not 0, stops after sending pull requests of the next repo, without closing sessions.
+func exportRepos(agent rpcdef.Agent, killAfter int) (skipped []string, _ error) {
+	send := func(sessionID int, refID string) {
+		agent.SendExported(strconv.Itoa(sessionID), []rpcdef.ExportObj{{Data: map[string]interface{}{"ref_id": refID}}})
+	}
+	reposID, _, err := agent.SessionStart(false, "sourcecode.Repo", 0, "", "")
+	if err != nil {
+		return nil, err
+	}
+	for i := 1; i <= 4; i++ {
+		repo := "r" + strconv.Itoa(i)
+		send(reposID, repo)
+		prsID, lastProcessed, err := agent.SessionStart(false, "sourcecode.PullRequest", reposID, repo, repo)
+		if err != nil {
+			return nil, err
+		}
+		if lastProcessed != nil {
+			skipped = append(skipped, repo)
+			agent.ExportDone(strconv.Itoa(prsID), lastProcessed)
+			continue
+		}
+		send(prsID, repo+"-pr1")
+		if i == killAfter+1 {
+			return skipped, errKilled
+		}
+		agent.ExportDone(strconv.Itoa(prsID), "t1")
+	}
+	agent.ExportDone(strconv.Itoa(reposID), "t1")
+	return skipped, nil
+}
+
+func TestResumeFromCheckpoints(t *testing.T) {
+	dir, err := ioutil.TempDir("", "cmdexport")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(dir)
+	assert := assert.New(t)
+
+	// export is killed after completing pull requests of 2 repos, last processed is not saved
+	s := newTestExport(t, dir)
+	skipped, err := exportRepos(newAgentDelegate(s, s.sessions.expsession, testIn), 2)
+	assert.Equal(errKilled, err)
+	assert.Empty(skipped)
+	err = s.checkpoints.Close()
+	if err != nil {
+		t.Fatal(err)
+	}
+	tempFiles, err := s.tempFilesInUploads()
+	if err != nil {
+		t.Fatal(err)
+	}
+	assert.Len(tempFiles, 2, "repos and pull requests of the third repo")
+
+	// next export skips completed repos and removes temp files of sessions in progress
+	s = newTestExport(t, dir)
+	tempFiles, err = s.tempFilesInUploads()
+	if err != nil {
+		t.Fatal(err)
+	}
+	assert.Empty(tempFiles)
+	skipped, err = exportRepos(newAgentDelegate(s, s.sessions.expsession, testIn), 0)
+	if err != nil {
+		t.Fatal(err)
+	}
+	assert.Equal([]string{"r1", "r2"}, skipped)
+	err = s.lastProcessed.Save()
+	if err != nil {
+		t.Fatal(err)
+	}
+	err = s.removeCheckpoints()
+	if err != nil {
+		t.Fatal(err)
+	}
+	err = s.sessions.Close()
+	if err != nil {
+		t.Fatal(err)
+	}
+	exists, err := fs.Exists(s.Locs.ExportCheckpointsFile)
+	if err != nil {
+		t.Fatal(err)
+	}
+	assert.False(exists, "checkpoints are removed after export completes")
+}
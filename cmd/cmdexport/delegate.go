@@ -5,6 +5,7 @@ import (
 
 	"github.com/pinpt/agent/pkg/expin"
 	"github.com/pinpt/agent/pkg/expsessions"
+	"github.com/pinpt/agent/pkg/metrics"
 	"github.com/pinpt/agent/rpcdef"
 )
 
@@ -38,6 +39,10 @@ func (s agentDelegate) ExportDone(sessionID string, lastProcessed interface{}) {
 }
 
 func (s agentDelegate) SendExported(sessionID string, objs []rpcdef.ExportObj) {
+	objectsWritten.Add(float64(len(objs)), metrics.Labels{
+		"integration": s.expin.IntegrationDef.Name,
+		"model":       s.expsession.GetModelType(idFromString(sessionID)),
+	})
 	err := s.export.sessions.Write(sessionID, objs)
 	if err != nil {
 		panic(err)
@@ -87,3 +92,7 @@ func (s agentDelegate) SendResumeEvent(msg string) error {
 func (s agentDelegate) GetWebhookURL() (url string, _ error) {
 	return s.export.GetWebhookURL(s.expin)
 }
+
+func (s agentDelegate) SendMetrics(data metrics.Snapshot) error {
+	return metrics.Default.Merge(data, metrics.Labels{"integration": s.expin.IntegrationDef.Name})
+}
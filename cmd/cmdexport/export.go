@@ -33,11 +33,17 @@ type Opts struct {
 	cmdintegration.Opts
 	Output              io.Writer
 	ReprocessHistorical bool
+	// Plan only lists the repos/projects that would be exported with estimated counts, without exporting data. Output receives PlanResult instead of Result.
+	Plan bool
 }
 
 type AgentConfig = cmdintegration.AgentConfig
 
 func Run(opts Opts) error {
+	if opts.Plan {
+		return runPlan(opts)
+	}
+
 	exp, err := newExport(opts)
 	if err != nil {
 		return err
@@ -79,6 +85,7 @@ type export struct {
 	stderr *bytes.Buffer
 
 	lastProcessed *jsonstore.Store
+	checkpoints   *expsessions.CheckpointFile
 
 	gitProcessingRepos chan gitRepoFetch
 	deviceInfo         deviceinfo.CommonInfo
@@ -128,6 +135,8 @@ func (s *export) Run() (_ Result, rerr error) {
 
 	startTime := time.Now()
 
+	defer s.writeMetricsFile()
+
 	s.deviceInfo = deviceinfo.CommonInfo{
 		CustomerID: opts.AgentConfig.CustomerID,
 		DeviceID:   s.EnrollConf.DeviceID,
@@ -155,6 +164,12 @@ func (s *export) Run() (_ Result, rerr error) {
 		return
 	}
 
+	err = s.resumeFromCheckpoints()
+	if err != nil {
+		rerr = err
+		return
+	}
+
 	err = s.checkIfIncremental()
 	if err != nil {
 		rerr = err
@@ -215,6 +230,13 @@ func (s *export) Run() (_ Result, rerr error) {
 		return
 	}
 
+	err = s.removeCheckpoints()
+	if err != nil {
+		s.Logger.Error("could not remove export checkpoints", "err", err)
+		rerr = err
+		return
+	}
+
 	err = s.sessions.Close()
 	if err != nil {
 		s.Logger.Error("could not close sessions", "err", err)
@@ -283,6 +305,10 @@ func (s *export) discardIncrementalData() error {
 	if err != nil {
 		return err
 	}
+	err = os.RemoveAll(s.Locs.ExportCheckpointsFile)
+	if err != nil {
+		return err
+	}
 	return os.RemoveAll(s.Locs.RipsrcCheckpoints)
 }
 
@@ -372,6 +398,10 @@ func (s *export) runExports() map[expin.Export]runResult {
 func (s *export) GetWebhookURL(exp expin.Export) (url string, rerr error) {
 	integration := s.Integrations[exp]
 
+	if u := s.Opts.AgentConfig.WebhookServer.IntegrationURL(integration.ExportConfig.Integration.Name, exp.IntegrationID); u != "" {
+		return u, nil
+	}
+
 	if !s.Opts.AgentConfig.Backend.Enable {
 		return "", errors.New("requested webhook url, but Backend.Enable is false")
 	}
@@ -88,6 +88,11 @@ func (s *export) gitProcessing() (hadErrors bool, fatalError error) {
 		i++
 		access := gitclone.AccessDetails{}
 		access.URL = fetch.URL
+		access.Mode = gitclone.AccessMode(fetch.AccessMode)
+		access.Username = fetch.Username
+		access.Password = fetch.Password
+		access.SSHKey = fetch.SSHKey
+		access.SSHKnownHosts = fetch.SSHKnownHosts
 
 		sessionID, err := s.gitSession(logger, fetch.exp)
 		if err != nil {
@@ -112,6 +117,14 @@ func (s *export) gitProcessing() (hadErrors bool, fatalError error) {
 			SessionRootID: sessionID,
 
 			CommitUsers: s.sessions.commitUsers,
+
+			CommitFiles: s.Opts.AgentConfig.Git.CommitFiles,
+			Tags:        s.Opts.AgentConfig.Git.Tags,
+			Mailmap:     !s.Opts.AgentConfig.Git.DisableMailmap,
+			AliasFile:   s.Opts.AgentConfig.Git.AliasFile,
+
+			Ownership:         s.Opts.AgentConfig.Git.Ownership,
+			OwnershipInterval: s.Opts.AgentConfig.Git.OwnershipInterval(),
 		}
 		for _, pr1 := range fetch.PRs {
 			pr2 := exportrepo.PR{}
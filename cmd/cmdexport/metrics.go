@@ -0,0 +1,15 @@
+package cmdexport
+
+import (
+	"github.com/pinpt/agent/pkg/metrics"
+)
+
+var objectsWritten = metrics.NewCounter("pinpoint_agent_objects_written_total", "Number of objects sent by integration by model")
+
+// writeMetricsFile saves metrics of export process, including the ones sent by integrations. Service merges them into its metrics when export finishes.
+func (s *export) writeMetricsFile() {
+	err := metrics.Default.Snapshot().WriteFile(s.Locs.ExportMetricsFile)
+	if err != nil {
+		s.Logger.Error("could not write metrics file", "err", err)
+	}
+}
@@ -0,0 +1,176 @@
+package cmdexport
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"os"
+	"sort"
+	"strings"
+	"text/tabwriter"
+
+	"github.com/pinpt/agent/cmd/cmdintegration"
+	"github.com/pinpt/agent/integrations/pkg/repoprojects"
+	"github.com/pinpt/agent/pkg/expin"
+	"github.com/pinpt/agent/pkg/structmarshal"
+	"github.com/pinpt/agent/rpcdef"
+)
+
+// PlanResult is the output of export in plan mode. It describes what would be exported without running the export.
+type PlanResult struct {
+	Integrations []PlanIntegration `json:"integrations"`
+	// APICalls is the estimated number of api requests for all integrations
+	APICalls int `json:"api_calls"`
+}
+
+// PlanIntegration is the plan for one integration
+type PlanIntegration struct {
+	ID string `json:"id"`
+	// Error is set when plan failed or is not supported by integration
+	Error string `json:"error,omitempty"`
+	repoprojects.Plan
+}
+
+// runPlan only runs the discovery phase of integrations, it does not create sessions, does not fetch git repos and does not modify last processed state
+func runPlan(opts Opts) error {
+	exp, err := newExport(opts)
+	if err != nil {
+		return err
+	}
+
+	res, err := exp.RunPlan()
+	if err != nil {
+		return err
+	}
+
+	err = exp.Destroy()
+	if err != nil {
+		return err
+	}
+
+	err = res.WriteTable(os.Stdout)
+	if err != nil {
+		return err
+	}
+
+	if opts.Output != nil {
+		b, err := json.Marshal(res)
+		if err != nil {
+			return err
+		}
+		_, err = opts.Output.Write(b)
+		if err != nil {
+			return err
+		}
+	}
+
+	return nil
+}
+
+func (s *export) RunPlan() (res PlanResult, rerr error) {
+	s.Logger.Info("Starting export plan, no data will be exported")
+
+	err := s.SetupIntegrations(nil)
+	if err != nil {
+		rerr = err
+		return
+	}
+
+	var exps []expin.Export
+	for exp := range s.Integrations {
+		exps = append(exps, exp)
+	}
+	sort.Slice(exps, func(i, j int) bool {
+		return exps[i].Index < exps[j].Index
+	})
+
+	res.Integrations = []PlanIntegration{}
+	for _, exp := range exps {
+		integration := s.Integrations[exp]
+		ires := PlanIntegration{}
+		ires.ID = exp.String()
+		plan, err := s.planIntegration(integration)
+		if err != nil {
+			s.Logger.Warn("Export plan failed for integration", "integration", exp.String(), "err", err)
+			ires.Error = err.Error()
+		} else {
+			ires.Plan = plan
+			res.APICalls += plan.APICalls
+		}
+		res.Integrations = append(res.Integrations, ires)
+	}
+	return
+}
+
+func (s *export) planIntegration(integration cmdintegration.Integration) (res repoprojects.Plan, _ error) {
+	ctx := context.Background()
+	client := integration.ILoader.RPCClient()
+
+	res0, err := client.OnboardExport(ctx, rpcdef.OnboardExportTypePlan, integration.ExportConfig)
+	if err != nil {
+		_ = s.CloseOnlyIntegrationAndHandlePanic(integration.ILoader)
+		return res, err
+	}
+
+	err = s.CloseOnlyIntegrationAndHandlePanic(integration.ILoader)
+	if err != nil {
+		return res, fmt.Errorf("error closing integration, err: %v", err)
+	}
+
+	if res0.Error != nil {
+		if res0.Error == rpcdef.ErrOnboardExportNotSupported {
+			return res, fmt.Errorf("export plan is not supported by integration")
+		}
+		return res, res0.Error
+	}
+
+	err = structmarshal.AnyToAny(res0.Data, &res)
+	if err != nil {
+		return res, fmt.Errorf("invalid plan returned by integration: %v", err)
+	}
+	return res, nil
+}
+
+// WriteTable writes human readable plan
+func (s PlanResult) WriteTable(w io.Writer) error {
+	tw := tabwriter.NewWriter(w, 0, 4, 2, ' ', 0)
+	for _, in := range s.Integrations {
+		fmt.Fprintf(tw, "Integration %v\n", in.ID)
+		if in.Error != "" {
+			fmt.Fprintf(tw, "  error: %v\n\n", in.Error)
+			continue
+		}
+		fmt.Fprintf(tw, "  PROJECT\tSTATUS\tCOUNTS\tAPI CALLS\n")
+		for _, p := range in.Projects {
+			status := "export"
+			if p.Error != "" {
+				status = "export, estimate failed: " + p.Error
+			}
+			calls := "unknown"
+			if p.APICalls != 0 {
+				calls = fmt.Sprint(p.APICalls)
+			}
+			fmt.Fprintf(tw, "  %v\t%v\t%v\t%v\n", p.ReadableID, status, formatCounts(p.Counts), calls)
+		}
+		for _, p := range in.Excluded {
+			fmt.Fprintf(tw, "  %v\texcluded: %v\t\t\n", p.ReadableID, p.Reason)
+		}
+		fmt.Fprintf(tw, "  projects: %v, excluded: %v, estimated api calls: %v\n\n", len(in.Projects), len(in.Excluded), in.APICalls)
+	}
+	fmt.Fprintf(tw, "Estimated api calls for all integrations: %v\n", s.APICalls)
+	return tw.Flush()
+}
+
+func formatCounts(counts map[string]int) string {
+	var keys []string
+	for k := range counts {
+		keys = append(keys, k)
+	}
+	sort.Strings(keys)
+	var res []string
+	for _, k := range keys {
+		res = append(res, fmt.Sprintf("%v=%v", k, counts[k]))
+	}
+	return strings.Join(res, " ")
+}
@@ -2,6 +2,7 @@ package process
 
 import (
 	"errors"
+	"sort"
 	"strings"
 	"sync"
 
@@ -9,17 +10,55 @@ import (
 	"github.com/pinpt/integration-sdk/sourcecode"
 )
 
+// UserAliasModelName is the model name of exported aliases
+const UserAliasModelName = "git.UserAlias"
+
+// UserAlias is the original commit identity mapped to exported user using mailmap or alias file
+type UserAlias struct {
+	ID         string `json:"id"`
+	CustomerID string `json:"customer_id"`
+	// UserID is the id of exported sourcecode.User
+	UserID        string `json:"user_id"`
+	OriginalEmail string `json:"original_email"`
+	OriginalName  string `json:"original_name"`
+}
+
+func (s UserAlias) ToMap() map[string]interface{} {
+	return map[string]interface{}{
+		"id":             s.ID,
+		"customer_id":    s.CustomerID,
+		"user_id":        s.UserID,
+		"original_email": s.OriginalEmail,
+		"original_name":  s.OriginalName,
+	}
+}
+
 type CommitUsers struct {
-	data map[string]bool
-	mu   sync.Mutex
+	data    map[string]bool
+	aliases map[string]UserAlias
+	mu      sync.Mutex
 }
 
 func NewCommitUsers() *CommitUsers {
 	s := &CommitUsers{}
 	s.data = map[string]bool{}
+	s.aliases = map[string]UserAlias{}
 	return s
 }
 
+// Aliases returns all original identities of users changed using mailmap or alias file, sorted by id. Exported separately, so that each user is sent once with all aliases kept.
+func (s *CommitUsers) Aliases() (res []UserAlias) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	for _, a := range s.aliases {
+		res = append(res, a)
+	}
+	sort.Slice(res, func(i, j int) bool {
+		return res[i].ID < res[j].ID
+	})
+	return
+}
+
 func (s *CommitUsers) Transform(data map[string]interface{}) (_ map[string]interface{}, _ error) {
 	s.mu.Lock()
 	defer s.mu.Unlock()
@@ -43,14 +82,16 @@ func (s *CommitUsers) Transform(data map[string]interface{}) (_ map[string]inter
 	}
 	sourceID, _ := data["source_id"].(string)
 
+	// set when identity was mapped using mailmap, kept so the mapping is auditable
+	originalEmail, _ := data["original_email"].(string)
+	originalEmail = strings.ToLower(originalEmail)
+	originalName, _ := data["original_name"].(string)
+
 	// We only send the first name encountered. For this reason name is not present in hash.
 	// TODO: maybe support multiple names, needs design discussion about pipeline
 	key := email + "@@@" + sourceID
 
-	if s.data[key] {
-		// was already added
-		return nil, nil
-	}
+	sent := s.data[key]
 	s.data[key] = true
 
 	obj := sourcecode.User{}
@@ -74,7 +115,20 @@ func (s *CommitUsers) Transform(data map[string]interface{}) (_ map[string]inter
 		obj.AssociatedRefID = &sourceID
 	}
 
-	res := obj.ToMap()
+	if originalEmail != "" || originalName != "" {
+		alias := UserAlias{}
+		alias.ID = hash.Values("UserAlias", obj.ID, originalEmail, originalName)
+		alias.CustomerID = customerID
+		alias.UserID = obj.ID
+		alias.OriginalEmail = originalEmail
+		alias.OriginalName = originalName
+		s.aliases[alias.ID] = alias
+	}
+
+	if sent {
+		// was already added
+		return nil, nil
+	}
 
-	return res, nil
+	return obj.ToMap(), nil
 }
@@ -0,0 +1,42 @@
+package process
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestCommitUsersAliases(t *testing.T) {
+	assert := assert.New(t)
+	s := NewCommitUsers()
+	transform := func(data map[string]interface{}) map[string]interface{} {
+		data["customer_id"] = "c1"
+		data["name"] = "User 1"
+		res, err := s.Transform(data)
+		if err != nil {
+			t.Fatal(err)
+		}
+		return res
+	}
+
+	u1 := transform(map[string]interface{}{"email": "u1@example.com", "original_email": "u1@old.example.com"})
+	if u1 == nil {
+		t.Fatal("expected user")
+	}
+	assert.Nil(u1["original_email"])
+	// the same user is sent once for all aliases
+	assert.Nil(transform(map[string]interface{}{"email": "U1@example.com", "original_email": "u1@laptop"}))
+	assert.Nil(transform(map[string]interface{}{"email": "u1@example.com"}))
+	assert.Nil(transform(map[string]interface{}{"email": "u1@example.com", "original_email": "u1@laptop"}))
+
+	aliases := s.Aliases()
+	if !assert.Len(aliases, 2) {
+		return
+	}
+	var emails []string
+	for _, a := range aliases {
+		assert.Equal(u1["id"], a.UserID)
+		emails = append(emails, a.OriginalEmail)
+	}
+	assert.ElementsMatch([]string{"u1@old.example.com", "u1@laptop"}, emails)
+}
@@ -14,6 +14,16 @@ import (
 type Result struct {
 	Duration     time.Duration       `json:"duration"`
 	Integrations []ResultIntegration `json:"integrations"`
+	// Redacted is the number of fields changed by redaction policies by model name and field path
+	Redacted map[string]map[string]int `json:"redacted,omitempty"`
+	// Secrets is the summary of secrets found and redacted in exported objects, the full list is in ReportFile
+	Secrets *ResultSecrets `json:"secrets,omitempty"`
+}
+
+type ResultSecrets struct {
+	Total      int            `json:"total"`
+	ByRule     map[string]int `json:"by_rule"`
+	ReportFile string         `json:"report_file"`
 }
 
 type ResultIntegration struct {
@@ -53,6 +63,17 @@ func (s *export) formatResults(runResult map[expin.Export]runResult, startTime t
 
 	resAll := Result{}
 	resAll.Duration = time.Now().Sub(startTime)
+	if s.sessions.redact != nil {
+		resAll.Redacted = s.sessions.redact.Report()
+	}
+	if s.sessions.secrets != nil {
+		rep := s.sessions.secrets.Report()
+		resAll.Secrets = &ResultSecrets{
+			Total:      rep.Total,
+			ByRule:     rep.ByRule,
+			ReportFile: s.Locs.SecretFindingsFile,
+		}
+	}
 	for exp, res0 := range runResult {
 		res := ResultIntegration{}
 		res.index = exp.Index
@@ -1,9 +1,11 @@
 package cmdexport
 
 import (
+	"bytes"
 	"context"
 	"encoding/json"
 	"os"
+	"path/filepath"
 	"strconv"
 	"strings"
 	"time"
@@ -13,6 +15,11 @@ import (
 	"github.com/pinpt/agent/pkg/commitusers"
 	"github.com/pinpt/agent/pkg/expin"
 	"github.com/pinpt/agent/pkg/expsessions"
+	"github.com/pinpt/agent/pkg/expsinks"
+	"github.com/pinpt/agent/pkg/fs"
+	"github.com/pinpt/agent/pkg/identity"
+	"github.com/pinpt/agent/pkg/redact"
+	"github.com/pinpt/agent/pkg/secrets"
 	"github.com/pinpt/agent/rpcdef"
 )
 
@@ -26,6 +33,16 @@ type sessions struct {
 
 	dedupStore expsessions.DedupStore
 
+	sinks     []expsessions.Sink
+	newWriter expsessions.NewWriterFunc
+
+	// identities is nil when identity resolution is disabled
+	identities *identity.Graph
+	// redact is nil when there are no redaction policies
+	redact *redact.Engine
+	// secrets is nil when secret scanning is disabled
+	secrets *secrets.Scanner
+
 	trackProgress bool
 }
 
@@ -41,12 +58,43 @@ func newSessions(logger hclog.Logger, export *export, trackProgress bool) (_ *se
 		s.progressTracker = expsessions.NewProgressTracker()
 	}
 
-	newWriter := func(modelName string, id expsessions.ID) expsessions.Writer {
-		return expsessions.NewFileWriter(modelName, export.Locs.Uploads, id)
+	var err error
+	s.sinks, err = expsinks.NewAll(expsinks.Opts{
+		Logger: logger,
+		Locs:   export.Locs,
+	}, export.Opts.AgentConfig.Sinks)
+	if err != nil {
+		rerr = err
+		return
+	}
+
+	newWriter := expsessions.NewWriterFromSinks(s.sinks)
+
+	redactEngine, err := redact.New(export.Opts.AgentConfig.Redaction)
+	if err != nil {
+		rerr = err
+		return
+	}
+	if redactEngine.Enabled() {
+		s.redact = redactEngine
+		newWriterPrev := newWriter
+		newWriter = func(modelName string, id expsessions.ID) expsessions.Writer {
+			wr := newWriterPrev(modelName, id)
+			return redact.NewWriter(wr, s.redact, modelName)
+		}
+	}
+
+	// scan for secrets before redaction policies, so that findings are reported for original fields
+	if !export.Opts.AgentConfig.Secrets.Disable {
+		s.secrets = secrets.New(export.Opts.AgentConfig.Secrets)
+		newWriterPrev := newWriter
+		newWriter = func(modelName string, id expsessions.ID) expsessions.Writer {
+			wr := newWriterPrev(modelName, id)
+			return secrets.NewWriter(wr, s.secrets, modelName)
+		}
 	}
 
 	if os.Getenv("PP_AGENT_DISABLE_DEDUP") == "" {
-		var err error
 		s.dedupStore, err = expsessions.NewDedupStore(export.Locs.DedupFile)
 		if err != nil {
 			rerr = err
@@ -59,9 +107,20 @@ func newSessions(logger hclog.Logger, export *export, trackProgress bool) (_ *se
 		}
 	}
 
+	s.newWriter = newWriter
+
+	if !export.Opts.AgentConfig.Identity.Disable {
+		s.identities, err = identity.NewGraph(export.Locs.IdentityGraphFile)
+		if err != nil {
+			rerr = err
+			return
+		}
+	}
+
 	s.expsession = expsessions.New(expsessions.Opts{
 		Logger:        logger,
 		LastProcessed: export.lastProcessed,
+		Checkpoints:   export.checkpoints,
 		NewWriter:     newWriter,
 		SendProgress: func(progressPath expsessions.ProgressPath, current, total int) {
 			if s.trackProgress {
@@ -97,6 +156,13 @@ func (s *sessions) sendProgress() {
 		s.logger.Debug("progress", "data", "\n\n"+res+"\n\n")
 	}
 
+	// progress and metrics files are used by status api of the service
+	err := s.writeProgressFile()
+	if err != nil {
+		s.logger.Error("could not write progress file", "err", err)
+	}
+	s.export.writeMetricsFile()
+
 	if s.export.Opts.AgentConfig.Backend.Enable {
 		skipDone := false
 		if os.Getenv("PP_AGENT_NO_PROGRESS_ALL") != "" {
@@ -122,6 +188,19 @@ func (s *sessions) sendProgress() {
 	}
 }
 
+func (s *sessions) writeProgressFile() error {
+	b, err := json.Marshal(s.progressTracker.ProgressLinesNestedMap(false))
+	if err != nil {
+		return err
+	}
+	loc := s.export.Locs.ExportProgressFile
+	err = os.MkdirAll(filepath.Dir(loc), 0777)
+	if err != nil {
+		return err
+	}
+	return fs.WriteToTempAndRename(bytes.NewReader(b), loc)
+}
+
 func (s *sessions) Close() error {
 
 	if s.trackProgress {
@@ -138,7 +217,30 @@ func (s *sessions) Close() error {
 			return err
 		}
 	}
-	return nil
+
+	if s.identities != nil {
+		err := s.writeIdentityLinks()
+		if err != nil {
+			return err
+		}
+	}
+
+	err := s.writeUserAliases()
+	if err != nil {
+		return err
+	}
+
+	if s.redact != nil {
+		s.logger.Info("Redacted fields", "changed", s.redact.ReportLines())
+	}
+
+	if s.secrets != nil {
+		err := s.writeSecretFindings()
+		if err != nil {
+			return err
+		}
+	}
+	return expsessions.CloseSinks(s.sinks)
 }
 
 func (s *sessions) new(export expin.Export, modelType string) (
@@ -204,5 +306,82 @@ func (s *sessions) Write(sessionID string, objs []rpcdef.ExportObj) error {
 	for _, obj := range objs {
 		data = append(data, obj.Data.(map[string]interface{}))
 	}
+	if s.identities != nil {
+		userModel := modelType
+		if modelType == commitusers.TableName {
+			// transformed into sourcecode.User above
+			userModel = "sourcecode.User"
+		}
+		for _, obj := range data {
+			s.identities.AddObject(userModel, obj)
+		}
+	}
 	return s.expsession.Write(id, data)
 }
+
+// writeSecretFindings saves findings report, it does not contain secret values
+func (s *sessions) writeSecretFindings() error {
+	rep := s.secrets.Report()
+	if rep.Total != 0 {
+		s.logger.Warn("Secrets found and redacted in exported data", "total", rep.Total, "by_rule", rep.ByRule, "report", s.export.Locs.SecretFindingsFile)
+	}
+	b, err := json.Marshal(rep)
+	if err != nil {
+		return err
+	}
+	loc := s.export.Locs.SecretFindingsFile
+	err = os.MkdirAll(filepath.Dir(loc), 0777)
+	if err != nil {
+		return err
+	}
+	return fs.WriteToTempAndRename(bytes.NewReader(b), loc)
+}
+
+// writeUserAliases writes original identities of commit users changed using mailmap or alias file to sinks
+func (s *sessions) writeUserAliases() error {
+	aliases := s.commitUsers.Aliases()
+	s.logger.Info("Commit user aliases", "count", len(aliases))
+	if len(aliases) == 0 {
+		return nil
+	}
+	var data []map[string]interface{}
+	for _, a := range aliases {
+		data = append(data, a.ToMap())
+	}
+	// not created by expsessions, ids of sessions start from 1
+	wr := s.newWriter(process.UserAliasModelName, 0)
+	err := wr.Write(s.logger, data)
+	if err != nil {
+		wr.Rollback()
+		return err
+	}
+	return wr.Close()
+}
+
+// writeIdentityLinks saves identity graph and writes links between identities to sinks
+func (s *sessions) writeIdentityLinks() error {
+	err := s.identities.Save()
+	if err != nil {
+		return err
+	}
+	links, err := identity.Resolve(s.identities.Identities(), s.export.Opts.AgentConfig.Identity, s.export.Opts.AgentConfig.CustomerID)
+	if err != nil {
+		return err
+	}
+	s.logger.Info("Identity links", "count", len(links))
+	if len(links) == 0 {
+		return nil
+	}
+	var data []map[string]interface{}
+	for _, l := range links {
+		data = append(data, l.ToMap())
+	}
+	// not created by expsessions, ids of sessions start from 1
+	wr := s.newWriter(identity.LinkModelName, 0)
+	err = wr.Write(s.logger, data)
+	if err != nil {
+		wr.Rollback()
+		return err
+	}
+	return wr.Close()
+}
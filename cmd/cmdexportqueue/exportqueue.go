@@ -0,0 +1,66 @@
+package cmdexportqueue
+
+import (
+	"fmt"
+	"io"
+	"text/tabwriter"
+	"time"
+
+	"github.com/hashicorp/go-hclog"
+	"github.com/pinpt/agent/cmd/cmdrunnorestarts/exporter"
+	"github.com/pinpt/agent/cmd/cmdrunnorestarts/exporter/fsqueue"
+	"github.com/pinpt/agent/pkg/fsconf"
+)
+
+// List writes pending and dead-lettered export requests
+func List(logger hclog.Logger, locs fsconf.Locs, w io.Writer) error {
+	opts := exporter.QueueOpts(logger, locs)
+	pending, err := fsqueue.ReadPending(opts.File)
+	if err != nil {
+		return err
+	}
+	dead, err := fsqueue.ReadDeadLetter(opts.DeadLetterFile)
+	if err != nil {
+		return err
+	}
+
+	tw := tabwriter.NewWriter(w, 0, 4, 2, ' ', 0)
+	fmt.Fprintf(tw, "Pending export requests: %v\n", len(pending))
+	fmt.Fprintf(tw, "  ID\tPRIORITY\tADDED\tATTEMPTS\tNEXT ATTEMPT\tLAST ERROR\n")
+	for _, e := range pending {
+		fmt.Fprintf(tw, "  %v\t%v\t%v\t%v\t%v\t%v\n", e.ID, priorityStr(e.Priority), formatTime(e.Added), e.Attempts, formatTime(e.NextAttempt), e.LastError)
+	}
+	fmt.Fprintf(tw, "\nDead-lettered export requests: %v\n", len(dead))
+	fmt.Fprintf(tw, "  ID\tPRIORITY\tADDED\tATTEMPTS\tREMOVED\tREASON\tLAST ERROR\n")
+	for _, e := range dead {
+		fmt.Fprintf(tw, "  %v\t%v\t%v\t%v\t%v\t%v\t%v\n", e.ID, priorityStr(e.Priority), formatTime(e.Added), e.Attempts, formatTime(e.Date), e.Reason, e.LastError)
+	}
+	return tw.Flush()
+}
+
+// Requeue moves dead-lettered export request back to the queue. Agent service should be stopped, since the queue is only read on start.
+func Requeue(logger hclog.Logger, locs fsconf.Locs, id int) error {
+	newID, err := fsqueue.Requeue(exporter.QueueOpts(logger, locs), id)
+	if err != nil {
+		return err
+	}
+	logger.Info("export request added back to queue, it will be processed when agent service is started", "id", id, "new_id", newID)
+	return nil
+}
+
+func priorityStr(p fsqueue.Priority) string {
+	switch p {
+	case fsqueue.PriorityNormal:
+		return "normal"
+	case fsqueue.PriorityHigh:
+		return "high"
+	}
+	return fmt.Sprint(int(p))
+}
+
+func formatTime(t time.Time) string {
+	if t.IsZero() {
+		return "-"
+	}
+	return t.Format(time.RFC3339)
+}
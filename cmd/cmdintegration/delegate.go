@@ -5,6 +5,7 @@ import (
 
 	"github.com/hashicorp/go-hclog"
 	"github.com/pinpt/agent/pkg/expin"
+	"github.com/pinpt/agent/pkg/metrics"
 	"github.com/pinpt/agent/rpcdef"
 )
 
@@ -75,3 +76,7 @@ func (s agentDelegate) SendResumeEvent(msg string) error {
 func (s agentDelegate) GetWebhookURL() (url string, _ error) {
 	panic("not implemented")
 }
+
+func (s agentDelegate) SendMetrics(data metrics.Snapshot) error {
+	return metrics.Default.Merge(data, metrics.Labels{"integration": s.exp.IntegrationDef.Name})
+}
@@ -11,9 +11,14 @@ import (
 	"time"
 
 	"github.com/pinpt/agent/cmd/cmdrunnorestarts/inconfig"
+	"github.com/pinpt/agent/cmd/cmdrunnorestarts/webhookserver"
 	"github.com/pinpt/agent/pkg/aevent"
 	"github.com/pinpt/agent/pkg/date"
 	"github.com/pinpt/agent/pkg/expin"
+	"github.com/pinpt/agent/pkg/expsinks"
+	"github.com/pinpt/agent/pkg/identity"
+	"github.com/pinpt/agent/pkg/redact"
+	"github.com/pinpt/agent/pkg/secrets"
 	"github.com/pinpt/agent/pkg/structmarshal"
 
 	"github.com/hashicorp/go-hclog"
@@ -21,6 +26,7 @@ import (
 	"github.com/pinpt/agent/pkg/agentconf"
 	"github.com/pinpt/agent/pkg/deviceinfo"
 	"github.com/pinpt/agent/pkg/fsconf"
+	"github.com/pinpt/agent/pkg/gitconf"
 	"github.com/pinpt/agent/pkg/iloader"
 	"github.com/pinpt/agent/rpcdef"
 	"github.com/pinpt/go-common/v10/datamodel"
@@ -45,6 +51,24 @@ type AgentConfig struct {
 	// DevUseCompiledIntegrations set to true to use compiled integrations in dev build. They are used by default in prod builds.
 	DevUseCompiledIntegrations bool `json:"dev_use_compiled_integrations"`
 
+	// Sinks defines where exported data is written. When empty only uploads dir is used, which is later sent to pinpoint backend.
+	Sinks []expsinks.Config `json:"sinks"`
+
+	// Identity configures linking of user identities across integrations, links are written as identity.Link objects.
+	Identity identity.Rules `json:"identity"`
+
+	// Redaction policies are applied to all objects before they are written by sinks.
+	Redaction redact.Config `json:"redaction"`
+
+	// Secrets configures redaction of api tokens and passwords found in exported objects.
+	Secrets secrets.Config `json:"secrets"`
+
+	// WebhookServer is used to return the url of built-in webhook receiver to integrations. Its secret is passed to integrations as webhook_secret config field.
+	WebhookServer webhookserver.Config `json:"webhook_server"`
+
+	// Git configures optional processing of git repos.
+	Git gitconf.Config `json:"git"`
+
 	Backend struct {
 		// Enable enables calls to pinpoint backend. It is disabled by default, but is required for the following features:
 		// - sending progress data to backend
@@ -148,6 +172,16 @@ func NewCommand(opts Opts) (*Command, error) {
 	return s, nil
 }
 
+// IntegrationsDir returns resolved location of integration binaries
+func (s *Command) IntegrationsDir() string {
+	return s.integrationsDir
+}
+
+// DevUseCompiledIntegrations returns true if compiled integrations should be used in dev build
+func (s *Command) DevUseCompiledIntegrations() bool {
+	return s.devUseCompiledIntegrations
+}
+
 func (s *Command) OnlyIntegration() Integration {
 	if len(s.Integrations) == 1 {
 		for _, v := range s.Integrations {
@@ -175,6 +209,11 @@ func (s *Command) setupConfig() error {
 		ec := rpcdef.ExportConfig{}
 		ec.Pinpoint.CustomerID = s.Opts.AgentConfig.CustomerID
 
+		if wh := s.Opts.AgentConfig.WebhookServer; wh.URL != "" && wh.Secret != "" {
+			obj.Config = copyMap(obj.Config)
+			obj.Config["webhook_secret"] = wh.Secret
+		}
+
 		if refresh, ok := obj.Config["refresh_token"].(string); ok && refresh != "" {
 			in.OauthRefreshToken = refresh
 			ec.UseOAuth = true
@@ -11,6 +11,7 @@ import (
 	"github.com/pinpt/agent/rpcdef"
 
 	"github.com/pinpt/agent/cmd/cmdintegration"
+	"github.com/pinpt/agent/cmd/pkg/directexport"
 )
 
 type Mutation struct {
@@ -80,12 +81,40 @@ func newExport(opts Opts) (*export, error) {
 func (s *export) runAndPrint() error {
 	res0, err := s.run()
 
-	res := Result{}
+	res := NewResult(res0, err, s.integration.Export.IntegrationDef.Name, s.Opts.Mutation.Fn)
+
+	filter, err := directexport.NewObjectFilter(s.Logger, s.Opts.AgentConfig)
+	if err != nil {
+		return err
+	}
+	filter.ApplyMutated(res.MutatedObjects)
+	filter.LogReport()
+
+	b, err := json.Marshal(res)
+	if err != nil {
+		return err
+	}
+	_, err = s.Opts.Output.Write(b)
+	if err != nil {
+		return err
+	}
+
+	s.Logger.Info("mutate completed", "success", res.Success, "err", res.Error)
+
+	// BUG: last log message is missing without this
+	time.Sleep(10 * time.Millisecond)
+	return nil
+}
+
+// NewResult converts mutate rpc call result and error into Result, adding integration name and mutation fn to error message
+func NewResult(res0 rpcdef.MutateResult, err error, integrationName string, fn string) (res Result) {
 	if err != nil {
 		res.Error = err.Error()
 	} else if res0.ErrorCode != "" {
 		res.ErrorCode = res0.ErrorCode
 		res.Error = res0.Error
+		// contains field errors for validation errors
+		res.WebappResponse = res0.WebappResponse
 		if res.Error == "" {
 			res.Error = "Full error message not provided for status code: " + res.ErrorCode + ". This is a bug, we should always provide full error message."
 		}
@@ -98,23 +127,9 @@ func (s *export) runAndPrint() error {
 	}
 	// add more context
 	if res.Error != "" {
-		res.Error = fmt.Sprintf("%v (%v/%v)", res.Error, s.integration.Export.IntegrationDef.Name, strings.ToLower(s.Opts.Mutation.Fn))
-	}
-
-	b, err := json.Marshal(res)
-	if err != nil {
-		return err
-	}
-	_, err = s.Opts.Output.Write(b)
-	if err != nil {
-		return err
+		res.Error = fmt.Sprintf("%v (%v/%v)", res.Error, integrationName, strings.ToLower(fn))
 	}
-
-	s.Logger.Info("mutate completed", "success", res.Success, "err", res.Error)
-
-	// BUG: last log message is missing without this
-	time.Sleep(10 * time.Millisecond)
-	return nil
+	return
 }
 
 func (s *export) run() (_ rpcdef.MutateResult, rerr error) {
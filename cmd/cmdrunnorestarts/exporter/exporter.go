@@ -9,17 +9,23 @@ import (
 	"sync"
 	"time"
 
+	"github.com/pinpt/agent/cmd/cmdbundle"
 	"github.com/pinpt/agent/cmd/cmdexport"
 	"github.com/pinpt/agent/cmd/cmdintegration"
 	"github.com/pinpt/agent/cmd/cmdrunnorestarts/exporter/fsqueue"
 	"github.com/pinpt/agent/cmd/cmdrunnorestarts/inconfig"
 	"github.com/pinpt/agent/cmd/cmdrunnorestarts/subcommand"
+	"github.com/pinpt/agent/cmd/cmdrunnorestarts/updater"
 	"github.com/pinpt/agent/cmd/cmdupload"
 
 	"github.com/pinpt/agent/pkg/agentconf"
+	"github.com/pinpt/agent/pkg/bundle"
 	"github.com/pinpt/agent/pkg/deviceinfo"
+	"github.com/pinpt/agent/pkg/expsinks"
 	"github.com/pinpt/agent/pkg/fsconf"
 	"github.com/pinpt/agent/pkg/logutils"
+	"github.com/pinpt/agent/pkg/metrics"
+	"github.com/pinpt/agent/pkg/structmarshal"
 
 	hclog "github.com/hashicorp/go-hclog"
 	"github.com/pinpt/integration-sdk/agent"
@@ -57,6 +63,15 @@ type Exporter struct {
 
 	queue                 *fsqueue.Queue
 	queueRequestForwarder chan fsqueue.Request
+
+	statusMu         sync.Mutex
+	current          *ExportStatus
+	last             *ExportStatus
+	lastResults      map[string]IntegrationResult
+	lastIntegrations []agent.ExportRequestIntegrations
+
+	// metricsMu protects export metrics file
+	metricsMu sync.Mutex
 }
 
 // Request is the export request to put into the ExportQueue
@@ -65,6 +80,8 @@ type Request struct {
 	Data *agent.ExportRequest
 	// MessageID is the message id received from the server in headers
 	MessageID string
+	// Local is set for exports queued using status api. These have no job on the server, so no export events are sent and data is not uploaded.
+	Local bool
 }
 
 // New creates exporter
@@ -83,14 +100,41 @@ func New(opts Opts) (*Exporter, error) {
 	}
 	s.logger = opts.Logger
 	s.ExportQueue = make(chan Request)
+	s.lastResults = map[string]IntegrationResult{}
 	var err error
-	s.queue, s.queueRequestForwarder, err = fsqueue.New(opts.Logger, s.opts.FSConf.ExportQueueFile)
+	s.lastIntegrations, err = loadLastIntegrations(opts.FSConf.LastExportIntegrationsFile)
+	if err != nil {
+		s.logger.Error("could not read integrations of the last export request, these will be available after the next request", "err", err)
+	}
+	s.queue, s.queueRequestForwarder, err = fsqueue.New(QueueOpts(opts.Logger, opts.FSConf))
 	if err != nil {
 		return nil, fmt.Errorf("could not create fsqueue: %v", err)
 	}
 	return s, nil
 }
 
+// QueueOpts returns the options for export queue. Also used for inspecting the queue from cli.
+func QueueOpts(logger hclog.Logger, locs fsconf.Locs) fsqueue.Opts {
+	opts := fsqueue.Opts{}
+	opts.Logger = logger
+	opts.File = locs.ExportQueueFile
+	opts.DeadLetterFile = locs.ExportQueueDeadLetterFile
+	opts.LegacyFile = locs.ExportQueueLegacyFile
+	opts.Priority = func(data fsqueue.Data) fsqueue.Priority {
+		req := Request{}
+		err := structmarshal.MapToStruct(data, &req)
+		if err != nil || req.Data == nil {
+			return fsqueue.PriorityNormal
+		}
+		// historical exports are requested manually, process them before scheduled incrementals
+		if req.Data.ReprocessHistorical {
+			return fsqueue.PriorityHigh
+		}
+		return fsqueue.PriorityNormal
+	}
+	return opts
+}
+
 func (s *Exporter) setRunning(ex bool) {
 	s.mu.Lock()
 	s.exporting = ex
@@ -105,17 +149,37 @@ func (s *Exporter) IsRunning() bool {
 	return ex
 }
 
-func (s *Exporter) export(data *agent.ExportRequest, messageID string) {
+// export runs export and sends the result to the backend. Returns error only if export failed and should be retried, in which case no failed event is sent for this job, so that the backend does not get a failure followed by a success. On the last attempt the failed event is always sent. No events are sent for local exports.
+func (s *Exporter) export(data *agent.ExportRequest, messageID string, lastAttempt bool, local bool) (retry error) {
 	started := time.Now()
 
+	outcome := "error"
+	defer func() {
+		exportsTotal.Inc(metrics.Labels{"outcome": outcome})
+	}()
+
 	handleError := func(err error) {
 		s.logger.Error("export finished with error", "err", err)
+		s.setCurrentError(err)
+		if local {
+			return
+		}
 		err2 := s.sendFailedEvent(data.JobID, started, time.Now(), err)
 		if err2 != nil {
 			s.logger.Error("error sending failed export event", "sending_err", err2, "export_err", err)
 		}
 	}
 
+	handleRetryableError := func(err error) error {
+		if lastAttempt {
+			handleError(err)
+			return nil
+		}
+		s.logger.Error("export finished with error, will retry", "err", err)
+		s.setCurrentError(err)
+		return err
+	}
+
 	var in2 []agent.ExportRequestIntegrations
 	hasIntegrationsWithNoInclusions := false
 	for _, in := range data.Integrations {
@@ -135,34 +199,43 @@ func (s *Exporter) export(data *agent.ExportRequest, messageID string) {
 		} else {
 			handleError(errors.New("passed export request has no integrations, ignoring it"))
 		}
-		return
+		return nil
 	}
 
-	if err := s.sendStartExportEvent(data.JobID, data.Integrations); err != nil {
-		handleError(fmt.Errorf("error sending export response start event: %v", err))
-		return
+	if !local {
+		if err := s.sendStartExportEvent(data.JobID, data.Integrations); err != nil {
+			return handleRetryableError(fmt.Errorf("error sending export response start event: %v", err))
+		}
 	}
 
 	exportResult, err := s.doExport(data, messageID)
 	if err != nil {
 		if _, o := err.(*subcommand.Cancelled); o {
+			outcome = "cancelled"
 			handleError(errors.New("export cancelled"))
-			return
+			return nil
 		}
-		handleError(err)
-		return
+		return handleRetryableError(err)
+	}
+	s.setLastResults(data.JobID, exportResult)
+	s.recordIntegrationMetrics(data, exportResult)
+	if local {
+		outcome = "success"
+		return nil
 	}
 	s.logger.Info("sending back export event")
 
 	if data.UploadURL == nil || *data.UploadURL == "" {
 		handleError(errors.New("No UploadURL provided in ExportRequest"))
-		return
+		return nil
 	}
 
+	outcome = "success"
 	err = s.sendSuccessEvent(data.JobID, started, exportResult, *data.UploadURL, data.Integrations)
 	if err != nil {
 		s.logger.Error("error sending back export completed event", "err", err)
 	}
+	return nil
 }
 
 type exportResult struct {
@@ -214,6 +287,15 @@ func (s *Exporter) doExport(data *agent.ExportRequest, messageID string) (res ex
 func (s *Exporter) doExport2(data *agent.ExportRequest, messageID string) (partsCount int, fileSize int64, res cmdexport.Result, rerr error) {
 	s.logger.Info("processing export request", "job_id", data.JobID, "request_date", data.RequestDate.Rfc3339, "reprocess_historical", data.ReprocessHistorical)
 
+	if s.conf.Bundle.Enabled() {
+		// commit the state before restoring it from backup, if all bundles were uploaded
+		err := cmdbundle.ImportAcks(s.logger, s.opts.FSConf, s.conf.Bundle)
+		if err != nil {
+			rerr = err
+			return
+		}
+	}
+
 	err := s.backupRestoreStateDir()
 	if err != nil {
 		rerr = fmt.Errorf("could not manage backup dir for export: %v", err)
@@ -240,10 +322,15 @@ func (s *Exporter) doExport2(data *agent.ExportRequest, messageID string) (parts
 	}
 
 	fsconf := s.opts.FSConf
-	// delete existing uploads
-	if err = os.RemoveAll(fsconf.Uploads); err != nil {
-		rerr = err
-		return
+	// keep uploads from previous interrupted export, since completed child sessions are skipped on resume and their data is only in uploads
+	if _, err := os.Stat(fsconf.ExportCheckpointsFile); err == nil && !data.ReprocessHistorical {
+		s.logger.Info("previous export was interrupted, keeping existing uploads")
+	} else {
+		// delete existing uploads
+		if err = os.RemoveAll(fsconf.Uploads); err != nil {
+			rerr = err
+			return
+		}
 	}
 
 	integrations = dedupInclusionsAndMergeUsers(s.logger, integrations)
@@ -260,7 +347,16 @@ func (s *Exporter) doExport2(data *agent.ExportRequest, messageID string) (parts
 
 	s.logger.Info("export finished")
 
-	if s.conf.Channel != "dev" {
+	if s.conf.Bundle.Enabled() {
+		committed, err := s.writeBundle(data, res, logFile)
+		if err != nil {
+			rerr = err
+			return
+		}
+		if !committed {
+			return
+		}
+	} else if s.conf.Channel != "dev" && data.UploadURL != nil {
 
 		s.logger.Info("running upload")
 
@@ -287,6 +383,62 @@ func (s *Exporter) doExport2(data *agent.ExportRequest, messageID string) (parts
 	return
 }
 
+// uploadRequired returns true if export results can only be sent to pinpoint using the upload url from export request
+func (s *Exporter) uploadRequired() bool {
+	if s.conf.Bundle.Enabled() || s.conf.Channel == "dev" {
+		return false
+	}
+	if len(s.conf.Sinks) == 0 {
+		return true
+	}
+	for _, c := range s.conf.Sinks {
+		if c.Type == expsinks.TypeUpload {
+			return true
+		}
+	}
+	return false
+}
+
+// writeBundle saves export results to bundle instead of uploading. Returns true if there are no pending bundles and the state could be committed.
+func (s *Exporter) writeBundle(data *agent.ExportRequest, res cmdexport.Result, logFile string) (committed bool, _ error) {
+	opts := cmdbundle.ExportOpts{}
+	opts.Logger = s.logger
+	opts.Locs = s.opts.FSConf
+	opts.Conf = s.conf.Bundle
+	opts.CustomerID = s.conf.CustomerID
+	opts.DeviceID = s.conf.DeviceID
+	opts.AgentVersion = os.Getenv("PP_AGENT_VERSION")
+	opts.JobID = data.JobID
+	if data.UploadURL != nil {
+		opts.UploadURL = *data.UploadURL
+	}
+	opts.Results = res
+	opts.LogFile = logFile
+	integrations, err := updater.New(s.logger, s.opts.FSConf, s.conf).Integrations()
+	if err != nil {
+		return false, err
+	}
+	opts.Integrations = map[string]string{}
+	for _, in := range integrations {
+		opts.Integrations[in.Name] = in.Version
+	}
+	_, err = cmdbundle.Export(opts)
+	if err == cmdupload.ErrNoFilesFound {
+		s.logger.Info("skipping bundle, no files generated")
+	} else if err != nil {
+		return false, err
+	}
+	pending, err := bundle.ReadPending(s.opts.FSConf.BundlePendingFile)
+	if err != nil {
+		return false, err
+	}
+	if len(pending) != 0 {
+		s.logger.Info("export state will be committed when bundle acks are imported", "pending_bundles", len(pending))
+		return false, nil
+	}
+	return true, nil
+}
+
 func dedupInclusionsAndMergeUsers(logger hclog.Logger, integrations []inconfig.IntegrationAgent) (res []inconfig.IntegrationAgent) {
 	res = dedupInclusions(integrations)
 	return mergeUsers(logger, res)
@@ -389,8 +541,11 @@ func (s *Exporter) execExport(integrations []inconfig.IntegrationAgent, reproces
 	if reprocessHistorical {
 		args = append(args, "--reprocess-historical=true")
 	}
+	// remove metrics left if service was stopped during export, they were not included in service metrics
+	os.Remove(s.opts.FSConf.ExportMetricsFile)
 	logFile, rerr = c.RunKeepLogFile(context.Background(), "export", messageID, &res, args...)
 	//s.logger.Debug("executed export command, got res", "v", fmt.Sprintf("%v", res))
+	s.mergeExportMetrics()
 
 	return
 }
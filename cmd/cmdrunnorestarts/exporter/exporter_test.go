@@ -1,10 +1,14 @@
 package exporter
 
 import (
+	"io/ioutil"
+	"os"
+	"path/filepath"
 	"testing"
 
 	hclog "github.com/hashicorp/go-hclog"
 	"github.com/pinpt/agent/cmd/cmdrunnorestarts/inconfig"
+	"github.com/pinpt/integration-sdk/agent"
 	"github.com/stretchr/testify/assert"
 )
 
@@ -178,3 +182,54 @@ func TestDedupInclusionsAndMergeUsers4(t *testing.T) {
 	assert.Equal(t, []string{"1", "2", "3"}, res)
 
 }
+
+func TestLastIntegrationsSaveLoad(t *testing.T) {
+	dir, err := ioutil.TempDir("", "agent-test")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(dir)
+	loc := filepath.Join(dir, "state", "last_export_integrations.json")
+
+	got, err := loadLastIntegrations(loc)
+	if err != nil {
+		t.Fatal(err)
+	}
+	assert.Nil(t, got)
+
+	in := agent.ExportRequestIntegrations{}
+	in.ID = "id1"
+	in.Name = "github"
+	in.Inclusions = []string{"1"}
+	err = saveLastIntegrations(loc, []agent.ExportRequestIntegrations{in})
+	if err != nil {
+		t.Fatal(err)
+	}
+	got, err = loadLastIntegrations(loc)
+	if err != nil {
+		t.Fatal(err)
+	}
+	assert.Equal(t, []agent.ExportRequestIntegrations{in}, got)
+}
+
+func TestIfNotRunning(t *testing.T) {
+	s := &Exporter{}
+	called := false
+	err := s.IfNotRunning(func() error {
+		called = true
+		return nil
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	assert.True(t, called)
+
+	s.setRunning(true)
+	called = false
+	err = s.IfNotRunning(func() error {
+		called = true
+		return nil
+	})
+	assert.Equal(t, ErrExportRunning, err)
+	assert.False(t, called)
+}
@@ -0,0 +1,160 @@
+package fsqueue
+
+import (
+	"bufio"
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"os"
+	"path/filepath"
+	"time"
+
+	"github.com/pinpt/agent/pkg/fs"
+)
+
+// Reasons for moving request to dead-letter file
+const (
+	DeadReasonMaxAttempts = "max attempts reached"
+	DeadReasonQueueFull   = "queue full"
+)
+
+// DeadEntry is the request that was removed from queue without successful processing
+type DeadEntry struct {
+	Entry
+	Reason string    `json:"reason"`
+	Date   time.Time `json:"date"`
+}
+
+// ReadDeadLetter returns all requests in dead-letter file, oldest first. Returns nil if file does not exist.
+func ReadDeadLetter(file string) (res []DeadEntry, _ error) {
+	f, err := os.Open(file)
+	if err != nil {
+		if os.IsNotExist(err) {
+			return nil, nil
+		}
+		return nil, err
+	}
+	defer f.Close()
+	sc := bufio.NewScanner(f)
+	sc.Buffer(nil, 10*1024*1024)
+	for sc.Scan() {
+		var e DeadEntry
+		err := json.Unmarshal(sc.Bytes(), &e)
+		if err != nil {
+			continue
+		}
+		res = append(res, e)
+	}
+	return res, sc.Err()
+}
+
+func writeDeadLetter(file string, entries []DeadEntry) error {
+	err := os.MkdirAll(filepath.Dir(file), 0777)
+	if err != nil {
+		return err
+	}
+	buf := &bytes.Buffer{}
+	for _, e := range entries {
+		b, err := json.Marshal(e)
+		if err != nil {
+			return err
+		}
+		buf.Write(b)
+		buf.WriteByte('\n')
+	}
+	return fs.WriteToTempAndRename(buf, file)
+}
+
+// appendDeadLetter adds entry to dead-letter file, keeping only the last max entries
+func appendDeadLetter(file string, e DeadEntry, max int) error {
+	entries, err := ReadDeadLetter(file)
+	if err != nil {
+		return err
+	}
+	entries = append(entries, e)
+	if len(entries) > max {
+		entries = entries[len(entries)-max:]
+	}
+	return writeDeadLetter(file, entries)
+}
+
+// ReadPending returns the requests waiting in queue journal in the order they would be processed if all are ready
+func ReadPending(file string) ([]Entry, error) {
+	entries, _, err := readJournal(file)
+	if err != nil {
+		return nil, err
+	}
+	return sortedByPriority(entries), nil
+}
+
+// Requeue moves request with passed id from dead-letter file back to the queue, resetting attempts. Should be called when queue is not running, since it is only read on start.
+func Requeue(opts Opts, id int) (newID int, _ error) {
+	opts = opts.withDefaults()
+	dead, err := ReadDeadLetter(opts.DeadLetterFile)
+	if err != nil {
+		return 0, err
+	}
+	var found *DeadEntry
+	var rest []DeadEntry
+	for i, e := range dead {
+		if e.ID == id {
+			found = &dead[i]
+			continue
+		}
+		rest = append(rest, e)
+	}
+	if found == nil {
+		return 0, fmt.Errorf("request not found in dead-letter file: %v", id)
+	}
+
+	entries, _, err := readJournal(opts.File)
+	if err != nil {
+		return 0, err
+	}
+	// append without compacting, so that the file is not replaced if the queue is running
+	err = os.MkdirAll(filepath.Dir(opts.File), 0777)
+	if err != nil {
+		return 0, err
+	}
+	f, err := os.OpenFile(opts.File, os.O_APPEND|os.O_CREATE|os.O_WRONLY, 0644)
+	if err != nil {
+		return 0, err
+	}
+	j := &journal{file: opts.File, f: f}
+	defer j.Close()
+	// the last line could be incomplete if queue was killed while writing, start a new one. Empty lines are skipped when reading.
+	_, err = f.Write([]byte("\n"))
+	if err != nil {
+		return 0, err
+	}
+
+	e := found.Entry
+	e.ID = maxID(entries, dead) + 1
+	e.Attempts = 0
+	e.LastError = ""
+	e.NextAttempt = time.Time{}
+	// add to the queue first, if interrupted the request would be in both files instead of being lost
+	err = j.put(e)
+	if err != nil {
+		return 0, err
+	}
+	err = writeDeadLetter(opts.DeadLetterFile, rest)
+	if err != nil {
+		return 0, err
+	}
+	return e.ID, nil
+}
+
+func maxID(entries map[int]Entry, dead []DeadEntry) (res int) {
+	for id := range entries {
+		if id > res {
+			res = id
+		}
+	}
+	for _, e := range dead {
+		if e.ID > res {
+			res = e.ID
+		}
+	}
+	return
+}
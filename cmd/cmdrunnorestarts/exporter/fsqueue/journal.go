@@ -0,0 +1,206 @@
+package fsqueue
+
+import (
+	"bufio"
+	"bytes"
+	"encoding/json"
+	"io"
+	"os"
+	"path/filepath"
+	"sort"
+	"time"
+
+	hclog "github.com/hashicorp/go-hclog"
+	"github.com/pinpt/agent/pkg/fs"
+)
+
+// Entry is the request stored in the queue
+type Entry struct {
+	ID       int       `json:"id"`
+	Priority Priority  `json:"priority"`
+	Data     Data      `json:"data"`
+	Added    time.Time `json:"added"`
+	// Attempts is the number of times request was forwarded for processing. Incremented before processing, so it also counts attempts interrupted by agent restart.
+	Attempts  int    `json:"attempts"`
+	LastError string `json:"last_error,omitempty"`
+	// NextAttempt is the time after which failed request could be retried
+	NextAttempt time.Time `json:"next_attempt"`
+}
+
+const (
+	opPut    = "put"
+	opDelete = "delete"
+)
+
+// journalRecord is one line in the journal file. Put records contain the full entry state and replace the previous one with the same id.
+type journalRecord struct {
+	Op    string `json:"op"`
+	Entry Entry  `json:"entry"`
+}
+
+// journal is the append-only file with queue changes. Every write is synced, so that state is kept if process is killed. It is compacted by rewriting current state when it grows too large.
+type journal struct {
+	file  string
+	f     *os.File
+	lines int
+}
+
+func openJournal(logger hclog.Logger, file string) (*journal, map[int]Entry, error) {
+	entries, skipped, err := readJournal(file)
+	if err != nil {
+		return nil, nil, err
+	}
+	if skipped != 0 {
+		logger.Warn("skipped queue journal lines that could not be read", "file", file, "lines", skipped)
+	}
+	s := &journal{}
+	s.file = file
+	// compact on open, this also removes incomplete last line if process was killed while writing
+	err = s.compact(entries)
+	if err != nil {
+		return nil, nil, err
+	}
+	return s, entries, nil
+}
+
+// maxJournalLine is the max length of journal record. Longer lines are skipped when reading.
+const maxJournalLine = 64 << 20
+
+// readJournal returns the entries pending in journal and the number of skipped lines. Lines that could not be parsed, which could happen if write was interrupted, and lines longer than maxJournalLine are skipped.
+func readJournal(file string) (_ map[int]Entry, skipped int, _ error) {
+	res := map[int]Entry{}
+	f, err := os.Open(file)
+	if err != nil {
+		if os.IsNotExist(err) {
+			return res, 0, nil
+		}
+		return nil, 0, err
+	}
+	defer f.Close()
+	r := bufio.NewReader(f)
+	for {
+		line, tooLong, rerr := readLine(r, maxJournalLine)
+		if tooLong {
+			skipped++
+		} else if len(line) != 0 {
+			var rec journalRecord
+			err := json.Unmarshal(line, &rec)
+			if err != nil {
+				skipped++
+			} else {
+				switch rec.Op {
+				case opPut:
+					res[rec.Entry.ID] = rec.Entry
+				case opDelete:
+					delete(res, rec.Entry.ID)
+				}
+			}
+		}
+		if rerr == io.EOF {
+			return res, skipped, nil
+		}
+		if rerr != nil {
+			return nil, 0, rerr
+		}
+	}
+}
+
+// readLine returns the next line without newline. If the line is longer than max, it is discarded and tooLong is true.
+func readLine(r *bufio.Reader, max int) (line []byte, tooLong bool, _ error) {
+	for {
+		frag, err := r.ReadSlice('\n')
+		if !tooLong {
+			if len(line)+len(frag) > max+1 {
+				tooLong = true
+				line = nil
+			} else {
+				line = append(line, frag...)
+			}
+		}
+		if err == bufio.ErrBufferFull {
+			continue
+		}
+		return bytes.TrimSuffix(line, []byte("\n")), tooLong, err
+	}
+}
+
+func (s *journal) put(e Entry) error {
+	return s.write(journalRecord{Op: opPut, Entry: e})
+}
+
+func (s *journal) delete(id int) error {
+	return s.write(journalRecord{Op: opDelete, Entry: Entry{ID: id}})
+}
+
+func (s *journal) write(rec journalRecord) error {
+	b, err := json.Marshal(rec)
+	if err != nil {
+		return err
+	}
+	b = append(b, '\n')
+	_, err = s.f.Write(b)
+	if err != nil {
+		return err
+	}
+	s.lines++
+	return s.f.Sync()
+}
+
+// compact replaces journal with one put record per entry
+func (s *journal) compact(entries map[int]Entry) error {
+	if s.f != nil {
+		err := s.f.Close()
+		if err != nil {
+			return err
+		}
+		s.f = nil
+	}
+	err := os.MkdirAll(filepath.Dir(s.file), 0777)
+	if err != nil {
+		return err
+	}
+	buf := &bytes.Buffer{}
+	for _, e := range sortedByID(entries) {
+		b, err := json.Marshal(journalRecord{Op: opPut, Entry: e})
+		if err != nil {
+			return err
+		}
+		buf.Write(b)
+		buf.WriteByte('\n')
+	}
+	err = fs.WriteToTempAndRename(buf, s.file)
+	if err != nil {
+		return err
+	}
+	s.lines = len(entries)
+	s.f, err = os.OpenFile(s.file, os.O_APPEND|os.O_WRONLY, 0644)
+	return err
+}
+
+func (s *journal) Close() error {
+	if s.f == nil {
+		return nil
+	}
+	err := s.f.Close()
+	s.f = nil
+	return err
+}
+
+func sortedByID(entries map[int]Entry) (res []Entry) {
+	for _, e := range entries {
+		res = append(res, e)
+	}
+	sort.Slice(res, func(i, j int) bool {
+		return res[i].ID < res[j].ID
+	})
+	return
+}
+
+// sortedByPriority returns entries in the order they would be processed if all are ready
+func sortedByPriority(entries map[int]Entry) []Entry {
+	res := sortedByID(entries)
+	sort.SliceStable(res, func(i, j int) bool {
+		return res[i].Priority > res[j].Priority
+	})
+	return res
+}
@@ -1,46 +1,140 @@
 package fsqueue
 
 import (
-	"bytes"
 	"context"
 	"encoding/json"
+	"errors"
 	"io/ioutil"
 	"os"
-	"path/filepath"
-	"sync"
+	"sort"
+	"time"
 
 	hclog "github.com/hashicorp/go-hclog"
-	"github.com/pinpt/agent/pkg/fs"
 )
 
+// Request is forwarded for processing. Exactly one of Done or Failed must be sent to when processing finishes.
 type Request struct {
 	Data Data
+	// Done marks request as processed and removes it from queue
 	Done chan (struct{})
+	// Failed schedules request for retry with backoff, or moves it to dead-letter file if it failed Opts.MaxAttempts times
+	Failed chan error
+	// Attempt is the number of this attempt, starting from 1
+	Attempt int
+	// LastAttempt is true if sending to Failed will move the request to dead-letter file instead of retrying
+	LastAttempt bool
+
+	id int
 }
 
 type Data map[string]interface{}
 
+// Priority of the request, requests with higher priority are processed first
+type Priority int
+
+const (
+	// PriorityNormal is used for scheduled incremental exports
+	PriorityNormal Priority = 0
+	// PriorityHigh is used for historical exports
+	PriorityHigh Priority = 1
+)
+
+// Opts are options for New call
+type Opts struct {
+	Logger hclog.Logger
+	// File is the location of queue journal
+	File string
+	// DeadLetterFile stores requests that failed MaxAttempts times or were dropped because queue was full. Defaults to File + ".dead".
+	DeadLetterFile string
+	// LegacyFile is the location of the queue stored in the old format (json map of all pending requests). Optional. Requests from it are added to the queue on start and the file is deleted.
+	LegacyFile string
+
+	// Priority returns priority for the request. Optional, PriorityNormal is used by default.
+	Priority func(Data) Priority
+
+	// MaxAttempts is the number of times request is processed before moving it to dead-letter file. Default 5.
+	MaxAttempts int
+	// MinBackoff is the delay before the first retry, doubled on every next failure. Default 1 minute.
+	MinBackoff time.Duration
+	// MaxBackoff is the max delay between retries. Default 1 hour.
+	MaxBackoff time.Duration
+	// MaxPending is the max number of requests in queue. When exceeded, the oldest request with the lowest priority is moved to dead-letter file. Default 100.
+	MaxPending int
+	// MaxDead is the max number of requests kept in dead-letter file. Default 100.
+	MaxDead int
+	// CompactAfter is the number of records in journal after which it is compacted. Default 100.
+	CompactAfter int
+}
+
+func (s Opts) withDefaults() Opts {
+	if s.DeadLetterFile == "" {
+		s.DeadLetterFile = s.File + ".dead"
+	}
+	if s.Priority == nil {
+		s.Priority = func(Data) Priority {
+			return PriorityNormal
+		}
+	}
+	if s.MaxAttempts == 0 {
+		s.MaxAttempts = 5
+	}
+	if s.MinBackoff == 0 {
+		s.MinBackoff = time.Minute
+	}
+	if s.MaxBackoff == 0 {
+		s.MaxBackoff = time.Hour
+	}
+	if s.MaxPending == 0 {
+		s.MaxPending = 100
+	}
+	if s.MaxDead == 0 {
+		s.MaxDead = 100
+	}
+	if s.CompactAfter == 0 {
+		s.CompactAfter = 100
+	}
+	return s
+}
+
+// Queue stores requests on disk and forwards them one by one for processing, highest priority first. Crash-safe, all changes are appended to journal file and synced before continuing.
 type Queue struct {
 	Input           chan Data
 	forwardRequests chan Request
 
+	opts   Opts
 	logger hclog.Logger
-	file   string
 
-	pending map[int]Data
-	idgen   int
-	mu      sync.Mutex
+	journal  *journal
+	pending  map[int]Entry
+	idgen    int
+	inflight *Request
 }
 
-func New(logger hclog.Logger, file string) (_ *Queue, forwardRequests chan Request, rerr error) {
+// New creates the queue and reads pending requests from disk. Returns the channel on which requests are forwarded for processing.
+func New(opts Opts) (_ *Queue, forwardRequests chan Request, rerr error) {
+	if opts.Logger == nil || opts.File == "" {
+		return nil, nil, errors.New("provide Logger and File")
+	}
 	s := &Queue{}
-	s.logger = logger
-	s.file = file
+	s.opts = opts.withDefaults()
+	s.logger = opts.Logger
 	s.Input = make(chan Data)
-	s.forwardRequests = make(chan Request, 10000)
-	s.pending = map[int]Data{}
+	s.forwardRequests = make(chan Request)
 
-	err := s.readData()
+	var err error
+	s.journal, s.pending, err = openJournal(s.logger, s.opts.File)
+	if err != nil {
+		rerr = err
+		return
+	}
+	dead, err := ReadDeadLetter(s.opts.DeadLetterFile)
+	if err != nil {
+		rerr = err
+		return
+	}
+	s.idgen = maxID(s.pending, dead)
+
+	err = s.migrateLegacy()
 	if err != nil {
 		rerr = err
 		return
@@ -49,88 +143,238 @@ func New(logger hclog.Logger, file string) (_ *Queue, forwardRequests chan Reque
 	return s, s.forwardRequests, nil
 }
 
-func (s *Queue) readData() error {
-	b, err := ioutil.ReadFile(s.file)
-	if err != nil {
-		if !os.IsNotExist(err) {
-			return err
-		}
+func (s *Queue) migrateLegacy() error {
+	if s.opts.LegacyFile == "" {
 		return nil
 	}
-	err = json.Unmarshal(b, &s.pending)
+	b, err := ioutil.ReadFile(s.opts.LegacyFile)
 	if err != nil {
+		if os.IsNotExist(err) {
+			return nil
+		}
 		return err
 	}
-	for id := range s.pending {
-		if id > s.idgen {
-			s.idgen = id
+	var legacy map[int]Data
+	err = json.Unmarshal(b, &legacy)
+	if err != nil {
+		s.logger.Error("could not read export queue in old format, ignoring it", "err", err)
+	}
+	for _, id := range sortedIDs(legacy) {
+		err := s.add(legacy[id])
+		if err != nil {
+			return err
 		}
 	}
-	return nil
+	s.logger.Info("migrated export queue to new format", "requests", len(legacy))
+	return os.Remove(s.opts.LegacyFile)
 }
 
-func (s *Queue) addData(data Data) (id int, _ error) {
-	s.mu.Lock()
-	defer s.mu.Unlock()
-	s.idgen++
-	id = s.idgen
-	s.pending[id] = data
-	return id, s.save()
+func sortedIDs(m map[int]Data) (res []int) {
+	for id := range m {
+		res = append(res, id)
+	}
+	sort.Ints(res)
+	return
 }
 
-func (s *Queue) dataDone(id int) error {
-	s.mu.Lock()
-	defer s.mu.Unlock()
+func (s *Queue) add(data Data) error {
+	s.idgen++
+	e := Entry{}
+	e.ID = s.idgen
+	e.Priority = s.opts.Priority(data)
+	e.Data = data
+	e.Added = time.Now()
+	err := s.put(e)
+	if err != nil {
+		return err
+	}
+	if len(s.pending) > s.opts.MaxPending {
+		return s.dropLowest()
+	}
+	return nil
+}
 
-	delete(s.pending, id)
-	return s.save()
+// dropLowest moves the oldest request with the lowest priority to dead-letter file, skipping the one in progress
+func (s *Queue) dropLowest() error {
+	sorted := sortedByPriority(s.pending)
+	lowest := sorted[len(sorted)-1].Priority
+	for _, e := range sorted {
+		if e.Priority != lowest || (s.inflight != nil && s.inflight.id == e.ID) {
+			continue
+		}
+		s.logger.Warn("export queue is full, dropping request", "id", e.ID, "max_pending", s.opts.MaxPending)
+		return s.moveToDead(e, DeadReasonQueueFull)
+	}
+	return nil
 }
 
-func (s *Queue) save() error {
-	b, err := json.Marshal(s.pending)
+func (s *Queue) put(e Entry) error {
+	s.pending[e.ID] = e
+	err := s.journal.put(e)
 	if err != nil {
 		return err
 	}
-	err = os.MkdirAll(filepath.Dir(s.file), 0777)
+	return s.compactIfNeeded()
+}
+
+func (s *Queue) remove(id int) error {
+	delete(s.pending, id)
+	err := s.journal.delete(id)
 	if err != nil {
 		return err
 	}
-	return fs.WriteToTempAndRename(bytes.NewReader(b), s.file)
+	return s.compactIfNeeded()
 }
 
-func (s *Queue) Run(ctx context.Context) error {
+func (s *Queue) compactIfNeeded() error {
+	if s.journal.lines < s.opts.CompactAfter+len(s.pending) {
+		return nil
+	}
+	return s.journal.compact(s.pending)
+}
 
-	send := func(ctx context.Context, id int, data Data) {
-		done := make(chan struct{})
-		s.forwardRequests <- Request{Done: done, Data: data}
-		go func() {
-			select {
-			case <-ctx.Done():
-			case <-done:
-				err := s.dataDone(id)
-				if err != nil {
-					s.logger.Error("could not mark export as done in fs", "err", err)
-				}
+func (s *Queue) moveToDead(e Entry, reason string) error {
+	de := DeadEntry{}
+	de.Entry = e
+	de.Reason = reason
+	de.Date = time.Now()
+	// write to dead-letter file first, so that request is not lost if interrupted
+	err := appendDeadLetter(s.opts.DeadLetterFile, de, s.opts.MaxDead)
+	if err != nil {
+		return err
+	}
+	return s.remove(e.ID)
+}
+
+// next returns the request to process. If no request is ready, returns time to wait for the next retry. ok is false if queue is empty.
+func (s *Queue) next() (res Entry, wait time.Duration, ok bool, _ error) {
+	now := time.Now()
+	for _, e := range sortedByPriority(s.pending) {
+		if e.Attempts >= s.opts.MaxAttempts {
+			s.logger.Error("export request failed too many times, moving to dead-letter file", "id", e.ID, "attempts", e.Attempts, "last_error", e.LastError)
+			err := s.moveToDead(e, DeadReasonMaxAttempts)
+			if err != nil {
+				return res, 0, false, err
 			}
-		}()
+			continue
+		}
+		if !e.NextAttempt.After(now) {
+			return e, 0, true, nil
+		}
+		w := e.NextAttempt.Sub(now)
+		if !ok || w < wait {
+			wait = w
+		}
+		ok = true
 	}
+	return
+}
 
-	s.mu.Lock()
-	for id, data := range s.pending {
-		send(ctx, id, data)
+func (s *Queue) backoff(attempts int) time.Duration {
+	res := s.opts.MinBackoff
+	for i := 1; i < attempts && res < s.opts.MaxBackoff; i++ {
+		res *= 2
 	}
-	s.mu.Unlock()
+	if res > s.opts.MaxBackoff {
+		res = s.opts.MaxBackoff
+	}
+	return res
+}
+
+func (s *Queue) failed(id int, rerr error) error {
+	e, ok := s.pending[id]
+	if !ok {
+		return nil
+	}
+	e.LastError = rerr.Error()
+	e.NextAttempt = time.Now().Add(s.backoff(e.Attempts))
+	s.logger.Warn("export request failed, will retry", "id", e.ID, "attempts", e.Attempts, "next_attempt", e.NextAttempt, "err", rerr)
+	return s.put(e)
+}
+
+// Run forwards pending requests and accepts new ones from Input until ctx is cancelled. Only one request is processed at a time.
+func (s *Queue) Run(ctx context.Context) error {
+	defer s.journal.Close()
 
 	for {
+		var forward chan Request
+		var req Request
+		var retry <-chan time.Time
+		var done chan struct{}
+		var failed chan error
+
+		if s.inflight == nil {
+			e, wait, ok, err := s.next()
+			if err != nil {
+				return err
+			}
+			if ok && wait == 0 {
+				req = Request{
+					Data:        e.Data,
+					Done:        make(chan struct{}, 1),
+					Failed:      make(chan error, 1),
+					Attempt:     e.Attempts + 1,
+					LastAttempt: e.Attempts+1 >= s.opts.MaxAttempts,
+					id:          e.ID,
+				}
+				forward = s.forwardRequests
+			} else if ok {
+				retry = time.After(wait)
+			}
+		} else {
+			done = s.inflight.Done
+			failed = s.inflight.Failed
+		}
+
 		select {
 		case data := <-s.Input:
-			id, err := s.addData(data)
+			err := s.add(data)
+			if err != nil {
+				return err
+			}
+		case forward <- req:
+			s.inflight = &req
+			e := s.pending[req.id]
+			e.Attempts = req.Attempt
+			err := s.put(e)
+			if err != nil {
+				return err
+			}
+		case <-done:
+			err := s.inflightDone(nil)
 			if err != nil {
 				return err
 			}
-			send(ctx, id, data)
+		case rerr := <-failed:
+			err := s.inflightDone(rerr)
+			if err != nil {
+				return err
+			}
+		case <-retry:
 		case <-ctx.Done():
+			// save the result if processing finished before cancel
+			select {
+			case <-done:
+				return s.inflightDone(nil)
+			case rerr := <-failed:
+				return s.inflightDone(rerr)
+			default:
+			}
 			return nil
 		}
 	}
 }
+
+func (s *Queue) inflightDone(rerr error) error {
+	id := s.inflight.id
+	s.inflight = nil
+	if rerr != nil {
+		return s.failed(id, rerr)
+	}
+	err := s.remove(id)
+	if err != nil {
+		s.logger.Error("could not mark export as done in fs", "err", err)
+		return err
+	}
+	return nil
+}
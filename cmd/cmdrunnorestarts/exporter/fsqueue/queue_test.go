@@ -1,11 +1,16 @@
 package fsqueue
 
 import (
+	"bufio"
+	"bytes"
 	"context"
+	"errors"
 	"fmt"
+	"io"
 	"io/ioutil"
 	"os"
 	"path/filepath"
+	"strings"
 	"testing"
 	"time"
 
@@ -25,7 +30,7 @@ func TestQueueRunCancel(t *testing.T) {
 	}
 	defer os.RemoveAll(dir)
 	file := filepath.Join(dir, "db")
-	q, _, err := New(testLogger(), file)
+	q, _, err := New(Opts{Logger: testLogger(), File: file})
 	if err != nil {
 		t.Fatal(err)
 	}
@@ -52,7 +57,7 @@ func TestQueuePassData(t *testing.T) {
 	}
 	defer os.RemoveAll(dir)
 	file := filepath.Join(dir, "db")
-	q, forward, err := New(testLogger(), file)
+	q, forward, err := New(Opts{Logger: testLogger(), File: file})
 	if err != nil {
 		t.Fatal(err)
 	}
@@ -98,7 +103,7 @@ func TestQueueStoreOnFS(t *testing.T) {
 	assert := assert.New(t)
 
 	runQueue := func(cb func(q *Queue, f chan Request)) {
-		q, forward, err := New(testLogger(), file)
+		q, forward, err := New(Opts{Logger: testLogger(), File: file})
 		if err != nil {
 			t.Fatal(err)
 		}
@@ -145,3 +150,259 @@ func TestQueueStoreOnFS(t *testing.T) {
 		req.Done <- struct{}{}
 	})
 }
+
+func testDir(t *testing.T) (dir string, cleanup func()) {
+	dir, err := ioutil.TempDir("", "test-")
+	if err != nil {
+		t.Fatal(err)
+	}
+	return dir, func() {
+		os.RemoveAll(dir)
+	}
+}
+
+// startQueue runs the queue until returned stop func is called
+func startQueue(t *testing.T, opts Opts) (q *Queue, forward chan Request, stop func()) {
+	q, forward, err := New(opts)
+	if err != nil {
+		t.Fatal(err)
+	}
+	ctx, cancel := context.WithCancel(context.Background())
+	exited := make(chan bool)
+	go func() {
+		err := q.Run(ctx)
+		if err != nil {
+			t.Error(err)
+		}
+		exited <- true
+	}()
+	return q, forward, func() {
+		cancel()
+		<-exited
+	}
+}
+
+func TestQueuePriority(t *testing.T) {
+	dir, cleanup := testDir(t)
+	defer cleanup()
+	opts := Opts{Logger: testLogger(), File: filepath.Join(dir, "db")}
+	opts.Priority = func(data Data) Priority {
+		if data["historical"] == true {
+			return PriorityHigh
+		}
+		return PriorityNormal
+	}
+
+	// forward is unbuffered, so both requests are queued before the first one is read
+	q, forward, stop := startQueue(t, opts)
+	q.Input <- Data{"k": "1"}
+	q.Input <- Data{"k": "2"}
+	q.Input <- Data{"k": "3", "historical": true}
+
+	assert := assert.New(t)
+	var got []interface{}
+	for i := 0; i < 3; i++ {
+		req := <-forward
+		got = append(got, req.Data["k"])
+		req.Done <- struct{}{}
+	}
+	stop()
+	assert.Equal([]interface{}{"3", "1", "2"}, got)
+}
+
+func TestQueueRetryAndDeadLetter(t *testing.T) {
+	dir, cleanup := testDir(t)
+	defer cleanup()
+	opts := Opts{Logger: testLogger(), File: filepath.Join(dir, "db")}
+	opts.MaxAttempts = 2
+	opts.MinBackoff = time.Millisecond
+
+	q, forward, stop := startQueue(t, opts)
+	q.Input <- Data{"k1": "v1"}
+
+	assert := assert.New(t)
+	req := <-forward
+	assert.Equal(1, req.Attempt)
+	assert.False(req.LastAttempt)
+	req.Failed <- errors.New("e1")
+	req = <-forward
+	assert.Equal(2, req.Attempt)
+	assert.True(req.LastAttempt)
+	req.Failed <- errors.New("e2")
+	// push another request through, to know that the failed one was processed
+	q.Input <- Data{"k2": "v2"}
+	req = <-forward
+	assert.Equal(Data{"k2": "v2"}, req.Data)
+	req.Done <- struct{}{}
+	stop()
+
+	pending, err := ReadPending(opts.File)
+	if err != nil {
+		t.Fatal(err)
+	}
+	assert.Len(pending, 0)
+
+	dead, err := ReadDeadLetter(opts.File + ".dead")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if assert.Len(dead, 1) {
+		assert.Equal(DeadReasonMaxAttempts, dead[0].Reason)
+		assert.Equal("e2", dead[0].LastError)
+		assert.Equal(Data{"k1": "v1"}, dead[0].Data)
+	}
+
+	_, err = Requeue(opts, dead[0].ID)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	dead, err = ReadDeadLetter(opts.File + ".dead")
+	if err != nil {
+		t.Fatal(err)
+	}
+	assert.Len(dead, 0)
+
+	_, forward, stop = startQueue(t, opts)
+	req = <-forward
+	assert.Equal(Data{"k1": "v1"}, req.Data)
+	assert.Equal(1, req.Attempt)
+	req.Done <- struct{}{}
+	stop()
+}
+
+func TestQueueMaxPending(t *testing.T) {
+	dir, cleanup := testDir(t)
+	defer cleanup()
+	opts := Opts{Logger: testLogger(), File: filepath.Join(dir, "db")}
+	opts.MaxPending = 2
+
+	q, forward, stop := startQueue(t, opts)
+	q.Input <- Data{"k": "1"}
+	q.Input <- Data{"k": "2"}
+	q.Input <- Data{"k": "3"}
+
+	assert := assert.New(t)
+	req := <-forward
+	assert.Equal("2", req.Data["k"])
+	req.Done <- struct{}{}
+	stop()
+
+	dead, err := ReadDeadLetter(opts.File + ".dead")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if assert.Len(dead, 1) {
+		assert.Equal(DeadReasonQueueFull, dead[0].Reason)
+		assert.Equal("1", dead[0].Data["k"])
+	}
+}
+
+func TestQueueJournalIncompleteLine(t *testing.T) {
+	dir, cleanup := testDir(t)
+	defer cleanup()
+	opts := Opts{Logger: testLogger(), File: filepath.Join(dir, "db")}
+
+	// simulates process killed while writing the second record
+	err := ioutil.WriteFile(opts.File, []byte(`{"op":"put","entry":{"id":1,"data":{"k1":"v1"}}}`+"\n"+`{"op":"put","entry":{"id":2,"da`), 0644)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	q, forward, stop := startQueue(t, opts)
+	q.Input <- Data{"k2": "v2"}
+
+	assert := assert.New(t)
+	req := <-forward
+	assert.Equal(Data{"k1": "v1"}, req.Data)
+	req.Done <- struct{}{}
+	req = <-forward
+	assert.Equal(Data{"k2": "v2"}, req.Data)
+	stop()
+
+	pending, err := ReadPending(opts.File)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if assert.Len(pending, 1) {
+		assert.Equal(2, pending[0].ID)
+		assert.Equal(1, pending[0].Attempts)
+	}
+}
+
+func TestJournalReadLineTooLong(t *testing.T) {
+	assert := assert.New(t)
+	r := bufio.NewReaderSize(bytes.NewReader([]byte("short\n"+strings.Repeat("x", 100)+"\nlast")), 16)
+
+	line, tooLong, err := readLine(r, 20)
+	assert.NoError(err)
+	assert.False(tooLong)
+	assert.Equal("short", string(line))
+
+	line, tooLong, err = readLine(r, 20)
+	assert.NoError(err)
+	assert.True(tooLong)
+	assert.Nil(line)
+
+	line, tooLong, err = readLine(r, 20)
+	assert.Equal(io.EOF, err)
+	assert.False(tooLong)
+	assert.Equal("last", string(line))
+}
+
+func TestQueueCompaction(t *testing.T) {
+	dir, cleanup := testDir(t)
+	defer cleanup()
+	opts := Opts{Logger: testLogger(), File: filepath.Join(dir, "db")}
+	opts.CompactAfter = 5
+
+	q, forward, stop := startQueue(t, opts)
+	for i := 0; i < 10; i++ {
+		q.Input <- Data{"i": i}
+		req := <-forward
+		req.Done <- struct{}{}
+	}
+	q.Input <- Data{"k": "last"}
+	stop()
+
+	b, err := ioutil.ReadFile(opts.File)
+	if err != nil {
+		t.Fatal(err)
+	}
+	lines := bytes.Count(b, []byte("\n"))
+	assert := assert.New(t)
+	assert.True(lines <= 6, "journal was not compacted, lines: %v", lines)
+
+	pending, err := ReadPending(opts.File)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if assert.Len(pending, 1) {
+		assert.Equal(Data{"k": "last"}, pending[0].Data)
+	}
+}
+
+func TestQueueMigrateLegacy(t *testing.T) {
+	dir, cleanup := testDir(t)
+	defer cleanup()
+	opts := Opts{Logger: testLogger(), File: filepath.Join(dir, "db")}
+	opts.LegacyFile = filepath.Join(dir, "legacy.json")
+
+	err := ioutil.WriteFile(opts.LegacyFile, []byte(`{"3":{"k":"3"},"1":{"k":"1"}}`), 0644)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	_, forward, stop := startQueue(t, opts)
+	assert := assert.New(t)
+	req := <-forward
+	assert.Equal("1", req.Data["k"])
+	req.Done <- struct{}{}
+	req = <-forward
+	assert.Equal("3", req.Data["k"])
+	req.Done <- struct{}{}
+	stop()
+
+	_, err = os.Stat(opts.LegacyFile)
+	assert.True(os.IsNotExist(err))
+}
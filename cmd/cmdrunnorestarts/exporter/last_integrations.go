@@ -0,0 +1,54 @@
+package exporter
+
+import (
+	"bytes"
+	"encoding/json"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+
+	"github.com/pinpt/agent/pkg/fs"
+	"github.com/pinpt/integration-sdk/agent"
+)
+
+// loadLastIntegrations reads integrations of the last export request saved by saveLastIntegrations. Returns nil if no requests were received yet.
+func loadLastIntegrations(loc string) (res []agent.ExportRequestIntegrations, _ error) {
+	b, err := ioutil.ReadFile(loc)
+	if os.IsNotExist(err) {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+	err = json.Unmarshal(b, &res)
+	return res, err
+}
+
+// saveLastIntegrations saves integrations of the export request received from the server. Auth is saved encrypted as received.
+func saveLastIntegrations(loc string, ints []agent.ExportRequestIntegrations) error {
+	b, err := json.Marshal(ints)
+	if err != nil {
+		return err
+	}
+	err = os.MkdirAll(filepath.Dir(loc), 0755)
+	if err != nil {
+		return err
+	}
+	return fs.WriteToTempAndRename(bytes.NewReader(b), loc)
+}
+
+func (s *Exporter) setLastIntegrations(ints []agent.ExportRequestIntegrations) {
+	s.statusMu.Lock()
+	s.lastIntegrations = ints
+	s.statusMu.Unlock()
+	err := saveLastIntegrations(s.opts.FSConf.LastExportIntegrationsFile, ints)
+	if err != nil {
+		s.logger.Error("could not save integrations of the last export request", "err", err)
+	}
+}
+
+func (s *Exporter) getLastIntegrations() []agent.ExportRequestIntegrations {
+	s.statusMu.Lock()
+	defer s.statusMu.Unlock()
+	return s.lastIntegrations
+}
@@ -0,0 +1,83 @@
+package exporter
+
+import (
+	"os"
+
+	"github.com/pinpt/agent/cmd/cmdrunnorestarts/exporter/fsqueue"
+	"github.com/pinpt/agent/pkg/metrics"
+	"github.com/pinpt/integration-sdk/agent"
+)
+
+var (
+	exportsTotal   = metrics.NewCounter("pinpoint_agent_exports_total", "Number of processed export requests by outcome: success, error or cancelled")
+	exportDuration = metrics.NewHistogram("pinpoint_agent_export_duration_seconds", "Duration of integration export by outcome", metrics.LongBuckets)
+	queueDepth     = metrics.NewGauge("pinpoint_agent_export_queue_depth", "Number of export requests in queue, including the one in progress")
+)
+
+func (s *Exporter) recordIntegrationMetrics(data *agent.ExportRequest, res exportResult) {
+	names := map[string]string{}
+	for _, in := range data.Integrations {
+		names[in.ID] = in.Name
+	}
+	for id, in := range res.Integrations {
+		outcome := "success"
+		if in.Error != "" {
+			outcome = "error"
+		}
+		name := names[id]
+		if name == "" {
+			// extra integrations from config are not in request
+			name = id
+		}
+		exportDuration.Observe(in.Duration.Seconds(), metrics.Labels{"integration": name, "outcome": outcome})
+	}
+}
+
+// mergeExportMetrics adds the metrics written by export command to service metrics
+func (s *Exporter) mergeExportMetrics() {
+	s.metricsMu.Lock()
+	defer s.metricsMu.Unlock()
+	loc := s.opts.FSConf.ExportMetricsFile
+	data, err := metrics.ReadFile(loc)
+	if err != nil {
+		s.logger.Error("could not read export metrics", "err", err)
+	} else {
+		err = metrics.Default.Merge(data, nil)
+		if err != nil {
+			s.logger.Error("could not merge export metrics", "err", err)
+		}
+	}
+	err = os.Remove(loc)
+	if err != nil && !os.IsNotExist(err) {
+		s.logger.Error("could not remove export metrics", "err", err)
+	}
+}
+
+// Metrics returns the metrics of the service together with the running export
+func (s *Exporter) Metrics() (metrics.Snapshot, error) {
+	pending, err := fsqueue.ReadPending(s.opts.FSConf.ExportQueueFile)
+	if err != nil {
+		return nil, err
+	}
+	queueDepth.Set(float64(len(pending)), nil)
+
+	s.metricsMu.Lock()
+	defer s.metricsMu.Unlock()
+	if !s.IsRunning() {
+		return metrics.Default.Snapshot(), nil
+	}
+	running, err := metrics.ReadFile(s.opts.FSConf.ExportMetricsFile)
+	if err != nil {
+		return nil, err
+	}
+	res := metrics.NewRegistry()
+	err = res.Merge(metrics.Default.Snapshot(), nil)
+	if err != nil {
+		return nil, err
+	}
+	err = res.Merge(running, nil)
+	if err != nil {
+		return nil, err
+	}
+	return res.Snapshot(), nil
+}
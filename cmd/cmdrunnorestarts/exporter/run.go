@@ -15,12 +15,24 @@ func (s *Exporter) Run() {
 		for req := range s.queueRequestForwarder {
 			req2 := Request{}
 			err := structmarshal.MapToStruct(req.Data, &req2)
-			if err != nil {
+			if err != nil || req2.Data == nil {
 				s.logger.Error("could not unmarshal export request from map", "err", err)
+				req.Done <- struct{}{}
+				continue
 			}
 			s.setRunning(true)
-			s.export(req2.Data, req2.MessageID)
+			s.setCurrent(req2.Data, req.Attempt)
+			if !req2.Local {
+				s.setLastIntegrations(req2.Data.Integrations)
+			}
+			s.logger.Info("processing export request from queue", "attempt", req.Attempt, "local", req2.Local)
+			err = s.export(req2.Data, req2.MessageID, req.LastAttempt, req2.Local)
+			s.setCurrentDone()
 			s.setRunning(false)
+			if err != nil {
+				req.Failed <- err
+				continue
+			}
 			req.Done <- struct{}{}
 		}
 	}()
@@ -37,6 +49,9 @@ func (s *Exporter) Run() {
 
 		handleError := func(err error) {
 			s.logger.Error("export finished with error", "err", err)
+			if req.Local {
+				return
+			}
 			err2 := s.sendFailedEvent(data.JobID, time.Now(), time.Now(), err)
 			if err2 != nil {
 				s.logger.Error("error sending failed export event", "sending_err", err2, "export_err", err)
@@ -27,9 +27,23 @@ func (s *Exporter) backupRestoreStateDir() error {
 		return err
 	}
 
-	if backupExists {
+	// bundles waiting for ack have the state saved when the last one was created, continue from it so that bundle data is not exported again
+	snapshotExists, err := fs.Exists(locs.BundleSnapshot)
+	if err != nil {
+		return err
+	}
 
-		s.logger.Info("previous export/upload did not finish since we found a backup dir, restoring previous state and trying again")
+	if backupExists || snapshotExists {
+
+		lastProcessedFrom := locs.LastProcessedFileBackup
+		ripsrcCheckpointsFrom := locs.RipsrcCheckpointsBackup
+		if snapshotExists {
+			s.logger.Info("restoring state saved with the last bundle, backup is kept until bundle acks are imported")
+			lastProcessedFrom = locs.LastProcessedFileBundleSnapshot
+			ripsrcCheckpointsFrom = locs.RipsrcCheckpointsBundleSnapshot
+		} else {
+			s.logger.Info("previous export/upload did not finish since we found a backup dir, restoring previous state and trying again")
+		}
 
 		// restore the backup, but also keep backup, so we could restore to it again
 
@@ -37,7 +51,7 @@ func (s *Exporter) backupRestoreStateDir() error {
 			return err
 		}
 
-		if err := fs.CopyFile(locs.LastProcessedFileBackup, locs.LastProcessedFile); err != nil {
+		if err := fs.CopyFile(lastProcessedFrom, locs.LastProcessedFile); err != nil {
 			// would happen when running first historical because backup does not have any state yet, but we should be able to recover to that in case of error
 			if !os.IsNotExist(err) {
 				return err
@@ -48,7 +62,7 @@ func (s *Exporter) backupRestoreStateDir() error {
 			return err
 		}
 
-		if err := fs.CopyDir(locs.RipsrcCheckpointsBackup, locs.RipsrcCheckpoints); err != nil {
+		if err := fs.CopyDir(ripsrcCheckpointsFrom, locs.RipsrcCheckpoints); err != nil {
 			// would happen when running first historical because backup does not have any state yet, but we should be able to recover to that in case of error
 			if !os.IsNotExist(err) {
 				return err
@@ -87,5 +101,9 @@ func (s *Exporter) deleteBackupStateDir() error {
 	if err := os.RemoveAll(locs.Backup); err != nil {
 		return fmt.Errorf("error deleting export backup file: %v", err)
 	}
+	// snapshot would be left if bundles were disabled after creating some, state is committed by upload now
+	if err := os.RemoveAll(locs.BundleSnapshot); err != nil {
+		return fmt.Errorf("error deleting bundle snapshot: %v", err)
+	}
 	return nil
 }
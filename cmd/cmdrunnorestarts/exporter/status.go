@@ -0,0 +1,234 @@
+package exporter
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io/ioutil"
+	"os"
+	"time"
+
+	"github.com/pinpt/agent/cmd/cmdrunnorestarts/exporter/fsqueue"
+	"github.com/pinpt/agent/cmd/cmdrunnorestarts/inconfig"
+	"github.com/pinpt/agent/pkg/date"
+	"github.com/pinpt/agent/pkg/structmarshal"
+	"github.com/pinpt/go-common/v10/hash"
+	"github.com/pinpt/integration-sdk/agent"
+)
+
+// Status contains current, queued and last exports
+type Status struct {
+	Current *ExportStatus `json:"current"`
+	// Progress is the progress tree of the current export. Updated by export command every 10s.
+	Progress map[string]interface{} `json:"progress,omitempty"`
+	// Queued contains pending requests, including the one being processed
+	Queued []QueuedExport `json:"queued"`
+	Last   *ExportStatus  `json:"last"`
+	// LastResults contains the result of the last finished export by integration id. Only kept in memory, empty after restart.
+	LastResults map[string]IntegrationResult `json:"last_results"`
+}
+
+// ExportStatus describes running or finished export
+type ExportStatus struct {
+	JobID               string    `json:"job_id"`
+	Integrations        []string  `json:"integrations"`
+	ReprocessHistorical bool      `json:"reprocess_historical"`
+	Attempt             int       `json:"attempt"`
+	Started             time.Time `json:"started"`
+	Finished            time.Time `json:"finished,omitempty"`
+	Error               string    `json:"error,omitempty"`
+}
+
+// QueuedExport is the export request waiting in queue
+type QueuedExport struct {
+	ID                  int       `json:"id"`
+	JobID               string    `json:"job_id"`
+	Integrations        []string  `json:"integrations"`
+	ReprocessHistorical bool      `json:"reprocess_historical"`
+	Priority            int       `json:"priority"`
+	Added               time.Time `json:"added"`
+	Attempts            int       `json:"attempts"`
+	NextAttempt         time.Time `json:"next_attempt"`
+	LastError           string    `json:"last_error,omitempty"`
+}
+
+// IntegrationResult is the result of the last export for integration
+type IntegrationResult struct {
+	JobID       string        `json:"job_id"`
+	Finished    time.Time     `json:"finished"`
+	Incremental bool          `json:"incremental"`
+	Duration    time.Duration `json:"duration"`
+	Error       string        `json:"error,omitempty"`
+	// ProjectErrors is the number of repos/projects that failed
+	ProjectErrors int `json:"project_errors"`
+}
+
+func integrationNames(data *agent.ExportRequest) (res []string) {
+	for _, in := range data.Integrations {
+		res = append(res, in.Name)
+	}
+	return
+}
+
+func (s *Exporter) setCurrent(data *agent.ExportRequest, attempt int) {
+	s.statusMu.Lock()
+	defer s.statusMu.Unlock()
+	st := &ExportStatus{}
+	st.JobID = data.JobID
+	st.Integrations = integrationNames(data)
+	st.ReprocessHistorical = data.ReprocessHistorical
+	st.Attempt = attempt
+	st.Started = time.Now()
+	s.current = st
+	// remove progress of the previous export
+	os.Remove(s.opts.FSConf.ExportProgressFile)
+}
+
+func (s *Exporter) setCurrentError(err error) {
+	s.statusMu.Lock()
+	defer s.statusMu.Unlock()
+	if s.current == nil {
+		return
+	}
+	s.current.Error = err.Error()
+}
+
+func (s *Exporter) setCurrentDone() {
+	s.statusMu.Lock()
+	defer s.statusMu.Unlock()
+	if s.current == nil {
+		return
+	}
+	s.current.Finished = time.Now()
+	s.last = s.current
+	s.current = nil
+}
+
+func (s *Exporter) setLastResults(jobID string, res exportResult) {
+	s.statusMu.Lock()
+	defer s.statusMu.Unlock()
+	for id, in := range res.Integrations {
+		r := IntegrationResult{}
+		r.JobID = jobID
+		r.Finished = time.Now()
+		r.Incremental = in.Incremental
+		r.Duration = in.Duration
+		r.Error = in.Error
+		for _, pr := range in.EntityErrors {
+			if pr.Error != "" {
+				r.ProjectErrors++
+			}
+		}
+		s.lastResults[id] = r
+	}
+}
+
+// Status returns current, queued and last exports
+func (s *Exporter) Status() (res Status, _ error) {
+	s.statusMu.Lock()
+	if s.current != nil {
+		c := *s.current
+		res.Current = &c
+	}
+	if s.last != nil {
+		c := *s.last
+		res.Last = &c
+	}
+	res.LastResults = map[string]IntegrationResult{}
+	for k, v := range s.lastResults {
+		res.LastResults[k] = v
+	}
+	s.statusMu.Unlock()
+
+	if res.Current != nil {
+		b, err := ioutil.ReadFile(s.opts.FSConf.ExportProgressFile)
+		if err != nil && !os.IsNotExist(err) {
+			return res, err
+		}
+		if len(b) != 0 {
+			err = json.Unmarshal(b, &res.Progress)
+			if err != nil {
+				return res, fmt.Errorf("could not read progress file: %v", err)
+			}
+		}
+	}
+
+	// journal is synced on every change and compacted using rename, so it is safe to read while queue is running
+	entries, err := fsqueue.ReadPending(s.opts.FSConf.ExportQueueFile)
+	if err != nil {
+		return res, err
+	}
+	res.Queued = []QueuedExport{}
+	for _, e := range entries {
+		q := QueuedExport{}
+		q.ID = e.ID
+		q.Priority = int(e.Priority)
+		q.Added = e.Added
+		q.Attempts = e.Attempts
+		q.NextAttempt = e.NextAttempt
+		q.LastError = e.LastError
+		req := Request{}
+		err := structmarshal.MapToStruct(e.Data, &req)
+		if err == nil && req.Data != nil {
+			q.JobID = req.Data.JobID
+			q.Integrations = integrationNames(req.Data)
+			q.ReprocessHistorical = req.Data.ReprocessHistorical
+		}
+		res.Queued = append(res.Queued, q)
+	}
+	return res, nil
+}
+
+// QueueIntegrationExport adds export of one integration to the queue. Integration config is taken from the last export request received from the server. Agent can't request a new job and upload url from the server, so the export is local and rejected if results could only be uploaded.
+func (s *Exporter) QueueIntegrationExport(name string, reprocessHistorical bool) error {
+	if s.uploadRequired() {
+		return errors.New("exports queued locally can't be uploaded, since upload url is only provided in export requests from the server. Enable bundles or configure sinks without upload to export locally")
+	}
+	var found *agent.ExportRequestIntegrations
+	for _, in := range s.getLastIntegrations() {
+		if in.Name == name {
+			in := in
+			found = &in
+			break
+		}
+	}
+	if found == nil {
+		return fmt.Errorf("integration %v was not in the last export request received from the server", name)
+	}
+	data := &agent.ExportRequest{}
+	data.JobID = "local-" + hash.Values(name, time.Now().UnixNano())
+	data.Integrations = []agent.ExportRequestIntegrations{*found}
+	data.ReprocessHistorical = reprocessHistorical
+	date.ConvertToModel(time.Now(), &data.RequestDate)
+	s.ExportQueue <- Request{Data: data, Local: true}
+	return nil
+}
+
+// IntegrationConfig returns the config of integration with the provided id from the last export request received from the server. Used to process webhooks received directly by agent.
+func (s *Exporter) IntegrationConfig(id string) (res inconfig.IntegrationAgent, ok bool, _ error) {
+	for _, in := range s.getLastIntegrations() {
+		conf, err := inconfig.AuthFromEvent(in.ToMap(), s.opts.PPEncryptionKey)
+		if err != nil {
+			return res, false, err
+		}
+		if conf.ID != id {
+			continue
+		}
+		conf.Type = inconfig.IntegrationType(in.SystemType)
+		return conf, true, nil
+	}
+	return
+}
+
+// IfNotRunning calls fn while holding the lock that prevents exports from starting. Returns error without calling fn if export is in progress.
+func (s *Exporter) IfNotRunning(fn func() error) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if s.exporting {
+		return ErrExportRunning
+	}
+	return fn()
+}
+
+// ErrExportRunning is returned from IfNotRunning when export is in progress
+var ErrExportRunning = errors.New("export is in progress")
@@ -112,5 +112,15 @@ func convertEdgeCases(in *IntegrationAgent) error {
 			in.Name = "jira-cloud"
 		}
 	}
+	if in.Name == "bitbucket" && in.Config.URL != "" {
+		u, err := url.Parse(in.Config.URL)
+		if err != nil {
+			return fmt.Errorf("invalid bitbucket url: %v", err)
+		}
+		// cloud could also be configured without url when using oauth
+		if u.Hostname() != "bitbucket.org" && u.Hostname() != "api.bitbucket.org" {
+			in.Name = "bitbucket-hosted"
+		}
+	}
 	return nil
 }
@@ -64,3 +64,31 @@ func TestURLAddHTTPSPrefix(t *testing.T) {
 		}
 	}
 }
+
+func TestConvertEdgeCases(t *testing.T) {
+	cases := []struct {
+		Name string
+		URL  string
+		Want string
+	}{
+		{"jira", "https://example.atlassian.net", "jira-cloud"},
+		{"jira", "https://jira.example.com", "jira-hosted"},
+		{"bitbucket", "", "bitbucket"},
+		{"bitbucket", "https://api.bitbucket.org", "bitbucket"},
+		{"bitbucket", "https://bitbucket.org", "bitbucket"},
+		{"bitbucket", "https://bitbucket.example.com/context", "bitbucket-hosted"},
+		{"github", "https://github.com", "github"},
+	}
+	for _, c := range cases {
+		in := IntegrationAgent{}
+		in.Name = c.Name
+		in.Config.URL = c.URL
+		err := convertEdgeCases(&in)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if in.Name != c.Want {
+			t.Errorf("invalid result for case %v %v want %v got %v", c.Name, c.URL, c.Want, in.Name)
+		}
+	}
+}
@@ -69,6 +69,8 @@ type IntegrationConfigAgent struct {
 	AccessToken string `json:"access_token"`
 	// RefreshToken Refresh token
 	RefreshToken string `json:"refresh_token"`
+	// MaxRequestsPerSecond limits the request rate of integration api clients, 0 means no limit
+	MaxRequestsPerSecond float64 `json:"max_requests_per_second,omitempty"`
 }
 
 // IntegrationDef defines a unique integration.
@@ -14,7 +14,7 @@ import (
 
 	"github.com/pinpt/agent/cmd/cmdmutate"
 	"github.com/pinpt/agent/cmd/cmdrunnorestarts/inconfig"
-	"github.com/pinpt/agent/cmd/cmdrunnorestarts/subcommand"
+	"github.com/pinpt/agent/cmd/pkg/directexport"
 	"github.com/pinpt/agent/pkg/date"
 	"github.com/pinpt/integration-sdk/agent"
 
@@ -24,6 +24,15 @@ import (
 
 const ignoreMutationRequestsFromOperatorOlderThan = 15 * time.Second
 
+// mutationErrorCodes maps error codes returned by integrations to the codes in mutation response
+var mutationErrorCodes = map[string]agent.IntegrationMutationResponseErrorCode{
+	mutate.ErrNotFound:         agent.IntegrationMutationResponseErrorCodeNotFound,
+	mutate.ErrPermissionDenied: agent.IntegrationMutationResponseErrorCodePermissionDenied,
+	mutate.ErrConflict:         agent.IntegrationMutationResponseErrorCodeConflict,
+	mutate.ErrValidation:       agent.IntegrationMutationResponseErrorCodeValidation,
+	mutate.ErrNotSupported:     agent.IntegrationMutationResponseErrorCodeNotSupported,
+}
+
 func (s *runner) handleMutationEvents(ctx context.Context) (closefunc, error) {
 	s.logger.Info("listening for mutation requests")
 
@@ -66,14 +75,16 @@ func (s *runner) handleMutationEvents(ctx context.Context) (closefunc, error) {
 			return datamodel.NewModelSendEvent(resp), nil
 		}
 
+		var errorWebappResponse string
+
 		sendError := func(errorCode string, err error) (datamodel.ModelSendEvent, error) {
 			logger.Info("mutation failed", "err", err)
 			resp := &agent.IntegrationMutationResponse{}
 			errStr := err.Error()
 			resp.Error = &errStr
-			switch errorCode {
-			case mutate.ErrNotFound:
-				resp.ErrorCode = agent.IntegrationMutationResponseErrorCodeNotFound
+			resp.WebappResponse = errorWebappResponse
+			if code, ok := mutationErrorCodes[errorCode]; ok {
+				resp.ErrorCode = code
 			}
 			return sendEvent(resp)
 		}
@@ -120,6 +131,13 @@ func (s *runner) handleMutationEvents(ctx context.Context) (closefunc, error) {
 			return sendError("", err)
 		}
 		if res.Error != "" || res.ErrorCode != "" {
+			if res.ErrorCode == mutate.ErrValidation && res.WebappResponse != nil {
+				// field errors
+				b, err := json.Marshal(res.WebappResponse)
+				if err == nil {
+					errorWebappResponse = string(b)
+				}
+			}
 			return sendError(res.ErrorCode, errors.New(res.Error))
 		}
 
@@ -153,35 +171,24 @@ func (s *runner) handleMutationEvents(ctx context.Context) (closefunc, error) {
 }
 
 func (s *runner) execMutate(ctx context.Context, config inconfig.IntegrationAgent, messageID string, mutation cmdmutate.Mutation) (res cmdmutate.Result, _ error) {
-	integrations := []inconfig.IntegrationAgent{config}
-
-	c, err := subcommand.New(subcommand.Opts{
-		Logger:            s.logger,
-		Tmpdir:            s.fsconf.Temp,
-		IntegrationConfig: s.agentConfig,
-		AgentConfig:       s.conf,
-		Integrations:      integrations,
-		DeviceInfo:        s.deviceInfo,
-	})
-
+	data, err := json.Marshal(mutation.Data)
 	if err != nil {
 		return res, err
 	}
 
-	mutationJSON, err := json.Marshal(mutation)
+	s.logger.Debug("executing mutation", "integration", config.Name, "fn", mutation.Fn, "data", string(data), "message_id", messageID)
+
+	filter, err := directexport.NewObjectFilter(s.logger, s.agentConfig)
 	if err != nil {
 		return res, err
 	}
 
-	s.logger.Debug("executing mutation", "integration", config.Name, "mutation", string(mutationJSON))
-
-	err = c.Run(ctx, "mutate", messageID, &res, "--mutation", string(mutationJSON))
+	res0, err := s.plugins.Mutate(ctx, config, mutation.Fn, string(data))
+	res = cmdmutate.NewResult(res0, err, config.Name, mutation.Fn)
+	filter.ApplyMutated(res.MutatedObjects)
+	filter.LogReport()
 
 	s.logger.Debug("executing mutation", "success", res.Success, "err", res.Error)
 
-	if err != nil {
-		return res, err
-	}
-
 	return res, nil
 }
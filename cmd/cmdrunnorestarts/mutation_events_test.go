@@ -0,0 +1,32 @@
+package cmdrunnorestarts
+
+import (
+	"testing"
+
+	"github.com/pinpt/agent/integrations/pkg/mutate"
+	"github.com/pinpt/integration-sdk/agent"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestMutationErrorCodes(t *testing.T) {
+	cases := []struct {
+		Code string
+		Want agent.IntegrationMutationResponseErrorCode
+	}{
+		{mutate.ErrNotFound, agent.IntegrationMutationResponseErrorCodeNotFound},
+		{mutate.ErrPermissionDenied, agent.IntegrationMutationResponseErrorCodePermissionDenied},
+		{mutate.ErrConflict, agent.IntegrationMutationResponseErrorCodeConflict},
+		{mutate.ErrValidation, agent.IntegrationMutationResponseErrorCodeValidation},
+		{mutate.ErrNotSupported, agent.IntegrationMutationResponseErrorCodeNotSupported},
+	}
+	for _, c := range cases {
+		got, ok := mutationErrorCodes[c.Code]
+		if !assert.True(t, ok, c.Code) {
+			continue
+		}
+		assert.Equal(t, c.Want, got, c.Code)
+	}
+	assert.Len(t, mutationErrorCodes, len(cases), "all mapped codes should be tested")
+	_, ok := mutationErrorCodes[""]
+	assert.False(t, ok)
+}
@@ -0,0 +1,185 @@
+package pluginpool
+
+import (
+	"errors"
+	"fmt"
+	"sync"
+	"time"
+
+	"github.com/hashicorp/go-hclog"
+
+	"github.com/pinpt/agent/cmd/cmdintegration"
+	"github.com/pinpt/agent/cmd/cmdrunnorestarts/inconfig"
+	"github.com/pinpt/agent/pkg/expin"
+	"github.com/pinpt/agent/pkg/iloader"
+	"github.com/pinpt/agent/pkg/metrics"
+	"github.com/pinpt/agent/pkg/structmarshal"
+	"github.com/pinpt/agent/rpcdef"
+)
+
+// plugin is the running integration, implemented by loadedPlugin and by fakes in tests
+type plugin interface {
+	RPCClient() rpcdef.Integration
+	Exited() bool
+	Ping() error
+	Close() error
+}
+
+type instance struct {
+	key          string
+	name         string
+	conf         inconfig.IntegrationAgent
+	exportConfig rpcdef.ExportConfig
+	plugin       plugin
+	agent        *agentDelegate
+
+	// busy, lastUsed and retired are protected by Pool.mu
+	busy     bool
+	lastUsed time.Time
+	// retired is set on Pool.Restart for plugins in use, these are closed when the call finishes
+	retired bool
+}
+
+func (s *instance) healthy() error {
+	if s.plugin.Exited() {
+		return errors.New("process exited")
+	}
+	return s.plugin.Ping()
+}
+
+// loadedPlugin wraps iloader.Integration to report panics on close
+type loadedPlugin struct {
+	*iloader.Integration
+	command *cmdintegration.Command
+}
+
+func (s loadedPlugin) Close() error {
+	return s.command.CloseOnlyIntegrationAndHandlePanic(s.Integration)
+}
+
+func (s *Pool) startPlugin(key string, conf inconfig.IntegrationAgent) (*instance, error) {
+	var conf2 inconfig.Integration
+	err := structmarshal.StructToStruct(conf, &conf2)
+	if err != nil {
+		return nil, err
+	}
+
+	opts := cmdintegration.Opts{}
+	opts.Logger = s.logger
+	opts.AgentConfig = s.opts.AgentConfig
+	opts.Integrations = []inconfig.Integration{conf2}
+	command, err := cmdintegration.NewCommand(opts)
+	if err != nil {
+		return nil, err
+	}
+	integration := command.OnlyIntegration()
+
+	in := &instance{}
+	in.key = key
+	in.name = conf.Name
+	in.conf = conf
+	in.exportConfig = integration.ExportConfig
+	in.agent = newAgentDelegate(s.logger, command, integration.Export)
+	in.lastUsed = time.Now()
+
+	iopts := iloader.IntegrationOpts{}
+	iopts.Logger = s.logger
+	iopts.Agent = in.agent
+	iopts.Export = integration.Export
+	iopts.Locs = command.Locs
+	iopts.IntegrationsDir = command.IntegrationsDir()
+	iopts.DevUseCompiledIntegrations = command.DevUseCompiledIntegrations()
+	loaded, err := iloader.NewIntegration(iopts)
+	if err != nil {
+		return nil, err
+	}
+	in.plugin = loadedPlugin{Integration: loaded, command: command}
+	return in, nil
+}
+
+// agentDelegate implements rpcdef.Agent for pooled plugins. Since plugin serves one call at a time, ExportGitRepo is forwarded to the handler set for the current call.
+type agentDelegate struct {
+	logger  hclog.Logger
+	command *cmdintegration.Command
+	exp     expin.Export
+
+	mu            sync.Mutex
+	exportGitRepo func(rpcdef.GitRepoFetch) error
+}
+
+func newAgentDelegate(logger hclog.Logger, command *cmdintegration.Command, exp expin.Export) *agentDelegate {
+	s := &agentDelegate{}
+	s.logger = logger
+	s.command = command
+	s.exp = exp
+	return s
+}
+
+func (s *agentDelegate) setExportGitRepo(fn func(rpcdef.GitRepoFetch) error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.exportGitRepo = fn
+}
+
+func (s *agentDelegate) ExportGitRepo(fetch rpcdef.GitRepoFetch) error {
+	s.mu.Lock()
+	fn := s.exportGitRepo
+	s.mu.Unlock()
+	if fn == nil {
+		return fmt.Errorf("ExportGitRepo is not supported for this call, repo: %v", fetch.UniqueName)
+	}
+	return fn(fetch)
+}
+
+// Methods below are only used in exports. Returning errors instead of panicking, since delegate runs in service process.
+
+func (s *agentDelegate) ExportStarted(modelType string) (sessionID string, lastProcessed interface{}) {
+	s.logger.Error("ExportStarted is not supported for pooled plugins", "integration", s.exp.String())
+	return "", nil
+}
+
+func (s *agentDelegate) ExportDone(sessionID string, lastProcessed interface{}) {
+	s.logger.Error("ExportDone is not supported for pooled plugins", "integration", s.exp.String())
+}
+
+func (s *agentDelegate) SendExported(sessionID string, objs []rpcdef.ExportObj) {
+	s.logger.Error("SendExported is not supported for pooled plugins", "integration", s.exp.String())
+}
+
+func (s *agentDelegate) SessionStart(isTracking bool, name string, parentSessionID int, parentObjectID, parentObjectName string) (sessionID int, lastProcessed interface{}, _ error) {
+	return 0, nil, errors.New("SessionStart is not supported for pooled plugins")
+}
+
+func (s *agentDelegate) SessionProgress(id int, current, total int) error {
+	return errors.New("SessionProgress is not supported for pooled plugins")
+}
+
+func (s *agentDelegate) SessionRollback(id int) error {
+	return errors.New("SessionRollback is not supported for pooled plugins")
+}
+
+func (s *agentDelegate) OAuthNewAccessToken() (token string, _ error) {
+	return s.command.OAuthNewAccessToken(s.exp)
+}
+
+func (s *agentDelegate) OAuthNewAccessTokenFromRefreshToken(name string, refresh string) (token string, _ error) {
+	return s.command.OAuthNewAccessTokenFromRefreshToken(name, refresh)
+}
+
+func (s *agentDelegate) SendPauseEvent(msg string, resumeDate time.Time) error {
+	s.logger.Info("pausing integration due to throttling", "msg", msg, "integration", s.exp.String(), "duration", resumeDate.Sub(time.Now()).String())
+	return nil
+}
+
+func (s *agentDelegate) SendResumeEvent(msg string) error {
+	s.logger.Info("continue with integration after throttling", "msg", msg, "integration", s.exp.String())
+	return nil
+}
+
+func (s *agentDelegate) GetWebhookURL() (url string, _ error) {
+	return "", errors.New("GetWebhookURL is not supported for pooled plugins")
+}
+
+func (s *agentDelegate) SendMetrics(data metrics.Snapshot) error {
+	return metrics.Default.Merge(data, metrics.Labels{"integration": s.exp.IntegrationDef.Name})
+}
@@ -0,0 +1,374 @@
+// Package pluginpool keeps initialized integration plugins running between requests.
+//
+// Starting integration binary for every command adds around 170ms latency, which is noticeable for interactive mutations and for webhooks. Pool keeps plugins warm per integration name and config hash and calls them directly over RPC. Exports still use separate processes.
+//
+// Each plugin serves one call at a time, when all plugins for the same config are busy a new one is started, up to MaxConcurrent calls in total. Plugins not used for IdleTimeout are closed. Idle plugins are checked every HealthCheckInterval and restarted if the process exited or does not respond to ping.
+package pluginpool
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"sync"
+	"time"
+
+	"github.com/hashicorp/go-hclog"
+	"github.com/pinpt/go-common/v10/hash"
+
+	"github.com/pinpt/agent/cmd/cmdintegration"
+	"github.com/pinpt/agent/cmd/cmdrunnorestarts/inconfig"
+	"github.com/pinpt/agent/pkg/metrics"
+	"github.com/pinpt/agent/rpcdef"
+)
+
+var pluginRestarts = metrics.NewCounter("pinpoint_agent_plugin_restarts_total", "Number of pooled integration plugins restarted by reason: crash or health_check")
+
+// Opts are options for New
+type Opts struct {
+	Logger      hclog.Logger
+	AgentConfig cmdintegration.AgentConfig
+	// MaxConcurrent is the max number of concurrent calls to all plugins. Defaults to DefaultMaxConcurrent.
+	MaxConcurrent int
+	// IdleTimeout is the time after which unused plugin is closed. Defaults to DefaultIdleTimeout.
+	IdleTimeout time.Duration
+	// HealthCheckInterval defines how often idle plugins are checked. Defaults to DefaultHealthCheckInterval.
+	HealthCheckInterval time.Duration
+}
+
+const (
+	DefaultMaxConcurrent       = 5
+	DefaultIdleTimeout         = 30 * time.Minute
+	DefaultHealthCheckInterval = time.Minute
+)
+
+// ErrClosed is returned when calling pool after Close
+var ErrClosed = errors.New("plugin pool is closed")
+
+// Pool keeps integration plugins running
+type Pool struct {
+	opts   Opts
+	logger hclog.Logger
+
+	// start creates a new plugin for config, replaced in tests
+	start func(key string, conf inconfig.IntegrationAgent) (*instance, error)
+
+	sem chan bool
+
+	mu        sync.Mutex
+	instances map[string][]*instance
+	closed    bool
+	// generation is incremented on Restart, plugins started before that are not added to the pool
+	generation int
+
+	stop     chan bool
+	stopOnce sync.Once
+}
+
+// New creates a pool and starts the background health check and eviction loop. Call Close when done.
+func New(opts Opts) *Pool {
+	if opts.Logger == nil || opts.AgentConfig.PinpointRoot == "" {
+		panic("provide all opts")
+	}
+	if opts.MaxConcurrent <= 0 {
+		opts.MaxConcurrent = DefaultMaxConcurrent
+	}
+	if opts.IdleTimeout <= 0 {
+		opts.IdleTimeout = DefaultIdleTimeout
+	}
+	if opts.HealthCheckInterval <= 0 {
+		opts.HealthCheckInterval = DefaultHealthCheckInterval
+	}
+	s := newPool(opts)
+	s.start = s.startPlugin
+	go s.loop()
+	return s
+}
+
+func newPool(opts Opts) *Pool {
+	s := &Pool{}
+	s.opts = opts
+	s.logger = opts.Logger.Named("plugin-pool")
+	s.sem = make(chan bool, opts.MaxConcurrent)
+	s.instances = map[string][]*instance{}
+	s.stop = make(chan bool)
+	return s
+}
+
+// Mutate calls Mutate on a pooled plugin for passed integration config
+func (s *Pool) Mutate(ctx context.Context, conf inconfig.IntegrationAgent, fn string, data string) (res rpcdef.MutateResult, rerr error) {
+	rerr = s.call(ctx, conf, nil, func(in *instance) error {
+		var err error
+		res, err = in.plugin.RPCClient().Mutate(ctx, fn, data, in.exportConfig)
+		return err
+	})
+	return
+}
+
+// Webhook calls Webhook on a pooled plugin for passed integration config. Git repos requested by integration are passed to exportGitRepo.
+func (s *Pool) Webhook(ctx context.Context, conf inconfig.IntegrationAgent, headers map[string]string, body string, exportGitRepo func(rpcdef.GitRepoFetch) error) (res rpcdef.WebhookResult, rerr error) {
+	rerr = s.call(ctx, conf, exportGitRepo, func(in *instance) error {
+		var err error
+		res, err = in.plugin.RPCClient().Webhook(ctx, headers, body, in.exportConfig)
+		return err
+	})
+	return
+}
+
+func (s *Pool) call(ctx context.Context, conf inconfig.IntegrationAgent, exportGitRepo func(rpcdef.GitRepoFetch) error, fn func(in *instance) error) error {
+	select {
+	case s.sem <- true:
+	case <-ctx.Done():
+		return ctx.Err()
+	}
+	defer func() {
+		<-s.sem
+	}()
+
+	in, err := s.acquire(conf)
+	if err != nil {
+		return err
+	}
+
+	in.agent.setExportGitRepo(exportGitRepo)
+	err = fn(in)
+	in.agent.setExportGitRepo(nil)
+
+	if err != nil && in.plugin.Exited() {
+		s.logger.Warn("integration plugin crashed, will restart on next request", "integration", in.name, "err", err)
+		pluginRestarts.Inc(metrics.Labels{"integration": in.name, "reason": "crash"})
+		s.remove(in)
+		return fmt.Errorf("integration plugin crashed: %v", err)
+	}
+	s.release(in)
+	return err
+}
+
+func configKey(conf inconfig.IntegrationAgent) (string, error) {
+	b, err := json.Marshal(conf)
+	if err != nil {
+		return "", err
+	}
+	return conf.Name + "@" + hash.Values(string(b)), nil
+}
+
+// acquire returns idle plugin for config or starts a new one, marking it as busy
+func (s *Pool) acquire(conf inconfig.IntegrationAgent) (*instance, error) {
+	key, err := configKey(conf)
+	if err != nil {
+		return nil, err
+	}
+
+	s.mu.Lock()
+	if s.closed {
+		s.mu.Unlock()
+		return nil, ErrClosed
+	}
+	for _, in := range s.instances[key] {
+		if !in.busy {
+			in.busy = true
+			s.mu.Unlock()
+			return in, nil
+		}
+	}
+	generation := s.generation
+	s.mu.Unlock()
+
+	s.logger.Info("starting integration plugin", "integration", conf.Name)
+	start := time.Now()
+	in, err := s.start(key, conf)
+	if err != nil {
+		return nil, fmt.Errorf("could not start integration plugin %v: %v", conf.Name, err)
+	}
+	s.logger.Debug("started integration plugin", "integration", conf.Name, "dur", time.Since(start).String())
+	in.busy = true
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if s.closed {
+		s.closeInstance(in)
+		return nil, ErrClosed
+	}
+	if generation != s.generation {
+		// pool was restarted while starting, use plugin for this call only
+		in.retired = true
+		return in, nil
+	}
+	s.instances[key] = append(s.instances[key], in)
+	return in, nil
+}
+
+func (s *Pool) release(in *instance) {
+	s.mu.Lock()
+	if s.closed || in.retired {
+		s.removeNoLock(in)
+		s.mu.Unlock()
+		s.closeInstance(in)
+		return
+	}
+	in.busy = false
+	in.lastUsed = time.Now()
+	s.mu.Unlock()
+}
+
+// remove removes instance from pool and closes it
+func (s *Pool) remove(in *instance) {
+	s.mu.Lock()
+	s.removeNoLock(in)
+	s.mu.Unlock()
+	s.closeInstance(in)
+}
+
+func (s *Pool) removeNoLock(in *instance) {
+	var res []*instance
+	for _, in2 := range s.instances[in.key] {
+		if in2 != in {
+			res = append(res, in2)
+		}
+	}
+	if len(res) == 0 {
+		delete(s.instances, in.key)
+		return
+	}
+	s.instances[in.key] = res
+}
+
+func (s *Pool) closeInstance(in *instance) {
+	err := in.plugin.Close()
+	if err != nil {
+		s.logger.Error("could not close integration plugin", "integration", in.name, "err", err)
+	}
+}
+
+// Len returns the number of running plugins
+func (s *Pool) Len() int {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	res := 0
+	for _, ins := range s.instances {
+		res += len(ins)
+	}
+	return res
+}
+
+func (s *Pool) loop() {
+	for {
+		select {
+		case <-s.stop:
+			return
+		case <-time.After(s.opts.HealthCheckInterval):
+			s.check()
+		}
+	}
+}
+
+// check closes plugins that were idle for longer than IdleTimeout and restarts the ones that crashed or do not respond
+func (s *Pool) check() {
+	var evict []*instance
+	var check []*instance
+
+	s.mu.Lock()
+	for _, ins := range s.instances {
+		for _, in := range ins {
+			if in.busy {
+				continue
+			}
+			if time.Since(in.lastUsed) > s.opts.IdleTimeout {
+				s.removeNoLock(in)
+				evict = append(evict, in)
+				continue
+			}
+			// mark as busy so that it's not used while checking
+			in.busy = true
+			check = append(check, in)
+		}
+	}
+	s.mu.Unlock()
+
+	for _, in := range evict {
+		s.logger.Info("closing idle integration plugin", "integration", in.name)
+		s.closeInstance(in)
+	}
+
+	for _, in := range check {
+		err := in.healthy()
+		s.mu.Lock()
+		retired := in.retired
+		if err == nil && !retired {
+			in.busy = false
+		}
+		generation := s.generation
+		s.mu.Unlock()
+		if retired {
+			// pool was restarted during check
+			s.closeInstance(in)
+			continue
+		}
+		if err == nil {
+			continue
+		}
+		s.logger.Warn("integration plugin health check failed, restarting", "integration", in.name, "err", err)
+		pluginRestarts.Inc(metrics.Labels{"integration": in.name, "reason": "health_check"})
+		s.remove(in)
+		in2, err := s.start(in.key, in.conf)
+		if err != nil {
+			s.logger.Error("could not restart integration plugin", "integration", in.name, "err", err)
+			continue
+		}
+		// keep lastUsed, so that restarted plugin is still evicted on time
+		in2.lastUsed = in.lastUsed
+		s.mu.Lock()
+		if s.closed || generation != s.generation {
+			s.mu.Unlock()
+			s.closeInstance(in2)
+			continue
+		}
+		s.instances[in2.key] = append(s.instances[in2.key], in2)
+		s.mu.Unlock()
+	}
+}
+
+// Restart stops all plugins, new plugins are started on next request. Plugins in use are closed when their call finishes. Used before updating integration binaries, so that updated binaries are used for all following requests.
+func (s *Pool) Restart() {
+	s.mu.Lock()
+	s.generation++
+	ins := s.removeIdleNoLock()
+	for _, sub := range s.instances {
+		for _, in := range sub {
+			in.retired = true
+		}
+	}
+	s.instances = map[string][]*instance{}
+	s.mu.Unlock()
+	for _, in := range ins {
+		s.closeInstance(in)
+	}
+}
+
+// Close stops all plugins. Calls in progress are allowed to finish, but their plugins are closed after that.
+func (s *Pool) Close() {
+	s.stopOnce.Do(func() {
+		close(s.stop)
+	})
+	s.mu.Lock()
+	s.closed = true
+	ins := s.removeIdleNoLock()
+	s.mu.Unlock()
+	for _, in := range ins {
+		s.closeInstance(in)
+	}
+}
+
+func (s *Pool) removeIdleNoLock() (res []*instance) {
+	for _, sub := range s.instances {
+		for _, in := range sub {
+			if !in.busy {
+				res = append(res, in)
+			}
+		}
+	}
+	for _, in := range res {
+		s.removeNoLock(in)
+	}
+	return
+}
@@ -0,0 +1,319 @@
+package pluginpool
+
+import (
+	"context"
+	"errors"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/hashicorp/go-hclog"
+	"github.com/pinpt/agent/cmd/cmdrunnorestarts/inconfig"
+	"github.com/pinpt/agent/pkg/expin"
+	"github.com/pinpt/agent/rpcdef"
+	"github.com/stretchr/testify/assert"
+)
+
+type fakePlugin struct {
+	mu      sync.Mutex
+	exited  bool
+	pingErr error
+	closed  bool
+	// block is used to keep mutate call running
+	block chan bool
+	agent rpcdef.Agent
+}
+
+func (s *fakePlugin) RPCClient() rpcdef.Integration {
+	return s
+}
+
+func (s *fakePlugin) Exited() bool {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	return s.exited
+}
+
+func (s *fakePlugin) Ping() error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	return s.pingErr
+}
+
+func (s *fakePlugin) Close() error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.closed = true
+	return nil
+}
+
+func (s *fakePlugin) Init(agent rpcdef.Agent) error {
+	s.agent = agent
+	return nil
+}
+
+func (s *fakePlugin) Export(context.Context, rpcdef.ExportConfig) (res rpcdef.ExportResult, _ error) {
+	return
+}
+
+func (s *fakePlugin) ValidateConfig(context.Context, rpcdef.ExportConfig) (res rpcdef.ValidationResult, _ error) {
+	return
+}
+
+func (s *fakePlugin) OnboardExport(ctx context.Context, objectType rpcdef.OnboardExportType, config rpcdef.ExportConfig) (res rpcdef.OnboardExportResult, _ error) {
+	return
+}
+
+func (s *fakePlugin) Mutate(ctx context.Context, fn string, data string, config rpcdef.ExportConfig) (res rpcdef.MutateResult, _ error) {
+	s.mu.Lock()
+	block := s.block
+	s.mu.Unlock()
+	if block != nil {
+		<-block
+	}
+	if fn == "crash" {
+		s.mu.Lock()
+		s.exited = true
+		s.mu.Unlock()
+		return res, errors.New("connection closed")
+	}
+	res.WebappResponse = fn + ":" + data
+	return
+}
+
+func (s *fakePlugin) Webhook(ctx context.Context, headers map[string]string, body string, config rpcdef.ExportConfig) (res rpcdef.WebhookResult, _ error) {
+	err := s.agent.ExportGitRepo(rpcdef.GitRepoFetch{UniqueName: body})
+	if err != nil {
+		res.Error = err.Error()
+	}
+	return
+}
+
+type testPool struct {
+	*Pool
+	mu      sync.Mutex
+	plugins []*fakePlugin
+	block   chan bool
+}
+
+func newTestPool(opts Opts) *testPool {
+	opts.Logger = hclog.NewNullLogger()
+	if opts.MaxConcurrent == 0 {
+		opts.MaxConcurrent = DefaultMaxConcurrent
+	}
+	if opts.IdleTimeout == 0 {
+		opts.IdleTimeout = DefaultIdleTimeout
+	}
+	s := &testPool{}
+	s.Pool = newPool(opts)
+	s.Pool.start = func(key string, conf inconfig.IntegrationAgent) (*instance, error) {
+		p := &fakePlugin{}
+		s.mu.Lock()
+		p.block = s.block
+		s.plugins = append(s.plugins, p)
+		s.mu.Unlock()
+		in := &instance{}
+		in.key = key
+		in.name = conf.Name
+		in.conf = conf
+		in.plugin = p
+		in.agent = newAgentDelegate(opts.Logger, nil, expin.NewExport(0, "", conf.IntegrationDef()))
+		in.lastUsed = time.Now()
+		p.Init(in.agent)
+		return in, nil
+	}
+	return s
+}
+
+func (s *testPool) started() int {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	return len(s.plugins)
+}
+
+func testConf(name string, apiKey string) inconfig.IntegrationAgent {
+	conf := inconfig.IntegrationAgent{}
+	conf.Name = name
+	conf.Config.APIKey = apiKey
+	return conf
+}
+
+func TestPoolReuse(t *testing.T) {
+	assert := assert.New(t)
+	pool := newTestPool(Opts{})
+	defer pool.Close()
+
+	ctx := context.Background()
+	res, err := pool.Mutate(ctx, testConf("jira", "k1"), "fn1", "d1")
+	if err != nil {
+		t.Fatal(err)
+	}
+	assert.Equal("fn1:d1", res.WebappResponse)
+	_, err = pool.Mutate(ctx, testConf("jira", "k1"), "fn1", "d2")
+	if err != nil {
+		t.Fatal(err)
+	}
+	assert.Equal(1, pool.started(), "same config should reuse plugin")
+
+	_, err = pool.Mutate(ctx, testConf("jira", "k2"), "fn1", "d1")
+	if err != nil {
+		t.Fatal(err)
+	}
+	assert.Equal(2, pool.started(), "different config should start new plugin")
+	assert.Equal(2, pool.Len())
+}
+
+func TestPoolConcurrency(t *testing.T) {
+	assert := assert.New(t)
+	pool := newTestPool(Opts{MaxConcurrent: 2})
+	defer pool.Close()
+	pool.block = make(chan bool)
+
+	ctx := context.Background()
+	wg := sync.WaitGroup{}
+	for i := 0; i < 3; i++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			_, err := pool.Mutate(ctx, testConf("jira", "k1"), "fn1", "d1")
+			if err != nil {
+				t.Error(err)
+			}
+		}()
+	}
+	// wait for calls to start
+	for pool.started() < 2 {
+		time.Sleep(time.Millisecond)
+	}
+	time.Sleep(10 * time.Millisecond)
+	assert.Equal(2, pool.started(), "third call should wait for a free slot")
+
+	close(pool.block)
+	wg.Wait()
+	assert.Equal(2, pool.Len())
+}
+
+func TestPoolCrash(t *testing.T) {
+	assert := assert.New(t)
+	pool := newTestPool(Opts{})
+	defer pool.Close()
+
+	ctx := context.Background()
+	_, err := pool.Mutate(ctx, testConf("jira", "k1"), "crash", "")
+	assert.Error(err)
+	assert.Equal(0, pool.Len())
+	assert.True(pool.plugins[0].closed)
+
+	_, err = pool.Mutate(ctx, testConf("jira", "k1"), "fn1", "")
+	assert.NoError(err)
+	assert.Equal(2, pool.started(), "should restart after crash")
+}
+
+func TestPoolCheck(t *testing.T) {
+	assert := assert.New(t)
+	pool := newTestPool(Opts{IdleTimeout: time.Hour})
+	defer pool.Close()
+
+	ctx := context.Background()
+	_, err := pool.Mutate(ctx, testConf("jira", "k1"), "fn1", "")
+	if err != nil {
+		t.Fatal(err)
+	}
+	_, err = pool.Mutate(ctx, testConf("jira", "k2"), "fn1", "")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	pool.plugins[0].pingErr = errors.New("not responding")
+	pool.check()
+	assert.True(pool.plugins[0].closed)
+	assert.False(pool.plugins[1].closed)
+	assert.Equal(3, pool.started(), "unhealthy plugin should be restarted")
+	assert.Equal(2, pool.Len())
+
+	pool.opts.IdleTimeout = time.Nanosecond
+	pool.check()
+	assert.Equal(0, pool.Len(), "idle plugins should be closed")
+	assert.True(pool.plugins[1].closed)
+	assert.True(pool.plugins[2].closed)
+}
+
+func TestPoolWebhookExportGitRepo(t *testing.T) {
+	assert := assert.New(t)
+	pool := newTestPool(Opts{})
+	defer pool.Close()
+
+	ctx := context.Background()
+	var repos []string
+	res, err := pool.Webhook(ctx, testConf("github", "k1"), nil, "r1", func(fetch rpcdef.GitRepoFetch) error {
+		repos = append(repos, fetch.UniqueName)
+		return nil
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	assert.Empty(res.Error)
+	assert.Equal([]string{"r1"}, repos)
+
+	// handler is only set for the duration of the call
+	_, err = pool.Mutate(ctx, testConf("github", "k1"), "fn1", "")
+	assert.NoError(err)
+	err = pool.plugins[0].agent.ExportGitRepo(rpcdef.GitRepoFetch{UniqueName: "r2"})
+	assert.Error(err)
+	assert.Equal([]string{"r1"}, repos)
+}
+
+func TestPoolClose(t *testing.T) {
+	assert := assert.New(t)
+	pool := newTestPool(Opts{})
+
+	ctx := context.Background()
+	_, err := pool.Mutate(ctx, testConf("jira", "k1"), "fn1", "")
+	if err != nil {
+		t.Fatal(err)
+	}
+	pool.Close()
+	assert.True(pool.plugins[0].closed)
+	_, err = pool.Mutate(ctx, testConf("jira", "k1"), "fn1", "")
+	assert.Equal(ErrClosed, err)
+}
+
+func TestPoolRestart(t *testing.T) {
+	assert := assert.New(t)
+	pool := newTestPool(Opts{})
+	defer pool.Close()
+
+	ctx := context.Background()
+	_, err := pool.Mutate(ctx, testConf("jira", "k1"), "fn1", "")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	pool.block = make(chan bool)
+	done := make(chan bool)
+	go func() {
+		_, err := pool.Mutate(ctx, testConf("jira", "k2"), "fn1", "")
+		if err != nil {
+			t.Error(err)
+		}
+		done <- true
+	}()
+	for pool.started() < 2 {
+		time.Sleep(time.Millisecond)
+	}
+
+	pool.Restart()
+	assert.True(pool.plugins[0].closed, "idle plugin should be closed")
+	assert.False(pool.plugins[1].closed, "busy plugin should be closed after call finishes")
+	assert.Equal(0, pool.Len())
+
+	close(pool.block)
+	<-done
+	assert.True(pool.plugins[1].closed)
+
+	pool.block = nil
+	_, err = pool.Mutate(ctx, testConf("jira", "k2"), "fn1", "")
+	assert.NoError(err)
+	assert.Equal(3, pool.started(), "should start new plugin after restart")
+	assert.Equal(1, pool.Len())
+}
@@ -6,6 +6,7 @@ import (
 	"fmt"
 	"os"
 	"runtime"
+	"sync"
 	"sync/atomic"
 	"time"
 
@@ -33,6 +34,8 @@ import (
 	"github.com/pinpt/agent/cmd/cmdrunnorestarts/crashes"
 	"github.com/pinpt/agent/cmd/cmdrunnorestarts/exporter"
 	"github.com/pinpt/agent/cmd/cmdrunnorestarts/logsender"
+	"github.com/pinpt/agent/cmd/cmdrunnorestarts/pluginpool"
+	"github.com/pinpt/agent/cmd/cmdrunnorestarts/statusapi"
 	"github.com/pinpt/agent/cmd/cmdrunnorestarts/updater"
 )
 
@@ -84,7 +87,15 @@ type runner struct {
 
 	logSender *logsender.Sender
 
+	// plugins keeps integrations running for mutations and webhooks
+	plugins *pluginpool.Pool
+
 	onboardingInProgress int64
+
+	started time.Time
+
+	updateMu    sync.Mutex
+	updateState statusapi.UpdaterState
 }
 
 func newRunner(opts Opts) (*runner, error) {
@@ -127,6 +138,7 @@ func (s *runner) close() {
 }
 
 func (s *runner) Run(ctx context.Context) error {
+	s.started = time.Now()
 	var closers []func()
 	// do not use defer, to avoid indefinite timeouts in case of panics
 	closers = append(closers, s.close)
@@ -244,6 +256,28 @@ func (s *runner) Run(ctx context.Context) error {
 		}
 		closers = append(closers, close)
 	}
+	s.plugins = pluginpool.New(pluginpool.Opts{
+		Logger:      s.logger,
+		AgentConfig: s.agentConfig,
+	})
+	closers = append(closers, s.plugins.Close)
+
+	if s.conf.StatusAPI != "" {
+		close, err := s.startStatusAPI()
+		if err != nil {
+			return fmt.Errorf("could not start status api, err: %v", err)
+		}
+		closers = append(closers, close)
+	}
+
+	if s.conf.WebhookServer.Enabled() {
+		close, err := s.startWebhookServer(ctx)
+		if err != nil {
+			return fmt.Errorf("could not start webhook server, err: %v", err)
+		}
+		closers = append(closers, close)
+	}
+
 	{
 		close, err := s.handleMutationEvents(ctx)
 		if err != nil {
@@ -456,6 +490,12 @@ func (s *runner) getAgentConfig() (res cmdintegration.AgentConfig) {
 	res.CustomerID = s.conf.CustomerID
 	res.PinpointRoot = s.opts.PinpointRoot
 	res.IntegrationsDir = s.conf.IntegrationsDir
+	res.Sinks = s.conf.Sinks
+	res.Identity = s.conf.Identity
+	res.Redaction = s.conf.Redaction
+	res.Secrets = s.conf.Secrets
+	res.WebhookServer = s.conf.WebhookServer
+	res.Git = s.conf.Git
 	res.Backend.Enable = true
 	return
 }
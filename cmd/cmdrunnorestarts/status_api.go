@@ -0,0 +1,108 @@
+package cmdrunnorestarts
+
+import (
+	"errors"
+	"fmt"
+	"os"
+	"time"
+
+	"github.com/pinpt/agent/cmd/cmdforcehistorical"
+	"github.com/pinpt/agent/cmd/cmdrunnorestarts/exporter"
+	"github.com/pinpt/agent/cmd/cmdrunnorestarts/statusapi"
+	"github.com/pinpt/agent/cmd/cmdrunnorestarts/updater"
+	"github.com/pinpt/agent/pkg/metrics"
+)
+
+func (s *runner) startStatusAPI() (closefunc, error) {
+	srv := statusapi.New(statusapi.Opts{
+		Logger: s.logger,
+		Addr:   s.conf.StatusAPI,
+		Agent:  &statusAgent{runner: s},
+	})
+	err := srv.Start()
+	if err != nil {
+		return nil, err
+	}
+	return srv.Close, nil
+}
+
+func (s *runner) setUpdateStarted(version string) {
+	s.updateMu.Lock()
+	defer s.updateMu.Unlock()
+	s.updateState.InProgress = true
+	s.updateState.LastVersion = version
+	s.updateState.LastStarted = time.Now()
+	s.updateState.LastError = ""
+}
+
+func (s *runner) setUpdateDone(err error) {
+	s.updateMu.Lock()
+	defer s.updateMu.Unlock()
+	s.updateState.InProgress = false
+	if err != nil {
+		s.updateState.LastError = err.Error()
+	}
+}
+
+// statusAgent implements statusapi.Agent
+type statusAgent struct {
+	runner *runner
+}
+
+func (s *statusAgent) Status() (res statusapi.Status, _ error) {
+	r := s.runner
+	res.Version = os.Getenv("PP_AGENT_VERSION")
+	res.Commit = os.Getenv("PP_AGENT_COMMIT")
+	res.Started = r.started
+
+	ping := r.getPing()
+	res.Exporting = ping.Exporting
+	res.Onboarding = ping.Onboarding
+
+	var err error
+	res.Export, err = r.exporter.Status()
+	if err != nil {
+		return res, fmt.Errorf("could not get export status: %v", err)
+	}
+	res.Integrations, err = updater.New(r.logger, r.fsconf, r.conf).Integrations()
+	if err != nil {
+		return res, fmt.Errorf("could not list integrations: %v", err)
+	}
+
+	r.updateMu.Lock()
+	res.Updater = r.updateState
+	r.updateMu.Unlock()
+	return res, nil
+}
+
+func (s *statusAgent) CancelExport() error {
+	r := s.runner
+	if !r.exporter.IsRunning() {
+		return errors.New("no export in progress")
+	}
+	r.logger.Info("cancelling export requested using status api")
+	return killCommand(r.logger, "export")
+}
+
+func (s *statusAgent) QueueExport(integration string, reprocessHistorical bool) error {
+	r := s.runner
+	r.logger.Info("export requested using status api", "integration", integration, "reprocess_historical", reprocessHistorical)
+	return r.exporter.QueueIntegrationExport(integration, reprocessHistorical)
+}
+
+func (s *statusAgent) Metrics() (metrics.Snapshot, error) {
+	return s.runner.exporter.Metrics()
+}
+
+func (s *statusAgent) ForceHistorical(integration string) error {
+	r := s.runner
+	r.logger.Info("force historical requested using status api", "integration", integration)
+	// export command saves last processed state when it finishes, which would overwrite the change, so do not allow export to start until state is updated
+	err := r.exporter.IfNotRunning(func() error {
+		return cmdforcehistorical.Run(r.logger, integration, r.fsconf.LastProcessedFile, r.fsconf.DedupFile)
+	})
+	if err == exporter.ErrExportRunning {
+		return errors.New("can't force historical while export is in progress, cancel it first")
+	}
+	return err
+}
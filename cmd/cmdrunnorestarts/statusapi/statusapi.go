@@ -0,0 +1,245 @@
+// Package statusapi provides local http api for checking the status of running agent service and for controlling exports.
+// It is only bound to localhost or unix socket, since it does not use authentication.
+// POST requests must include ActionHeader. Browsers could not send custom headers cross-origin without CORS preflight, which is not allowed, so websites could not trigger actions.
+package statusapi
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net"
+	"net/http"
+	"os"
+	"strings"
+	"time"
+
+	"github.com/hashicorp/go-hclog"
+	"github.com/pinpt/agent/cmd/cmdrunnorestarts/exporter"
+	"github.com/pinpt/agent/cmd/cmdrunnorestarts/updater"
+	"github.com/pinpt/agent/pkg/metrics"
+)
+
+// ActionHeader must be set on requests changing agent state
+const ActionHeader = "X-Pinpoint-Agent"
+
+// Agent is the running agent service
+type Agent interface {
+	Status() (Status, error)
+	// CancelExport stops the running export
+	CancelExport() error
+	// QueueExport adds export for one integration to the queue
+	QueueExport(integration string, reprocessHistorical bool) error
+	// ForceHistorical removes integration state, so that the next export is historical
+	ForceHistorical(integration string) error
+	// Metrics returns metrics of the service, running export and integrations
+	Metrics() (metrics.Snapshot, error)
+}
+
+// Status is the status of agent service
+type Status struct {
+	Version    string    `json:"version"`
+	Commit     string    `json:"commit"`
+	Started    time.Time `json:"started"`
+	Exporting  bool      `json:"exporting"`
+	Onboarding bool      `json:"onboarding"`
+
+	Export       exporter.Status             `json:"export"`
+	Integrations []updater.IntegrationBinary `json:"integrations"`
+	Updater      UpdaterState                `json:"updater"`
+}
+
+// UpdaterState is the state of agent self-update
+type UpdaterState struct {
+	InProgress bool `json:"in_progress"`
+	// LastVersion is the version requested in the last update
+	LastVersion string    `json:"last_version,omitempty"`
+	LastStarted time.Time `json:"last_started,omitempty"`
+	LastError   string    `json:"last_error,omitempty"`
+}
+
+// Opts are options for New call
+type Opts struct {
+	Logger hclog.Logger
+	// Addr is localhost:port or unix:/path/to/socket
+	Addr  string
+	Agent Agent
+}
+
+// Server is the status api server
+type Server struct {
+	opts   Opts
+	logger hclog.Logger
+	srv    *http.Server
+}
+
+// New creates the server
+func New(opts Opts) *Server {
+	if opts.Logger == nil || opts.Addr == "" || opts.Agent == nil {
+		panic("provide all opts")
+	}
+	s := &Server{}
+	s.opts = opts
+	s.logger = opts.Logger.Named("status-api")
+	return s
+}
+
+func listen(addr string) (net.Listener, error) {
+	if strings.HasPrefix(addr, "unix:") {
+		loc := strings.TrimPrefix(addr, "unix:")
+		// remove socket left after previous run
+		err := os.Remove(loc)
+		if err != nil && !os.IsNotExist(err) {
+			return nil, err
+		}
+		l, err := net.Listen("unix", loc)
+		if err != nil {
+			return nil, err
+		}
+		err = os.Chmod(loc, 0600)
+		if err != nil {
+			l.Close()
+			return nil, err
+		}
+		return l, nil
+	}
+	host, _, err := net.SplitHostPort(addr)
+	if err != nil {
+		return nil, fmt.Errorf("invalid status api address: %v", err)
+	}
+	switch host {
+	case "localhost", "127.0.0.1", "::1":
+	default:
+		return nil, fmt.Errorf("status api could only be bound to localhost or unix socket, got: %v", addr)
+	}
+	return net.Listen("tcp", addr)
+}
+
+// Start starts serving requests in background
+func (s *Server) Start() error {
+	l, err := listen(s.opts.Addr)
+	if err != nil {
+		return err
+	}
+	s.srv = &http.Server{Handler: s.Handler()}
+	s.logger.Info("status api listening", "addr", s.opts.Addr)
+	go func() {
+		err := s.srv.Serve(l)
+		if err != nil && err != http.ErrServerClosed {
+			s.logger.Error("status api stopped", "err", err)
+		}
+	}()
+	return nil
+}
+
+// Close stops the server
+func (s *Server) Close() {
+	if s.srv == nil {
+		return
+	}
+	err := s.srv.Close()
+	if err != nil {
+		s.logger.Error("could not close status api", "err", err)
+	}
+}
+
+// Handler returns handler with all api endpoints
+func (s *Server) Handler() http.Handler {
+	mux := http.NewServeMux()
+	mux.HandleFunc("/status", s.handle(http.MethodGet, func(r *http.Request) (interface{}, error) {
+		return s.opts.Agent.Status()
+	}))
+	mux.HandleFunc("/export/cancel", s.handle(http.MethodPost, func(r *http.Request) (interface{}, error) {
+		return nil, s.opts.Agent.CancelExport()
+	}))
+	mux.HandleFunc("/export", s.handle(http.MethodPost, func(r *http.Request) (interface{}, error) {
+		name, err := integrationParam(r)
+		if err != nil {
+			return nil, err
+		}
+		historical := r.URL.Query().Get("reprocess_historical") == "true"
+		return nil, s.opts.Agent.QueueExport(name, historical)
+	}))
+	mux.HandleFunc("/force-historical", s.handle(http.MethodPost, func(r *http.Request) (interface{}, error) {
+		name, err := integrationParam(r)
+		if err != nil {
+			return nil, err
+		}
+		return nil, s.opts.Agent.ForceHistorical(name)
+	}))
+	mux.HandleFunc("/metrics", s.handleMetrics)
+	return mux
+}
+
+func (s *Server) handleMetrics(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodGet {
+		s.write(w, http.StatusMethodNotAllowed, result{Error: "method not allowed, use " + http.MethodGet})
+		return
+	}
+	data, err := s.opts.Agent.Metrics()
+	if err != nil {
+		s.logger.Warn("could not get metrics", "err", err)
+		s.write(w, http.StatusInternalServerError, result{Error: err.Error()})
+		return
+	}
+	w.Header().Set("Content-Type", metrics.ContentType)
+	err = data.WriteText(w)
+	if err != nil {
+		s.logger.Warn("could not write metrics", "err", err)
+	}
+}
+
+type badRequest struct {
+	error
+}
+
+func integrationParam(r *http.Request) (string, error) {
+	name := r.URL.Query().Get("integration")
+	if name == "" {
+		return "", badRequest{errors.New("integration param is required")}
+	}
+	return name, nil
+}
+
+type result struct {
+	OK    bool   `json:"ok"`
+	Error string `json:"error,omitempty"`
+}
+
+func (s *Server) handle(method string, fn func(r *http.Request) (interface{}, error)) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		if r.Method != method {
+			s.write(w, http.StatusMethodNotAllowed, result{Error: "method not allowed, use " + method})
+			return
+		}
+		if method != http.MethodGet && r.Header.Get(ActionHeader) == "" {
+			s.write(w, http.StatusForbidden, result{Error: ActionHeader + " header is required"})
+			return
+		}
+		res, err := fn(r)
+		if err != nil {
+			code := http.StatusInternalServerError
+			if _, ok := err.(badRequest); ok {
+				code = http.StatusBadRequest
+			}
+			s.logger.Warn("status api request failed", "path", r.URL.Path, "err", err)
+			s.write(w, code, result{Error: err.Error()})
+			return
+		}
+		if res == nil {
+			res = result{OK: true}
+		}
+		s.write(w, http.StatusOK, res)
+	}
+}
+
+func (s *Server) write(w http.ResponseWriter, code int, data interface{}) {
+	b, err := json.MarshalIndent(data, "", "  ")
+	if err != nil {
+		s.logger.Error("could not marshal status api response", "err", err)
+		w.WriteHeader(http.StatusInternalServerError)
+		return
+	}
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(code)
+	w.Write(b)
+}
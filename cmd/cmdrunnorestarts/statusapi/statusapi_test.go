@@ -0,0 +1,205 @@
+package statusapi
+
+import (
+	"encoding/json"
+	"errors"
+	"io/ioutil"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/hashicorp/go-hclog"
+	"github.com/pinpt/agent/cmd/cmdrunnorestarts/exporter"
+	"github.com/pinpt/agent/pkg/metrics"
+	"github.com/stretchr/testify/assert"
+)
+
+type agentMock struct {
+	exporting  bool
+	queued     []string
+	historical []string
+	cancelled  int
+}
+
+func (s *agentMock) Status() (res Status, _ error) {
+	res.Version = "v1"
+	res.Exporting = s.exporting
+	res.Export.Current = &exporter.ExportStatus{JobID: "j1", Integrations: []string{"github"}}
+	return
+}
+
+func (s *agentMock) CancelExport() error {
+	if !s.exporting {
+		return errors.New("no export in progress")
+	}
+	s.cancelled++
+	return nil
+}
+
+func (s *agentMock) QueueExport(integration string, reprocessHistorical bool) error {
+	if reprocessHistorical {
+		integration += ":historical"
+	}
+	s.queued = append(s.queued, integration)
+	return nil
+}
+
+func (s *agentMock) ForceHistorical(integration string) error {
+	s.historical = append(s.historical, integration)
+	return nil
+}
+
+func (s *agentMock) Metrics() (metrics.Snapshot, error) {
+	r := metrics.NewRegistry()
+	r.Counter("requests_total", "Number of requests").Inc(metrics.Labels{"integration": "github"})
+	return r.Snapshot(), nil
+}
+
+func newTestServer(agent Agent) *httptest.Server {
+	s := New(Opts{Logger: hclog.NewNullLogger(), Addr: "localhost:0", Agent: agent})
+	return httptest.NewServer(s.Handler())
+}
+
+func request(t *testing.T, method string, url string, res interface{}) int {
+	t.Helper()
+	req, err := http.NewRequest(method, url, nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	req.Header.Set(ActionHeader, "1")
+	resp, err := http.DefaultClient.Do(req)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer resp.Body.Close()
+	b, err := ioutil.ReadAll(resp.Body)
+	if err != nil {
+		t.Fatal(err)
+	}
+	err = json.Unmarshal(b, res)
+	if err != nil {
+		t.Fatal(err, string(b))
+	}
+	return resp.StatusCode
+}
+
+func TestStatus(t *testing.T) {
+	assert := assert.New(t)
+	agent := &agentMock{exporting: true}
+	srv := newTestServer(agent)
+	defer srv.Close()
+
+	var res Status
+	code := request(t, http.MethodGet, srv.URL+"/status", &res)
+	assert.Equal(http.StatusOK, code)
+	assert.Equal("v1", res.Version)
+	assert.True(res.Exporting)
+	assert.Equal("j1", res.Export.Current.JobID)
+
+	var r result
+	code = request(t, http.MethodPost, srv.URL+"/status", &r)
+	assert.Equal(http.StatusMethodNotAllowed, code)
+	assert.NotEmpty(r.Error)
+}
+
+func TestActions(t *testing.T) {
+	assert := assert.New(t)
+	agent := &agentMock{}
+	srv := newTestServer(agent)
+	defer srv.Close()
+
+	var r result
+	code := request(t, http.MethodPost, srv.URL+"/export/cancel", &r)
+	assert.Equal(http.StatusInternalServerError, code)
+	assert.Equal("no export in progress", r.Error)
+
+	agent.exporting = true
+	r = result{}
+	code = request(t, http.MethodPost, srv.URL+"/export/cancel", &r)
+	assert.Equal(http.StatusOK, code)
+	assert.True(r.OK)
+	assert.Equal(1, agent.cancelled)
+
+	r = result{}
+	code = request(t, http.MethodPost, srv.URL+"/export", &r)
+	assert.Equal(http.StatusBadRequest, code)
+
+	request(t, http.MethodPost, srv.URL+"/export?integration=jira", &r)
+	request(t, http.MethodPost, srv.URL+"/export?integration=github&reprocess_historical=true", &r)
+	assert.Equal([]string{"jira", "github:historical"}, agent.queued)
+
+	r = result{}
+	code = request(t, http.MethodPost, srv.URL+"/force-historical?integration=jira", &r)
+	assert.Equal(http.StatusOK, code)
+	assert.Equal([]string{"jira"}, agent.historical)
+}
+
+func TestActionsRequireHeader(t *testing.T) {
+	assert := assert.New(t)
+	agent := &agentMock{exporting: true}
+	srv := newTestServer(agent)
+	defer srv.Close()
+
+	for _, path := range []string{"/export/cancel", "/export?integration=jira", "/force-historical?integration=jira"} {
+		// simple form post, which browsers send cross-origin without preflight
+		resp, err := http.Post(srv.URL+path, "application/x-www-form-urlencoded", nil)
+		if err != nil {
+			t.Fatal(err)
+		}
+		resp.Body.Close()
+		assert.Equal(http.StatusForbidden, resp.StatusCode, path)
+	}
+	assert.Equal(0, agent.cancelled)
+	assert.Empty(agent.queued)
+	assert.Empty(agent.historical)
+}
+
+func TestMetrics(t *testing.T) {
+	srv := newTestServer(&agentMock{})
+	defer srv.Close()
+
+	resp, err := http.Get(srv.URL + "/metrics")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer resp.Body.Close()
+	b, err := ioutil.ReadAll(resp.Body)
+	if err != nil {
+		t.Fatal(err)
+	}
+	assert.Equal(t, metrics.ContentType, resp.Header.Get("Content-Type"))
+	assert.Contains(t, string(b), `requests_total{integration="github"} 1`)
+}
+
+func TestListen(t *testing.T) {
+	_, err := listen("0.0.0.0:7733")
+	assert.Error(t, err)
+	_, err = listen("example.com:7733")
+	assert.Error(t, err)
+
+	l, err := listen("127.0.0.1:0")
+	if err != nil {
+		t.Fatal(err)
+	}
+	l.Close()
+
+	dir, err := ioutil.TempDir("", "statusapi")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(dir)
+	loc := filepath.Join(dir, "agent.sock")
+	l, err = listen("unix:" + loc)
+	if err != nil {
+		t.Fatal(err)
+	}
+	l.Close()
+	// stale socket is removed on start
+	l, err = listen("unix:" + loc)
+	if err != nil {
+		t.Fatal(err)
+	}
+	l.Close()
+}
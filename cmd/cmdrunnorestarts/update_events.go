@@ -150,6 +150,16 @@ func (s *runner) updateTo(version string) (oldVersion string, updated bool, rerr
 		}
 	}
 
+	// pooled plugins keep integration binaries open
+	if s.plugins != nil {
+		s.plugins.Restart()
+	}
+
+	s.setUpdateStarted(version)
+	defer func() {
+		s.setUpdateDone(rerr)
+	}()
+
 	upd := updater.New(s.logger, s.fsconf, s.conf)
 	err := upd.Update(version)
 	if err != nil {
@@ -16,6 +16,7 @@ import (
 	"runtime"
 	"strconv"
 	"strings"
+	"time"
 
 	pstrings "github.com/pinpt/go-common/v10/strings"
 
@@ -155,7 +156,54 @@ func (s *Updater) downloadIntegrations(version string, dir string) error {
 			return err
 		}
 	}
-	return nil
+	return ioutil.WriteFile(filepath.Join(integrationsDir, integrationsVersionFile), []byte(version), 0644)
+}
+
+// integrationsVersionFile is written next to downloaded integration binaries
+const integrationsVersionFile = "version.txt"
+
+// IntegrationBinary describes installed integration
+type IntegrationBinary struct {
+	Name string `json:"name"`
+	// Version is the version of downloaded integrations. Empty if integrations were installed manually.
+	Version  string    `json:"version"`
+	Modified time.Time `json:"modified"`
+}
+
+// Integrations returns the list of installed integration binaries
+func (s *Updater) Integrations() (res []IntegrationBinary, _ error) {
+	dir := s.integrationsParentDir
+	// integrations are in bin subdir if they were downloaded by updater
+	ok, err := fs.Exists(s.integrationsSubDir)
+	if err != nil {
+		return nil, err
+	}
+	if ok {
+		dir = s.integrationsSubDir
+	}
+	files, err := ioutil.ReadDir(dir)
+	if err != nil {
+		if os.IsNotExist(err) {
+			return nil, nil
+		}
+		return nil, err
+	}
+	version := ""
+	b, err := ioutil.ReadFile(filepath.Join(dir, integrationsVersionFile))
+	if err == nil {
+		version = strings.TrimSpace(string(b))
+	}
+	for _, f := range files {
+		if f.IsDir() || f.Name() == integrationsVersionFile {
+			continue
+		}
+		bin := IntegrationBinary{}
+		bin.Name = strings.TrimSuffix(f.Name(), ".exe")
+		bin.Version = version
+		bin.Modified = f.ModTime()
+		res = append(res, bin)
+	}
+	return
 }
 
 func (s *Updater) updateAgent(version, downloadDir string) error {
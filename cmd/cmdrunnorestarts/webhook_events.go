@@ -12,13 +12,19 @@ import (
 	"encoding/json"
 	"errors"
 	"fmt"
+	"io/ioutil"
+	"os"
+	"sync"
 	"time"
 
 	"github.com/pinpt/agent/cmd/cmdmutate"
 	"github.com/pinpt/agent/cmd/cmdrunnorestarts/inconfig"
 	"github.com/pinpt/agent/cmd/cmdrunnorestarts/subcommand"
 	"github.com/pinpt/agent/cmd/cmdwebhook"
+	"github.com/pinpt/agent/cmd/pkg/directexport"
 	"github.com/pinpt/agent/pkg/date"
+	"github.com/pinpt/agent/pkg/webhookhistory"
+	"github.com/pinpt/agent/rpcdef"
 	"github.com/pinpt/integration-sdk/agent"
 
 	"github.com/pinpt/go-common/v10/datamodel"
@@ -143,36 +149,134 @@ func (s *runner) handleWebhookEvents(ctx context.Context) (closefunc, error) {
 
 }
 
-func (s *runner) execWebhook(ctx context.Context, config inconfig.IntegrationAgent, messageID string, data cmdwebhook.Data) (res cmdmutate.Result, _ error) {
-	integrations := []inconfig.IntegrationAgent{config}
+func (s *runner) execWebhook(ctx context.Context, config inconfig.IntegrationAgent, messageID string, data cmdwebhook.Data) (res cmdmutate.Result, rerr error) {
+	body, err := json.Marshal(data.Body)
+	if err != nil {
+		return res, err
+	}
+
+	started := time.Now()
+	defer func() {
+		s.addWebhookToHistory(config, messageID, data.Headers, body, res, rerr, time.Since(started))
+	}()
+
+	s.logger.Debug("executing webhook", "integration", config.Name, "message_id", messageID)
+
+	retErr := func(err error) (cmdmutate.Result, error) {
+		res.Error = fmt.Sprintf("%v (%v)", err, config.Name)
+		s.logger.Debug("executing webhook", "success", res.Success, "err", res.Error)
+		return res, nil
+	}
+
+	filter, err := directexport.NewObjectFilter(s.logger, s.agentConfig)
+	if err != nil {
+		return res, err
+	}
+
+	// git repos are processed in a separate process after plugin call, same as in exports
+	var reposMu sync.Mutex
+	var repos []rpcdef.GitRepoFetch
+	exportGitRepo := func(fetch rpcdef.GitRepoFetch) error {
+		reposMu.Lock()
+		defer reposMu.Unlock()
+		repos = append(repos, fetch)
+		return nil
+	}
+
+	res0, err := s.plugins.Webhook(ctx, config, data.Headers, string(body), exportGitRepo)
+	if err != nil {
+		return retErr(err)
+	}
+	if res0.Error != "" {
+		return retErr(errors.New(res0.Error))
+	}
+
+	objs := res0.MutatedObjects
+	if objs == nil {
+		objs = rpcdef.MutatedObjects{}
+	}
+	// git objects are filtered in subcommand
+	filter.ApplyMutated(objs)
+	filter.LogReport()
+
+	if len(repos) != 0 {
+		gitObjs, err := s.execWebhookGit(ctx, config, messageID, repos)
+		if err != nil {
+			return retErr(fmt.Errorf("git processing failed, err: %v", err))
+		}
+		for k, v := range gitObjs {
+			objs[k] = append(objs[k], v...)
+		}
+	}
+
+	res.Success = true
+	res.MutatedObjects = objs
+	s.logger.Debug("executing webhook", "success", res.Success)
+
+	return res, nil
+}
 
+// execWebhookGit runs git processing for repos requested by webhook in a subcommand
+func (s *runner) execWebhookGit(ctx context.Context, config inconfig.IntegrationAgent, messageID string, repos []rpcdef.GitRepoFetch) (rpcdef.MutatedObjects, error) {
 	c, err := subcommand.New(subcommand.Opts{
 		Logger:            s.logger,
 		Tmpdir:            s.fsconf.Temp,
 		IntegrationConfig: s.agentConfig,
 		AgentConfig:       s.conf,
-		Integrations:      integrations,
+		Integrations:      []inconfig.IntegrationAgent{config},
 		DeviceInfo:        s.deviceInfo,
 	})
-
 	if err != nil {
-		return res, err
+		return nil, err
 	}
 
-	dataJSON, err := json.Marshal(data)
+	// repos contain access credentials, pass them in a file instead of args
+	err = os.MkdirAll(s.fsconf.Temp, 0777)
 	if err != nil {
-		return res, err
+		return nil, err
+	}
+	f, err := ioutil.TempFile(s.fsconf.Temp, "")
+	if err != nil {
+		return nil, err
+	}
+	defer os.Remove(f.Name())
+	err = json.NewEncoder(f).Encode(repos)
+	if err != nil {
+		f.Close()
+		return nil, err
+	}
+	err = f.Close()
+	if err != nil {
+		return nil, err
 	}
 
-	s.logger.Debug("executing webhook", "integration", config.Name, "data", string(dataJSON))
-
-	err = c.Run(ctx, "webhook", messageID, &res, "--data", string(dataJSON))
-
-	s.logger.Debug("executing webhook", "success", res.Success, "err", res.Error)
-
+	var res cmdwebhook.GitResult
+	err = c.Run(ctx, "webhook-git", messageID, &res, "--repos-file", f.Name())
 	if err != nil {
-		return res, err
+		return nil, err
+	}
+	if res.Error != "" {
+		return nil, errors.New(res.Error)
 	}
+	return res.MutatedObjects, nil
+}
 
-	return res, nil
+func (s *runner) addWebhookToHistory(config inconfig.IntegrationAgent, messageID string, headers map[string]string, body []byte, res cmdmutate.Result, err error, dur time.Duration) {
+	rec := webhookhistory.Record{}
+	rec.Integration = config.Name
+	rec.IntegrationID = config.ID
+	rec.MessageID = messageID
+	rec.Headers = headers
+	rec.Body = body
+	rec.DurationMs = dur.Milliseconds()
+	rec.Success = res.Success
+	rec.Error = res.Error
+	if err != nil {
+		rec.Error = err.Error()
+	}
+	rec.SetMutatedObjects(res.MutatedObjects)
+	_, err = webhookhistory.New(s.fsconf.WebhookHistory, 0).Add(rec)
+	if err != nil {
+		s.logger.Error("could not save webhook to history", "err", err)
+	}
 }
@@ -0,0 +1,164 @@
+package cmdrunnorestarts
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"time"
+
+	"github.com/pinpt/agent/cmd/cmdrunnorestarts/exporter/fsqueue"
+	"github.com/pinpt/agent/cmd/cmdrunnorestarts/inconfig"
+	"github.com/pinpt/agent/cmd/cmdrunnorestarts/webhookserver"
+	"github.com/pinpt/agent/cmd/cmdwebhook"
+	"github.com/pinpt/agent/pkg/date"
+	"github.com/pinpt/agent/pkg/structmarshal"
+	"github.com/pinpt/integration-sdk/agent"
+)
+
+// webhookQueueOpts returns the options for the queue of webhooks received by built-in webhook server. Retried for a few hours, since integration config is only available after the first export request after enroll.
+func (s *runner) webhookQueueOpts() fsqueue.Opts {
+	opts := fsqueue.Opts{}
+	opts.Logger = s.logger.Named("webhook-queue")
+	opts.File = s.fsconf.WebhookQueueFile
+	opts.DeadLetterFile = s.fsconf.WebhookQueueDeadLetterFile
+	opts.MaxAttempts = 20
+	opts.MinBackoff = 10 * time.Second
+	opts.MaxBackoff = 10 * time.Minute
+	opts.MaxPending = 10000
+	opts.MaxDead = 1000
+	return opts
+}
+
+// startWebhookServer starts built-in webhook server. Received webhooks are saved to queue on disk and processed one by one using the integration Webhook call.
+func (s *runner) startWebhookServer(ctx context.Context) (closefunc, error) {
+	queue, forwardRequests, err := fsqueue.New(s.webhookQueueOpts())
+	if err != nil {
+		return nil, fmt.Errorf("could not create webhook queue: %v", err)
+	}
+
+	ctx, cancel := context.WithCancel(ctx)
+	queueDone := make(chan struct{})
+	go func() {
+		defer close(queueDone)
+		err := queue.Run(ctx)
+		if err != nil {
+			s.logger.Error("webhook queue stopped", "err", err)
+		}
+	}()
+
+	go func() {
+		for req := range forwardRequests {
+			d := webhookserver.Delivery{}
+			err := structmarshal.MapToStruct(req.Data, &d)
+			if err != nil {
+				s.logger.Error("could not unmarshal webhook delivery from map", "err", err)
+				req.Done <- struct{}{}
+				continue
+			}
+			err = s.processWebhookDelivery(ctx, d)
+			if err != nil {
+				s.logger.Error("could not process webhook delivery", "delivery_id", d.ID, "attempt", req.Attempt, "err", err)
+				req.Failed <- err
+				continue
+			}
+			req.Done <- struct{}{}
+		}
+	}()
+
+	srv, err := webhookserver.New(webhookserver.Opts{
+		Logger:    s.logger,
+		Config:    s.conf.WebhookServer,
+		DedupFile: s.fsconf.WebhookDeliveriesFile,
+		Enqueue: func(d webhookserver.Delivery) error {
+			m, err := structmarshal.StructToMap(d)
+			if err != nil {
+				return err
+			}
+			select {
+			case queue.Input <- m:
+				return nil
+			case <-queueDone:
+				return errors.New("webhook queue is stopped")
+			}
+		},
+	})
+	if err != nil {
+		cancel()
+		return nil, err
+	}
+	err = srv.Start()
+	if err != nil {
+		cancel()
+		return nil, err
+	}
+	return func() {
+		srv.Close()
+		cancel()
+	}, nil
+}
+
+func (s *runner) processWebhookDelivery(ctx context.Context, d webhookserver.Delivery) error {
+	logger := s.logger.With("in", d.IntegrationName, "integration_id", d.IntegrationID, "delivery_id", d.ID)
+	start := time.Now()
+	logger.Info("processing webhook delivery")
+
+	conf, err := s.webhookIntegrationConfig(d.IntegrationName, d.IntegrationID)
+	if err != nil {
+		return err
+	}
+
+	data := cmdwebhook.Data{}
+	data.Headers = d.Headers
+	err = json.Unmarshal([]byte(d.Body), &data.Body)
+	if err != nil {
+		// retrying would not help
+		logger.Error("webhook body is not valid json, skipping", "err", err)
+		return nil
+	}
+
+	res, err := s.execWebhook(ctx, conf, d.ID, data)
+	if err != nil {
+		return err
+	}
+	if res.Error != "" {
+		return errors.New(res.Error)
+	}
+
+	resp := &agent.WebhookResponse{}
+	resp.Success = true
+	mutatedObjectsJSON, err := json.Marshal(res.MutatedObjects)
+	if err != nil {
+		return err
+	}
+	resp.UpdatedObjects = string(mutatedObjectsJSON)
+	date.ConvertToModel(d.Received, &resp.AgentReceivedDate)
+	date.ConvertToModel(time.Now(), &resp.AgentResponseSentDate)
+	date.ConvertToModel(time.Now(), &resp.EventDate)
+	err = s.sendEventAppendingDeviceInfoDefault(ctx, resp)
+	if err != nil {
+		return fmt.Errorf("could not send webhook response: %v", err)
+	}
+	logger.Info("processed webhook delivery", "dur", time.Since(start).String())
+	return nil
+}
+
+// webhookIntegrationConfig returns integration config from extra_integrations or from the last export request received from the server, which is saved in state.
+func (s *runner) webhookIntegrationConfig(name string, id string) (res inconfig.IntegrationAgent, _ error) {
+	for _, in := range s.conf.ExtraIntegrations {
+		if in.ID == id || (in.ID == "" && in.Name == id) {
+			return in, nil
+		}
+	}
+	conf, ok, err := s.exporter.IntegrationConfig(id)
+	if err != nil {
+		return res, err
+	}
+	if !ok {
+		return res, fmt.Errorf("integration %v %v was not in the last export request, will retry after the next export", name, id)
+	}
+	if conf.Name != name {
+		return res, fmt.Errorf("integration %v has name %v, but webhook was sent to %v", id, conf.Name, name)
+	}
+	return conf, nil
+}
@@ -0,0 +1,159 @@
+package webhookserver
+
+import (
+	"bufio"
+	"bytes"
+	"encoding/json"
+	"os"
+	"path/filepath"
+	"sort"
+	"time"
+
+	"github.com/pinpt/agent/pkg/fs"
+)
+
+// dedupRetention is how long delivery ids are kept. Source systems retry failed deliveries within hours, redeliveries requested manually later are processed again.
+const dedupRetention = 72 * time.Hour
+
+// dedupMax limits the number of stored delivery ids
+const dedupMax = 50000
+
+// dedupCompactAfter is the number of records appended to journal after which it is compacted
+const dedupCompactAfter = 1000
+
+// dedupRecord is one line in the journal file
+type dedupRecord struct {
+	ID       string    `json:"id"`
+	Received time.Time `json:"received"`
+}
+
+// dedupStore keeps ids of received deliveries on disk. Ids are appended to journal file, which is compacted by rewriting ids that are not expired when it grows too large. Not safe for concurrent use.
+type dedupStore struct {
+	loc      string
+	f        *os.File
+	ids      map[string]time.Time
+	appended int
+}
+
+func newDedupStore(loc string) (*dedupStore, error) {
+	s := &dedupStore{}
+	s.loc = loc
+	var err error
+	s.ids, err = readDedupJournal(loc)
+	if err != nil {
+		return nil, err
+	}
+	// compact on open, this also removes incomplete last line if process was killed while writing
+	err = s.compact(time.Now())
+	if err != nil {
+		return nil, err
+	}
+	return s, nil
+}
+
+// readDedupJournal returns ids saved in journal. Lines that could not be parsed, which could happen if write was interrupted, are skipped.
+func readDedupJournal(loc string) (map[string]time.Time, error) {
+	res := map[string]time.Time{}
+	f, err := os.Open(loc)
+	if err != nil {
+		if os.IsNotExist(err) {
+			return res, nil
+		}
+		return nil, err
+	}
+	defer f.Close()
+	sc := bufio.NewScanner(f)
+	for sc.Scan() {
+		var rec dedupRecord
+		err := json.Unmarshal(sc.Bytes(), &rec)
+		if err != nil || rec.ID == "" {
+			continue
+		}
+		res[rec.ID] = rec.Received
+	}
+	return res, sc.Err()
+}
+
+// Seen returns true if delivery id was received within dedupRetention
+func (s *dedupStore) Seen(id string, now time.Time) bool {
+	received, ok := s.ids[id]
+	return ok && now.Sub(received) <= dedupRetention
+}
+
+// Add saves delivery id. Not synced to disk, if process is killed only the redeliveries of the last webhooks could be processed twice.
+func (s *dedupStore) Add(id string, now time.Time) error {
+	s.ids[id] = now
+	b, err := json.Marshal(dedupRecord{ID: id, Received: now})
+	if err != nil {
+		return err
+	}
+	b = append(b, '\n')
+	_, err = s.f.Write(b)
+	if err != nil {
+		return err
+	}
+	s.appended++
+	if s.appended < dedupCompactAfter {
+		return nil
+	}
+	return s.compact(now)
+}
+
+// compact removes expired ids, keeps at most dedupMax of the latest ids and rewrites journal with them
+func (s *dedupStore) compact(now time.Time) error {
+	if s.f != nil {
+		err := s.f.Close()
+		if err != nil {
+			return err
+		}
+		s.f = nil
+	}
+	var recs []dedupRecord
+	for id, received := range s.ids {
+		if now.Sub(received) > dedupRetention {
+			delete(s.ids, id)
+			continue
+		}
+		recs = append(recs, dedupRecord{ID: id, Received: received})
+	}
+	sort.Slice(recs, func(i, j int) bool {
+		return recs[i].Received.Before(recs[j].Received)
+	})
+	if len(recs) > dedupMax {
+		for _, rec := range recs[:len(recs)-dedupMax] {
+			delete(s.ids, rec.ID)
+		}
+		recs = recs[len(recs)-dedupMax:]
+	}
+
+	err := os.MkdirAll(filepath.Dir(s.loc), 0777)
+	if err != nil {
+		return err
+	}
+	buf := &bytes.Buffer{}
+	for _, rec := range recs {
+		b, err := json.Marshal(rec)
+		if err != nil {
+			return err
+		}
+		buf.Write(b)
+		buf.WriteByte('\n')
+	}
+	err = fs.WriteToTempAndRename(buf, s.loc)
+	if err != nil {
+		return err
+	}
+	s.appended = 0
+	s.f, err = os.OpenFile(s.loc, os.O_APPEND|os.O_WRONLY, 0644)
+	return err
+}
+
+// Close closes the journal file
+func (s *dedupStore) Close() error {
+	if s.f == nil {
+		return nil
+	}
+	err := s.f.Close()
+	s.f = nil
+	return err
+}
@@ -0,0 +1,124 @@
+package webhookserver
+
+import (
+	"crypto/hmac"
+	"crypto/sha256"
+	"crypto/subtle"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/http"
+	"strings"
+)
+
+// provider is the source system sending webhooks, it defines how deliveries are verified and identified
+type provider string
+
+const (
+	providerGitHub    provider = "github"
+	providerGitLab    provider = "gitlab"
+	providerBitbucket provider = "bitbucket"
+	providerAzure     provider = "azure"
+)
+
+func providerFromIntegration(name string) (provider, error) {
+	switch name {
+	case "github":
+		return providerGitHub, nil
+	case "gitlab":
+		return providerGitLab, nil
+	case "bitbucket", "bitbucket-hosted":
+		return providerBitbucket, nil
+	case "azure":
+		return providerAzure, nil
+	}
+	return "", fmt.Errorf("webhooks are not supported for integration: %v", name)
+}
+
+// ErrUnauthorized is returned when delivery signature, token or credentials do not match
+var ErrUnauthorized = errors.New("invalid webhook signature or credentials")
+
+// verifyHeaders checks token or credentials sent in headers. Called before reading the body, providers signing the body are checked by verifyBody.
+func verifyHeaders(conf Config, p provider, r *http.Request) error {
+	if conf.Secret == "" {
+		return errors.New("webhook secret is not configured")
+	}
+	switch p {
+	case providerGitHub, providerBitbucket:
+		return nil
+	case providerGitLab:
+		if !equal(r.Header.Get("X-Gitlab-Token"), conf.Secret) {
+			return ErrUnauthorized
+		}
+		return nil
+	case providerAzure:
+		user, pass, ok := r.BasicAuth()
+		if !ok || !equal(user, conf.AzureUsername) || !equal(pass, conf.Secret) {
+			return ErrUnauthorized
+		}
+		return nil
+	}
+	return fmt.Errorf("unsupported provider: %v", p)
+}
+
+// verifyBody checks body signature for providers that sign it
+func verifyBody(conf Config, p provider, r *http.Request, body []byte) error {
+	switch p {
+	case providerGitHub:
+		return verifyHMAC(r.Header.Get("X-Hub-Signature-256"), conf.Secret, body)
+	case providerBitbucket:
+		return verifyHMAC(r.Header.Get("X-Hub-Signature"), conf.Secret, body)
+	}
+	return nil
+}
+
+// verifyHMAC checks signature in sha256=<hex> format used by GitHub and Bitbucket
+func verifyHMAC(header string, secret string, body []byte) error {
+	if !strings.HasPrefix(header, "sha256=") {
+		return ErrUnauthorized
+	}
+	got, err := hex.DecodeString(strings.TrimPrefix(header, "sha256="))
+	if err != nil {
+		return ErrUnauthorized
+	}
+	mac := hmac.New(sha256.New, []byte(secret))
+	mac.Write(body)
+	if !hmac.Equal(got, mac.Sum(nil)) {
+		return ErrUnauthorized
+	}
+	return nil
+}
+
+func equal(a, b string) bool {
+	return subtle.ConstantTimeCompare([]byte(a), []byte(b)) == 1
+}
+
+// deliveryID returns the id of delivery set by source system, used to skip redelivered webhooks. Uses the hash of body if id is not available.
+func deliveryID(p provider, r *http.Request, body []byte) string {
+	var id string
+	switch p {
+	case providerGitHub:
+		id = r.Header.Get("X-GitHub-Delivery")
+	case providerGitLab:
+		id = r.Header.Get("X-Gitlab-Event-UUID")
+	case providerBitbucket:
+		id = r.Header.Get("X-Request-UUID")
+		if id == "" {
+			id = r.Header.Get("X-Request-Id")
+		}
+	case providerAzure:
+		// service hook event id
+		var data struct {
+			ID string `json:"id"`
+		}
+		if json.Unmarshal(body, &data) == nil {
+			id = data.ID
+		}
+	}
+	if id != "" {
+		return string(p) + ":" + id
+	}
+	sum := sha256.Sum256(body)
+	return string(p) + ":body:" + hex.EncodeToString(sum[:])
+}
@@ -0,0 +1,247 @@
+// Package webhookserver receives webhooks directly from source systems, for servers behind a firewall that can't deliver webhooks to pinpoint cloud.
+package webhookserver
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io/ioutil"
+	"net"
+	"net/http"
+	"net/url"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/hashicorp/go-hclog"
+	"github.com/pinpt/agent/pkg/webhookhistory"
+)
+
+// Config configures the webhook receiver
+type Config struct {
+	// Addr to listen on, for example :8443. Receiver is disabled when empty.
+	Addr string `json:"addr"`
+	// TLSCertFile and TLSKeyFile enable https, recommended when receiver is reachable from outside of the host
+	TLSCertFile string `json:"tls_cert_file"`
+	TLSKeyFile  string `json:"tls_key_file"`
+	// URL is the receiver url reachable from source systems, for example https://agent.example.com:8443. Integrations register webhooks using this url instead of pinpoint cloud.
+	URL string `json:"url"`
+	// Secret is the GitHub and Bitbucket webhook secret, GitLab secret token and Azure DevOps basic auth password
+	Secret string `json:"secret"`
+	// AzureUsername is the basic auth username for Azure DevOps service hooks
+	AzureUsername string `json:"azure_username"`
+}
+
+// Enabled returns true if receiver should be started
+func (s Config) Enabled() bool {
+	return s.Addr != ""
+}
+
+// IntegrationURL returns the url to register in source system for integration. Returns empty string if URL is not set.
+func (s Config) IntegrationURL(integrationName, integrationID string) string {
+	if s.URL == "" {
+		return ""
+	}
+	return strings.TrimSuffix(s.URL, "/") + pathPrefix + url.PathEscape(integrationName) + "/" + url.PathEscape(integrationID)
+}
+
+const pathPrefix = "/webhooks/"
+
+// maxBodySize limits the accepted payload, so that delivery fits into one line of the webhook queue journal, which is limited to 64MB. JSON escaping could make the body up to 6 times larger. GitHub payloads could be up to 25MB, but are usually much smaller.
+const maxBodySize = 8 << 20
+
+// maxHeaderBytes limits the size of request headers, which are also saved in the webhook queue
+const maxHeaderBytes = 64 << 10
+
+// Delivery is a verified webhook received from source system
+type Delivery struct {
+	ID              string `json:"id"`
+	IntegrationName string `json:"integration_name"`
+	IntegrationID   string `json:"integration_id"`
+	// Headers have lowercase keys, the same as in webhooks forwarded by pinpoint cloud. Credentials are redacted.
+	Headers  map[string]string `json:"headers"`
+	Body     string            `json:"body"`
+	Received time.Time         `json:"received"`
+}
+
+// Opts are options for New call
+type Opts struct {
+	Logger hclog.Logger
+	Config Config
+	// DedupFile stores ids of received deliveries
+	DedupFile string
+	// Enqueue saves delivery in durable queue for processing. Delivery is confirmed to source system only after it returns.
+	Enqueue func(Delivery) error
+}
+
+// Server is the webhook receiver
+type Server struct {
+	opts   Opts
+	logger hclog.Logger
+	srv    *http.Server
+
+	// mu serializes dedup check and enqueue, so that concurrent redeliveries are not processed twice
+	mu    sync.Mutex
+	dedup *dedupStore
+}
+
+// New creates the server
+func New(opts Opts) (*Server, error) {
+	if opts.Logger == nil || !opts.Config.Enabled() || opts.DedupFile == "" || opts.Enqueue == nil {
+		panic("provide all opts")
+	}
+	if (opts.Config.TLSCertFile == "") != (opts.Config.TLSKeyFile == "") {
+		return nil, errors.New("provide both tls_cert_file and tls_key_file")
+	}
+	s := &Server{}
+	s.opts = opts
+	s.logger = opts.Logger.Named("webhook-server")
+	var err error
+	s.dedup, err = newDedupStore(opts.DedupFile)
+	if err != nil {
+		return nil, fmt.Errorf("could not read webhook deliveries file: %v", err)
+	}
+	return s, nil
+}
+
+// Start starts serving requests in background
+func (s *Server) Start() error {
+	l, err := net.Listen("tcp", s.opts.Config.Addr)
+	if err != nil {
+		return err
+	}
+	s.srv = &http.Server{
+		Handler:           s.Handler(),
+		ReadHeaderTimeout: 10 * time.Second,
+		ReadTimeout:       time.Minute,
+		WriteTimeout:      time.Minute,
+		MaxHeaderBytes:    maxHeaderBytes,
+	}
+	conf := s.opts.Config
+	s.logger.Info("webhook server listening", "addr", conf.Addr, "url", conf.URL, "tls", conf.TLSCertFile != "")
+	go func() {
+		var err error
+		if conf.TLSCertFile != "" {
+			err = s.srv.ServeTLS(l, conf.TLSCertFile, conf.TLSKeyFile)
+		} else {
+			err = s.srv.Serve(l)
+		}
+		if err != nil && err != http.ErrServerClosed {
+			s.logger.Error("webhook server stopped", "err", err)
+		}
+	}()
+	return nil
+}
+
+// Close stops the server
+func (s *Server) Close() {
+	if s.srv != nil {
+		err := s.srv.Close()
+		if err != nil {
+			s.logger.Error("could not close webhook server", "err", err)
+		}
+	}
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	err := s.dedup.Close()
+	if err != nil {
+		s.logger.Error("could not close webhook deliveries file", "err", err)
+	}
+}
+
+// Handler returns handler for /webhooks/<integration name>/<integration id>
+func (s *Server) Handler() http.Handler {
+	mux := http.NewServeMux()
+	mux.HandleFunc(pathPrefix, s.handleWebhook)
+	return mux
+}
+
+type result struct {
+	OK        bool   `json:"ok"`
+	Duplicate bool   `json:"duplicate,omitempty"`
+	Error     string `json:"error,omitempty"`
+}
+
+func (s *Server) handleWebhook(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodPost {
+		s.write(w, http.StatusMethodNotAllowed, result{Error: "method not allowed, use " + http.MethodPost})
+		return
+	}
+	parts := strings.Split(strings.TrimPrefix(r.URL.Path, pathPrefix), "/")
+	if len(parts) != 2 || parts[0] == "" || parts[1] == "" {
+		s.write(w, http.StatusNotFound, result{Error: "use " + pathPrefix + "<integration name>/<integration id>"})
+		return
+	}
+	name, id := parts[0], parts[1]
+	logger := s.logger.With("in", name, "integration_id", id)
+
+	p, err := providerFromIntegration(name)
+	if err != nil {
+		s.write(w, http.StatusNotFound, result{Error: err.Error()})
+		return
+	}
+	// check token and credentials before reading the body, body signature can only be checked after
+	err = verifyHeaders(s.opts.Config, p, r)
+	if err != nil {
+		logger.Warn("rejected webhook", "remote_addr", r.RemoteAddr, "err", err)
+		s.write(w, http.StatusUnauthorized, result{Error: err.Error()})
+		return
+	}
+	body, err := ioutil.ReadAll(http.MaxBytesReader(w, r.Body, maxBodySize))
+	if err != nil {
+		s.write(w, http.StatusBadRequest, result{Error: "could not read body: " + err.Error()})
+		return
+	}
+	err = verifyBody(s.opts.Config, p, r, body)
+	if err != nil {
+		logger.Warn("rejected webhook", "remote_addr", r.RemoteAddr, "err", err)
+		s.write(w, http.StatusUnauthorized, result{Error: err.Error()})
+		return
+	}
+
+	d := Delivery{}
+	d.ID = deliveryID(p, r, body)
+	d.IntegrationName = name
+	d.IntegrationID = id
+	headers := map[string]string{}
+	for k := range r.Header {
+		headers[strings.ToLower(k)] = r.Header.Get(k)
+	}
+	// deliveries are saved to disk, credentials are not needed after verification
+	d.Headers = webhookhistory.RedactHeaders(headers)
+	d.Body = string(body)
+	d.Received = time.Now()
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if s.dedup.Seen(d.ID, d.Received) {
+		logger.Info("skipping duplicate webhook delivery", "delivery_id", d.ID)
+		s.write(w, http.StatusOK, result{OK: true, Duplicate: true})
+		return
+	}
+	err = s.opts.Enqueue(d)
+	if err != nil {
+		logger.Error("could not queue webhook delivery", "delivery_id", d.ID, "err", err)
+		s.write(w, http.StatusInternalServerError, result{Error: err.Error()})
+		return
+	}
+	err = s.dedup.Add(d.ID, d.Received)
+	if err != nil {
+		// delivery is queued, only redeliveries could be processed twice
+		logger.Error("could not save webhook delivery id", "delivery_id", d.ID, "err", err)
+	}
+	logger.Info("queued webhook delivery", "delivery_id", d.ID)
+	s.write(w, http.StatusAccepted, result{OK: true})
+}
+
+func (s *Server) write(w http.ResponseWriter, code int, data interface{}) {
+	b, err := json.Marshal(data)
+	if err != nil {
+		s.logger.Error("could not marshal webhook server response", "err", err)
+		w.WriteHeader(http.StatusInternalServerError)
+		return
+	}
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(code)
+	w.Write(b)
+}
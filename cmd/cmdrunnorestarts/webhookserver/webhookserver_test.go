@@ -0,0 +1,228 @@
+package webhookserver
+
+import (
+	"crypto/hmac"
+	"crypto/sha256"
+	"encoding/base64"
+	"encoding/hex"
+	"io/ioutil"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"strconv"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/hashicorp/go-hclog"
+	"github.com/stretchr/testify/assert"
+)
+
+const testSecret = "s1"
+
+func newTestServer(t *testing.T, dir string, queued *[]Delivery) *httptest.Server {
+	conf := Config{}
+	conf.Addr = ":0"
+	conf.Secret = testSecret
+	conf.AzureUsername = "pinpoint"
+	srv, err := New(Opts{
+		Logger:    hclog.NewNullLogger(),
+		Config:    conf,
+		DedupFile: filepath.Join(dir, "deliveries.jsonl"),
+		Enqueue: func(d Delivery) error {
+			*queued = append(*queued, d)
+			return nil
+		},
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	return httptest.NewServer(srv.Handler())
+}
+
+func githubSignature(body string) string {
+	mac := hmac.New(sha256.New, []byte(testSecret))
+	mac.Write([]byte(body))
+	return "sha256=" + hex.EncodeToString(mac.Sum(nil))
+}
+
+func post(t *testing.T, u string, body string, header http.Header) int {
+	req, err := http.NewRequest(http.MethodPost, u, strings.NewReader(body))
+	if err != nil {
+		t.Fatal(err)
+	}
+	for k, v := range header {
+		req.Header[k] = v
+	}
+	resp, err := http.DefaultClient.Do(req)
+	if err != nil {
+		t.Fatal(err)
+	}
+	resp.Body.Close()
+	return resp.StatusCode
+}
+
+func TestWebhookGitHub(t *testing.T) {
+	assert := assert.New(t)
+	dir, err := ioutil.TempDir("", "webhookserver")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(dir)
+
+	var queued []Delivery
+	ts := newTestServer(t, dir, &queued)
+	defer ts.Close()
+
+	body := `{"action":"opened"}`
+	header := http.Header{}
+	header.Set("X-GitHub-Event", "pull_request")
+	header.Set("X-GitHub-Delivery", "d1")
+
+	header.Set("X-Hub-Signature-256", "sha256=00")
+	assert.Equal(http.StatusUnauthorized, post(t, ts.URL+"/webhooks/github/i1", body, header))
+	assert.Len(queued, 0)
+
+	header.Set("X-Hub-Signature-256", githubSignature(body))
+	assert.Equal(http.StatusAccepted, post(t, ts.URL+"/webhooks/github/i1", body, header))
+	if !assert.Len(queued, 1) {
+		return
+	}
+	d := queued[0]
+	assert.Equal("github:d1", d.ID)
+	assert.Equal("github", d.IntegrationName)
+	assert.Equal("i1", d.IntegrationID)
+	assert.Equal("pull_request", d.Headers["x-github-event"])
+	assert.Equal(body, d.Body)
+
+	// redelivery is skipped
+	assert.Equal(http.StatusOK, post(t, ts.URL+"/webhooks/github/i1", body, header))
+	assert.Len(queued, 1)
+
+	assert.Equal(http.StatusNotFound, post(t, ts.URL+"/webhooks/jira/i1", body, header))
+}
+
+func TestWebhookAzureRedactsCredentials(t *testing.T) {
+	assert := assert.New(t)
+	dir, err := ioutil.TempDir("", "webhookserver")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(dir)
+
+	var queued []Delivery
+	ts := newTestServer(t, dir, &queued)
+	defer ts.Close()
+
+	body := `{"id":"e1"}`
+	header := http.Header{}
+	header.Set("Authorization", "Basic "+base64.StdEncoding.EncodeToString([]byte("pinpoint:"+testSecret)))
+	assert.Equal(http.StatusAccepted, post(t, ts.URL+"/webhooks/azure/i1", body, header))
+	if !assert.Len(queued, 1) {
+		return
+	}
+	assert.Equal("[redacted]", queued[0].Headers["authorization"])
+
+	big := strings.Repeat("a", maxBodySize+1)
+	assert.Equal(http.StatusBadRequest, post(t, ts.URL+"/webhooks/azure/i1", big, header))
+	assert.Len(queued, 1)
+}
+
+func TestWebhookDedupPersisted(t *testing.T) {
+	assert := assert.New(t)
+	dir, err := ioutil.TempDir("", "webhookserver")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(dir)
+
+	header := http.Header{}
+	header.Set("X-Gitlab-Token", testSecret)
+	header.Set("X-Gitlab-Event-UUID", "u1")
+
+	var queued []Delivery
+	ts := newTestServer(t, dir, &queued)
+	assert.Equal(http.StatusAccepted, post(t, ts.URL+"/webhooks/gitlab/i1", "{}", header))
+	ts.Close()
+
+	ts = newTestServer(t, dir, &queued)
+	defer ts.Close()
+	assert.Equal(http.StatusOK, post(t, ts.URL+"/webhooks/gitlab/i1", "{}", header))
+	assert.Len(queued, 1)
+
+	header.Set("X-Gitlab-Token", "wrong")
+	header.Set("X-Gitlab-Event-UUID", "u2")
+	assert.Equal(http.StatusUnauthorized, post(t, ts.URL+"/webhooks/gitlab/i1", "{}", header))
+}
+
+func TestDedupStore(t *testing.T) {
+	assert := assert.New(t)
+	dir, err := ioutil.TempDir("", "webhookserver")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(dir)
+	loc := filepath.Join(dir, "deliveries.jsonl")
+
+	now := time.Now()
+	s, err := newDedupStore(loc)
+	if err != nil {
+		t.Fatal(err)
+	}
+	for i := 0; i < dedupCompactAfter+1; i++ {
+		err = s.Add(strconv.Itoa(i), now.Add(-dedupRetention+time.Hour))
+		if err != nil {
+			t.Fatal(err)
+		}
+	}
+	err = s.Add("new", now)
+	if err != nil {
+		t.Fatal(err)
+	}
+	assert.True(s.Seen("0", now))
+	assert.True(s.Seen("new", now))
+	// expired ids are not seen even before compaction removes them
+	assert.False(s.Seen("0", now.Add(2*time.Hour)))
+	assert.True(s.Seen("new", now.Add(2*time.Hour)))
+	err = s.Close()
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	s, err = newDedupStore(loc)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer s.Close()
+	assert.True(s.Seen("0", now))
+	assert.True(s.Seen("new", now))
+	assert.Len(s.ids, dedupCompactAfter+2)
+	err = s.compact(now.Add(2 * time.Hour))
+	if err != nil {
+		t.Fatal(err)
+	}
+	assert.Len(s.ids, 1)
+}
+
+func TestVerify(t *testing.T) {
+	assert := assert.New(t)
+	conf := Config{Secret: testSecret, AzureUsername: "pinpoint"}
+	body := []byte(`{"id":"e1"}`)
+
+	r := httptest.NewRequest(http.MethodPost, "/", nil)
+	r.Header.Set("X-Hub-Signature", githubSignature(string(body)))
+	assert.NoError(verifyHeaders(conf, providerBitbucket, r))
+	assert.NoError(verifyBody(conf, providerBitbucket, r, body))
+	assert.Equal(ErrUnauthorized, verifyBody(conf, providerGitHub, r, body))
+
+	r = httptest.NewRequest(http.MethodPost, "/", nil)
+	r.SetBasicAuth("pinpoint", testSecret)
+	assert.NoError(verifyHeaders(conf, providerAzure, r))
+	assert.NoError(verifyBody(conf, providerAzure, r, body))
+	assert.Equal("azure:e1", deliveryID(providerAzure, r, body))
+	r.SetBasicAuth("other", testSecret)
+	assert.Equal(ErrUnauthorized, verifyHeaders(conf, providerAzure, r))
+
+	assert.Error(verifyHeaders(Config{}, providerGitLab, r))
+}
@@ -71,7 +71,7 @@ func Run(ctx context.Context,
 	}
 	logger.Info("uploading export result", "upload_url", uploadURL, "zip_path", zipPath)
 
-	parts, size, err = runUpload(logger, zipPath, uploadURL, apiKey)
+	parts, size, err = UploadZip(logger, zipPath, uploadURL, apiKey)
 	if err != nil {
 		rerr = err
 		return
@@ -87,7 +87,8 @@ func Run(ctx context.Context,
 	return
 }
 
-func runUpload(logger hclog.Logger, zipPath, uploadURL, apiKey string) (parts int, uploadedSize int64, rerr error) {
+// UploadZip uploads zip file created from uploads dir
+func UploadZip(logger hclog.Logger, zipPath, uploadURL, apiKey string) (parts int, uploadedSize int64, rerr error) {
 
 	f, err := os.Open(zipPath)
 	defer f.Close()
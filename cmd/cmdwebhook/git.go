@@ -0,0 +1,94 @@
+package cmdwebhook
+
+import (
+	"encoding/json"
+	"io"
+
+	"github.com/pinpt/agent/cmd/cmdintegration"
+	"github.com/pinpt/agent/cmd/pkg/directexport"
+	"github.com/pinpt/agent/pkg/jsonstore"
+	"github.com/pinpt/agent/rpcdef"
+)
+
+// GitResult is the output of RunGit
+type GitResult struct {
+	MutatedObjects rpcdef.MutatedObjects `json:"mutated_objects"`
+	Error          string                `json:"error"`
+}
+
+// GitOpts are options for RunGit
+type GitOpts struct {
+	cmdintegration.Opts
+	Output io.Writer
+	// Repos are the git repos requested by integration webhook
+	Repos []rpcdef.GitRepoFetch
+}
+
+// RunGit clones and processes git repos requested by webhook and writes GitResult to output. Service runs it as a separate process, same as exports, so that git and ripsrc do not run in the service process.
+func RunGit(opts GitOpts) error {
+	res := GitResult{}
+	data, err := runGit(opts)
+	if err != nil {
+		res.Error = err.Error()
+	} else {
+		res.MutatedObjects = data
+	}
+	b, err := json.Marshal(res)
+	if err != nil {
+		return err
+	}
+	_, err = opts.Output.Write(b)
+	if err != nil {
+		return err
+	}
+	opts.Logger.Info("webhook git processing completed", "repos", len(opts.Repos), "err", res.Error)
+	return nil
+}
+
+func runGit(opts GitOpts) (rpcdef.MutatedObjects, error) {
+	locs, err := opts.AgentConfig.Locs()
+	if err != nil {
+		return nil, err
+	}
+	lastProcessed, err := jsonstore.New(locs.LastProcessedFile)
+	if err != nil {
+		return nil, err
+	}
+	filter, err := directexport.NewObjectFilter(opts.Logger, opts.AgentConfig)
+	if err != nil {
+		return nil, err
+	}
+	exporter := directexport.NewRepoExporter(directexport.RepoExporterOpts{
+		Logger:        opts.Logger,
+		AgentConfig:   opts.AgentConfig,
+		LastProcessed: lastProcessed,
+		Locs:          locs,
+		Filter:        filter,
+	})
+
+	gitExportRes := make(chan directexport.RepoExporterRes)
+	go func() {
+		gitExportRes <- exporter.Run()
+	}()
+	for _, fetch := range opts.Repos {
+		err := exporter.ExportGitRepo(fetch)
+		if err != nil {
+			exporter.Done()
+			<-gitExportRes
+			return nil, err
+		}
+	}
+	exporter.Done()
+	gitRes := <-gitExportRes
+	if gitRes.Err != nil {
+		return nil, gitRes.Err
+	}
+	filter.LogReport()
+
+	err = lastProcessed.Save()
+	if err != nil {
+		opts.Logger.Error("could not save updated last_processed file", "err", err)
+		return nil, err
+	}
+	return gitRes.Data, nil
+}
@@ -8,6 +8,7 @@ import (
 	"time"
 
 	"github.com/pinpt/agent/pkg/jsonstore"
+	"github.com/pinpt/agent/pkg/webhookhistory"
 	"github.com/pinpt/agent/rpcdef"
 
 	"github.com/pinpt/agent/cmd/cmdintegration"
@@ -29,6 +30,8 @@ type Opts struct {
 	cmdintegration.Opts
 	Output io.Writer
 	Data   Data
+	// ReplayOf is the id of the webhook history record being replayed
+	ReplayOf string
 }
 
 func Run(opts Opts) error {
@@ -47,6 +50,7 @@ type export struct {
 	integration cmdintegration.Integration
 
 	exporter *directexport.RepoExporter
+	filter   *directexport.ObjectFilter
 
 	lastProcessed *jsonstore.Store
 }
@@ -69,11 +73,17 @@ func newExport(opts Opts) (*export, error) {
 		return nil, err
 	}
 
+	s.filter, err = directexport.NewObjectFilter(s.Logger, opts.AgentConfig)
+	if err != nil {
+		return nil, err
+	}
+
 	s.exporter = directexport.NewRepoExporter(directexport.RepoExporterOpts{
 		Logger:        s.Logger,
 		AgentConfig:   opts.AgentConfig,
 		LastProcessed: s.lastProcessed,
 		Locs:          s.Locs,
+		Filter:        s.filter,
 	})
 
 	err = s.SetupIntegrations(directexport.AgentDelegateFactory(s.Logger, s))
@@ -94,6 +104,7 @@ func (s *export) ExportGitRepo(fetch rpcdef.GitRepoFetch) error {
 	return s.exporter.ExportGitRepo(fetch)
 }
 func (s *export) runAndPrint() error {
+	started := time.Now()
 	res0, err := s.run()
 
 	res := Result{}
@@ -110,6 +121,8 @@ func (s *export) runAndPrint() error {
 		res.Error = fmt.Sprintf("%v (%v)", res.Error, s.integration.Export.IntegrationDef.Name)
 	}
 
+	s.addToHistory(res, time.Since(started))
+
 	b, err := json.Marshal(res)
 	if err != nil {
 		return err
@@ -126,6 +139,30 @@ func (s *export) runAndPrint() error {
 	return nil
 }
 
+func (s *export) addToHistory(res Result, dur time.Duration) {
+	rec := webhookhistory.Record{}
+	rec.Integration = s.integration.Export.IntegrationDef.Name
+	rec.IntegrationID = s.integration.Export.IntegrationID
+	rec.ReplayOf = s.Opts.ReplayOf
+	rec.Headers = s.Opts.Data.Headers
+	var err error
+	rec.Body, err = json.Marshal(s.Opts.Data.Body)
+	if err != nil {
+		s.Logger.Error("could not marshal webhook body for history", "err", err)
+		return
+	}
+	rec.DurationMs = dur.Milliseconds()
+	rec.Success = res.Success
+	rec.Error = res.Error
+	rec.SetMutatedObjects(res.MutatedObjects)
+	rec, err = webhookhistory.New(s.Locs.WebhookHistory, 0).Add(rec)
+	if err != nil {
+		s.Logger.Error("could not save webhook to history", "err", err)
+		return
+	}
+	s.Logger.Debug("saved webhook to history", "id", rec.ID)
+}
+
 func (s *export) run() (_ rpcdef.WebhookResult, rerr error) {
 	ctx := context.Background()
 	client := s.integration.ILoader.RPCClient()
@@ -168,6 +205,9 @@ func (s *export) run() (_ rpcdef.WebhookResult, rerr error) {
 	if res.MutatedObjects == nil {
 		res.MutatedObjects = rpcdef.MutatedObjects{}
 	}
+	// git objects are filtered when written
+	s.filter.ApplyMutated(res.MutatedObjects)
+	s.filter.LogReport()
 	for k, v := range gitRes.Data {
 		res.MutatedObjects[k] = append(res.MutatedObjects[k], v...)
 	}
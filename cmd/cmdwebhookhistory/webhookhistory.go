@@ -0,0 +1,185 @@
+// Package cmdwebhookhistory contains commands to inspect and replay processed webhooks
+package cmdwebhookhistory
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"sort"
+	"strings"
+	"text/tabwriter"
+	"time"
+
+	"github.com/pinpt/agent/cmd/cmdintegration"
+	"github.com/pinpt/agent/cmd/cmdrunnorestarts/inconfig"
+	"github.com/pinpt/agent/cmd/cmdwebhook"
+	"github.com/pinpt/agent/pkg/aevent"
+	"github.com/pinpt/agent/pkg/agentconf"
+	"github.com/pinpt/agent/pkg/date"
+	"github.com/pinpt/agent/pkg/deviceinfo"
+	"github.com/pinpt/agent/pkg/fsconf"
+	"github.com/pinpt/agent/pkg/webhookhistory"
+	"github.com/pinpt/go-common/v10/event"
+	"github.com/pinpt/integration-sdk/agent"
+)
+
+// List writes processed webhooks, newest first
+func List(locs fsconf.Locs, w io.Writer) error {
+	recs, err := webhookhistory.New(locs.WebhookHistory, 0).List()
+	if err != nil {
+		return err
+	}
+	tw := tabwriter.NewWriter(w, 0, 4, 2, ' ', 0)
+	fmt.Fprintf(tw, "Processed webhooks: %v\n", len(recs))
+	fmt.Fprintf(tw, "  ID\tDATE\tINTEGRATION\tSUCCESS\tDURATION\tOBJECTS\tREPLAY OF\tERROR\n")
+	for _, rec := range recs {
+		fmt.Fprintf(tw, "  %v\t%v\t%v\t%v\t%v\t%v\t%v\t%v\n", rec.ID, rec.Date.Format(time.RFC3339), rec.Integration, rec.Success, time.Duration(rec.DurationMs)*time.Millisecond, formatCounts(rec.MutatedObjects), orDash(rec.ReplayOf), orDash(rec.Error))
+	}
+	return tw.Flush()
+}
+
+func formatCounts(counts map[string]int) string {
+	if len(counts) == 0 {
+		return "-"
+	}
+	var res []string
+	for k, v := range counts {
+		res = append(res, fmt.Sprintf("%v=%v", k, v))
+	}
+	sort.Strings(res)
+	return strings.Join(res, ",")
+}
+
+func orDash(s string) string {
+	if s == "" {
+		return "-"
+	}
+	return s
+}
+
+// Show writes the record with headers and body as json
+func Show(locs fsconf.Locs, id string, w io.Writer) error {
+	rec, err := webhookhistory.New(locs.WebhookHistory, 0).Get(id)
+	if err != nil {
+		return err
+	}
+	b, err := json.MarshalIndent(rec, "", "  ")
+	if err != nil {
+		return err
+	}
+	_, err = w.Write(append(b, '\n'))
+	return err
+}
+
+// ReplayOpts are options for Replay
+type ReplayOpts struct {
+	cmdintegration.Opts
+	// Conf is the agent config used to send the result to pinpoint, not needed when NoUpload is set
+	Conf agentconf.Config
+	// ID is the id of record to replay
+	ID string
+	// NoUpload writes mutated objects to Output instead of sending them to pinpoint
+	NoUpload bool
+	Output   io.Writer
+}
+
+// Replay runs the stored webhook using the current integration binary. Integration config is not stored in history and needs to be passed in Opts.Integrations.
+func Replay(opts ReplayOpts) error {
+	rec, err := webhookhistory.New(fsconf.New(opts.AgentConfig.PinpointRoot).WebhookHistory, 0).Get(opts.ID)
+	if err != nil {
+		return err
+	}
+	in, err := findIntegration(opts.Integrations, rec)
+	if err != nil {
+		return err
+	}
+
+	whOpts := cmdwebhook.Opts{}
+	whOpts.Opts = opts.Opts
+	whOpts.Opts.Integrations = []inconfig.Integration{in}
+	whOpts.ReplayOf = rec.ID
+	whOpts.Data.Headers = rec.Headers
+	err = json.Unmarshal(rec.Body, &whOpts.Data.Body)
+	if err != nil {
+		return fmt.Errorf("stored webhook body is not valid json: %v", err)
+	}
+	out := &bytes.Buffer{}
+	whOpts.Output = out
+
+	opts.Logger.Info("replaying webhook", "id", rec.ID, "integration", rec.Integration)
+	err = cmdwebhook.Run(whOpts)
+	if err != nil {
+		return err
+	}
+	var res cmdwebhook.Result
+	err = json.Unmarshal(out.Bytes(), &res)
+	if err != nil {
+		return fmt.Errorf("could not parse webhook result: %v", err)
+	}
+	if !res.Success {
+		return fmt.Errorf("replayed webhook failed: %v", res.Error)
+	}
+
+	if opts.NoUpload {
+		b, err := json.MarshalIndent(res.MutatedObjects, "", "  ")
+		if err != nil {
+			return err
+		}
+		_, err = opts.Output.Write(append(b, '\n'))
+		return err
+	}
+
+	err = sendResult(opts.Conf, opts.AgentConfig.PinpointRoot, res)
+	if err != nil {
+		return fmt.Errorf("could not send webhook result: %v", err)
+	}
+	opts.Logger.Info("sent replayed webhook result", "id", rec.ID)
+	return nil
+}
+
+// findIntegration returns the integration matching the record by id, or by name if id does not match
+func findIntegration(ins []inconfig.Integration, rec webhookhistory.Record) (res inconfig.Integration, _ error) {
+	for _, in := range ins {
+		if rec.IntegrationID != "" && in.ID == rec.IntegrationID {
+			return in, nil
+		}
+	}
+	for _, in := range ins {
+		if in.Name == rec.Integration {
+			return in, nil
+		}
+	}
+	return res, fmt.Errorf("webhook was processed by integration %v, pass its config in integrations-json", rec.Integration)
+}
+
+func sendResult(conf agentconf.Config, pinpointRoot string, res cmdwebhook.Result) error {
+	if conf.APIKey == "" || conf.DeviceID == "" {
+		return errors.New("agent is not enrolled, use no-upload")
+	}
+	resp := &agent.WebhookResponse{}
+	resp.Success = true
+	b, err := json.Marshal(res.MutatedObjects)
+	if err != nil {
+		return err
+	}
+	resp.UpdatedObjects = string(b)
+	date.ConvertToModel(time.Now(), &resp.EventDate)
+	ci := deviceinfo.CommonInfo{
+		CustomerID: conf.CustomerID,
+		DeviceID:   conf.DeviceID,
+		SystemID:   conf.SystemID,
+		Root:       pinpointRoot,
+	}
+	ci.AppendCommonInfo(resp)
+	e := event.PublishEvent{
+		Object: resp,
+		Headers: map[string]string{
+			"uuid":        conf.DeviceID,
+			"customer_id": conf.CustomerID,
+		},
+	}
+	return aevent.Publish(context.Background(), e, conf.Channel, conf.APIKey)
+}
@@ -5,21 +5,31 @@ import (
 	"encoding/json"
 	"errors"
 	"fmt"
+	"io/ioutil"
+	"os"
+	"strconv"
+	"strings"
 
 	pservice "github.com/kardianos/service"
+	"github.com/pinpt/agent/cmd/cmdbundle"
 	"github.com/pinpt/agent/cmd/cmdenroll"
 	"github.com/pinpt/agent/cmd/cmdexport"
 	"github.com/pinpt/agent/cmd/cmdexportonboarddata"
+	"github.com/pinpt/agent/cmd/cmdexportqueue"
 	"github.com/pinpt/agent/cmd/cmdforcehistorical"
 	"github.com/pinpt/agent/cmd/cmdmutate"
 	"github.com/pinpt/agent/cmd/cmdrun"
 	"github.com/pinpt/agent/cmd/cmdrunnorestarts"
+	"github.com/pinpt/agent/cmd/cmdrunnorestarts/updater"
 	"github.com/pinpt/agent/cmd/cmdserviceinstall"
 	"github.com/pinpt/agent/cmd/cmdvalidate"
 	"github.com/pinpt/agent/cmd/cmdvalidateconfig"
 	"github.com/pinpt/agent/cmd/cmdwebhook"
+	"github.com/pinpt/agent/cmd/cmdwebhookhistory"
 	"github.com/pinpt/agent/cmd/pkg/cmdlogger"
+	"github.com/pinpt/agent/integrations/pkg/mutate"
 	"github.com/pinpt/agent/pkg/agentconf"
+	"github.com/pinpt/agent/pkg/bundle"
 	"github.com/pinpt/agent/pkg/fsconf"
 	"github.com/pinpt/agent/pkg/service"
 	"github.com/pinpt/agent/rpcdef"
@@ -130,6 +140,7 @@ var cmdExport = &cobra.Command{
 		logger, opts2 := integrationCommandOpts(cmd)
 		opts.Opts = opts2
 		opts.ReprocessHistorical, _ = cmd.Flags().GetBool("reprocess-historical")
+		opts.Plan, _ = cmd.Flags().GetBool("plan")
 
 		outputFile, _ := cmd.Flags().GetString("output-file")
 		if outputFile != "" {
@@ -150,6 +161,7 @@ func init() {
 	integrationCommandFlags(cmd)
 	flagOutputFile(cmd)
 	cmd.Flags().Bool("reprocess-historical", false, "Set to true to discard incremental checkpoint and reprocess historical instead.")
+	cmd.Flags().Bool("plan", false, "Only list repos/projects that would be exported with estimated counts and api calls, without exporting any data.")
 	cmdRoot.AddCommand(cmd)
 }
 
@@ -267,6 +279,36 @@ func init() {
 	cmdRoot.AddCommand(cmd)
 }
 
+var cmdMutationsList = &cobra.Command{
+	Use:   "mutations-list",
+	Short: "Shows mutations supported by integration with json schema of their input",
+	Args:  cobra.NoArgs,
+	Run: func(cmd *cobra.Command, args []string) {
+		logger, baseOpts := integrationCommandOpts(cmd)
+		opts := cmdmutate.Opts{}
+		opts.Opts = baseOpts
+
+		outputFile := newOutputFile(logger, cmd)
+		defer outputFile.Close()
+		opts.Output = outputFile.Writer
+
+		opts.Mutation.Fn = mutate.FnListMutations
+		opts.Mutation.Data = map[string]interface{}{}
+
+		err := cmdmutate.Run(opts)
+		if err != nil {
+			exitWithErr(logger, err)
+		}
+	},
+}
+
+func init() {
+	cmd := cmdMutationsList
+	integrationCommandFlags(cmd)
+	flagOutputFile(cmd)
+	cmdRoot.AddCommand(cmd)
+}
+
 var cmdWebhook = &cobra.Command{
 	Use:    "webhook",
 	Hidden: true,
@@ -309,6 +351,50 @@ func init() {
 	cmdRoot.AddCommand(cmd)
 }
 
+var cmdWebhookGit = &cobra.Command{
+	Use:    "webhook-git",
+	Hidden: true,
+	Short:  "Process git repos requested by webhook",
+	Args:   cobra.NoArgs,
+	Run: func(cmd *cobra.Command, args []string) {
+		logger, baseOpts := integrationCommandOpts(cmd)
+		opts := cmdwebhook.GitOpts{}
+		opts.Opts = baseOpts
+
+		outputFile := newOutputFile(logger, cmd)
+		defer outputFile.Close()
+		opts.Output = outputFile.Writer
+
+		{
+			v, _ := cmd.Flags().GetString("repos-file")
+			if v == "" {
+				exitWithErr(logger, errors.New("provide repos-file arg"))
+			}
+			b, err := ioutil.ReadFile(v)
+			if err != nil {
+				exitWithErr(logger, fmt.Errorf("repos-file does not point to a correct file, err %v", err))
+			}
+			err = json.Unmarshal(b, &opts.Repos)
+			if err != nil {
+				exitWithErr(logger, fmt.Errorf("repos-file contains invalid json: %v", err))
+			}
+		}
+
+		err := cmdwebhook.RunGit(opts)
+		if err != nil {
+			exitWithErr(logger, err)
+		}
+	},
+}
+
+func init() {
+	cmd := cmdWebhookGit
+	integrationCommandFlags(cmd)
+	flagOutputFile(cmd)
+	cmd.Flags().String("repos-file", "", "File with git repos to process in json format")
+	cmdRoot.AddCommand(cmd)
+}
+
 func envBasedOnAgentConfig(cmd *cobra.Command) (_ cmdlogger.Logger, _ agentconf.Config, pinpointRoot string) {
 	pinpointRoot, err := getPinpointRoot(cmd)
 	if err != nil {
@@ -493,3 +579,267 @@ func init() {
 	integrationCommandFlags(cmd)
 	cmdRoot.AddCommand(cmd)
 }
+
+var cmdExportQueueList = &cobra.Command{
+	Use:   "export-queue-list",
+	Short: "Shows pending export requests and requests that failed too many times",
+	Args:  cobra.NoArgs,
+	Run: func(cmd *cobra.Command, args []string) {
+		logger := cmdlogger.NewLogger(cmd)
+		pinpointRoot, err := getPinpointRoot(cmd)
+		if err != nil {
+			exitWithErr(logger, err)
+		}
+		fsconf := fsconf.New(pinpointRoot)
+		if err := cmdexportqueue.List(logger, fsconf, os.Stdout); err != nil {
+			exitWithErr(logger, err)
+		}
+	},
+}
+
+func init() {
+	cmd := cmdExportQueueList
+	integrationCommandFlags(cmd)
+	cmdRoot.AddCommand(cmd)
+}
+
+var cmdExportQueueRequeue = &cobra.Command{
+	Use:   "export-queue-requeue <id>",
+	Short: "Moves export request that failed too many times back to the queue. Stop the agent service before running, queue is read on service start.",
+	Args:  cobra.ExactArgs(1),
+	Run: func(cmd *cobra.Command, args []string) {
+		logger := cmdlogger.NewLogger(cmd)
+		pinpointRoot, err := getPinpointRoot(cmd)
+		if err != nil {
+			exitWithErr(logger, err)
+		}
+		id, err := strconv.Atoi(args[0])
+		if err != nil {
+			exitWithErr(logger, fmt.Errorf("invalid id: %v", err))
+		}
+		fsconf := fsconf.New(pinpointRoot)
+		if err := cmdexportqueue.Requeue(logger, fsconf, id); err != nil {
+			exitWithErr(logger, err)
+		}
+	},
+}
+
+func init() {
+	cmd := cmdExportQueueRequeue
+	integrationCommandFlags(cmd)
+	cmdRoot.AddCommand(cmd)
+}
+
+// bundleKey returns the key from key-file flag or from agent config
+func bundleKey(cmd *cobra.Command, conf bundle.Config) (string, error) {
+	keyFile, _ := cmd.Flags().GetString("key-file")
+	if keyFile == "" {
+		if conf.Key == "" {
+			return "", errors.New("provide key-file or set bundle key in config")
+		}
+		return conf.Key, nil
+	}
+	b, err := ioutil.ReadFile(keyFile)
+	if err != nil {
+		return "", err
+	}
+	return strings.TrimSpace(string(b)), nil
+}
+
+var cmdExportBundle = &cobra.Command{
+	Use:   "export-bundle",
+	Short: "Packages the results of the last export into encrypted bundle for manual transfer from air-gapped hosts",
+	Args:  cobra.NoArgs,
+	Run: func(cmd *cobra.Command, args []string) {
+		logger, agentConf, pinpointRoot := envBasedOnAgentConfig(cmd)
+		locs := fsconf.New(pinpointRoot)
+
+		opts := cmdbundle.ExportOpts{}
+		opts.Logger = logger
+		opts.Locs = locs
+		opts.Conf = agentConf.Bundle
+		if v, _ := cmd.Flags().GetString("dir"); v != "" {
+			opts.Conf.Dir = v
+		}
+		var err error
+		opts.Conf.Key, err = bundleKey(cmd, agentConf.Bundle)
+		if err != nil {
+			exitWithErr(logger, err)
+		}
+		opts.CustomerID = agentConf.CustomerID
+		opts.DeviceID = agentConf.DeviceID
+		opts.AgentVersion = Version
+		opts.JobID, _ = cmd.Flags().GetString("job-id")
+		opts.UploadURL, _ = cmd.Flags().GetString("upload-url")
+		integrations, err := updater.New(logger, locs, agentConf).Integrations()
+		if err != nil {
+			exitWithErr(logger, err)
+		}
+		opts.Integrations = map[string]string{}
+		for _, in := range integrations {
+			opts.Integrations[in.Name] = in.Version
+		}
+		if _, err := cmdbundle.Export(opts); err != nil {
+			exitWithErr(logger, err)
+		}
+	},
+}
+
+func init() {
+	cmd := cmdExportBundle
+	flagPinpointRoot(cmd)
+	cmd.Flags().String("dir", "", "Directory to write bundle to. Defaults to bundle dir from config.")
+	cmd.Flags().String("key-file", "", "File with hex encoded bundle key. Defaults to bundle key from config.")
+	cmd.Flags().String("job-id", "", "Job id of the export, optional.")
+	cmd.Flags().String("upload-url", "", "Upload url to use when bundle is uploaded, could also be passed to upload-bundle.")
+	cmdRoot.AddCommand(cmd)
+}
+
+var cmdUploadBundle = &cobra.Command{
+	Use:     "upload-bundle <file>",
+	Aliases: []string{"import-bundle"},
+	Short:   "Verifies export bundle created on air-gapped host, uploads it and writes ack file to transfer back",
+	Args:    cobra.ExactArgs(1),
+	Run: func(cmd *cobra.Command, args []string) {
+		logger := cmdlogger.NewLogger(cmd)
+		opts := cmdbundle.UploadOpts{}
+		opts.Logger = logger
+		opts.File = args[0]
+		var err error
+		opts.Key, err = bundleKey(cmd, bundle.Config{})
+		if err != nil {
+			exitWithErr(logger, err)
+		}
+		opts.APIKey, _ = cmd.Flags().GetString("api-key")
+		if opts.APIKey == "" {
+			exitWithErr(logger, errors.New("provide api-key"))
+		}
+		opts.UploadURL, _ = cmd.Flags().GetString("upload-url")
+		opts.AckFile, _ = cmd.Flags().GetString("ack-file")
+		if _, err := cmdbundle.Upload(opts); err != nil {
+			exitWithErr(logger, err)
+		}
+	},
+}
+
+func init() {
+	cmd := cmdUploadBundle
+	flagsLogger(cmd)
+	cmd.Flags().String("key-file", "", "File with hex encoded bundle key.")
+	cmd.Flags().String("api-key", "", "Agent api key, the same as api_key in agent config.")
+	cmd.Flags().String("upload-url", "", "Overrides upload url from bundle.")
+	cmd.Flags().String("ack-file", "", "Where to write ack file. Defaults to bundle file with .ack extension.")
+	cmdRoot.AddCommand(cmd)
+}
+
+var cmdImportBundleAck = &cobra.Command{
+	Use:   "import-bundle-ack <file>",
+	Short: "Imports ack of uploaded bundle and commits export state when all bundles are uploaded. Stop the agent service before running or copy ack to bundle dir instead, service imports acks from there before each export.",
+	Args:  cobra.ExactArgs(1),
+	Run: func(cmd *cobra.Command, args []string) {
+		logger, agentConf, pinpointRoot := envBasedOnAgentConfig(cmd)
+		key, err := bundleKey(cmd, agentConf.Bundle)
+		if err != nil {
+			exitWithErr(logger, err)
+		}
+		if err := cmdbundle.ImportAck(logger, fsconf.New(pinpointRoot), key, args[0]); err != nil {
+			exitWithErr(logger, err)
+		}
+	},
+}
+
+func init() {
+	cmd := cmdImportBundleAck
+	flagPinpointRoot(cmd)
+	cmd.Flags().String("key-file", "", "File with hex encoded bundle key. Defaults to bundle key from config.")
+	cmdRoot.AddCommand(cmd)
+}
+
+var cmdWebhookHistoryList = &cobra.Command{
+	Use:   "webhook-history-list",
+	Short: "Shows recently processed webhooks",
+	Args:  cobra.NoArgs,
+	Run: func(cmd *cobra.Command, args []string) {
+		logger := cmdlogger.NewLogger(cmd)
+		pinpointRoot, err := getPinpointRoot(cmd)
+		if err != nil {
+			exitWithErr(logger, err)
+		}
+		if err := cmdwebhookhistory.List(fsconf.New(pinpointRoot), os.Stdout); err != nil {
+			exitWithErr(logger, err)
+		}
+	},
+}
+
+func init() {
+	cmd := cmdWebhookHistoryList
+	flagsLogger(cmd)
+	flagPinpointRoot(cmd)
+	cmdRoot.AddCommand(cmd)
+}
+
+var cmdWebhookHistoryShow = &cobra.Command{
+	Use:   "webhook-history-show <id>",
+	Short: "Shows headers, body and result of processed webhook",
+	Args:  cobra.ExactArgs(1),
+	Run: func(cmd *cobra.Command, args []string) {
+		logger := cmdlogger.NewLogger(cmd)
+		pinpointRoot, err := getPinpointRoot(cmd)
+		if err != nil {
+			exitWithErr(logger, err)
+		}
+		if err := cmdwebhookhistory.Show(fsconf.New(pinpointRoot), args[0], os.Stdout); err != nil {
+			exitWithErr(logger, err)
+		}
+	},
+}
+
+func init() {
+	cmd := cmdWebhookHistoryShow
+	flagsLogger(cmd)
+	flagPinpointRoot(cmd)
+	cmdRoot.AddCommand(cmd)
+}
+
+var cmdWebhookHistoryReplay = &cobra.Command{
+	Use:   "webhook-history-replay <id>",
+	Short: "Processes stored webhook again using the current integration binary and sends the result to pinpoint",
+	Args:  cobra.ExactArgs(1),
+	Run: func(cmd *cobra.Command, args []string) {
+		logger, baseOpts := integrationCommandOpts(cmd)
+		if baseOpts.AgentConfig.PinpointRoot == "" {
+			pinpointRoot, err := getPinpointRoot(cmd)
+			if err != nil {
+				exitWithErr(logger, err)
+			}
+			baseOpts.AgentConfig.PinpointRoot = pinpointRoot
+		}
+		opts := cmdwebhookhistory.ReplayOpts{}
+		opts.Opts = baseOpts
+		opts.ID = args[0]
+		opts.NoUpload, _ = cmd.Flags().GetBool("no-upload")
+
+		outputFile := newOutputFile(logger, cmd)
+		defer outputFile.Close()
+		opts.Output = outputFile.Writer
+
+		if !opts.NoUpload {
+			var err error
+			opts.Conf, err = agentconf.Load(fsconf.New(baseOpts.AgentConfig.PinpointRoot).Config2)
+			if err != nil {
+				exitWithErr(logger, fmt.Errorf("could not load agent config, use no-upload if agent is not enrolled: %v", err))
+			}
+		}
+		if err := cmdwebhookhistory.Replay(opts); err != nil {
+			exitWithErr(logger, err)
+		}
+	},
+}
+
+func init() {
+	cmd := cmdWebhookHistoryReplay
+	integrationCommandFlags(cmd)
+	flagOutputFile(cmd)
+	cmd.Flags().Bool("no-upload", false, "Print mutated objects instead of sending them to pinpoint")
+	cmdRoot.AddCommand(cmd)
+}
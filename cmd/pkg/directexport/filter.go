@@ -0,0 +1,79 @@
+package directexport
+
+import (
+	hclog "github.com/hashicorp/go-hclog"
+	"github.com/pinpt/agent/cmd/cmdintegration"
+	"github.com/pinpt/agent/pkg/redact"
+	"github.com/pinpt/agent/pkg/secrets"
+	"github.com/pinpt/agent/rpcdef"
+)
+
+// ObjectFilter redacts secrets and applies redaction policies to objects returned directly to the backend from webhooks and mutations. Regular exports do the same in export session writers.
+type ObjectFilter struct {
+	logger hclog.Logger
+	// redact is nil when there are no redaction policies
+	redact *redact.Engine
+	// secrets is nil when secret scanning is disabled
+	secrets *secrets.Scanner
+}
+
+// NewObjectFilter creates ObjectFilter using redaction and secrets config from agent config
+func NewObjectFilter(logger hclog.Logger, conf cmdintegration.AgentConfig) (*ObjectFilter, error) {
+	s := &ObjectFilter{}
+	s.logger = logger
+	engine, err := redact.New(conf.Redaction)
+	if err != nil {
+		return nil, err
+	}
+	if engine.Enabled() {
+		s.redact = engine
+	}
+	if !conf.Secrets.Disable {
+		s.secrets = secrets.New(conf.Secrets)
+	}
+	return s, nil
+}
+
+// Apply changes objects in place
+func (s *ObjectFilter) Apply(modelName string, objs []map[string]interface{}) {
+	for _, obj := range objs {
+		s.apply(modelName, obj)
+	}
+}
+
+// ApplyMutated changes mutated objects in place. Objects that are not maps are left as is.
+func (s *ObjectFilter) ApplyMutated(objs rpcdef.MutatedObjects) {
+	for modelName, arr := range objs {
+		for _, obj := range arr {
+			m, ok := obj.(map[string]interface{})
+			if !ok {
+				continue
+			}
+			s.apply(modelName, m)
+		}
+	}
+}
+
+func (s *ObjectFilter) apply(modelName string, obj map[string]interface{}) {
+	// scan for secrets before redaction policies, so that findings are reported for original fields
+	if s.secrets != nil {
+		s.secrets.Scan(modelName, obj)
+	}
+	if s.redact != nil {
+		s.redact.Apply(modelName, obj)
+	}
+}
+
+// LogReport logs the fields that were changed
+func (s *ObjectFilter) LogReport() {
+	if s.secrets != nil {
+		if rep := s.secrets.Report(); rep.Total != 0 {
+			s.logger.Warn("Secrets found and redacted in returned objects", "total", rep.Total, "by_rule", rep.ByRule, "findings", rep.Findings)
+		}
+	}
+	if s.redact != nil {
+		if lines := s.redact.ReportLines(); len(lines) != 0 {
+			s.logger.Info("Redacted fields", "changed", lines)
+		}
+	}
+}
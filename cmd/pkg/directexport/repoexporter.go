@@ -21,6 +21,8 @@ type RepoExporterOpts struct {
 	AgentConfig   cmdintegration.AgentConfig
 	LastProcessed *jsonstore.Store
 	Locs          fsconf.Locs
+	// Filter is applied to exported objects, optional
+	Filter *ObjectFilter
 }
 
 type RepoExporter struct {
@@ -55,12 +57,17 @@ func (s *RepoExporter) Done() {
 func (s RepoExporter) Run() (res RepoExporterRes) {
 	s.logger.Debug("running repo exporter")
 
-	writer := NewObjectsWriter()
+	writer := NewObjectsWriter(s.opts.Filter)
 	commitUsers := process.NewCommitUsers()
 
 	for fetch := range s.repoFetch {
 		access := gitclone.AccessDetails{}
 		access.URL = fetch.URL
+		access.Mode = gitclone.AccessMode(fetch.AccessMode)
+		access.Username = fetch.Username
+		access.Password = fetch.Password
+		access.SSHKey = fetch.SSHKey
+		access.SSHKnownHosts = fetch.SSHKnownHosts
 
 		opts := exportrepo.Opts{
 			Logger:     s.logger,
@@ -80,6 +87,14 @@ func (s RepoExporter) Run() (res RepoExporterRes) {
 			SessionRootID: 0,
 
 			CommitUsers: commitUsers,
+
+			CommitFiles: s.opts.AgentConfig.Git.CommitFiles,
+			Tags:        s.opts.AgentConfig.Git.Tags,
+			Mailmap:     !s.opts.AgentConfig.Git.DisableMailmap,
+			AliasFile:   s.opts.AgentConfig.Git.AliasFile,
+
+			Ownership:         s.opts.AgentConfig.Git.Ownership,
+			OwnershipInterval: s.opts.AgentConfig.Git.OwnershipInterval(),
 		}
 		for _, pr1 := range fetch.PRs {
 			pr2 := exportrepo.PR{}
@@ -112,13 +127,16 @@ type session struct {
 }
 
 type ObjectsWriter struct {
+	filter   *ObjectFilter
 	sessions map[expsessions.ID]session
 	lastID   expsessions.ID
 	mu       sync.Mutex
 }
 
-func NewObjectsWriter() *ObjectsWriter {
+// NewObjectsWriter creates ObjectsWriter. Filter is optional.
+func NewObjectsWriter(filter *ObjectFilter) *ObjectsWriter {
 	return &ObjectsWriter{
+		filter:   filter,
 		sessions: map[expsessions.ID]session{},
 	}
 }
@@ -140,6 +158,9 @@ func (s *ObjectsWriter) Write(id expsessions.ID, objs []map[string]interface{})
 	s.mu.Lock()
 	defer s.mu.Unlock()
 	session := s.sessions[id]
+	if s.filter != nil {
+		s.filter.Apply(session.modelType, objs)
+	}
 	session.data = append(session.data, objs...)
 	s.sessions[id] = session
 	return nil
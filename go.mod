@@ -15,6 +15,7 @@ require (
 	github.com/hashicorp/go-plugin v1.3.0
 	github.com/kardianos/service v1.1.0
 	github.com/mattn/go-colorable v0.1.7 // indirect
+	github.com/mattn/go-sqlite3 v1.14.6
 	github.com/mitchellh/go-homedir v1.1.0
 	github.com/mitchellh/go-ps v1.0.0
 	github.com/pbnjay/memory v0.0.0-20190104145345-974d429e7ae4
@@ -23,7 +24,7 @@ require (
 	github.com/pinpt/httpclient v0.0.0-20200627153820-d374c2f15648
 	github.com/pinpt/integration-sdk v0.0.1181
 	github.com/russross/blackfriday/v2 v2.0.1
-	github.com/sergi/go-diff v1.1.0 // indirect
+	github.com/sergi/go-diff v1.1.0
 	github.com/songgao/stacktraces v0.0.0-20170719224503-0f98d2fb7fc3
 	github.com/spf13/cobra v1.0.0
 	github.com/stretchr/testify v1.6.1
@@ -114,6 +114,28 @@ func (api *API) getRequest(endPoint string, params stringmap, out interface{}) e
 }
 
 func (api *API) doRequest(method, endPoint string, params stringmap, reader io.Reader, out interface{}) error {
+	_, err := api.doRequestHeader(method, endPoint, params, reader, out)
+	return err
+}
+
+// statusError is returned when api responds with status code other than 200
+type statusError struct {
+	Code int
+	URL  string
+}
+
+func (s statusError) Error() string {
+	return fmt.Sprintf("invalid response code: %v request url: %v", s.Code, s.URL)
+}
+
+// isNotFound returns true if api responded with 404, for example when api is not supported by server version
+func isNotFound(err error) bool {
+	e, ok := err.(statusError)
+	return ok && e.Code == http.StatusNotFound
+}
+
+// doRequestHeader is the same as doRequest, but also returns response headers, used for continuation tokens
+func (api *API) doRequestHeader(method, endPoint string, params stringmap, reader io.Reader, out interface{}) (http.Header, error) {
 
 	var rawurl string
 	if api.tfs {
@@ -132,18 +154,18 @@ func (api *API) doRequest(method, endPoint string, params stringmap, reader io.R
 	u.RawQuery = vals.Encode()
 	req, err := http.NewRequest(method, u.String(), reader)
 	if err != nil {
-		return err
+		return nil, err
 	}
 	req.SetBasicAuth("", api.creds.APIKey)
 	req.Header.Set("Content-Type", "application/json")
 
 	res, err := api.client.Do(req)
 	if err != nil {
-		return err
+		return nil, err
 	}
 	b, err := ioutil.ReadAll(res.Body)
 	if err != nil {
-		return err
+		return nil, err
 	}
 	if method == http.MethodGet {
 		b = append([]byte{'['}, b...)
@@ -158,13 +180,13 @@ func (api *API) doRequest(method, endPoint string, params stringmap, reader io.R
 			var r []interface{}
 			err = json.Unmarshal(b, &r)
 			if err != nil {
-				return fmt.Errorf("invalid json: response code: %v request url: %v %v", res.StatusCode, res.Request.URL, err)
+				return nil, fmt.Errorf("invalid json: response code: %v request url: %v %v", res.StatusCode, res.Request.URL, err)
 			}
-			return fmt.Errorf("invalid json object: response code: %v request url: %v\npayload: %v err: %v", res.StatusCode, res.Request.URL, stringify(r), rerr)
+			return nil, fmt.Errorf("invalid json object: response code: %v request url: %v\npayload: %v err: %v", res.StatusCode, res.Request.URL, stringify(r), rerr)
 		}
-		return nil
+		return res.Header, nil
 	}
-	return fmt.Errorf("invalid response code: %v request url: %v", res.StatusCode, res.Request.URL)
+	return nil, statusError{Code: res.StatusCode, URL: res.Request.URL.String()}
 }
 
 // some util functions
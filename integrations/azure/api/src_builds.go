@@ -0,0 +1,273 @@
+package api
+
+import (
+	"fmt"
+	"net/http"
+	"net/url"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/pinpt/agent/integrations/pkg/cicd"
+	"github.com/pinpt/agent/integrations/pkg/repoprojects"
+	"github.com/pinpt/agent/pkg/date"
+)
+
+// pageSize is the number of builds and deployment records requested per page. These apis do not support $skip, next pages are requested using continuation token.
+const pageSize = 1000
+
+// continuationTokenHeader is set in responses when there are more results
+const continuationTokenHeader = "x-ms-continuationtoken"
+
+// FetchBuilds calls the builds api for the pipelines of the repo and sends cicd.Build objects with jobs from build timeline. Then sends cicd.Deployment objects for environment deployments of these builds. Skips builds or deployments if api is not available, for example on older TFS versions.
+func (api *API) FetchBuilds(ctx *repoprojects.ProjectCtx, repoid string) error {
+	sender, err := ctx.Session(cicd.BuildModelName)
+	if err != nil {
+		return err
+	}
+	repo, err := api.fetchRepo(repoid)
+	if err != nil {
+		return err
+	}
+	projid := repo.Project.ID
+	since := cicd.Since(sender.LastProcessedTime())
+	res, err := api.fetchBuilds(projid, repoid, since)
+	if isNotFound(err) {
+		ctx.Logger.Info("builds api is not available, skipping builds and deployments", "err", err)
+		return nil
+	}
+	if err != nil {
+		return err
+	}
+	if err := sender.SetTotal(len(res)); err != nil {
+		return err
+	}
+	repoID := api.IDs.CodeRepo(repoid)
+	for _, b := range res {
+		build := &cicd.Build{}
+		build.CustomerID = api.customerid
+		build.RefType = api.reftype
+		build.RefID = strconv.FormatInt(b.ID, 10)
+		build.RepoID = repoID
+		build.ID = cicd.NewBuildID(api.customerid, api.reftype, repoID, build.RefID)
+		build.Name = b.Definition.Name + " " + b.BuildNumber
+		build.URL = b.Links.Web.Href
+		build.Status = buildStatus(b.Status, b.Result)
+		build.Trigger = b.Reason
+		build.CommitSha = b.SourceVersion
+		build.CommitID = api.IDs.CodeCommit(repoID, b.SourceVersion)
+		build.Branch = strings.TrimPrefix(b.SourceBranch, "refs/heads/")
+		date.ConvertToModel(b.QueueTime, &build.CreatedDate)
+		date.ConvertToModel(b.StartTime, &build.StartedDate)
+		if build.Status.Finished() {
+			date.ConvertToModel(b.FinishTime, &build.CompletedDate)
+			build.Duration = cicd.Duration(b.StartTime, b.FinishTime)
+		}
+		records, err := api.fetchBuildTimeline(projid, b.ID)
+		if err != nil {
+			return err
+		}
+		for _, r := range records {
+			if r.Type != "Job" {
+				continue
+			}
+			job := cicd.Job{}
+			job.RefID = r.ID
+			job.Name = r.Name
+			job.Status = buildStatus(r.State, r.Result)
+			date.ConvertToModel(r.StartTime, &job.StartedDate)
+			date.ConvertToModel(r.FinishTime, &job.CompletedDate)
+			job.Duration = cicd.Duration(r.StartTime, r.FinishTime)
+			build.Jobs = append(build.Jobs, job)
+		}
+		if err := sender.Send(build); err != nil {
+			return err
+		}
+	}
+	return api.fetchDeployments(ctx, projid, repoID, res)
+}
+
+// fetchDeployments sends deployments to environments done by the passed builds. Environments are not linked to repos, so the deployments are matched to repo using the build that ran them.
+func (api *API) fetchDeployments(ctx *repoprojects.ProjectCtx, projid string, repoID string, builds []buildResponse) error {
+	sender, err := ctx.Session(cicd.DeploymentModelName)
+	if err != nil {
+		return err
+	}
+	byID := map[int64]buildResponse{}
+	for _, b := range builds {
+		byID[b.ID] = b
+	}
+	since := cicd.Since(sender.LastProcessedTime())
+	envs, err := api.fetchEnvironments(projid)
+	if isNotFound(err) {
+		ctx.Logger.Info("environments api is not available, skipping deployments", "err", err)
+		return nil
+	}
+	if err != nil {
+		return err
+	}
+	for _, env := range envs {
+		records, err := api.fetchEnvironmentDeployments(projid, env.ID, since)
+		if err != nil {
+			return err
+		}
+		for _, r := range records {
+			b, ok := byID[r.Owner.ID]
+			if r.PlanType != "Build" || !ok {
+				continue
+			}
+			deployment := &cicd.Deployment{}
+			deployment.CustomerID = api.customerid
+			deployment.RefType = api.reftype
+			deployment.RefID = strconv.FormatInt(r.ID, 10)
+			deployment.RepoID = repoID
+			deployment.ID = cicd.NewDeploymentID(api.customerid, api.reftype, repoID, deployment.RefID)
+			deployment.Environment = env.Name
+			deployment.URL = b.Links.Web.Href
+			deployment.CommitSha = b.SourceVersion
+			deployment.CommitID = api.IDs.CodeCommit(repoID, b.SourceVersion)
+			deployment.Branch = strings.TrimPrefix(b.SourceBranch, "refs/heads/")
+			date.ConvertToModel(r.QueueTime, &deployment.CreatedDate)
+			if r.FinishTime.IsZero() {
+				deployment.Status = cicd.StatusRunning
+			} else {
+				deployment.Status = buildStatus("", r.Result)
+				date.ConvertToModel(r.FinishTime, &deployment.CompletedDate)
+				deployment.Duration = cicd.Duration(r.StartTime, r.FinishTime)
+			}
+			if err := sender.Send(deployment); err != nil {
+				return err
+			}
+		}
+	}
+	return nil
+}
+
+// buildStatus converts build status or timeline record state and result
+func buildStatus(status, result string) cicd.Status {
+	switch status {
+	case "notStarted", "postponed", "pending":
+		return cicd.StatusPending
+	case "inProgress", "cancelling":
+		return cicd.StatusRunning
+	}
+	switch result {
+	case "succeeded", "succeededWithIssues", "partiallySucceeded":
+		return cicd.StatusSuccess
+	case "canceled", "abandoned":
+		return cicd.StatusCancelled
+	case "skipped":
+		return cicd.StatusSkipped
+	}
+	return cicd.StatusFailure
+}
+
+func (api *API) fetchRepo(repoid string) (res reposResponse, _ error) {
+	u := fmt.Sprintf(`_apis/git/repositories/%s`, url.PathEscape(repoid))
+	var out []reposResponse
+	if err := api.getRequest(u, stringmap{"pagingoff": "true"}, &out); err != nil {
+		return res, err
+	}
+	if len(out) == 0 {
+		return res, fmt.Errorf("repo not found: %v", repoid)
+	}
+	return out[0], nil
+}
+
+func (api *API) fetchBuilds(projid string, repoid string, since time.Time) (res []buildResponse, _ error) {
+	u := fmt.Sprintf(`%s/_apis/build/builds`, url.PathEscape(projid))
+	params := stringmap{
+		"pagingoff":      "true",
+		"$top":           strconv.Itoa(pageSize),
+		"repositoryId":   repoid,
+		"repositoryType": "TfsGit",
+		"queryOrder":     "queueTimeDescending",
+	}
+	if !since.IsZero() {
+		params["minTime"] = since.Format(time.RFC3339)
+	}
+	for {
+		var page []buildResponse
+		header, err := api.doRequestHeader(http.MethodGet, u, params, nil, &page)
+		if err != nil {
+			return nil, err
+		}
+		res = append(res, page...)
+		if !setContinuationToken(params, header) {
+			return res, nil
+		}
+	}
+}
+
+// environmentsAPIVersion is the first api version supporting environments
+const environmentsAPIVersion = "6.0-preview.1"
+
+func (api *API) fetchEnvironments(projid string) (res []environmentResponse, _ error) {
+	u := fmt.Sprintf(`%s/_apis/distributedtask/environments`, url.PathEscape(projid))
+	params := stringmap{
+		"pagingoff":   "true",
+		"$top":        strconv.Itoa(pageSize),
+		"api-version": environmentsAPIVersion,
+	}
+	for {
+		var page []environmentResponse
+		header, err := api.doRequestHeader(http.MethodGet, u, params, nil, &page)
+		if err != nil {
+			return nil, err
+		}
+		res = append(res, page...)
+		if !setContinuationToken(params, header) {
+			return res, nil
+		}
+	}
+}
+
+// fetchEnvironmentDeployments returns deployment records of environment queued after since. Records are returned from the newest, so paging stops at the first older one.
+func (api *API) fetchEnvironmentDeployments(projid string, envid int64, since time.Time) (res []environmentDeploymentRecord, _ error) {
+	u := fmt.Sprintf(`%s/_apis/distributedtask/environments/%d/environmentdeploymentrecords`, url.PathEscape(projid), envid)
+	params := stringmap{
+		"pagingoff":   "true",
+		"top":         strconv.Itoa(pageSize),
+		"api-version": environmentsAPIVersion,
+	}
+	for {
+		var page []environmentDeploymentRecord
+		header, err := api.doRequestHeader(http.MethodGet, u, params, nil, &page)
+		if err != nil {
+			return nil, err
+		}
+		for _, r := range page {
+			if r.QueueTime.Before(since) {
+				return res, nil
+			}
+			res = append(res, r)
+		}
+		if !setContinuationToken(params, header) {
+			return res, nil
+		}
+	}
+}
+
+// setContinuationToken sets the token for requesting the next page from response header. Returns false if there are no more pages.
+func setContinuationToken(params stringmap, header http.Header) bool {
+	token := header.Get(continuationTokenHeader)
+	if token == "" {
+		return false
+	}
+	params["continuationToken"] = token
+	return true
+}
+
+func (api *API) fetchBuildTimeline(projid string, buildid int64) ([]buildTimelineRecord, error) {
+	u := fmt.Sprintf(`%s/_apis/build/builds/%d/timeline`, url.PathEscape(projid), buildid)
+	var res []struct {
+		Records []buildTimelineRecord `json:"records"`
+	}
+	if err := api.getRequest(u, stringmap{"pagingoff": "true"}, &res); err != nil {
+		return nil, err
+	}
+	if len(res) == 0 {
+		return nil, nil
+	}
+	return res[0].Records, nil
+}
@@ -0,0 +1,25 @@
+package api
+
+import (
+	"net/http"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestSetContinuationToken(t *testing.T) {
+	params := stringmap{"$top": "1000"}
+	header := http.Header{}
+	assert.False(t, setContinuationToken(params, header))
+	assert.Equal(t, stringmap{"$top": "1000"}, params)
+
+	header.Set("X-MS-ContinuationToken", "t1")
+	assert.True(t, setContinuationToken(params, header))
+	assert.Equal(t, "t1", params["continuationToken"])
+}
+
+func TestIsNotFound(t *testing.T) {
+	assert.True(t, isNotFound(statusError{Code: http.StatusNotFound}))
+	assert.False(t, isNotFound(statusError{Code: http.StatusUnauthorized}))
+	assert.False(t, isNotFound(nil))
+}
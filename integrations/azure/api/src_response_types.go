@@ -186,3 +186,56 @@ type singleCommitResponse struct {
 	} `json:"push"`
 	RemoteURL string `json:"remoteUrl"`
 }
+
+// used in src_builds.go - fetchBuilds
+type buildResponse struct {
+	ID          int64  `json:"id"`
+	BuildNumber string `json:"buildNumber"`
+	Status      string `json:"status"`
+	Result      string `json:"result"`
+	Reason      string `json:"reason"`
+	Definition  struct {
+		Name string `json:"name"`
+	} `json:"definition"`
+	Links struct {
+		Web struct {
+			Href string `json:"href"`
+		} `json:"web"`
+	} `json:"_links"`
+	SourceBranch  string    `json:"sourceBranch"`
+	SourceVersion string    `json:"sourceVersion"`
+	QueueTime     time.Time `json:"queueTime"`
+	StartTime     time.Time `json:"startTime"`
+	FinishTime    time.Time `json:"finishTime"`
+}
+
+// used in src_builds.go - fetchEnvironments
+type environmentResponse struct {
+	ID   int64  `json:"id"`
+	Name string `json:"name"`
+}
+
+// used in src_builds.go - fetchEnvironmentDeployments
+type environmentDeploymentRecord struct {
+	ID       int64  `json:"id"`
+	PlanType string `json:"planType"`
+	Result   string `json:"result"`
+	// Owner is the pipeline run, which is the build for Build plan type
+	Owner struct {
+		ID int64 `json:"id"`
+	} `json:"owner"`
+	QueueTime  time.Time `json:"queueTime"`
+	StartTime  time.Time `json:"startTime"`
+	FinishTime time.Time `json:"finishTime"`
+}
+
+// used in src_builds.go - fetchBuildTimeline
+type buildTimelineRecord struct {
+	ID         string    `json:"id"`
+	Name       string    `json:"name"`
+	Type       string    `json:"type"`
+	State      string    `json:"state"`
+	Result     string    `json:"result"`
+	StartTime  time.Time `json:"startTime"`
+	FinishTime time.Time `json:"finishTime"`
+}
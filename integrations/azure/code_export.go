@@ -97,6 +97,9 @@ func (s *Integration) processRepos() (projectIDs []string, exportResults []rpcde
 		if err != nil {
 			return err
 		}
+		if err := s.api.FetchBuilds(ctx, repo.RefID); err != nil {
+			return err
+		}
 		return s.ripSource(repo.Repo, fetchprs)
 	}
 
@@ -145,6 +148,18 @@ func (s *Integration) appendCredentials(repoURL string) (string, error) {
 	return u.String(), nil
 }
 
+// sshRepoPath returns repo path for ssh clone url. Azure uses v3/org/project/repo, tfs uses the same path as http.
+func (s *Integration) sshRepoPath(repoURL string) (string, error) {
+	u, err := url.Parse(repoURL)
+	if err != nil {
+		return "", err
+	}
+	if s.RefType == RefTypeTFS {
+		return u.Path, nil
+	}
+	return "v3/" + strings.Replace(strings.TrimPrefix(u.Path, "/"), "/_git/", "/", 1), nil
+}
+
 func (s *Integration) ripSource(repo *sourcecode.Repo, fetchprs []rpcdef.GitRepoFetchPR) error {
 
 	repoURL, err := s.appendCredentials(repo.URL)
@@ -156,7 +171,14 @@ func (s *Integration) ripSource(repo *sourcecode.Repo, fetchprs []rpcdef.GitRepo
 	args.RepoID = s.api.IDs.CodeRepo(repo.RefID)
 	args.UniqueName = repo.Name
 	args.RefType = s.RefType.String()
-	args.URL = repoURL
+	sshPath, err := s.sshRepoPath(repo.URL)
+	if err != nil {
+		return err
+	}
+	err = s.GitAccess.Apply(&args, repoURL, sshPath)
+	if err != nil {
+		return err
+	}
 	args.CommitURLTemplate = commitURLTemplate(repo.Name, s.Creds.URL)
 	args.BranchURLTemplate = branchURLTemplate(repo.Name, s.Creds.URL)
 	args.PRs = fetchprs
@@ -9,6 +9,7 @@ import (
 
 	hclog "github.com/hashicorp/go-hclog"
 	"github.com/pinpt/agent/integrations/azure/api"
+	"github.com/pinpt/agent/integrations/pkg/gitaccess"
 	"github.com/pinpt/agent/integrations/pkg/ibase"
 	"github.com/pinpt/agent/integrations/pkg/objsender"
 	"github.com/pinpt/agent/pkg/structmarshal"
@@ -62,6 +63,8 @@ type Integration struct {
 	Projects            []string `json:"projects"`
 	OverrideGitHostName string   `json:"git_host_name"`
 	Concurrency         int      `json:"concurrency"`
+	// GitAccess selects how credentials are passed to git clone
+	GitAccess gitaccess.Config `json:"-"`
 }
 
 // Init the init function
@@ -196,6 +199,16 @@ func (s *Integration) initConfig(ctx context.Context, config rpcdef.ExportConfig
 	if s.Creds.APIKey == "" {
 		return fmt.Errorf("missing api key %s", stringify(config.Integration.Config))
 	}
+	err = structmarshal.StructToStruct(config.Integration.Config, &s.GitAccess)
+	if err != nil {
+		return err
+	}
+	if err := s.GitAccess.Validate(); err != nil {
+		return err
+	}
+	if s.RefType == RefTypeAzure && s.GitAccess.GitSSHURLPrefix == "" {
+		s.GitAccess.GitSSHURLPrefix = "ssh://git@ssh.dev.azure.com/"
+	}
 	s.IntegrationType = IntegrationType(config.Integration.Type.String())
 
 	if s.IntegrationType != IntegrationTypeCode && s.IntegrationType != IntegrationTypeIssues {
@@ -0,0 +1,63 @@
+package api
+
+import (
+	"net/url"
+	"time"
+
+	"github.com/hashicorp/go-hclog"
+	"github.com/pinpt/agent/pkg/commitusers"
+
+	pstrings "github.com/pinpt/go-common/v10/strings"
+)
+
+// CommitUsersSourcecodePage returns authors and committers of commits on default branch, newest first
+func CommitUsersSourcecodePage(
+	qc QueryContext,
+	logger hclog.Logger,
+	repo string,
+	defaultBranch string,
+	params url.Values,
+	stopOnUpdatedAt time.Time,
+	nextPage NextPage) (np NextPage, users []commitusers.CommitUser, err error) {
+
+	logger.Debug("commit users", "default_branch", defaultBranch, "inc_date", stopOnUpdatedAt, "params", params, "next", nextPage)
+
+	objectPath := pstrings.JoinURL(repoPath(repo), "commits")
+	params.Set("until", "refs/heads/"+defaultBranch)
+
+	var rcommits []rawCommit
+
+	np, err = qc.Request(objectPath, params, true, &rcommits, nextPage)
+	if err != nil {
+		return
+	}
+
+	for _, c := range rcommits {
+		if epochMsToTime(c.CommitterTimestamp).Before(stopOnUpdatedAt) {
+			np = ""
+			return
+		}
+
+		author := commitusers.CommitUser{}
+		author.CustomerID = qc.CustomerID
+		author.Name = c.Author.Name
+		author.Email = c.Author.EmailAddress
+		if c.Author.ID != 0 {
+			// author is linked to a bitbucket user
+			author.SourceID = rawUser{ID: c.Author.ID}.RefID()
+		}
+		if author.Email != "" {
+			users = append(users, author)
+		}
+
+		committer := commitusers.CommitUser{}
+		committer.CustomerID = qc.CustomerID
+		committer.Name = c.Committer.Name
+		committer.Email = c.Committer.EmailAddress
+		if committer.Email != "" && committer.Email != author.Email {
+			users = append(users, committer)
+		}
+	}
+
+	return
+}
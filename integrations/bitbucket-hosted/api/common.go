@@ -0,0 +1,87 @@
+package api
+
+import (
+	"net/url"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/pinpt/agent/pkg/ids2"
+	pstrings "github.com/pinpt/go-common/v10/strings"
+
+	"github.com/hashicorp/go-hclog"
+)
+
+type QueryContext struct {
+	// BaseURL is the website url of bitbucket server, could include context path, for example https://example.com/bitbucket
+	BaseURL string
+	Logger  hclog.Logger
+	Request func(string, url.Values, bool, interface{}, NextPage) (NextPage, error)
+
+	CustomerID string
+	RefType    string
+
+	IDs ids2.Gen
+}
+
+// NextPage is the start parameter for the next page, empty when on last page
+type NextPage string
+
+// RepoWebURL returns url of the repo in bitbucket server ui. nameWithOwner looks like PROJECT_KEY/repo_slug.
+func (s QueryContext) RepoWebURL(nameWithOwner string) string {
+	projectKey, slug := SplitNameWithOwner(nameWithOwner)
+	return pstrings.JoinURL(s.BaseURL, "projects", projectKey, "repos", slug)
+}
+
+// SplitNameWithOwner splits PROJECT_KEY/repo_slug into project key and repo slug
+func SplitNameWithOwner(nameWithOwner string) (projectKey string, slug string) {
+	parts := strings.SplitN(nameWithOwner, "/", 2)
+	if len(parts) != 2 {
+		return "", nameWithOwner
+	}
+	return parts[0], parts[1]
+}
+
+// repoPath returns api path for repo, used as prefix for all repo related calls
+func repoPath(nameWithOwner string) string {
+	projectKey, slug := SplitNameWithOwner(nameWithOwner)
+	return pstrings.JoinURL("projects", projectKey, "repos", slug)
+}
+
+// epochMsToTime converts timestamps returned by bitbucket server api, which are in milliseconds
+func epochMsToTime(ms int64) time.Time {
+	if ms == 0 {
+		return time.Time{}
+	}
+	return time.Unix(0, ms*int64(time.Millisecond))
+}
+
+type rawUser struct {
+	ID           int64  `json:"id"`
+	Name         string `json:"name"`
+	EmailAddress string `json:"emailAddress"`
+	DisplayName  string `json:"displayName"`
+	Slug         string `json:"slug"`
+	Type         string `json:"type"`
+	Links        links  `json:"links"`
+}
+
+func (s rawUser) RefID() string {
+	if s.ID == 0 {
+		return ""
+	}
+	return strconv.FormatInt(s.ID, 10)
+}
+
+type links struct {
+	Self []struct {
+		Href string `json:"href"`
+	} `json:"self"`
+}
+
+func (s links) SelfHref() string {
+	if len(s.Self) == 0 {
+		return ""
+	}
+	return s.Self[0].Href
+}
@@ -0,0 +1,101 @@
+package api
+
+import (
+	"fmt"
+	"net/url"
+	"strconv"
+	"time"
+
+	"github.com/hashicorp/go-hclog"
+	"github.com/pinpt/agent/integrations/pkg/commonpr"
+	"github.com/pinpt/agent/integrations/pkg/commonrepo"
+
+	"github.com/pinpt/agent/pkg/date"
+	pstrings "github.com/pinpt/go-common/v10/strings"
+	"github.com/pinpt/integration-sdk/sourcecode"
+)
+
+// PullRequestPage returns a page of pull requests sorted by updated date, newest first. Stops when reaching pull requests not updated since stopOnUpdatedAt.
+func PullRequestPage(
+	qc QueryContext,
+	log hclog.Logger,
+	repo commonrepo.Repo,
+	params url.Values,
+	stopOnUpdatedAt time.Time,
+	nextPage NextPage) (np NextPage, res []sourcecode.PullRequest, err error) {
+
+	log.Debug("repo prs", "params", params, "next_page", nextPage)
+
+	objectPath := pstrings.JoinURL(repoPath(repo.NameWithOwner), "pull-requests")
+
+	var rprs []struct {
+		ID          int64  `json:"id"`
+		Title       string `json:"title"`
+		Description string `json:"description"`
+		State       string `json:"state"`
+		CreatedDate int64  `json:"createdDate"`
+		UpdatedDate int64  `json:"updatedDate"`
+		ClosedDate  int64  `json:"closedDate"`
+		FromRef     struct {
+			DisplayID    string `json:"displayId"`
+			LatestCommit string `json:"latestCommit"`
+		} `json:"fromRef"`
+		Author struct {
+			User rawUser `json:"user"`
+		} `json:"author"`
+		Properties struct {
+			// MergeCommit is only returned by newer bitbucket server versions
+			MergeCommit struct {
+				ID string `json:"id"`
+			} `json:"mergeCommit"`
+		} `json:"properties"`
+		Links links `json:"links"`
+	}
+
+	np, err = qc.Request(objectPath, params, true, &rprs, nextPage)
+	if err != nil {
+		return
+	}
+
+	for _, rpr := range rprs {
+		updatedAt := epochMsToTime(rpr.UpdatedDate)
+		if updatedAt.Before(stopOnUpdatedAt) {
+			np = ""
+			return
+		}
+		pr := sourcecode.PullRequest{}
+		pr.CustomerID = qc.CustomerID
+		pr.RefType = qc.RefType
+		pr.RefID = strconv.FormatInt(rpr.ID, 10)
+		pr.RepoID = qc.IDs.CodeRepo(repo.RefID)
+		pr.BranchName = rpr.FromRef.DisplayID
+		pr.Title = rpr.Title
+		pr.Description = commonpr.ConvertMarkdownToHTML(rpr.Description)
+		pr.URL = rpr.Links.SelfHref()
+		pr.Identifier = fmt.Sprintf("#%d", rpr.ID)
+		date.ConvertToModel(epochMsToTime(rpr.CreatedDate), &pr.CreatedDate)
+		date.ConvertToModel(updatedAt, &pr.UpdatedDate)
+		switch rpr.State {
+		case "OPEN":
+			pr.Status = sourcecode.PullRequestStatusOpen
+		case "DECLINED":
+			pr.Status = sourcecode.PullRequestStatusClosed
+			date.ConvertToModel(epochMsToTime(rpr.ClosedDate), &pr.ClosedDate)
+		case "MERGED":
+			pr.Status = sourcecode.PullRequestStatusMerged
+			if sha := rpr.Properties.MergeCommit.ID; sha != "" {
+				pr.MergeSha = sha
+				pr.MergeCommitID = qc.IDs.CodeCommit(pr.RepoID, sha)
+			}
+			date.ConvertToModel(epochMsToTime(rpr.ClosedDate), &pr.MergedDate)
+			date.ConvertToModel(epochMsToTime(rpr.ClosedDate), &pr.ClosedDate)
+		default:
+			qc.Logger.Error("PR has an unknown state", "state", rpr.State, "ref_id", pr.RefID)
+		}
+		pr.CreatedByRefID = rpr.Author.User.RefID()
+
+		res = append(res, pr)
+	}
+
+	return
+}
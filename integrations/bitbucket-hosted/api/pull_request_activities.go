@@ -0,0 +1,108 @@
+package api
+
+import (
+	"net/url"
+	"strconv"
+
+	"github.com/hashicorp/go-hclog"
+	"github.com/pinpt/agent/integrations/pkg/commonrepo"
+	"github.com/pinpt/agent/pkg/date"
+	pstrings "github.com/pinpt/go-common/v10/strings"
+	"github.com/pinpt/integration-sdk/sourcecode"
+)
+
+// PullRequestActivities contains data extracted from pull request activity stream
+type PullRequestActivities struct {
+	Comments []*sourcecode.PullRequestComment
+	Reviews  []*sourcecode.PullRequestReview
+	// ClosedByRefID is the user that merged or declined the pull request
+	ClosedByRefID string
+}
+
+// PullRequestActivitiesPage returns comments, reviews and the user who closed the pull request from activities api. Bitbucket server does not have a separate api for reviews, approvals and "needs work" are only available as activities.
+func PullRequestActivitiesPage(
+	qc QueryContext,
+	logger hclog.Logger,
+	repo commonrepo.Repo,
+	pr sourcecode.PullRequest,
+	params url.Values,
+	nextPage NextPage) (np NextPage, res PullRequestActivities, err error) {
+
+	logger.Debug("pr activities", "params", params, "next_page", nextPage)
+
+	objectPath := pstrings.JoinURL(repoPath(repo.NameWithOwner), "pull-requests", pr.RefID, "activities")
+
+	var ractivities []struct {
+		ID            int64   `json:"id"`
+		CreatedDate   int64   `json:"createdDate"`
+		User          rawUser `json:"user"`
+		Action        string  `json:"action"`
+		CommentAction string  `json:"commentAction"`
+		Comment       *struct {
+			ID          int64   `json:"id"`
+			Text        string  `json:"text"`
+			Author      rawUser `json:"author"`
+			CreatedDate int64   `json:"createdDate"`
+			UpdatedDate int64   `json:"updatedDate"`
+		} `json:"comment"`
+		CommentAnchor *struct {
+			Path string `json:"path"`
+		} `json:"commentAnchor"`
+	}
+
+	np, err = qc.Request(objectPath, params, true, &ractivities, nextPage)
+	if err != nil {
+		return
+	}
+
+	repoID := qc.IDs.CodeRepo(repo.RefID)
+	prID := qc.IDs.CodePullRequest(repoID, pr.RefID)
+
+	for _, ra := range ractivities {
+		switch ra.Action {
+		case "COMMENTED":
+			// ignore inline review comments on code, same as in bitbucket cloud
+			if ra.Comment == nil || ra.CommentAnchor != nil || ra.CommentAction != "ADDED" {
+				continue
+			}
+			item := &sourcecode.PullRequestComment{}
+			item.CustomerID = qc.CustomerID
+			item.RefType = qc.RefType
+			item.RefID = strconv.FormatInt(ra.Comment.ID, 10)
+			item.URL = pr.URL + "/overview?commentId=" + item.RefID
+			item.RepoID = repoID
+			item.PullRequestID = prID
+			item.Body = ra.Comment.Text
+			date.ConvertToModel(epochMsToTime(ra.Comment.CreatedDate), &item.CreatedDate)
+			date.ConvertToModel(epochMsToTime(ra.Comment.UpdatedDate), &item.UpdatedDate)
+			item.UserRefID = ra.Comment.Author.RefID()
+			res.Comments = append(res.Comments, item)
+		case "APPROVED", "UNAPPROVED", "REVIEWED":
+			review := &sourcecode.PullRequestReview{}
+			review.CustomerID = qc.CustomerID
+			review.RefType = qc.RefType
+			review.RefID = strconv.FormatInt(ra.ID, 10)
+			review.RepoID = repoID
+			review.PullRequestID = prID
+			switch ra.Action {
+			case "APPROVED":
+				review.State = sourcecode.PullRequestReviewStateApproved
+			case "UNAPPROVED":
+				review.State = sourcecode.PullRequestReviewStateDismissed
+			case "REVIEWED":
+				// shown as "needs work" in ui
+				review.State = sourcecode.PullRequestReviewStateChangesRequested
+			}
+			review.UserRefID = ra.User.RefID()
+			date.ConvertToModel(epochMsToTime(ra.CreatedDate), &review.CreatedDate)
+			res.Reviews = append(res.Reviews, review)
+		case "MERGED", "DECLINED":
+			// activities are returned newest first, use the latest one in case pr was reopened
+			if res.ClosedByRefID == "" {
+				res.ClosedByRefID = ra.User.RefID()
+			}
+		}
+	}
+
+	return
+}
@@ -0,0 +1,71 @@
+package api
+
+import (
+	"net/url"
+
+	"github.com/hashicorp/go-hclog"
+	"github.com/pinpt/agent/integrations/pkg/commonrepo"
+
+	"github.com/pinpt/agent/pkg/date"
+	"github.com/pinpt/agent/pkg/ids"
+	"github.com/pinpt/integration-sdk/sourcecode"
+
+	pstrings "github.com/pinpt/go-common/v10/strings"
+)
+
+type rawCommit struct {
+	ID     string `json:"id"`
+	Author struct {
+		Name         string `json:"name"`
+		EmailAddress string `json:"emailAddress"`
+		ID           int64  `json:"id"`
+	} `json:"author"`
+	AuthorTimestamp int64 `json:"authorTimestamp"`
+	Committer       struct {
+		Name         string `json:"name"`
+		EmailAddress string `json:"emailAddress"`
+	} `json:"committer"`
+	CommitterTimestamp int64  `json:"committerTimestamp"`
+	Message            string `json:"message"`
+}
+
+func PullRequestCommitsPage(
+	qc QueryContext,
+	logger hclog.Logger,
+	repo commonrepo.Repo,
+	pr sourcecode.PullRequest,
+	params url.Values,
+	nextPage NextPage) (np NextPage, res []*sourcecode.PullRequestCommit, err error) {
+
+	logger.Debug("pr commits", "params", params, "next_page", nextPage)
+
+	objectPath := pstrings.JoinURL(repoPath(repo.NameWithOwner), "pull-requests", pr.RefID, "commits")
+
+	var rcommits []rawCommit
+
+	np, err = qc.Request(objectPath, params, true, &rcommits, nextPage)
+	if err != nil {
+		return
+	}
+
+	for _, rcommit := range rcommits {
+		createdAt := epochMsToTime(rcommit.CommitterTimestamp)
+		item := &sourcecode.PullRequestCommit{}
+		item.CustomerID = qc.CustomerID
+		item.RefType = qc.RefType
+		item.RefID = rcommit.ID
+		item.RepoID = qc.IDs.CodeRepo(repo.RefID)
+		item.PullRequestID = qc.IDs.CodePullRequest(item.RepoID, pr.RefID)
+		item.Sha = rcommit.ID
+		item.Message = rcommit.Message
+		item.URL = pstrings.JoinURL(qc.RepoWebURL(repo.NameWithOwner), "commits", rcommit.ID)
+		date.ConvertToModel(createdAt, &item.CreatedDate)
+
+		item.AuthorRefID = ids.CodeCommitEmail(qc.CustomerID, rcommit.Author.EmailAddress)
+		item.CommitterRefID = ids.CodeCommitEmail(qc.CustomerID, rcommit.Committer.EmailAddress)
+
+		res = append(res, item)
+	}
+
+	return
+}
@@ -0,0 +1,149 @@
+package api
+
+import (
+	"net/url"
+	"strconv"
+
+	"github.com/pinpt/agent/integrations/pkg/commonrepo"
+	pstrings "github.com/pinpt/go-common/v10/strings"
+	"github.com/pinpt/integration-sdk/agent"
+	"github.com/pinpt/integration-sdk/sourcecode"
+)
+
+type rawRepo struct {
+	ID          int64  `json:"id"`
+	Slug        string `json:"slug"`
+	Name        string `json:"name"`
+	Description string `json:"description"`
+	Project     struct {
+		Key string `json:"key"`
+	} `json:"project"`
+	Links links `json:"links"`
+}
+
+func (s rawRepo) RefID() string {
+	return strconv.FormatInt(s.ID, 10)
+}
+
+func (s rawRepo) NameWithOwner() string {
+	return s.Project.Key + "/" + s.Slug
+}
+
+// reposPage returns a page of all repos the user has access to
+func reposPage(qc QueryContext, params url.Values, nextPage NextPage) (np NextPage, res []rawRepo, err error) {
+	np, err = qc.Request("repos", params, true, &res, nextPage)
+	return
+}
+
+// ResposUserHasAccessToPage it will fetch repos the user has access to
+func ResposUserHasAccessToPage(
+	qc QueryContext,
+	params url.Values,
+	nextPage NextPage) (np NextPage, repos []*agent.RepoResponseRepos, err error) {
+
+	qc.Logger.Debug("onboard repos request", "params", params, "next_page", nextPage)
+
+	np, rr, err := reposPage(qc, params, nextPage)
+	if err != nil {
+		return
+	}
+
+	for _, v := range rr {
+		repo := &agent.RepoResponseRepos{
+			Active:      true,
+			RefID:       v.RefID(),
+			RefType:     qc.RefType,
+			Name:        v.NameWithOwner(),
+			Description: v.Description,
+			// Language and CreatedDate are not available in bitbucket server api
+		}
+		repos = append(repos, repo)
+	}
+
+	return
+}
+
+func ReposAll(qc interface{}, res chan []commonrepo.Repo) error {
+
+	params := url.Values{}
+	params.Set("limit", "100")
+
+	return Paginate(func(nextPage NextPage) (NextPage, error) {
+		pi, repos, err := ReposPage(qc.(QueryContext), params, nextPage)
+		if err != nil {
+			return pi, err
+		}
+		res <- repos
+		return pi, nil
+	})
+}
+
+func ReposPage(
+	qc QueryContext,
+	params url.Values,
+	nextPage NextPage) (np NextPage, repos []commonrepo.Repo, err error) {
+
+	qc.Logger.Debug("repos", "params", params)
+
+	np, rr, err := reposPage(qc, params, nextPage)
+	if err != nil {
+		return
+	}
+
+	for _, r := range rr {
+		repo := commonrepo.Repo{
+			RefID:         r.RefID(),
+			NameWithOwner: r.NameWithOwner(),
+		}
+		repo.DefaultBranch, err = RepoDefaultBranch(qc, repo.NameWithOwner)
+		if err != nil {
+			return
+		}
+		repos = append(repos, repo)
+	}
+
+	return
+}
+
+// RepoDefaultBranch returns the name of default branch for repo, empty if repo has no commits
+func RepoDefaultBranch(qc QueryContext, nameWithOwner string) (_ string, rerr error) {
+	var res struct {
+		DisplayID string `json:"displayId"`
+	}
+	_, err := qc.Request(pstrings.JoinURL(repoPath(nameWithOwner), "branches", "default"), nil, false, &res, "")
+	if err != nil {
+		rerr = err
+		return
+	}
+	return res.DisplayID, nil
+}
+
+// ReposSourcecodePage returns a page of repos. Bitbucket server does not return updated date for repos, so all repos are returned on incremental exports as well.
+func ReposSourcecodePage(
+	qc QueryContext,
+	params url.Values,
+	nextPage NextPage) (np NextPage, repos []*sourcecode.Repo, err error) {
+
+	qc.Logger.Debug("repos sourcecode", "params", params, "next", nextPage)
+
+	np, rr, err := reposPage(qc, params, nextPage)
+	if err != nil {
+		return
+	}
+
+	for _, r := range rr {
+		repo := &sourcecode.Repo{
+			RefID:       r.RefID(),
+			RefType:     qc.RefType,
+			CustomerID:  qc.CustomerID,
+			Name:        r.NameWithOwner(),
+			URL:         r.Links.SelfHref(),
+			Description: r.Description,
+			Active:      true,
+		}
+
+		repos = append(repos, repo)
+	}
+
+	return
+}
@@ -0,0 +1,176 @@
+package api
+
+import (
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"net/url"
+	"strconv"
+
+	"github.com/hashicorp/go-hclog"
+	pstrings "github.com/pinpt/go-common/v10/strings"
+)
+
+type RequesterOpts struct {
+	Logger hclog.Logger
+	// APIURL is the base url for rest api, for example https://example.com/rest/api/1.0
+	APIURL   string
+	Username string
+	Password string
+	// APIKey is a personal access token, used instead of password when set
+	APIKey     string
+	HTTPClient *http.Client
+}
+
+type internalRequest struct {
+	URL      string
+	Params   url.Values
+	Pageable bool
+	Response interface{}
+	NextPage NextPage
+}
+
+func NewRequester(opts RequesterOpts) *Requester {
+	s := &Requester{}
+	s.opts = opts
+	s.logger = opts.Logger
+	s.httpClient = opts.HTTPClient
+	return s
+}
+
+type Requester struct {
+	logger     hclog.Logger
+	opts       RequesterOpts
+	httpClient *http.Client
+}
+
+func (s *Requester) setAuth(req *http.Request) {
+	if s.opts.APIKey != "" {
+		req.Header.Set("Authorization", "Bearer "+s.opts.APIKey)
+	} else {
+		req.SetBasicAuth(s.opts.Username, s.opts.Password)
+	}
+}
+
+// Request make request
+func (e *Requester) Request(url string, params url.Values, pageable bool, response interface{}, nextPage NextPage) (np NextPage, err error) {
+
+	ir := &internalRequest{
+		URL:      url,
+		Params:   params,
+		Pageable: pageable,
+		Response: response,
+		NextPage: nextPage,
+	}
+
+	return e.makeRequestRetry(ir, 0)
+
+}
+
+const maxGeneralRetries = 2
+
+func (e *Requester) makeRequestRetry(req *internalRequest, generalRetry int) (nextPage NextPage, err error) {
+	var isRetryable bool
+	isRetryable, nextPage, err = e.request(req, generalRetry+1)
+	if err != nil {
+		if !isRetryable {
+			return nextPage, err
+		}
+		if generalRetry >= maxGeneralRetries {
+			return nextPage, fmt.Errorf(`can't retry request, too many retries, err: %v`, err)
+		}
+		return e.makeRequestRetry(req, generalRetry+1)
+	}
+	return
+}
+
+func (e *Requester) request(r *internalRequest, retryThrottled int) (isErrorRetryable bool, np NextPage, rerr error) {
+
+	u := pstrings.JoinURL(e.opts.APIURL, r.URL)
+
+	params := url.Values{}
+	for k, v := range r.Params {
+		params[k] = v
+	}
+	if r.Pageable && r.NextPage != "" {
+		params.Set("start", string(r.NextPage))
+	}
+	if len(params) != 0 {
+		u += "?" + params.Encode()
+	}
+
+	req, err := http.NewRequest(http.MethodGet, u, nil)
+	if err != nil {
+		rerr = err
+		return
+	}
+	req.Header.Set("Accept", "application/json")
+	e.setAuth(req)
+
+	resp, err := e.httpClient.Do(req)
+	if err != nil {
+		rerr = err
+		return
+	}
+	defer resp.Body.Close()
+
+	e.logger.Debug("api request", "url", u, "status", resp.StatusCode)
+
+	if resp.StatusCode == http.StatusNoContent {
+		// returned for default branch of empty repos
+		return
+	}
+
+	if resp.StatusCode != http.StatusOK {
+
+		if resp.StatusCode == http.StatusUnauthorized {
+			return false, np, fmt.Errorf("request not authorized")
+		}
+
+		if resp.StatusCode == http.StatusTooManyRequests {
+			// http client already waited and retried
+			return false, np, fmt.Errorf("rate limit hit")
+		}
+
+		if resp.StatusCode == http.StatusNotFound {
+			e.logger.Warn("the source or destination could not be found", "url", u)
+			return false, np, nil
+		}
+
+		e.logger.Debug("api request failed", "url", u, "status", resp.StatusCode)
+		return true, np, fmt.Errorf(`bitbucket server returned invalid status code: %v`, resp.StatusCode)
+	}
+
+	if r.Pageable {
+		var response Response
+
+		if err = json.NewDecoder(resp.Body).Decode(&response); err != nil {
+			return false, np, err
+		}
+
+		if err = json.Unmarshal(response.Values, &r.Response); err != nil {
+			return false, np, err
+		}
+
+		if !response.IsLastPage {
+			np = NextPage(strconv.Itoa(response.NextPageStart))
+		}
+
+	} else {
+		if err = json.NewDecoder(resp.Body).Decode(&r.Response); err != nil {
+			return false, np, err
+		}
+	}
+
+	return
+}
+
+// Response is the paged response format used by bitbucket server
+type Response struct {
+	Size          int             `json:"size"`
+	Limit         int             `json:"limit"`
+	Start         int             `json:"start"`
+	IsLastPage    bool            `json:"isLastPage"`
+	NextPageStart int             `json:"nextPageStart"`
+	Values        json.RawMessage `json:"values"`
+}
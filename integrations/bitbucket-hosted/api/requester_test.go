@@ -0,0 +1,67 @@
+package api
+
+import (
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"testing"
+
+	"github.com/hashicorp/go-hclog"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestRequestPaginate(t *testing.T) {
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.URL.Path != "/rest/api/1.0/repos" {
+			w.WriteHeader(http.StatusNotFound)
+			return
+		}
+		if r.Header.Get("Authorization") != "Bearer t1" {
+			w.WriteHeader(http.StatusUnauthorized)
+			return
+		}
+		switch r.URL.Query().Get("start") {
+		case "":
+			fmt.Fprint(w, `{"size":1,"limit":1,"start":0,"isLastPage":false,"nextPageStart":1,"values":[{"id":1,"slug":"r1","project":{"key":"P1"}}]}`)
+		case "1":
+			fmt.Fprint(w, `{"size":1,"limit":1,"start":1,"isLastPage":true,"values":[{"id":2,"slug":"r2","project":{"key":"P1"}}]}`)
+		default:
+			w.WriteHeader(http.StatusBadRequest)
+		}
+	}))
+	defer srv.Close()
+
+	opts := RequesterOpts{}
+	opts.Logger = hclog.NewNullLogger()
+	opts.APIURL = srv.URL + "/rest/api/1.0"
+	opts.APIKey = "t1"
+	opts.HTTPClient = srv.Client()
+	requester := NewRequester(opts)
+
+	qc := QueryContext{}
+	qc.BaseURL = srv.URL
+	qc.Logger = opts.Logger
+	qc.Request = requester.Request
+
+	params := url.Values{}
+	params.Set("limit", "1")
+
+	var got []string
+	err := Paginate(func(nextPage NextPage) (NextPage, error) {
+		np, repos, err := reposPage(qc, params, nextPage)
+		if err != nil {
+			return np, err
+		}
+		for _, repo := range repos {
+			got = append(got, repo.RefID()+":"+repo.NameWithOwner())
+		}
+		return np, nil
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	assert.Equal(t, []string{"1:P1/r1", "2:P1/r2"}, got)
+	assert.Equal(t, "1", params.Get("limit"), "request should not modify passed params")
+	assert.Equal(t, "", params.Get("start"))
+}
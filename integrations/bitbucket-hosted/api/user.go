@@ -0,0 +1,54 @@
+package api
+
+import (
+	"net/url"
+
+	"github.com/pinpt/agent/pkg/commitusers"
+	pstrings "github.com/pinpt/go-common/v10/strings"
+	"github.com/pinpt/integration-sdk/sourcecode"
+)
+
+// UsersSourcecodePage returns all users visible to the current user. Unlike bitbucket cloud, email is available, so commit users are returned as well.
+func UsersSourcecodePage(
+	qc QueryContext,
+	params url.Values,
+	nextPage NextPage) (np NextPage, users []*sourcecode.User, commitUsers []commitusers.CommitUser, err error) {
+
+	qc.Logger.Debug("users request", "params", params, "next_page", nextPage)
+
+	var us []rawUser
+
+	np, err = qc.Request("users", params, true, &us, nextPage)
+	if err != nil {
+		return
+	}
+
+	for _, u := range us {
+		user := &sourcecode.User{
+			RefID:      u.RefID(),
+			RefType:    qc.RefType,
+			CustomerID: qc.CustomerID,
+			Name:       u.DisplayName,
+			Username:   pstrings.Pointer(u.Name),
+			Member:     true,
+			Type:       sourcecode.UserTypeHuman,
+			URL:        pstrings.Pointer(u.Links.SelfHref()),
+			AvatarURL:  pstrings.Pointer(pstrings.JoinURL(qc.BaseURL, "users", u.Slug, "avatar.png")),
+		}
+		if u.Type == "SERVICE" {
+			user.Type = sourcecode.UserTypeBot
+		}
+		users = append(users, user)
+
+		if u.EmailAddress != "" {
+			commitUsers = append(commitUsers, commitusers.CommitUser{
+				CustomerID: qc.CustomerID,
+				Email:      u.EmailAddress,
+				Name:       u.DisplayName,
+				SourceID:   user.RefID,
+			})
+		}
+	}
+
+	return
+}
@@ -0,0 +1,35 @@
+package api
+
+import (
+	"net/url"
+)
+
+// ServerVersion returns bitbucket server version
+func ServerVersion(qc QueryContext) (_ string, rerr error) {
+	qc.Logger.Debug("application properties request")
+
+	var res struct {
+		Version string `json:"version"`
+	}
+	_, err := qc.Request("application-properties", nil, false, &res, "")
+	if err != nil {
+		rerr = err
+		return
+	}
+	return res.Version, nil
+}
+
+// AreUserCredentialsValid if this returns ok the credentials are fine. Application properties are available for anonymous users, so we request repos instead.
+func AreUserCredentialsValid(qc QueryContext) (err error) {
+
+	qc.Logger.Debug("users credentials validation")
+
+	params := url.Values{}
+	params.Set("limit", "1")
+
+	var res interface{}
+
+	_, err = qc.Request("repos", params, true, &res, "")
+
+	return
+}
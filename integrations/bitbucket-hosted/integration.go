@@ -0,0 +1,462 @@
+package main
+
+import (
+	"context"
+	"fmt"
+	"net/url"
+	"path"
+	"strings"
+
+	pstrings "github.com/pinpt/go-common/v10/strings"
+
+	"github.com/pinpt/agent/cmd/cmdrunnorestarts/inconfig"
+	"github.com/pinpt/agent/integrations/pkg/objsender"
+	"github.com/pinpt/agent/integrations/pkg/repoprojects"
+	"github.com/pinpt/integration-sdk/sourcecode"
+
+	"github.com/hashicorp/go-hclog"
+	"github.com/pinpt/agent/integrations/bitbucket-hosted/api"
+	"github.com/pinpt/agent/integrations/pkg/commonrepo"
+	"github.com/pinpt/agent/integrations/pkg/gitaccess"
+	"github.com/pinpt/agent/integrations/pkg/ibase"
+	"github.com/pinpt/agent/pkg/commitusers"
+	"github.com/pinpt/agent/pkg/ids2"
+	"github.com/pinpt/agent/pkg/reqstats"
+	"github.com/pinpt/agent/pkg/structmarshal"
+	"github.com/pinpt/agent/rpcdef"
+)
+
+func main() {
+	ibase.MainFunc(func(logger hclog.Logger) rpcdef.Integration {
+		return NewIntegration(logger)
+	})
+}
+
+func NewIntegration(logger hclog.Logger) *Integration {
+	s := &Integration{}
+	s.logger = logger
+	return s
+}
+
+type Config struct {
+	commonrepo.FilterConfig
+	gitaccess.Config
+	URL      string `json:"url"`
+	Username string `json:"username"`
+	Password string `json:"password"`
+	// APIKey is a personal access token, used instead of password when set. Username is still required for git clone.
+	APIKey             string `json:"api_key"`
+	OnlyGit            bool   `json:"only_git"`
+	InsecureSkipVerify bool   `json:"insecure_skip_verify"`
+
+	Exclusions []string `json:"exclusions"`
+
+	// MaxRequestsPerSecond limits the request rate to bitbucket server api. No limit by default.
+	MaxRequestsPerSecond float64 `json:"max_requests_per_second"`
+}
+
+type Integration struct {
+	logger     hclog.Logger
+	agent      rpcdef.Agent
+	customerID string
+
+	qc api.QueryContext
+
+	config Config
+
+	refType string
+
+	clientManager *reqstats.ClientManager
+}
+
+func (s *Integration) Init(agent rpcdef.Agent) error {
+	s.agent = agent
+	// using the same ref_type as bitbucket cloud, data is stored in the same models
+	s.refType = "bitbucket"
+
+	s.qc = api.QueryContext{
+		Logger: s.logger,
+	}
+
+	return nil
+}
+
+func (s *Integration) ValidateConfig(ctx context.Context, exportConfig rpcdef.ExportConfig) (res rpcdef.ValidationResult, _ error) {
+
+	rerr := func(err error) {
+		res.Errors = append(res.Errors, err.Error())
+	}
+
+	err := s.initWithConfig(exportConfig)
+	if err != nil {
+		rerr(err)
+		return
+	}
+
+	res.ServerVersion, err = api.ServerVersion(s.qc)
+	if err != nil {
+		rerr(err)
+		return
+	}
+
+	err = api.AreUserCredentialsValid(s.qc)
+	if err != nil {
+		rerr(err)
+		return
+	}
+
+	return
+}
+
+func (s *Integration) Export(ctx context.Context,
+	exportConfig rpcdef.ExportConfig) (res rpcdef.ExportResult, rerr error) {
+
+	err := s.initWithConfig(exportConfig)
+	if err != nil {
+		rerr = err
+		return
+	}
+
+	projects, err := s.export(ctx)
+	if err != nil {
+		rerr = err
+		return
+	}
+
+	res.Projects = projects
+
+	return
+}
+
+func (s *Integration) initWithConfig(config rpcdef.ExportConfig) error {
+	err := s.setConfig(config)
+	if err != nil {
+		return err
+	}
+
+	s.qc.BaseURL = s.config.URL
+	s.qc.CustomerID = config.Pinpoint.CustomerID
+	s.qc.Logger = s.logger
+	s.qc.RefType = s.refType
+	s.customerID = config.Pinpoint.CustomerID
+
+	s.clientManager, err = reqstats.New(reqstats.Opts{
+		Logger:                s.logger,
+		TLSInsecureSkipVerify: s.config.InsecureSkipVerify,
+		MaxRequestsPerSecond:  s.config.MaxRequestsPerSecond,
+		Pauser:                s.agent,
+	})
+	if err != nil {
+		return err
+	}
+
+	{
+		opts := api.RequesterOpts{}
+		opts.Logger = s.logger
+		opts.APIURL = pstrings.JoinURL(s.config.URL, "rest/api/1.0")
+		opts.Username = s.config.Username
+		opts.Password = s.config.Password
+		opts.APIKey = s.config.APIKey
+		opts.HTTPClient = s.clientManager.Clients.TLSInsecure
+		requester := api.NewRequester(opts)
+
+		s.qc.Request = requester.Request
+		s.qc.IDs = ids2.New(s.customerID, s.refType)
+	}
+
+	return nil
+}
+
+func (s *Integration) setConfig(config rpcdef.ExportConfig) error {
+	rerr := func(msg string, args ...interface{}) error {
+		return fmt.Errorf("config validation error: "+msg, args...)
+	}
+	var def Config
+	err := structmarshal.MapToStruct(config.Integration.Config, &def)
+	if err != nil {
+		return err
+	}
+	if def.URL == "" {
+		return rerr("url is missing")
+	}
+	if def.Username == "" {
+		return rerr("username is missing")
+	}
+	if def.Password == "" && def.APIKey == "" {
+		return rerr("password or api_key is missing")
+	}
+	def.URL = strings.TrimSuffix(def.URL, "/")
+	if err := def.Config.Validate(); err != nil {
+		return fmt.Errorf("config validation error: %v", err)
+	}
+	s.config = def
+	return nil
+}
+
+func (s *Integration) export(ctx context.Context) (exportResults []rpcdef.ExportProject, rerr error) {
+
+	exportResults, rerr = s.exportAllRepos(ctx)
+	if rerr != nil {
+		return
+	}
+
+	s.logger.Info(s.clientManager.PrintStats())
+
+	return
+}
+
+func (s *Integration) exportAllRepos(ctx context.Context) (_ []rpcdef.ExportProject, err error) {
+
+	repos, err := commonrepo.ReposAllSlice(func(res chan []commonrepo.Repo) error {
+		return api.ReposAll(s.qc, res)
+	})
+	if err != nil {
+		return
+	}
+
+	repos = commonrepo.Filter(s.logger, repos, s.config.FilterConfig)
+
+	if s.config.OnlyGit {
+		s.logger.Warn("only_ripsrc flag passed, skipping export of data from bitbucket server api")
+		for _, repo := range repos {
+			err = s.exportGit(repo, nil)
+			if err != nil {
+				return
+			}
+		}
+		return
+	}
+
+	if err = s.exportUsers(ctx); err != nil {
+		return
+	}
+
+	repoSender, err := objsender.Root(s.agent, sourcecode.RepoModelName.String())
+	if err != nil {
+		return
+	}
+
+	// export repos
+	err = s.exportRepos(ctx, repoSender, repos)
+	if err != nil {
+		return
+	}
+
+	s.logger.Info("exporting repos", "len", len(repos))
+
+	var reposIface []repoprojects.RepoProject
+	for _, repo := range repos {
+		reposIface = append(reposIface, repo)
+	}
+
+	repoSender.SetNoAutoProgress(true)
+	if err = repoSender.SetTotal(len(reposIface)); err != nil {
+		return
+	}
+
+	processOpts := repoprojects.ProcessOpts{}
+	processOpts.Logger = s.logger
+	processOpts.ProjectFn = func(ctx *repoprojects.ProjectCtx) error {
+		repo := ctx.Project.(commonrepo.Repo)
+		return s.exportRepoChildren(ctx, repo)
+	}
+
+	processOpts.Concurrency = 1
+	processOpts.Projects = reposIface
+
+	processOpts.IntegrationType = inconfig.IntegrationTypeSourcecode
+	processOpts.CustomerID = s.customerID
+	processOpts.RefType = s.refType
+	processOpts.Sender = repoSender
+
+	processor := repoprojects.NewProcess(processOpts)
+	exportResult, err := processor.Run()
+	if err != nil {
+		return
+	}
+
+	err = repoSender.Done()
+	if err != nil {
+		return
+	}
+
+	return exportResult, nil
+}
+
+func (s *Integration) exportRepoChildren(ctx *repoprojects.ProjectCtx, repo commonrepo.Repo) error {
+	err := s.exportCommitUsersForRepo(ctx, repo)
+	if err != nil {
+		return err
+	}
+
+	prs, err := s.exportPullRequestsForRepo(ctx, repo)
+	if err != nil {
+		return err
+	}
+
+	err = s.exportGit(repo, prs)
+	if err != nil {
+		return err
+	}
+
+	return nil
+}
+
+func (s *Integration) exportRepos(ctx context.Context, sender *objsender.Session, onlyInclude []commonrepo.Repo) error {
+
+	shouldInclude := map[string]bool{}
+	for _, repo := range onlyInclude {
+		shouldInclude[repo.NameWithOwner] = true
+	}
+
+	params := url.Values{}
+	params.Set("limit", "100")
+
+	return api.Paginate(func(nextPage api.NextPage) (api.NextPage, error) {
+		np, repos, err := api.ReposSourcecodePage(s.qc, params, nextPage)
+		if err != nil {
+			return np, err
+		}
+
+		for _, repo := range repos {
+			if !shouldInclude[repo.Name] {
+				continue
+			}
+			if err := sender.Send(repo); err != nil {
+				return np, err
+			}
+		}
+		return np, nil
+	})
+}
+
+func (s *Integration) exportUsers(ctx context.Context) error {
+
+	sender, err := objsender.Root(s.agent, sourcecode.UserModelName.String())
+	if err != nil {
+		return err
+	}
+
+	commitUsersSender, err := objsender.Root(s.agent, commitusers.TableName)
+	if err != nil {
+		return err
+	}
+
+	params := url.Values{}
+	params.Set("limit", "100")
+
+	err = api.Paginate(func(nextPage api.NextPage) (api.NextPage, error) {
+		np, users, commitUsers, err := api.UsersSourcecodePage(s.qc, params, nextPage)
+		if err != nil {
+			return np, err
+		}
+		for _, user := range users {
+			if err := sender.Send(user); err != nil {
+				return np, err
+			}
+		}
+		for _, user := range commitUsers {
+			if err := user.Validate(); err != nil {
+				s.logger.Warn("commit user", "err", err)
+				continue
+			}
+			if err := commitUsersSender.SendMap(user.ToMap()); err != nil {
+				return np, err
+			}
+		}
+		return np, nil
+	})
+
+	if err != nil {
+		return err
+	}
+
+	if err = commitUsersSender.Done(); err != nil {
+		return err
+	}
+
+	return sender.Done()
+}
+
+func (s *Integration) exportCommitUsersForRepo(ctx *repoprojects.ProjectCtx, repo commonrepo.Repo) (err error) {
+	if repo.DefaultBranch == "" {
+		// empty repo
+		return nil
+	}
+
+	usersSender, err := ctx.Session(commitusers.TableName)
+	if err != nil {
+		return err
+	}
+
+	params := url.Values{}
+	params.Set("limit", "100")
+
+	stopOnUpdatedAt := usersSender.LastProcessedTime()
+
+	return api.Paginate(func(nextPage api.NextPage) (api.NextPage, error) {
+		np, users, err := api.CommitUsersSourcecodePage(s.qc, ctx.Logger, repo.NameWithOwner, repo.DefaultBranch, params, stopOnUpdatedAt, nextPage)
+		if err != nil {
+			return np, err
+		}
+		for _, user := range users {
+			err := user.Validate()
+			if err != nil {
+				s.logger.Warn("commit user", "err", err)
+				continue
+			}
+			if err := usersSender.SendMap(user.ToMap()); err != nil {
+				return np, err
+			}
+		}
+		return np, nil
+	})
+}
+
+// getRepoURL returns http clone url with credentials, looks like https://example.com/scm/project_key/repo_slug.git
+func (s *Integration) getRepoURL(nameWithOwner string) (string, error) {
+	u, err := url.Parse(s.config.URL)
+	if err != nil {
+		return "", err
+	}
+	password := s.config.Password
+	if s.config.APIKey != "" {
+		// personal access tokens can be used instead of password for git over http
+		password = s.config.APIKey
+	}
+	u.User = url.UserPassword(s.config.Username, password)
+	projectKey, slug := api.SplitNameWithOwner(nameWithOwner)
+	u.Path = path.Join("/", u.Path, "scm", strings.ToLower(projectKey), slug+".git")
+	return u.String(), nil
+}
+
+// sshRepoPath returns repo path for ssh clone url, looks like project_key/repo_slug.git
+func sshRepoPath(nameWithOwner string) string {
+	projectKey, slug := api.SplitNameWithOwner(nameWithOwner)
+	return strings.ToLower(projectKey) + "/" + slug + ".git"
+}
+
+func (s *Integration) exportGit(repo commonrepo.Repo, prs []rpcdef.GitRepoFetchPR) error {
+	repoURL, err := s.getRepoURL(repo.NameWithOwner)
+	if err != nil {
+		return err
+	}
+
+	webURL := s.qc.RepoWebURL(repo.NameWithOwner)
+
+	args := rpcdef.GitRepoFetch{}
+	args.RepoID = s.qc.IDs.CodeRepo(repo.RefID)
+	args.UniqueName = repo.NameWithOwner
+	args.RefType = s.refType
+	err = s.config.Config.Apply(&args, repoURL, sshRepoPath(repo.NameWithOwner))
+	if err != nil {
+		return err
+	}
+	args.CommitURLTemplate = webURL + "/commits/@@@sha@@@"
+	args.BranchURLTemplate = webURL + "/browse?at=refs/heads/@@@branch@@@"
+	args.PRs = prs
+	if err = s.agent.ExportGitRepo(args); err != nil {
+		return err
+	}
+	return nil
+}
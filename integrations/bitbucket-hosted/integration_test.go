@@ -0,0 +1,67 @@
+package main
+
+import (
+	"context"
+	"testing"
+
+	"github.com/hashicorp/go-hclog"
+	"github.com/pinpt/agent/rpcdef"
+	"github.com/stretchr/testify/assert"
+)
+
+const testSecret = "secret-value-1"
+
+func newTestIntegration(conf map[string]interface{}) (*Integration, rpcdef.ExportConfig) {
+	s := NewIntegration(hclog.NewNullLogger())
+	err := s.Init(nil)
+	if err != nil {
+		panic(err)
+	}
+	config := rpcdef.ExportConfig{}
+	config.Pinpoint.CustomerID = "c1"
+	config.Integration.Config = conf
+	return s, config
+}
+
+// invalidConfigs all contain testSecret and fail validation before any request is made
+var invalidConfigs = []map[string]interface{}{
+	{"username": "u1", "password": testSecret},
+	{"url": "http://localhost", "password": testSecret},
+	{"url": "http://localhost", "username": testSecret},
+	{"url": "http://localhost", "username": "u1", "api_key": testSecret, "git_access": "ssh"},
+	{"url": "http://localhost", "username": "u1", "password": testSecret, "git_access": "other"},
+}
+
+func TestValidateConfigErrorsDoNotIncludeConfig(t *testing.T) {
+	assert := assert.New(t)
+	for _, conf := range invalidConfigs {
+		s, config := newTestIntegration(conf)
+		res, err := s.ValidateConfig(context.Background(), config)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if !assert.Len(res.Errors, 1, conf) {
+			continue
+		}
+		assert.Contains(res.Errors[0], "config validation error")
+		assert.NotContains(res.Errors[0], testSecret)
+	}
+}
+
+func TestExportErrorsDoNotIncludeConfig(t *testing.T) {
+	assert := assert.New(t)
+	for _, conf := range invalidConfigs {
+		s, config := newTestIntegration(conf)
+		_, err := s.Export(context.Background(), config)
+		if !assert.Error(err, conf) {
+			continue
+		}
+		assert.NotContains(err.Error(), testSecret)
+
+		_, err = s.OnboardExport(context.Background(), rpcdef.OnboardExportTypeRepos, config)
+		if !assert.Error(err, conf) {
+			continue
+		}
+		assert.NotContains(err.Error(), testSecret)
+	}
+}
@@ -0,0 +1,66 @@
+package main
+
+import (
+	"context"
+	"net/url"
+
+	"github.com/pinpt/agent/integrations/bitbucket-hosted/api"
+	"github.com/pinpt/agent/integrations/pkg/commonrepo"
+	"github.com/pinpt/agent/integrations/pkg/repoprojects"
+	"github.com/pinpt/agent/rpcdef"
+)
+
+// OnboardExport onboard export
+func (s *Integration) OnboardExport(ctx context.Context, objectType rpcdef.OnboardExportType, config rpcdef.ExportConfig) (res rpcdef.OnboardExportResult, _ error) {
+	err := s.initWithConfig(config)
+	if err != nil {
+		return res, err
+	}
+	switch objectType {
+	case rpcdef.OnboardExportTypeRepos:
+		return s.onboardExportRepos(ctx)
+	case rpcdef.OnboardExportTypePlan:
+		return s.onboardExportPlan(ctx)
+	default:
+		res.Error = rpcdef.ErrOnboardExportNotSupported
+		return
+	}
+}
+
+func (s *Integration) onboardExportRepos(ctx context.Context) (res rpcdef.OnboardExportResult, rerr error) {
+	var records []map[string]interface{}
+
+	params := url.Values{}
+	params.Set("limit", "100")
+
+	rerr = api.Paginate(func(nextPage api.NextPage) (np api.NextPage, _ error) {
+		pageInfo, repos, err := api.ResposUserHasAccessToPage(s.qc, params, nextPage)
+		if err != nil {
+			return np, err
+		}
+		for _, repo := range repos {
+			records = append(records, repo.ToMap())
+		}
+		return pageInfo, nil
+	})
+	if rerr != nil {
+		return
+	}
+
+	res.Data = records
+
+	return
+}
+
+// onboardExportPlan returns repos that would be exported. Bitbucket Server api does not return totals, so only repo listing is estimated.
+func (s *Integration) onboardExportPlan(ctx context.Context) (res rpcdef.OnboardExportResult, rerr error) {
+	repos, err := commonrepo.ReposAllSlice(func(res chan []commonrepo.Repo) error {
+		return api.ReposAll(s.qc, res)
+	})
+	if err != nil {
+		rerr = err
+		return
+	}
+	res.Data = commonrepo.Plan(s.logger, repos, s.config.FilterConfig, repoprojects.Pages(len(repos), 100), nil)
+	return
+}
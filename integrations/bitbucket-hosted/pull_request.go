@@ -0,0 +1,186 @@
+package main
+
+import (
+	"net/url"
+
+	"github.com/pinpt/agent/integrations/pkg/objsender"
+	"github.com/pinpt/agent/integrations/pkg/repoprojects"
+	"github.com/pinpt/agent/rpcdef"
+
+	"github.com/hashicorp/go-hclog"
+	"github.com/pinpt/agent/integrations/bitbucket-hosted/api"
+	"github.com/pinpt/agent/integrations/pkg/commonrepo"
+	"github.com/pinpt/integration-sdk/sourcecode"
+)
+
+type prSenders struct {
+	prs      *objsender.Session
+	comments *objsender.Session
+	reviews  *objsender.Session
+	commits  *objsender.Session
+}
+
+func (s *Integration) exportPullRequestsForRepo(ctx *repoprojects.ProjectCtx, repo commonrepo.Repo) (res []rpcdef.GitRepoFetchPR, rerr error) {
+
+	senders := prSenders{}
+	var err error
+
+	senders.prs, err = ctx.Session(sourcecode.PullRequestModelName)
+	if err != nil {
+		rerr = err
+		return
+	}
+
+	senders.comments, err = ctx.Session(sourcecode.PullRequestCommentModelName)
+	if err != nil {
+		rerr = err
+		return
+	}
+
+	senders.commits, err = ctx.Session(sourcecode.PullRequestCommitModelName)
+	if err != nil {
+		rerr = err
+		return
+	}
+
+	senders.reviews, err = ctx.Session(sourcecode.PullRequestReviewModelName)
+	if err != nil {
+		rerr = err
+		return
+	}
+
+	ctx.Logger.Info("exporting")
+
+	params := url.Values{}
+	params.Set("state", "ALL")
+	// NEWEST sorts by updated date, so we can stop on incremental exports when reaching already processed prs
+	params.Set("order", "NEWEST")
+	params.Set("limit", "100")
+
+	stopOnUpdatedAt := senders.prs.LastProcessedTime()
+
+	rerr = api.Paginate(func(nextPage api.NextPage) (api.NextPage, error) {
+		np, prs, err := api.PullRequestPage(s.qc, ctx.Logger, repo, params, stopOnUpdatedAt, nextPage)
+		if err != nil {
+			return np, err
+		}
+		for _, pr := range prs {
+			logger := ctx.Logger.With("pr_id", pr.RefID)
+			meta, err := s.exportPullRequest(logger, senders, repo, pr)
+			if err != nil {
+				return np, err
+			}
+			if meta != nil {
+				res = append(res, *meta)
+			}
+		}
+		return np, nil
+	})
+	return
+}
+
+// exportPullRequest exports pull request with comments, reviews and commits. Returns pr info needed for git export, nil if pr has no commits.
+func (s *Integration) exportPullRequest(logger hclog.Logger, senders prSenders, repo commonrepo.Repo, pr sourcecode.PullRequest) (_ *rpcdef.GitRepoFetchPR, rerr error) {
+
+	closedByRefID, err := s.exportPullRequestActivities(logger, senders, repo, pr)
+	if err != nil {
+		rerr = err
+		return
+	}
+	switch pr.Status {
+	case sourcecode.PullRequestStatusMerged:
+		pr.MergedByRefID = closedByRefID
+	case sourcecode.PullRequestStatusClosed:
+		pr.ClosedByRefID = closedByRefID
+	}
+
+	commits, err := s.exportPullRequestCommits(logger, repo, pr)
+	if err != nil {
+		rerr = err
+		return
+	}
+
+	var meta *rpcdef.GitRepoFetchPR
+	if len(commits) > 0 {
+		meta = &rpcdef.GitRepoFetchPR{}
+		repoID := s.qc.IDs.CodeRepo(repo.RefID)
+		meta.ID = s.qc.IDs.CodePullRequest(repoID, pr.RefID)
+		meta.RefID = pr.RefID
+		meta.URL = pr.URL
+		meta.BranchName = pr.BranchName
+		meta.LastCommitSHA = commits[0].Sha
+	}
+
+	// commits are returned newest first
+	for ind := len(commits) - 1; ind >= 0; ind-- {
+		pr.CommitShas = append(pr.CommitShas, commits[ind].Sha)
+	}
+
+	pr.CommitIds = s.qc.IDs.CodeCommits(pr.RepoID, pr.CommitShas)
+	if len(pr.CommitShas) == 0 {
+		logger.Info("found PullRequest with no commits (ignoring it)", "repo", repo.NameWithOwner, "pr_ref_id", pr.RefID, "pr.url", pr.URL)
+	} else {
+		pr.BranchID = s.qc.IDs.CodeBranch(pr.RepoID, pr.BranchName, pr.CommitShas[0])
+	}
+
+	if err = senders.prs.Send(&pr); err != nil {
+		rerr = err
+		return
+	}
+
+	for _, c := range commits {
+		c.BranchID = pr.BranchID
+		if err := senders.commits.Send(c); err != nil {
+			rerr = err
+			return
+		}
+	}
+
+	return meta, nil
+}
+
+func (s *Integration) exportPullRequestActivities(logger hclog.Logger, senders prSenders, repo commonrepo.Repo, pr sourcecode.PullRequest) (closedByRefID string, rerr error) {
+
+	params := url.Values{}
+	params.Set("limit", "100")
+
+	rerr = api.Paginate(func(nextPage api.NextPage) (api.NextPage, error) {
+		np, res, err := api.PullRequestActivitiesPage(s.qc, logger, repo, pr, params, nextPage)
+		if err != nil {
+			return np, err
+		}
+		if closedByRefID == "" {
+			closedByRefID = res.ClosedByRefID
+		}
+		for _, obj := range res.Comments {
+			if err := senders.comments.Send(obj); err != nil {
+				return np, err
+			}
+		}
+		for _, obj := range res.Reviews {
+			if err := senders.reviews.Send(obj); err != nil {
+				return np, err
+			}
+		}
+		return np, nil
+	})
+	return
+}
+
+func (s *Integration) exportPullRequestCommits(logger hclog.Logger, repo commonrepo.Repo, pr sourcecode.PullRequest) (res []*sourcecode.PullRequestCommit, rerr error) {
+
+	params := url.Values{}
+	params.Set("limit", "100")
+
+	// always getting all commits, since they are needed for pr commit_shas
+	rerr = api.Paginate(func(nextPage api.NextPage) (api.NextPage, error) {
+		np, sub, err := api.PullRequestCommitsPage(s.qc, logger, repo, pr, params, nextPage)
+		if err != nil {
+			return np, err
+		}
+		res = append(res, sub...)
+		return np, nil
+	})
+
+	return
+}
@@ -7,11 +7,9 @@ import (
 	"net/url"
 	"reflect"
 	"strings"
-	"time"
 
 	"github.com/hashicorp/go-hclog"
 	"github.com/pinpt/agent/pkg/oauthtoken"
-	"github.com/pinpt/agent/rpcdef"
 	pstrings "github.com/pinpt/go-common/v10/strings"
 )
 
@@ -22,7 +20,6 @@ type RequesterOpts struct {
 	Password   string
 	UseOAuth   bool
 	OAuth      *oauthtoken.Manager
-	Agent      rpcdef.Agent
 	HTTPClient *http.Client
 }
 
@@ -134,15 +131,8 @@ func (e *Requester) request(r *internalRequest, retryThrottled int) (isErrorRetr
 		}
 
 		if resp.StatusCode == http.StatusTooManyRequests {
-			// according to docs there is quota available every minute
-			waitTime := 1 * time.Minute
-
-			e.opts.Logger.Warn("api request failed due to throttling, will sleep for 1m and retry", "retryThrottled", retryThrottled)
-			paused := time.Now()
-			e.opts.Agent.SendPauseEvent(fmt.Sprintf("bitbucket paused, it will resume in %v", waitTime), paused.Add(waitTime))
-			time.Sleep(waitTime)
-			e.opts.Agent.SendResumeEvent(fmt.Sprintf("bitbucket resumed, time elapsed %v", time.Since(paused)))
-			return true, np, fmt.Errorf("rate limit hit")
+			// http client already waited and retried
+			return false, np, fmt.Errorf("rate limit hit")
 		}
 
 		if resp.StatusCode == http.StatusNotFound {
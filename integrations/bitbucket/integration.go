@@ -18,6 +18,7 @@ import (
 	"github.com/pinpt/agent/integrations/bitbucket/api"
 	"github.com/pinpt/agent/integrations/pkg/commiturl"
 	"github.com/pinpt/agent/integrations/pkg/commonrepo"
+	"github.com/pinpt/agent/integrations/pkg/gitaccess"
 	"github.com/pinpt/agent/integrations/pkg/ibase"
 	"github.com/pinpt/agent/pkg/commitusers"
 	"github.com/pinpt/agent/pkg/ids2"
@@ -41,6 +42,7 @@ func NewIntegration(logger hclog.Logger) *Integration {
 
 type Config struct {
 	commonrepo.FilterConfig
+	gitaccess.Config
 	URL                string `json:"url"`
 	Username           string `json:"username"`
 	Password           string `json:"password"`
@@ -48,6 +50,9 @@ type Config struct {
 	InsecureSkipVerify bool   `json:"insecure_skip_verify"`
 
 	Exclusions []string `json:"exclusions"`
+
+	// MaxRequestsPerSecond limits the request rate to bitbucket api. No limit by default.
+	MaxRequestsPerSecond float64 `json:"max_requests_per_second"`
 }
 
 type Integration struct {
@@ -145,6 +150,8 @@ func (s *Integration) initWithConfig(config rpcdef.ExportConfig) error {
 	s.clientManager, err = reqstats.New(reqstats.Opts{
 		Logger:                s.logger,
 		TLSInsecureSkipVerify: s.config.InsecureSkipVerify,
+		MaxRequestsPerSecond:  s.config.MaxRequestsPerSecond,
+		Pauser:                s.agent,
 	})
 	if err != nil {
 		return err
@@ -158,7 +165,6 @@ func (s *Integration) initWithConfig(config rpcdef.ExportConfig) error {
 		opts.Password = s.config.Password
 		opts.UseOAuth = s.UseOAuth
 		opts.OAuth = oauth
-		opts.Agent = s.agent
 		opts.HTTPClient = s.clientManager.Clients.TLSInsecure
 		requester := api.NewRequester(opts)
 
@@ -193,6 +199,9 @@ func (s *Integration) setConfig(config rpcdef.ExportConfig) error {
 			return rerr("password is missing")
 		}
 	}
+	if err := def.Config.Validate(); err != nil {
+		return fmt.Errorf("config validation error: %v", err)
+	}
 	s.config = def
 	return nil
 }
@@ -466,7 +475,10 @@ func (s *Integration) exportGit(repo commonrepo.Repo, prs []rpcdef.GitRepoFetchP
 	args.RepoID = s.qc.IDs.CodeRepo(repo.RefID)
 	args.UniqueName = repo.NameWithOwner
 	args.RefType = s.refType
-	args.URL = repoURL
+	err = s.config.Config.Apply(&args, repoURL, repo.NameWithOwner+".git")
+	if err != nil {
+		return err
+	}
 	args.CommitURLTemplate = commiturl.CommitURLTemplate(repo, s.config.URL)
 	args.BranchURLTemplate = commiturl.BranchURLTemplate(repo, s.config.URL)
 	args.PRs = prs
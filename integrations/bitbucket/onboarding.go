@@ -5,6 +5,8 @@ import (
 	"net/url"
 
 	"github.com/pinpt/agent/integrations/bitbucket/api"
+	"github.com/pinpt/agent/integrations/pkg/commonrepo"
+	"github.com/pinpt/agent/integrations/pkg/repoprojects"
 	"github.com/pinpt/agent/rpcdef"
 )
 
@@ -17,6 +19,8 @@ func (s *Integration) OnboardExport(ctx context.Context, objectType rpcdef.Onboa
 	switch objectType {
 	case rpcdef.OnboardExportTypeRepos:
 		return s.onboardExportRepos(ctx)
+	case rpcdef.OnboardExportTypePlan:
+		return s.onboardExportPlan(ctx)
 	default:
 		res.Error = rpcdef.ErrOnboardExportNotSupported
 		return
@@ -47,3 +51,16 @@ func (s *Integration) onboardExportRepos(ctx context.Context) (res rpcdef.Onboar
 
 	return
 }
+
+// onboardExportPlan returns repos that would be exported. Only repo listing is estimated, pull request counts are not available without paginating.
+func (s *Integration) onboardExportPlan(ctx context.Context) (res rpcdef.OnboardExportResult, rerr error) {
+	repos, err := commonrepo.ReposAllSlice(func(res chan []commonrepo.Repo) error {
+		return api.ReposAll(s.qc, res)
+	})
+	if err != nil {
+		rerr = err
+		return
+	}
+	res.Data = commonrepo.Plan(s.logger, repos, s.config.FilterConfig, repoprojects.Pages(len(repos), 100), nil)
+	return
+}
@@ -0,0 +1,133 @@
+package api
+
+import (
+	"net/url"
+	"strconv"
+	"testing"
+
+	"github.com/hashicorp/go-hclog"
+	"github.com/pinpt/agent/integrations/gitea/giteatest"
+	"github.com/pinpt/agent/pkg/ids2"
+	"github.com/pinpt/integration-sdk/sourcecode"
+	"github.com/stretchr/testify/assert"
+)
+
+func testQueryContext(srv *giteatest.Server) QueryContext {
+	logger := hclog.NewNullLogger()
+	opts := RequesterOpts{}
+	opts.Logger = logger
+	opts.APIURL = srv.URL + "/api/v1"
+	opts.APIKey = srv.Token
+	opts.HTTPClient = srv.Client()
+	requester := NewRequester(opts)
+
+	qc := QueryContext{}
+	qc.BaseURL = srv.URL
+	qc.Logger = logger
+	qc.Request = requester.Request
+	qc.CustomerID = "c1"
+	qc.RefType = "gitea"
+	qc.IDs = ids2.New(qc.CustomerID, qc.RefType)
+	return qc
+}
+
+func TestNextPageFromLink(t *testing.T) {
+	cases := []struct {
+		In   string
+		Want int
+	}{
+		{"", 0},
+		{`<https://example.com/api/v1/repos/search?limit=50&page=3>; rel="last"`, 0},
+		{`<https://example.com/api/v1/repos/search?limit=50&page=2>; rel="next",<https://example.com/api/v1/repos/search?limit=50&page=3>; rel="last"`, 2},
+		{`<https://example.com/api/v1/repos/search?limit=50&page=1>; rel="first", <https://example.com/api/v1/repos/search?page=4&limit=50>; rel="next"`, 4},
+	}
+	for _, c := range cases {
+		got := nextPageFromLink(c.In)
+		if got != c.Want {
+			t.Errorf("invalid result for case %v want %v got %v", c.In, c.Want, got)
+		}
+	}
+}
+
+func TestReposAllPaginate(t *testing.T) {
+	srv := giteatest.New()
+	defer srv.Close()
+	for i := 1; i <= PageSize+5; i++ {
+		srv.Repos = append(srv.Repos, giteatest.Object{
+			"id":             i,
+			"full_name":      "org/r" + strconv.Itoa(i),
+			"default_branch": "main",
+			"empty":          i == 1,
+		})
+	}
+	qc := testQueryContext(srv)
+
+	var got []string
+	err := Paginate(func(paginationParams url.Values) (PageInfo, error) {
+		pi, repos, err := ReposPage(qc, paginationParams)
+		if err != nil {
+			return pi, err
+		}
+		assert.Equal(t, PageSize+5, pi.Total)
+		for _, r := range repos {
+			got = append(got, r.Common().NameWithOwner)
+		}
+		return pi, nil
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	assert.Len(t, got, PageSize+5)
+	assert.Equal(t, "org/r1", got[0])
+	assert.Equal(t, "org/r55", got[len(got)-1])
+}
+
+func TestRequestUnauthorized(t *testing.T) {
+	srv := giteatest.New()
+	defer srv.Close()
+	qc := testQueryContext(srv)
+	srv.Token = "other"
+	_, err := CurrentUser(qc)
+	if err == nil {
+		t.Fatal("expected error for invalid token")
+	}
+}
+
+func TestPullRequestSourcecode(t *testing.T) {
+	srv := giteatest.New()
+	defer srv.Close()
+	qc := testQueryContext(srv)
+
+	srv.PullRequests["org/r1"] = []giteatest.Object{
+		{
+			"number":           2,
+			"title":            "t2",
+			"body":             "b2",
+			"state":            "closed",
+			"merged":           true,
+			"merged_at":        "2020-01-02T00:00:00Z",
+			"merged_by":        giteatest.Object{"id": 5},
+			"merge_commit_sha": "m1",
+			"user":             giteatest.Object{"id": 3},
+			"created_at":       "2020-01-01T00:00:00Z",
+			"updated_at":       "2020-01-02T00:00:00Z",
+			"head":             giteatest.Object{"ref": "feature", "sha": "c2"},
+		},
+	}
+	repo := Repo{ID: 1, FullName: "org/r1"}.Common()
+
+	rpr, err := PullRequestByNumber(qc, "org/r1", 2)
+	if err != nil {
+		t.Fatal(err)
+	}
+	pr := rpr.Sourcecode(qc, repo)
+	assert := assert.New(t)
+	assert.Equal("2", pr.RefID)
+	assert.Equal("#2", pr.Identifier)
+	assert.Equal("feature", pr.BranchName)
+	assert.Equal(sourcecode.PullRequestStatusMerged, pr.Status)
+	assert.Equal("5", pr.MergedByRefID)
+	assert.Equal("3", pr.CreatedByRefID)
+	assert.Equal("m1", pr.MergeSha)
+	assert.NotZero(pr.MergedDate.Epoch)
+}
@@ -0,0 +1,72 @@
+package api
+
+import (
+	"net/url"
+	"time"
+
+	"github.com/hashicorp/go-hclog"
+	"github.com/pinpt/agent/pkg/commitusers"
+	pstrings "github.com/pinpt/go-common/v10/strings"
+)
+
+// Commit is the commit object returned by gitea api
+type Commit struct {
+	SHA     string `json:"sha"`
+	HTMLURL string `json:"html_url"`
+	Commit  struct {
+		Message string `json:"message"`
+		Author  struct {
+			Name  string    `json:"name"`
+			Email string    `json:"email"`
+			Date  time.Time `json:"date"`
+		} `json:"author"`
+		Committer struct {
+			Name  string    `json:"name"`
+			Email string    `json:"email"`
+			Date  time.Time `json:"date"`
+		} `json:"committer"`
+	} `json:"commit"`
+	// Author is the gitea user linked to commit author email, could be nil
+	Author *User `json:"author"`
+}
+
+// CommitUsersPage returns authors and committers of commits on default branch. Commits are returned newest first, stops when reaching commits older than stopOnUpdatedAt.
+func CommitUsersPage(qc QueryContext, logger hclog.Logger, nameWithOwner string, defaultBranch string, params url.Values, stopOnUpdatedAt time.Time) (pi PageInfo, res []commitusers.CommitUser, rerr error) {
+	logger.Debug("commit users", "default_branch", defaultBranch, "inc_date", stopOnUpdatedAt, "params", params)
+
+	params.Set("sha", defaultBranch)
+	// skip expensive stats and file lists, available in newer versions
+	params.Set("stat", "false")
+	params.Set("files", "false")
+
+	var commits []Commit
+	pi, err := qc.Request(pstrings.JoinURL("repos", nameWithOwner, "commits"), params, &commits)
+	if err != nil {
+		rerr = err
+		return
+	}
+	for _, c := range commits {
+		if c.Commit.Committer.Date.Before(stopOnUpdatedAt) {
+			pi.NextPage = 0
+			return
+		}
+		author := commitusers.CommitUser{}
+		author.CustomerID = qc.CustomerID
+		author.Email = c.Commit.Author.Email
+		author.Name = c.Commit.Author.Name
+		if c.Author != nil {
+			author.SourceID = c.Author.RefID()
+		}
+		if author.Email != "" {
+			res = append(res, author)
+		}
+		committer := commitusers.CommitUser{}
+		committer.CustomerID = qc.CustomerID
+		committer.Email = c.Commit.Committer.Email
+		committer.Name = c.Commit.Committer.Name
+		if committer.Email != "" && committer.Email != author.Email {
+			res = append(res, committer)
+		}
+	}
+	return
+}
@@ -0,0 +1,62 @@
+package api
+
+import (
+	"net/url"
+	"strconv"
+	"time"
+
+	"github.com/hashicorp/go-hclog"
+	"github.com/pinpt/agent/pkg/ids2"
+)
+
+// QueryContext query context
+type QueryContext struct {
+	// BaseURL is the website url of gitea instance, for example https://gitea.example.com
+	BaseURL string
+	Logger  hclog.Logger
+	Request func(objPath string, params url.Values, response interface{}) (PageInfo, error)
+
+	CustomerID string
+	RefType    string
+
+	IDs ids2.Gen
+}
+
+// PageInfo page info
+type PageInfo struct {
+	// NextPage is 0 when on last page
+	NextPage int
+	// Total is the number of objects returned in X-Total-Count header, 0 if not set
+	Total int
+}
+
+// User is the user object returned in all gitea apis
+type User struct {
+	ID        int64  `json:"id"`
+	Login     string `json:"login"`
+	FullName  string `json:"full_name"`
+	Email     string `json:"email"`
+	AvatarURL string `json:"avatar_url"`
+	IsAdmin   bool   `json:"is_admin"`
+}
+
+// RefID returns user id as string, empty for missing user
+func (s User) RefID() string {
+	if s.ID == 0 {
+		return ""
+	}
+	return strconv.FormatInt(s.ID, 10)
+}
+
+// Name returns full name if set, login otherwise
+func (s User) Name() string {
+	if s.FullName != "" {
+		return s.FullName
+	}
+	return s.Login
+}
+
+// isZero checks for unset dates, gitea returns 0001-01-01 or null for missing dates
+func isZero(t *time.Time) bool {
+	return t == nil || t.IsZero() || t.Year() <= 1
+}
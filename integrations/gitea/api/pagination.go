@@ -0,0 +1,44 @@
+package api
+
+import (
+	"net/url"
+	"strconv"
+)
+
+// PageSize is the default page size. Gitea limits max page size using MAX_RESPONSE_ITEMS setting, which is 50 by default.
+const PageSize = 50
+
+type PaginateFn func(paginationParams url.Values) (PageInfo, error)
+
+// Paginate calls fn for all pages, starting from the first one, passing page and limit params
+func Paginate(fn PaginateFn) error {
+	page := 1
+	for {
+		q := url.Values{}
+		q.Set("page", strconv.Itoa(page))
+		q.Set("limit", strconv.Itoa(PageSize))
+		pageInfo, err := fn(q)
+		if err != nil {
+			return err
+		}
+		if pageInfo.NextPage == 0 {
+			return nil
+		}
+		page = pageInfo.NextPage
+	}
+}
+
+// Count returns the total number of objects for list endpoint using X-Total-Count header, requesting only 1 object
+func Count(qc QueryContext, objPath string, params url.Values) (int, error) {
+	if params == nil {
+		params = url.Values{}
+	}
+	params.Set("page", "1")
+	params.Set("limit", "1")
+	var res []interface{}
+	pi, err := qc.Request(objPath, params, &res)
+	if err != nil {
+		return 0, err
+	}
+	return pi.Total, nil
+}
@@ -0,0 +1,244 @@
+package api
+
+import (
+	"fmt"
+	"net/url"
+	"sort"
+	"strconv"
+	"time"
+
+	"github.com/hashicorp/go-hclog"
+	"github.com/pinpt/agent/integrations/pkg/commonpr"
+	"github.com/pinpt/agent/integrations/pkg/commonrepo"
+	"github.com/pinpt/agent/pkg/date"
+	"github.com/pinpt/agent/pkg/ids"
+	pstrings "github.com/pinpt/go-common/v10/strings"
+	"github.com/pinpt/integration-sdk/sourcecode"
+)
+
+// PullRequest is the pull request object returned by gitea api
+type PullRequest struct {
+	ID             int64      `json:"id"`
+	Number         int64      `json:"number"`
+	Title          string     `json:"title"`
+	Body           string     `json:"body"`
+	State          string     `json:"state"`
+	HTMLURL        string     `json:"html_url"`
+	User           User       `json:"user"`
+	Merged         bool       `json:"merged"`
+	MergedAt       *time.Time `json:"merged_at"`
+	MergedBy       *User      `json:"merged_by"`
+	MergeCommitSHA string     `json:"merge_commit_sha"`
+	CreatedAt      time.Time  `json:"created_at"`
+	UpdatedAt      time.Time  `json:"updated_at"`
+	ClosedAt       *time.Time `json:"closed_at"`
+	Head           struct {
+		Ref string `json:"ref"`
+		SHA string `json:"sha"`
+	} `json:"head"`
+}
+
+// PullRequestsPage returns a page of pull requests sorted by recent update. Stops when reaching pull requests not updated since stopOnUpdatedAt.
+func PullRequestsPage(qc QueryContext, logger hclog.Logger, repo commonrepo.Repo, params url.Values, stopOnUpdatedAt time.Time) (pi PageInfo, res []PullRequest, rerr error) {
+	logger.Debug("repo prs", "params", params, "inc_date", stopOnUpdatedAt)
+
+	params.Set("state", "all")
+	params.Set("sort", "recentupdate")
+
+	var prs []PullRequest
+	pi, err := qc.Request(pstrings.JoinURL("repos", repo.NameWithOwner, "pulls"), params, &prs)
+	if err != nil {
+		rerr = err
+		return
+	}
+	for _, pr := range prs {
+		if pr.UpdatedAt.Before(stopOnUpdatedAt) {
+			pi.NextPage = 0
+			return
+		}
+		res = append(res, pr)
+	}
+	return
+}
+
+// PullRequestByNumber returns a single pull request, used in webhooks
+func PullRequestByNumber(qc QueryContext, nameWithOwner string, number int64) (res PullRequest, rerr error) {
+	_, rerr = qc.Request(pstrings.JoinURL("repos", nameWithOwner, "pulls", strconv.FormatInt(number, 10)), nil, &res)
+	return
+}
+
+// RefID returns pull request number as string, number is used as ref_id since all apis and urls use it
+func (s PullRequest) RefID() string {
+	return strconv.FormatInt(s.Number, 10)
+}
+
+// Sourcecode converts pull request to sourcecode.PullRequest, without commit fields
+func (s PullRequest) Sourcecode(qc QueryContext, repo commonrepo.Repo) sourcecode.PullRequest {
+	pr := sourcecode.PullRequest{}
+	pr.CustomerID = qc.CustomerID
+	pr.RefType = qc.RefType
+	pr.RefID = s.RefID()
+	pr.RepoID = qc.IDs.CodeRepo(repo.RefID)
+	pr.BranchName = s.Head.Ref
+	pr.Title = s.Title
+	pr.Description = commonpr.ConvertMarkdownToHTML(s.Body)
+	pr.URL = s.HTMLURL
+	pr.Identifier = fmt.Sprintf("#%d", s.Number)
+	pr.CreatedByRefID = s.User.RefID()
+	date.ConvertToModel(s.CreatedAt, &pr.CreatedDate)
+	date.ConvertToModel(s.UpdatedAt, &pr.UpdatedDate)
+	switch {
+	case s.Merged:
+		pr.Status = sourcecode.PullRequestStatusMerged
+		if !isZero(s.MergedAt) {
+			date.ConvertToModel(*s.MergedAt, &pr.MergedDate)
+			date.ConvertToModel(*s.MergedAt, &pr.ClosedDate)
+		}
+		if s.MergedBy != nil {
+			pr.MergedByRefID = s.MergedBy.RefID()
+		}
+		if s.MergeCommitSHA != "" {
+			pr.MergeSha = s.MergeCommitSHA
+			pr.MergeCommitID = ids.CodeCommit(qc.CustomerID, qc.RefType, pr.RepoID, s.MergeCommitSHA)
+		}
+	case s.State == "closed":
+		pr.Status = sourcecode.PullRequestStatusClosed
+		if !isZero(s.ClosedAt) {
+			date.ConvertToModel(*s.ClosedAt, &pr.ClosedDate)
+		}
+	case s.State == "open":
+		pr.Status = sourcecode.PullRequestStatusOpen
+	default:
+		qc.Logger.Error("PR has an unknown state", "state", s.State, "ref_id", pr.RefID)
+	}
+	return pr
+}
+
+// PullRequestCommitsPage returns a page of pull request commits. Requires gitea 1.17 or newer.
+func PullRequestCommitsPage(qc QueryContext, logger hclog.Logger, repo commonrepo.Repo, prRefID string, params url.Values) (pi PageInfo, res []*sourcecode.PullRequestCommit, rerr error) {
+	logger.Debug("pr commits", "params", params)
+
+	params.Set("stat", "false")
+	params.Set("files", "false")
+
+	var commits []Commit
+	pi, err := qc.Request(pstrings.JoinURL("repos", repo.NameWithOwner, "pulls", prRefID, "commits"), params, &commits)
+	if err != nil {
+		rerr = err
+		return
+	}
+	for _, c := range commits {
+		item := &sourcecode.PullRequestCommit{}
+		item.CustomerID = qc.CustomerID
+		item.RefType = qc.RefType
+		item.RefID = c.SHA
+		item.RepoID = qc.IDs.CodeRepo(repo.RefID)
+		item.PullRequestID = qc.IDs.CodePullRequest(item.RepoID, prRefID)
+		item.Sha = c.SHA
+		item.Message = c.Commit.Message
+		item.URL = c.HTMLURL
+		date.ConvertToModel(c.Commit.Committer.Date, &item.CreatedDate)
+		item.AuthorRefID = ids.CodeCommitEmail(qc.CustomerID, c.Commit.Author.Email)
+		item.CommitterRefID = ids.CodeCommitEmail(qc.CustomerID, c.Commit.Committer.Email)
+		res = append(res, item)
+	}
+	return
+}
+
+// SortPullRequestCommits sorts commits by created date, oldest first
+func SortPullRequestCommits(commits []*sourcecode.PullRequestCommit) {
+	sort.SliceStable(commits, func(i, j int) bool {
+		return commits[i].CreatedDate.Epoch < commits[j].CreatedDate.Epoch
+	})
+}
+
+// PullRequestReviewsPage returns a page of pull request reviews
+func PullRequestReviewsPage(qc QueryContext, logger hclog.Logger, repo commonrepo.Repo, prRefID string, params url.Values) (pi PageInfo, res []*sourcecode.PullRequestReview, rerr error) {
+	logger.Debug("pr reviews", "params", params)
+
+	var reviews []struct {
+		ID          int64     `json:"id"`
+		User        *User     `json:"user"`
+		State       string    `json:"state"`
+		Dismissed   bool      `json:"dismissed"`
+		Stale       bool      `json:"stale"`
+		SubmittedAt time.Time `json:"submitted_at"`
+		HTMLURL     string    `json:"html_url"`
+	}
+	pi, err := qc.Request(pstrings.JoinURL("repos", repo.NameWithOwner, "pulls", prRefID, "reviews"), params, &reviews)
+	if err != nil {
+		rerr = err
+		return
+	}
+	for _, r := range reviews {
+		if r.User == nil {
+			// reviews by deleted users
+			continue
+		}
+		item := &sourcecode.PullRequestReview{}
+		item.CustomerID = qc.CustomerID
+		item.RefType = qc.RefType
+		item.RefID = strconv.FormatInt(r.ID, 10)
+		item.RepoID = qc.IDs.CodeRepo(repo.RefID)
+		item.PullRequestID = qc.IDs.CodePullRequest(item.RepoID, prRefID)
+		item.URL = r.HTMLURL
+		item.UserRefID = r.User.RefID()
+		date.ConvertToModel(r.SubmittedAt, &item.CreatedDate)
+		switch r.State {
+		case "APPROVED":
+			item.State = sourcecode.PullRequestReviewStateApproved
+		case "REQUEST_CHANGES":
+			item.State = sourcecode.PullRequestReviewStateChangesRequested
+		case "COMMENT":
+			item.State = sourcecode.PullRequestReviewStateCommented
+		case "PENDING":
+			item.State = sourcecode.PullRequestReviewStatePending
+		case "REQUEST_REVIEW":
+			item.State = sourcecode.PullRequestReviewStateRequested
+		default:
+			qc.Logger.Warn("unknown review state", "state", r.State, "pr_ref_id", prRefID)
+			continue
+		}
+		if r.Dismissed {
+			item.State = sourcecode.PullRequestReviewStateDismissed
+		}
+		res = append(res, item)
+	}
+	return
+}
+
+// PullRequestCommentsPage returns a page of pull request comments. Gitea stores pull request comments as issue comments.
+func PullRequestCommentsPage(qc QueryContext, logger hclog.Logger, repo commonrepo.Repo, prRefID string, params url.Values) (pi PageInfo, res []*sourcecode.PullRequestComment, rerr error) {
+	logger.Debug("pr comments", "params", params)
+
+	var comments []struct {
+		ID        int64     `json:"id"`
+		HTMLURL   string    `json:"html_url"`
+		User      *User     `json:"user"`
+		Body      string    `json:"body"`
+		CreatedAt time.Time `json:"created_at"`
+		UpdatedAt time.Time `json:"updated_at"`
+	}
+	pi, err := qc.Request(pstrings.JoinURL("repos", repo.NameWithOwner, "issues", prRefID, "comments"), params, &comments)
+	if err != nil {
+		rerr = err
+		return
+	}
+	for _, c := range comments {
+		item := &sourcecode.PullRequestComment{}
+		item.CustomerID = qc.CustomerID
+		item.RefType = qc.RefType
+		item.RefID = strconv.FormatInt(c.ID, 10)
+		item.RepoID = qc.IDs.CodeRepo(repo.RefID)
+		item.PullRequestID = qc.IDs.CodePullRequest(item.RepoID, prRefID)
+		item.URL = c.HTMLURL
+		item.Body = commonpr.ConvertMarkdownToHTML(c.Body)
+		if c.User != nil {
+			item.UserRefID = c.User.RefID()
+		}
+		date.ConvertToModel(c.CreatedAt, &item.CreatedDate)
+		date.ConvertToModel(c.UpdatedAt, &item.UpdatedDate)
+		res = append(res, item)
+	}
+	return
+}
@@ -0,0 +1,134 @@
+package api
+
+import (
+	"fmt"
+	"net/url"
+	"strconv"
+	"time"
+
+	"github.com/pinpt/agent/integrations/pkg/commonrepo"
+	"github.com/pinpt/agent/pkg/date"
+	"github.com/pinpt/go-common/v10/datetime"
+	"github.com/pinpt/integration-sdk/agent"
+	"github.com/pinpt/integration-sdk/sourcecode"
+)
+
+// Repo is the repository object returned by gitea api
+type Repo struct {
+	ID            int64     `json:"id"`
+	FullName      string    `json:"full_name"`
+	Description   string    `json:"description"`
+	Language      string    `json:"language"`
+	HTMLURL       string    `json:"html_url"`
+	CloneURL      string    `json:"clone_url"`
+	DefaultBranch string    `json:"default_branch"`
+	Empty         bool      `json:"empty"`
+	Archived      bool      `json:"archived"`
+	Fork          bool      `json:"fork"`
+	CreatedAt     time.Time `json:"created_at"`
+	UpdatedAt     time.Time `json:"updated_at"`
+}
+
+// RefID returns repo id as string
+func (s Repo) RefID() string {
+	return strconv.FormatInt(s.ID, 10)
+}
+
+// Common converts repo to commonrepo.Repo used for filtering and processing
+func (s Repo) Common() commonrepo.Repo {
+	res := commonrepo.Repo{}
+	res.RefID = s.RefID()
+	res.NameWithOwner = s.FullName
+	if !s.Empty {
+		res.DefaultBranch = s.DefaultBranch
+	}
+	return res
+}
+
+// ReposPage returns a page of repos the user has access to
+func ReposPage(qc QueryContext, params url.Values) (pi PageInfo, res []Repo, rerr error) {
+	qc.Logger.Debug("repos request", "params", params)
+
+	var resp struct {
+		OK   bool   `json:"ok"`
+		Data []Repo `json:"data"`
+	}
+	pi, err := qc.Request("repos/search", params, &resp)
+	if err != nil {
+		rerr = err
+		return
+	}
+	if !resp.OK {
+		rerr = fmt.Errorf("repos search returned ok=false")
+		return
+	}
+	return pi, resp.Data, nil
+}
+
+// ReposAll returns all repos user has access to, sending them to res channel page by page
+func ReposAll(qc interface{}, res chan []commonrepo.Repo) error {
+	return Paginate(func(paginationParams url.Values) (PageInfo, error) {
+		pi, repos, err := ReposPage(qc.(QueryContext), paginationParams)
+		if err != nil {
+			return pi, err
+		}
+		var sub []commonrepo.Repo
+		for _, repo := range repos {
+			sub = append(sub, repo.Common())
+		}
+		res <- sub
+		return pi, nil
+	})
+}
+
+// RepoByName returns a single repo by owner/name
+func RepoByName(qc QueryContext, nameWithOwner string) (res Repo, rerr error) {
+	_, rerr = qc.Request("repos/"+nameWithOwner, nil, &res)
+	return
+}
+
+// ReposForOnboardPage returns a page of repos in the format needed for onboarding
+func ReposForOnboardPage(qc QueryContext, params url.Values) (pi PageInfo, res []*agent.RepoResponseRepos, rerr error) {
+	pi, repos, err := ReposPage(qc, params)
+	if err != nil {
+		rerr = err
+		return
+	}
+	for _, r := range repos {
+		repo := &agent.RepoResponseRepos{
+			Active:      true,
+			RefID:       r.RefID(),
+			RefType:     qc.RefType,
+			Name:        r.FullName,
+			Description: r.Description,
+			Language:    r.Language,
+		}
+		date.ConvertToModel(r.CreatedAt, &repo.CreatedDate)
+		res = append(res, repo)
+	}
+	return
+}
+
+// ReposSourcecodePage returns a page of repos converted to sourcecode.Repo
+func ReposSourcecodePage(qc QueryContext, params url.Values) (pi PageInfo, res []*sourcecode.Repo, rerr error) {
+	pi, repos, err := ReposPage(qc, params)
+	if err != nil {
+		rerr = err
+		return
+	}
+	for _, r := range repos {
+		repo := &sourcecode.Repo{
+			RefID:       r.RefID(),
+			RefType:     qc.RefType,
+			CustomerID:  qc.CustomerID,
+			Name:        r.FullName,
+			URL:         r.HTMLURL,
+			Language:    r.Language,
+			Description: r.Description,
+			UpdatedAt:   datetime.TimeToEpoch(r.UpdatedAt),
+			Active:      !r.Archived,
+		}
+		res = append(res, repo)
+	}
+	return
+}
@@ -0,0 +1,158 @@
+package api
+
+import (
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"net/url"
+	"strconv"
+	"strings"
+
+	"github.com/hashicorp/go-hclog"
+	pstrings "github.com/pinpt/go-common/v10/strings"
+)
+
+// RequesterOpts requester opts
+type RequesterOpts struct {
+	Logger hclog.Logger
+	// APIURL is the base url for api, for example https://gitea.example.com/api/v1
+	APIURL     string
+	APIKey     string
+	HTTPClient *http.Client
+	// NoRetries disables retries, used for webhooks
+	NoRetries bool
+}
+
+// NewRequester new requester
+func NewRequester(opts RequesterOpts) *Requester {
+	s := &Requester{}
+	s.opts = opts
+	s.logger = opts.Logger
+	s.httpClient = opts.HTTPClient
+	return s
+}
+
+// Requester requester
+type Requester struct {
+	logger     hclog.Logger
+	opts       RequesterOpts
+	httpClient *http.Client
+}
+
+type internalRequest struct {
+	URL      string
+	Params   url.Values
+	Response interface{}
+}
+
+// Request makes a get request to objPath, decoding json response into response. Page info is extracted from response headers.
+func (e *Requester) Request(objPath string, params url.Values, response interface{}) (pi PageInfo, err error) {
+	ir := &internalRequest{
+		URL:      objPath,
+		Params:   params,
+		Response: response,
+	}
+	return e.makeRequestRetry(ir, 0)
+}
+
+const maxGeneralRetries = 2
+
+func (e *Requester) makeRequestRetry(req *internalRequest, generalRetry int) (pageInfo PageInfo, err error) {
+	var isRetryable bool
+	isRetryable, pageInfo, err = e.request(req, generalRetry+1)
+	if err != nil {
+		if !isRetryable || e.opts.NoRetries {
+			return pageInfo, err
+		}
+		if generalRetry >= maxGeneralRetries {
+			return pageInfo, fmt.Errorf(`can't retry request, too many retries, err: %v`, err)
+		}
+		return e.makeRequestRetry(req, generalRetry+1)
+	}
+	return
+}
+
+func (e *Requester) request(r *internalRequest, retryThrottled int) (isErrorRetryable bool, pi PageInfo, rerr error) {
+	u := pstrings.JoinURL(e.opts.APIURL, r.URL)
+
+	if len(r.Params) != 0 {
+		u += "?" + r.Params.Encode()
+	}
+
+	req, err := http.NewRequest(http.MethodGet, u, nil)
+	if err != nil {
+		return false, pi, err
+	}
+	req.Header.Set("Accept", "application/json")
+	req.Header.Set("Authorization", "token "+e.opts.APIKey)
+
+	resp, err := e.httpClient.Do(req)
+	if err != nil {
+		return true, pi, err
+	}
+	defer resp.Body.Close()
+
+	e.logger.Debug("api request", "url", u, "status", resp.StatusCode)
+
+	if resp.StatusCode != http.StatusOK {
+
+		if resp.StatusCode == http.StatusUnauthorized || resp.StatusCode == http.StatusForbidden {
+			return false, pi, fmt.Errorf("request not authorized, status: %v, token needs read access to repositories, users and organizations", resp.StatusCode)
+		}
+
+		if resp.StatusCode == http.StatusNotFound {
+			return false, pi, fmt.Errorf("not found: %v", u)
+		}
+
+		if resp.StatusCode == http.StatusTooManyRequests {
+			// http client already waited and retried
+			return false, pi, fmt.Errorf("rate limit hit")
+		}
+
+		e.logger.Warn("gitea returned invalid status code, retrying", "code", resp.StatusCode, "retry", retryThrottled)
+		return true, pi, fmt.Errorf(`gitea returned invalid status code: %v`, resp.StatusCode)
+	}
+
+	err = json.NewDecoder(resp.Body).Decode(r.Response)
+	if err != nil {
+		return false, pi, err
+	}
+
+	if v := resp.Header.Get("X-Total-Count"); v != "" {
+		pi.Total, err = strconv.Atoi(v)
+		if err != nil {
+			return false, pi, fmt.Errorf("invalid X-Total-Count header: %v", err)
+		}
+	}
+	pi.NextPage = nextPageFromLink(resp.Header.Get("Link"))
+
+	return
+}
+
+// nextPageFromLink returns page param from rel="next" link in Link header. Returns 0 if there is no next page.
+// Link header looks like <https://gitea.example.com/api/v1/repos/search?limit=50&page=2>; rel="next",<https://gitea.example.com/api/v1/repos/search?limit=50&page=3>; rel="last"
+func nextPageFromLink(header string) int {
+	for _, link := range strings.Split(header, ",") {
+		parts := strings.Split(link, ";")
+		if len(parts) < 2 {
+			continue
+		}
+		isNext := false
+		for _, p := range parts[1:] {
+			if strings.TrimSpace(p) == `rel="next"` {
+				isNext = true
+			}
+		}
+		if !isNext {
+			continue
+		}
+		raw := strings.Trim(strings.TrimSpace(parts[0]), "<>")
+		u, err := url.Parse(raw)
+		if err != nil {
+			return 0
+		}
+		page, _ := strconv.Atoi(u.Query().Get("page"))
+		return page
+	}
+	return 0
+}
@@ -0,0 +1,85 @@
+package api
+
+import (
+	"fmt"
+	"net/url"
+
+	"github.com/pinpt/agent/pkg/commitusers"
+	pstrings "github.com/pinpt/go-common/v10/strings"
+	"github.com/pinpt/integration-sdk/sourcecode"
+)
+
+// UsersPage returns a page of users. Email is only returned when token user is admin or user has public email.
+func UsersPage(qc QueryContext, params url.Values) (pi PageInfo, res []User, rerr error) {
+	qc.Logger.Debug("users request", "params", params)
+
+	var resp struct {
+		OK   bool   `json:"ok"`
+		Data []User `json:"data"`
+	}
+	pi, err := qc.Request("users/search", params, &resp)
+	if err != nil {
+		rerr = err
+		return
+	}
+	if !resp.OK {
+		rerr = fmt.Errorf("users search returned ok=false")
+		return
+	}
+	return pi, resp.Data, nil
+}
+
+// UsersSourcecodePage returns a page of users converted to sourcecode.User and commit users for users with email
+func UsersSourcecodePage(qc QueryContext, params url.Values) (pi PageInfo, users []*sourcecode.User, commitUsers []commitusers.CommitUser, rerr error) {
+	pi, rusers, err := UsersPage(qc, params)
+	if err != nil {
+		rerr = err
+		return
+	}
+	for _, u := range rusers {
+		users = append(users, UserToSourcecode(qc, u))
+		if u.Email != "" {
+			commitUsers = append(commitUsers, commitusers.CommitUser{
+				CustomerID: qc.CustomerID,
+				Email:      u.Email,
+				Name:       u.Name(),
+				SourceID:   u.RefID(),
+			})
+		}
+	}
+	return
+}
+
+// UserToSourcecode converts gitea user to sourcecode.User
+func UserToSourcecode(qc QueryContext, u User) *sourcecode.User {
+	user := &sourcecode.User{}
+	user.CustomerID = qc.CustomerID
+	user.RefType = qc.RefType
+	user.RefID = u.RefID()
+	user.Name = u.Name()
+	user.Username = pstrings.Pointer(u.Login)
+	user.AvatarURL = pstrings.Pointer(u.AvatarURL)
+	user.URL = pstrings.Pointer(pstrings.JoinURL(qc.BaseURL, u.Login))
+	user.Member = true
+	user.Type = sourcecode.UserTypeHuman
+	return user
+}
+
+// CurrentUser returns the user for the token, used to validate credentials
+func CurrentUser(qc QueryContext) (res User, rerr error) {
+	_, rerr = qc.Request("user", nil, &res)
+	return
+}
+
+// ServerVersion returns gitea version
+func ServerVersion(qc QueryContext) (_ string, rerr error) {
+	var res struct {
+		Version string `json:"version"`
+	}
+	_, err := qc.Request("version", nil, &res)
+	if err != nil {
+		rerr = err
+		return
+	}
+	return res.Version, nil
+}
@@ -0,0 +1,198 @@
+// Package giteatest provides a fake gitea api server for tests.
+//
+// Only the endpoints used by gitea integration are supported. Data is set directly on the server fields as json objects, lists are paginated using page and limit params, with X-Total-Count and Link headers set the same way as in gitea.
+package giteatest
+
+import (
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"strconv"
+	"strings"
+	"sync"
+)
+
+// Object is a json object returned by api
+type Object = map[string]interface{}
+
+// Server is a fake gitea server
+type Server struct {
+	*httptest.Server
+
+	// Token is the access token expected in Authorization header
+	Token string
+	// Version is returned by /version
+	Version string
+	// User is returned by /user
+	User Object
+
+	Repos []Object
+	Users []Object
+
+	// Commits contains default branch commits by repo full name
+	Commits map[string][]Object
+	// PullRequests by repo full name
+	PullRequests map[string][]Object
+	// PullRequestReviews, PullRequestComments and PullRequestCommits by PRKey
+	PullRequestReviews  map[string][]Object
+	PullRequestComments map[string][]Object
+	PullRequestCommits  map[string][]Object
+
+	mu       sync.Mutex
+	requests []string
+}
+
+// PRKey returns key used for pull request child objects
+func PRKey(repoFullName string, number int) string {
+	return repoFullName + "#" + strconv.Itoa(number)
+}
+
+// New creates and starts fake gitea server. Call Close when done.
+func New() *Server {
+	s := &Server{}
+	s.Token = "token1"
+	s.Version = "1.20.0"
+	s.User = Object{"id": 1, "login": "admin"}
+	s.Commits = map[string][]Object{}
+	s.PullRequests = map[string][]Object{}
+	s.PullRequestReviews = map[string][]Object{}
+	s.PullRequestComments = map[string][]Object{}
+	s.PullRequestCommits = map[string][]Object{}
+	s.Server = httptest.NewServer(http.HandlerFunc(s.handle))
+	return s
+}
+
+// Requests returns paths of all requests made to server, including query
+func (s *Server) Requests() []string {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	return append([]string{}, s.requests...)
+}
+
+func (s *Server) handle(w http.ResponseWriter, r *http.Request) {
+	s.mu.Lock()
+	s.requests = append(s.requests, r.URL.RequestURI())
+	s.mu.Unlock()
+
+	if r.Method != http.MethodGet {
+		http.Error(w, "method not allowed", http.StatusMethodNotAllowed)
+		return
+	}
+	if r.Header.Get("Authorization") != "token "+s.Token {
+		http.Error(w, "unauthorized", http.StatusUnauthorized)
+		return
+	}
+	if !strings.HasPrefix(r.URL.Path, "/api/v1/") {
+		http.NotFound(w, r)
+		return
+	}
+	parts := strings.Split(strings.TrimPrefix(r.URL.Path, "/api/v1/"), "/")
+
+	switch {
+	case len(parts) == 1 && parts[0] == "version":
+		writeJSON(w, Object{"version": s.Version})
+	case len(parts) == 1 && parts[0] == "user":
+		writeJSON(w, s.User)
+	case len(parts) == 2 && parts[0] == "repos" && parts[1] == "search":
+		s.writeSearch(w, r, s.Repos)
+	case len(parts) == 2 && parts[0] == "users" && parts[1] == "search":
+		s.writeSearch(w, r, s.Users)
+	case len(parts) >= 3 && parts[0] == "repos":
+		s.handleRepo(w, r, parts[1]+"/"+parts[2], parts[3:])
+	default:
+		http.NotFound(w, r)
+	}
+}
+
+func (s *Server) handleRepo(w http.ResponseWriter, r *http.Request, fullName string, parts []string) {
+	if len(parts) == 0 {
+		for _, repo := range s.Repos {
+			if repo["full_name"] == fullName {
+				writeJSON(w, repo)
+				return
+			}
+		}
+		http.NotFound(w, r)
+		return
+	}
+	switch {
+	case len(parts) == 1 && parts[0] == "commits":
+		s.writeList(w, r, s.Commits[fullName])
+	case len(parts) == 1 && parts[0] == "pulls":
+		s.writeList(w, r, s.PullRequests[fullName])
+	case len(parts) == 2 && parts[0] == "pulls":
+		number, _ := strconv.Atoi(parts[1])
+		for _, pr := range s.PullRequests[fullName] {
+			if fmt.Sprint(pr["number"]) == strconv.Itoa(number) {
+				writeJSON(w, pr)
+				return
+			}
+		}
+		http.NotFound(w, r)
+	case len(parts) == 3 && parts[0] == "pulls" && parts[2] == "reviews":
+		number, _ := strconv.Atoi(parts[1])
+		s.writeList(w, r, s.PullRequestReviews[PRKey(fullName, number)])
+	case len(parts) == 3 && parts[0] == "pulls" && parts[2] == "commits":
+		number, _ := strconv.Atoi(parts[1])
+		s.writeList(w, r, s.PullRequestCommits[PRKey(fullName, number)])
+	case len(parts) == 3 && parts[0] == "issues" && parts[2] == "comments":
+		number, _ := strconv.Atoi(parts[1])
+		s.writeList(w, r, s.PullRequestComments[PRKey(fullName, number)])
+	default:
+		http.NotFound(w, r)
+	}
+}
+
+// page returns objects for requested page and sets pagination headers
+func (s *Server) page(w http.ResponseWriter, r *http.Request, all []Object) []Object {
+	q := r.URL.Query()
+	page, _ := strconv.Atoi(q.Get("page"))
+	if page < 1 {
+		page = 1
+	}
+	limit, _ := strconv.Atoi(q.Get("limit"))
+	if limit < 1 {
+		limit = 30
+	}
+	start := (page - 1) * limit
+	end := start + limit
+	if start > len(all) {
+		start = len(all)
+	}
+	if end > len(all) {
+		end = len(all)
+	}
+	w.Header().Set("X-Total-Count", strconv.Itoa(len(all)))
+	if end < len(all) {
+		next := url.Values{}
+		for k, v := range q {
+			next[k] = v
+		}
+		next.Set("page", strconv.Itoa(page+1))
+		u := s.URL + r.URL.Path + "?" + next.Encode()
+		w.Header().Set("Link", `<`+u+`>; rel="next"`)
+	}
+	res := all[start:end]
+	if res == nil {
+		res = []Object{}
+	}
+	return res
+}
+
+func (s *Server) writeList(w http.ResponseWriter, r *http.Request, all []Object) {
+	writeJSON(w, s.page(w, r, all))
+}
+
+func (s *Server) writeSearch(w http.ResponseWriter, r *http.Request, all []Object) {
+	writeJSON(w, Object{"ok": true, "data": s.page(w, r, all)})
+}
+
+func writeJSON(w http.ResponseWriter, obj interface{}) {
+	w.Header().Set("Content-Type", "application/json")
+	err := json.NewEncoder(w).Encode(obj)
+	if err != nil {
+		panic(err)
+	}
+}
@@ -0,0 +1,272 @@
+package main
+
+import (
+	"context"
+	"strconv"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/hashicorp/go-hclog"
+	"github.com/pinpt/agent/integrations/gitea/giteatest"
+	"github.com/pinpt/agent/integrations/pkg/repoprojects"
+	"github.com/pinpt/agent/pkg/metrics"
+	"github.com/pinpt/agent/rpcdef"
+	"github.com/pinpt/integration-sdk/sourcecode"
+	"github.com/stretchr/testify/assert"
+)
+
+// testAgent implements rpcdef.Agent, storing all sent objects by model name
+type testAgent struct {
+	mu       sync.Mutex
+	lastID   int
+	sessions map[int]string
+	objs     map[string][]map[string]interface{}
+	fetches  []rpcdef.GitRepoFetch
+}
+
+func newTestAgent() *testAgent {
+	s := &testAgent{}
+	s.sessions = map[int]string{}
+	s.objs = map[string][]map[string]interface{}{}
+	return s
+}
+
+func (s *testAgent) ExportStarted(modelType string) (sessionID string, lastProcessed interface{}) {
+	id, _, _ := s.SessionStart(false, modelType, 0, "", "")
+	return strconv.Itoa(id), nil
+}
+
+func (s *testAgent) ExportDone(sessionID string, lastProcessed interface{}) {}
+
+func (s *testAgent) SendExported(sessionID string, objs []rpcdef.ExportObj) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	id, _ := strconv.Atoi(sessionID)
+	name := s.sessions[id]
+	for _, obj := range objs {
+		s.objs[name] = append(s.objs[name], obj.Data.(map[string]interface{}))
+	}
+}
+
+func (s *testAgent) SessionStart(isTracking bool, name string, parentSessionID int, parentObjectID, parentObjectName string) (sessionID int, lastProcessed interface{}, _ error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.lastID++
+	s.sessions[s.lastID] = name
+	return s.lastID, nil, nil
+}
+
+func (s *testAgent) SessionProgress(id int, current, total int) error { return nil }
+
+func (s *testAgent) SessionRollback(id int) error { return nil }
+
+func (s *testAgent) ExportGitRepo(fetch rpcdef.GitRepoFetch) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.fetches = append(s.fetches, fetch)
+	return nil
+}
+
+func (s *testAgent) OAuthNewAccessToken() (token string, _ error) { return "", nil }
+
+func (s *testAgent) OAuthNewAccessTokenFromRefreshToken(name string, refresh string) (token string, _ error) {
+	return "", nil
+}
+
+func (s *testAgent) SendPauseEvent(msg string, resumeDate time.Time) error { return nil }
+
+func (s *testAgent) SendResumeEvent(msg string) error { return nil }
+
+func (s *testAgent) GetWebhookURL() (url string, _ error) { return "", nil }
+
+func (s *testAgent) SendMetrics(data metrics.Snapshot) error { return nil }
+
+func (s *testAgent) refIDs(modelName string) (res []string) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	for _, obj := range s.objs[modelName] {
+		res = append(res, obj["ref_id"].(string))
+	}
+	return
+}
+
+func newTestServer() *giteatest.Server {
+	srv := giteatest.New()
+	srv.Repos = []giteatest.Object{
+		{"id": 1, "full_name": "org/r1", "default_branch": "main", "html_url": srv.URL + "/org/r1", "updated_at": "2020-01-03T00:00:00Z"},
+		{"id": 2, "full_name": "org/empty", "default_branch": "main", "empty": true, "updated_at": "2020-01-03T00:00:00Z"},
+	}
+	srv.Users = []giteatest.Object{
+		{"id": 3, "login": "u3", "full_name": "User 3", "email": "u3@example.com"},
+		{"id": 4, "login": "u4"},
+	}
+	srv.Commits["org/r1"] = []giteatest.Object{
+		{"sha": "c2", "commit": giteatest.Object{
+			"author":    giteatest.Object{"name": "User 3", "email": "u3@example.com", "date": "2020-01-02T00:00:00Z"},
+			"committer": giteatest.Object{"name": "User 3", "email": "u3@example.com", "date": "2020-01-02T00:00:00Z"},
+		}, "author": giteatest.Object{"id": 3}},
+	}
+	srv.PullRequests["org/r1"] = []giteatest.Object{
+		{
+			"number":     1,
+			"title":      "t1",
+			"state":      "open",
+			"user":       giteatest.Object{"id": 3},
+			"created_at": "2020-01-01T00:00:00Z",
+			"updated_at": "2020-01-02T00:00:00Z",
+			"head":       giteatest.Object{"ref": "feature", "sha": "c2"},
+			"html_url":   srv.URL + "/org/r1/pulls/1",
+		},
+	}
+	key := giteatest.PRKey("org/r1", 1)
+	srv.PullRequestReviews[key] = []giteatest.Object{
+		{"id": 10, "user": giteatest.Object{"id": 4}, "state": "APPROVED", "submitted_at": "2020-01-02T00:00:00Z"},
+		{"id": 11, "user": giteatest.Object{"id": 4}, "state": "REQUEST_CHANGES", "dismissed": true, "submitted_at": "2020-01-02T00:00:00Z"},
+	}
+	srv.PullRequestComments[key] = []giteatest.Object{
+		{"id": 20, "user": giteatest.Object{"id": 4}, "body": "comment", "created_at": "2020-01-02T00:00:00Z", "updated_at": "2020-01-02T00:00:00Z"},
+	}
+	srv.PullRequestCommits[key] = []giteatest.Object{
+		{"sha": "c2", "commit": giteatest.Object{
+			"message":   "second",
+			"author":    giteatest.Object{"email": "u3@example.com", "date": "2020-01-02T00:00:00Z"},
+			"committer": giteatest.Object{"email": "u3@example.com", "date": "2020-01-02T00:00:00Z"},
+		}},
+		{"sha": "c1", "commit": giteatest.Object{
+			"message":   "first",
+			"author":    giteatest.Object{"email": "u3@example.com", "date": "2020-01-01T00:00:00Z"},
+			"committer": giteatest.Object{"email": "u3@example.com", "date": "2020-01-01T00:00:00Z"},
+		}},
+	}
+	return srv
+}
+
+func newTestIntegration(srv *giteatest.Server) (*Integration, *testAgent, rpcdef.ExportConfig) {
+	agent := newTestAgent()
+	s := NewIntegration(hclog.NewNullLogger())
+	err := s.Init(agent)
+	if err != nil {
+		panic(err)
+	}
+	config := rpcdef.ExportConfig{}
+	config.Pinpoint.CustomerID = "c1"
+	config.Integration.Config = map[string]interface{}{
+		"url":     srv.URL,
+		"api_key": srv.Token,
+	}
+	return s, agent, config
+}
+
+func TestExport(t *testing.T) {
+	srv := newTestServer()
+	defer srv.Close()
+	s, agent, config := newTestIntegration(srv)
+
+	res, err := s.Export(context.Background(), config)
+	if err != nil {
+		t.Fatal(err)
+	}
+	assert := assert.New(t)
+	assert.Len(res.Projects, 2)
+	for _, p := range res.Projects {
+		assert.Empty(p.Error, p.ReadableID)
+	}
+
+	assert.Equal([]string{"1", "2"}, agent.refIDs(sourcecode.RepoModelName.String()))
+	assert.Equal([]string{"3", "4"}, agent.refIDs(sourcecode.UserModelName.String()))
+	assert.Equal([]string{"1"}, agent.refIDs(sourcecode.PullRequestModelName.String()))
+	assert.Equal([]string{"10", "11"}, agent.refIDs(sourcecode.PullRequestReviewModelName.String()))
+	assert.Equal([]string{"20"}, agent.refIDs(sourcecode.PullRequestCommentModelName.String()))
+	// sorted oldest first
+	assert.Equal([]string{"c1", "c2"}, agent.refIDs(sourcecode.PullRequestCommitModelName.String()))
+
+	assert.Len(agent.fetches, 2)
+	fetch := agent.fetches[0]
+	assert.Equal("org/r1", fetch.UniqueName)
+	assert.Equal("gitea", fetch.RefType)
+	assert.Len(fetch.PRs, 1)
+	assert.Equal("c2", fetch.PRs[0].LastCommitSHA)
+	assert.Equal("feature", fetch.PRs[0].BranchName)
+}
+
+func TestValidateConfig(t *testing.T) {
+	srv := newTestServer()
+	defer srv.Close()
+	s, _, config := newTestIntegration(srv)
+
+	res, err := s.ValidateConfig(context.Background(), config)
+	if err != nil {
+		t.Fatal(err)
+	}
+	assert.Empty(t, res.Errors)
+	assert.Equal(t, srv.Version, res.ServerVersion)
+
+	srv.Token = "other"
+	res, err = s.ValidateConfig(context.Background(), config)
+	if err != nil {
+		t.Fatal(err)
+	}
+	assert.NotEmpty(t, res.Errors)
+}
+
+func TestWebhookPullRequest(t *testing.T) {
+	srv := newTestServer()
+	defer srv.Close()
+	s, agent, config := newTestIntegration(srv)
+
+	body := `{"action":"opened","number":1,"pull_request":{"number":1},"repository":{"id":1,"full_name":"org/r1","default_branch":"main"}}`
+	res, err := s.Webhook(context.Background(), map[string]string{"x-gitea-event": "pull_request"}, body, config)
+	if err != nil {
+		t.Fatal(err)
+	}
+	assert := assert.New(t)
+	assert.Empty(res.Error)
+	assert.Len(res.MutatedObjects[sourcecode.PullRequestModelName.String()], 1)
+	assert.Len(res.MutatedObjects[sourcecode.PullRequestReviewModelName.String()], 2)
+	assert.Len(res.MutatedObjects[sourcecode.PullRequestCommentModelName.String()], 1)
+	assert.Len(res.MutatedObjects[sourcecode.PullRequestCommitModelName.String()], 2)
+	assert.Len(agent.fetches, 1)
+	assert.Len(agent.fetches[0].PRs, 1)
+}
+
+func TestWebhookPush(t *testing.T) {
+	srv := newTestServer()
+	defer srv.Close()
+	s, agent, config := newTestIntegration(srv)
+
+	body := `{"ref":"refs/heads/main","repository":{"id":1,"full_name":"org/r1","default_branch":"main"}}`
+	res, err := s.Webhook(context.Background(), map[string]string{"x-forgejo-event": "push"}, body, config)
+	if err != nil {
+		t.Fatal(err)
+	}
+	assert.Empty(t, res.Error)
+	assert.Len(t, agent.fetches, 1)
+	assert.Len(t, agent.fetches[0].PRs, 0)
+}
+
+func TestOnboardExportPlan(t *testing.T) {
+	srv := newTestServer()
+	defer srv.Close()
+	s, agent, config := newTestIntegration(srv)
+	config.Integration.Config["exclusions"] = []string{"2"}
+
+	res, err := s.OnboardExport(context.Background(), rpcdef.OnboardExportTypePlan, config)
+	if err != nil {
+		t.Fatal(err)
+	}
+	assert := assert.New(t)
+	assert.NoError(res.Error)
+	plan := res.Data.(repoprojects.Plan)
+	assert.Len(plan.Projects, 1)
+	p := plan.Projects[0]
+	assert.Equal("org/r1", p.ReadableID)
+	assert.Equal(1, p.Counts[sourcecode.PullRequestModelName.String()])
+	assert.Equal(1, p.Counts[sourcecode.CommitModelName.String()])
+	// 1 page of prs, 3 calls for pr children and 1 page of commits
+	assert.Equal(5, p.APICalls)
+	assert.Equal([]repoprojects.PlanExcluded{{RefID: "2", ReadableID: "org/empty", Reason: repoprojects.ExcludedInExclusions}}, plan.Excluded)
+	// 2 calls to list repos
+	assert.Equal(7, plan.APICalls)
+	assert.Empty(agent.objs, "plan should not export data")
+}
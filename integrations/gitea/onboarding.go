@@ -0,0 +1,117 @@
+package main
+
+import (
+	"context"
+	"net/url"
+
+	"github.com/pinpt/agent/integrations/gitea/api"
+	"github.com/pinpt/agent/integrations/pkg/commonrepo"
+	"github.com/pinpt/agent/integrations/pkg/repoprojects"
+	"github.com/pinpt/agent/rpcdef"
+	pstrings "github.com/pinpt/go-common/v10/strings"
+	"github.com/pinpt/integration-sdk/sourcecode"
+)
+
+// OnboardExport onboard export
+func (s *Integration) OnboardExport(ctx context.Context, objectType rpcdef.OnboardExportType, config rpcdef.ExportConfig) (res rpcdef.OnboardExportResult, _ error) {
+	err := s.initWithConfig(config, true)
+	if err != nil {
+		return res, err
+	}
+	switch objectType {
+	case rpcdef.OnboardExportTypeRepos:
+		return s.onboardExportRepos(ctx)
+	case rpcdef.OnboardExportTypeUsers:
+		return s.onboardExportUsers(ctx)
+	case rpcdef.OnboardExportTypePlan:
+		return s.onboardExportPlan(ctx)
+	default:
+		res.Error = rpcdef.ErrOnboardExportNotSupported
+		return
+	}
+}
+
+func (s *Integration) onboardExportRepos(ctx context.Context) (res rpcdef.OnboardExportResult, rerr error) {
+	var records []map[string]interface{}
+
+	rerr = api.Paginate(func(paginationParams url.Values) (api.PageInfo, error) {
+		pi, repos, err := api.ReposForOnboardPage(s.qc, paginationParams)
+		if err != nil {
+			return pi, err
+		}
+		for _, repo := range repos {
+			records = append(records, repo.ToMap())
+		}
+		return pi, nil
+	})
+	if rerr != nil {
+		return
+	}
+
+	res.Data = records
+
+	return
+}
+
+func (s *Integration) onboardExportUsers(ctx context.Context) (res rpcdef.OnboardExportResult, rerr error) {
+	var records []map[string]interface{}
+
+	rerr = api.Paginate(func(paginationParams url.Values) (api.PageInfo, error) {
+		pi, users, _, err := api.UsersSourcecodePage(s.qc, paginationParams)
+		if err != nil {
+			return pi, err
+		}
+		for _, user := range users {
+			records = append(records, user.ToMap())
+		}
+		return pi, nil
+	})
+	if rerr != nil {
+		return
+	}
+
+	res.Data = records
+
+	return
+}
+
+func (s *Integration) onboardExportPlan(ctx context.Context) (res rpcdef.OnboardExportResult, rerr error) {
+	repos, err := commonrepo.ReposAllSlice(func(res chan []commonrepo.Repo) error {
+		return api.ReposAll(s.qc, res)
+	})
+	if err != nil {
+		rerr = err
+		return
+	}
+	// repos are listed twice in export, for filtering and when sending repo objects
+	discoveryCalls := 2 * repoprojects.Pages(len(repos), api.PageSize)
+	res.Data = commonrepo.Plan(s.logger, repos, s.config.FilterConfig, discoveryCalls, s.planRepo)
+	return
+}
+
+func (s *Integration) planRepo(repo commonrepo.Repo) (res repoprojects.PlanProject, rerr error) {
+	res.Counts = map[string]int{}
+
+	prs, err := api.Count(s.qc, pstrings.JoinURL("repos", repo.NameWithOwner, "pulls"), url.Values{"state": []string{"all"}})
+	if err != nil {
+		rerr = err
+		return
+	}
+	res.Counts[sourcecode.PullRequestModelName.String()] = prs
+	// pull request pages, and reviews, comments and commits for each pull request
+	res.APICalls += repoprojects.Pages(prs, api.PageSize) + 3*prs
+
+	if repo.DefaultBranch == "" {
+		// empty repo
+		return
+	}
+	commits, err := api.Count(s.qc, pstrings.JoinURL("repos", repo.NameWithOwner, "commits"), url.Values{"sha": []string{repo.DefaultBranch}})
+	if err != nil {
+		rerr = err
+		return
+	}
+	res.Counts[sourcecode.CommitModelName.String()] = commits
+	// commit users are read from default branch commits
+	res.APICalls += repoprojects.Pages(commits, api.PageSize)
+	return
+}
@@ -0,0 +1,167 @@
+package main
+
+import (
+	"net/url"
+
+	"github.com/hashicorp/go-hclog"
+	"github.com/pinpt/agent/integrations/gitea/api"
+	"github.com/pinpt/agent/integrations/pkg/commonrepo"
+	"github.com/pinpt/agent/integrations/pkg/objsender"
+	"github.com/pinpt/agent/integrations/pkg/repoprojects"
+	"github.com/pinpt/agent/rpcdef"
+	"github.com/pinpt/integration-sdk/sourcecode"
+)
+
+// prSenders contains sessions for pull request and child objects. Uses SessionCommon, so that the same code is used for export and webhooks.
+type prSenders struct {
+	prs      objsender.SessionCommon
+	comments objsender.SessionCommon
+	reviews  objsender.SessionCommon
+	commits  objsender.SessionCommon
+}
+
+func (s *Integration) exportPullRequestsForRepo(ctx *repoprojects.ProjectCtx, repo commonrepo.Repo) (res []rpcdef.GitRepoFetchPR, rerr error) {
+
+	prSender, err := ctx.Session(sourcecode.PullRequestModelName)
+	if err != nil {
+		rerr = err
+		return
+	}
+	senders := prSenders{}
+	senders.prs = prSender
+
+	senders.comments, err = ctx.Session(sourcecode.PullRequestCommentModelName)
+	if err != nil {
+		rerr = err
+		return
+	}
+
+	senders.commits, err = ctx.Session(sourcecode.PullRequestCommitModelName)
+	if err != nil {
+		rerr = err
+		return
+	}
+
+	senders.reviews, err = ctx.Session(sourcecode.PullRequestReviewModelName)
+	if err != nil {
+		rerr = err
+		return
+	}
+
+	ctx.Logger.Info("exporting")
+
+	stopOnUpdatedAt := prSender.LastProcessedTime()
+
+	rerr = api.Paginate(func(paginationParams url.Values) (api.PageInfo, error) {
+		pi, prs, err := api.PullRequestsPage(s.qc, ctx.Logger, repo, paginationParams, stopOnUpdatedAt)
+		if err != nil {
+			return pi, err
+		}
+		for _, pr := range prs {
+			logger := ctx.Logger.With("pr_id", pr.RefID())
+			meta, err := s.exportPullRequest(logger, senders, repo, pr)
+			if err != nil {
+				return pi, err
+			}
+			if meta != nil {
+				res = append(res, *meta)
+			}
+		}
+		return pi, nil
+	})
+	return
+}
+
+// exportPullRequest exports pull request with reviews, comments and commits. Returns pr info needed for git export, nil if pr has no commits.
+func (s *Integration) exportPullRequest(logger hclog.Logger, senders prSenders, repo commonrepo.Repo, rpr api.PullRequest) (_ *rpcdef.GitRepoFetchPR, rerr error) {
+
+	pr := rpr.Sourcecode(s.qc, repo)
+
+	err := api.Paginate(func(paginationParams url.Values) (api.PageInfo, error) {
+		pi, reviews, err := api.PullRequestReviewsPage(s.qc, logger, repo, pr.RefID, paginationParams)
+		if err != nil {
+			return pi, err
+		}
+		for _, obj := range reviews {
+			if err := senders.reviews.Send(obj); err != nil {
+				return pi, err
+			}
+		}
+		return pi, nil
+	})
+	if err != nil {
+		rerr = err
+		return
+	}
+
+	err = api.Paginate(func(paginationParams url.Values) (api.PageInfo, error) {
+		pi, comments, err := api.PullRequestCommentsPage(s.qc, logger, repo, pr.RefID, paginationParams)
+		if err != nil {
+			return pi, err
+		}
+		for _, obj := range comments {
+			if err := senders.comments.Send(obj); err != nil {
+				return pi, err
+			}
+		}
+		return pi, nil
+	})
+	if err != nil {
+		rerr = err
+		return
+	}
+
+	// always getting all commits, since they are needed for pr commit_shas
+	var commits []*sourcecode.PullRequestCommit
+	err = api.Paginate(func(paginationParams url.Values) (api.PageInfo, error) {
+		pi, sub, err := api.PullRequestCommitsPage(s.qc, logger, repo, pr.RefID, paginationParams)
+		if err != nil {
+			return pi, err
+		}
+		commits = append(commits, sub...)
+		return pi, nil
+	})
+	if err != nil {
+		rerr = err
+		return
+	}
+	api.SortPullRequestCommits(commits)
+
+	var meta *rpcdef.GitRepoFetchPR
+	if len(commits) > 0 {
+		meta = &rpcdef.GitRepoFetchPR{}
+		meta.ID = s.qc.IDs.CodePullRequest(pr.RepoID, pr.RefID)
+		meta.RefID = pr.RefID
+		meta.URL = pr.URL
+		meta.BranchName = pr.BranchName
+		meta.LastCommitSHA = rpr.Head.SHA
+		if meta.LastCommitSHA == "" {
+			meta.LastCommitSHA = commits[len(commits)-1].Sha
+		}
+	}
+
+	for _, c := range commits {
+		pr.CommitShas = append(pr.CommitShas, c.Sha)
+	}
+	pr.CommitIds = s.qc.IDs.CodeCommits(pr.RepoID, pr.CommitShas)
+	if len(pr.CommitShas) == 0 {
+		logger.Info("found PullRequest with no commits (ignoring it)", "repo", repo.NameWithOwner, "pr_ref_id", pr.RefID, "pr.url", pr.URL)
+	} else {
+		pr.BranchID = s.qc.IDs.CodeBranch(pr.RepoID, pr.BranchName, pr.CommitShas[0])
+	}
+
+	if err = senders.prs.Send(&pr); err != nil {
+		rerr = err
+		return
+	}
+
+	for _, c := range commits {
+		c.BranchID = pr.BranchID
+		if err := senders.commits.Send(c); err != nil {
+			rerr = err
+			return
+		}
+	}
+
+	return meta, nil
+}
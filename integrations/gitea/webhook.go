@@ -0,0 +1,139 @@
+package main
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"strings"
+
+	"github.com/pinpt/agent/integrations/gitea/api"
+	"github.com/pinpt/agent/integrations/pkg/commonrepo"
+	"github.com/pinpt/agent/integrations/pkg/objsender"
+	"github.com/pinpt/agent/rpcdef"
+	"github.com/pinpt/integration-sdk/sourcecode"
+)
+
+// webhookPayload contains fields we need from push, pull_request and issue_comment payloads
+type webhookPayload struct {
+	Number      int64 `json:"number"`
+	PullRequest *struct {
+		Number int64 `json:"number"`
+	} `json:"pull_request"`
+	Issue *struct {
+		Number int64 `json:"number"`
+	} `json:"issue"`
+	IsPull     bool      `json:"is_pull"`
+	Repository *api.Repo `json:"repository"`
+}
+
+// webhookEventName returns event name from headers. Forgejo sends both x-forgejo-event and x-gitea-event, older versions only x-gogs-event.
+func webhookEventName(headers map[string]string) string {
+	for _, k := range []string{"x-gitea-event", "x-forgejo-event", "x-gogs-event"} {
+		if v := headers[k]; v != "" {
+			return v
+		}
+	}
+	return ""
+}
+
+func (s *Integration) Webhook(ctx context.Context, headers map[string]string, body string, config rpcdef.ExportConfig) (res rpcdef.WebhookResult, _ error) {
+
+	rerr := func(err error) {
+		res.Error = err.Error()
+	}
+
+	if len(body) == 0 {
+		rerr(errors.New("empty webhook body passed"))
+		return
+	}
+
+	err := s.initWithConfig(config, false)
+	if err != nil {
+		rerr(err)
+		return
+	}
+
+	var data webhookPayload
+	err = json.Unmarshal([]byte(body), &data)
+	if err != nil {
+		rerr(err)
+		return
+	}
+
+	event := webhookEventName(headers)
+	if event == "" {
+		rerr(fmt.Errorf("x-gitea-event key is not provided in headers %v", headers))
+		return
+	}
+
+	if data.Repository == nil || data.Repository.ID == 0 || data.Repository.FullName == "" {
+		rerr(errors.New("missing repository in payload"))
+		return
+	}
+	repo := data.Repository.Common()
+
+	prNumber := int64(0)
+	switch {
+	case event == "push":
+		err = s.exportGit(repo, nil)
+		if err != nil {
+			rerr(err)
+		}
+		return
+	case strings.HasPrefix(event, "pull_request"):
+		// pull_request, pull_request_approved, pull_request_rejected, pull_request_comment and other pr events
+		prNumber = data.Number
+		if prNumber == 0 && data.PullRequest != nil {
+			prNumber = data.PullRequest.Number
+		}
+	case event == "issue_comment":
+		if !data.IsPull || data.Issue == nil {
+			s.logger.Info("skipping issue_comment webhook for issue, only pull request comments are supported")
+			return
+		}
+		prNumber = data.Issue.Number
+	default:
+		s.logger.Info("skipping webhook with unsupported event, this is not in a list of supported webhooks", "event", event)
+		return
+	}
+	if prNumber == 0 {
+		rerr(errors.New("missing pull request number in payload"))
+		return
+	}
+
+	sessions := objsender.NewSessionsWebhook()
+	meta, err := s.webhookPullRequest(sessions, repo, prNumber)
+	if err != nil {
+		rerr(fmt.Errorf("could not get pull request %v", err))
+		return
+	}
+	var prs []rpcdef.GitRepoFetchPR
+	if meta != nil {
+		prs = append(prs, *meta)
+	}
+	err = s.exportGit(repo, prs)
+	if err != nil {
+		rerr(err)
+		return
+	}
+	res.MutatedObjects = sessions.Data
+	return
+}
+
+func (s *Integration) webhookPullRequest(sessions *objsender.SessionsWebhook, repo commonrepo.Repo, prNumber int64) (*rpcdef.GitRepoFetchPR, error) {
+	logger := s.logger.With("repo", repo.NameWithOwner, "pr_number", prNumber)
+
+	pr, err := api.PullRequestByNumber(s.qc, repo.NameWithOwner, prNumber)
+	if err != nil {
+		return nil, err
+	}
+
+	senders := prSenders{}
+	senders.prs = sessions.NewSession(sourcecode.PullRequestModelName.String())
+	senders.comments = sessions.NewSession(sourcecode.PullRequestCommentModelName.String())
+	senders.reviews = sessions.NewSession(sourcecode.PullRequestReviewModelName.String())
+	senders.commits = sessions.NewSession(sourcecode.PullRequestCommitModelName.String())
+
+	return s.exportPullRequest(logger, senders, repo, pr)
+}
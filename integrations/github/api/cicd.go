@@ -0,0 +1,252 @@
+package api
+
+import (
+	"errors"
+	"net/http"
+	"strconv"
+	"time"
+
+	"github.com/pinpt/agent/integrations/pkg/cicd"
+	"github.com/pinpt/agent/pkg/date"
+	"github.com/pinpt/agent/pkg/ids"
+	"github.com/pinpt/agent/pkg/requests"
+)
+
+// ErrCICDNotAvailable is returned when actions or deployments api is not available, for example in older github enterprise versions
+var ErrCICDNotAvailable = errors.New("github actions api is not available")
+
+func restNotFound(err error) bool {
+	var statusErr requests.StatusCodeError
+	return errors.As(err, &statusErr) && statusErr.Got == http.StatusNotFound
+}
+
+func buildStatus(status, conclusion string) cicd.Status {
+	switch status {
+	case "queued", "waiting", "requested", "pending":
+		return cicd.StatusPending
+	case "in_progress":
+		return cicd.StatusRunning
+	}
+	switch conclusion {
+	case "success", "neutral":
+		return cicd.StatusSuccess
+	case "cancelled", "stale":
+		return cicd.StatusCancelled
+	case "skipped":
+		return cicd.StatusSkipped
+	}
+	// failure, timed_out, action_required and startup_failure
+	return cicd.StatusFailure
+}
+
+func deploymentStatus(state string) cicd.Status {
+	switch state {
+	case "success", "inactive":
+		return cicd.StatusSuccess
+	case "error", "failure":
+		return cicd.StatusFailure
+	case "in_progress":
+		return cicd.StatusRunning
+	}
+	// queued, pending or no statuses yet
+	return cicd.StatusPending
+}
+
+type workflowRun struct {
+	ID           int64     `json:"id"`
+	Name         string    `json:"name"`
+	HeadBranch   string    `json:"head_branch"`
+	HeadSha      string    `json:"head_sha"`
+	Event        string    `json:"event"`
+	Status       string    `json:"status"`
+	Conclusion   string    `json:"conclusion"`
+	HTMLURL      string    `json:"html_url"`
+	CreatedAt    time.Time `json:"created_at"`
+	UpdatedAt    time.Time `json:"updated_at"`
+	RunStartedAt time.Time `json:"run_started_at"`
+}
+
+// BuildsPage returns actions workflow runs created after since, newest first. Jobs are not set, use BuildJobs to get them.
+func BuildsPage(qc QueryContext, repo Repo, since time.Time, u string) (res []*cicd.Build, header http.Header, rerr error) {
+	qc.Logger.Debug("workflow runs request", "repo", repo.NameWithOwner, "u", u)
+
+	req := newRestRequest(qc, "repos/"+repo.NameWithOwner+"/actions/runs")
+	req.Query.Set("per_page", "100")
+	if !since.IsZero() {
+		req.Query.Set("created", ">="+since.UTC().Format(time.RFC3339))
+	}
+	if u != "" {
+		req.URL = u
+		req.Query = nil
+	}
+	var respJSON struct {
+		WorkflowRuns []workflowRun `json:"workflow_runs"`
+	}
+	reqs := requests.New(qc.Logger, qc.Clients.TLSInsecure)
+	resp, err := reqs.JSON(req, &respJSON)
+	if err != nil {
+		if restNotFound(err) {
+			rerr = ErrCICDNotAvailable
+			return
+		}
+		rerr = err
+		return
+	}
+	for _, data := range respJSON.WorkflowRuns {
+		item := &cicd.Build{}
+		item.CustomerID = qc.CustomerID
+		item.RefType = "github"
+		item.RefID = strconv.FormatInt(data.ID, 10)
+		item.RepoID = qc.RepoID(repo.ID)
+		item.ID = cicd.NewBuildID(qc.CustomerID, item.RefType, item.RepoID, item.RefID)
+		item.Name = data.Name
+		item.URL = data.HTMLURL
+		item.Status = buildStatus(data.Status, data.Conclusion)
+		item.Trigger = data.Event
+		item.CommitSha = data.HeadSha
+		item.CommitID = ids.CodeCommit(qc.CustomerID, qc.RefType, item.RepoID, data.HeadSha)
+		item.Branch = data.HeadBranch
+		started := data.RunStartedAt
+		if started.IsZero() {
+			started = data.CreatedAt
+		}
+		date.ConvertToModel(data.CreatedAt, &item.CreatedDate)
+		date.ConvertToModel(started, &item.StartedDate)
+		if item.Status.Finished() {
+			// updated_at is set when run completes
+			date.ConvertToModel(data.UpdatedAt, &item.CompletedDate)
+			item.Duration = cicd.Duration(started, data.UpdatedAt)
+		}
+		res = append(res, item)
+	}
+	return res, resp.Resp.Header, nil
+}
+
+// BuildJobs returns all jobs of the workflow run
+func BuildJobs(qc QueryContext, repo Repo, buildRefID string) (res []cicd.Job, rerr error) {
+	rerr = PaginateV3(func(u string) (header http.Header, _ error) {
+		qc.Logger.Debug("workflow run jobs request", "repo", repo.NameWithOwner, "run", buildRefID, "u", u)
+
+		req := newRestRequest(qc, "repos/"+repo.NameWithOwner+"/actions/runs/"+buildRefID+"/jobs")
+		req.Query.Set("per_page", "100")
+		if u != "" {
+			req.URL = u
+			req.Query = nil
+		}
+		var respJSON struct {
+			Jobs []struct {
+				ID          int64     `json:"id"`
+				Name        string    `json:"name"`
+				Status      string    `json:"status"`
+				Conclusion  string    `json:"conclusion"`
+				StartedAt   time.Time `json:"started_at"`
+				CompletedAt time.Time `json:"completed_at"`
+			} `json:"jobs"`
+		}
+		reqs := requests.New(qc.Logger, qc.Clients.TLSInsecure)
+		resp, err := reqs.JSON(req, &respJSON)
+		if err != nil {
+			return nil, err
+		}
+		for _, data := range respJSON.Jobs {
+			item := cicd.Job{}
+			item.RefID = strconv.FormatInt(data.ID, 10)
+			item.Name = data.Name
+			item.Status = buildStatus(data.Status, data.Conclusion)
+			date.ConvertToModel(data.StartedAt, &item.StartedDate)
+			date.ConvertToModel(data.CompletedAt, &item.CompletedDate)
+			item.Duration = cicd.Duration(data.StartedAt, data.CompletedAt)
+			res = append(res, item)
+		}
+		return resp.Resp.Header, nil
+	})
+	return
+}
+
+// DeploymentsPage returns deployments created after since, newest first. Status is taken from the latest deployment status.
+func DeploymentsPage(qc QueryContext, repo Repo, since time.Time, u string) (res []*cicd.Deployment, header http.Header, stop bool, rerr error) {
+	qc.Logger.Debug("deployments request", "repo", repo.NameWithOwner, "u", u)
+
+	req := newRestRequest(qc, "repos/"+repo.NameWithOwner+"/deployments")
+	req.Query.Set("per_page", "100")
+	if u != "" {
+		req.URL = u
+		req.Query = nil
+	}
+	var respJSON []struct {
+		ID          int64     `json:"id"`
+		Sha         string    `json:"sha"`
+		Ref         string    `json:"ref"`
+		Environment string    `json:"environment"`
+		CreatedAt   time.Time `json:"created_at"`
+		Creator     struct {
+			NodeID string `json:"node_id"`
+		} `json:"creator"`
+	}
+	reqs := requests.New(qc.Logger, qc.Clients.TLSInsecure)
+	resp, err := reqs.JSON(req, &respJSON)
+	if err != nil {
+		if restNotFound(err) {
+			rerr = ErrCICDNotAvailable
+			return
+		}
+		rerr = err
+		return
+	}
+	for _, data := range respJSON {
+		// api does not support filtering by date, results are sorted by creation date
+		if data.CreatedAt.Before(since) {
+			stop = true
+			break
+		}
+		item := &cicd.Deployment{}
+		item.CustomerID = qc.CustomerID
+		item.RefType = "github"
+		item.RefID = strconv.FormatInt(data.ID, 10)
+		item.RepoID = qc.RepoID(repo.ID)
+		item.ID = cicd.NewDeploymentID(qc.CustomerID, item.RefType, item.RepoID, item.RefID)
+		item.Environment = data.Environment
+		item.CommitSha = data.Sha
+		item.CommitID = ids.CodeCommit(qc.CustomerID, qc.RefType, item.RepoID, data.Sha)
+		item.Branch = data.Ref
+		item.CreatorRefID = data.Creator.NodeID
+		date.ConvertToModel(data.CreatedAt, &item.CreatedDate)
+
+		status, err := deploymentLastStatus(qc, repo, item.RefID)
+		if err != nil {
+			rerr = err
+			return
+		}
+		item.Status = deploymentStatus(status.State)
+		item.URL = status.EnvironmentURL
+		if item.Status.Finished() {
+			date.ConvertToModel(status.CreatedAt, &item.CompletedDate)
+			item.Duration = cicd.Duration(data.CreatedAt, status.CreatedAt)
+		}
+		res = append(res, item)
+	}
+	return res, resp.Resp.Header, stop, nil
+}
+
+type deploymentStatusREST struct {
+	State          string    `json:"state"`
+	EnvironmentURL string    `json:"environment_url"`
+	CreatedAt      time.Time `json:"created_at"`
+}
+
+func deploymentLastStatus(qc QueryContext, repo Repo, deploymentRefID string) (res deploymentStatusREST, rerr error) {
+	req := newRestRequest(qc, "repos/"+repo.NameWithOwner+"/deployments/"+deploymentRefID+"/statuses")
+	// statuses are returned newest first
+	req.Query.Set("per_page", "1")
+	var respJSON []deploymentStatusREST
+	reqs := requests.New(qc.Logger, qc.Clients.TLSInsecure)
+	_, err := reqs.JSON(req, &respJSON)
+	if err != nil {
+		rerr = err
+		return
+	}
+	if len(respJSON) != 0 {
+		res = respJSON[0]
+	}
+	return
+}
@@ -19,6 +19,8 @@ type QueryContext struct {
 	Logger hclog.Logger
 
 	Request func(query string, vars map[string]interface{}, res interface{}) error
+	// RequestPreview is the same as Request, with schema preview enabled using the passed accept header
+	RequestPreview func(preview string, query string, vars map[string]interface{}, res interface{}) error
 
 	APIURL  string
 	APIURL3 string
@@ -57,3 +59,19 @@ func (s QueryContext) PullRequestID(repoID, refID string) string {
 func (s QueryContext) BranchID(repoID, branchName, firstCommitSHA string) string {
 	return ids.CodeBranch(s.CustomerID, s.RefType, repoID, branchName, firstCommitSHA)
 }
+
+func (s QueryContext) WorkProjectID(refID string) string {
+	return ids.WorkProject(s.CustomerID, s.RefType, refID)
+}
+
+func (s QueryContext) WorkIssueID(refID string) string {
+	return ids.WorkIssue(s.CustomerID, s.RefType, refID)
+}
+
+func (s QueryContext) WorkSprintID(refID string) string {
+	return ids.WorkSprint(s.CustomerID, s.RefType, refID)
+}
+
+func (s QueryContext) WorkIssueStatusID(refID string) string {
+	return ids.WorkIssueStatus(s.CustomerID, s.RefType, refID)
+}
@@ -252,3 +252,73 @@ func ReposPage(qc QueryContext, org Org, queryParams string, stopOnUpdatedAt tim
 
 	return repositories.PageInfo, repos, repositories.TotalCount, nil
 }
+
+// RepoCounts contains the total number of objects in repo, used for export plan
+type RepoCounts struct {
+	PullRequests int
+	// Commits is the number of commits on default branch
+	Commits int
+	Issues  int
+}
+
+// RepoCountsByID returns the number of pull requests, issues and commits on default branch using totalCount fields
+func RepoCountsByID(qc QueryContext, repoRefID string) (res RepoCounts, rerr error) {
+
+	qc.Logger.Debug("repo counts request", "repo", repoRefID)
+
+	query := `
+	query {
+		node (id: "` + repoRefID + `") {
+			... on Repository {
+				pullRequests {
+					totalCount
+				}
+				issues {
+					totalCount
+				}
+				defaultBranchRef {
+					target {
+						... on Commit {
+							history {
+								totalCount
+							}
+						}
+					}
+				}
+			}
+		}
+	}
+	`
+
+	var requestRes struct {
+		Data struct {
+			Node struct {
+				PullRequests struct {
+					TotalCount int `json:"totalCount"`
+				} `json:"pullRequests"`
+				Issues struct {
+					TotalCount int `json:"totalCount"`
+				} `json:"issues"`
+				DefaultBranchRef struct {
+					Target struct {
+						History struct {
+							TotalCount int `json:"totalCount"`
+						} `json:"history"`
+					} `json:"target"`
+				} `json:"defaultBranchRef"`
+			} `json:"node"`
+		} `json:"data"`
+	}
+
+	err := qc.Request(query, nil, &requestRes)
+	if err != nil {
+		rerr = err
+		return
+	}
+
+	node := requestRes.Data.Node
+	res.PullRequests = node.PullRequests.TotalCount
+	res.Commits = node.DefaultBranchRef.Target.History.TotalCount
+	res.Issues = node.Issues.TotalCount
+	return
+}
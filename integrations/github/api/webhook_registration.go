@@ -20,7 +20,7 @@ import (
 // update this using current time, if the format of the url changes, or need a new url for some other reason
 const WebhookReplaceOlderThan = "2020-05-04T17:19:42Z"
 
-func WebhookCreateIfNotExists(qc QueryContext, repo Repo, webhookURL string, events []string, webhookReplaceOlderThan string) (rerr error) {
+func WebhookCreateIfNotExists(qc QueryContext, repo Repo, webhookURL string, secret string, events []string, webhookReplaceOlderThan string) (rerr error) {
 	logger := qc.Logger.With("repo", repo.NameWithOwner, "events", events)
 
 	logger.Debug("checking if webhook registration is needed")
@@ -67,7 +67,7 @@ func WebhookCreateIfNotExists(qc QueryContext, repo Repo, webhookURL string, eve
 	whCount := len(pinptWebHooks)
 
 	if whCount == 0 {
-		return webhookCreate(qc, repo, webhookURL, events)
+		return webhookCreate(qc, repo, webhookURL, secret, events)
 	} else if whCount > 1 {
 
 		sort.SliceStable(pinptWebHooks, func(i, j int) bool {
@@ -100,7 +100,7 @@ func WebhookCreateIfNotExists(qc QueryContext, repo Repo, webhookURL string, eve
 			rerr = err
 			return
 		}
-		err = webhookCreate(qc, repo, webhookURL, events)
+		err = webhookCreate(qc, repo, webhookURL, secret, events)
 		if err != nil {
 			rerr = err
 			return
@@ -140,7 +140,7 @@ func WebhookList(qc QueryContext, repo Repo) (res []webhook, noPermissions bool,
 	return
 }
 
-func webhookCreate(qc QueryContext, repo Repo, webhookURL string, events []string) (rerr error) {
+func webhookCreate(qc QueryContext, repo Repo, webhookURL string, secret string, events []string) (rerr error) {
 	qc.Logger.Info("registering webhook for repo", "repo", repo.NameWithOwner, "events", events)
 
 	data := struct {
@@ -151,6 +151,7 @@ func webhookCreate(qc QueryContext, repo Repo, webhookURL string, events []strin
 			URL         string `json:"url"`
 			ContentType string `json:"content_type"`
 			InsecureSSL string `json:"insecure_ssl"`
+			Secret      string `json:"secret,omitempty"`
 		} `json:"config"`
 	}{}
 	data.Name = "web"
@@ -159,6 +160,7 @@ func webhookCreate(qc QueryContext, repo Repo, webhookURL string, events []strin
 	data.Config.URL = webhookURL
 	data.Config.ContentType = "json"
 	data.Config.InsecureSSL = "0"
+	data.Config.Secret = secret
 
 	reqs := requests.New(qc.Logger, qc.Clients.TLSInsecure)
 
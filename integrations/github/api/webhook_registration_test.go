@@ -142,7 +142,7 @@ func TestWebhookCreateIfNotExists(t *testing.T) {
 		NameWithOwner: "pinpt/test",
 	}
 
-	err = WebhookCreateIfNotExists(qc, testRepo, "https://test.example.com", []string{"wh1"}, WebhookReplaceOlderThan)
+	err = WebhookCreateIfNotExists(qc, testRepo, "https://test.example.com", "", []string{"wh1"}, WebhookReplaceOlderThan)
 	assert.NoError(err)
 
 	// no permissions
@@ -151,19 +151,19 @@ func TestWebhookCreateIfNotExists(t *testing.T) {
 		NameWithOwner: "pinpt/noperm",
 	}
 
-	err = WebhookCreateIfNotExists(qc, noPerm, "https://test.example.com", []string{"wh1"}, WebhookReplaceOlderThan)
+	err = WebhookCreateIfNotExists(qc, noPerm, "https://test.example.com", "", []string{"wh1"}, WebhookReplaceOlderThan)
 	assert.Equal(errors.New("no permissions to list webhooks for repo"), err)
 
 	// bad replace date
-	err = WebhookCreateIfNotExists(qc, testRepo, "https://test.example.com", []string{"wh1"}, "baddate")
+	err = WebhookCreateIfNotExists(qc, testRepo, "https://test.example.com", "", []string{"wh1"}, "baddate")
 	assert.True(strings.Contains(err.Error(), "invalid webhookReplaceOlderThan constant format"))
 
 	// bad webhook url
-	err = WebhookCreateIfNotExists(qc, testRepo, " http://foo.com", []string{"wh1"}, WebhookReplaceOlderThan)
+	err = WebhookCreateIfNotExists(qc, testRepo, " http://foo.com", "", []string{"wh1"}, WebhookReplaceOlderThan)
 	assert.Error(err)
 
 	// update webhook
-	err = WebhookCreateIfNotExists(qc, testRepo, "https://test.example.com", []string{"wh1", "wh2"}, WebhookReplaceOlderThan)
+	err = WebhookCreateIfNotExists(qc, testRepo, "https://test.example.com", "", []string{"wh1", "wh2"}, WebhookReplaceOlderThan)
 	assert.NoError(err)
 
 	// update with date webhook
@@ -171,7 +171,7 @@ func TestWebhookCreateIfNotExists(t *testing.T) {
 		ID:            "1",
 		NameWithOwner: "pinpt/test2",
 	}
-	err = WebhookCreateIfNotExists(qc, testRepo2, "https://test.example.com", []string{"wh1"}, secondDateStr)
+	err = WebhookCreateIfNotExists(qc, testRepo2, "https://test.example.com", "", []string{"wh1"}, secondDateStr)
 	assert.NoError(err)
 
 	// just delete old pinpt webhooks
@@ -179,7 +179,7 @@ func TestWebhookCreateIfNotExists(t *testing.T) {
 		ID:            "3",
 		NameWithOwner: "pinpt/test3",
 	}
-	err = WebhookCreateIfNotExists(qc, testRepo3, "https://test.example2.com", []string{"wh1"}, secondDateStr)
+	err = WebhookCreateIfNotExists(qc, testRepo3, "https://test.example2.com", "", []string{"wh1"}, secondDateStr)
 	assert.NoError(err)
 
 }
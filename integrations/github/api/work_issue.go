@@ -0,0 +1,231 @@
+package api
+
+import (
+	"fmt"
+	"time"
+
+	"github.com/pinpt/agent/pkg/date"
+	"github.com/pinpt/integration-sdk/work"
+)
+
+// Issue statuses used when issue is not on a project board
+const (
+	WorkStatusOpen   = "Open"
+	WorkStatusClosed = "Closed"
+)
+
+type WorkIssue struct {
+	*work.Issue
+	HasComments bool
+}
+
+const workIssueFieldsGraphql = `
+updatedAt
+id
+number
+repository {
+	id
+	nameWithOwner
+}
+title
+bodyHTML
+url
+createdAt
+closedAt
+# OPEN or CLOSED
+state
+author ` + userFields + `
+assignees(first:1) {
+	nodes ` + userFields2 + `
+}
+labels(first:100) {
+	nodes {
+		name
+	}
+}
+milestone {
+	id
+	dueOn
+}
+projectCards(first:10) {
+	nodes {
+		column {
+			id
+			name
+		}
+	}
+}
+comments {
+	totalCount
+}
+`
+
+type workIssueGraphql struct {
+	ID         string `json:"id"`
+	Number     int    `json:"number"`
+	Repository struct {
+		ID            string `json:"id"`
+		NameWithOwner string `json:"nameWithOwner"`
+	} `json:"repository"`
+	Title     string    `json:"title"`
+	BodyHTML  string    `json:"bodyHTML"`
+	URL       string    `json:"url"`
+	CreatedAt time.Time `json:"createdAt"`
+	ClosedAt  time.Time `json:"closedAt"`
+	UpdatedAt time.Time `json:"updatedAt"`
+	State     string    `json:"state"`
+	Author    User      `json:"author"`
+	Assignees struct {
+		Nodes []User `json:"nodes"`
+	} `json:"assignees"`
+	Labels struct {
+		Nodes []struct {
+			Name string `json:"name"`
+		} `json:"nodes"`
+	} `json:"labels"`
+	Milestone *struct {
+		ID    string    `json:"id"`
+		DueOn time.Time `json:"dueOn"`
+	} `json:"milestone"`
+	ProjectCards struct {
+		Nodes []workIssueCard `json:"nodes"`
+	} `json:"projectCards"`
+	Comments struct {
+		TotalCount int `json:"totalCount"`
+	} `json:"comments"`
+}
+
+type workIssueCard struct {
+	// Column is empty for cards in projects without columns
+	Column *workIssueColumn `json:"column"`
+}
+
+type workIssueColumn struct {
+	ID   string `json:"id"`
+	Name string `json:"name"`
+}
+
+func convertWorkIssue(qc QueryContext, data workIssueGraphql) WorkIssue {
+	item := &work.Issue{}
+	item.CustomerID = qc.CustomerID
+	item.RefType = "github"
+	item.RefID = data.ID
+	item.ProjectID = qc.WorkProjectID(data.Repository.ID)
+	item.Identifier = fmt.Sprintf("%s#%d", data.Repository.NameWithOwner, data.Number)
+	item.Title = data.Title
+	item.Description = `<div class="source-github">` + data.BodyHTML + `</div>`
+	item.URL = data.URL
+	item.Type = "Issue"
+	date.ConvertToModel(data.CreatedAt, &item.CreatedDate)
+	date.ConvertToModel(data.UpdatedAt, &item.UpdatedDate)
+
+	// open issues on a project board use the column name as status, closed issues are always closed
+	item.Status = WorkStatusOpen
+	item.StatusID = qc.WorkIssueStatusID(workStatusRefID(WorkStatusOpen))
+	if data.State == "CLOSED" {
+		item.Status = WorkStatusClosed
+		item.StatusID = qc.WorkIssueStatusID(workStatusRefID(WorkStatusClosed))
+	} else {
+		for _, card := range data.ProjectCards.Nodes {
+			if card.Column == nil {
+				continue
+			}
+			item.Status = card.Column.Name
+			item.StatusID = qc.WorkIssueStatusID(card.Column.ID)
+			break
+		}
+	}
+
+	item.Tags = []string{}
+	for _, label := range data.Labels.Nodes {
+		item.Tags = append(item.Tags, label.Name)
+	}
+
+	if data.Milestone != nil {
+		item.SprintIds = []string{qc.WorkSprintID(data.Milestone.ID)}
+		date.ConvertToModel(data.Milestone.DueOn, &item.PlannedEndDate)
+	}
+
+	if qc.ExportUserUsingFullDetails != nil {
+		refID, err := qc.ExportUserUsingFullDetails(qc.Logger, data.Author)
+		if err != nil {
+			qc.Logger.Error("could not resolve issue author", "login", data.Author.Login, "issue_url", data.URL)
+		}
+		item.CreatorRefID = refID
+		item.ReporterRefID = refID
+		if len(data.Assignees.Nodes) != 0 {
+			assignee := data.Assignees.Nodes[0]
+			item.AssigneeRefID, err = qc.ExportUserUsingFullDetails(qc.Logger, assignee)
+			if err != nil {
+				qc.Logger.Error("could not resolve issue assignee", "login", assignee.Login, "issue_url", data.URL)
+			}
+		}
+	}
+
+	res := WorkIssue{}
+	res.Issue = item
+	res.HasComments = data.Comments.TotalCount != 0
+	return res
+}
+
+// workStatusRefID returns ref id for statuses that are not project columns
+func workStatusRefID(status string) string {
+	return "github-" + status
+}
+
+func WorkIssuesPage(
+	qc QueryContext,
+	repo Repo,
+	queryParams string, stopOnUpdatedAt time.Time) (pi PageInfo, res []WorkIssue, totalCount int, rerr error) {
+
+	qc.Logger.Debug("work issues request", "repo", repo.NameWithOwner, "q", queryParams)
+
+	query := `
+	query {
+		node (id: "` + repo.ID + `") {
+			... on Repository {
+				issues(` + queryParams + `) {
+					totalCount
+					pageInfo {
+						hasNextPage
+						endCursor
+						hasPreviousPage
+						startCursor
+					}
+					nodes {
+						` + workIssueFieldsGraphql + `
+					}
+				}
+			}
+		}
+	}
+	`
+
+	var requestRes struct {
+		Data struct {
+			Node struct {
+				Issues struct {
+					TotalCount int                `json:"totalCount"`
+					PageInfo   PageInfo           `json:"pageInfo"`
+					Nodes      []workIssueGraphql `json:"nodes"`
+				} `json:"issues"`
+			} `json:"node"`
+		} `json:"data"`
+	}
+
+	err := qc.Request(query, nil, &requestRes)
+	if err != nil {
+		rerr = err
+		return
+	}
+
+	issues := requestRes.Data.Node.Issues
+	for _, data := range issues.Nodes {
+		if data.UpdatedAt.Before(stopOnUpdatedAt) {
+			return
+		}
+		res = append(res, convertWorkIssue(qc, data))
+	}
+
+	return issues.PageInfo, res, issues.TotalCount, nil
+}
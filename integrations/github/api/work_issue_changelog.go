@@ -0,0 +1,241 @@
+package api
+
+import (
+	"time"
+
+	"github.com/pinpt/agent/pkg/date"
+	"github.com/pinpt/agent/pkg/structmarshal"
+	"github.com/pinpt/go-common/v10/datetime"
+	"github.com/pinpt/integration-sdk/work"
+)
+
+type workIssueEvent struct {
+	ID        string    `json:"id"`
+	CreatedAt time.Time `json:"createdAt"`
+	Actor     User      `json:"actor"`
+	Assignee  User      `json:"assignee"`
+	// User is used instead of Assignee in older github enterprise versions
+	User  User `json:"user"`
+	Label struct {
+		Name string `json:"name"`
+	} `json:"label"`
+	PreviousTitle             string `json:"previousTitle"`
+	CurrentTitle              string `json:"currentTitle"`
+	MilestoneTitle            string `json:"milestoneTitle"`
+	PreviousProjectColumnName string `json:"previousProjectColumnName"`
+	ProjectColumnName         string `json:"projectColumnName"`
+}
+
+// PreviewProjectEvents enables project event fields used in issue changelogs
+// https://docs.github.com/en/graphql/overview/schema-previews#project-event-details-preview
+const PreviewProjectEvents = "application/vnd.github.starfox-preview+json"
+
+func workIssueTimelineQuery(issueRefID, queryParams string, assigneeAvailability bool) string {
+	assigneeField := "user " + userFields2
+	if assigneeAvailability {
+		assigneeField = "assignee " + userFields
+	}
+	common := `
+		__typename
+		id
+		createdAt
+		actor ` + userFields + `
+	`
+	return `
+	query {
+		node (id: "` + issueRefID + `") {
+			... on Issue {
+				timelineItems(` + queryParams + `) {
+					totalCount
+					pageInfo {
+						hasNextPage
+						endCursor
+						hasPreviousPage
+						startCursor
+					}
+					nodes {
+						... on AssignedEvent {` + common + assigneeField + `}
+						... on UnassignedEvent {` + common + assigneeField + `}
+						... on ClosedEvent {` + common + `}
+						... on ReopenedEvent {` + common + `}
+						... on RenamedTitleEvent {` + common + `
+							previousTitle
+							currentTitle
+						}
+						... on LabeledEvent {` + common + `
+							label { name }
+						}
+						... on UnlabeledEvent {` + common + `
+							label { name }
+						}
+						... on MilestonedEvent {` + common + `
+							milestoneTitle
+						}
+						... on DemilestonedEvent {` + common + `
+							milestoneTitle
+						}
+						... on AddedToProjectEvent {` + common + `
+							projectColumnName
+						}
+						... on MovedColumnsInProjectEvent {` + common + `
+							previousProjectColumnName
+							projectColumnName
+						}
+						... on RemovedFromProjectEvent {` + common + `
+							projectColumnName
+						}
+					}
+				}
+			}
+		}
+	}
+	`
+}
+
+// workIssueState tracks issue status while processing timeline events, since close, reopen and project events only contain one side of the change
+type workIssueState struct {
+	closed bool
+	column string
+}
+
+func (s workIssueState) status() string {
+	if s.closed {
+		return WorkStatusClosed
+	}
+	if s.column != "" {
+		return s.column
+	}
+	return WorkStatusOpen
+}
+
+// WorkIssueChangelog returns issue changelog built from all timeline events of the issue
+func WorkIssueChangelog(qc QueryContext, issueRefID string, assigneeAvailability bool) (res []work.IssueChangeLog, rerr error) {
+	if issueRefID == "" {
+		panic("missing issue id")
+	}
+
+	state := workIssueState{}
+	// using current timestamp, so that ordinal also increases compared to changelogs sent in previous exports
+	ordinal := datetime.EpochNow()
+
+	rerr = PaginateRegular(func(queryParams string) (pi PageInfo, _ error) {
+		queryParams += " itemTypes:[ASSIGNED_EVENT,UNASSIGNED_EVENT,CLOSED_EVENT,REOPENED_EVENT,RENAMED_TITLE_EVENT,LABELED_EVENT,UNLABELED_EVENT,MILESTONED_EVENT,DEMILESTONED_EVENT,ADDED_TO_PROJECT_EVENT,MOVED_COLUMNS_IN_PROJECT_EVENT,REMOVED_FROM_PROJECT_EVENT]"
+
+		qc.Logger.Debug("work issue timeline request", "issue", issueRefID, "q", queryParams)
+
+		query := workIssueTimelineQuery(issueRefID, queryParams, assigneeAvailability)
+
+		var requestRes struct {
+			Data struct {
+				Node struct {
+					TimelineItems struct {
+						PageInfo PageInfo                 `json:"pageInfo"`
+						Nodes    []map[string]interface{} `json:"nodes"`
+					} `json:"timelineItems"`
+				} `json:"node"`
+			} `json:"data"`
+		}
+
+		err := qc.RequestPreview(PreviewProjectEvents, query, nil, &requestRes)
+		if err != nil {
+			return pi, err
+		}
+
+		items := requestRes.Data.Node.TimelineItems
+		for _, m := range items.Nodes {
+			typename, _ := m["__typename"].(string)
+			if typename == "" {
+				continue
+			}
+			var data workIssueEvent
+			err := structmarshal.MapToStruct(m, &data)
+			if err != nil {
+				return pi, err
+			}
+
+			item := work.IssueChangeLog{}
+			item.RefID = data.ID
+			date.ConvertToModel(data.CreatedAt, &item.CreatedDate)
+
+			exportUser := func(user User) string {
+				if qc.ExportUserUsingFullDetails == nil {
+					return ""
+				}
+				refID, err := qc.ExportUserUsingFullDetails(qc.Logger, user)
+				if err != nil {
+					qc.Logger.Error("could not resolve user in issue timeline", "login", user.Login, "issue", issueRefID)
+				}
+				return refID
+			}
+
+			prevStatus := state.status()
+			switch typename {
+			case "AssignedEvent", "UnassignedEvent":
+				assignee := data.Assignee
+				if !assigneeAvailability {
+					assignee = data.User
+				}
+				if assignee.Login == "" {
+					continue
+				}
+				item.Field = work.IssueChangeLogFieldAssigneeRefID
+				if typename == "AssignedEvent" {
+					item.To = exportUser(assignee)
+					item.ToString = assignee.Login
+				} else {
+					item.From = exportUser(assignee)
+					item.FromString = assignee.Login
+				}
+			case "RenamedTitleEvent":
+				item.Field = work.IssueChangeLogFieldTitle
+				item.From = data.PreviousTitle
+				item.FromString = data.PreviousTitle
+				item.To = data.CurrentTitle
+				item.ToString = data.CurrentTitle
+			case "LabeledEvent":
+				item.Field = work.IssueChangeLogFieldTags
+				item.To = data.Label.Name
+				item.ToString = data.Label.Name
+			case "UnlabeledEvent":
+				item.Field = work.IssueChangeLogFieldTags
+				item.From = data.Label.Name
+				item.FromString = data.Label.Name
+			case "MilestonedEvent":
+				// event only contains the title of the milestone
+				item.Field = work.IssueChangeLogFieldSprintIds
+				item.ToString = data.MilestoneTitle
+			case "DemilestonedEvent":
+				item.Field = work.IssueChangeLogFieldSprintIds
+				item.FromString = data.MilestoneTitle
+			case "ClosedEvent", "ReopenedEvent", "AddedToProjectEvent", "MovedColumnsInProjectEvent", "RemovedFromProjectEvent":
+				switch typename {
+				case "ClosedEvent":
+					state.closed = true
+				case "ReopenedEvent":
+					state.closed = false
+				case "AddedToProjectEvent", "MovedColumnsInProjectEvent":
+					state.column = data.ProjectColumnName
+				case "RemovedFromProjectEvent":
+					state.column = ""
+				}
+				if state.status() == prevStatus {
+					continue
+				}
+				item.Field = work.IssueChangeLogFieldStatus
+				item.From = prevStatus
+				item.FromString = prevStatus
+				item.To = state.status()
+				item.ToString = state.status()
+			default:
+				continue
+			}
+
+			item.UserID = exportUser(data.Actor)
+			item.Ordinal = ordinal
+			ordinal++
+			res = append(res, item)
+		}
+		return items.PageInfo, nil
+	})
+	return
+}
@@ -0,0 +1,44 @@
+package api
+
+import (
+	"testing"
+
+	"github.com/hashicorp/go-hclog"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestConvertWorkIssueStatus(t *testing.T) {
+	qc := QueryContext{Logger: hclog.NewNullLogger(), CustomerID: "c1", RefType: "github"}
+
+	onBoard := func(state string) workIssueGraphql {
+		data := workIssueGraphql{State: state}
+		card := workIssueCard{Column: &workIssueColumn{ID: "col1", Name: "In progress"}}
+		data.ProjectCards.Nodes = []workIssueCard{card}
+		return data
+	}
+
+	cases := []struct {
+		Label string
+		In    workIssueGraphql
+		Want  string
+	}{
+		{"open", workIssueGraphql{State: "OPEN"}, WorkStatusOpen},
+		{"closed", workIssueGraphql{State: "CLOSED"}, WorkStatusClosed},
+		{"open on board", onBoard("OPEN"), "In progress"},
+		{"closed on board", onBoard("CLOSED"), WorkStatusClosed},
+	}
+	for _, c := range cases {
+		got := convertWorkIssue(qc, c.In)
+		assert.Equal(t, c.Want, got.Status, c.Label)
+	}
+}
+
+func TestWorkIssueState(t *testing.T) {
+	assert := assert.New(t)
+	s := workIssueState{}
+	assert.Equal(WorkStatusOpen, s.status())
+	s.column = "Review"
+	assert.Equal("Review", s.status())
+	s.closed = true
+	assert.Equal(WorkStatusClosed, s.status())
+}
@@ -0,0 +1,99 @@
+package api
+
+import (
+	"time"
+
+	"github.com/pinpt/agent/pkg/date"
+	"github.com/pinpt/integration-sdk/work"
+)
+
+type workSprintGraphql struct {
+	ID          string `json:"id"`
+	Title       string `json:"title"`
+	Description string `json:"description"`
+	// OPEN or CLOSED
+	State     string    `json:"state"`
+	CreatedAt time.Time `json:"createdAt"`
+	UpdatedAt time.Time `json:"updatedAt"`
+	ClosedAt  time.Time `json:"closedAt"`
+	DueOn     time.Time `json:"dueOn"`
+}
+
+// WorkSprintsPage returns repo milestones converted to sprints. Milestones do not have start date, creation date is used instead.
+func WorkSprintsPage(
+	qc QueryContext,
+	repo Repo,
+	queryParams string, stopOnUpdatedAt time.Time) (pi PageInfo, res []*work.Sprint, totalCount int, rerr error) {
+
+	qc.Logger.Debug("work sprints request", "repo", repo.NameWithOwner, "q", queryParams)
+
+	query := `
+	query {
+		node (id: "` + repo.ID + `") {
+			... on Repository {
+				milestones(` + queryParams + `) {
+					totalCount
+					pageInfo {
+						hasNextPage
+						endCursor
+						hasPreviousPage
+						startCursor
+					}
+					nodes {
+						id
+						title
+						description
+						state
+						createdAt
+						updatedAt
+						closedAt
+						dueOn
+					}
+				}
+			}
+		}
+	}
+	`
+
+	var requestRes struct {
+		Data struct {
+			Node struct {
+				Milestones struct {
+					TotalCount int                 `json:"totalCount"`
+					PageInfo   PageInfo            `json:"pageInfo"`
+					Nodes      []workSprintGraphql `json:"nodes"`
+				} `json:"milestones"`
+			} `json:"node"`
+		} `json:"data"`
+	}
+
+	err := qc.Request(query, nil, &requestRes)
+	if err != nil {
+		rerr = err
+		return
+	}
+
+	milestones := requestRes.Data.Node.Milestones
+	for _, data := range milestones.Nodes {
+		if data.UpdatedAt.Before(stopOnUpdatedAt) {
+			return
+		}
+		item := &work.Sprint{}
+		item.CustomerID = qc.CustomerID
+		item.RefType = "github"
+		item.RefID = data.ID
+		item.Name = data.Title
+		item.Goal = data.Description
+		date.ConvertToModel(data.CreatedAt, &item.StartedDate)
+		date.ConvertToModel(data.DueOn, &item.EndedDate)
+		if data.State == "CLOSED" {
+			item.Status = work.SprintStatusClosed
+			date.ConvertToModel(data.ClosedAt, &item.CompletedDate)
+		} else {
+			item.Status = work.SprintStatusActive
+		}
+		res = append(res, item)
+	}
+
+	return milestones.PageInfo, res, milestones.TotalCount, nil
+}
@@ -0,0 +1,138 @@
+package api
+
+import (
+	"github.com/pinpt/integration-sdk/work"
+)
+
+func workStatus(qc QueryContext, refID, name, description string) *work.IssueStatus {
+	item := &work.IssueStatus{}
+	item.CustomerID = qc.CustomerID
+	item.RefType = "github"
+	item.RefID = refID
+	item.Name = name
+	item.Description = description
+	return item
+}
+
+// ProjectColumn is a column of repo project board, used as issue status
+type ProjectColumn struct {
+	ID          string
+	Name        string
+	ProjectName string
+	// Purpose is the column automation preset, TODO, IN_PROGRESS, DONE or empty if not set
+	Purpose string
+}
+
+// Project column purposes
+const (
+	ProjectColumnTodo       = "TODO"
+	ProjectColumnInProgress = "IN_PROGRESS"
+	ProjectColumnDone       = "DONE"
+)
+
+// WorkStatusesAll returns Open and Closed statuses and the columns of all project boards in repo
+func WorkStatusesAll(qc QueryContext, repo Repo) (res []*work.IssueStatus, rerr error) {
+	res = append(res,
+		workStatus(qc, workStatusRefID(WorkStatusOpen), WorkStatusOpen, "Open issue that is not on a project board"),
+		workStatus(qc, workStatusRefID(WorkStatusClosed), WorkStatusClosed, "Closed issue"))
+
+	columns, err := ProjectColumnsAll(qc, repo)
+	if err != nil {
+		rerr = err
+		return
+	}
+	for _, column := range columns {
+		res = append(res, workStatus(qc, column.ID, column.Name, "Column in "+column.ProjectName+" project"))
+	}
+	return
+}
+
+// ProjectColumnsAll returns the columns of all project boards in repo
+func ProjectColumnsAll(qc QueryContext, repo Repo) (res []ProjectColumn, rerr error) {
+	rerr = PaginateRegular(func(queryParams string) (pi PageInfo, _ error) {
+		qc.Logger.Debug("project columns request", "repo", repo.NameWithOwner, "q", queryParams)
+
+		query := `
+		query {
+			node (id: "` + repo.ID + `") {
+				... on Repository {
+					projects(` + queryParams + `) {
+						pageInfo {
+							hasNextPage
+							endCursor
+							hasPreviousPage
+							startCursor
+						}
+						nodes {
+							name
+							columns(first:100) {
+								nodes {
+									id
+									name
+									purpose
+								}
+							}
+						}
+					}
+				}
+			}
+		}
+		`
+
+		var requestRes struct {
+			Data struct {
+				Node struct {
+					Projects struct {
+						PageInfo PageInfo `json:"pageInfo"`
+						Nodes    []struct {
+							Name    string `json:"name"`
+							Columns struct {
+								Nodes []struct {
+									ID      string `json:"id"`
+									Name    string `json:"name"`
+									Purpose string `json:"purpose"`
+								} `json:"nodes"`
+							} `json:"columns"`
+						} `json:"nodes"`
+					} `json:"projects"`
+				} `json:"node"`
+			} `json:"data"`
+		}
+
+		err := qc.Request(query, nil, &requestRes)
+		if err != nil {
+			return pi, err
+		}
+
+		projects := requestRes.Data.Node.Projects
+		for _, project := range projects.Nodes {
+			for _, column := range project.Columns.Nodes {
+				res = append(res, ProjectColumn{ID: column.ID, Name: column.Name, ProjectName: project.Name, Purpose: column.Purpose})
+			}
+		}
+		return projects.PageInfo, nil
+	})
+	return
+}
+
+// WorkStatusCategories returns status names grouped for work config. Issues not on a project board are Open or Closed, otherwise column name is used as status, grouped by column purpose. Columns without purpose are in progress.
+func WorkStatusCategories(columns []ProjectColumn) (open, inProgress, closed []string) {
+	open = []string{WorkStatusOpen}
+	closed = []string{WorkStatusClosed}
+	seen := map[string]bool{WorkStatusOpen: true, WorkStatusClosed: true}
+	for _, column := range columns {
+		if seen[column.Name] {
+			continue
+		}
+		seen[column.Name] = true
+		switch column.Purpose {
+		case ProjectColumnTodo:
+			open = append(open, column.Name)
+		case ProjectColumnDone:
+			closed = append(closed, column.Name)
+		default:
+			inProgress = append(inProgress, column.Name)
+		}
+	}
+	return
+}
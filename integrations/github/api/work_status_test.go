@@ -0,0 +1,21 @@
+package api
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestWorkStatusCategories(t *testing.T) {
+	open, inProgress, closed := WorkStatusCategories([]ProjectColumn{
+		{Name: "Backlog", Purpose: ProjectColumnTodo},
+		{Name: "Doing", Purpose: ProjectColumnInProgress},
+		{Name: "Review"},
+		{Name: "Shipped", Purpose: ProjectColumnDone},
+		// the same column in another project
+		{Name: "Doing", Purpose: ProjectColumnTodo},
+	})
+	assert.Equal(t, []string{WorkStatusOpen, "Backlog"}, open)
+	assert.Equal(t, []string{"Doing", "Review"}, inProgress)
+	assert.Equal(t, []string{WorkStatusClosed, "Shipped"}, closed)
+}
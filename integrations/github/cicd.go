@@ -0,0 +1,85 @@
+package main
+
+import (
+	"net/http"
+
+	"github.com/hashicorp/go-hclog"
+	"github.com/pinpt/agent/integrations/github/api"
+	"github.com/pinpt/agent/integrations/pkg/cicd"
+	"github.com/pinpt/agent/integrations/pkg/objsender"
+	"github.com/pinpt/agent/integrations/pkg/repoprojects"
+)
+
+// exportCICD exports actions workflow runs and deployments of the repo. Missing apis are skipped, since actions could be disabled for repo or not supported by the server. Other errors are returned.
+func (s *Integration) exportCICD(ctx *repoprojects.ProjectCtx, repo api.Repo) error {
+	logger := ctx.Logger.With("repo", repo.NameWithOwner)
+
+	buildSender, err := ctx.Session(cicd.BuildModelName)
+	if err != nil {
+		return err
+	}
+	deploymentSender, err := ctx.Session(cicd.DeploymentModelName)
+	if err != nil {
+		return err
+	}
+
+	err = s.exportBuilds(logger, repo, buildSender)
+	if err != nil {
+		return err
+	}
+	return s.exportDeployments(logger, repo, deploymentSender)
+}
+
+func (s *Integration) exportBuilds(logger hclog.Logger, repo api.Repo, sender *objsender.Session) error {
+	qc := s.qc.WithLogger(logger)
+	since := cicd.Since(sender.LastProcessedTime())
+	err := api.PaginateV3(func(u string) (http.Header, error) {
+		builds, header, err := api.BuildsPage(qc, repo, since, u)
+		if err != nil {
+			return nil, err
+		}
+		for _, build := range builds {
+			build.Jobs, err = api.BuildJobs(qc, repo, build.RefID)
+			if err != nil {
+				return nil, err
+			}
+			err = sender.Send(build)
+			if err != nil {
+				return nil, err
+			}
+		}
+		return header, nil
+	})
+	if err == api.ErrCICDNotAvailable {
+		logger.Info("workflow runs api is not available, skipping")
+		return nil
+	}
+	return err
+}
+
+func (s *Integration) exportDeployments(logger hclog.Logger, repo api.Repo, sender *objsender.Session) error {
+	qc := s.qc.WithLogger(logger)
+	since := cicd.Since(sender.LastProcessedTime())
+	err := api.PaginateV3(func(u string) (http.Header, error) {
+		deployments, header, stop, err := api.DeploymentsPage(qc, repo, since, u)
+		if err != nil {
+			return nil, err
+		}
+		for _, deployment := range deployments {
+			err = sender.Send(deployment)
+			if err != nil {
+				return nil, err
+			}
+		}
+		if stop {
+			// no link header ends pagination
+			return http.Header{}, nil
+		}
+		return header, nil
+	})
+	if err == api.ErrCICDNotAvailable {
+		logger.Info("deployments api is not available, skipping")
+		return nil
+	}
+	return err
+}
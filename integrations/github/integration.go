@@ -9,6 +9,7 @@ import (
 	"sync"
 
 	"github.com/pinpt/agent/cmd/cmdrunnorestarts/inconfig"
+	"github.com/pinpt/agent/integrations/pkg/gitaccess"
 	"github.com/pinpt/agent/integrations/pkg/objsender"
 	"github.com/pinpt/agent/integrations/pkg/repoprojects"
 	"github.com/pinpt/agent/pkg/ids"
@@ -63,8 +64,6 @@ type Integration struct {
 	config Config
 
 	requestConcurrencyChan chan bool
-	requestsMadeAtomic     *int64
-	requestsBuffer         float64
 
 	refType string
 
@@ -135,9 +134,14 @@ type Config struct {
 	Repos                 []string
 	Concurrency           int
 	TLSInsecureSkipVerify bool
+	MaxRequestsPerSecond  float64
+	WebhookSecret         string
+	GitAccess             gitaccess.Config
 }
 
 type configDef struct {
+	gitaccess.Config
+
 	OnlyGit bool `json:"only_git"`
 
 	// Repos specifies the repos to export. By default all repos are exported not including the ones from ExcludedRepos. This option overrides this.
@@ -155,6 +159,12 @@ type configDef struct {
 	// github enterprise
 	// Needs testing.
 	Concurrency int `json:"concurrency"`
+
+	// MaxRequestsPerSecond limits the request rate to github api. No limit by default.
+	MaxRequestsPerSecond float64 `json:"max_requests_per_second"`
+
+	// WebhookSecret is set by agent when built-in webhook server is used, webhooks are registered with this secret
+	WebhookSecret string `json:"webhook_secret"`
 }
 
 func (s *Integration) setIntegrationConfig(data rpcdef.IntegrationConfig) error {
@@ -197,6 +207,12 @@ func (s *Integration) setIntegrationConfig(data rpcdef.IntegrationConfig) error
 	res.Repos = def.Repos
 	res.OnlyGit = def.OnlyGit
 	res.StopAfterN = def.StopAfterN
+	res.MaxRequestsPerSecond = def.MaxRequestsPerSecond
+	res.WebhookSecret = def.WebhookSecret
+	if err := def.Config.Validate(); err != nil {
+		return rerr("%v", err)
+	}
+	res.GitAccess = def.Config
 
 	{
 		u, err := url.Parse(purl)
@@ -243,6 +259,11 @@ func urlAppend(p1, p2 string) string {
 }
 
 func (s *Integration) initWithConfig(exportConfig rpcdef.ExportConfig) error {
+	return s.initWithConfigBuffer(exportConfig, 0)
+}
+
+// initWithConfigBuffer inits integration keeping requestsBuffer fraction of the hourly quota unused
+func (s *Integration) initWithConfigBuffer(exportConfig rpcdef.ExportConfig, requestsBuffer float64) error {
 	s.customerID = exportConfig.Pinpoint.CustomerID
 	s.assigneeAvailability = AssigneeAvailability{}
 	s.qc.CustomerID = s.customerID
@@ -253,9 +274,6 @@ func (s *Integration) initWithConfig(exportConfig rpcdef.ExportConfig) error {
 	}
 
 	s.requestConcurrencyChan = make(chan bool, s.config.Concurrency)
-	requestsMade := int64(-1) // start with -1 to check quotas before 1 request
-	s.requestsMadeAtomic = &requestsMade
-	s.requestsBuffer = 0
 
 	s.qc.APIURL = s.config.APIURL
 	s.qc.APIURL3 = s.config.APIURL3
@@ -263,6 +281,9 @@ func (s *Integration) initWithConfig(exportConfig rpcdef.ExportConfig) error {
 	s.clientManager, err = reqstats.New(reqstats.Opts{
 		Logger:                s.logger,
 		TLSInsecureSkipVerify: s.config.TLSInsecureSkipVerify,
+		MaxRequestsPerSecond:  s.config.MaxRequestsPerSecond,
+		ReserveQuota:          requestsBuffer,
+		Pauser:                s.agent,
 	})
 	if err != nil {
 		return err
@@ -270,6 +291,7 @@ func (s *Integration) initWithConfig(exportConfig rpcdef.ExportConfig) error {
 	s.clients = s.clientManager.Clients
 	s.qc.Clients = s.clients
 	s.qc.Request = s.makeRequest
+	s.qc.RequestPreview = s.makeRequestPreview
 
 	if s.config.Enterprise {
 		err := s.checkEnterpriseVersion()
@@ -282,14 +304,18 @@ func (s *Integration) initWithConfig(exportConfig rpcdef.ExportConfig) error {
 }
 
 func (s *Integration) Export(ctx context.Context, exportConfig rpcdef.ExportConfig) (res rpcdef.ExportResult, _ error) {
-	err := s.initWithConfig(exportConfig)
+	// we keep a request buffer for exports, but not onboarding or validation
+	err := s.initWithConfigBuffer(exportConfig, exportRequestBuffer)
 	if err != nil {
 		return res, err
 	}
-	// we keep a request buffer for exports, but not onboarding or validation
-	s.requestsBuffer = exportRequestBuffer
 
-	projects, err := s.export(ctx)
+	var projects []rpcdef.ExportProject
+	if exportConfig.Integration.Type == inconfig.IntegrationTypeWork {
+		projects, err = s.exportWork(ctx)
+	} else {
+		projects, err = s.export(ctx)
+	}
 	if err != nil {
 		return res, err
 	}
@@ -330,6 +356,15 @@ func (s *Integration) getOrgs() (res []api.Org, _ error) {
 	return res, nil
 }
 
+func (s *Integration) repoFilterConfig() repoprojects.FilterConfig {
+	return repoprojects.FilterConfig{
+		OnlyIncludeReadableIDs: s.config.Repos,
+		ExcludedIDs:            s.config.ExcludedRepos,
+		IncludedIDs:            s.config.IncludedRepos,
+		StopAfterN:             s.config.StopAfterN,
+	}
+}
+
 type exportRepo struct {
 	api.RepoWithDefaultBranch
 }
@@ -365,31 +400,12 @@ func (s *Integration) export(ctx context.Context) (_ []rpcdef.ExportProject, rer
 	s.qc.UserLoginToRefIDFromCommit = s.users.LoginToRefIDFromCommit
 	s.qc.ExportUserUsingFullDetails = s.users.ExportUserUsingFullDetails
 
-	var unfilteredRepos []api.RepoWithDefaultBranch
-	if len(orgs) > 0 {
-		unfilteredRepos, rerr = s.getAllOrgRepos(orgs)
-		if rerr != nil {
-			return
-		}
-	} else {
-		unfilteredRepos, rerr = s.getAllPersonalRepos(orgs)
-		if rerr != nil {
-			return
-		}
-	}
-
-	var unfilteredReposIface []repoprojects.RepoProject
-	for _, r := range unfilteredRepos {
-		unfilteredReposIface = append(unfilteredReposIface, exportRepo{r})
+	filteredReposIface, err := s.getFilteredRepos(orgs)
+	if err != nil {
+		rerr = err
+		return
 	}
 
-	filteredReposIface := repoprojects.Filter(s.logger, unfilteredReposIface, repoprojects.FilterConfig{
-		OnlyIncludeReadableIDs: s.config.Repos,
-		ExcludedIDs:            s.config.ExcludedRepos,
-		IncludedIDs:            s.config.IncludedRepos,
-		StopAfterN:             s.config.StopAfterN,
-	})
-
 	var filteredRepos []exportRepo
 	for _, r := range filteredReposIface {
 		filteredRepos = append(filteredRepos, r.(exportRepo))
@@ -459,6 +475,29 @@ func (s *Integration) export(ctx context.Context) (_ []rpcdef.ExportProject, rer
 	return exportResult, nil
 }
 
+// getFilteredRepos returns repos of passed orgs, or personal repos if there are no orgs, filtered using integration config
+func (s *Integration) getFilteredRepos(orgs []api.Org) (_ []repoprojects.RepoProject, rerr error) {
+	var unfilteredRepos []api.RepoWithDefaultBranch
+	if len(orgs) > 0 {
+		unfilteredRepos, rerr = s.getAllOrgRepos(orgs)
+		if rerr != nil {
+			return
+		}
+	} else {
+		unfilteredRepos, rerr = s.getAllPersonalRepos(orgs)
+		if rerr != nil {
+			return
+		}
+	}
+
+	var unfilteredReposIface []repoprojects.RepoProject
+	for _, r := range unfilteredRepos {
+		unfilteredReposIface = append(unfilteredReposIface, exportRepo{r})
+	}
+
+	return repoprojects.Filter(s.logger, unfilteredReposIface, s.repoFilterConfig()), nil
+}
+
 func (s *Integration) registerWebhooks(repos []exportRepo) error {
 	s.logger.Info("registering webhooks")
 
@@ -468,7 +507,7 @@ func (s *Integration) registerWebhooks(repos []exportRepo) error {
 	}
 
 	for _, repo := range repos {
-		err := api.WebhookCreateIfNotExists(s.qc, repo.Repo(), url, webhookEvents, api.WebhookReplaceOlderThan)
+		err := api.WebhookCreateIfNotExists(s.qc, repo.Repo(), url, s.config.WebhookSecret, webhookEvents, api.WebhookReplaceOlderThan)
 		if err != nil {
 			s.logger.Info("could not register webhooks for repo", "err", err, "repo", repo.NameWithOwner)
 		}
@@ -544,6 +583,11 @@ func (s *Integration) exportRepo(ctx *repoprojects.ProjectCtx, repo api.RepoWith
 		return err
 	}
 
+	err = s.exportCICD(ctx, repo.Repo())
+	if err != nil {
+		return err
+	}
+
 	return nil
 }
 
@@ -557,7 +601,10 @@ func (s *Integration) exportGit(repo api.Repo, prs []PRMeta) error {
 	args.RepoID = s.qc.RepoID(repo.ID)
 	args.UniqueName = repo.NameWithOwner
 	args.RefType = s.refType
-	args.URL = repoURL
+	err = s.config.GitAccess.Apply(&args, repoURL, repo.NameWithOwner+".git")
+	if err != nil {
+		return err
+	}
 	args.CommitURLTemplate = commitURLTemplate(repo, s.config.RepoURLPrefix)
 	args.BranchURLTemplate = branchURLTemplate(repo, s.config.RepoURLPrefix)
 	for _, pr := range prs {
@@ -2,9 +2,6 @@ package main
 
 import (
 	"context"
-	"encoding/json"
-	"errors"
-	"fmt"
 
 	"github.com/pinpt/agent/integrations/github/api"
 	"github.com/pinpt/agent/integrations/pkg/mutate"
@@ -52,68 +49,72 @@ func (s *Integration) mutationResult(modelName datamodel.ModelNameType, obj Mode
 	return
 }
 
-func (s *Integration) Mutate(ctx context.Context, fn, data string, config rpcdef.ExportConfig) (res rpcdef.MutateResult, _ error) {
+type prSetTitle struct {
+	RefID string `json:"ref_id" mutate:"required" description:"Pull request id"`
+	Title string `json:"title" mutate:"required" description:"New title"`
+}
 
-	rerr := func(err error) {
-		res = mutate.ResultFromError(err)
-	}
+type prSetDescription struct {
+	RefID        string `json:"ref_id" mutate:"required" description:"Pull request id"`
+	BodyMarkdown string `json:"body_markdown" mutate:"required" description:"New description in markdown"`
+}
 
-	err := s.initWithConfig(config)
-	if err != nil {
-		rerr(err)
-		return
+func (s *Integration) mutations() *mutate.Registry {
+	reg := mutate.NewRegistry()
+	checkPR := func(ctx context.Context, refID string) error {
+		_, err := api.PullRequestByID(s.qc, refID)
+		return err
 	}
+	// this is actually pr title
+	reg.Add(mutate.Action{
+		Name:        agent.IntegrationMutationRequestActionPrSetTitle.String(),
+		Description: "Changes pull request title",
+		Input:       &prSetTitle{},
+		Check: func(ctx context.Context, input interface{}) error {
+			return checkPR(ctx, input.(*prSetTitle).RefID)
+		},
+		Run: func(ctx context.Context, input interface{}) (res rpcdef.MutateResult, _ error) {
+			obj := input.(*prSetTitle)
+			err := api.PREditTitle(s.qc, obj.RefID, obj.Title)
+			if err != nil {
+				return res, err
+			}
+			return s.returnUpdatedPR(obj.RefID)
+		},
+	})
+	// this is actually pr description
+	reg.Add(mutate.Action{
+		Name:        agent.IntegrationMutationRequestActionPrSetDescription.String(),
+		Description: "Changes pull request description",
+		Input:       &prSetDescription{},
+		Check: func(ctx context.Context, input interface{}) error {
+			return checkPR(ctx, input.(*prSetDescription).RefID)
+		},
+		Run: func(ctx context.Context, input interface{}) (res rpcdef.MutateResult, _ error) {
+			obj := input.(*prSetDescription)
+			err := api.PREditBody(s.qc, obj.RefID, obj.BodyMarkdown)
+			if err != nil {
+				return res, err
+			}
+			return s.returnUpdatedPR(obj.RefID)
+		},
+	})
+	return reg
+}
 
-	s.qc.Request = s.makeRequestNoRetries
+func (s *Integration) Mutate(ctx context.Context, fn, data string, config rpcdef.ExportConfig) (res rpcdef.MutateResult, _ error) {
+	reg := s.mutations()
+	if fn == mutate.FnListMutations {
+		return reg.ListResult(), nil
+	}
 
-	var action agent.IntegrationMutationRequestAction
-	err = action.FromInterface(fn)
+	err := s.initWithConfig(config)
 	if err != nil {
-		rerr(err)
-		return
+		return mutate.ResultFromError(err), nil
 	}
 
-	switch action {
-	// this is actually pr title
-	case agent.IntegrationMutationRequestActionPrSetTitle:
-		var obj struct {
-			RefID string `json:"ref_id"`
-			Title string `json:"title"`
-		}
-		err := json.Unmarshal([]byte(data), &obj)
-		if err != nil {
-			rerr(err)
-			return
-		}
-		err = api.PREditTitle(s.qc, obj.RefID, obj.Title)
-		if err != nil {
-			rerr(err)
-			return
-		}
-		return s.returnUpdatedPR(obj.RefID)
-		// this is actually pr description
-	case agent.IntegrationMutationRequestActionPrSetDescription:
-		var obj struct {
-			RefID        string `json:"ref_id"`
-			BodyMarkdown string `json:"body_markdown"`
-		}
-		err := json.Unmarshal([]byte(data), &obj)
-		if err != nil {
-			rerr(err)
-			return
-		}
-		if obj.BodyMarkdown == "" {
-			rerr(errors.New("body_markdown field not provided"))
-			return
-		}
-		err = api.PREditBody(s.qc, obj.RefID, obj.BodyMarkdown)
-		if err != nil {
-			rerr(err)
-			return
-		}
-		return s.returnUpdatedPR(obj.RefID)
-	}
+	s.qc.Request = s.makeRequestNoRetries
+	s.qc.RequestPreview = s.makeRequestNoRetriesPreview
 
-	rerr(fmt.Errorf("mutate fn not supported: %v", fn))
-	return
+	return reg.Mutate(ctx, fn, data), nil
 }
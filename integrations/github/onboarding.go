@@ -3,20 +3,74 @@ package main
 import (
 	"context"
 
+	"github.com/pinpt/agent/cmd/cmdrunnorestarts/inconfig"
 	"github.com/pinpt/agent/integrations/github/api"
+	"github.com/pinpt/agent/integrations/pkg/repoprojects"
 	"github.com/pinpt/agent/rpcdef"
+	"github.com/pinpt/integration-sdk/agent"
+	"github.com/pinpt/integration-sdk/sourcecode"
+	"github.com/pinpt/integration-sdk/work"
 )
 
 func (s *Integration) OnboardExport(ctx context.Context, objectType rpcdef.OnboardExportType, config rpcdef.ExportConfig) (res rpcdef.OnboardExportResult, _ error) {
 	switch objectType {
 	case rpcdef.OnboardExportTypeRepos:
 		return s.onboardExportRepos(ctx, config)
+	case rpcdef.OnboardExportTypeWorkConfig:
+		return s.onboardWorkConfig(ctx, config)
+	case rpcdef.OnboardExportTypePlan:
+		return s.onboardExportPlan(ctx, config)
 	default:
 		res.Error = rpcdef.ErrOnboardExportNotSupported
 		return
 	}
 }
 
+func (s *Integration) onboardWorkConfig(ctx context.Context, config rpcdef.ExportConfig) (res rpcdef.OnboardExportResult, _ error) {
+
+	err := s.initWithConfig(config)
+	if err != nil {
+		return res, err
+	}
+
+	orgs, err := s.getOrgs()
+	if err != nil {
+		return res, err
+	}
+
+	repos, err := s.getFilteredRepos(orgs)
+	if err != nil {
+		return res, err
+	}
+
+	var columns []api.ProjectColumn
+	for _, r := range repos {
+		repoColumns, err := api.ProjectColumnsAll(s.qc, r.(exportRepo).Repo())
+		if err != nil {
+			return res, err
+		}
+		columns = append(columns, repoColumns...)
+	}
+
+	ws := &agent.WorkStatusResponseWorkConfig{}
+	ws.CustomerID = config.Pinpoint.CustomerID
+	ws.IntegrationID = config.Integration.ID
+	ws.RefType = "github"
+	open, inProgress, closed := api.WorkStatusCategories(columns)
+	ws.Statuses = agent.WorkStatusResponseWorkConfigStatuses{
+		OpenStatus:       open,
+		InProgressStatus: inProgress,
+		ClosedStatus:     closed,
+	}
+	ws.TopLevelIssue = agent.WorkStatusResponseWorkConfigTopLevelIssue{
+		Name: "Issue",
+		Type: "Issue",
+	}
+
+	res.Data = ws.ToMap()
+	return
+}
+
 func (s *Integration) onboardExportRepos(ctx context.Context, config rpcdef.ExportConfig) (res rpcdef.OnboardExportResult, _ error) {
 
 	err := s.initWithConfig(config)
@@ -55,3 +109,58 @@ func (s *Integration) onboardExportRepos(ctx context.Context, config rpcdef.Expo
 
 	return res, nil
 }
+
+func (s *Integration) onboardExportPlan(ctx context.Context, config rpcdef.ExportConfig) (res rpcdef.OnboardExportResult, _ error) {
+
+	err := s.initWithConfig(config)
+	if err != nil {
+		return res, err
+	}
+
+	orgs, err := s.getOrgs()
+	if err != nil {
+		return res, err
+	}
+
+	var repos []api.RepoWithDefaultBranch
+	if len(orgs) > 0 {
+		repos, err = s.getAllOrgRepos(orgs)
+	} else {
+		repos, err = s.getAllPersonalRepos(orgs)
+	}
+	if err != nil {
+		return res, err
+	}
+
+	var reposIface []repoprojects.RepoProject
+	for _, r := range repos {
+		reposIface = append(reposIface, exportRepo{r})
+	}
+
+	// orgs request and repo pages
+	discoveryCalls := 1 + repoprojects.Pages(len(repos), 100)
+
+	res.Data = repoprojects.NewPlan(s.logger, reposIface, s.repoFilterConfig(), discoveryCalls, func(p repoprojects.RepoProject) (res repoprojects.PlanProject, _ error) {
+		counts, err := api.RepoCountsByID(s.qc, p.GetID())
+		if err != nil {
+			return res, err
+		}
+		if config.Integration.Type == inconfig.IntegrationTypeWork {
+			res.Counts = map[string]int{
+				work.IssueModelName.String(): counts.Issues,
+			}
+			// issue pages, and timeline and comments for each issue
+			res.APICalls = repoprojects.Pages(counts.Issues, pageSizeHeavyQueries) + 2*counts.Issues
+			return res, nil
+		}
+		res.Counts = map[string]int{
+			sourcecode.PullRequestModelName.String(): counts.PullRequests,
+			sourcecode.CommitModelName.String():      counts.Commits,
+		}
+		// pull request pages, and comments, reviews and commits for each pull request, commit users are read from default branch commits
+		res.APICalls = repoprojects.Pages(counts.PullRequests, pageSizeHeavyQueries) + 3*counts.PullRequests + repoprojects.Pages(counts.Commits, 100)
+		return res, nil
+	})
+
+	return res, nil
+}
@@ -7,25 +7,18 @@ import (
 	"fmt"
 	"io/ioutil"
 	"net/http"
-	"strconv"
 	"strings"
-	"sync/atomic"
 	"time"
 
 	"github.com/pinpt/agent/pkg/requests"
 )
 
-const checkRateLimitEveryNRequest = 100
-
 func (s *Integration) makeRequest(query string, vars map[string]interface{}, res interface{}) error {
-	v := atomic.AddInt64(s.requestsMadeAtomic, 1)
-	if v%checkRateLimitEveryNRequest == 0 {
-		err := s.checkRateLimitAndSleepIfNecessary()
-		if err != nil {
-			s.logger.Warn("could not check available rate limit quota, issuing request as normal", "err", err)
-		}
-	}
+	return s.makeRequestPreview("", query, vars, res)
+}
 
+// makeRequestPreview makes request with schema preview enabled using accept header
+func (s *Integration) makeRequestPreview(preview string, query string, vars map[string]interface{}, res interface{}) error {
 	data := map[string]interface{}{
 		"query":     query,
 		"variables": vars,
@@ -38,12 +31,16 @@ func (s *Integration) makeRequest(query string, vars map[string]interface{}, res
 
 	u := s.config.APIURL
 
-	req := request{Method: "POST", URL: u, Body: b}
+	req := request{Method: "POST", URL: u, Body: b, Preview: preview}
 
 	return s.makeRequestThrottled(req, res)
 }
 
 func (s *Integration) makeRequestNoRetries(query string, vars map[string]interface{}, res interface{}) error {
+	return s.makeRequestNoRetriesPreview("", query, vars, res)
+}
+
+func (s *Integration) makeRequestNoRetriesPreview(preview string, query string, vars map[string]interface{}, res interface{}) error {
 	data := map[string]interface{}{
 		"query":     query,
 		"variables": vars,
@@ -59,6 +56,7 @@ func (s *Integration) makeRequestNoRetries(query string, vars map[string]interfa
 	req.Header = http.Header{}
 	req.Header.Set("Authorization", "bearer "+s.config.Token)
 	s.setAcceptHeader(&req.Header)
+	addPreview(&req.Header, preview)
 	req.Header.Set("Content-Type", "application/json")
 
 	resp, err := requests.New(s.logger, s.clients.TLSInsecure).Do(context.Background(), req)
@@ -98,82 +96,17 @@ type request struct {
 	URL    string
 	Method string
 	Body   []byte
+	// Preview is the accept header enabling schema preview used in query
+	Preview string
 }
 
+// exportRequestBuffer is the fraction of hourly quota left unused by exports, so that onboarding and other apps using the same token keep working
 const exportRequestBuffer = 0.2
-const minWaitTime = 5 * time.Minute
-const maxWaitTime = 30 * time.Minute
-
-func (s *Integration) checkRateLimitAndSleepIfNecessary() error {
-	if s.requestsBuffer == 0 {
-		return nil
-	}
-
-	s.logger.Info("making request to check rate limit quota")
-
-	query := `
-	query {
-		rateLimit {
-			limit
-			remaining
-			resetAt
-		}
-	}
-	`
-
-	var res struct {
-		Data struct {
-			RateLimit struct {
-				Limit     int       `json:"limit"`
-				Remaining int       `json:"remaining"`
-				ResetAt   time.Time `json:"resetAt"`
-			} `json:"rateLimit"`
-		} `json:"data"`
-	}
-
-	data := map[string]string{
-		"query": query,
-	}
-
-	b, err := json.Marshal(data)
-	if err != nil {
-		return fmt.Errorf("could not make request, marshaling of request data failed, err: %v", err)
-	}
-
-	u := s.config.APIURL
-	req := request{Method: "POST", URL: u, Body: b}
-
-	_, err = s.makeRequestRetryThrottled(req, &res, maxThrottledRetries)
-	if err != nil {
-		return err
-	}
-
-	rl := res.Data.RateLimit
-	if rl.Limit == 0 {
-		// {"data":{"rateLimit":null}}
-		return fmt.Errorf("rateLimit returned invalid object, resulting data Limit is 0")
-	}
-
-	if float64(rl.Remaining)/float64(rl.Limit) > s.requestsBuffer {
-		// still more than buffer requests left in quota
-		return nil
-	}
 
-	s.logger.Warn("pausing due to used up request quota, keeping some buffer unused", "remaining", rl.Remaining, "limit", rl.Limit, "wanted_buffer", s.requestsBuffer, "reset_at", rl.ResetAt)
-
-	// used all up-to buffer, pause
-	waitTime := rl.ResetAt.Sub(time.Now())
-	s.pause(waitTime)
-	return nil
-}
+// abuseDetectionWait is the pause after github abuse detection response. Responses with Retry-After are already retried by governor in http client, this handles the ones without it.
+const abuseDetectionWait = 5 * time.Minute
 
 func (s *Integration) pause(waitTime time.Duration) {
-	if waitTime < minWaitTime {
-		waitTime = minWaitTime
-	}
-	if waitTime > maxWaitTime {
-		waitTime = maxWaitTime
-	}
 	paused := time.Now()
 	resumeDate := paused.Add(waitTime)
 
@@ -236,6 +169,17 @@ func (s *Integration) setAcceptHeader(header *http.Header) {
 	}
 }
 
+// addPreview adds schema preview to accept header, keeping previews that are already set
+func addPreview(header *http.Header, preview string) {
+	if preview == "" {
+		return
+	}
+	if v := header.Get("Accept"); v != "" {
+		preview += ", " + v
+	}
+	header.Set("Accept", preview)
+}
+
 func (s *Integration) makeRequestRetryThrottled(reqDef request, res interface{}, retryThrottled int) (isErrorRetryable bool, rerr error) {
 
 	req, err := http.NewRequest(reqDef.Method, reqDef.URL, bytes.NewReader(reqDef.Body))
@@ -248,6 +192,8 @@ func (s *Integration) makeRequestRetryThrottled(reqDef request, res interface{},
 		s.setAcceptHeader(&req.Header)
 	}
 
+	addPreview(&req.Header, reqDef.Preview)
+
 	req.Header.Set("Authorization", "bearer "+s.config.Token)
 	resp, err := s.clients.TLSInsecure.Do(req)
 	if err != nil {
@@ -269,24 +215,10 @@ func (s *Integration) makeRequestRetryThrottled(reqDef request, res interface{},
 			rerr = fmt.Errorf(`can't retry, too many retries already (resp.StatusCode=%v)`, resp.StatusCode)
 			return
 		}
-		limitReset := resp.Header.Get("X-RateLimit-Reset")
-		waitTime := 30 * time.Minute
-
-		// set defaults in case limitReset returns ""
-		if limitReset != "" {
-			if i, err := strconv.Atoi(limitReset); err != nil {
-				s.logger.Error("can't convert X-RateLimit-Reset to number", "err", err)
-			} else {
-				waitTime = time.Until(time.Unix(int64(i), 0))
-			}
-		}
-
-		s.logger.Warn("api request failed due to throttling, will sleep and retry, this should only happen if hourly quota is used up, check here (https://developer.github.com/v4/guides/resource-limitations/#returning-a-calls-rate-limit-status)", "body", string(b), "retryThrottled", retryThrottled)
-
-		s.pause(waitTime)
+		// graphql returns RATE_LIMITED with 200 status code, rate limit headers of this response make the governor in http client wait for quota reset before the retry
+		s.logger.Warn("api request failed due to throttling, will retry after quota reset, this should only happen if hourly quota is used up, check here (https://developer.github.com/v4/guides/resource-limitations/#returning-a-calls-rate-limit-status)", "body", string(b), "retryThrottled", retryThrottled)
 
 		return s.makeRequestRetryThrottled(reqDef, res, retryThrottled+1)
-
 	}
 
 	// check if there were errors returned first
@@ -294,8 +226,12 @@ func (s *Integration) makeRequestRetryThrottled(reqDef request, res interface{},
 	if resp.StatusCode != 200 {
 
 		if resp.StatusCode == 403 && strings.Contains(string(b), "You have triggered an abuse detection mechanism. Please wait a few minutes before you try again.") {
+			if retryThrottled >= maxThrottledRetries {
+				rerr = fmt.Errorf(`can't retry, too many retries already (resp.StatusCode=%v)`, resp.StatusCode)
+				return
+			}
 			s.logger.Warn("api request failed due to temporary throttling or concurrency being too high, pausing for 5m", "body", string(b), "retryThrottled", retryThrottled)
-			s.pause(5 * time.Minute)
+			s.pause(abuseDetectionWait)
 			return s.makeRequestRetryThrottled(reqDef, res, retryThrottled+1)
 		}
 
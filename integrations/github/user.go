@@ -12,6 +12,7 @@ import (
 
 	"github.com/pinpt/agent/integrations/github/api"
 	"github.com/pinpt/integration-sdk/sourcecode"
+	"github.com/pinpt/integration-sdk/work"
 )
 
 // map[login]refID
@@ -21,6 +22,8 @@ type Users struct {
 	loginToID     map[string]string
 	exportedRefID map[string]bool
 	mu            sync.Mutex
+	// workUsers sends users as work.User for work integration
+	workUsers bool
 }
 
 func NewUsers(integration *Integration, noExport bool) (*Users, error) {
@@ -38,6 +41,21 @@ func NewUsers(integration *Integration, noExport bool) (*Users, error) {
 	return s, nil
 }
 
+// NewWorkUsers creates users exporting work.User objects, used in work mode
+func NewWorkUsers(integration *Integration) (*Users, error) {
+	s := &Users{}
+	s.integration = integration
+	s.workUsers = true
+	var err error
+	s.sender, err = objsender.Root(integration.agent, work.UserModelName.String())
+	if err != nil {
+		return nil, err
+	}
+	s.loginToID = map[string]string{}
+	s.exportedRefID = map[string]bool{}
+	return s, nil
+}
+
 func NewUsersWebhooks(integration *Integration, sessions *objsender.SessionsWebhook) (*Users, error) {
 	s := &Users{}
 	s.integration = integration
@@ -133,7 +151,7 @@ func (s *Users) sendUsers(users []*sourcecode.User) error {
 			return nil
 		}
 		s.exportedRefID[user.RefID] = true
-		err := s.sender.Send(user)
+		err := s.send(user)
 		if err != nil {
 			return err
 		}
@@ -141,6 +159,28 @@ func (s *Users) sendUsers(users []*sourcecode.User) error {
 	return nil
 }
 
+func (s *Users) send(user *sourcecode.User) error {
+	if !s.workUsers {
+		return s.sender.Send(user)
+	}
+	var username string
+	if user.Username != nil {
+		username = *user.Username
+	}
+	return s.sender.Send(&work.User{
+		AssociatedRefID: user.AssociatedRefID,
+		AvatarURL:       user.AvatarURL,
+		CustomerID:      user.CustomerID,
+		Email:           user.Email,
+		Member:          user.Member,
+		Name:            user.Name,
+		RefID:           user.RefID,
+		RefType:         user.RefType,
+		URL:             user.URL,
+		Username:        username,
+	})
+}
+
 func (s *Users) exportInstanceUsers() error {
 	resChan := make(chan []*sourcecode.User)
 	done := make(chan error)
@@ -164,7 +204,7 @@ func (s *Users) exportUsersFromChan(usersChan chan []*sourcecode.User) error {
 	for users := range usersChan {
 		for _, user := range users {
 			s.loginToID[*user.Username] = user.RefID
-			err := s.sender.Send(user)
+			err := s.send(user)
 			if err != nil {
 				return err
 			}
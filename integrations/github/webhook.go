@@ -39,6 +39,7 @@ func (s *Integration) Webhook(ctx context.Context, headers map[string]string, bo
 	}
 
 	s.qc.Request = s.makeRequestNoRetries
+	s.qc.RequestPreview = s.makeRequestNoRetriesPreview
 
 	var data map[string]interface{}
 
@@ -0,0 +1,170 @@
+package main
+
+import (
+	"context"
+	"time"
+
+	"github.com/pinpt/agent/cmd/cmdrunnorestarts/inconfig"
+	"github.com/pinpt/agent/integrations/github/api"
+	"github.com/pinpt/agent/integrations/pkg/objsender"
+	"github.com/pinpt/agent/integrations/pkg/repoprojects"
+	"github.com/pinpt/agent/rpcdef"
+	"github.com/pinpt/integration-sdk/work"
+)
+
+// exportWork exports issues, milestones and project board columns of repos for work integration
+func (s *Integration) exportWork(ctx context.Context) (_ []rpcdef.ExportProject, rerr error) {
+
+	orgs, err := s.getOrgs()
+	if err != nil {
+		rerr = err
+		return
+	}
+
+	s.users, err = NewWorkUsers(s)
+	if err != nil {
+		rerr = err
+		return
+	}
+	err = s.users.ExportAllOrgUsers(orgs)
+	if err != nil {
+		rerr = err
+		return
+	}
+
+	s.qc.ExportUserUsingFullDetails = s.users.ExportUserUsingFullDetails
+
+	repos, err := s.getFilteredRepos(orgs)
+	if err != nil {
+		rerr = err
+		return
+	}
+
+	projectSender, err := objsender.Root(s.agent, work.ProjectModelName.String())
+	if err != nil {
+		rerr = err
+		return
+	}
+	// project is marked as exported after all issues are exported
+	projectSender.SetNoAutoProgress(true)
+	err = projectSender.SetTotal(len(repos))
+	if err != nil {
+		rerr = err
+		return
+	}
+
+	for _, r := range repos {
+		repo := r.(exportRepo)
+		project := &work.Project{}
+		project.CustomerID = s.customerID
+		project.RefType = s.refType
+		project.RefID = repo.ID
+		project.Name = repo.NameWithOwner
+		project.Identifier = repo.NameWithOwner
+		project.URL = urlAppend(s.config.RepoURLPrefix, repo.NameWithOwner)
+		project.Active = true
+		err := projectSender.Send(project)
+		if err != nil {
+			rerr = err
+			return
+		}
+	}
+
+	processOpts := repoprojects.ProcessOpts{}
+	processOpts.Logger = s.logger
+	processOpts.ProjectFn = func(ctx *repoprojects.ProjectCtx) error {
+		repo := ctx.Project.(exportRepo)
+		return s.exportWorkRepo(ctx, repo.Repo())
+	}
+	processOpts.Concurrency = s.config.Concurrency
+	if processOpts.Concurrency < 1 {
+		processOpts.Concurrency = 1
+	}
+	processOpts.Projects = repos
+
+	processOpts.IntegrationType = inconfig.IntegrationTypeWork
+	processOpts.CustomerID = s.customerID
+	processOpts.RefType = s.refType
+	processOpts.Sender = projectSender
+
+	processor := repoprojects.NewProcess(processOpts)
+	exportResult, err := processor.Run()
+	if err != nil {
+		rerr = err
+		return
+	}
+
+	err = projectSender.Done()
+	if err != nil {
+		rerr = err
+		return
+	}
+
+	err = s.users.Done()
+	if err != nil {
+		rerr = err
+		return
+	}
+
+	s.logger.Info(s.clientManager.PrintStats())
+
+	return exportResult, nil
+}
+
+func (s *Integration) exportWorkRepo(ctx *repoprojects.ProjectCtx, repo api.Repo) error {
+	err := s.exportWorkStatuses(ctx, repo)
+	if err != nil {
+		return err
+	}
+	err = s.exportWorkSprints(ctx, repo)
+	if err != nil {
+		return err
+	}
+	return s.exportWorkIssues(ctx, repo)
+}
+
+func (s *Integration) exportWorkStatuses(ctx *repoprojects.ProjectCtx, repo api.Repo) error {
+	sender, err := ctx.Session(work.IssueStatusModelName)
+	if err != nil {
+		return err
+	}
+	statuses, err := api.WorkStatusesAll(s.qc.WithLogger(ctx.Logger), repo)
+	if err != nil {
+		return err
+	}
+	err = sender.SetTotal(len(statuses))
+	if err != nil {
+		return err
+	}
+	for _, obj := range statuses {
+		err := sender.Send(obj)
+		if err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+func (s *Integration) exportWorkSprints(ctx *repoprojects.ProjectCtx, repo api.Repo) error {
+	sender, err := ctx.Session(work.SprintModelName)
+	if err != nil {
+		return err
+	}
+	return api.PaginateNewerThan(sender.LastProcessedTime(), func(query string, stopOnUpdatedAt time.Time) (api.PageInfo, error) {
+		pi, res, totalCount, err := api.WorkSprintsPage(s.qc.WithLogger(ctx.Logger), repo, query, stopOnUpdatedAt)
+		if err != nil {
+			return pi, err
+		}
+		err = sender.SetTotal(totalCount)
+		if err != nil {
+			return pi, err
+		}
+		for _, obj := range res {
+			err := sender.Send(obj)
+			if err != nil {
+				return pi, err
+			}
+		}
+		return pi, nil
+	})
+}
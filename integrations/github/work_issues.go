@@ -0,0 +1,87 @@
+package main
+
+import (
+	"fmt"
+	"strings"
+	"time"
+
+	"github.com/pinpt/agent/integrations/github/api"
+	"github.com/pinpt/agent/integrations/pkg/objsender"
+	"github.com/pinpt/agent/integrations/pkg/repoprojects"
+	"github.com/pinpt/integration-sdk/work"
+)
+
+func (s *Integration) exportWorkIssues(ctx *repoprojects.ProjectCtx, repo api.Repo) error {
+	qc := s.qc.WithLogger(ctx.Logger.With("repo", repo.NameWithOwner))
+
+	issueSender, err := ctx.Session(work.IssueModelName)
+	if err != nil {
+		return err
+	}
+	commentSender, err := ctx.Session(work.IssueCommentModelName)
+	if err != nil {
+		return err
+	}
+
+	return api.PaginateNewerThanWithPageSize(issueSender.LastProcessedTime(), pageSizeHeavyQueries, func(query string, stopOnUpdatedAt time.Time) (api.PageInfo, error) {
+		pi, res, totalCount, err := api.WorkIssuesPage(qc, repo, query, stopOnUpdatedAt)
+		if err != nil {
+			return pi, err
+		}
+		err = issueSender.SetTotal(totalCount)
+		if err != nil {
+			return pi, err
+		}
+		for _, issue := range res {
+			issue.ChangeLog, err = s.workIssueChangelog(qc, issue.RefID)
+			if err != nil {
+				return pi, fmt.Errorf("could not get issue changelog: %v", err)
+			}
+			if issue.HasComments {
+				err := s.exportWorkIssueComments(qc, repo, issue.RefID, commentSender)
+				if err != nil {
+					return pi, fmt.Errorf("could not export issue comments: %v", err)
+				}
+			}
+			err = issueSender.Send(issue.Issue)
+			if err != nil {
+				return pi, err
+			}
+		}
+		return pi, nil
+	})
+}
+
+func (s *Integration) workIssueChangelog(qc api.QueryContext, issueRefID string) ([]work.IssueChangeLog, error) {
+	if !s.isAssigneeAvailableSet() {
+		s.logger.Info("check assignee availability")
+		_, err := api.WorkIssueChangelog(qc, issueRefID, true)
+		if err != nil {
+			if !strings.Contains(err.Error(), "Field 'assignee' doesn't exist on type 'AssignedEvent'") {
+				return nil, err
+			}
+			s.logger.Info("setting assignee availability", "status", false)
+			s.setAssigneeAvailability(false)
+		} else {
+			s.logger.Info("setting assignee availability", "status", true)
+			s.setAssigneeAvailability(true)
+		}
+	}
+	return api.WorkIssueChangelog(qc, issueRefID, s.isAssigneeAvailable())
+}
+
+func (s *Integration) exportWorkIssueComments(qc api.QueryContext, repo api.Repo, issueRefID string, sender *objsender.Session) error {
+	return api.PaginateRegular(func(query string) (api.PageInfo, error) {
+		pi, res, _, err := api.WorkIssueCommentsPage(qc, repo, issueRefID, query)
+		if err != nil {
+			return pi, err
+		}
+		for _, obj := range res {
+			err := sender.Send(obj)
+			if err != nil {
+				return pi, err
+			}
+		}
+		return pi, nil
+	})
+}
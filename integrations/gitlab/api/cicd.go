@@ -0,0 +1,201 @@
+package api
+
+import (
+	"net/url"
+	"strconv"
+	"time"
+
+	"github.com/hashicorp/go-hclog"
+	"github.com/pinpt/agent/integrations/pkg/cicd"
+	"github.com/pinpt/agent/integrations/pkg/commonrepo"
+	"github.com/pinpt/agent/pkg/date"
+	pstrings "github.com/pinpt/go-common/v10/strings"
+)
+
+func buildStatus(status string) cicd.Status {
+	switch status {
+	case "created", "waiting_for_resource", "preparing", "pending", "scheduled", "manual":
+		return cicd.StatusPending
+	case "running":
+		return cicd.StatusRunning
+	case "success":
+		return cicd.StatusSuccess
+	case "canceled":
+		return cicd.StatusCancelled
+	case "skipped":
+		return cicd.StatusSkipped
+	}
+	return cicd.StatusFailure
+}
+
+func deploymentStatus(status string) cicd.Status {
+	switch status {
+	case "created", "blocked":
+		return cicd.StatusPending
+	case "running":
+		return cicd.StatusRunning
+	case "success":
+		return cicd.StatusSuccess
+	case "canceled":
+		return cicd.StatusCancelled
+	}
+	return cicd.StatusFailure
+}
+
+// BuildsPage returns pipelines updated after lastProcessed, if set. Jobs and start and completion dates are not set, use BuildJobs.
+func BuildsPage(qc QueryContext, repo commonrepo.Repo, params url.Values, lastProcessed time.Time) (pi PageInfo, res []*cicd.Build, err error) {
+
+	qc.Logger.Debug("repo pipelines", "repo", repo.NameWithOwner, "params", params)
+
+	objectPath := pstrings.JoinURL("projects", url.QueryEscape(repo.RefID), "pipelines")
+	params.Set("per_page", strconv.Itoa(PageSize))
+	if !lastProcessed.IsZero() {
+		params.Set("updated_after", lastProcessed.Format(time.RFC3339Nano))
+		params.Set("order_by", "updated_at")
+	}
+
+	var rpipelines []struct {
+		ID        int64     `json:"id"`
+		Sha       string    `json:"sha"`
+		Ref       string    `json:"ref"`
+		Status    string    `json:"status"`
+		Source    string    `json:"source"`
+		WebURL    string    `json:"web_url"`
+		CreatedAt time.Time `json:"created_at"`
+	}
+
+	pi, err = qc.Request(objectPath, params, &rpipelines)
+	if err != nil {
+		return
+	}
+
+	for _, data := range rpipelines {
+		item := &cicd.Build{}
+		item.CustomerID = qc.CustomerID
+		item.RefType = qc.RefType
+		item.RefID = strconv.FormatInt(data.ID, 10)
+		item.RepoID = qc.IDs.CodeRepo(repo.RefID)
+		item.ID = cicd.NewBuildID(qc.CustomerID, qc.RefType, item.RepoID, item.RefID)
+		item.Name = data.Ref
+		item.URL = data.WebURL
+		item.Status = buildStatus(data.Status)
+		item.Trigger = data.Source
+		item.CommitSha = data.Sha
+		item.CommitID = qc.IDs.CodeCommit(item.RepoID, data.Sha)
+		item.Branch = data.Ref
+		date.ConvertToModel(data.CreatedAt, &item.CreatedDate)
+		res = append(res, item)
+	}
+
+	return
+}
+
+// BuildJobs sets jobs of the pipeline on build. Build start and completion dates are taken from the first and last job.
+func BuildJobs(qc QueryContext, repo commonrepo.Repo, build *cicd.Build) error {
+	objectPath := pstrings.JoinURL("projects", url.QueryEscape(repo.RefID), "pipelines", build.RefID, "jobs")
+
+	var started, finished time.Time
+	err := PaginateStartAt(qc.Logger, func(log hclog.Logger, params url.Values) (pi PageInfo, _ error) {
+		params.Set("per_page", strconv.Itoa(PageSize))
+		var rjobs []struct {
+			ID         int64     `json:"id"`
+			Name       string    `json:"name"`
+			Stage      string    `json:"stage"`
+			Status     string    `json:"status"`
+			StartedAt  time.Time `json:"started_at"`
+			FinishedAt time.Time `json:"finished_at"`
+		}
+		pi, err := qc.Request(objectPath, params, &rjobs)
+		if err != nil {
+			return pi, err
+		}
+		for _, data := range rjobs {
+			item := cicd.Job{}
+			item.RefID = strconv.FormatInt(data.ID, 10)
+			item.Name = data.Stage + ":" + data.Name
+			item.Status = buildStatus(data.Status)
+			date.ConvertToModel(data.StartedAt, &item.StartedDate)
+			date.ConvertToModel(data.FinishedAt, &item.CompletedDate)
+			item.Duration = cicd.Duration(data.StartedAt, data.FinishedAt)
+			build.Jobs = append(build.Jobs, item)
+			if !data.StartedAt.IsZero() && (started.IsZero() || data.StartedAt.Before(started)) {
+				started = data.StartedAt
+			}
+			if data.FinishedAt.After(finished) {
+				finished = data.FinishedAt
+			}
+		}
+		return pi, nil
+	})
+	if err != nil {
+		return err
+	}
+	date.ConvertToModel(started, &build.StartedDate)
+	if build.Status.Finished() {
+		date.ConvertToModel(finished, &build.CompletedDate)
+		build.Duration = cicd.Duration(started, finished)
+	}
+	return nil
+}
+
+// DeploymentsPage returns deployments updated after lastProcessed, if set
+func DeploymentsPage(qc QueryContext, repo commonrepo.Repo, params url.Values, lastProcessed time.Time) (pi PageInfo, res []*cicd.Deployment, err error) {
+
+	qc.Logger.Debug("repo deployments", "repo", repo.NameWithOwner, "params", params)
+
+	objectPath := pstrings.JoinURL("projects", url.QueryEscape(repo.RefID), "deployments")
+	params.Set("per_page", strconv.Itoa(PageSize))
+	params.Set("order_by", "updated_at")
+	if !lastProcessed.IsZero() {
+		params.Set("updated_after", lastProcessed.Format(time.RFC3339Nano))
+	}
+
+	var rdeployments []struct {
+		ID          int64     `json:"id"`
+		Sha         string    `json:"sha"`
+		Ref         string    `json:"ref"`
+		Status      string    `json:"status"`
+		CreatedAt   time.Time `json:"created_at"`
+		Environment struct {
+			Name        string `json:"name"`
+			ExternalURL string `json:"external_url"`
+		} `json:"environment"`
+		User struct {
+			ID int64 `json:"id"`
+		} `json:"user"`
+		Deployable struct {
+			FinishedAt time.Time `json:"finished_at"`
+		} `json:"deployable"`
+	}
+
+	pi, err = qc.Request(objectPath, params, &rdeployments)
+	if err != nil {
+		return
+	}
+
+	for _, data := range rdeployments {
+		item := &cicd.Deployment{}
+		item.CustomerID = qc.CustomerID
+		item.RefType = qc.RefType
+		item.RefID = strconv.FormatInt(data.ID, 10)
+		item.RepoID = qc.IDs.CodeRepo(repo.RefID)
+		item.ID = cicd.NewDeploymentID(qc.CustomerID, qc.RefType, item.RepoID, item.RefID)
+		item.Environment = data.Environment.Name
+		item.URL = data.Environment.ExternalURL
+		item.Status = deploymentStatus(data.Status)
+		item.CommitSha = data.Sha
+		item.CommitID = qc.IDs.CodeCommit(item.RepoID, data.Sha)
+		item.Branch = data.Ref
+		if data.User.ID != 0 {
+			item.CreatorRefID = strconv.FormatInt(data.User.ID, 10)
+		}
+		date.ConvertToModel(data.CreatedAt, &item.CreatedDate)
+		if item.Status.Finished() {
+			date.ConvertToModel(data.Deployable.FinishedAt, &item.CompletedDate)
+			item.Duration = cicd.Duration(data.CreatedAt, data.Deployable.FinishedAt)
+		}
+		res = append(res, item)
+	}
+
+	return
+}
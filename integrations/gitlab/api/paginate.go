@@ -55,3 +55,21 @@ func PaginateNewerThan(log hclog.Logger, lastProcessed time.Time, fn PaginateNew
 		nextPage = pageInfo.NextPage
 	}
 }
+
+// PageSize is the per_page param used in paginated requests
+const PageSize = 100
+
+// Count returns the total number of objects for list endpoint using X-Total header, requesting only 1 object. Gitlab does not return the header for more than 10,000 objects, in that case 0 is returned.
+func Count(qc QueryContext, objectPath string, params url.Values) (int, error) {
+	if params == nil {
+		params = url.Values{}
+	}
+	params.Set("page", "1")
+	params.Set("per_page", "1")
+	var res []interface{}
+	pi, err := qc.Request(objectPath, params, &res)
+	if err != nil {
+		return 0, err
+	}
+	return pi.Total, nil
+}
@@ -1,7 +1,6 @@
 package api
 
 import (
-	"crypto/tls"
 	"encoding/json"
 	"fmt"
 	"io/ioutil"
@@ -9,38 +8,26 @@ import (
 	"net/url"
 	"strconv"
 	"sync"
-	"time"
 
 	"github.com/hashicorp/go-hclog"
-	"github.com/pinpt/agent/rpcdef"
-	"github.com/pinpt/go-common/v10/httpdefaults"
 	pstrings "github.com/pinpt/go-common/v10/strings"
 )
 
 // RequesterOpts requester opts
 type RequesterOpts struct {
-	Logger             hclog.Logger
-	APIURL             string
-	APIKey             string
-	AccessToken        string
-	InsecureSkipVerify bool
-	ServerType         ServerType
-	Concurrency        chan bool
-	Client             *http.Client
-	Agent              rpcdef.Agent
+	Logger      hclog.Logger
+	APIURL      string
+	APIKey      string
+	AccessToken string
+	ServerType  ServerType
+	Concurrency chan bool
+	// Client is the http client created by reqstats, it handles rate limits
+	Client *http.Client
 }
 
 // NewRequester new requester
 func NewRequester(opts RequesterOpts) *Requester {
 	re := &Requester{}
-	{
-		c := &http.Client{}
-		transport := httpdefaults.DefaultTransport()
-		transport.TLSClientConfig = &tls.Config{InsecureSkipVerify: opts.InsecureSkipVerify}
-		c.Transport = transport
-		opts.Client = c
-	}
-
 	re.opts = opts
 
 	return re
@@ -149,28 +136,11 @@ func (e *Requester) request(r *internalRequest, retryThrottled int) (isErrorRetr
 		isErrorRetryable = true
 		return
 	}
-	rateLimited := func() (isErrorRetryable bool, pageInfo PageInfo, rerr error) {
-
-		waitTime := time.Minute * 3
-
-		e.opts.Logger.Warn("api request failed due to throttling, the quota of 600 calls has been reached, will sleep for 3m and retry", "retryThrottled", retryThrottled)
-
-		paused := time.Now()
-		resumeDate := paused.Add(waitTime)
-		e.opts.Agent.SendPauseEvent(fmt.Sprintf("gitlab paused, it will resume in %v", waitTime), resumeDate)
-
-		time.Sleep(waitTime)
-
-		e.opts.Agent.SendResumeEvent(fmt.Sprintf("gitlab resumed, time elapsed %v", time.Since(paused)))
-
-		return true, PageInfo{}, fmt.Errorf("Too many requests")
-
-	}
-
 	if resp.StatusCode != http.StatusOK {
 
 		if resp.StatusCode == http.StatusTooManyRequests {
-			return rateLimited()
+			// http client already waited and retried
+			return false, pi, fmt.Errorf("Too many requests")
 		}
 
 		if resp.StatusCode == http.StatusForbidden {
@@ -0,0 +1,90 @@
+package main
+
+import (
+	"net/url"
+
+	"github.com/hashicorp/go-hclog"
+	"github.com/pinpt/agent/integrations/gitlab/api"
+	"github.com/pinpt/agent/integrations/pkg/cicd"
+	"github.com/pinpt/agent/integrations/pkg/commonrepo"
+	"github.com/pinpt/agent/integrations/pkg/objsender"
+	"github.com/pinpt/agent/integrations/pkg/repoprojects"
+)
+
+// exportCICD exports pipelines and deployments of the repo. Api errors are only logged, since CI/CD could be disabled for the project.
+func (s *Integration) exportCICD(ctx *repoprojects.ProjectCtx, repo commonrepo.Repo) error {
+	logger := ctx.Logger.With("repo", repo.NameWithOwner)
+
+	buildSender, err := ctx.Session(cicd.BuildModelName)
+	if err != nil {
+		return err
+	}
+	deploymentSender, err := ctx.Session(cicd.DeploymentModelName)
+	if err != nil {
+		return err
+	}
+
+	err = s.exportBuilds(logger, repo, buildSender)
+	if err != nil {
+		return err
+	}
+	return s.exportDeployments(logger, repo, deploymentSender)
+}
+
+func (s *Integration) exportBuilds(logger hclog.Logger, repo commonrepo.Repo, sender *objsender.Session) error {
+	qc := s.qc
+	qc.Logger = logger
+	lastProcessed := sender.LastProcessedTime()
+	var sendErr error
+	err := api.PaginateStartAt(logger, func(log hclog.Logger, params url.Values) (api.PageInfo, error) {
+		pi, builds, err := api.BuildsPage(qc, repo, params, lastProcessed)
+		if err != nil {
+			return pi, err
+		}
+		for _, build := range builds {
+			err = api.BuildJobs(qc, repo, build)
+			if err != nil {
+				return pi, err
+			}
+			sendErr = sender.Send(build)
+			if sendErr != nil {
+				return pi, sendErr
+			}
+		}
+		return pi, nil
+	})
+	if sendErr != nil {
+		return sendErr
+	}
+	if err != nil {
+		logger.Warn("could not export pipelines", "err", err)
+	}
+	return nil
+}
+
+func (s *Integration) exportDeployments(logger hclog.Logger, repo commonrepo.Repo, sender *objsender.Session) error {
+	qc := s.qc
+	qc.Logger = logger
+	lastProcessed := sender.LastProcessedTime()
+	var sendErr error
+	err := api.PaginateStartAt(logger, func(log hclog.Logger, params url.Values) (api.PageInfo, error) {
+		pi, deployments, err := api.DeploymentsPage(qc, repo, params, lastProcessed)
+		if err != nil {
+			return pi, err
+		}
+		for _, deployment := range deployments {
+			sendErr = sender.Send(deployment)
+			if sendErr != nil {
+				return pi, sendErr
+			}
+		}
+		return pi, nil
+	})
+	if sendErr != nil {
+		return sendErr
+	}
+	if err != nil {
+		logger.Warn("could not export deployments", "err", err)
+	}
+	return nil
+}
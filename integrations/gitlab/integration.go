@@ -12,11 +12,13 @@ import (
 	"github.com/pinpt/agent/integrations/gitlab/api"
 	"github.com/pinpt/agent/integrations/pkg/commiturl"
 	"github.com/pinpt/agent/integrations/pkg/commonrepo"
+	"github.com/pinpt/agent/integrations/pkg/gitaccess"
 	"github.com/pinpt/agent/integrations/pkg/ibase"
 	"github.com/pinpt/agent/integrations/pkg/objsender"
 	"github.com/pinpt/agent/integrations/pkg/repoprojects"
 	"github.com/pinpt/agent/pkg/ids"
 	"github.com/pinpt/agent/pkg/ids2"
+	"github.com/pinpt/agent/pkg/reqstats"
 	"github.com/pinpt/agent/pkg/structmarshal"
 	"github.com/pinpt/agent/rpcdef"
 	"github.com/pinpt/go-common/v10/datamodel"
@@ -26,11 +28,15 @@ import (
 
 type Config struct {
 	commonrepo.FilterConfig
+	gitaccess.Config
 	URL                string `json:"url"`
 	APIKey             string `json:"api_key"`
 	AccessToken        string `json:"access_token"`
 	OnlyGit            bool   `json:"only_git"`
 	InsecureSkipVerify bool   `json:"insecure_skip_verify"`
+
+	// MaxRequestsPerSecond limits the request rate to gitlab api. No limit by default.
+	MaxRequestsPerSecond float64 `json:"max_requests_per_second"`
 }
 
 type Integration struct {
@@ -47,6 +53,8 @@ type Integration struct {
 	refType string
 
 	isGitlabCom bool
+
+	clientManager *reqstats.ClientManager
 }
 
 func main() {
@@ -148,15 +156,25 @@ func (s *Integration) initWithConfig(config rpcdef.ExportConfig) error {
 	s.qc.Logger = s.logger
 	s.qc.RefType = s.refType
 	s.customerID = config.Pinpoint.CustomerID
+
+	s.clientManager, err = reqstats.New(reqstats.Opts{
+		Logger:                s.logger,
+		TLSInsecureSkipVerify: s.config.InsecureSkipVerify,
+		MaxRequestsPerSecond:  s.config.MaxRequestsPerSecond,
+		Pauser:                s.agent,
+	})
+	if err != nil {
+		return err
+	}
+
 	{
 		opts := api.RequesterOpts{}
 		opts.Logger = s.logger
 		opts.APIURL = s.config.URL + "/api/v4"
 		opts.APIKey = s.config.APIKey
 		opts.AccessToken = s.config.AccessToken
-		opts.InsecureSkipVerify = s.config.InsecureSkipVerify
+		opts.Client = s.clientManager.Clients.TLSInsecure
 		opts.Concurrency = make(chan bool, 10)
-		opts.Agent = s.agent
 		requester := api.NewRequester(opts)
 
 		s.qc.Request = requester.MakeRequest
@@ -192,6 +210,9 @@ func (s *Integration) setIntegrationConfig(data rpcdef.IntegrationConfig) error
 		return rerr(fmt.Sprintf("url is not valid: %v", err))
 	}
 	s.isGitlabCom = u.Hostname() == "gitlab.com"
+	if err := conf.Config.Validate(); err != nil {
+		return rerr("%v", err)
+	}
 	s.config = conf
 	return nil
 }
@@ -253,7 +274,10 @@ func (s *Integration) exportGit(repo commonrepo.Repo, prs []rpcdef.GitRepoFetchP
 	args.RepoID = s.qc.IDs.CodeRepo(repo.RefID)
 	args.UniqueName = repo.NameWithOwner
 	args.RefType = s.refType
-	args.URL = repoURL
+	err = s.config.Config.Apply(&args, repoURL, repo.NameWithOwner+".git")
+	if err != nil {
+		return err
+	}
 	args.CommitURLTemplate = commiturl.CommitURLTemplate(repo, s.config.URL)
 	args.BranchURLTemplate = commiturl.BranchURLTemplate(repo, s.config.URL)
 	args.PRs = prs
@@ -384,6 +408,11 @@ func (s *Integration) exportRepoChildren(ctx *repoprojects.ProjectCtx, repo comm
 		return err
 	}
 
+	err = s.exportCICD(ctx, repo)
+	if err != nil {
+		return err
+	}
+
 	return s.exportGit(repo, prs)
 }
 
@@ -6,9 +6,15 @@ import (
 	"strings"
 
 	"github.com/hashicorp/go-hclog"
+	"github.com/pinpt/agent/cmd/cmdrunnorestarts/inconfig"
 	"github.com/pinpt/agent/integrations/gitlab/api"
+	"github.com/pinpt/agent/integrations/pkg/commonrepo"
+	"github.com/pinpt/agent/integrations/pkg/repoprojects"
 	"github.com/pinpt/agent/rpcdef"
+	pstrings "github.com/pinpt/go-common/v10/strings"
 	"github.com/pinpt/integration-sdk/agent"
+	"github.com/pinpt/integration-sdk/sourcecode"
+	"github.com/pinpt/integration-sdk/work"
 )
 
 func (s *Integration) OnboardExport(ctx context.Context, objectType rpcdef.OnboardExportType, config rpcdef.ExportConfig) (res rpcdef.OnboardExportResult, _ error) {
@@ -21,6 +27,8 @@ func (s *Integration) OnboardExport(ctx context.Context, objectType rpcdef.Onboa
 		return s.onboardExportRepos(ctx, objectType)
 	case rpcdef.OnboardExportTypeWorkConfig:
 		return s.onboardWorkConfig(ctx, config.Integration.ID)
+	case rpcdef.OnboardExportTypePlan:
+		return s.onboardExportPlan(ctx, config.Integration.Type)
 	default:
 		res.Error = rpcdef.ErrOnboardExportNotSupported
 		return
@@ -95,3 +103,62 @@ func (s *Integration) onboardExportRepos(ctx context.Context, objectType rpcdef.
 
 	return res, nil
 }
+
+// onboardExportPlan returns repos that would be exported. Filtering is done per group, same as in export.
+func (s *Integration) onboardExportPlan(ctx context.Context, intType inconfig.IntegrationType) (res rpcdef.OnboardExportResult, _ error) {
+	groups, err := api.GroupsAll(s.qc)
+	if err != nil {
+		return res, err
+	}
+
+	plan := repoprojects.Plan{}
+	plan.Projects = []repoprojects.PlanProject{}
+	plan.Excluded = []repoprojects.PlanExcluded{}
+	plan.APICalls = repoprojects.Pages(len(groups), api.PageSize)
+
+	for _, group := range groups {
+		repos, err := commonrepo.ReposAllSlice(func(res chan []commonrepo.Repo) error {
+			return api.ReposAll(s.qc, group, res)
+		})
+		if err != nil {
+			return res, err
+		}
+		// repos are listed twice in export, for filtering and when sending repo objects
+		discoveryCalls := 2 * repoprojects.Pages(len(repos), api.PageSize)
+		groupPlan := commonrepo.Plan(s.logger.With("org", group), repos, s.config.FilterConfig, discoveryCalls, func(repo commonrepo.Repo) (repoprojects.PlanProject, error) {
+			return s.planRepo(repo, intType)
+		})
+		plan.Projects = append(plan.Projects, groupPlan.Projects...)
+		plan.Excluded = append(plan.Excluded, groupPlan.Excluded...)
+		plan.APICalls += groupPlan.APICalls
+	}
+
+	res.Data = plan
+	return res, nil
+}
+
+func (s *Integration) planRepo(repo commonrepo.Repo, intType inconfig.IntegrationType) (res repoprojects.PlanProject, rerr error) {
+	res.Counts = map[string]int{}
+
+	if intType == inconfig.IntegrationTypeWork {
+		issues, err := api.Count(s.qc, pstrings.JoinURL("projects", url.QueryEscape(repo.RefID), "issues"), url.Values{"scope": []string{"all"}})
+		if err != nil {
+			rerr = err
+			return
+		}
+		res.Counts[work.IssueModelName.String()] = issues
+		// issue pages, and discussions and state events for each issue
+		res.APICalls = repoprojects.Pages(issues, api.PageSize) + 2*issues
+		return
+	}
+
+	prs, err := api.Count(s.qc, pstrings.JoinURL("projects", url.QueryEscape(repo.RefID), "merge_requests"), url.Values{"scope": []string{"all"}, "state": []string{"all"}})
+	if err != nil {
+		rerr = err
+		return
+	}
+	res.Counts[sourcecode.PullRequestModelName.String()] = prs
+	// merge request pages, and comments, commits and approvals for each merge request
+	res.APICalls = repoprojects.Pages(prs, api.PageSize) + 3*prs
+	return
+}
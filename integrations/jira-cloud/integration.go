@@ -100,6 +100,8 @@ func (s *Integration) initWithConfig(config rpcdef.ExportConfig, retryRequests b
 	s.clientManager, err = reqstats.New(reqstats.Opts{
 		Logger:                s.logger,
 		TLSInsecureSkipVerify: false,
+		MaxRequestsPerSecond:  s.config.MaxRequestsPerSecond,
+		Pauser:                s.agent,
 	})
 	if err != nil {
 		return err
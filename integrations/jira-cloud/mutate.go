@@ -3,10 +3,15 @@ package main
 import (
 	"context"
 
+	"github.com/pinpt/agent/integrations/jira/common"
+	"github.com/pinpt/agent/integrations/pkg/mutate"
 	"github.com/pinpt/agent/rpcdef"
 )
 
 func (s *Integration) Mutate(ctx context.Context, fn, data string, config rpcdef.ExportConfig) (res rpcdef.MutateResult, _ error) {
+	if fn == mutate.FnListMutations {
+		return common.ListMutations(), nil
+	}
 	err := s.initWithConfig(config, false)
 	if err != nil {
 		return res, err
@@ -7,6 +7,7 @@ import (
 	"github.com/pinpt/agent/integrations/jira-cloud/api"
 	"github.com/pinpt/agent/integrations/jira/common"
 	"github.com/pinpt/agent/integrations/jira/commonapi"
+	"github.com/pinpt/agent/integrations/pkg/repoprojects"
 	"github.com/pinpt/agent/rpcdef"
 	"github.com/pinpt/integration-sdk/agent"
 )
@@ -17,6 +18,8 @@ func (s *Integration) OnboardExport(ctx context.Context, objectType rpcdef.Onboa
 		return s.onboardExportProjects(ctx, config)
 	case rpcdef.OnboardExportTypeWorkConfig:
 		return s.onboardWorkConfig(ctx, config)
+	case rpcdef.OnboardExportTypePlan:
+		return s.onboardExportPlan(ctx, config)
 	default:
 		res.Error = rpcdef.ErrOnboardExportNotSupported
 		return
@@ -66,3 +69,19 @@ func (s *Integration) onboardWorkConfig(ctx context.Context, config rpcdef.Expor
 
 	return common.GetWorkConfig(s.qc.Common())
 }
+
+func (s *Integration) onboardExportPlan(ctx context.Context, config rpcdef.ExportConfig) (res rpcdef.OnboardExportResult, _ error) {
+
+	err := s.initWithConfig(config, false)
+	if err != nil {
+		return res, err
+	}
+
+	projects, err := s.projects()
+	if err != nil {
+		return res, err
+	}
+
+	res.Data = s.common.Plan(projects, repoprojects.Pages(len(projects), 50))
+	return res, nil
+}
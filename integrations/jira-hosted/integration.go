@@ -92,6 +92,8 @@ func (s *Integration) initWithConfig(config rpcdef.ExportConfig, retryRequests b
 		OAuth1ConsumerKey:     s.config.Username,
 		OAuth1Token:           s.config.Password,
 		OAuth1URL:             s.config.URL,
+		MaxRequestsPerSecond:  s.config.MaxRequestsPerSecond,
+		Pauser:                s.agent,
 	})
 	if err != nil {
 		return err
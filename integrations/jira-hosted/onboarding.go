@@ -14,6 +14,8 @@ func (s *Integration) OnboardExport(ctx context.Context, objectType rpcdef.Onboa
 		return s.onboardExportProjects(ctx, config)
 	case rpcdef.OnboardExportTypeWorkConfig:
 		return s.onboardWorkConfig(ctx, config)
+	case rpcdef.OnboardExportTypePlan:
+		return s.onboardExportPlan(ctx, config)
 	default:
 		res.Error = rpcdef.ErrOnboardExportNotSupported
 		return
@@ -49,3 +51,19 @@ func (s *Integration) onboardWorkConfig(ctx context.Context, config rpcdef.Expor
 
 	return common.GetWorkConfig(s.qc.Common())
 }
+
+func (s *Integration) onboardExportPlan(ctx context.Context, config rpcdef.ExportConfig) (res rpcdef.OnboardExportResult, _ error) {
+
+	err := s.initWithConfig(config, false)
+	if err != nil {
+		return res, err
+	}
+
+	projects, err := api.Projects(s.qc)
+	if err != nil {
+		return res, err
+	}
+
+	res.Data = s.common.Plan(projects, 1)
+	return res, nil
+}
@@ -9,4 +9,7 @@ type Config struct {
 	Inclusions []string `json:"inclusions"`
 	// Projects specifies a specific projects to process. Ignores excluded_projects in this case. Specify projects using jira key. For example: DE.
 	Projects []string `json:"projects"`
+
+	// MaxRequestsPerSecond limits the request rate to jira api. No limit by default.
+	MaxRequestsPerSecond float64 `json:"max_requests_per_second"`
 }
@@ -0,0 +1,130 @@
+package common
+
+import (
+	"net/url"
+	"time"
+
+	"github.com/pinpt/agent/integrations/jira/commonapi"
+	"github.com/pinpt/agent/integrations/pkg/objsender"
+	"github.com/pinpt/agent/integrations/pkg/repoprojects"
+)
+
+// issueExtraSenders are project sessions for worklogs, time tracking, issue links and remote links
+type issueExtraSenders struct {
+	worklogs     *objsender.Session
+	timeTracking *objsender.Session
+	links        *objsender.Session
+	remoteLinks  *objsender.Session
+}
+
+func newIssueExtraSenders(ctx *repoprojects.ProjectCtx) (res issueExtraSenders, rerr error) {
+	var err error
+	res.worklogs, err = ctx.Session(commonapi.IssueWorklogModelName)
+	if err != nil {
+		rerr = err
+		return
+	}
+	res.timeTracking, err = ctx.Session(commonapi.IssueTimeTrackingModelName)
+	if err != nil {
+		rerr = err
+		return
+	}
+	res.links, err = ctx.Session(commonapi.IssueLinkModelName)
+	if err != nil {
+		rerr = err
+		return
+	}
+	res.remoteLinks, err = ctx.Session(commonapi.IssueRemoteLinkModelName)
+	if err != nil {
+		rerr = err
+		return
+	}
+	return
+}
+
+func (s issueExtraSenders) all() []*objsender.Session {
+	return []*objsender.Session{s.worklogs, s.timeTracking, s.links, s.remoteLinks}
+}
+
+// lastProcessed returns the oldest of issuesLastProcessed and last processed time of extra sessions. Zero time means historical export.
+func (s issueExtraSenders) lastProcessed(issuesLastProcessed time.Time) time.Time {
+	res := issuesLastProcessed
+	for _, sender := range s.all() {
+		t := sender.LastProcessedTime()
+		if res.IsZero() || t.IsZero() {
+			return time.Time{}
+		}
+		if t.Before(res) {
+			res = t
+		}
+	}
+	return res
+}
+
+// exportIssueExtras exports worklogs, time tracking, issue links and remote links of changed issue
+func (s *JiraCommon) exportIssueExtras(senders issueExtraSenders, project Project, issue commonapi.IssueWithCustomFields) error {
+	s.opts.Logger.Debug("exporting worklogs and links for issue", "project", project.Key, "issue_ref_id", issue.RefID)
+
+	err := s.exportIssueWorklogs(senders.worklogs, project, issue)
+	if err != nil {
+		return err
+	}
+	if issue.TimeTracking != nil {
+		err := senders.timeTracking.Send(issue.TimeTracking)
+		if err != nil {
+			return err
+		}
+	}
+	for _, link := range issue.Links {
+		err := senders.links.Send(link)
+		if err != nil {
+			return err
+		}
+	}
+	remoteLinks, err := commonapi.IssueRemoteLinks(s.CommonQC(), project.Project, issue.RefID)
+	if err != nil {
+		return err
+	}
+	for _, link := range remoteLinks {
+		err := senders.remoteLinks.Send(link)
+		if err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+func (s *JiraCommon) exportIssueWorklogs(sender *objsender.Session, project Project, issue commonapi.IssueWithCustomFields) error {
+	lastProcessed := sender.LastProcessedTime()
+	send := func(worklogs []*commonapi.IssueWorklog) error {
+		for _, item := range worklogs {
+			if item.UpdatedBefore(lastProcessed) {
+				continue
+			}
+			err := sender.Send(item)
+			if err != nil {
+				return err
+			}
+		}
+		return nil
+	}
+
+	if len(issue.Worklogs) >= issue.WorklogsTotal {
+		return send(issue.Worklogs)
+	}
+
+	// search only returns first 20 worklogs
+	return commonapi.PaginateStartAt(func(paginationParams url.Values) (hasMore bool, pageSize int, rerr error) {
+		pi, res, err := commonapi.IssueWorklogs(s.CommonQC(), project.Project, issue.RefID, paginationParams)
+		if err != nil {
+			rerr = err
+			return
+		}
+		err = send(res)
+		if err != nil {
+			rerr = err
+			return
+		}
+		return pi.HasMore, pi.MaxResults, nil
+	})
+}
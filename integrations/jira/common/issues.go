@@ -181,8 +181,16 @@ func (s *JiraCommon) issuesAndChangelogsForProject(
 		return err
 	}
 
+	senders, err := newIssueExtraSenders(ctx)
+	if err != nil {
+		return err
+	}
+
+	// worklogs and links are only requested for changed issues, use the oldest last processed time in case these were not exported before
+	updatedSince := senders.lastProcessed(senderIssues.LastProcessedTime())
+
 	err = commonapi.PaginateStartAt(func(paginationParams url.Values) (hasMore bool, pageSize int, rerr error) {
-		pi, resIssues, err := commonapi.IssuesAndChangelogsPage(qc, project.Project, fieldByID, senderIssues.LastProcessedTime(), paginationParams, issueResolver.IssueRefIDFromKey)
+		pi, resIssues, err := commonapi.IssuesAndChangelogsPage(qc, project.Project, fieldByID, updatedSince, paginationParams, issueResolver.IssueRefIDFromKey)
 		if err != nil {
 			rerr = err
 			return
@@ -221,6 +229,11 @@ func (s *JiraCommon) issuesAndChangelogsForProject(
 				rerr = err
 				return
 			}
+			err = s.exportIssueExtras(senders, project, obj)
+			if err != nil {
+				rerr = err
+				return
+			}
 		}
 		return pi.HasMore, pi.MaxResults, nil
 
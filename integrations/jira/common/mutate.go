@@ -2,8 +2,6 @@ package common
 
 import (
 	"context"
-	"encoding/json"
-	"fmt"
 
 	"github.com/pinpt/agent/integrations/jira/commonapi"
 	"github.com/pinpt/agent/integrations/pkg/mutate"
@@ -42,121 +40,162 @@ func (s *JiraCommon) mutationResult(modelName datamodel.ModelNameType, obj Model
 	return
 }
 
-func (s *JiraCommon) Mutate(ctx context.Context, fn, data string, config rpcdef.ExportConfig) (res rpcdef.MutateResult, _ error) {
+type issueAddComment struct {
+	IssueRefID string `json:"ref_id" mutate:"required" description:"Issue id"`
+	Body       string `json:"body" mutate:"required" description:"Comment text"`
+}
 
-	rerr := func(err error) {
-		res = mutate.ResultFromError(err)
-	}
+type issueSetTitle struct {
+	IssueID string `json:"ref_id" mutate:"required" description:"Issue id"`
+	Title   string `json:"title" mutate:"required" description:"New summary"`
+}
 
-	var action agent.IntegrationMutationRequestAction
-	err := action.FromInterface(fn)
-	if err != nil {
-		rerr(err)
-		return
-	}
+type issueSetStatus struct {
+	IssueID      string            `json:"ref_id" mutate:"required" description:"Issue id"`
+	TransitionID string            `json:"transition_id" mutate:"required" description:"Transition id returned by ISSUE_GET_TRANSITIONS"`
+	Fields       map[string]string `json:"fields" description:"Values of transition fields by field id"`
+}
 
-	qc := s.CommonQC()
+type issueSetPriority struct {
+	IssueID    string `json:"ref_id" mutate:"required" description:"Issue id"`
+	PriorityID string `json:"priority_ref_id" mutate:"required" description:"Priority id"`
+}
 
-	switch action {
-	case agent.IntegrationMutationRequestActionIssueAddComment:
-		var obj struct {
-			IssueRefID string `json:"ref_id"`
-			Body       string `json:"body"`
-		}
-		err := json.Unmarshal([]byte(data), &obj)
-		if err != nil {
-			rerr(err)
-			return
-		}
-		comment, err := commonapi.AddComment(qc, obj.IssueRefID, obj.Body)
-		if err != nil {
-			rerr(err)
-			return
-		}
-		return s.mutationResult(work.IssueCommentModelName, comment)
-	case agent.IntegrationMutationRequestActionIssueSetTitle:
-		var obj struct {
-			IssueID string `json:"ref_id"`
-			Title   string `json:"title"`
-		}
-		err := json.Unmarshal([]byte(data), &obj)
-		if err != nil {
-			rerr(err)
-			return
-		}
-		err = commonapi.EditTitle(qc, obj.IssueID, obj.Title)
-		if err != nil {
-			rerr(err)
-			return
-		}
-		return s.returnUpdatedIssue(obj.IssueID)
-	case agent.IntegrationMutationRequestActionIssueSetStatus:
-		var obj struct {
-			IssueID      string            `json:"ref_id"`
-			TransitionID string            `json:"transition_id"`
-			Fields       map[string]string `json:"fields"`
-		}
-		err := json.Unmarshal([]byte(data), &obj)
-		if err != nil {
-			rerr(err)
-			return
-		}
-		err = commonapi.EditStatus(qc, obj.IssueID, obj.TransitionID, obj.Fields)
-		if err != nil {
-			rerr(err)
-			return
-		}
-		return s.returnUpdatedIssue(obj.IssueID)
-	case agent.IntegrationMutationRequestActionIssueSetPriority:
-		var obj struct {
-			IssueID    string `json:"ref_id"`
-			PriorityID string `json:"priority_ref_id"`
-		}
-		err := json.Unmarshal([]byte(data), &obj)
-		if err != nil {
-			rerr(err)
-			return
-		}
-		err = commonapi.EditPriority(qc, obj.IssueID, obj.PriorityID)
-		if err != nil {
-			rerr(err)
-			return
-		}
-		return s.returnUpdatedIssue(obj.IssueID)
-	case agent.IntegrationMutationRequestActionIssueSetAssignee:
-		var obj struct {
-			IssueID string `json:"ref_id"`
-			UserID  string `json:"user_ref_id"`
-		}
-		err := json.Unmarshal([]byte(data), &obj)
-		if err != nil {
-			rerr(err)
-			return
-		}
-		err = commonapi.AssignUser(qc, obj.IssueID, obj.UserID)
-		if err != nil {
-			rerr(err)
-			return
-		}
-		return s.returnUpdatedIssue(obj.IssueID)
-	case agent.IntegrationMutationRequestActionIssueGetTransitions:
-		var obj struct {
-			IssueID string `json:"ref_id"`
-		}
-		err := json.Unmarshal([]byte(data), &obj)
-		if err != nil {
-			rerr(err)
-			return
-		}
-		transitions, err := commonapi.GetIssueTransitions(qc, obj.IssueID)
-		if err != nil {
-			rerr(err)
-			return
+type issueSetAssignee struct {
+	IssueID string `json:"ref_id" mutate:"required" description:"Issue id"`
+	UserID  string `json:"user_ref_id" description:"User id, empty to unassign"`
+}
+
+type issueGetTransitions struct {
+	IssueID string `json:"ref_id" mutate:"required" description:"Issue id"`
+}
+
+// ListMutations returns the result for mutate.FnListMutations, does not need integration to be initialized
+func ListMutations() rpcdef.MutateResult {
+	s := &JiraCommon{}
+	return s.mutations().ListResult()
+}
+
+func (s *JiraCommon) checkIssue(issueID string) error {
+	_, err := commonapi.IssueByIDFieldsForMutation(s.CommonQC(), issueID)
+	return err
+}
+
+// checkTransition checks that transition is available for issue. Required transition fields are not checked, since they could have default values.
+func (s *JiraCommon) checkTransition(obj *issueSetStatus) error {
+	transitions, err := commonapi.GetIssueTransitions(s.CommonQC(), obj.IssueID)
+	if err != nil {
+		return err
+	}
+	for _, tr := range transitions {
+		if tr.ID == obj.TransitionID {
+			return nil
 		}
-		res.WebappResponse = transitions
-		return
 	}
+	return mutate.NewValidationError("transition_id", "transition is not available for issue")
+}
 
-	rerr(fmt.Errorf("mutate fn not supported: %v", fn))
-	return
+func (s *JiraCommon) mutations() *mutate.Registry {
+	reg := mutate.NewRegistry()
+	reg.Add(mutate.Action{
+		Name:        agent.IntegrationMutationRequestActionIssueAddComment.String(),
+		Description: "Adds comment to issue",
+		Input:       &issueAddComment{},
+		Check: func(ctx context.Context, input interface{}) error {
+			return s.checkIssue(input.(*issueAddComment).IssueRefID)
+		},
+		Run: func(ctx context.Context, input interface{}) (res rpcdef.MutateResult, _ error) {
+			obj := input.(*issueAddComment)
+			comment, err := commonapi.AddComment(s.CommonQC(), obj.IssueRefID, obj.Body)
+			if err != nil {
+				return res, err
+			}
+			return s.mutationResult(work.IssueCommentModelName, comment)
+		},
+	})
+	reg.Add(mutate.Action{
+		Name:        agent.IntegrationMutationRequestActionIssueSetTitle.String(),
+		Description: "Changes issue summary",
+		Input:       &issueSetTitle{},
+		Check: func(ctx context.Context, input interface{}) error {
+			return s.checkIssue(input.(*issueSetTitle).IssueID)
+		},
+		Run: func(ctx context.Context, input interface{}) (res rpcdef.MutateResult, _ error) {
+			obj := input.(*issueSetTitle)
+			err := commonapi.EditTitle(s.CommonQC(), obj.IssueID, obj.Title)
+			if err != nil {
+				return res, err
+			}
+			return s.returnUpdatedIssue(obj.IssueID)
+		},
+	})
+	reg.Add(mutate.Action{
+		Name:        agent.IntegrationMutationRequestActionIssueSetStatus.String(),
+		Description: "Moves issue to another status using transition",
+		Input:       &issueSetStatus{},
+		Check: func(ctx context.Context, input interface{}) error {
+			return s.checkTransition(input.(*issueSetStatus))
+		},
+		// jira returns bad request without field errors for transitions not available for issue
+		CheckBeforeRun: true,
+		Run: func(ctx context.Context, input interface{}) (res rpcdef.MutateResult, _ error) {
+			obj := input.(*issueSetStatus)
+			err := commonapi.EditStatus(s.CommonQC(), obj.IssueID, obj.TransitionID, obj.Fields)
+			if err != nil {
+				return res, err
+			}
+			return s.returnUpdatedIssue(obj.IssueID)
+		},
+	})
+	reg.Add(mutate.Action{
+		Name:        agent.IntegrationMutationRequestActionIssueSetPriority.String(),
+		Description: "Changes issue priority",
+		Input:       &issueSetPriority{},
+		Check: func(ctx context.Context, input interface{}) error {
+			return s.checkIssue(input.(*issueSetPriority).IssueID)
+		},
+		Run: func(ctx context.Context, input interface{}) (res rpcdef.MutateResult, _ error) {
+			obj := input.(*issueSetPriority)
+			err := commonapi.EditPriority(s.CommonQC(), obj.IssueID, obj.PriorityID)
+			if err != nil {
+				return res, err
+			}
+			return s.returnUpdatedIssue(obj.IssueID)
+		},
+	})
+	reg.Add(mutate.Action{
+		Name:        agent.IntegrationMutationRequestActionIssueSetAssignee.String(),
+		Description: "Changes issue assignee",
+		Input:       &issueSetAssignee{},
+		Check: func(ctx context.Context, input interface{}) error {
+			return s.checkIssue(input.(*issueSetAssignee).IssueID)
+		},
+		Run: func(ctx context.Context, input interface{}) (res rpcdef.MutateResult, _ error) {
+			obj := input.(*issueSetAssignee)
+			err := commonapi.AssignUser(s.CommonQC(), obj.IssueID, obj.UserID)
+			if err != nil {
+				return res, err
+			}
+			return s.returnUpdatedIssue(obj.IssueID)
+		},
+	})
+	reg.Add(mutate.Action{
+		Name:        agent.IntegrationMutationRequestActionIssueGetTransitions.String(),
+		Description: "Returns transitions available for issue in webapp response",
+		Input:       &issueGetTransitions{},
+		Run: func(ctx context.Context, input interface{}) (res rpcdef.MutateResult, _ error) {
+			obj := input.(*issueGetTransitions)
+			transitions, err := commonapi.GetIssueTransitions(s.CommonQC(), obj.IssueID)
+			if err != nil {
+				return res, err
+			}
+			res.WebappResponse = transitions
+			return res, nil
+		},
+	})
+	return reg
+}
+
+func (s *JiraCommon) Mutate(ctx context.Context, fn, data string, config rpcdef.ExportConfig) (res rpcdef.MutateResult, _ error) {
+	return s.mutations().Mutate(ctx, fn, data), nil
 }
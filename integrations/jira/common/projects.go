@@ -3,6 +3,7 @@ package common
 import (
 	"github.com/pinpt/integration-sdk/work"
 
+	"github.com/pinpt/agent/integrations/jira/commonapi"
 	"github.com/pinpt/agent/integrations/pkg/objsender"
 	"github.com/pinpt/agent/integrations/pkg/repoprojects"
 )
@@ -21,21 +22,47 @@ func commonToProjects(common []repoprojects.RepoProject) (res []Project) {
 	return
 }
 
-func (s *JiraCommon) ProcessAllProjectsUsingExclusions(projectSender *objsender.Session, allProjectsDetailed []*work.Project) (notExcluded []Project, rerr error) {
+func (s *JiraCommon) filterConfig() repoprojects.FilterConfig {
+	return repoprojects.FilterConfig{
+		OnlyIncludeReadableIDs: s.opts.Projects,
+		ExcludedIDs:            s.opts.ExcludedProjects,
+		IncludedIDs:            s.opts.IncludedProjects,
+	}
+}
 
-	var allProjects []Project
+func projectsFromDetailed(allProjectsDetailed []*work.Project) (res []Project) {
 	for _, obj := range allProjectsDetailed {
 		p := Project{}
 		p.JiraID = obj.RefID
 		p.Key = obj.Identifier
-		allProjects = append(allProjects, p)
+		res = append(res, p)
 	}
+	return
+}
 
-	res := repoprojects.Filter(s.opts.Logger, projectsToCommon(allProjects), repoprojects.FilterConfig{
-		OnlyIncludeReadableIDs: s.opts.Projects,
-		ExcludedIDs:            s.opts.ExcludedProjects,
-		IncludedIDs:            s.opts.IncludedProjects,
+// issuesPageSize is the default maxResults for search api
+const issuesPageSize = 50
+
+// Plan returns export plan for projects using the same exclusions as export. discoveryCalls is the number of requests used to get projects.
+func (s *JiraCommon) Plan(allProjectsDetailed []*work.Project, discoveryCalls int) repoprojects.Plan {
+	qc := s.CommonQC()
+	return repoprojects.NewPlan(s.opts.Logger, projectsToCommon(projectsFromDetailed(allProjectsDetailed)), s.filterConfig(), discoveryCalls, func(p repoprojects.RepoProject) (res repoprojects.PlanProject, _ error) {
+		issues, err := commonapi.IssuesCount(qc, p.(Project).Project)
+		if err != nil {
+			return res, err
+		}
+		res.Counts = map[string]int{work.IssueModelName.String(): issues}
+		// issue pages and comments for each issue
+		res.APICalls = repoprojects.Pages(issues, issuesPageSize) + issues
+		return res, nil
 	})
+}
+
+func (s *JiraCommon) ProcessAllProjectsUsingExclusions(projectSender *objsender.Session, allProjectsDetailed []*work.Project) (notExcluded []Project, rerr error) {
+
+	allProjects := projectsFromDetailed(allProjectsDetailed)
+
+	res := repoprojects.Filter(s.opts.Logger, projectsToCommon(allProjects), s.filterConfig())
 
 	notExcluded = commonToProjects(res)
 
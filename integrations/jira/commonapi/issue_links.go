@@ -0,0 +1,131 @@
+package commonapi
+
+import (
+	"strconv"
+
+	"github.com/pinpt/go-common/v10/hash"
+)
+
+// IssueLink is a link between two issues. Unlike work.Issue LinkedIssues it includes custom link types.
+type IssueLink struct {
+	ID               string `json:"id"`
+	CustomerID       string `json:"customer_id"`
+	RefType          string `json:"ref_type"`
+	RefID            string `json:"ref_id"`
+	ProjectID        string `json:"project_id"`
+	IssueID          string `json:"issue_id"`
+	LinkedIssueID    string `json:"linked_issue_id"`
+	LinkedIssueRefID string `json:"linked_issue_ref_id"`
+	LinkedIssueKey   string `json:"linked_issue_key"`
+	// Type is the name of link type, for example Blocks or Duplicate
+	Type string `json:"type"`
+	// Description is the link type description in the link direction, for example blocks or is blocked by
+	Description string `json:"description"`
+	// ReverseDirection is true for inward links, where linked issue is the source of the link
+	ReverseDirection bool `json:"reverse_direction"`
+}
+
+func (s *IssueLink) ToMap() map[string]interface{} {
+	return toMap(s)
+}
+
+// IssueRemoteLink is a link from issue to external object, such as confluence page or pull request
+type IssueRemoteLink struct {
+	ID           string `json:"id"`
+	CustomerID   string `json:"customer_id"`
+	RefType      string `json:"ref_type"`
+	RefID        string `json:"ref_id"`
+	ProjectID    string `json:"project_id"`
+	IssueID      string `json:"issue_id"`
+	GlobalID     string `json:"global_id"`
+	Application  string `json:"application"`
+	Relationship string `json:"relationship"`
+	URL          string `json:"url"`
+	Title        string `json:"title"`
+	Summary      string `json:"summary"`
+}
+
+func (s *IssueRemoteLink) ToMap() map[string]interface{} {
+	return toMap(s)
+}
+
+func convertIssueLinks(qc QueryContext, projectRefID string, issueRefID string, fields issueFields) (res []*IssueLink) {
+	for _, link := range fields.IssueLinks {
+		item := &IssueLink{}
+		item.CustomerID = qc.CustomerID
+		item.RefType = refType
+		item.RefID = link.ID
+		item.ProjectID = qc.ProjectID(projectRefID)
+		item.IssueID = qc.IssueID(issueRefID)
+		item.Type = link.Type.Name
+		var linkedIssue linkedIssue
+		if link.OutwardIssue.ID != "" {
+			linkedIssue = link.OutwardIssue
+			item.Description = link.Type.Outward
+		} else if link.InwardIssue.ID != "" {
+			linkedIssue = link.InwardIssue
+			item.Description = link.Type.Inward
+			item.ReverseDirection = true
+		} else {
+			continue
+		}
+		// the same link is returned for both issues, include issue in id
+		item.ID = hash.Values("IssueLink", qc.CustomerID, refType, link.ID, issueRefID)
+		item.LinkedIssueRefID = linkedIssue.ID
+		item.LinkedIssueID = qc.IssueID(linkedIssue.ID)
+		item.LinkedIssueKey = linkedIssue.Key
+		res = append(res, item)
+	}
+	return
+}
+
+// IssueRemoteLinks returns all remote links of issue. Api does not support pagination.
+func IssueRemoteLinks(qc QueryContext, project Project, issueRefID string) (res []*IssueRemoteLink, rerr error) {
+
+	objectPath := "issue/" + issueRefID + "/remotelink"
+
+	qc.Logger.Debug("issue remote links request", "project", project.Key, "issue", issueRefID)
+
+	var rr []struct {
+		ID          int64  `json:"id"`
+		GlobalID    string `json:"globalId"`
+		Application struct {
+			Type string `json:"type"`
+			Name string `json:"name"`
+		} `json:"application"`
+		Relationship string `json:"relationship"`
+		Object       struct {
+			URL     string `json:"url"`
+			Title   string `json:"title"`
+			Summary string `json:"summary"`
+		} `json:"object"`
+	}
+
+	err := qc.Req.Get(objectPath, nil, &rr)
+	if err != nil {
+		rerr = err
+		return
+	}
+
+	for _, data := range rr {
+		item := &IssueRemoteLink{}
+		item.CustomerID = qc.CustomerID
+		item.RefType = refType
+		item.RefID = strconv.FormatInt(data.ID, 10)
+		item.ID = hash.Values("IssueRemoteLink", qc.CustomerID, refType, item.RefID)
+		item.ProjectID = qc.ProjectID(project.JiraID)
+		item.IssueID = qc.IssueID(issueRefID)
+		item.GlobalID = data.GlobalID
+		item.Application = data.Application.Name
+		if item.Application == "" {
+			item.Application = data.Application.Type
+		}
+		item.Relationship = data.Relationship
+		item.URL = data.Object.URL
+		item.Title = data.Object.Title
+		item.Summary = data.Object.Summary
+		res = append(res, item)
+	}
+
+	return
+}
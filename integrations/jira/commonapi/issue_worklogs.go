@@ -0,0 +1,203 @@
+package commonapi
+
+import (
+	"encoding/json"
+	"net/url"
+	"strings"
+	"time"
+
+	"github.com/pinpt/agent/pkg/date"
+	"github.com/pinpt/go-common/v10/hash"
+)
+
+// IssueWorklog is time logged on issue
+type IssueWorklog struct {
+	ID               string `json:"id"`
+	CustomerID       string `json:"customer_id"`
+	RefType          string `json:"ref_type"`
+	RefID            string `json:"ref_id"`
+	ProjectID        string `json:"project_id"`
+	IssueID          string `json:"issue_id"`
+	UserRefID        string `json:"user_ref_id"`
+	Comment          string `json:"comment"`
+	TimeSpentSeconds int64  `json:"time_spent_seconds"`
+	StartedDate      Date   `json:"started_date"`
+	CreatedDate      Date   `json:"created_date"`
+	UpdatedDate      Date   `json:"updated_date"`
+}
+
+func (s *IssueWorklog) ToMap() map[string]interface{} {
+	return toMap(s)
+}
+
+// IssueTimeTracking contains original estimate, remaining estimate and total time spent on issue
+type IssueTimeTracking struct {
+	ID                       string `json:"id"`
+	CustomerID               string `json:"customer_id"`
+	RefType                  string `json:"ref_type"`
+	RefID                    string `json:"ref_id"`
+	ProjectID                string `json:"project_id"`
+	IssueID                  string `json:"issue_id"`
+	OriginalEstimateSeconds  int64  `json:"original_estimate_seconds"`
+	RemainingEstimateSeconds int64  `json:"remaining_estimate_seconds"`
+	TimeSpentSeconds         int64  `json:"time_spent_seconds"`
+}
+
+func (s *IssueTimeTracking) ToMap() map[string]interface{} {
+	return toMap(s)
+}
+
+type timeTrackingResponse struct {
+	OriginalEstimateSeconds  int64 `json:"originalEstimateSeconds"`
+	RemainingEstimateSeconds int64 `json:"remainingEstimateSeconds"`
+	TimeSpentSeconds         int64 `json:"timeSpentSeconds"`
+}
+
+func (s timeTrackingResponse) IsZero() bool {
+	return s == timeTrackingResponse{}
+}
+
+type worklogResponse struct {
+	ID     string `json:"id"`
+	Author struct {
+		Key       string `json:"key"`       // hosted,cloud
+		AccountID string `json:"accountId"` // cloud only
+	} `json:"author"`
+	// Comment is a string in hosted jira (api v2) and a document in jira cloud (api v3)
+	Comment          json.RawMessage `json:"comment"`
+	Started          string          `json:"started"`
+	Created          string          `json:"created"`
+	Updated          string          `json:"updated"`
+	TimeSpentSeconds int64           `json:"timeSpentSeconds"`
+}
+
+// IssueWorklogs returns a page of worklogs for issue. Search api only returns the first 20 worklogs for each issue, use this when there are more.
+func IssueWorklogs(
+	qc QueryContext,
+	project Project,
+	issueRefID string,
+	paginationParams url.Values) (pi PageInfo, res []*IssueWorklog, rerr error) {
+
+	objectPath := "issue/" + issueRefID + "/worklog"
+	params := paginationParams
+
+	qc.Logger.Debug("issue worklogs request", "project", project.Key, "issue", issueRefID, "params", params)
+
+	var rr struct {
+		Total      int               `json:"total"`
+		MaxResults int               `json:"maxResults"`
+		Worklogs   []worklogResponse `json:"worklogs"`
+	}
+
+	err := qc.Req.Get(objectPath, params, &rr)
+	if err != nil {
+		rerr = err
+		return
+	}
+
+	pi.Total = rr.Total
+	pi.MaxResults = rr.MaxResults
+	if len(rr.Worklogs) == rr.MaxResults {
+		pi.HasMore = true
+	}
+
+	for _, data := range rr.Worklogs {
+		item, err := convertWorklog(qc, project.JiraID, issueRefID, data)
+		if err != nil {
+			rerr = err
+			return
+		}
+		res = append(res, item)
+	}
+
+	return
+}
+
+func convertWorklog(qc QueryContext, projectRefID string, issueRefID string, data worklogResponse) (_ *IssueWorklog, rerr error) {
+	item := &IssueWorklog{}
+	item.CustomerID = qc.CustomerID
+	item.RefType = refType
+	item.RefID = data.ID
+	item.ID = hash.Values("IssueWorklog", qc.CustomerID, refType, data.ID)
+	item.ProjectID = qc.ProjectID(projectRefID)
+	item.IssueID = qc.IssueID(issueRefID)
+
+	item.UserRefID = data.Author.AccountID // cloud jira
+	if item.UserRefID == "" {
+		item.UserRefID = data.Author.Key // hosted jira
+	}
+	item.Comment = worklogComment(data.Comment)
+	item.TimeSpentSeconds = data.TimeSpentSeconds
+
+	for _, d := range []struct {
+		v   string
+		res *Date
+	}{
+		{data.Started, &item.StartedDate},
+		{data.Created, &item.CreatedDate},
+		{data.Updated, &item.UpdatedDate},
+	} {
+		t, err := ParseTime(d.v)
+		if err != nil {
+			rerr = err
+			return
+		}
+		date.ConvertToModel(t, d.res)
+	}
+	return item, nil
+}
+
+func convertTimeTracking(qc QueryContext, projectRefID string, issueRefID string, data timeTrackingResponse) *IssueTimeTracking {
+	item := &IssueTimeTracking{}
+	item.CustomerID = qc.CustomerID
+	item.RefType = refType
+	item.RefID = issueRefID
+	item.ID = hash.Values("IssueTimeTracking", qc.CustomerID, refType, issueRefID)
+	item.ProjectID = qc.ProjectID(projectRefID)
+	item.IssueID = qc.IssueID(issueRefID)
+	item.OriginalEstimateSeconds = data.OriginalEstimateSeconds
+	item.RemainingEstimateSeconds = data.RemainingEstimateSeconds
+	item.TimeSpentSeconds = data.TimeSpentSeconds
+	return item
+}
+
+// worklogComment returns comment as plain text. Jira cloud returns comments in atlassian document format, in that case text nodes are joined.
+func worklogComment(data json.RawMessage) string {
+	if len(data) == 0 {
+		return ""
+	}
+	var str string
+	if err := json.Unmarshal(data, &str); err == nil {
+		return str
+	}
+	type node struct {
+		Type    string `json:"type"`
+		Text    string `json:"text"`
+		Content []node `json:"content"`
+	}
+	var doc node
+	if err := json.Unmarshal(data, &doc); err != nil {
+		return ""
+	}
+	var lines []string
+	var text func(n node) string
+	text = func(n node) string {
+		res := n.Text
+		for _, c := range n.Content {
+			res += text(c)
+		}
+		return res
+	}
+	for _, n := range doc.Content {
+		lines = append(lines, text(n))
+	}
+	return strings.Join(lines, "\n")
+}
+
+// UpdatedBefore returns true if worklog was last updated before t
+func (s *IssueWorklog) UpdatedBefore(t time.Time) bool {
+	if t.IsZero() {
+		return false
+	}
+	return s.UpdatedDate.Epoch < t.UnixNano()/int64(time.Millisecond)
+}
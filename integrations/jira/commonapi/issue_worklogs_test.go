@@ -0,0 +1,25 @@
+package commonapi
+
+import (
+	"encoding/json"
+	"testing"
+)
+
+func TestWorklogComment(t *testing.T) {
+	cases := []struct {
+		Label string
+		In    string
+		Want  string
+	}{
+		{"empty", ``, ""},
+		{"null", `null`, ""},
+		{"hosted", `"fixed tests"`, "fixed tests"},
+		{"cloud", `{"type":"doc","version":1,"content":[{"type":"paragraph","content":[{"type":"text","text":"fixed "},{"type":"text","text":"tests"}]},{"type":"paragraph","content":[{"type":"text","text":"and docs"}]}]}`, "fixed tests\nand docs"},
+	}
+	for _, c := range cases {
+		got := worklogComment(json.RawMessage(c.In))
+		if got != c.Want {
+			t.Errorf("case %v wanted %q got %q", c.Label, c.Want, got)
+		}
+	}
+}
@@ -29,6 +29,13 @@ type CustomFieldValue struct {
 type IssueWithCustomFields struct {
 	*work.Issue
 	CustomFields []CustomFieldValue
+
+	// Worklogs contains up to 20 worklogs returned in search, if WorklogsTotal is larger use IssueWorklogs to get all
+	Worklogs      []*IssueWorklog
+	WorklogsTotal int
+	// TimeTracking is nil if no estimate or time spent is set on issue
+	TimeTracking *IssueTimeTracking
+	Links        []*IssueLink
 }
 
 func relativeDuration(d time.Duration) string {
@@ -114,7 +121,9 @@ type issueFields struct {
 		ID   string `json:"id"`
 		Type struct {
 			//ID   string `json:"id"`
-			Name string `json:"name"` // Using Name instead of ID for mapping
+			Name    string `json:"name"` // Using Name instead of ID for mapping
+			Inward  string `json:"inward"`
+			Outward string `json:"outward"`
 		} `json:"type"`
 		OutwardIssue linkedIssue `json:"outwardIssue"`
 		InwardIssue  linkedIssue `json:"inwardIssue"`
@@ -132,6 +141,11 @@ type issueFields struct {
 		Content   string `json:"content"`
 		Thumbnail string `json:"thumbnail"`
 	} `json:"attachment"`
+	Worklog struct {
+		Total    int               `json:"total"`
+		Worklogs []worklogResponse `json:"worklogs"`
+	} `json:"worklog"`
+	TimeTracking timeTrackingResponse `json:"timetracking"`
 }
 
 // IssuesAndChangelogsPage returns issues and related changelogs. Calls qc.ExportUser for each user. Current difference from jira-cloud version is that user.Key is used instead of user.AccountID everywhere.
@@ -166,7 +180,7 @@ func IssuesAndChangelogsPage(
 	params.Set("jql", jql)
 	// we need both fields and renderedFields so that we can get the unprocessed (fields) and processed (html for renderedFields)
 	params.Add("expand", "changelog,fields,renderedFields")
-	params.Add("fields", "*navigable,attachment")
+	params.Add("fields", "*navigable,attachment,worklog,timetracking")
 
 	qc.Logger.Info("issues request", "project", project.Key, "params", params)
 
@@ -202,6 +216,26 @@ func IssuesAndChangelogsPage(
 
 // BUG: returned data will have missing start and end date, because we don't pass fieldsByID here
 // Will also be missing story points and epic link
+// IssuesCount returns the total number of issues in project, used for export plan
+func IssuesCount(qc QueryContext, project Project) (_ int, rerr error) {
+	params := url.Values{}
+	params.Set("jql", `project="`+project.JiraID+`"`)
+	params.Set("maxResults", "0")
+	params.Set("fields", "id")
+
+	qc.Logger.Debug("issues count request", "project", project.Key)
+
+	var rr struct {
+		Total int `json:"total"`
+	}
+	err := qc.Req.Get("search", params, &rr)
+	if err != nil {
+		rerr = err
+		return
+	}
+	return rr.Total, nil
+}
+
 func IssueByIDFieldsForMutation(qc QueryContext, issueIDOrKey string) (_ IssueWithCustomFields, rerr error) {
 	// https://developer.atlassian.com/cloud/jira/platform/rest/v3/#api-rest-api-3-issue-issueIdOrKey-get
 
@@ -466,6 +500,21 @@ func convertIssue(qc QueryContext, data issueSource, fieldByID map[string]Custom
 		item.LinkedIssues = append(item.LinkedIssues, link2)
 	}
 
+	item.Links = convertIssueLinks(qc, project.JiraID, data.ID, fields)
+
+	item.WorklogsTotal = fields.Worklog.Total
+	for _, wl := range fields.Worklog.Worklogs {
+		worklog, err := convertWorklog(qc, project.JiraID, data.ID, wl)
+		if err != nil {
+			rerr = err
+			return
+		}
+		item.Worklogs = append(item.Worklogs, worklog)
+	}
+	if !fields.TimeTracking.IsZero() {
+		item.TimeTracking = convertTimeTracking(qc, project.JiraID, data.ID, fields.TimeTracking)
+	}
+
 	for _, data := range fields.Attachment {
 		var attachment work.IssueAttachments
 		attachment.RefID = data.ID
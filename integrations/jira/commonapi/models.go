@@ -0,0 +1,30 @@
+package commonapi
+
+import (
+	"github.com/pinpt/agent/pkg/structmarshal"
+	"github.com/pinpt/go-common/v10/datamodel"
+)
+
+// Models exported in addition to integration-sdk work models. These are not available in integration-sdk yet.
+const (
+	IssueWorklogModelName      datamodel.ModelNameType = "work.IssueWorklog"
+	IssueTimeTrackingModelName datamodel.ModelNameType = "work.IssueTimeTracking"
+	IssueLinkModelName         datamodel.ModelNameType = "work.IssueLink"
+	IssueRemoteLinkModelName   datamodel.ModelNameType = "work.IssueRemoteLink"
+)
+
+// Date has the same fields as dates in integration-sdk models, set it using date.ConvertToModel
+type Date struct {
+	Epoch   int64  `json:"epoch"`
+	Offset  int64  `json:"offset"`
+	Rfc3339 string `json:"rfc3339"`
+}
+
+func toMap(obj interface{}) map[string]interface{} {
+	res, err := structmarshal.StructToMap(obj)
+	if err != nil {
+		// only contains strings, numbers and nested structs
+		panic(err)
+	}
+	return res
+}
@@ -2,22 +2,24 @@ package main
 
 import (
 	"strconv"
-	"time"
 
+	"github.com/pinpt/agent/integrations/pkg/objsender"
 	"github.com/pinpt/agent/rpcdef"
 	"github.com/pinpt/integration-sdk/sourcecode"
 )
 
-func (s *Integration) exportRepoObjects() (repos []rpcdef.ExportProject) {
+func (s *Integration) exportRepoObjects() (repos []rpcdef.ExportProject, _ error) {
 
-	sessionID, lastProcessed := s.agent.ExportStarted(sourcecode.RepoModelName.String())
-	defer s.agent.ExportDone(sessionID, time.Now().Format(time.RFC3339))
+	sender, err := objsender.Root(s.agent, sourcecode.RepoModelName.String())
+	if err != nil {
+		return nil, err
+	}
 
-	s.logger.Info("exporting repos", "lastProcessed", lastProcessed)
+	s.logger.Info("exporting repos", "lastProcessed", sender.LastProcessed())
 	c := 0
 
+	var rows []sourcecode.Repo
 	for i := 0; i < 2; i++ {
-		rows := []map[string]interface{}{}
 		for j := 0; j < 2; j++ {
 			c++
 			n := strconv.Itoa(c)
@@ -25,7 +27,7 @@ func (s *Integration) exportRepoObjects() (repos []rpcdef.ExportProject) {
 			row.RefType = "mock"
 			row.RefID = "r" + n
 			row.Name = "Repo: " + n
-			rows = append(rows, row.ToMap())
+			rows = append(rows, row)
 
 			repo := rpcdef.ExportProject{}
 			repo.ID = row.GetID()
@@ -34,17 +36,45 @@ func (s *Integration) exportRepoObjects() (repos []rpcdef.ExportProject) {
 			repo.Error = ""
 			repos = append(repos, repo)
 		}
-		var objs []rpcdef.ExportObj
-		for _, row := range rows {
-			obj := rpcdef.ExportObj{}
-			obj.Data = row
-			objs = append(objs, obj)
+	}
+
+	for _, row := range rows {
+		err := sender.Send(&row)
+		if err != nil {
+			return nil, err
 		}
-		s.agent.SendExported(
-			sessionID,
-			objs)
+	}
 
+	for _, row := range rows {
+		err := s.exportPullRequests(sender, row)
+		if err != nil {
+			return nil, err
+		}
 	}
 
-	return
+	return repos, sender.Done()
+}
+
+func (s *Integration) exportPullRequests(repoSender *objsender.Session, repo sourcecode.Repo) error {
+	sender, err := repoSender.Session(sourcecode.PullRequestModelName.String(), repo.RefID, repo.Name)
+	if err != nil {
+		return err
+	}
+	// mock data does not change, so if pull requests were exported before, including in interrupted export, there is nothing to send
+	if sender.LastProcessed() != "" {
+		s.logger.Info("skipping pull requests, already exported", "repo", repo.RefID, "lastProcessed", sender.LastProcessed())
+		return sender.DoneLastProcessed(sender.LastProcessed())
+	}
+	for i := 1; i <= 2; i++ {
+		pr := sourcecode.PullRequest{}
+		pr.RefType = "mock"
+		pr.RefID = repo.RefID + "-pr" + strconv.Itoa(i)
+		pr.RepoID = repo.GetID()
+		pr.Title = "PR: " + strconv.Itoa(i)
+		err := sender.Send(&pr)
+		if err != nil {
+			return err
+		}
+	}
+	return sender.Done()
 }
@@ -0,0 +1,109 @@
+package main
+
+import (
+	"context"
+	"strconv"
+	"testing"
+	"time"
+
+	"github.com/hashicorp/go-hclog"
+	"github.com/pinpt/agent/pkg/metrics"
+	"github.com/pinpt/agent/rpcdef"
+	"github.com/pinpt/integration-sdk/sourcecode"
+	"github.com/stretchr/testify/assert"
+)
+
+// testAgent implements rpcdef.Agent, returning lastProcessed for child sessions of the objects in it, same as export restored from checkpoints
+type testAgent struct {
+	lastID        int
+	sessions      map[int]string
+	lastProcessed map[string]interface{}
+	objs          map[string][]map[string]interface{}
+	done          map[string]int
+}
+
+func newTestAgent() *testAgent {
+	s := &testAgent{}
+	s.sessions = map[int]string{}
+	s.lastProcessed = map[string]interface{}{}
+	s.objs = map[string][]map[string]interface{}{}
+	s.done = map[string]int{}
+	return s
+}
+
+func (s *testAgent) ExportStarted(modelType string) (sessionID string, lastProcessed interface{}) {
+	panic("not used")
+}
+
+func (s *testAgent) ExportDone(sessionID string, lastProcessed interface{}) {
+	id, _ := strconv.Atoi(sessionID)
+	s.done[s.sessions[id]]++
+}
+
+func (s *testAgent) SendExported(sessionID string, objs []rpcdef.ExportObj) {
+	id, _ := strconv.Atoi(sessionID)
+	name := s.sessions[id]
+	for _, obj := range objs {
+		s.objs[name] = append(s.objs[name], obj.Data.(map[string]interface{}))
+	}
+}
+
+func (s *testAgent) SessionStart(isTracking bool, name string, parentSessionID int, parentObjectID, parentObjectName string) (sessionID int, lastProcessed interface{}, _ error) {
+	s.lastID++
+	s.sessions[s.lastID] = name
+	if parentSessionID == 0 {
+		return s.lastID, nil, nil
+	}
+	return s.lastID, s.lastProcessed[parentObjectID], nil
+}
+
+func (s *testAgent) SessionProgress(id int, current, total int) error { return nil }
+
+func (s *testAgent) SessionRollback(id int) error { return nil }
+
+func (s *testAgent) ExportGitRepo(fetch rpcdef.GitRepoFetch) error { return nil }
+
+func (s *testAgent) OAuthNewAccessToken() (token string, _ error) { return "", nil }
+
+func (s *testAgent) OAuthNewAccessTokenFromRefreshToken(name string, refresh string) (token string, _ error) {
+	return "", nil
+}
+
+func (s *testAgent) SendPauseEvent(msg string, resumeDate time.Time) error { return nil }
+
+func (s *testAgent) SendResumeEvent(msg string) error { return nil }
+
+func (s *testAgent) GetWebhookURL() (url string, _ error) { return "", nil }
+
+func (s *testAgent) SendMetrics(data metrics.Snapshot) error { return nil }
+
+func (s *testAgent) refIDs(modelName string) (res []string) {
+	for _, obj := range s.objs[modelName] {
+		res = append(res, obj["ref_id"].(string))
+	}
+	return
+}
+
+func TestExportSkipsCompletedPullRequests(t *testing.T) {
+	agent := newTestAgent()
+	// pull requests of the first 2 repos were completed in interrupted export
+	agent.lastProcessed["r1"] = "2020-01-01T00:00:00Z"
+	agent.lastProcessed["r2"] = "2020-01-01T00:00:00Z"
+
+	integration := NewIntegration(hclog.NewNullLogger())
+	err := integration.Init(agent)
+	if err != nil {
+		t.Fatal(err)
+	}
+	res, err := integration.Export(context.Background(), rpcdef.ExportConfig{})
+	if err != nil {
+		t.Fatal(err)
+	}
+	assert := assert.New(t)
+	assert.Len(res.Projects, 4)
+	assert.Equal([]string{"r1", "r2", "r3", "r4"}, agent.refIDs(sourcecode.RepoModelName.String()))
+	assert.Equal([]string{"r3-pr1", "r3-pr2", "r4-pr1", "r4-pr2"}, agent.refIDs(sourcecode.PullRequestModelName.String()))
+	// skipped sessions are still completed
+	assert.Equal(4, agent.done[sourcecode.PullRequestModelName.String()])
+	assert.Equal(1, agent.done[sourcecode.RepoModelName.String()])
+}
@@ -39,7 +39,10 @@ func (s *Integration) Export(ctx context.Context, config rpcdef.ExportConfig) (r
 		return res, err
 	}
 
-	res.Projects = s.exportRepoObjects()
+	res.Projects, err = s.exportRepoObjects()
+	if err != nil {
+		return res, err
+	}
 	return res, nil
 }
 
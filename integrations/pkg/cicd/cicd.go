@@ -0,0 +1,139 @@
+// Package cicd contains build and deployment objects exported by sourcecode integrations from their CI/CD apis.
+package cicd
+
+import (
+	"time"
+
+	"github.com/pinpt/agent/pkg/structmarshal"
+	"github.com/pinpt/go-common/v10/datamodel"
+	"github.com/pinpt/go-common/v10/hash"
+)
+
+const (
+	BuildModelName      datamodel.ModelNameType = "cicd.Build"
+	DeploymentModelName datamodel.ModelNameType = "cicd.Deployment"
+)
+
+// Status is the status of build, job or deployment, normalized across integrations
+type Status string
+
+const (
+	StatusPending   Status = "PENDING"
+	StatusRunning   Status = "RUNNING"
+	StatusSuccess   Status = "SUCCESS"
+	StatusFailure   Status = "FAILURE"
+	StatusCancelled Status = "CANCELLED"
+	StatusSkipped   Status = "SKIPPED"
+)
+
+// Finished returns true if build, job or deployment with this status will not change anymore
+func (s Status) Finished() bool {
+	switch s {
+	case StatusSuccess, StatusFailure, StatusCancelled, StatusSkipped:
+		return true
+	}
+	return false
+}
+
+// Date has the same fields as dates in integration-sdk models, set it using date.ConvertToModel
+type Date struct {
+	Epoch   int64  `json:"epoch"`
+	Offset  int64  `json:"offset"`
+	Rfc3339 string `json:"rfc3339"`
+}
+
+// Build is a workflow run or pipeline
+type Build struct {
+	ID         string `json:"id"`
+	CustomerID string `json:"customer_id"`
+	RefType    string `json:"ref_type"`
+	RefID      string `json:"ref_id"`
+	RepoID     string `json:"repo_id"`
+	Name       string `json:"name"`
+	URL        string `json:"url"`
+	Status     Status `json:"status"`
+	// Trigger is the event that started the build, for example push or pull_request
+	Trigger       string `json:"trigger"`
+	CommitSha     string `json:"commit_sha"`
+	CommitID      string `json:"commit_id"`
+	Branch        string `json:"branch"`
+	CreatedDate   Date   `json:"created_date"`
+	StartedDate   Date   `json:"started_date"`
+	CompletedDate Date   `json:"completed_date"`
+	// Duration is the time from start to completion in milliseconds, 0 if build is not finished
+	Duration int64 `json:"duration"`
+	Jobs     []Job `json:"jobs"`
+}
+
+// Job is a job or stage of a build
+type Job struct {
+	RefID         string `json:"ref_id"`
+	Name          string `json:"name"`
+	Status        Status `json:"status"`
+	StartedDate   Date   `json:"started_date"`
+	CompletedDate Date   `json:"completed_date"`
+	Duration      int64  `json:"duration"`
+}
+
+// Deployment is a deployment of a commit to an environment
+type Deployment struct {
+	ID            string `json:"id"`
+	CustomerID    string `json:"customer_id"`
+	RefType       string `json:"ref_type"`
+	RefID         string `json:"ref_id"`
+	RepoID        string `json:"repo_id"`
+	Environment   string `json:"environment"`
+	URL           string `json:"url"`
+	Status        Status `json:"status"`
+	CommitSha     string `json:"commit_sha"`
+	CommitID      string `json:"commit_id"`
+	Branch        string `json:"branch"`
+	CreatorRefID  string `json:"creator_ref_id"`
+	CreatedDate   Date   `json:"created_date"`
+	CompletedDate Date   `json:"completed_date"`
+	Duration      int64  `json:"duration"`
+}
+
+func (s *Build) ToMap() map[string]interface{} {
+	return toMap(s)
+}
+
+func (s *Deployment) ToMap() map[string]interface{} {
+	return toMap(s)
+}
+
+func toMap(obj interface{}) map[string]interface{} {
+	res, err := structmarshal.StructToMap(obj)
+	if err != nil {
+		// only contains strings, numbers and nested structs
+		panic(err)
+	}
+	return res
+}
+
+func NewBuildID(customerID, refType, repoID, refID string) string {
+	return hash.Values("Build", customerID, refType, repoID, refID)
+}
+
+func NewDeploymentID(customerID, refType, repoID, refID string) string {
+	return hash.Values("Deployment", customerID, refType, repoID, refID)
+}
+
+// Duration returns the time between started and completed in milliseconds, or 0 if not completed
+func Duration(started, completed time.Time) int64 {
+	if started.IsZero() || completed.IsZero() || completed.Before(started) {
+		return 0
+	}
+	return int64(completed.Sub(started) / time.Millisecond)
+}
+
+// RunningLookback is how far before last export builds and deployments are requested again in incremental export. Apis list them by creation date, this allows updating the ones that were still running during last export.
+const RunningLookback = 24 * time.Hour
+
+// Since returns the creation date from which to request builds and deployments. Returns zero time for historical export.
+func Since(lastProcessed time.Time) time.Time {
+	if lastProcessed.IsZero() {
+		return lastProcessed
+	}
+	return lastProcessed.Add(-RunningLookback)
+}
@@ -0,0 +1,33 @@
+package cicd
+
+import (
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestDuration(t *testing.T) {
+	assert := assert.New(t)
+	t1 := time.Date(2020, 1, 1, 0, 0, 0, 0, time.UTC)
+	t2 := t1.Add(90 * time.Second)
+	assert.Equal(int64(90000), Duration(t1, t2))
+	assert.Equal(int64(0), Duration(t1, time.Time{}))
+	assert.Equal(int64(0), Duration(time.Time{}, t2))
+	assert.Equal(int64(0), Duration(t2, t1))
+}
+
+func TestSince(t *testing.T) {
+	assert := assert.New(t)
+	assert.True(Since(time.Time{}).IsZero())
+	t1 := time.Date(2020, 1, 2, 0, 0, 0, 0, time.UTC)
+	assert.Equal(time.Date(2020, 1, 1, 0, 0, 0, 0, time.UTC), Since(t1))
+}
+
+func TestStatusFinished(t *testing.T) {
+	assert := assert.New(t)
+	assert.False(StatusPending.Finished())
+	assert.False(StatusRunning.Finished())
+	assert.True(StatusSuccess.Finished())
+	assert.True(StatusCancelled.Finished())
+}
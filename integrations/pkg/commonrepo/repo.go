@@ -64,11 +64,26 @@ type FilterConfig struct {
 }
 
 func Filter(logger hclog.Logger, repos []Repo, config FilterConfig) []Repo {
-	res := repoprojects.Filter(logger, reposToCommon(repos), repoprojects.FilterConfig{
+	res := repoprojects.Filter(logger, reposToCommon(repos), config.common())
+	return commonToRepos(res)
+}
+
+func (config FilterConfig) common() repoprojects.FilterConfig {
+	return repoprojects.FilterConfig{
 		OnlyIncludeReadableIDs: config.OnlyIncludeNames,
 		ExcludedIDs:            config.Exclusions,
 		IncludedIDs:            config.Inclusions,
 		StopAfterN:             config.StopAfterN,
-	})
-	return commonToRepos(res)
+	}
+}
+
+// Plan returns export plan for repos, see repoprojects.NewPlan. estimate could be nil.
+func Plan(logger hclog.Logger, repos []Repo, config FilterConfig, discoveryCalls int, estimate func(repo Repo) (repoprojects.PlanProject, error)) repoprojects.Plan {
+	var est repoprojects.PlanEstimate
+	if estimate != nil {
+		est = func(p repoprojects.RepoProject) (repoprojects.PlanProject, error) {
+			return estimate(p.(Repo))
+		}
+	}
+	return repoprojects.NewPlan(logger, reposToCommon(repos), config.common(), discoveryCalls, est)
 }
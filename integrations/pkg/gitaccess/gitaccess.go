@@ -0,0 +1,80 @@
+// Package gitaccess selects how integrations pass git credentials to agent for repo clone.
+package gitaccess
+
+import (
+	"errors"
+	"net/url"
+	"strings"
+
+	"github.com/pinpt/agent/pkg/gitclone"
+	"github.com/pinpt/agent/rpcdef"
+)
+
+// Config is the git access config shared by all source code integrations. Embed it in integration config struct.
+type Config struct {
+	// GitAccess is the access mode: url (default), askpass, header or ssh. See gitclone.AccessMode.
+	GitAccess string `json:"git_access"`
+	// GitSSHKey is the private deploy key used in ssh mode
+	GitSSHKey string `json:"git_ssh_key"`
+	// GitSSHKnownHosts are known_hosts lines with host keys of git server, required in ssh mode
+	GitSSHKnownHosts string `json:"git_ssh_known_hosts"`
+	// GitSSHURLPrefix is the prefix of ssh repo urls in ssh mode. Defaults to ssh://git@<repo host>/
+	// Example: ssh://git@bitbucket.example.com:7999/
+	GitSSHURLPrefix string `json:"git_ssh_url_prefix"`
+}
+
+// Validate checks that mode is valid and ssh key is set for ssh mode
+func (s Config) Validate() error {
+	mode, err := gitclone.ParseAccessMode(s.GitAccess)
+	if err != nil {
+		return err
+	}
+	if mode != gitclone.AccessSSH {
+		return nil
+	}
+	if s.GitSSHKey == "" {
+		return errors.New("git_ssh_key is required for ssh git_access")
+	}
+	if strings.TrimSpace(s.GitSSHKnownHosts) == "" {
+		return errors.New("git_ssh_known_hosts is required for ssh git_access")
+	}
+	return nil
+}
+
+// Apply sets repo url and credentials in fetch based on access mode.
+// repoURL is the https clone url with credentials, sshPath is the repo path relative to ssh url prefix, used in ssh mode.
+func (s Config) Apply(fetch *rpcdef.GitRepoFetch, repoURL string, sshPath string) error {
+	mode, err := gitclone.ParseAccessMode(s.GitAccess)
+	if err != nil {
+		return err
+	}
+	fetch.AccessMode = string(mode)
+	switch mode {
+	case gitclone.AccessURL:
+		fetch.URL = repoURL
+	case gitclone.AccessAskpass, gitclone.AccessHeader:
+		u, err := url.Parse(repoURL)
+		if err != nil {
+			return err
+		}
+		if u.User != nil {
+			fetch.Username = u.User.Username()
+			fetch.Password, _ = u.User.Password()
+			u.User = nil
+		}
+		fetch.URL = u.String()
+	case gitclone.AccessSSH:
+		prefix := s.GitSSHURLPrefix
+		if prefix == "" {
+			u, err := url.Parse(repoURL)
+			if err != nil {
+				return err
+			}
+			prefix = "ssh://git@" + u.Hostname() + "/"
+		}
+		fetch.URL = strings.TrimSuffix(prefix, "/") + "/" + strings.TrimPrefix(sshPath, "/")
+		fetch.SSHKey = s.GitSSHKey
+		fetch.SSHKnownHosts = s.GitSSHKnownHosts
+	}
+	return nil
+}
@@ -1,6 +1,7 @@
 package mutate
 
 import (
+	"fmt"
 	"net/http"
 
 	"github.com/pinpt/agent/pkg/requests"
@@ -26,13 +27,93 @@ type AllowedValue struct {
 	Name string `json:"name"`
 }
 
-const ErrNotFound = "not_found"
+// Error codes returned in MutateResult.ErrorCode
+const (
+	ErrNotFound         = "not_found"
+	ErrPermissionDenied = "permission_denied"
+	ErrConflict         = "conflict"
+	ErrValidation       = "validation"
+	ErrNotSupported     = "not_supported"
+)
+
+// CodeError is an error with MutateResult error code
+type CodeError struct {
+	Code string
+	Err  error
+}
+
+// NewError returns error with the provided code
+func NewError(code string, err error) error {
+	return &CodeError{Code: code, Err: err}
+}
+
+func (s *CodeError) Error() string {
+	return s.Err.Error()
+}
 
+func (s *CodeError) Unwrap() error {
+	return s.Err
+}
+
+// ResultFromError converts error into MutateResult, setting error code based on error type or http status code
 func ResultFromError(err error) (res rpcdef.MutateResult) {
+	res.Error = err.Error()
+	var ve *ValidationError
+	if errors.As(err, &ve) {
+		res.ErrorCode = ErrValidation
+		res.WebappResponse = ve
+		return
+	}
+	var ce *CodeError
+	if errors.As(err, &ce) {
+		res.ErrorCode = ce.Code
+		return
+	}
 	var e requests.StatusCodeError
-	if errors.As(err, &e) && e.Got == http.StatusNotFound {
-		res.ErrorCode = ErrNotFound
+	if errors.As(err, &e) {
+		res.ErrorCode = codeFromStatus(e.Got)
+	}
+	return res
+}
+
+func codeFromStatus(status int) string {
+	switch status {
+	case http.StatusNotFound:
+		return ErrNotFound
+	case http.StatusUnauthorized, http.StatusForbidden:
+		return ErrPermissionDenied
+	case http.StatusConflict, http.StatusPreconditionFailed:
+		return ErrConflict
+	case http.StatusBadRequest, http.StatusUnprocessableEntity:
+		return ErrValidation
+	}
+	return ""
+}
+
+// FieldError is a validation error of one input field
+type FieldError struct {
+	// Field is the path of the field, for example fields.summary or items[0]
+	Field string `json:"field"`
+	Error string `json:"error"`
+}
+
+// ValidationError is returned when mutation input is not valid. Sent to webapp in MutateResult.WebappResponse.
+type ValidationError struct {
+	Fields []FieldError `json:"fields"`
+}
+
+// NewValidationError returns validation error for one field
+func NewValidationError(field string, msg string) error {
+	return &ValidationError{Fields: []FieldError{{Field: field, Error: msg}}}
+}
+
+func (s *ValidationError) Error() string {
+	res := "invalid mutation input:"
+	for i, f := range s.Fields {
+		if i != 0 {
+			res += ","
+		}
+		res += fmt.Sprintf(" %v: %v", f.Field, f.Error)
 	}
-	res.Error = err.Error()
 	return res
 }
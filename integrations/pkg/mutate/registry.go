@@ -0,0 +1,169 @@
+package mutate
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"reflect"
+	"sort"
+
+	"github.com/pinpt/agent/rpcdef"
+)
+
+// FnListMutations is the mutation fn returning descriptions of all supported mutations in WebappResponse
+const FnListMutations = "list_mutations"
+
+// DryRunField is the field in mutation data enabling dry run. In dry run input is validated against the live system, but nothing is changed.
+const DryRunField = "dry_run"
+
+// Action is a mutation supported by integration
+type Action struct {
+	// Name is the mutation fn, usually agent.IntegrationMutationRequestAction
+	Name        string
+	Description string
+	// Input is a pointer to the struct passed to Check and Run. Described using json, mutate and description tags, see SchemaFor.
+	Input interface{}
+	// Check validates input against the live system without making changes. Called in dry run, Run should return the same errors when making the change. Optional.
+	Check func(ctx context.Context, input interface{}) error
+	// CheckBeforeRun also calls Check before Run. Use when source system does not return a useful error for invalid input, since it costs an extra api call.
+	CheckBeforeRun bool
+	// Run makes the change and returns mutated objects
+	Run func(ctx context.Context, input interface{}) (rpcdef.MutateResult, error)
+}
+
+// Description describes supported mutation, returned for FnListMutations
+type Description struct {
+	Name        string  `json:"name"`
+	Description string  `json:"description,omitempty"`
+	Input       *Schema `json:"input"`
+	DryRun      bool    `json:"dry_run"`
+}
+
+// DryRunResult is returned in WebappResponse when dry run succeeds
+type DryRunResult struct {
+	DryRun bool `json:"dry_run"`
+	Valid  bool `json:"valid"`
+}
+
+type registeredAction struct {
+	Action
+	inputType reflect.Type
+	schema    *Schema
+}
+
+// Registry contains mutations supported by integration and handles validation and dry run
+type Registry struct {
+	actions map[string]registeredAction
+}
+
+// NewRegistry creates empty registry
+func NewRegistry() *Registry {
+	s := &Registry{}
+	s.actions = map[string]registeredAction{}
+	return s
+}
+
+// Add registers action. Panics if action is not valid, since that is a bug in integration.
+func (s *Registry) Add(a Action) {
+	if a.Name == "" || a.Input == nil || a.Run == nil {
+		panic("provide Name, Input and Run for mutation action")
+	}
+	if _, ok := s.actions[a.Name]; ok {
+		panic("mutation action registered twice: " + a.Name)
+	}
+	t := reflect.TypeOf(a.Input)
+	if t.Kind() != reflect.Ptr || t.Elem().Kind() != reflect.Struct {
+		panic("mutation action Input must be a pointer to struct: " + a.Name)
+	}
+	schema, err := SchemaFor(a.Input)
+	if err != nil {
+		panic(fmt.Sprintf("invalid input of mutation action %v: %v", a.Name, err))
+	}
+	s.actions[a.Name] = registeredAction{Action: a, inputType: t.Elem(), schema: schema}
+}
+
+// Descriptions returns all registered mutations sorted by name
+func (s *Registry) Descriptions() (res []Description) {
+	for _, a := range s.actions {
+		res = append(res, Description{
+			Name:        a.Name,
+			Description: a.Description,
+			Input:       a.schema,
+			DryRun:      a.Check != nil,
+		})
+	}
+	sort.Slice(res, func(i, j int) bool {
+		return res[i].Name < res[j].Name
+	})
+	return
+}
+
+// ListResult returns the result for FnListMutations. Integrations call it before initializing api clients, so that listing does not need valid credentials.
+func (s *Registry) ListResult() (res rpcdef.MutateResult) {
+	res.WebappResponse = s.Descriptions()
+	return
+}
+
+// Mutate validates data and runs the mutation. Errors are returned in result using ResultFromError.
+func (s *Registry) Mutate(ctx context.Context, fn string, data string) rpcdef.MutateResult {
+	if fn == FnListMutations {
+		return s.ListResult()
+	}
+	a, ok := s.actions[fn]
+	if !ok {
+		return ResultFromError(NewError(ErrNotSupported, fmt.Errorf("mutate fn not supported: %v", fn)))
+	}
+	input, dryRun, err := a.parse(data)
+	if err != nil {
+		return ResultFromError(err)
+	}
+	if dryRun && a.Check == nil {
+		return ResultFromError(NewError(ErrNotSupported, fmt.Errorf("dry run is not supported for: %v", fn)))
+	}
+	if a.Check != nil && (dryRun || a.CheckBeforeRun) {
+		err := a.Check(ctx, input)
+		if err != nil {
+			return ResultFromError(err)
+		}
+	}
+	if dryRun {
+		return rpcdef.MutateResult{WebappResponse: DryRunResult{DryRun: true, Valid: true}}
+	}
+	res, err := a.Run(ctx, input)
+	if err != nil {
+		return ResultFromError(err)
+	}
+	return res
+}
+
+func (s registeredAction) parse(data string) (input interface{}, dryRun bool, _ error) {
+	dec := json.NewDecoder(bytes.NewReader([]byte(data)))
+	dec.UseNumber()
+	var raw interface{}
+	err := dec.Decode(&raw)
+	if err != nil {
+		return nil, false, NewValidationError(".", "invalid json: "+err.Error())
+	}
+	obj, ok := raw.(map[string]interface{})
+	if !ok {
+		return nil, false, NewValidationError(".", "expected object")
+	}
+	if v, ok := obj[DryRunField]; ok {
+		dryRun, ok = v.(bool)
+		if !ok {
+			return nil, false, NewValidationError(DryRunField, "expected boolean")
+		}
+		delete(obj, DryRunField)
+	}
+	err = Validate(s.schema, obj)
+	if err != nil {
+		return nil, false, err
+	}
+	input = reflect.New(s.inputType).Interface()
+	err = json.Unmarshal([]byte(data), input)
+	if err != nil {
+		return nil, false, NewValidationError(".", err.Error())
+	}
+	return input, dryRun, nil
+}
@@ -0,0 +1,149 @@
+package mutate
+
+import (
+	"context"
+	"errors"
+	"testing"
+
+	"github.com/pinpt/agent/pkg/requests"
+	"github.com/pinpt/agent/rpcdef"
+	"github.com/stretchr/testify/assert"
+)
+
+type setTitle struct {
+	RefID    string            `json:"ref_id" mutate:"required" description:"Issue id"`
+	Title    string            `json:"title" mutate:"required"`
+	Priority string            `json:"priority" mutate:"enum=low|high"`
+	Fields   map[string]string `json:"fields"`
+	Count    int               `json:"count"`
+}
+
+func newTestRegistry(written *[]string, checks *int) *Registry {
+	reg := NewRegistry()
+	reg.Add(Action{
+		Name:  "set_title",
+		Input: &setTitle{},
+		Check: func(ctx context.Context, input interface{}) error {
+			if checks != nil {
+				*checks++
+			}
+			in := input.(*setTitle)
+			if in.RefID == "missing" {
+				return requests.StatusCodeError{WantStart: 200, WantEnd: 299, Got: 404}
+			}
+			if in.RefID == "locked" {
+				return NewError(ErrConflict, errors.New("issue is locked"))
+			}
+			return nil
+		},
+		Run: func(ctx context.Context, input interface{}) (res rpcdef.MutateResult, _ error) {
+			in := input.(*setTitle)
+			*written = append(*written, in.RefID+":"+in.Title)
+			res.MutatedObjects = rpcdef.MutatedObjects{"work.Issue": {in.RefID}}
+			return
+		},
+	})
+	return reg
+}
+
+func TestRegistryMutate(t *testing.T) {
+	assert := assert.New(t)
+	var written []string
+	checks := 0
+	reg := newTestRegistry(&written, &checks)
+	ctx := context.Background()
+
+	res := reg.Mutate(ctx, "set_title", `{"ref_id":"1","title":"t1","fields":{"a":"b"}}`)
+	assert.Empty(res.Error)
+	assert.Equal([]string{"1:t1"}, written)
+	assert.Equal(rpcdef.MutatedObjects{"work.Issue": {"1"}}, res.MutatedObjects)
+	assert.Equal(0, checks, "check is only called in dry run")
+
+	res = reg.Mutate(ctx, "set_title", `{"ref_id":"1","title":"t2","dry_run":true}`)
+	assert.Empty(res.Error)
+	assert.Equal(DryRunResult{DryRun: true, Valid: true}, res.WebappResponse)
+	assert.Len(written, 1)
+	assert.Equal(1, checks)
+
+	res = reg.Mutate(ctx, "set_title", `{"ref_id":"missing","title":"t","dry_run":true}`)
+	assert.Equal(ErrNotFound, res.ErrorCode)
+	res = reg.Mutate(ctx, "set_title", `{"ref_id":"locked","title":"t","dry_run":true}`)
+	assert.Equal(ErrConflict, res.ErrorCode)
+	assert.Equal("issue is locked", res.Error)
+	assert.Len(written, 1)
+
+	res = reg.Mutate(ctx, "other", `{}`)
+	assert.Equal(ErrNotSupported, res.ErrorCode)
+}
+
+func TestRegistryCheckBeforeRun(t *testing.T) {
+	assert := assert.New(t)
+	var written []string
+	reg := NewRegistry()
+	reg.Add(Action{
+		Name:  "set_title",
+		Input: &setTitle{},
+		Check: func(ctx context.Context, input interface{}) error {
+			return NewError(ErrConflict, errors.New("issue is locked"))
+		},
+		CheckBeforeRun: true,
+		Run: func(ctx context.Context, input interface{}) (res rpcdef.MutateResult, _ error) {
+			written = append(written, input.(*setTitle).RefID)
+			return
+		},
+	})
+	res := reg.Mutate(context.Background(), "set_title", `{"ref_id":"1","title":"t1"}`)
+	assert.Equal(ErrConflict, res.ErrorCode)
+	assert.Empty(written)
+}
+
+func TestRegistryValidation(t *testing.T) {
+	assert := assert.New(t)
+	var written []string
+	reg := newTestRegistry(&written, nil)
+
+	res := reg.Mutate(context.Background(), "set_title", `{"title":"","priority":"medium","fields":{"a":1},"count":1.5,"dry_run":"yes"}`)
+	assert.Equal(ErrValidation, res.ErrorCode)
+	assert.Equal(&ValidationError{Fields: []FieldError{{Field: "dry_run", Error: "expected boolean"}}}, res.WebappResponse)
+
+	res = reg.Mutate(context.Background(), "set_title", `{"title":"","priority":"medium","fields":{"a":1},"count":1.5}`)
+	assert.Equal(ErrValidation, res.ErrorCode)
+	assert.Equal(&ValidationError{Fields: []FieldError{
+		{Field: "ref_id", Error: "required"},
+		{Field: "title", Error: "required"},
+		{Field: "count", Error: "expected integer"},
+		{Field: "fields.a", Error: "expected string"},
+		{Field: "priority", Error: "expected one of: low, high"},
+	}}, res.WebappResponse)
+
+	res = reg.Mutate(context.Background(), "set_title", `[]`)
+	assert.Equal(ErrValidation, res.ErrorCode)
+	assert.Empty(written)
+}
+
+func TestRegistryList(t *testing.T) {
+	assert := assert.New(t)
+	reg := newTestRegistry(nil, nil)
+	res := reg.Mutate(context.Background(), FnListMutations, "")
+	desc := res.WebappResponse.([]Description)
+	if !assert.Len(desc, 1) {
+		return
+	}
+	assert.Equal("set_title", desc[0].Name)
+	assert.True(desc[0].DryRun)
+	schema := desc[0].Input
+	assert.Equal("object", schema.Type)
+	assert.Equal([]string{"ref_id", "title"}, schema.Required)
+	assert.Equal("Issue id", schema.Properties["ref_id"].Description)
+	assert.Equal([]string{"low", "high"}, schema.Properties["priority"].Enum)
+	assert.Equal("string", schema.Properties["fields"].AdditionalProperties.Type)
+	assert.Equal("integer", schema.Properties["count"].Type)
+}
+
+func TestResultFromError(t *testing.T) {
+	assert := assert.New(t)
+	for status, code := range map[int]string{404: ErrNotFound, 403: ErrPermissionDenied, 401: ErrPermissionDenied, 409: ErrConflict, 422: ErrValidation, 500: ""} {
+		res := ResultFromError(requests.StatusCodeError{Got: status})
+		assert.Equal(code, res.ErrorCode, "status %v", status)
+	}
+}
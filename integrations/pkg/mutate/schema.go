@@ -0,0 +1,92 @@
+package mutate
+
+import (
+	"fmt"
+	"reflect"
+	"strings"
+)
+
+// Schema is a subset of JSON Schema describing mutation input
+type Schema struct {
+	Type                 string             `json:"type"`
+	Description          string             `json:"description,omitempty"`
+	Properties           map[string]*Schema `json:"properties,omitempty"`
+	Required             []string           `json:"required,omitempty"`
+	Items                *Schema            `json:"items,omitempty"`
+	AdditionalProperties *Schema            `json:"additionalProperties,omitempty"`
+	Enum                 []string           `json:"enum,omitempty"`
+}
+
+// SchemaFor returns the schema of struct based on field tags:
+//
+//	json - field name, fields without json name are skipped
+//	mutate - comma separated options: required, enum=a|b|c
+//	description - field description
+func SchemaFor(v interface{}) (*Schema, error) {
+	return schemaForType(reflect.TypeOf(v))
+}
+
+func schemaForType(t reflect.Type) (*Schema, error) {
+	for t.Kind() == reflect.Ptr {
+		t = t.Elem()
+	}
+	res := &Schema{}
+	switch t.Kind() {
+	case reflect.String:
+		res.Type = "string"
+	case reflect.Bool:
+		res.Type = "boolean"
+	case reflect.Int, reflect.Int8, reflect.Int16, reflect.Int32, reflect.Int64,
+		reflect.Uint, reflect.Uint8, reflect.Uint16, reflect.Uint32, reflect.Uint64:
+		res.Type = "integer"
+	case reflect.Float32, reflect.Float64:
+		res.Type = "number"
+	case reflect.Slice, reflect.Array:
+		res.Type = "array"
+		var err error
+		res.Items, err = schemaForType(t.Elem())
+		if err != nil {
+			return nil, err
+		}
+	case reflect.Map:
+		if t.Key().Kind() != reflect.String {
+			return nil, fmt.Errorf("only string map keys are supported, got: %v", t)
+		}
+		res.Type = "object"
+		var err error
+		res.AdditionalProperties, err = schemaForType(t.Elem())
+		if err != nil {
+			return nil, err
+		}
+	case reflect.Struct:
+		res.Type = "object"
+		res.Properties = map[string]*Schema{}
+		for i := 0; i < t.NumField(); i++ {
+			f := t.Field(i)
+			name := strings.Split(f.Tag.Get("json"), ",")[0]
+			if name == "" || name == "-" {
+				continue
+			}
+			fs, err := schemaForType(f.Type)
+			if err != nil {
+				return nil, fmt.Errorf("%v: %v", name, err)
+			}
+			fs.Description = f.Tag.Get("description")
+			for _, opt := range strings.Split(f.Tag.Get("mutate"), ",") {
+				switch {
+				case opt == "":
+				case opt == "required":
+					res.Required = append(res.Required, name)
+				case strings.HasPrefix(opt, "enum="):
+					fs.Enum = strings.Split(strings.TrimPrefix(opt, "enum="), "|")
+				default:
+					return nil, fmt.Errorf("%v: unknown mutate tag option: %v", name, opt)
+				}
+			}
+			res.Properties[name] = fs
+		}
+	default:
+		return nil, fmt.Errorf("unsupported type: %v", t)
+	}
+	return res, nil
+}
@@ -0,0 +1,110 @@
+package mutate
+
+import (
+	"encoding/json"
+	"fmt"
+	"sort"
+	"strings"
+)
+
+// Validate checks json decoded using UseNumber against schema. Returns *ValidationError listing all invalid fields.
+func Validate(schema *Schema, data interface{}) error {
+	var errs []FieldError
+	validate(schema, data, "", &errs)
+	if len(errs) != 0 {
+		return &ValidationError{Fields: errs}
+	}
+	return nil
+}
+
+func fieldPath(parent, name string) string {
+	if parent == "" {
+		return name
+	}
+	return parent + "." + name
+}
+
+func validate(schema *Schema, data interface{}, path string, errs *[]FieldError) {
+	add := func(msg string) {
+		field := path
+		if field == "" {
+			field = "."
+		}
+		*errs = append(*errs, FieldError{Field: field, Error: msg})
+	}
+	if data == nil {
+		// null is the same as missing value, required is checked on parent
+		return
+	}
+	switch schema.Type {
+	case "string":
+		v, ok := data.(string)
+		if !ok {
+			add("expected string")
+			return
+		}
+		if len(schema.Enum) != 0 && v != "" {
+			found := false
+			for _, e := range schema.Enum {
+				if v == e {
+					found = true
+					break
+				}
+			}
+			if !found {
+				add("expected one of: " + strings.Join(schema.Enum, ", "))
+			}
+		}
+	case "boolean":
+		if _, ok := data.(bool); !ok {
+			add("expected boolean")
+		}
+	case "integer":
+		n, ok := data.(json.Number)
+		if !ok {
+			add("expected integer")
+			return
+		}
+		if _, err := n.Int64(); err != nil {
+			add("expected integer")
+		}
+	case "number":
+		if _, ok := data.(json.Number); !ok {
+			add("expected number")
+		}
+	case "array":
+		arr, ok := data.([]interface{})
+		if !ok {
+			add("expected array")
+			return
+		}
+		for i, v := range arr {
+			validate(schema.Items, v, fmt.Sprintf("%v[%v]", path, i), errs)
+		}
+	case "object":
+		obj, ok := data.(map[string]interface{})
+		if !ok {
+			add("expected object")
+			return
+		}
+		for _, name := range schema.Required {
+			v := obj[name]
+			if v == nil || v == "" {
+				*errs = append(*errs, FieldError{Field: fieldPath(path, name), Error: "required"})
+			}
+		}
+		var keys []string
+		for k := range obj {
+			keys = append(keys, k)
+		}
+		sort.Strings(keys)
+		for _, k := range keys {
+			if ps, ok := schema.Properties[k]; ok {
+				validate(ps, obj[k], fieldPath(path, k), errs)
+			} else if schema.AdditionalProperties != nil {
+				validate(schema.AdditionalProperties, obj[k], fieldPath(path, k), errs)
+			}
+			// unknown fields are ignored, the same as in json.Unmarshal
+		}
+	}
+}
@@ -0,0 +1,73 @@
+package repoprojects
+
+import "github.com/hashicorp/go-hclog"
+
+// Plan describes what export would do without exporting any data. Returned as data for rpcdef.OnboardExportTypePlan.
+type Plan struct {
+	Projects []PlanProject  `json:"projects"`
+	Excluded []PlanExcluded `json:"excluded"`
+	// APICalls is the estimated number of api requests needed for full export, including the requests to list projects. Projects without estimate are not included.
+	APICalls int `json:"api_calls"`
+}
+
+// PlanProject is the repo/project that would be exported
+type PlanProject struct {
+	RefID      string `json:"ref_id"`
+	ReadableID string `json:"readable_id"`
+	// Counts contains the estimated number of objects by model, for example pull requests. Only set when api returns totals.
+	Counts map[string]int `json:"counts,omitempty"`
+	// APICalls is the estimated number of api requests to export project. 0 if unknown.
+	APICalls int `json:"api_calls"`
+	// Error is set if estimate failed for this project
+	Error string `json:"error,omitempty"`
+}
+
+// PlanExcluded is the repo/project that would be skipped
+type PlanExcluded struct {
+	RefID      string `json:"ref_id"`
+	ReadableID string `json:"readable_id"`
+	Reason     string `json:"reason"`
+}
+
+// PlanEstimate returns counts and api calls for one project. Should only use calls returning totals, not paginate all objects.
+type PlanEstimate func(project RepoProject) (PlanProject, error)
+
+// NewPlan filters projects in the same way as export and calls estimate for each remaining project. discoveryCalls is the number of requests used to list all projects. estimate could be nil if integration can't provide it.
+func NewPlan(logger hclog.Logger, projects []RepoProject, config FilterConfig, discoveryCalls int, estimate PlanEstimate) (res Plan) {
+	filtered, excluded := FilterWithReasons(logger, projects, config)
+	res.Projects = []PlanProject{}
+	res.Excluded = []PlanExcluded{}
+	res.APICalls = discoveryCalls
+	for _, p := range filtered {
+		var p2 PlanProject
+		if estimate != nil {
+			var err error
+			p2, err = estimate(p)
+			if err != nil {
+				logger.Warn("could not estimate project export", "project", p.GetReadableID(), "err", err)
+				p2 = PlanProject{}
+				p2.Error = err.Error()
+			}
+		}
+		p2.RefID = p.GetID()
+		p2.ReadableID = p.GetReadableID()
+		res.APICalls += p2.APICalls
+		res.Projects = append(res.Projects, p2)
+	}
+	for _, e := range excluded {
+		res.Excluded = append(res.Excluded, PlanExcluded{
+			RefID:      e.Project.GetID(),
+			ReadableID: e.Project.GetReadableID(),
+			Reason:     e.Reason,
+		})
+	}
+	return
+}
+
+// Pages returns the number of requests needed to get total objects with pageSize per page. Returns 1 for 0 objects, since the first request is always made.
+func Pages(total int, pageSize int) int {
+	if total <= 0 {
+		return 1
+	}
+	return (total + pageSize - 1) / pageSize
+}
@@ -18,7 +18,32 @@ type FilterConfig struct {
 	StopAfterN             int
 }
 
+// Filter returns repos/projects that should be exported. Result keeps the order of passed repos, so StopAfterN keeps the first ones.
 func Filter(logger hclog.Logger, repos []RepoProject, config FilterConfig) (res []RepoProject) {
+	res, _ = FilterWithReasons(logger, repos, config)
+	return
+}
+
+// Reasons for excluding repo/project from export, returned from FilterWithReasons
+const (
+	ExcludedNotInOnlyInclude = "not in repos list"
+	ExcludedNotInInclusions  = "not in inclusions"
+	ExcludedInExclusions     = "in exclusions"
+	ExcludedStopAfterN       = "over stop_after_n limit"
+)
+
+// Excluded is the repo/project that was filtered out with the reason
+type Excluded struct {
+	Project RepoProject
+	Reason  string
+}
+
+// FilterWithReasons works the same as Filter, but also returns the projects that were filtered out. Used in export plan.
+func FilterWithReasons(logger hclog.Logger, repos []RepoProject, config FilterConfig) (res []RepoProject, excluded []Excluded) {
+
+	exclude := func(repo RepoProject, reason string) {
+		excluded = append(excluded, Excluded{Project: repo, Reason: reason})
+	}
 
 	if len(config.OnlyIncludeReadableIDs) != 0 {
 		onlyInclude := config.OnlyIncludeReadableIDs
@@ -29,6 +54,7 @@ func Filter(logger hclog.Logger, repos []RepoProject, config FilterConfig) (res
 		}
 		for _, repo := range repos {
 			if !ok[repo.GetReadableID()] {
+				exclude(repo, ExcludedNotInOnlyInclude)
 				continue
 			}
 			res = append(res, repo)
@@ -46,6 +72,7 @@ func Filter(logger hclog.Logger, repos []RepoProject, config FilterConfig) (res
 			}
 			for _, repo := range repos {
 				if !ok[repo.GetID()] {
+					exclude(repo, ExcludedNotInInclusions)
 					continue
 				}
 				included = append(included, repo)
@@ -55,30 +82,35 @@ func Filter(logger hclog.Logger, repos []RepoProject, config FilterConfig) (res
 		}
 	}
 
-	excluded := map[string]bool{}
+	excludedIDs := map[string]bool{}
 	for _, id := range config.ExcludedIDs {
-		excluded[id] = true
+		excludedIDs[id] = true
 	}
 
-	filtered := map[string]RepoProject{}
+	// keep the original order, but skip duplicates
+	seen := map[string]bool{}
 	for _, repo := range included {
-		if excluded[repo.GetID()] {
+		if excludedIDs[repo.GetID()] {
+			exclude(repo, ExcludedInExclusions)
 			continue
 		}
-		filtered[repo.GetID()] = repo
-	}
-
-	logger.Info("projects", "found", len(repos), "excluded_definition", len(config.ExcludedIDs), "included_definition", len(config.IncludedIDs), "result", len(filtered))
-
-	for _, repo := range filtered {
+		if seen[repo.GetID()] {
+			continue
+		}
+		seen[repo.GetID()] = true
 		res = append(res, repo)
 	}
 
+	logger.Info("projects", "found", len(repos), "excluded_definition", len(config.ExcludedIDs), "included_definition", len(config.IncludedIDs), "result", len(res))
+
 	if config.StopAfterN > 0 {
 		// only leave 1 repo/project for export
 		stopAfter := config.StopAfterN
 		l := len(res)
 		if l > stopAfter {
+			for _, repo := range res[stopAfter:] {
+				exclude(repo, ExcludedStopAfterN)
+			}
 			res = res[0:stopAfter]
 		}
 
@@ -0,0 +1,114 @@
+package repoprojects
+
+import (
+	"errors"
+	"testing"
+
+	"github.com/hashicorp/go-hclog"
+	"github.com/stretchr/testify/assert"
+)
+
+type testProject struct {
+	id   string
+	name string
+}
+
+func (s testProject) GetID() string {
+	return s.id
+}
+
+func (s testProject) GetReadableID() string {
+	return s.name
+}
+
+func testProjects() []RepoProject {
+	return []RepoProject{
+		testProject{"1", "a/r1"},
+		testProject{"2", "a/r2"},
+		testProject{"3", "a/r3"},
+		testProject{"4", "a/r4"},
+	}
+}
+
+func reasons(excluded []Excluded) map[string]string {
+	res := map[string]string{}
+	for _, e := range excluded {
+		res[e.Project.GetID()] = e.Reason
+	}
+	return res
+}
+
+func TestFilterWithReasonsOnlyInclude(t *testing.T) {
+	res, excluded := FilterWithReasons(hclog.NewNullLogger(), testProjects(), FilterConfig{
+		OnlyIncludeReadableIDs: []string{"a/r2"},
+		// ignored when OnlyIncludeReadableIDs is set
+		ExcludedIDs: []string{"2"},
+	})
+	assert := assert.New(t)
+	assert.Equal([]RepoProject{testProject{"2", "a/r2"}}, res)
+	assert.Equal(map[string]string{
+		"1": ExcludedNotInOnlyInclude,
+		"3": ExcludedNotInOnlyInclude,
+		"4": ExcludedNotInOnlyInclude,
+	}, reasons(excluded))
+}
+
+func TestFilterWithReasons(t *testing.T) {
+	res, excluded := FilterWithReasons(hclog.NewNullLogger(), testProjects(), FilterConfig{
+		IncludedIDs: []string{"1", "2", "3"},
+		ExcludedIDs: []string{"2"},
+		StopAfterN:  1,
+	})
+	assert := assert.New(t)
+	assert.Equal([]RepoProject{testProject{"1", "a/r1"}}, res)
+	assert.Equal(map[string]string{
+		"2": ExcludedInExclusions,
+		"3": ExcludedStopAfterN,
+		"4": ExcludedNotInInclusions,
+	}, reasons(excluded))
+}
+
+func TestFilterKeepsOrder(t *testing.T) {
+	repos := []RepoProject{
+		testProject{"3", "a/r3"},
+		testProject{"1", "a/r1"},
+		testProject{"4", "a/r4"},
+		testProject{"1", "a/r1"},
+		testProject{"2", "a/r2"},
+	}
+	assert := assert.New(t)
+	for i := 0; i < 10; i++ {
+		res := Filter(hclog.NewNullLogger(), repos, FilterConfig{StopAfterN: 3})
+		assert.Equal([]RepoProject{
+			testProject{"3", "a/r3"},
+			testProject{"1", "a/r1"},
+			testProject{"4", "a/r4"},
+		}, res)
+	}
+	res, excluded := FilterWithReasons(hclog.NewNullLogger(), repos, FilterConfig{ExcludedIDs: []string{"1"}, StopAfterN: 2})
+	assert.Equal([]RepoProject{
+		testProject{"3", "a/r3"},
+		testProject{"4", "a/r4"},
+	}, res)
+	assert.Equal("2", excluded[len(excluded)-1].Project.GetID())
+	assert.Equal(ExcludedStopAfterN, excluded[len(excluded)-1].Reason)
+}
+
+func TestNewPlan(t *testing.T) {
+	plan := NewPlan(hclog.NewNullLogger(), testProjects(), FilterConfig{ExcludedIDs: []string{"4"}}, 2, func(p RepoProject) (res PlanProject, _ error) {
+		if p.GetID() == "3" {
+			return res, errors.New("e1")
+		}
+		res.Counts = map[string]int{"pr": 120}
+		res.APICalls = Pages(120, 100)
+		return
+	})
+	assert := assert.New(t)
+	assert.Len(plan.Projects, 3)
+	assert.Equal("a/r1", plan.Projects[0].ReadableID)
+	assert.Equal(120, plan.Projects[0].Counts["pr"])
+	assert.Equal("e1", plan.Projects[2].Error)
+	assert.Equal("3", plan.Projects[2].RefID)
+	assert.Equal([]PlanExcluded{{RefID: "4", ReadableID: "a/r4", Reason: ExcludedInExclusions}}, plan.Excluded)
+	assert.Equal(2+2+2, plan.APICalls)
+}
@@ -8,7 +8,14 @@ import (
 	"path/filepath"
 
 	"github.com/pinpt/agent/cmd/cmdrunnorestarts/inconfig"
+	"github.com/pinpt/agent/cmd/cmdrunnorestarts/webhookserver"
+	"github.com/pinpt/agent/pkg/bundle"
+	"github.com/pinpt/agent/pkg/expsinks"
 	"github.com/pinpt/agent/pkg/fs"
+	"github.com/pinpt/agent/pkg/gitconf"
+	"github.com/pinpt/agent/pkg/identity"
+	"github.com/pinpt/agent/pkg/redact"
+	"github.com/pinpt/agent/pkg/secrets"
 )
 
 type Config struct {
@@ -25,6 +32,30 @@ type Config struct {
 
 	// ExtraIntegrations defines additional integrations that will run on every export trigger in run command. This is needed to run a custom integration for one of our customers. You need to add these custom integrations to config manually after enroll.
 	ExtraIntegrations []inconfig.IntegrationAgent `json:"extra_integrations"`
+
+	// Sinks defines where exported data is written. By default data is only written into uploads dir and sent to pinpoint. Add upload sink explicitly together with others to keep a local copy and still send data to pinpoint. You need to add these to config manually after enroll.
+	Sinks []expsinks.Config `json:"sinks"`
+
+	// StatusAPI is the address for local http status and control api. Use localhost:port or unix:/path/to/socket. Disabled if empty.
+	StatusAPI string `json:"status_api"`
+
+	// Identity configures rules for linking user identities across integrations. You need to add these to config manually after enroll.
+	Identity identity.Rules `json:"identity"`
+
+	// Redaction defines policies for removing or hashing personal data before it is written by sinks. You need to add these to config manually after enroll.
+	Redaction redact.Config `json:"redaction"`
+
+	// Secrets configures detection and redaction of api tokens and passwords in exported data, enabled by default.
+	Secrets secrets.Config `json:"secrets"`
+
+	// Bundle configures writing export results to encrypted bundles instead of uploading them, used on air-gapped hosts.
+	Bundle bundle.Config `json:"bundle"`
+
+	// WebhookServer configures built-in webhook receiver, used when source systems can't deliver webhooks to pinpoint cloud.
+	WebhookServer webhookserver.Config `json:"webhook_server"`
+
+	// Git configures optional processing of git repos.
+	Git gitconf.Config `json:"git"`
 }
 
 func Save(c Config, loc string) error {
@@ -0,0 +1,133 @@
+package bundle
+
+import (
+	"bytes"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"time"
+
+	"github.com/pinpt/agent/pkg/fs"
+)
+
+// AckFileExt is the extension of ack files
+const AckFileExt = ".ack"
+
+// Ack confirms that bundle was uploaded. It is created on the connected machine and imported back on the air-gapped host to commit the export state.
+type Ack struct {
+	BundleID string    `json:"bundle_id"`
+	JobIDs   []string  `json:"job_ids"`
+	Uploaded time.Time `json:"uploaded"`
+	// Signature is HMAC-SHA256 of other fields, using signing key derived from bundle key
+	Signature string `json:"signature"`
+}
+
+func (s Ack) signedData() []byte {
+	s.Signature = ""
+	b, err := json.Marshal(s)
+	if err != nil {
+		panic(err)
+	}
+	return b
+}
+
+// NewAck returns signed ack for uploaded bundle
+func NewAck(manifest Manifest, uploaded time.Time, key string) Ack {
+	s := Ack{}
+	s.BundleID = manifest.ID
+	s.JobIDs = manifest.JobIDs
+	s.Uploaded = uploaded.UTC()
+	s.Signature = sign(s.signedData(), key)
+	return s
+}
+
+// WriteAck saves ack to loc
+func WriteAck(loc string, ack Ack) error {
+	b, err := json.Marshal(ack)
+	if err != nil {
+		return err
+	}
+	return fs.WriteToTempAndRename(bytes.NewReader(b), loc)
+}
+
+// ReadAck reads ack from loc and verifies its signature
+func ReadAck(loc string, key string) (res Ack, _ error) {
+	b, err := ioutil.ReadFile(loc)
+	if err != nil {
+		return res, err
+	}
+	err = json.Unmarshal(b, &res)
+	if err != nil {
+		return res, fmt.Errorf("not a valid ack file: %v", err)
+	}
+	if !verify(res.signedData(), res.Signature, key) {
+		return res, ErrInvalidSignature
+	}
+	return res, nil
+}
+
+// PendingBundle is a bundle that was created but not acknowledged yet
+type PendingBundle struct {
+	ID      string    `json:"id"`
+	File    string    `json:"file"`
+	Created time.Time `json:"created"`
+	JobIDs  []string  `json:"job_ids"`
+}
+
+// ReadPending returns bundles waiting for ack, saved at loc
+func ReadPending(loc string) (res []PendingBundle, _ error) {
+	b, err := ioutil.ReadFile(loc)
+	if os.IsNotExist(err) {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+	err = json.Unmarshal(b, &res)
+	if err != nil {
+		return nil, fmt.Errorf("could not parse pending bundles file: %v", err)
+	}
+	return res, nil
+}
+
+// WritePending saves the list of bundles waiting for ack, removes the file if list is empty
+func WritePending(loc string, pending []PendingBundle) error {
+	if len(pending) == 0 {
+		err := os.Remove(loc)
+		if os.IsNotExist(err) {
+			return nil
+		}
+		return err
+	}
+	b, err := json.MarshalIndent(pending, "", "  ")
+	if err != nil {
+		return err
+	}
+	err = os.MkdirAll(filepath.Dir(loc), 0777)
+	if err != nil {
+		return err
+	}
+	return fs.WriteToTempAndRename(bytes.NewReader(b), loc)
+}
+
+// ErrNotPending is returned when ack is for a bundle that is not waiting for ack, for example if it was already imported
+var ErrNotPending = errors.New("bundle is not pending, ack was already imported or bundle was created on another host")
+
+// RemovePending removes acknowledged bundle from pending list and returns the remaining bundles
+func RemovePending(pending []PendingBundle, ack Ack) (res []PendingBundle, _ error) {
+	found := false
+	for _, b := range pending {
+		if b.ID == ack.BundleID {
+			found = true
+			continue
+		}
+		res = append(res, b)
+	}
+	if !found {
+		return pending, ErrNotPending
+	}
+	return res, nil
+}
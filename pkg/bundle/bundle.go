@@ -0,0 +1,312 @@
+// Package bundle packages export results into signed and encrypted files, which could be moved manually from air-gapped hosts and uploaded from a connected machine.
+package bundle
+
+import (
+	"bytes"
+	"crypto/hmac"
+	"crypto/rand"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"sort"
+	"time"
+
+	"github.com/pinpt/agent/pkg/archive"
+	"github.com/pinpt/agent/pkg/encrypt"
+	"github.com/pinpt/agent/pkg/fs"
+)
+
+// Config configures writing bundles instead of uploading export results
+type Config struct {
+	// Dir is the directory where bundles are written by the service and where ack files are read from. Bundles are not used when empty.
+	Dir string `json:"dir"`
+	// Key is the hex encoded 32 byte key used to encrypt and sign bundles and acks, generate it using openssl rand -hex 32. Separate keys for encryption and signing are derived from it.
+	Key string `json:"key"`
+}
+
+// Enabled returns true if service should write bundles instead of uploading
+func (s Config) Enabled() bool {
+	return s.Dir != ""
+}
+
+// FileExt is the extension of bundle files
+const FileExt = ".ppbundle"
+
+// ManifestFile is the name of manifest inside of bundle
+const ManifestFile = "manifest.json"
+
+const formatVersion = 1
+
+// Manifest describes the bundle contents
+type Manifest struct {
+	ID           string    `json:"id"`
+	Created      time.Time `json:"created"`
+	CustomerID   string    `json:"customer_id"`
+	DeviceID     string    `json:"device_id"`
+	AgentVersion string    `json:"agent_version"`
+	// Integrations are versions of integrations by name
+	Integrations map[string]string `json:"integrations"`
+	JobIDs       []string          `json:"job_ids"`
+	// UploadURL is the upload url of the last export request, could be empty for manual exports
+	UploadURL string `json:"upload_url"`
+	Files     []File `json:"files"`
+}
+
+// File is a file in bundle with its checksum
+type File struct {
+	Name   string `json:"name"`
+	Size   int64  `json:"size"`
+	SHA256 string `json:"sha256"`
+}
+
+type envelope struct {
+	Version int    `json:"version"`
+	ID      string `json:"id"`
+	// Signature is HMAC-SHA256 of Data, using signing key derived from bundle key
+	Signature string `json:"signature"`
+	// Data is the encrypted zip file
+	Data string `json:"data"`
+}
+
+// ErrInvalidKey is returned when key is not 32 bytes hex encoded
+var ErrInvalidKey = errors.New("bundle key must be 32 bytes hex encoded, generate it using openssl rand -hex 32")
+
+func validateKey(key string) error {
+	b, err := hex.DecodeString(key)
+	if err != nil || len(b) != 32 {
+		return ErrInvalidKey
+	}
+	return nil
+}
+
+// ErrInvalidSignature is returned when bundle or ack was not created with the same key or was modified
+var ErrInvalidSignature = errors.New("invalid signature, bundle or ack was modified or created with a different key")
+
+// subkey derives key for one purpose from the configured key, so that encryption and signing do not use the same key
+func subkey(key string, purpose string) string {
+	mac := hmac.New(sha256.New, []byte(key))
+	mac.Write([]byte(purpose))
+	return hex.EncodeToString(mac.Sum(nil))
+}
+
+func encryptionKey(key string) string {
+	return subkey(key, "enc")
+}
+
+func sign(data []byte, key string) string {
+	mac := hmac.New(sha256.New, []byte(subkey(key, "sig")))
+	mac.Write(data)
+	return hex.EncodeToString(mac.Sum(nil))
+}
+
+func verify(data []byte, signature string, key string) bool {
+	return hmac.Equal([]byte(sign(data, key)), []byte(signature))
+}
+
+// Create writes bundle containing all files in dir to loc. Files and ID of manifest are set by Create, other fields are taken from passed manifest.
+func Create(loc string, key string, manifest Manifest, dir string) (_ Manifest, rerr error) {
+	if err := validateKey(key); err != nil {
+		rerr = err
+		return
+	}
+	if manifest.ID == "" {
+		manifest.ID = NewID(manifest.Created)
+	}
+	manifest.Files = nil
+	err := filepath.Walk(dir, func(p string, info os.FileInfo, err error) error {
+		if err != nil {
+			return err
+		}
+		if info.IsDir() {
+			return nil
+		}
+		name, err := filepath.Rel(dir, p)
+		if err != nil {
+			return err
+		}
+		name = filepath.ToSlash(name)
+		if name == ManifestFile {
+			return nil
+		}
+		sum, err := fileSHA256(p)
+		if err != nil {
+			return err
+		}
+		manifest.Files = append(manifest.Files, File{Name: name, Size: info.Size(), SHA256: sum})
+		return nil
+	})
+	if err != nil {
+		rerr = err
+		return
+	}
+	sort.Slice(manifest.Files, func(i, j int) bool {
+		return manifest.Files[i].Name < manifest.Files[j].Name
+	})
+
+	b, err := json.MarshalIndent(manifest, "", "  ")
+	if err != nil {
+		rerr = err
+		return
+	}
+	err = ioutil.WriteFile(filepath.Join(dir, ManifestFile), b, 0644)
+	if err != nil {
+		rerr = err
+		return
+	}
+
+	zipFile, err := ioutil.TempFile("", "pinpoint-bundle-*.zip")
+	if err != nil {
+		rerr = err
+		return
+	}
+	zipFile.Close()
+	defer os.Remove(zipFile.Name())
+	err = archive.ZipDir(zipFile.Name(), dir)
+	if err != nil {
+		rerr = err
+		return
+	}
+	data, err := ioutil.ReadFile(zipFile.Name())
+	if err != nil {
+		rerr = err
+		return
+	}
+	encrypted, err := encrypt.Encrypt(data, encryptionKey(key))
+	if err != nil {
+		rerr = fmt.Errorf("could not encrypt bundle: %v", err)
+		return
+	}
+	env := envelope{}
+	env.Version = formatVersion
+	env.ID = manifest.ID
+	env.Signature = sign(encrypted, key)
+	env.Data = string(encrypted)
+	b, err = json.Marshal(env)
+	if err != nil {
+		rerr = err
+		return
+	}
+	err = os.MkdirAll(filepath.Dir(loc), 0777)
+	if err != nil {
+		rerr = err
+		return
+	}
+	err = fs.WriteToTempAndRename(bytes.NewReader(b), loc)
+	if err != nil {
+		rerr = err
+		return
+	}
+	return manifest, nil
+}
+
+// Open verifies signature of bundle at loc, decrypts it into dir and checks that files match the manifest checksums
+func Open(loc string, key string, dir string) (res Manifest, rerr error) {
+	if err := validateKey(key); err != nil {
+		rerr = err
+		return
+	}
+	b, err := ioutil.ReadFile(loc)
+	if err != nil {
+		rerr = err
+		return
+	}
+	var env envelope
+	err = json.Unmarshal(b, &env)
+	if err != nil {
+		rerr = fmt.Errorf("not a valid bundle file: %v", err)
+		return
+	}
+	if env.Version != formatVersion {
+		rerr = fmt.Errorf("unsupported bundle version: %v", env.Version)
+		return
+	}
+	if !verify([]byte(env.Data), env.Signature, key) {
+		rerr = ErrInvalidSignature
+		return
+	}
+	data, err := encrypt.Decrypt([]byte(env.Data), encryptionKey(key))
+	if err != nil {
+		rerr = fmt.Errorf("could not decrypt bundle: %v", err)
+		return
+	}
+
+	zipFile, err := ioutil.TempFile("", "pinpoint-bundle-*.zip")
+	if err != nil {
+		rerr = err
+		return
+	}
+	defer os.Remove(zipFile.Name())
+	_, err = zipFile.Write(data)
+	if err != nil {
+		zipFile.Close()
+		rerr = err
+		return
+	}
+	err = zipFile.Close()
+	if err != nil {
+		rerr = err
+		return
+	}
+	err = archive.Unzip(dir, zipFile.Name())
+	if err != nil {
+		rerr = err
+		return
+	}
+
+	b, err = ioutil.ReadFile(filepath.Join(dir, ManifestFile))
+	if err != nil {
+		rerr = fmt.Errorf("could not read bundle manifest: %v", err)
+		return
+	}
+	err = json.Unmarshal(b, &res)
+	if err != nil {
+		rerr = fmt.Errorf("could not parse bundle manifest: %v", err)
+		return
+	}
+	if res.ID != env.ID {
+		rerr = fmt.Errorf("manifest id %v does not match bundle id %v", res.ID, env.ID)
+		return
+	}
+	for _, f := range res.Files {
+		sum, err := fileSHA256(filepath.Join(dir, filepath.FromSlash(f.Name)))
+		if err != nil {
+			rerr = err
+			return
+		}
+		if sum != f.SHA256 {
+			rerr = fmt.Errorf("checksum mismatch for file %v", f.Name)
+			return
+		}
+	}
+	return res, nil
+}
+
+func fileSHA256(loc string) (string, error) {
+	f, err := os.Open(loc)
+	if err != nil {
+		return "", err
+	}
+	defer f.Close()
+	h := sha256.New()
+	_, err = io.Copy(h, f)
+	if err != nil {
+		return "", err
+	}
+	return hex.EncodeToString(h.Sum(nil)), nil
+}
+
+// NewID returns unique bundle id starting with creation time, so that bundle files are sorted by time
+func NewID(created time.Time) string {
+	b := make([]byte, 4)
+	_, err := rand.Read(b)
+	if err != nil {
+		panic(err)
+	}
+	return created.UTC().Format("20060102T150405Z") + "-" + hex.EncodeToString(b)
+}
@@ -0,0 +1,154 @@
+package bundle
+
+import (
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/pinpt/agent/pkg/encrypt"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestCreateOpen(t *testing.T) {
+	assert := assert.New(t)
+	tmp, err := ioutil.TempDir("", "bundle-test")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(tmp)
+
+	key, err := encrypt.GenerateKey()
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	src := filepath.Join(tmp, "src")
+	err = os.MkdirAll(filepath.Join(src, "uploads", "github"), 0777)
+	if err != nil {
+		t.Fatal(err)
+	}
+	err = ioutil.WriteFile(filepath.Join(src, "uploads", "github", "a.gz"), []byte("data1"), 0644)
+	if err != nil {
+		t.Fatal(err)
+	}
+	err = ioutil.WriteFile(filepath.Join(src, "results.json"), []byte("{}"), 0644)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	m0 := Manifest{}
+	m0.Created = time.Now()
+	m0.JobIDs = []string{"j1"}
+	loc := filepath.Join(tmp, "out", "b1"+FileExt)
+	m1, err := Create(loc, key, m0, src)
+	if err != nil {
+		t.Fatal(err)
+	}
+	assert.NotEmpty(m1.ID)
+	assert.Len(m1.Files, 2)
+	assert.Equal("results.json", m1.Files[0].Name)
+	assert.Equal("uploads/github/a.gz", m1.Files[1].Name)
+
+	dst := filepath.Join(tmp, "dst")
+	m2, err := Open(loc, key, dst)
+	if err != nil {
+		t.Fatal(err)
+	}
+	assert.Equal(m1.ID, m2.ID)
+	assert.Equal([]string{"j1"}, m2.JobIDs)
+	b, err := ioutil.ReadFile(filepath.Join(dst, "uploads", "github", "a.gz"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	assert.Equal("data1", string(b))
+
+	otherKey, err := encrypt.GenerateKey()
+	if err != nil {
+		t.Fatal(err)
+	}
+	_, err = Open(loc, otherKey, filepath.Join(tmp, "dst2"))
+	assert.Equal(ErrInvalidSignature, err)
+}
+
+func TestAck(t *testing.T) {
+	assert := assert.New(t)
+	tmp, err := ioutil.TempDir("", "bundle-test")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(tmp)
+
+	key, err := encrypt.GenerateKey()
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	pendingLoc := filepath.Join(tmp, "pending.json")
+	pending := []PendingBundle{{ID: "b1"}, {ID: "b2"}}
+	err = WritePending(pendingLoc, pending)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	ackLoc := filepath.Join(tmp, "b1"+AckFileExt)
+	err = WriteAck(ackLoc, NewAck(Manifest{ID: "b1"}, time.Now(), key))
+	if err != nil {
+		t.Fatal(err)
+	}
+	ack, err := ReadAck(ackLoc, key)
+	if err != nil {
+		t.Fatal(err)
+	}
+	assert.Equal("b1", ack.BundleID)
+
+	pending, err = ReadPending(pendingLoc)
+	if err != nil {
+		t.Fatal(err)
+	}
+	pending, err = RemovePending(pending, ack)
+	if err != nil {
+		t.Fatal(err)
+	}
+	assert.Equal([]PendingBundle{{ID: "b2"}}, pending)
+
+	_, err = RemovePending(pending, ack)
+	assert.Equal(ErrNotPending, err)
+
+	err = WritePending(pendingLoc, nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	pending, err = ReadPending(pendingLoc)
+	if err != nil {
+		t.Fatal(err)
+	}
+	assert.Empty(pending)
+
+	ack.BundleID = "b2"
+	err = WriteAck(ackLoc, ack)
+	if err != nil {
+		t.Fatal(err)
+	}
+	_, err = ReadAck(ackLoc, key)
+	assert.Equal(ErrInvalidSignature, err)
+}
+
+func TestSubkeys(t *testing.T) {
+	assert := assert.New(t)
+	key, err := encrypt.GenerateKey()
+	if err != nil {
+		t.Fatal(err)
+	}
+	enc := encryptionKey(key)
+	sig := subkey(key, "sig")
+	assert.NotEqual(key, enc)
+	assert.NotEqual(key, sig)
+	assert.NotEqual(enc, sig)
+	// derived key is valid for encrypt package
+	assert.NoError(validateKey(enc))
+
+	_, err = Create(filepath.Join(os.TempDir(), "invalid"+FileExt), "abc", Manifest{}, os.TempDir())
+	assert.Equal(ErrInvalidKey, err)
+}
@@ -0,0 +1,130 @@
+// Package codeownership contains the models for exported code ownership snapshots.
+package codeownership
+
+import (
+	"encoding/json"
+)
+
+const FileTableName = "sourcecode.FileOwnership"
+
+const DirectoryTableName = "sourcecode.DirectoryOwnership"
+
+// SnapshotDate is the same as date fields in integration-sdk, use date.ConvertToModel to set it
+type SnapshotDate struct {
+	Epoch   int64
+	Offset  int64
+	Rfc3339 string
+}
+
+func (s SnapshotDate) ToMap() map[string]interface{} {
+	res := map[string]interface{}{}
+	res["epoch"] = s.Epoch
+	res["offset"] = s.Offset
+	res["rfc3339"] = s.Rfc3339
+	return res
+}
+
+// Author is the number of lines last changed by commit author
+type Author struct {
+	RefID string
+	Name  string
+	Email string
+	Lines int
+}
+
+func (s Author) ToMap() map[string]interface{} {
+	res := map[string]interface{}{}
+	res["ref_id"] = s.RefID
+	res["name"] = s.Name
+	res["email"] = s.Email
+	res["lines"] = s.Lines
+	return res
+}
+
+func authorsToMap(authors []Author) (res []map[string]interface{}) {
+	res = []map[string]interface{}{}
+	for _, a := range authors {
+		res = append(res, a.ToMap())
+	}
+	return
+}
+
+// File is blame based line ownership of a file at default branch head
+type File struct {
+	ID         string
+	CustomerID string
+	RefType    string
+	RepoID     string
+	CommitSha  string
+	CommitID   string
+	Path       string
+	Lines      int
+	Authors    []Author
+	// DeclaredOwners are users, teams or emails assigned to the file in CODEOWNERS
+	DeclaredOwners []string
+	SnapshotDate   SnapshotDate
+	// Deleted is set for files removed since the last snapshot, only id and path are set in that case
+	Deleted bool
+}
+
+func (s File) Stringify() string {
+	b, _ := json.Marshal(s.ToMap())
+	return string(b)
+}
+
+func (s File) ToMap() map[string]interface{} {
+	res := map[string]interface{}{}
+	res["id"] = s.ID
+	res["customer_id"] = s.CustomerID
+	res["ref_type"] = s.RefType
+	res["repo_id"] = s.RepoID
+	res["commit_sha"] = s.CommitSha
+	res["commit_id"] = s.CommitID
+	res["path"] = s.Path
+	res["lines"] = s.Lines
+	res["authors"] = authorsToMap(s.Authors)
+	res["declared_owners"] = s.DeclaredOwners
+	res["snapshot_date"] = s.SnapshotDate.ToMap()
+	res["deleted"] = s.Deleted
+	return res
+}
+
+// Directory is line ownership of all files in directory and its subdirectories
+type Directory struct {
+	ID         string
+	CustomerID string
+	RefType    string
+	RepoID     string
+	CommitSha  string
+	CommitID   string
+	// Path is empty for repo root
+	Path         string
+	Files        int
+	Lines        int
+	Authors      []Author
+	SnapshotDate SnapshotDate
+	// Deleted is set for directories removed since the last snapshot, only id and path are set in that case
+	Deleted bool
+}
+
+func (s Directory) Stringify() string {
+	b, _ := json.Marshal(s.ToMap())
+	return string(b)
+}
+
+func (s Directory) ToMap() map[string]interface{} {
+	res := map[string]interface{}{}
+	res["id"] = s.ID
+	res["customer_id"] = s.CustomerID
+	res["ref_type"] = s.RefType
+	res["repo_id"] = s.RepoID
+	res["commit_sha"] = s.CommitSha
+	res["commit_id"] = s.CommitID
+	res["path"] = s.Path
+	res["files"] = s.Files
+	res["lines"] = s.Lines
+	res["authors"] = authorsToMap(s.Authors)
+	res["snapshot_date"] = s.SnapshotDate.ToMap()
+	res["deleted"] = s.Deleted
+	return res
+}
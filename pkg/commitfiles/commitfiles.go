@@ -0,0 +1,53 @@
+package commitfiles
+
+import (
+	"encoding/json"
+)
+
+const TableName = "sourcecode.CommitFile"
+
+// CommitFile contains changes to a file in commit compared to its first parent
+type CommitFile struct {
+	ID         string
+	CustomerID string
+	RefType    string
+	RepoID     string
+	CommitID   string
+	CommitSha  string
+	Path       string
+	// PreviousPath is set for renamed files
+	PreviousPath string
+	// Status is one of added, modified, removed, renamed
+	Status    string
+	Additions int
+	Deletions int
+	Binary    bool
+	Language  string
+	Generated bool
+	Vendored  bool
+}
+
+func (s CommitFile) Stringify() string {
+	b, _ := json.Marshal(s.ToMap())
+	return string(b)
+}
+
+func (s CommitFile) ToMap() map[string]interface{} {
+	res := map[string]interface{}{}
+	res["id"] = s.ID
+	res["customer_id"] = s.CustomerID
+	res["ref_type"] = s.RefType
+	res["repo_id"] = s.RepoID
+	res["commit_id"] = s.CommitID
+	res["commit_sha"] = s.CommitSha
+	res["path"] = s.Path
+	res["previous_path"] = s.PreviousPath
+	res["status"] = s.Status
+	res["additions"] = s.Additions
+	res["deletions"] = s.Deletions
+	res["binary"] = s.Binary
+	res["language"] = s.Language
+	res["generated"] = s.Generated
+	res["vendored"] = s.Vendored
+	return res
+}
@@ -12,6 +12,9 @@ type CommitUser struct {
 	Email      string
 	Name       string
 	SourceID   string
+	// OriginalEmail and OriginalName are set when email or name were changed using mailmap or alias file
+	OriginalEmail string
+	OriginalName  string
 }
 
 func (s CommitUser) Validate() error {
@@ -32,5 +35,7 @@ func (s CommitUser) ToMap() map[string]interface{} {
 	res["email"] = s.Email
 	res["name"] = s.Name
 	res["source_id"] = s.SourceID
+	res["original_email"] = s.OriginalEmail
+	res["original_name"] = s.OriginalName
 	return res
 }
@@ -38,7 +38,7 @@ func decodeKey(keyHex string) (key string, _ error) {
 }
 
 func Encrypt(data []byte, keyHex string) ([]byte, error) {
-	res, err := DecryptString(string(data), keyHex)
+	res, err := EncryptString(string(data), keyHex)
 	if err != nil {
 		return nil, err
 	}
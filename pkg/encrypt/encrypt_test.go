@@ -0,0 +1,37 @@
+package encrypt
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestEncryptDecrypt(t *testing.T) {
+	key, err := GenerateKey()
+	if err != nil {
+		t.Fatal(err)
+	}
+	data := []byte("export data")
+	encrypted, err := Encrypt(data, key)
+	if err != nil {
+		t.Fatal(err)
+	}
+	assert.NotEqual(t, data, encrypted)
+	res, err := Decrypt(encrypted, key)
+	if err != nil {
+		t.Fatal(err)
+	}
+	assert.Equal(t, data, res)
+
+	otherKey, err := GenerateKey()
+	if err != nil {
+		t.Fatal(err)
+	}
+	res, err = Decrypt(encrypted, otherKey)
+	if err == nil {
+		assert.NotEqual(t, data, res)
+	}
+
+	_, err = Encrypt(data, "abc")
+	assert.Error(t, err)
+}
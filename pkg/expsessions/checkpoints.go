@@ -0,0 +1,141 @@
+package expsessions
+
+import (
+	"bufio"
+	"encoding/json"
+	"os"
+	"path/filepath"
+	"sync"
+)
+
+// Checkpoint is saved when a child session completes. Root sessions only save last processed when the complete export finishes, checkpoints allow continuing interrupted export without processing completed repos/projects again.
+type Checkpoint struct {
+	// Root is the last processed key of the root session, for example github/sourcecode.Repo
+	Root string `json:"root"`
+	// ObjectID is the id of the root session object this child belongs to, for example repo id
+	ObjectID string `json:"object_id"`
+	// Key is the last processed key of the completed child session
+	Key           string      `json:"key"`
+	LastProcessed interface{} `json:"last_processed"`
+}
+
+// CheckpointStore saves checkpoints for completed child sessions
+type CheckpointStore interface {
+	Save(Checkpoint) error
+}
+
+// CheckpointFile appends checkpoints to a file, one json object per line. File is synced after every write, so that checkpoints are kept if process is killed.
+type CheckpointFile struct {
+	f  *os.File
+	mu sync.Mutex
+}
+
+// NewCheckpointFile opens checkpoint file for appending, keeping existing checkpoints
+func NewCheckpointFile(loc string) (*CheckpointFile, error) {
+	err := os.MkdirAll(filepath.Dir(loc), 0777)
+	if err != nil {
+		return nil, err
+	}
+	s := &CheckpointFile{}
+	s.f, err = os.OpenFile(loc, os.O_APPEND|os.O_CREATE|os.O_RDWR, 0644)
+	if err != nil {
+		return nil, err
+	}
+	err = s.terminateLastLine()
+	if err != nil {
+		s.f.Close()
+		return nil, err
+	}
+	return s, nil
+}
+
+// terminateLastLine adds a newline if the last write was interrupted, so that new checkpoints are not appended to incomplete line
+func (s *CheckpointFile) terminateLastLine() error {
+	info, err := s.f.Stat()
+	if err != nil {
+		return err
+	}
+	if info.Size() == 0 {
+		return nil
+	}
+	b := make([]byte, 1)
+	_, err = s.f.ReadAt(b, info.Size()-1)
+	if err != nil {
+		return err
+	}
+	if b[0] == '\n' {
+		return nil
+	}
+	_, err = s.f.Write([]byte{'\n'})
+	return err
+}
+
+// Save appends checkpoint to file
+func (s *CheckpointFile) Save(c Checkpoint) error {
+	b, err := json.Marshal(c)
+	if err != nil {
+		return err
+	}
+	b = append(b, '\n')
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	_, err = s.f.Write(b)
+	if err != nil {
+		return err
+	}
+	return s.f.Sync()
+}
+
+// Close closes the file
+func (s *CheckpointFile) Close() error {
+	return s.f.Close()
+}
+
+// ReadCheckpoints returns all checkpoints saved in file. Returns nil if file does not exist. Incomplete last line, which could happen if process was killed while writing, is skipped.
+func ReadCheckpoints(loc string) (res []Checkpoint, _ error) {
+	f, err := os.Open(loc)
+	if err != nil {
+		if os.IsNotExist(err) {
+			return nil, nil
+		}
+		return nil, err
+	}
+	defer f.Close()
+	sc := bufio.NewScanner(f)
+	sc.Buffer(nil, 10*1024*1024)
+	for sc.Scan() {
+		var c Checkpoint
+		err := json.Unmarshal(sc.Bytes(), &c)
+		if err != nil {
+			continue
+		}
+		res = append(res, c)
+	}
+	return res, sc.Err()
+}
+
+// ApplyCheckpoints sets last processed values from checkpoints, so that they are returned for the same sessions on export restart
+func ApplyCheckpoints(store LastProcessedStore, checkpoints []Checkpoint) error {
+	for _, c := range checkpoints {
+		err := store.Set(c.LastProcessed, c.Key)
+		if err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// CheckpointObjects returns the ids of objects with at least one completed child session grouped by root session key
+func CheckpointObjects(checkpoints []Checkpoint) map[string][]string {
+	res := map[string][]string{}
+	seen := map[string]bool{}
+	for _, c := range checkpoints {
+		k := c.Root + "@" + c.ObjectID
+		if seen[k] {
+			continue
+		}
+		seen[k] = true
+		res[c.Root] = append(res[c.Root], c.ObjectID)
+	}
+	return res
+}
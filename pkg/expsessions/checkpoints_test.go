@@ -0,0 +1,122 @@
+package expsessions
+
+import (
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/hashicorp/go-hclog"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestCheckpointsResume(t *testing.T) {
+	dir, err := ioutil.TempDir("", "expsessions")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(dir)
+	loc := filepath.Join(dir, "checkpoints.jsonl")
+
+	newManager := func(lpm lastProcessedMock) (*Manager, *CheckpointFile) {
+		cf, err := NewCheckpointFile(loc)
+		if err != nil {
+			t.Fatal(err)
+		}
+		opts := Opts{}
+		opts.Logger = hclog.NewNullLogger()
+		opts.NewWriter = func(modelType string, id ID) Writer {
+			return NewMockWriter()
+		}
+		opts.LastProcessed = lpm
+		opts.Checkpoints = cf
+		return New(opts), cf
+	}
+
+	m, cf := newManager(lastProcessedMock{})
+	root, _, err := m.SessionRoot(testIn, "repo")
+	if err != nil {
+		t.Fatal(err)
+	}
+	pr1, _, err := m.Session("pr", root, "r1", "repo1")
+	if err != nil {
+		t.Fatal(err)
+	}
+	comments1, _, err := m.Session("comment", pr1, "pr1", "pr1")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := m.Done(comments1, "c1"); err != nil {
+		t.Fatal(err)
+	}
+	if err := m.Done(pr1, "t1"); err != nil {
+		t.Fatal(err)
+	}
+	// the second repo is not completed, and root is not done before export is killed
+	_, _, err = m.Session("pr", root, "r2", "repo2")
+	if err != nil {
+		t.Fatal(err)
+	}
+	cf.Close()
+
+	checkpoints, err := ReadCheckpoints(loc)
+	if err != nil {
+		t.Fatal(err)
+	}
+	assert := assert.New(t)
+	assert.Len(checkpoints, 2)
+	assert.Equal(map[string][]string{inPre + "repo": {"r1"}}, CheckpointObjects(checkpoints))
+
+	// restart with empty last processed state, since it is only saved when export finishes
+	lpm := lastProcessedMock{}
+	err = ApplyCheckpoints(lpm, checkpoints)
+	if err != nil {
+		t.Fatal(err)
+	}
+	m, cf = newManager(lpm)
+	defer cf.Close()
+	root, lastProcessed, err := m.SessionRoot(testIn, "repo")
+	if err != nil {
+		t.Fatal(err)
+	}
+	assert.Nil(lastProcessed)
+	_, lastProcessed, err = m.Session("pr", root, "r1", "repo1")
+	if err != nil {
+		t.Fatal(err)
+	}
+	assert.Equal("t1", lastProcessed)
+	_, lastProcessed, err = m.Session("pr", root, "r2", "repo2")
+	if err != nil {
+		t.Fatal(err)
+	}
+	assert.Nil(lastProcessed)
+}
+
+func TestCheckpointsIncompleteLine(t *testing.T) {
+	dir, err := ioutil.TempDir("", "expsessions")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(dir)
+	loc := filepath.Join(dir, "checkpoints.jsonl")
+
+	err = ioutil.WriteFile(loc, []byte(`{"key":"k1","last_processed":"v1"}`+"\n"+`{"key":"k2","last_`), 0644)
+	if err != nil {
+		t.Fatal(err)
+	}
+	cf, err := NewCheckpointFile(loc)
+	if err != nil {
+		t.Fatal(err)
+	}
+	err = cf.Save(Checkpoint{Key: "k3", LastProcessed: "v3"})
+	if err != nil {
+		t.Fatal(err)
+	}
+	cf.Close()
+
+	checkpoints, err := ReadCheckpoints(loc)
+	if err != nil {
+		t.Fatal(err)
+	}
+	assert.Equal(t, []Checkpoint{{Key: "k1", LastProcessed: "v1"}, {Key: "k3", LastProcessed: "v3"}}, checkpoints)
+}
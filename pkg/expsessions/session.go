@@ -16,6 +16,11 @@ type session struct {
 	sendProgress SendProgressFunc
 	parent       *session
 
+	// root is the top level session, nil for root sessions
+	root *session
+	// rootObjectID is the parentObjectID of the child of root session, for example repo id
+	rootObjectID string
+
 	ProgressPath ProgressPath
 
 	writer Writer
@@ -49,6 +54,13 @@ func newSession(
 		if parentObjectID == "" {
 			panic("parentObjectID must be set if using parent session")
 		}
+		if s.parent.root == nil {
+			s.root = s.parent
+			s.rootObjectID = parentObjectID
+		} else {
+			s.root = s.parent.root
+			s.rootObjectID = s.parent.rootObjectID
+		}
 		s.ProgressPath = s.parent.ProgressPath.Copy()
 		s.ProgressPath = append(s.ProgressPath, ProgressPathComponent{
 			ObjectID:   parentObjectID,
@@ -12,6 +12,8 @@ import (
 type Opts struct {
 	Logger        hclog.Logger
 	LastProcessed LastProcessedStore
+	// Checkpoints is optional, when set last processed of every completed child session is saved there
+	Checkpoints CheckpointStore
 
 	NewWriter NewWriterFunc
 
@@ -166,6 +168,17 @@ func (s *Manager) Done(id ID, lastProcessed interface{}) error {
 			return err
 		}
 	}
+	if s.opts.Checkpoints != nil && sess.root != nil {
+		err = s.opts.Checkpoints.Save(Checkpoint{
+			Root:          sess.root.LastProcessedKey(),
+			ObjectID:      sess.rootObjectID,
+			Key:           sess.LastProcessedKey(),
+			LastProcessed: lastProcessed,
+		})
+		if err != nil {
+			return err
+		}
+	}
 	//s.logger.Info("session done", "type", modelType, "last_processed_new", lastProcessed)
 	return nil
 }
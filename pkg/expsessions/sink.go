@@ -0,0 +1,90 @@
+package expsessions
+
+import (
+	"fmt"
+
+	hclog "github.com/hashicorp/go-hclog"
+)
+
+// Sink is a destination for exported objects. Each session gets a separate writer from every configured sink.
+type Sink interface {
+	// NewWriter returns writer for a single session. Objects written are committed on writer Close and discarded on Rollback.
+	NewWriter(modelName string, id ID) Writer
+	// Close is called once after all sessions are done.
+	Close() error
+}
+
+// NewWriterFromSinks returns NewWriterFunc that passes the same objects to writers from all sinks.
+func NewWriterFromSinks(sinks []Sink) NewWriterFunc {
+	return func(modelName string, id ID) Writer {
+		if len(sinks) == 1 {
+			return sinks[0].NewWriter(modelName, id)
+		}
+		var wr []Writer
+		for _, sink := range sinks {
+			wr = append(wr, sink.NewWriter(modelName, id))
+		}
+		return NewMultiWriter(wr)
+	}
+}
+
+// MultiWriter writes the same objects to multiple writers.
+type MultiWriter struct {
+	wr []Writer
+}
+
+// NewMultiWriter creates MultiWriter
+func NewMultiWriter(wr []Writer) *MultiWriter {
+	s := &MultiWriter{}
+	s.wr = wr
+	return s
+}
+
+func (s *MultiWriter) Write(logger hclog.Logger, objs []map[string]interface{}) error {
+	for _, wr := range s.wr {
+		err := wr.Write(logger, objs)
+		if err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// Close closes all writers, returning the first error encountered.
+func (s *MultiWriter) Close() error {
+	var rerr error
+	for _, wr := range s.wr {
+		err := wr.Close()
+		if err != nil && rerr == nil {
+			rerr = err
+		}
+	}
+	return rerr
+}
+
+// Rollback rolls back all writers, returning the first error encountered.
+func (s *MultiWriter) Rollback() error {
+	var rerr error
+	for _, wr := range s.wr {
+		err := wr.Rollback()
+		if err != nil && rerr == nil {
+			rerr = err
+		}
+	}
+	return rerr
+}
+
+// CloseSinks closes all passed sinks, returning the combined error.
+func CloseSinks(sinks []Sink) error {
+	var errs []error
+	for _, sink := range sinks {
+		err := sink.Close()
+		if err != nil {
+			errs = append(errs, err)
+		}
+	}
+	if len(errs) != 0 {
+		return fmt.Errorf("could not close sinks: %v", errs)
+	}
+	return nil
+}
@@ -0,0 +1,100 @@
+// Package expsinks contains the destinations where export sessions write objects. By default data is written only into the uploads dir, which is later sent to pinpoint backend, but it is also possible to keep a copy locally or in customer owned S3 compatible bucket.
+package expsinks
+
+import (
+	"crypto/rand"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"strconv"
+	"time"
+
+	"github.com/hashicorp/go-hclog"
+	"github.com/pinpt/agent/pkg/expsessions"
+	"github.com/pinpt/agent/pkg/fsconf"
+)
+
+// Type is the type of sink
+type Type string
+
+const (
+	// TypeUpload writes gzipped json files into uploads dir, these are later uploaded to pinpoint
+	TypeUpload Type = "upload"
+	// TypeNDJSON writes newline delimited json files into a local directory
+	TypeNDJSON Type = "ndjson"
+	// TypeSQLite writes objects into SQLite database, using one table per model name
+	TypeSQLite Type = "sqlite"
+	// TypeS3 uploads gzipped newline delimited json files into S3 compatible bucket
+	TypeS3 Type = "s3"
+)
+
+// Config is the configuration for a single sink. It is set in agent config.
+type Config struct {
+	Type Type `json:"type"`
+
+	// Dir is the output directory for ndjson sink
+	Dir string `json:"dir,omitempty"`
+
+	// Path is the database file for sqlite sink
+	Path string `json:"path,omitempty"`
+
+	// S3 contains options for s3 sink
+	S3 S3Config `json:"s3,omitempty"`
+}
+
+// Opts are common options passed to all sinks
+type Opts struct {
+	Logger hclog.Logger
+	Locs   fsconf.Locs
+}
+
+// DefaultConfigs are the sinks used when nothing is set in agent config
+var DefaultConfigs = []Config{{Type: TypeUpload}}
+
+// New creates a sink based on config
+func New(opts Opts, conf Config) (expsessions.Sink, error) {
+	switch conf.Type {
+	case TypeUpload:
+		return NewUpload(opts.Locs.Uploads), nil
+	case TypeNDJSON:
+		if conf.Dir == "" {
+			return nil, errors.New("dir is required for ndjson sink")
+		}
+		return NewNDJSON(conf.Dir), nil
+	case TypeSQLite:
+		if conf.Path == "" {
+			return nil, errors.New("path is required for sqlite sink")
+		}
+		return NewSQLite(opts.Logger, conf.Path, opts.Locs.Temp)
+	case TypeS3:
+		return NewS3(opts.Logger, conf.S3, opts.Locs.Temp)
+	}
+	return nil, fmt.Errorf("unsupported sink type: %q", conf.Type)
+}
+
+// NewAll creates all sinks from passed configs. If configs are empty, DefaultConfigs are used.
+func NewAll(opts Opts, confs []Config) (res []expsessions.Sink, rerr error) {
+	if len(confs) == 0 {
+		confs = DefaultConfigs
+	}
+	for _, conf := range confs {
+		sink, err := New(opts, conf)
+		if err != nil {
+			rerr = fmt.Errorf("could not create %v sink: %v", conf.Type, err)
+			expsessions.CloseSinks(res)
+			return
+		}
+		res = append(res, sink)
+	}
+	return
+}
+
+// sessionFileName returns the file name for session data. Time keeps files sorted by export. Session ids are only unique within one export, random suffix avoids overwriting files of another export started in the same second.
+func sessionFileName(id expsessions.ID) string {
+	b := make([]byte, 4)
+	_, err := rand.Read(b)
+	if err != nil {
+		panic(fmt.Errorf("could not read random bytes: %v", err))
+	}
+	return strconv.FormatInt(time.Now().Unix(), 10) + "_" + strconv.Itoa(int(id)) + "_" + hex.EncodeToString(b) + ".ndjson"
+}
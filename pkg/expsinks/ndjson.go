@@ -0,0 +1,169 @@
+package expsinks
+
+import (
+	"bufio"
+	"encoding/json"
+	"os"
+	"path/filepath"
+
+	"github.com/hashicorp/go-hclog"
+	"github.com/pinpt/agent/pkg/expsessions"
+)
+
+// NDJSON writes newline delimited json files into a local directory. One file per session, in a subdir named after the model.
+type NDJSON struct {
+	dir string
+}
+
+// NewNDJSON creates ndjson sink
+func NewNDJSON(dir string) *NDJSON {
+	s := &NDJSON{}
+	s.dir = dir
+	return s
+}
+
+func (s *NDJSON) NewWriter(modelName string, id expsessions.ID) expsessions.Writer {
+	base := sessionFileName(id)
+	return newNDJSONWriter(filepath.Join(s.dir, modelName, base))
+}
+
+func (s *NDJSON) Close() error {
+	return nil
+}
+
+type ndjsonWriter struct {
+	loc string
+	f   *ndjsonFile
+}
+
+func newNDJSONWriter(loc string) *ndjsonWriter {
+	s := &ndjsonWriter{}
+	s.loc = loc
+	s.f = newNDJSONFile(loc + ".temp")
+	return s
+}
+
+func (s *ndjsonWriter) Write(logger hclog.Logger, objs []map[string]interface{}) error {
+	return s.f.Write(objs)
+}
+
+func (s *ndjsonWriter) Close() error {
+	if !s.f.Created() {
+		// there was no file, since no objects were sent
+		return nil
+	}
+	err := s.f.Close()
+	if err != nil {
+		return err
+	}
+	return os.Rename(s.f.Loc(), s.loc)
+}
+
+func (s *ndjsonWriter) Rollback() error {
+	return s.f.Remove()
+}
+
+// ndjsonFile is a lazily created newline delimited json file. Used directly for ndjson sink and as a spool file for other sinks.
+type ndjsonFile struct {
+	loc   string
+	f     *os.File
+	wr    *bufio.Writer
+	enc   *json.Encoder
+	count int
+}
+
+func newNDJSONFile(loc string) *ndjsonFile {
+	s := &ndjsonFile{}
+	s.loc = loc
+	return s
+}
+
+func (s *ndjsonFile) Loc() string {
+	return s.loc
+}
+
+// Created returns true if at least one object was written and file exists
+func (s *ndjsonFile) Created() bool {
+	return s.f != nil
+}
+
+// Count returns the number of written objects
+func (s *ndjsonFile) Count() int {
+	return s.count
+}
+
+func (s *ndjsonFile) create() error {
+	err := os.MkdirAll(filepath.Dir(s.loc), 0777)
+	if err != nil {
+		return err
+	}
+	f, err := os.Create(s.loc)
+	if err != nil {
+		return err
+	}
+	s.f = f
+	s.wr = bufio.NewWriter(f)
+	s.enc = json.NewEncoder(s.wr)
+	return nil
+}
+
+func (s *ndjsonFile) Write(objs []map[string]interface{}) error {
+	if s.f == nil {
+		err := s.create()
+		if err != nil {
+			return err
+		}
+	}
+	for _, obj := range objs {
+		// Encode adds newline after every object
+		err := s.enc.Encode(obj)
+		if err != nil {
+			return err
+		}
+		s.count++
+	}
+	return nil
+}
+
+func (s *ndjsonFile) Close() error {
+	if s.f == nil {
+		return nil
+	}
+	err := s.wr.Flush()
+	if err != nil {
+		s.f.Close()
+		return err
+	}
+	return s.f.Close()
+}
+
+// Remove closes and deletes the file
+func (s *ndjsonFile) Remove() error {
+	if s.f == nil {
+		return nil
+	}
+	s.f.Close()
+	return os.Remove(s.loc)
+}
+
+// readNDJSON calls cb for every object in newline delimited json file
+func readNDJSON(loc string, cb func(obj map[string]interface{}) error) error {
+	f, err := os.Open(loc)
+	if err != nil {
+		return err
+	}
+	defer f.Close()
+	dec := json.NewDecoder(bufio.NewReader(f))
+	for dec.More() {
+		var obj map[string]interface{}
+		err := dec.Decode(&obj)
+		if err != nil {
+			return err
+		}
+		err = cb(obj)
+		if err != nil {
+			return err
+		}
+	}
+	return nil
+}
@@ -0,0 +1,92 @@
+package expsinks
+
+import (
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/hashicorp/go-hclog"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestNDJSONCloseAndRollback(t *testing.T) {
+	assert := assert.New(t)
+	dir, err := ioutil.TempDir("", "expsinks-ndjson")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(dir)
+
+	sink := NewNDJSON(dir)
+	logger := hclog.NewNullLogger()
+
+	wr1 := sink.NewWriter("m1", 1)
+	err = wr1.Write(logger, []map[string]interface{}{{"id": "1"}, {"id": "2"}})
+	assert.NoError(err)
+	assert.NoError(wr1.Close())
+
+	wr2 := sink.NewWriter("m1", 2)
+	err = wr2.Write(logger, []map[string]interface{}{{"id": "3"}})
+	assert.NoError(err)
+	assert.NoError(wr2.Rollback())
+
+	// no objects, no file
+	wr3 := sink.NewWriter("m2", 3)
+	assert.NoError(wr3.Close())
+
+	assert.NoError(sink.Close())
+
+	files, err := filepath.Glob(filepath.Join(dir, "m1", "*"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !assert.Len(files, 1) {
+		return
+	}
+	var ids []string
+	err = readNDJSON(files[0], func(obj map[string]interface{}) error {
+		ids = append(ids, obj["id"].(string))
+		return nil
+	})
+	assert.NoError(err)
+	assert.Equal([]string{"1", "2"}, ids)
+
+	exists, err := fileExists(filepath.Join(dir, "m2"))
+	assert.NoError(err)
+	assert.False(exists)
+}
+
+func fileExists(loc string) (bool, error) {
+	_, err := os.Stat(loc)
+	if os.IsNotExist(err) {
+		return false, nil
+	}
+	return err == nil, err
+}
+
+func TestNDJSONSameSessionID(t *testing.T) {
+	assert := assert.New(t)
+	dir, err := ioutil.TempDir("", "expsinks-ndjson")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(dir)
+
+	logger := hclog.NewNullLogger()
+	// session ids start from 1 in every export, exports started in the same second must not overwrite each other
+	for i := 0; i < 2; i++ {
+		sink := NewNDJSON(dir)
+		wr := sink.NewWriter("m1", 1)
+		err = wr.Write(logger, []map[string]interface{}{{"id": "1"}})
+		assert.NoError(err)
+		assert.NoError(wr.Close())
+		assert.NoError(sink.Close())
+	}
+
+	files, err := filepath.Glob(filepath.Join(dir, "m1", "*"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	assert.Len(files, 2)
+}
@@ -0,0 +1,151 @@
+package expsinks
+
+import (
+	"compress/gzip"
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"path"
+	"path/filepath"
+
+	"github.com/aws/aws-sdk-go/aws"
+	"github.com/aws/aws-sdk-go/aws/credentials"
+	"github.com/aws/aws-sdk-go/aws/session"
+	"github.com/aws/aws-sdk-go/service/s3/s3manager"
+	"github.com/hashicorp/go-hclog"
+	"github.com/pinpt/agent/pkg/expsessions"
+)
+
+// S3Config contains options for s3 sink
+type S3Config struct {
+	Bucket string `json:"bucket"`
+	// Prefix is prepended to all object keys
+	Prefix string `json:"prefix"`
+	Region string `json:"region"`
+	// Endpoint is a custom endpoint for S3 compatible storage, such as MinIO. Leave empty for AWS.
+	Endpoint string `json:"endpoint"`
+	// AccessKeyID and SecretAccessKey are optional, default AWS credential chain is used if not set
+	AccessKeyID     string `json:"access_key_id"`
+	SecretAccessKey string `json:"secret_access_key"`
+	// ForcePathStyle uses bucket in path instead of host name, required by most S3 compatible servers
+	ForcePathStyle bool `json:"force_path_style"`
+}
+
+// S3 uploads gzipped newline delimited json files into S3 compatible bucket. One object per session with prefix/model/time_id_random.ndjson.gz key.
+//
+// Objects are spooled into temp dir and uploaded on writer Close, rolled back sessions are not uploaded.
+type S3 struct {
+	logger   hclog.Logger
+	conf     S3Config
+	spoolDir string
+	uploader *s3manager.Uploader
+}
+
+// NewS3 creates s3 sink
+func NewS3(logger hclog.Logger, conf S3Config, tempDir string) (*S3, error) {
+	if conf.Bucket == "" {
+		return nil, errors.New("bucket is required for s3 sink")
+	}
+	if conf.Region == "" {
+		conf.Region = "us-east-1"
+	}
+	awsConf := &aws.Config{
+		Region:           aws.String(conf.Region),
+		S3ForcePathStyle: aws.Bool(conf.ForcePathStyle),
+	}
+	if conf.Endpoint != "" {
+		awsConf.Endpoint = aws.String(conf.Endpoint)
+	}
+	if conf.AccessKeyID != "" {
+		awsConf.Credentials = credentials.NewStaticCredentials(conf.AccessKeyID, conf.SecretAccessKey, "")
+	}
+	sess, err := session.NewSession(awsConf)
+	if err != nil {
+		return nil, fmt.Errorf("could not create aws session: %v", err)
+	}
+	s := &S3{}
+	s.logger = logger.Named("sink-s3")
+	s.conf = conf
+	s.spoolDir = filepath.Join(tempDir, "sink-s3")
+	s.uploader = s3manager.NewUploader(sess)
+	return s, nil
+}
+
+func (s *S3) NewWriter(modelName string, id expsessions.ID) expsessions.Writer {
+	base := sessionFileName(id)
+	return &s3Writer{
+		sink:  s,
+		key:   path.Join(s.conf.Prefix, modelName, base+".gz"),
+		spool: newNDJSONFile(filepath.Join(s.spoolDir, modelName, base)),
+	}
+}
+
+func (s *S3) Close() error {
+	return nil
+}
+
+func (s *S3) upload(key string, loc string) error {
+	f, err := os.Open(loc)
+	if err != nil {
+		return err
+	}
+	defer f.Close()
+
+	pr, pw := io.Pipe()
+	go func() {
+		gz := gzip.NewWriter(pw)
+		_, err := io.Copy(gz, f)
+		if err != nil {
+			pw.CloseWithError(err)
+			return
+		}
+		pw.CloseWithError(gz.Close())
+	}()
+
+	_, err = s.uploader.Upload(&s3manager.UploadInput{
+		Bucket:          aws.String(s.conf.Bucket),
+		Key:             aws.String(key),
+		Body:            pr,
+		ContentType:     aws.String("application/x-ndjson"),
+		ContentEncoding: aws.String("gzip"),
+	})
+	if err != nil {
+		// unblock gzip goroutine if upload failed before reading everything
+		pr.CloseWithError(err)
+		return err
+	}
+	s.logger.Debug("uploaded session file", "key", key)
+	return nil
+}
+
+type s3Writer struct {
+	sink  *S3
+	key   string
+	spool *ndjsonFile
+}
+
+func (s *s3Writer) Write(logger hclog.Logger, objs []map[string]interface{}) error {
+	return s.spool.Write(objs)
+}
+
+func (s *s3Writer) Close() error {
+	if !s.spool.Created() {
+		// there was no file, since no objects were sent
+		return nil
+	}
+	defer os.Remove(s.spool.Loc())
+	err := s.spool.Close()
+	if err != nil {
+		return err
+	}
+	err = s.sink.upload(s.key, s.spool.Loc())
+	if err != nil {
+		return fmt.Errorf("could not upload %v to s3: %v", s.key, err)
+	}
+	return nil
+}
+
+func (s *s3Writer) Rollback() error {
+	return s.spool.Remove()
+}
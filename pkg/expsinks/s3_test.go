@@ -0,0 +1,100 @@
+package expsinks
+
+import (
+	"bytes"
+	"compress/gzip"
+	"io/ioutil"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"sync"
+	"testing"
+
+	"github.com/hashicorp/go-hclog"
+	"github.com/stretchr/testify/assert"
+)
+
+// fakeS3 is a minimal stand-in for MinIO, supports only PutObject with path style urls
+type fakeS3 struct {
+	mu      sync.Mutex
+	objects map[string][]byte
+}
+
+func newFakeS3() *fakeS3 {
+	return &fakeS3{objects: map[string][]byte{}}
+}
+
+func (s *fakeS3) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodPut {
+		w.WriteHeader(http.StatusNotImplemented)
+		return
+	}
+	b, err := ioutil.ReadAll(r.Body)
+	if err != nil {
+		w.WriteHeader(http.StatusInternalServerError)
+		return
+	}
+	s.mu.Lock()
+	s.objects[r.URL.Path] = b
+	s.mu.Unlock()
+	w.Header().Set("ETag", `"etag"`)
+	w.WriteHeader(http.StatusOK)
+}
+
+func TestS3Upload(t *testing.T) {
+	assert := assert.New(t)
+
+	fake := newFakeS3()
+	srv := httptest.NewServer(fake)
+	defer srv.Close()
+
+	tempDir, err := ioutil.TempDir("", "expsinks-s3")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(tempDir)
+
+	logger := hclog.NewNullLogger()
+	sink, err := NewS3(logger, S3Config{
+		Bucket:          "b1",
+		Prefix:          "p1",
+		Endpoint:        srv.URL,
+		AccessKeyID:     "key",
+		SecretAccessKey: "secret",
+		ForcePathStyle:  true,
+	}, tempDir)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	wr := sink.NewWriter("m1", 1).(*s3Writer)
+	err = wr.Write(logger, []map[string]interface{}{{"id": "1"}})
+	assert.NoError(err)
+	assert.NoError(wr.Close())
+
+	wr2 := sink.NewWriter("m1", 2)
+	err = wr2.Write(logger, []map[string]interface{}{{"id": "2"}})
+	assert.NoError(err)
+	assert.NoError(wr2.Rollback())
+
+	assert.NoError(sink.Close())
+
+	fake.mu.Lock()
+	defer fake.mu.Unlock()
+	if !assert.Len(fake.objects, 1) {
+		return
+	}
+	data, ok := fake.objects["/b1/"+wr.key]
+	if !assert.True(ok, "object not found with expected key") {
+		return
+	}
+	gz, err := gzip.NewReader(bytes.NewReader(data))
+	if err != nil {
+		t.Fatal(err)
+	}
+	b, err := ioutil.ReadAll(gz)
+	if err != nil {
+		t.Fatal(err)
+	}
+	assert.Equal(`{"id":"1"}`+"\n", string(b))
+}
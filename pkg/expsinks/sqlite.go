@@ -0,0 +1,177 @@
+package expsinks
+
+import (
+	"database/sql"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/hashicorp/go-hclog"
+	"github.com/pinpt/agent/pkg/expsessions"
+)
+
+// SQLiteDriverName is the database/sql driver used for sqlite sink. Driver is only registered when agent is built with sqlite build tag, since it requires cgo.
+const SQLiteDriverName = "sqlite3"
+
+// SQLite writes objects into SQLite database, using one table per model name. Objects are upserted by id.
+//
+// Session objects are first written into a spool file in temp dir and inserted in a single transaction on writer Close. This way rolled back sessions do not leave partial data and we do not hold long write transactions, which would block other sessions.
+type SQLite struct {
+	logger   hclog.Logger
+	db       *sql.DB
+	spoolDir string
+
+	// mu serializes inserts, sqlite only supports one writer at a time
+	mu sync.Mutex
+}
+
+// NewSQLite creates sqlite sink, creating the database file if it does not exist
+func NewSQLite(logger hclog.Logger, loc string, tempDir string) (_ *SQLite, rerr error) {
+	if !sqliteDriverRegistered() {
+		rerr = errors.New("sqlite sink requires agent built with sqlite build tag")
+		return
+	}
+	err := os.MkdirAll(filepath.Dir(loc), 0777)
+	if err != nil {
+		rerr = err
+		return
+	}
+	s := &SQLite{}
+	s.logger = logger.Named("sink-sqlite")
+	s.spoolDir = filepath.Join(tempDir, "sink-sqlite")
+	s.db, err = sql.Open(SQLiteDriverName, loc)
+	if err != nil {
+		rerr = err
+		return
+	}
+	// only one connection, so that writes are serialized on the driver side as well
+	s.db.SetMaxOpenConns(1)
+	err = s.db.Ping()
+	if err != nil {
+		s.db.Close()
+		rerr = fmt.Errorf("could not open sqlite database: %v", err)
+		return
+	}
+	return s, nil
+}
+
+func sqliteDriverRegistered() bool {
+	for _, d := range sql.Drivers() {
+		if d == SQLiteDriverName {
+			return true
+		}
+	}
+	return false
+}
+
+func (s *SQLite) NewWriter(modelName string, id expsessions.ID) expsessions.Writer {
+	base := sessionFileName(id)
+	return &sqliteWriter{
+		sink:      s,
+		modelName: modelName,
+		spool:     newNDJSONFile(filepath.Join(s.spoolDir, modelName, base)),
+	}
+}
+
+func (s *SQLite) Close() error {
+	return s.db.Close()
+}
+
+func sqliteTableName(modelName string) string {
+	// model names look like sourcecode.PullRequest, use them as is but quote
+	return `"` + strings.ReplaceAll(modelName, `"`, `""`) + `"`
+}
+
+func (s *SQLite) createTableIfNeeded(tx *sql.Tx, modelName string) error {
+	q := `CREATE TABLE IF NOT EXISTS ` + sqliteTableName(modelName) + ` (
+		id TEXT PRIMARY KEY NOT NULL,
+		ref_type TEXT,
+		ref_id TEXT,
+		hashcode TEXT,
+		data TEXT NOT NULL,
+		updated_at INTEGER NOT NULL
+	)`
+	_, err := tx.Exec(q)
+	return err
+}
+
+func (s *SQLite) insertFile(modelName string, loc string) (rerr error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	tx, err := s.db.Begin()
+	if err != nil {
+		return err
+	}
+	defer func() {
+		if rerr != nil {
+			tx.Rollback()
+		}
+	}()
+	err = s.createTableIfNeeded(tx, modelName)
+	if err != nil {
+		return err
+	}
+	stmt, err := tx.Prepare(`INSERT OR REPLACE INTO ` + sqliteTableName(modelName) + ` (id, ref_type, ref_id, hashcode, data, updated_at) VALUES (?, ?, ?, ?, ?, ?)`)
+	if err != nil {
+		return err
+	}
+	defer stmt.Close()
+
+	now := time.Now().Unix()
+	err = readNDJSON(loc, func(obj map[string]interface{}) error {
+		id, _ := obj["id"].(string)
+		if id == "" {
+			return fmt.Errorf("sqlite sink: object in %v does not have id", modelName)
+		}
+		refType, _ := obj["ref_type"].(string)
+		refID, _ := obj["ref_id"].(string)
+		hashcode, _ := obj["hashcode"].(string)
+		b, err := json.Marshal(obj)
+		if err != nil {
+			return err
+		}
+		_, err = stmt.Exec(id, refType, refID, hashcode, string(b), now)
+		return err
+	})
+	if err != nil {
+		return err
+	}
+	return tx.Commit()
+}
+
+type sqliteWriter struct {
+	sink      *SQLite
+	modelName string
+	spool     *ndjsonFile
+}
+
+func (s *sqliteWriter) Write(logger hclog.Logger, objs []map[string]interface{}) error {
+	return s.spool.Write(objs)
+}
+
+func (s *sqliteWriter) Close() error {
+	if !s.spool.Created() {
+		// there was no file, since no objects were sent
+		return nil
+	}
+	defer os.Remove(s.spool.Loc())
+	err := s.spool.Close()
+	if err != nil {
+		return err
+	}
+	err = s.sink.insertFile(s.modelName, s.spool.Loc())
+	if err != nil {
+		return fmt.Errorf("could not insert %v objects into sqlite: %v", s.modelName, err)
+	}
+	return nil
+}
+
+func (s *sqliteWriter) Rollback() error {
+	return s.spool.Remove()
+}
@@ -0,0 +1,9 @@
+//go:build sqlite
+// +build sqlite
+
+package expsinks
+
+import (
+	// registers sqlite3 database/sql driver, requires cgo
+	_ "github.com/mattn/go-sqlite3"
+)
@@ -0,0 +1,69 @@
+//go:build sqlite
+// +build sqlite
+
+package expsinks
+
+import (
+	"encoding/json"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/hashicorp/go-hclog"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestSQLiteWriteAndRollback(t *testing.T) {
+	assert := assert.New(t)
+	dir, err := ioutil.TempDir("", "expsinks-sqlite")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(dir)
+
+	logger := hclog.NewNullLogger()
+	sink, err := NewSQLite(logger, filepath.Join(dir, "db", "export.db"), filepath.Join(dir, "tmp"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer sink.Close()
+
+	wr1 := sink.NewWriter("sourcecode.Repo", 1)
+	err = wr1.Write(logger, []map[string]interface{}{{"id": "1", "ref_type": "github", "ref_id": "r1", "name": "repo1"}})
+	assert.NoError(err)
+	assert.NoError(wr1.Close())
+
+	wr2 := sink.NewWriter("sourcecode.Repo", 2)
+	err = wr2.Write(logger, []map[string]interface{}{{"id": "2"}})
+	assert.NoError(err)
+	assert.NoError(wr2.Rollback())
+
+	// upsert by id
+	wr3 := sink.NewWriter("sourcecode.Repo", 3)
+	err = wr3.Write(logger, []map[string]interface{}{{"id": "1", "ref_type": "github", "ref_id": "r1", "name": "repo1b"}})
+	assert.NoError(err)
+	assert.NoError(wr3.Close())
+
+	rows, err := sink.db.Query(`SELECT id, ref_id, data FROM "sourcecode.Repo"`)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer rows.Close()
+	var got []map[string]interface{}
+	for rows.Next() {
+		var id, refID, data string
+		if err := rows.Scan(&id, &refID, &data); err != nil {
+			t.Fatal(err)
+		}
+		assert.Equal("1", id)
+		assert.Equal("r1", refID)
+		var obj map[string]interface{}
+		if err := json.Unmarshal([]byte(data), &obj); err != nil {
+			t.Fatal(err)
+		}
+		got = append(got, obj)
+	}
+	assert.NoError(rows.Err())
+	assert.Equal([]map[string]interface{}{{"id": "1", "ref_type": "github", "ref_id": "r1", "name": "repo1b"}}, got)
+}
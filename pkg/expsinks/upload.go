@@ -0,0 +1,25 @@
+package expsinks
+
+import (
+	"github.com/pinpt/agent/pkg/expsessions"
+)
+
+// Upload writes gzipped json files into uploads dir. These files are later zipped and sent to pinpoint backend in cmdupload.
+type Upload struct {
+	dir string
+}
+
+// NewUpload creates upload sink
+func NewUpload(dir string) *Upload {
+	s := &Upload{}
+	s.dir = dir
+	return s
+}
+
+func (s *Upload) NewWriter(modelName string, id expsessions.ID) expsessions.Writer {
+	return expsessions.NewFileWriter(modelName, s.dir, id)
+}
+
+func (s *Upload) Close() error {
+	return nil
+}
@@ -27,8 +27,16 @@ type Locs struct {
 	Backup                  string
 	RipsrcCheckpointsBackup string
 
+	// BundleSnapshot is the export state saved when the last bundle was created, next exports continue from it until bundle acks are imported. Uses the same layout as Backup.
+	BundleSnapshot                  string
+	RipsrcCheckpointsBundleSnapshot string
+	LastProcessedFileBundleSnapshot string
+
 	ServiceRunCrashes string
 
+	// WebhookHistory contains the last processed webhooks, used for inspecting and replaying them
+	WebhookHistory string
+
 	IntegrationsDefaultDir string
 
 	// Special files
@@ -38,12 +46,44 @@ type Locs struct {
 	LastProcessedFile       string
 	LastProcessedFileBackup string
 
-	// ExportQueueFile stores exports requests
+	// ExportCheckpointsFile stores last processed of completed child sessions, used to resume interrupted exports. Deleted when export finishes.
+	ExportCheckpointsFile string
+
+	// ExportProgressFile contains the progress of the running export, updated periodically by export command
+	ExportProgressFile string
+
+	// ExportMetricsFile contains the metrics of the running export, updated periodically by export command
+	ExportMetricsFile string
+
+	// ExportQueueFile is the journal of pending export requests
 	ExportQueueFile string
+	// ExportQueueDeadLetterFile stores export requests that failed too many times or were dropped because queue was full
+	ExportQueueDeadLetterFile string
+	// ExportQueueLegacyFile stores exports requests in the format used before journal, migrated on start
+	ExportQueueLegacyFile string
+
+	// LastExportIntegrationsFile stores integrations from the last export request received from the server, with auth encrypted as received. Used for exports queued locally and for processing webhooks after restart.
+	LastExportIntegrationsFile string
 
 	// DedupFile contains hashes of all objects sent in incrementals to avoid sending the same objects multiple times
 	DedupFile string
 
+	// IdentityGraphFile contains user identities seen in all exports, used to link identities across integrations
+	IdentityGraphFile string
+
+	// SecretFindingsFile lists secrets found and redacted in the last export, without the secret values
+	SecretFindingsFile string
+
+	// BundlePendingFile lists export bundles waiting for upload ack
+	BundlePendingFile string
+
+	// WebhookQueueFile is the journal of webhooks received by built-in webhook server, waiting for processing
+	WebhookQueueFile string
+	// WebhookQueueDeadLetterFile stores webhooks that failed too many times
+	WebhookQueueDeadLetterFile string
+	// WebhookDeliveriesFile is the journal of ids of recently received webhooks, used to skip redeliveries
+	WebhookDeliveriesFile string
+
 	// CleanupDirs are directories that will be removed on every run
 	CleanupDirs []string
 }
@@ -89,14 +129,31 @@ func New(pinpointRoot string) Locs {
 	s.RipsrcCheckpoints = j(s.State, "ripsrc_checkpoints/v3")
 	s.RipsrcCheckpointsBackup = j(s.Backup, "ripsrc_checkpoints/v3")
 
+	s.BundleSnapshot = j(s.State, "bundle_snapshot")
+	s.RipsrcCheckpointsBundleSnapshot = j(s.BundleSnapshot, "ripsrc_checkpoints/v3")
+	s.LastProcessedFileBundleSnapshot = j(s.BundleSnapshot, "last_processed.json")
+
 	s.ServiceRunCrashes = j(s.Logs, "service-run-crashes")
+	s.WebhookHistory = j(s.Logs, "webhooks")
 
 	s.IntegrationsDefaultDir = j(s.Root, "integrations")
 
 	s.Config2 = j(s.Root, "config.json")
 	s.LastProcessedFile = j(s.State, "last_processed.json")
 	s.LastProcessedFileBackup = j(s.Backup, "last_processed.json")
-	s.ExportQueueFile = j(s.State, "export_queue.json")
+	s.ExportCheckpointsFile = j(s.State, "export_checkpoints.jsonl")
+	s.ExportProgressFile = j(s.Temp, "export_progress.json")
+	s.ExportMetricsFile = j(s.Temp, "export_metrics.json")
+	s.ExportQueueFile = j(s.State, "export_queue.jsonl")
+	s.ExportQueueDeadLetterFile = j(s.State, "export_queue_dead.jsonl")
+	s.ExportQueueLegacyFile = j(s.State, "export_queue.json")
+	s.LastExportIntegrationsFile = j(s.State, "last_export_integrations.json")
 	s.DedupFile = j(s.State, "dedup_v2.json")
+	s.IdentityGraphFile = j(s.State, "identity_graph.json")
+	s.SecretFindingsFile = j(s.Logs, "secret_findings.json")
+	s.BundlePendingFile = j(s.State, "bundle_pending.json")
+	s.WebhookQueueFile = j(s.State, "webhook_queue.jsonl")
+	s.WebhookQueueDeadLetterFile = j(s.State, "webhook_queue_dead.jsonl")
+	s.WebhookDeliveriesFile = j(s.State, "webhook_deliveries.jsonl")
 	return s
 }
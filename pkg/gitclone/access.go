@@ -0,0 +1,271 @@
+package gitclone
+
+import (
+	"bytes"
+	"context"
+	"encoding/base64"
+	"errors"
+	"fmt"
+	"io/ioutil"
+	"net/url"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"regexp"
+	"strconv"
+	"strings"
+)
+
+// AccessMode selects how credentials are passed to git
+type AccessMode string
+
+const (
+	// AccessURL uses credentials included in URL. This is the default.
+	AccessURL AccessMode = ""
+	// AccessAskpass passes Username and Password to git using GIT_ASKPASS helper
+	AccessAskpass AccessMode = "askpass"
+	// AccessHeader passes Username and Password as basic auth using http.extraHeader set in environment. Requires git 2.31 or later.
+	AccessHeader AccessMode = "header"
+	// AccessSSH uses SSHKey deploy key and only connects to hosts in SSHKnownHosts
+	AccessSSH AccessMode = "ssh"
+)
+
+// ParseAccessMode returns the access mode for name, url is the same as empty
+func ParseAccessMode(name string) (AccessMode, error) {
+	switch m := AccessMode(strings.ToLower(name)); m {
+	case AccessURL, AccessAskpass, AccessHeader, AccessSSH:
+		return m, nil
+	case "url":
+		return AccessURL, nil
+	}
+	return "", fmt.Errorf("invalid git access mode: %v, supported: url, askpass, header, ssh", name)
+}
+
+type AccessDetails struct {
+	// URL is the repo url. For askpass and header modes it must not contain credentials.
+	URL  string
+	Mode AccessMode
+	// Username and Password are used with askpass and header modes
+	Username string
+	Password string
+	// SSHKey is the private key in OpenSSH or PEM format used with ssh mode
+	SSHKey string
+	// SSHKnownHosts are known_hosts lines with the allowed host keys of the server, required for ssh mode
+	SSHKnownHosts string
+}
+
+func (s AccessDetails) Validate() error {
+	switch s.Mode {
+	case AccessURL:
+	case AccessAskpass, AccessHeader:
+		u, err := url.Parse(s.URL)
+		if err != nil {
+			return err
+		}
+		if u.User != nil {
+			return fmt.Errorf("url must not contain credentials in %v access mode", s.Mode)
+		}
+		if s.Username == "" && s.Password == "" {
+			return fmt.Errorf("username or password is required in %v access mode", s.Mode)
+		}
+	case AccessSSH:
+		if s.SSHKey == "" {
+			return errors.New("SSHKey is required in ssh access mode")
+		}
+		if strings.TrimSpace(s.SSHKnownHosts) == "" {
+			return errors.New("SSHKnownHosts is required in ssh access mode")
+		}
+	default:
+		return fmt.Errorf("invalid access mode: %v", s.Mode)
+	}
+	return nil
+}
+
+// secrets returns values that must not be shown in logs or errors
+func (s AccessDetails) secrets() (res []string) {
+	if u, err := url.Parse(s.URL); err == nil && u.User != nil {
+		res = append(res, u.User.String())
+		if p, ok := u.User.Password(); ok && p != "" {
+			res = append(res, p, url.QueryEscape(p))
+		}
+		// tokens are often passed as username
+		if len(u.User.Username()) >= minSecretLen {
+			res = append(res, u.User.Username(), url.QueryEscape(u.User.Username()))
+		}
+	}
+	if s.Password != "" {
+		res = append(res, s.Password)
+	}
+	if len(s.Username) >= minSecretLen {
+		res = append(res, s.Username)
+	}
+	if s.Mode == AccessHeader {
+		res = append(res, basicAuth(s.Username, s.Password))
+	}
+	return
+}
+
+// minSecretLen is the minimum length of username to redact, to avoid replacing common usernames such as git or oauth2
+const minSecretLen = 8
+
+// redactSecrets replaces all secrets in text
+func redactSecrets(text string, secrets []string) string {
+	for _, v := range secrets {
+		if v == "" {
+			continue
+		}
+		text = strings.Replace(text, v, "[redacted]", -1)
+	}
+	return text
+}
+
+func basicAuth(username, password string) string {
+	return base64.StdEncoding.EncodeToString([]byte(username + ":" + password))
+}
+
+// auth contains the environment for git commands and the secrets to redact from their output
+type auth struct {
+	env     []string
+	secrets []string
+	tempDir string
+}
+
+// newAuth prepares git environment for access. Call Close to remove temp files with credentials.
+func newAuth(ctx context.Context, access AccessDetails) (_ *auth, rerr error) {
+	err := access.Validate()
+	if err != nil {
+		return nil, err
+	}
+	s := &auth{}
+	s.secrets = access.secrets()
+	// never wait for user input
+	s.env = append(s.env, "GIT_TERMINAL_PROMPT=0")
+	defer func() {
+		if rerr != nil {
+			s.Close()
+		}
+	}()
+	switch access.Mode {
+	case AccessAskpass:
+		err := s.writeTempFile("askpass.sh", askpassScript, 0700)
+		if err != nil {
+			return nil, err
+		}
+		s.env = append(s.env,
+			"GIT_ASKPASS="+filepath.Join(s.tempDir, "askpass.sh"),
+			"PINPOINT_GIT_USERNAME="+access.Username,
+			"PINPOINT_GIT_PASSWORD="+access.Password)
+	case AccessHeader:
+		err := checkGitVersion(ctx, 2, 31)
+		if err != nil {
+			return nil, fmt.Errorf("header access mode: %v", err)
+		}
+		// config from environment is not visible in process list, unlike -c args
+		s.env = append(s.env,
+			"GIT_CONFIG_COUNT=1",
+			"GIT_CONFIG_KEY_0=http.extraHeader",
+			"GIT_CONFIG_VALUE_0=Authorization: Basic "+basicAuth(access.Username, access.Password))
+	case AccessSSH:
+		key := access.SSHKey
+		if !strings.HasSuffix(key, "\n") {
+			// ssh fails to load keys without final newline
+			key += "\n"
+		}
+		err := s.writeTempFile("id", key, 0600)
+		if err != nil {
+			return nil, err
+		}
+		err = s.writeTempFile("known_hosts", access.SSHKnownHosts, 0600)
+		if err != nil {
+			return nil, err
+		}
+		s.env = append(s.env, "GIT_SSH_COMMAND="+sshCommand(s.tempDir))
+	}
+	return s, nil
+}
+
+const askpassScript = `#!/bin/sh
+case "$1" in
+Username*) printf '%s\n' "$PINPOINT_GIT_USERNAME" ;;
+*) printf '%s\n' "$PINPOINT_GIT_PASSWORD" ;;
+esac
+`
+
+// sshCommand returns ssh command that only uses the deploy key and fails for hosts not in known_hosts
+func sshCommand(dir string) string {
+	q := func(s string) string {
+		return "'" + filepath.ToSlash(s) + "'"
+	}
+	return strings.Join([]string{
+		"ssh",
+		"-F", "none",
+		"-i", q(filepath.Join(dir, "id")),
+		"-o", "IdentitiesOnly=yes",
+		"-o", "UserKnownHostsFile=" + q(filepath.Join(dir, "known_hosts")),
+		"-o", "GlobalKnownHostsFile=/dev/null",
+		"-o", "StrictHostKeyChecking=yes",
+		"-o", "BatchMode=yes",
+	}, " ")
+}
+
+func (s *auth) writeTempFile(name string, content string, perm os.FileMode) error {
+	if s.tempDir == "" {
+		dir, err := ioutil.TempDir("", "pinpoint-git-")
+		if err != nil {
+			return err
+		}
+		s.tempDir = dir
+	}
+	return ioutil.WriteFile(filepath.Join(s.tempDir, name), []byte(content), perm)
+}
+
+// Close removes temp files
+func (s *auth) Close() error {
+	if s == nil || s.tempDir == "" {
+		return nil
+	}
+	return os.RemoveAll(s.tempDir)
+}
+
+// setup sets environment for the command
+func (s *auth) setup(cmd *exec.Cmd) {
+	if s == nil {
+		return
+	}
+	cmd.Env = append(os.Environ(), s.env...)
+}
+
+// redact removes secrets from text
+func (s *auth) redact(text string) string {
+	if s == nil {
+		return text
+	}
+	return redactSecrets(text, s.secrets)
+}
+
+var gitVersionRe = regexp.MustCompile(`git version (\d+)\.(\d+)`)
+
+func checkGitVersion(ctx context.Context, major, minor int) error {
+	out := bytes.NewBuffer(nil)
+	cmd := exec.CommandContext(ctx, "git", "version")
+	cmd.Stdout = out
+	err := cmd.Run()
+	if err != nil {
+		return fmt.Errorf("could not get git version: %v", err)
+	}
+	return gitVersionError(out.String(), major, minor)
+}
+
+// gitVersionError returns an error if git version output is older than major.minor
+func gitVersionError(versionOutput string, major, minor int) error {
+	m := gitVersionRe.FindStringSubmatch(versionOutput)
+	if m == nil {
+		return fmt.Errorf("could not parse git version: %v", versionOutput)
+	}
+	gotMajor, _ := strconv.Atoi(m[1])
+	gotMinor, _ := strconv.Atoi(m[2])
+	if gotMajor < major || gotMajor == major && gotMinor < minor {
+		return fmt.Errorf("git %v.%v or later is required, installed %v.%v", major, minor, gotMajor, gotMinor)
+	}
+	return nil
+}
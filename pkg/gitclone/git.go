@@ -3,9 +3,7 @@ package gitclone
 import (
 	"bytes"
 	"context"
-	"errors"
 	"fmt"
-	"net/url"
 	"os"
 	"os/exec"
 	"path/filepath"
@@ -17,10 +15,6 @@ import (
 	"github.com/pinpt/go-common/v10/fileutil"
 )
 
-type AccessDetails struct {
-	URL string
-}
-
 type Dirs struct {
 	CacheRoot string
 }
@@ -95,22 +89,33 @@ func cloneWithCacheNoRetries(ctx context.Context, logger hclog.Logger, access Ac
 
 	cacheDir := filepath.Join(dirs.CacheRoot, cacheDirName)
 
+	a, err := newAuth(ctx, access)
+	if err != nil {
+		rerr = err
+		return
+	}
+	defer a.Close()
+
 	if !fileutil.FileExists(cacheDir) {
 		logger.Info("git clone if exist")
-		err := cloneFreshIntoCache(ctx, logger, access, dirs, cacheDirName)
+		opStarted := time.Now()
+		err := cloneFreshIntoCache(ctx, logger, access, a, dirs, cacheDirName)
+		observeGitDuration("clone", opStarted, err)
 		if err != nil {
 			rerr = err
 			return
 		}
 	} else {
 		logger.Info("git clone updating credentials")
-		err := updateCredentials(ctx, logger, access, dirs, cacheDirName)
+		err := updateCredentials(ctx, logger, access, a, dirs, cacheDirName)
 		if err != nil {
 			rerr = err
 			return
 		}
 		logger.Info("git clone updating credentials")
-		err = updateClonedRepo(ctx, logger, access, dirs, cacheDirName)
+		opStarted := time.Now()
+		err = updateClonedRepo(ctx, logger, access, a, dirs, cacheDirName)
+		observeGitDuration("fetch", opStarted, err)
 		if err != nil {
 			rerr = err
 			return
@@ -120,19 +125,19 @@ func cloneWithCacheNoRetries(ctx context.Context, logger hclog.Logger, access Ac
 	return
 }
 
-func updateCredentials(ctx context.Context, logger hclog.Logger, access AccessDetails, dirs Dirs, cacheDirName string) error {
+func updateCredentials(ctx context.Context, logger hclog.Logger, access AccessDetails, a *auth, dirs Dirs, cacheDirName string) error {
 	logger.Debug("updateCredentials")
 	cacheDir := filepath.Join(dirs.CacheRoot, cacheDirName)
 	cmd := exec.CommandContext(ctx, "git", "remote", "set-url", "origin", access.URL)
 	cmd.Dir = cacheDir
-	err := runGitCommand(ctx, logger, cmd)
+	err := runGitCommand(ctx, logger, a, cmd)
 	if err != nil {
 		return err
 	}
 	return nil
 }
 
-func cloneFreshIntoCache(ctx context.Context, logger hclog.Logger, access AccessDetails, dirs Dirs, cacheDirName string) error {
+func cloneFreshIntoCache(ctx context.Context, logger hclog.Logger, access AccessDetails, a *auth, dirs Dirs, cacheDirName string) error {
 	logger.Debug("cloneFreshIntoCache")
 	cloneStarted := time.Now()
 	tempDir := filepath.Join(dirs.CacheRoot, "tmp", cacheDirName)
@@ -144,22 +149,18 @@ func cloneFreshIntoCache(ctx context.Context, logger hclog.Logger, access Access
 
 	args = append(args, cloneArgs(access.URL)...)
 	cmd := exec.CommandContext(ctx, "git", args...)
-	err = runGitCommand(ctx, logger, cmd)
+	err = runGitCommand(ctx, logger, a, cmd)
 	if err != nil {
-		output, err := RedactCredsInText(err.Error(), access.URL)
-		if err != nil {
-			return err
-		}
-		return errors.New(output)
+		return err
 	}
 	// set the git config, so the further updates would use the same config as initial clone
-	err = setRepoConfig(ctx, logger, access.URL, tempDir)
+	err = setRepoConfig(ctx, logger, a, access.URL, tempDir)
 	if err != nil {
 		return err
 	}
 	if time.Since(cloneStarted) > time.Duration(30)*time.Second {
 		logger.Debug("running git gc because clone took >30s", "duration", time.Since(cloneStarted))
-		err := gitRunGCForLongClone(ctx, logger, tempDir)
+		err := gitRunGCForLongClone(ctx, logger, a, tempDir)
 		if err != nil {
 			return err
 		}
@@ -168,12 +169,12 @@ func cloneFreshIntoCache(ctx context.Context, logger hclog.Logger, access Access
 	return os.Rename(tempDir, filepath.Join(dirs.CacheRoot, cacheDirName))
 }
 
-func updateClonedRepo(ctx context.Context, logger hclog.Logger, access AccessDetails, dirs Dirs, cacheDirName string) error {
+func updateClonedRepo(ctx context.Context, logger hclog.Logger, access AccessDetails, a *auth, dirs Dirs, cacheDirName string) error {
 	logger.Debug("updateClonedRepo")
 	cacheDir := filepath.Join(dirs.CacheRoot, cacheDirName)
 	cmd := exec.CommandContext(ctx, "git", "remote", "update", "--prune")
 	cmd.Dir = cacheDir
-	err := runGitCommand(ctx, logger, cmd)
+	err := runGitCommand(ctx, logger, a, cmd)
 	if err != nil {
 		return err
 	}
@@ -184,21 +185,21 @@ func updateClonedRepo(ctx context.Context, logger hclog.Logger, access AccessDet
 	// in the mirror and if it fails, we just blow away and start clean
 	cmd = exec.CommandContext(ctx, "git", "log", "-n", "1")
 	cmd.Dir = cacheDir
-	err = runGitCommand(ctx, logger, cmd)
+	err = runGitCommand(ctx, logger, a, cmd)
 	if err != nil {
 		logger.Info("detected a git mirror which needs to be updated, will do a fresh reclone")
 		os.RemoveAll(cacheDir)
-		return cloneFreshIntoCache(ctx, logger, access, dirs, cacheDirName)
+		return cloneFreshIntoCache(ctx, logger, access, a, dirs, cacheDirName)
 	}
 
 	return nil
 }
 
-func gitRunGCForLongClone(ctx context.Context, logger hclog.Logger, dir string) error {
+func gitRunGCForLongClone(ctx context.Context, logger hclog.Logger, a *auth, dir string) error {
 	cmd := exec.CommandContext(ctx, "git", "gc")
 	cmd.Dir = dir
 	gcStarted := time.Now()
-	err := runGitCommand(ctx, logger, cmd)
+	err := runGitCommand(ctx, logger, a, cmd)
 	if err != nil {
 		return err
 	}
@@ -206,16 +207,19 @@ func gitRunGCForLongClone(ctx context.Context, logger hclog.Logger, dir string)
 	return nil
 }
 
-func runGitCommand(ctx context.Context, logger hclog.Logger, cmd *exec.Cmd) error {
-	var stderr bytes.Buffer
-	cmd.Stderr = &stderr
+// runGitCommand runs git with auth environment, secrets are redacted from the output included in returned error
+func runGitCommand(ctx context.Context, logger hclog.Logger, a *auth, cmd *exec.Cmd) error {
+	var output bytes.Buffer
+	cmd.Stdout = &output
+	cmd.Stderr = &output
+	a.setup(cmd)
 	if err := cmd.Run(); err != nil {
 		c := ""
 		if len(cmd.Args) > 2 {
 			c = cmd.Args[1]
 		}
-		//logger.Info("git command failed", "command", c, "output", stderr.String())
-		return fmt.Errorf("git command failed, command %v, output %v", c, stderr.String())
+		//logger.Info("git command failed", "command", c, "output", output.String())
+		return fmt.Errorf("git command failed, command %v, output %v", c, a.redact(output.String()))
 	}
 	return nil
 }
@@ -242,14 +246,14 @@ func cloneArgs(repoURL string) (args []string) {
 	return
 }
 
-func setRepoConfig(ctx context.Context, logger hclog.Logger, repoURL, repoDir string) error {
+func setRepoConfig(ctx context.Context, logger hclog.Logger, a *auth, repoURL, repoDir string) error {
 
 	conf := makeConfig(repoURL)
 
 	for k, v := range conf {
 		cmd := exec.CommandContext(ctx, "git", "config", k, v)
 		cmd.Dir = repoDir
-		err := runGitCommand(ctx, logger, cmd)
+		err := runGitCommand(ctx, logger, a, cmd)
 		if err != nil {
 			return err
 		}
@@ -262,15 +266,3 @@ var alphaNumericRe = regexp.MustCompile(`[^a-zA-Z\d]`)
 func escapeForFS(name string) string {
 	return alphaNumericRe.ReplaceAllString(name, "-")
 }
-
-func RedactCredsInText(text string, urlStr string) (redactedText string, _ error) {
-	u, err := url.Parse(urlStr)
-	if err != nil {
-		return "", err
-	}
-	if u.User.String() == "" {
-		return text, nil
-	}
-	res := strings.Replace(text, u.User.String(), "[redacted]", -1)
-	return res, nil
-}
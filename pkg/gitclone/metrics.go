@@ -0,0 +1,17 @@
+package gitclone
+
+import (
+	"time"
+
+	"github.com/pinpt/agent/pkg/metrics"
+)
+
+var gitDuration = metrics.NewHistogram("pinpoint_agent_git_duration_seconds", "Duration of git clone and fetch into repo cache by op and outcome", metrics.LongBuckets)
+
+func observeGitDuration(op string, started time.Time, err error) {
+	outcome := "success"
+	if err != nil {
+		outcome = "error"
+	}
+	gitDuration.Observe(time.Since(started).Seconds(), metrics.Labels{"op": op, "outcome": outcome})
+}
@@ -62,11 +62,6 @@ func TestClone(logger hclog.Logger, url string, tempDirRoot string) error {
 		return err
 	}
 
-	outputStr, err := RedactCredsInText(string(output), url)
-	if err != nil {
-		return err
-	}
-
-	return errors.New(outputStr)
+	return errors.New(redactSecrets(string(output), AccessDetails{URL: url}.secrets()))
 }
 */
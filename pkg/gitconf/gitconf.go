@@ -0,0 +1,28 @@
+// Package gitconf contains agent config options for processing git repos.
+package gitconf
+
+import "time"
+
+// Config contains options for processing git repos
+type Config struct {
+	// CommitFiles enables export of per file stats for commits. Disabled by default, since it takes a lot more time on large repos.
+	CommitFiles bool `json:"commit_files"`
+	// Tags enables export of tags and releases.
+	Tags bool `json:"tags"`
+	// DisableMailmap disables mapping of commit authors using .mailmap file from the repo.
+	DisableMailmap bool `json:"disable_mailmap"`
+	// AliasFile is a path to file in .mailmap format with author aliases applied to all repos.
+	AliasFile string `json:"alias_file"`
+	// Ownership enables export of blame based code ownership snapshot of default branch. Disabled by default, since blame is slow on large repos.
+	Ownership bool `json:"ownership"`
+	// OwnershipIntervalHours is the minimum time between ownership snapshots of the same repo, defaults to 24.
+	OwnershipIntervalHours int `json:"ownership_interval_hours"`
+}
+
+// OwnershipInterval returns the minimum time between ownership snapshots
+func (s Config) OwnershipInterval() time.Duration {
+	if s.OwnershipIntervalHours <= 0 {
+		return 24 * time.Hour
+	}
+	return time.Duration(s.OwnershipIntervalHours) * time.Hour
+}
@@ -0,0 +1,127 @@
+// Package gittags contains the models for exported git tags and releases.
+package gittags
+
+import (
+	"encoding/json"
+)
+
+const TagTableName = "sourcecode.Tag"
+
+const ReleaseTableName = "sourcecode.Release"
+
+// Date is the same as date fields in integration-sdk, use date.ConvertToModel to set it
+type Date struct {
+	Epoch   int64
+	Offset  int64
+	Rfc3339 string
+}
+
+func (s Date) ToMap() map[string]interface{} {
+	res := map[string]interface{}{}
+	res["epoch"] = s.Epoch
+	res["offset"] = s.Offset
+	res["rfc3339"] = s.Rfc3339
+	return res
+}
+
+// Tag is a git tag pointing to a commit
+type Tag struct {
+	ID         string
+	CustomerID string
+	RefType    string
+	RepoID     string
+	Name       string
+	CommitSha  string
+	CommitID   string
+	// Annotated is true for tags with tag object, Tagger and Message are only set for these
+	Annotated        bool
+	TagSha           string
+	TaggerRefID      string
+	TaggerName       string
+	TaggerEmail      string
+	Message          string
+	CreatedDate      Date
+	Semver           string
+	SemverMajor      int
+	SemverMinor      int
+	SemverPatch      int
+	SemverPrerelease string
+	// Deleted is set for tags deleted since the last export, only id and name are set in that case
+	Deleted bool
+}
+
+func (s Tag) Stringify() string {
+	b, _ := json.Marshal(s.ToMap())
+	return string(b)
+}
+
+func (s Tag) ToMap() map[string]interface{} {
+	res := map[string]interface{}{}
+	res["id"] = s.ID
+	res["customer_id"] = s.CustomerID
+	res["ref_type"] = s.RefType
+	res["repo_id"] = s.RepoID
+	res["name"] = s.Name
+	res["commit_sha"] = s.CommitSha
+	res["commit_id"] = s.CommitID
+	res["annotated"] = s.Annotated
+	res["tag_sha"] = s.TagSha
+	res["tagger_ref_id"] = s.TaggerRefID
+	res["tagger_name"] = s.TaggerName
+	res["tagger_email"] = s.TaggerEmail
+	res["message"] = s.Message
+	res["created_date"] = s.CreatedDate.ToMap()
+	res["semver"] = s.Semver
+	res["semver_major"] = s.SemverMajor
+	res["semver_minor"] = s.SemverMinor
+	res["semver_patch"] = s.SemverPatch
+	res["semver_prerelease"] = s.SemverPrerelease
+	res["deleted"] = s.Deleted
+	return res
+}
+
+// Release is a version tag on the default branch with commits included since the previous release
+type Release struct {
+	ID         string
+	CustomerID string
+	RefType    string
+	RepoID     string
+	TagName    string
+	TagID      string
+	Version    string
+	CommitSha  string
+	CommitID   string
+	// PreviousTagName is the tag of the previous release, empty for the first release
+	PreviousTagName string
+	PreviousTagID   string
+	ReleasedDate    Date
+	CommitShas      []string
+	CommitIds       []string
+	// Deleted is set for releases whose tag was deleted since the last export, only id and tag name are set in that case
+	Deleted bool
+}
+
+func (s Release) Stringify() string {
+	b, _ := json.Marshal(s.ToMap())
+	return string(b)
+}
+
+func (s Release) ToMap() map[string]interface{} {
+	res := map[string]interface{}{}
+	res["id"] = s.ID
+	res["customer_id"] = s.CustomerID
+	res["ref_type"] = s.RefType
+	res["repo_id"] = s.RepoID
+	res["tag_name"] = s.TagName
+	res["tag_id"] = s.TagID
+	res["version"] = s.Version
+	res["commit_sha"] = s.CommitSha
+	res["commit_id"] = s.CommitID
+	res["previous_tag_name"] = s.PreviousTagName
+	res["previous_tag_id"] = s.PreviousTagID
+	res["released_date"] = s.ReleasedDate.ToMap()
+	res["commit_shas"] = s.CommitShas
+	res["commit_ids"] = s.CommitIds
+	res["deleted"] = s.Deleted
+	return res
+}
@@ -0,0 +1,187 @@
+// Package identity links user identities from different integrations, for example github user, git commit author and jira user of the same person.
+package identity
+
+import (
+	"bytes"
+	"encoding/json"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+	"sync"
+
+	"github.com/pinpt/agent/pkg/fs"
+)
+
+// Identity is a single user object exported by integration, with all values seen for it across exports
+type Identity struct {
+	CustomerID string   `json:"customer_id"`
+	RefType    string   `json:"ref_type"`
+	RefID      string   `json:"ref_id"`
+	Emails     []string `json:"emails,omitempty"`
+	Logins     []string `json:"logins,omitempty"`
+	Names      []string `json:"names,omitempty"`
+	// AssociatedRefIDs are ref ids of users in other integrations, set for git commit users linked by integration
+	AssociatedRefIDs []string `json:"associated_ref_ids,omitempty"`
+}
+
+// Key returns unique key of identity
+func (s Identity) Key() string {
+	return s.RefType + "/" + s.RefID
+}
+
+func (s *Identity) merge(o Identity) {
+	s.Emails = appendUnique(s.Emails, o.Emails...)
+	s.Logins = appendUnique(s.Logins, o.Logins...)
+	s.Names = appendUnique(s.Names, o.Names...)
+	s.AssociatedRefIDs = appendUnique(s.AssociatedRefIDs, o.AssociatedRefIDs...)
+}
+
+func appendUnique(arr []string, vals ...string) []string {
+	for _, v := range vals {
+		if v == "" {
+			continue
+		}
+		found := false
+		for _, v2 := range arr {
+			if v == v2 {
+				found = true
+				break
+			}
+		}
+		if !found {
+			arr = append(arr, v)
+		}
+	}
+	return arr
+}
+
+// Graph contains all identities seen in exports. It is persisted, since incremental exports only send changed users. Safe for concurrent use.
+type Graph struct {
+	loc        string
+	identities map[string]*Identity
+	mu         sync.Mutex
+}
+
+// NewGraph loads graph from loc, if file does not exist returns empty graph
+func NewGraph(loc string) (*Graph, error) {
+	s := &Graph{}
+	s.loc = loc
+	s.identities = map[string]*Identity{}
+	b, err := ioutil.ReadFile(loc)
+	if os.IsNotExist(err) {
+		return s, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+	var data []*Identity
+	err = json.Unmarshal(b, &data)
+	if err != nil {
+		return nil, err
+	}
+	for _, id := range data {
+		s.identities[id.Key()] = id
+	}
+	return s, nil
+}
+
+// Add adds identity to graph, merging values if identity already exists
+func (s *Graph) Add(id Identity) {
+	if id.RefType == "" || id.RefID == "" {
+		return
+	}
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if ex, ok := s.identities[id.Key()]; ok {
+		ex.merge(id)
+		return
+	}
+	n := &Identity{CustomerID: id.CustomerID, RefType: id.RefType, RefID: id.RefID}
+	n.merge(id)
+	s.identities[id.Key()] = n
+}
+
+// AddObject adds identity from exported user object. Objects that are not users are ignored.
+func (s *Graph) AddObject(modelName string, data map[string]interface{}) {
+	id, ok := FromObject(modelName, data)
+	if !ok {
+		return
+	}
+	s.Add(id)
+}
+
+// Identities returns all identities sorted by key
+func (s *Graph) Identities() (res []Identity) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	for _, id := range s.identities {
+		res = append(res, *id)
+	}
+	sort.Slice(res, func(i, j int) bool {
+		return res[i].Key() < res[j].Key()
+	})
+	return
+}
+
+// Save writes graph to file
+func (s *Graph) Save() error {
+	b, err := json.Marshal(s.Identities())
+	if err != nil {
+		return err
+	}
+	err = os.MkdirAll(filepath.Dir(s.loc), 0777)
+	if err != nil {
+		return err
+	}
+	return fs.WriteToTempAndRename(bytes.NewReader(b), s.loc)
+}
+
+// userModels are models containing users, all use the same field names
+var userModels = map[string]bool{
+	"sourcecode.User": true,
+	"work.User":       true,
+	"calendar.User":   true,
+}
+
+// FromObject returns identity for exported user object
+func FromObject(modelName string, data map[string]interface{}) (res Identity, ok bool) {
+	if !userModels[modelName] {
+		return
+	}
+	str := func(k string) string {
+		switch v := data[k].(type) {
+		case string:
+			return v
+		case *string:
+			if v != nil {
+				return *v
+			}
+		}
+		return ""
+	}
+	res.CustomerID = str("customer_id")
+	res.RefType = str("ref_type")
+	res.RefID = str("ref_id")
+	if res.RefType == "" || res.RefID == "" {
+		return
+	}
+	if v := normalizeEmail(str("email")); v != "" {
+		res.Emails = []string{v}
+	}
+	if v := strings.ToLower(strings.TrimSpace(str("username"))); v != "" {
+		res.Logins = []string{v}
+	}
+	if v := strings.TrimSpace(str("name")); v != "" {
+		res.Names = []string{v}
+	}
+	if v := str("associated_ref_id"); v != "" {
+		res.AssociatedRefIDs = []string{v}
+	}
+	return res, true
+}
+
+func normalizeEmail(email string) string {
+	return strings.ToLower(strings.TrimSpace(email))
+}
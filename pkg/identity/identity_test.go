@@ -0,0 +1,146 @@
+package identity
+
+import (
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestFromObject(t *testing.T) {
+	assert := assert.New(t)
+	email := "John@Example.com"
+	_, ok := FromObject("work.Issue", map[string]interface{}{"ref_type": "jira", "ref_id": "1"})
+	assert.False(ok)
+	id, ok := FromObject("sourcecode.User", map[string]interface{}{
+		"customer_id": "c1",
+		"ref_type":    "github",
+		"ref_id":      "u1",
+		"email":       &email,
+		"username":    "JohnD",
+		"name":        "John Doe",
+	})
+	assert.True(ok)
+	assert.Equal(Identity{CustomerID: "c1", RefType: "github", RefID: "u1", Emails: []string{"john@example.com"}, Logins: []string{"johnd"}, Names: []string{"John Doe"}}, id)
+}
+
+func TestResolve(t *testing.T) {
+	assert := assert.New(t)
+	ids := []Identity{
+		{RefType: "github", RefID: "u1", Logins: []string{"johnd"}, Names: []string{"John Doe"}},
+		{RefType: "git", RefID: "e1", Emails: []string{"123+johnd@users.noreply.github.com"}},
+		{RefType: "git", RefID: "e2", Emails: []string{"john@example.com"}, AssociatedRefIDs: []string{"u1"}},
+		{RefType: "jira", RefID: "j1", Emails: []string{"john@example.com"}, Names: []string{"john  doe"}},
+		{RefType: "gitlab", RefID: "u2"},
+		{RefType: "git", RefID: "e3", AssociatedRefIDs: []string{"u2"}},
+		{RefType: "bitbucket", RefID: "u2"},
+	}
+	links, err := Resolve(ids, Rules{}, "c1")
+	if err != nil {
+		t.Fatal(err)
+	}
+	got := map[string]string{}
+	for _, l := range links {
+		got[l.FromRefType+"/"+l.FromRefID+"-"+l.ToRefType+"/"+l.ToRefID] = l.Rule
+	}
+	assert.Equal(map[string]string{
+		"git/e1-github/u1": RuleNoreply,
+		"git/e2-github/u1": RuleAssociatedRefID,
+		"git/e2-jira/j1":   RuleEmail,
+	}, got)
+
+	links, err = Resolve(ids, Rules{MatchNames: true}, "c1")
+	if err != nil {
+		t.Fatal(err)
+	}
+	assert.Len(links, 4)
+}
+
+func TestResolveAliases(t *testing.T) {
+	dir, err := ioutil.TempDir("", "identity")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(dir)
+	loc := filepath.Join(dir, "aliases")
+	err = ioutil.WriteFile(loc, []byte("# comment\njohn@example.com, jd@old.example.com\n"), 0666)
+	if err != nil {
+		t.Fatal(err)
+	}
+	ids := []Identity{
+		{RefType: "git", RefID: "e1", Emails: []string{"john@example.com"}},
+		{RefType: "git", RefID: "e2", Emails: []string{"jd@old.example.com"}},
+	}
+	links, err := Resolve(ids, Rules{AliasesFile: loc}, "c1")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if assert.Len(t, links, 1) {
+		assert.Equal(t, RuleAlias, links[0].Rule)
+		assert.Equal(t, ConfidenceAlias, links[0].Confidence)
+	}
+}
+
+func TestResolveLogins(t *testing.T) {
+	assert := assert.New(t)
+	ids := []Identity{
+		{RefType: "github", RefID: "u1", Logins: []string{"johnd"}},
+		{RefType: "gitlab", RefID: "u2", Logins: []string{"JohnD"}},
+		{RefType: "git", RefID: "e1", Emails: []string{"johnd@users.noreply.github.com"}},
+	}
+	links, err := Resolve(ids, Rules{}, "c1")
+	if err != nil {
+		t.Fatal(err)
+	}
+	got := map[string]string{}
+	for _, l := range links {
+		got[l.FromRefType+"/"+l.FromRefID+"-"+l.ToRefType+"/"+l.ToRefID] = l.Rule
+	}
+	assert.Equal(map[string]string{
+		"git/e1-github/u1": RuleNoreply,
+		"git/e1-gitlab/u2": RuleNoreply,
+	}, got)
+
+	links, err = Resolve(ids, Rules{MatchLogins: true}, "c1")
+	if err != nil {
+		t.Fatal(err)
+	}
+	got = map[string]string{}
+	for _, l := range links {
+		got[l.FromRefType+"/"+l.FromRefID+"-"+l.ToRefType+"/"+l.ToRefID] = l.Rule
+		if l.Rule == RuleLogin {
+			assert.Equal(ConfidenceLogin, l.Confidence)
+		}
+	}
+	assert.Equal(map[string]string{
+		"git/e1-github/u1":    RuleNoreply,
+		"git/e1-gitlab/u2":    RuleNoreply,
+		"github/u1-gitlab/u2": RuleLogin,
+	}, got)
+}
+
+func TestGraphSave(t *testing.T) {
+	dir, err := ioutil.TempDir("", "identity")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(dir)
+	loc := filepath.Join(dir, "graph.json")
+	g, err := NewGraph(loc)
+	if err != nil {
+		t.Fatal(err)
+	}
+	g.Add(Identity{RefType: "git", RefID: "e1", Names: []string{"a"}})
+	g.Add(Identity{RefType: "git", RefID: "e1", Names: []string{"b"}})
+	err = g.Save()
+	if err != nil {
+		t.Fatal(err)
+	}
+	g, err = NewGraph(loc)
+	if err != nil {
+		t.Fatal(err)
+	}
+	assert.Equal(t, []Identity{{RefType: "git", RefID: "e1", Names: []string{"a", "b"}}}, g.Identities())
+}
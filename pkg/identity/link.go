@@ -0,0 +1,51 @@
+package identity
+
+import (
+	"github.com/pinpt/go-common/v10/hash"
+)
+
+// LinkModelName is the model name of exported links
+const LinkModelName = "identity.Link"
+
+// Link links two identities that likely belong to the same person
+type Link struct {
+	ID          string `json:"id"`
+	CustomerID  string `json:"customer_id"`
+	FromRefType string `json:"from_ref_type"`
+	FromRefID   string `json:"from_ref_id"`
+	ToRefType   string `json:"to_ref_type"`
+	ToRefID     string `json:"to_ref_id"`
+	// Rule is the name of the rule with highest confidence that matched
+	Rule string `json:"rule"`
+	// Confidence is from 0 to 1
+	Confidence float64 `json:"confidence"`
+}
+
+func newLink(customerID string, a, b Identity, rule string, confidence float64) Link {
+	if b.Key() < a.Key() {
+		a, b = b, a
+	}
+	res := Link{}
+	res.ID = hash.Values("IdentityLink", customerID, a.Key(), b.Key())
+	res.CustomerID = customerID
+	res.FromRefType = a.RefType
+	res.FromRefID = a.RefID
+	res.ToRefType = b.RefType
+	res.ToRefID = b.RefID
+	res.Rule = rule
+	res.Confidence = confidence
+	return res
+}
+
+func (s Link) ToMap() map[string]interface{} {
+	return map[string]interface{}{
+		"id":            s.ID,
+		"customer_id":   s.CustomerID,
+		"from_ref_type": s.FromRefType,
+		"from_ref_id":   s.FromRefID,
+		"to_ref_type":   s.ToRefType,
+		"to_ref_id":     s.ToRefID,
+		"rule":          s.Rule,
+		"confidence":    s.Confidence,
+	}
+}
@@ -0,0 +1,141 @@
+package identity
+
+import (
+	"sort"
+	"strconv"
+	"strings"
+)
+
+// Resolve returns links between identities of customer matched by rules. For each pair of identities only the link with highest confidence is returned.
+func Resolve(identities []Identity, rules Rules, customerID string) ([]Link, error) {
+	cr, err := rules.compile()
+	if err != nil {
+		return nil, err
+	}
+
+	type ruleIndex struct {
+		rule       string
+		confidence float64
+		byKey      map[string][]int
+	}
+	newIndex := func(rule string, confidence float64) *ruleIndex {
+		return &ruleIndex{rule: rule, confidence: confidence, byKey: map[string][]int{}}
+	}
+	email := newIndex(RuleEmail, ConfidenceEmail)
+	alias := newIndex(RuleAlias, ConfidenceAlias)
+	noreply := newIndex(RuleNoreply, ConfidenceNoreply)
+	login := newIndex(RuleLogin, ConfidenceLogin)
+	name := newIndex(RuleName, ConfidenceName)
+
+	var ids []Identity
+	for _, id := range identities {
+		if id.CustomerID != "" && id.CustomerID != customerID {
+			continue
+		}
+		ids = append(ids, id)
+	}
+
+	byRefID := map[string][]int{}
+	for i, id := range ids {
+		byRefID[id.RefID] = append(byRefID[id.RefID], i)
+	}
+
+	best := map[string]Link{}
+	addLink := func(a, b Identity, rule string, confidence float64) {
+		if a.Key() == b.Key() {
+			return
+		}
+		l := newLink(customerID, a, b, rule, confidence)
+		if ex, ok := best[l.ID]; ok && ex.Confidence >= l.Confidence {
+			return
+		}
+		best[l.ID] = l
+	}
+
+	for i, id := range ids {
+		add := func(ind *ruleIndex, key string) {
+			ind.byKey[key] = append(ind.byKey[key], i)
+		}
+		for _, v := range id.AssociatedRefIDs {
+			// ref type of associated user is not known, skip if ref id is ambiguous
+			var candidates []int
+			for _, j := range byRefID[v] {
+				if ids[j].RefType != id.RefType {
+					candidates = append(candidates, j)
+				}
+			}
+			if len(candidates) == 1 {
+				addLink(id, ids[candidates[0]], RuleAssociatedRefID, ConfidenceAssociatedRefID)
+			}
+		}
+		for _, v := range id.Emails {
+			add(email, v)
+			if login := cr.noreplyLogin(v); login != "" {
+				add(noreply, login)
+			}
+			if g, ok := cr.aliases[v]; ok {
+				add(alias, strconv.Itoa(g))
+			}
+		}
+		for _, v := range id.Logins {
+			v = strings.ToLower(v)
+			add(login, v)
+			if g, ok := cr.aliases[v]; ok {
+				add(alias, strconv.Itoa(g))
+			}
+		}
+		if cr.MatchNames {
+			for _, v := range id.Names {
+				if n := normalizeName(v); n != "" {
+					add(name, n)
+				}
+			}
+		}
+	}
+
+	linkGroup := func(ind *ruleIndex, group []int) {
+		for x := 0; x < len(group); x++ {
+			for y := x + 1; y < len(group); y++ {
+				addLink(ids[group[x]], ids[group[y]], ind.rule, ind.confidence)
+			}
+		}
+	}
+
+	// noreply emails are linked to each other and to users with the same login, but users with the same login are not linked to each other unless MatchLogins is set
+	for key, group := range noreply.byKey {
+		linkGroup(noreply, group)
+		for _, x := range group {
+			for _, y := range login.byKey[key] {
+				addLink(ids[x], ids[y], noreply.rule, noreply.confidence)
+			}
+		}
+	}
+
+	indexes := []*ruleIndex{email, alias, name}
+	if cr.MatchLogins {
+		indexes = append(indexes, login)
+	}
+	for _, ind := range indexes {
+		for _, group := range ind.byKey {
+			linkGroup(ind, group)
+		}
+	}
+
+	var res []Link
+	for _, l := range best {
+		res = append(res, l)
+	}
+	sort.Slice(res, func(i, j int) bool {
+		return res[i].ID < res[j].ID
+	})
+	return res, nil
+}
+
+// normalizeName returns lowercase name with single spaces. Single word names are ignored, since these are often logins or first names only.
+func normalizeName(name string) string {
+	parts := strings.Fields(strings.ToLower(name))
+	if len(parts) < 2 {
+		return ""
+	}
+	return strings.Join(parts, " ")
+}
@@ -0,0 +1,120 @@
+package identity
+
+import (
+	"bufio"
+	"fmt"
+	"os"
+	"regexp"
+	"strings"
+)
+
+// Rules configures identity matching. Identities with the same email are always linked.
+type Rules struct {
+	// Disable turns off identity resolution
+	Disable bool `json:"disable"`
+	// NoreplyPatterns are regexps for noreply emails used in commits. The first group must match the login of user. Defaults to github and gitlab patterns when empty.
+	NoreplyPatterns []string `json:"noreply_patterns"`
+	// AliasesFile is a file with one person per line, listing emails or logins separated by whitespace or comma. Lines starting with # are ignored.
+	AliasesFile string `json:"aliases_file"`
+	// MatchNames links identities with the same display name. Names are not unique, so these links have low confidence.
+	MatchNames bool `json:"match_names"`
+	// MatchLogins links identities with the same login, case insensitive. The same login in different systems may belong to different people, so these links have lower confidence than noreply emails.
+	MatchLogins bool `json:"match_logins"`
+}
+
+// DefaultNoreplyPatterns match github and gitlab noreply commit emails
+var DefaultNoreplyPatterns = []string{
+	`^(?:\d+\+)?([^@]+)@users\.noreply\.github\.com$`,
+	`^(?:\d+-)?([^@]+)@users\.noreply\.gitlab\.com$`,
+}
+
+// Confidence of links for each rule
+const (
+	ConfidenceAssociatedRefID = 1.0
+	ConfidenceEmail           = 1.0
+	ConfidenceAlias           = 0.95
+	ConfidenceNoreply         = 0.9
+	ConfidenceLogin           = 0.7
+	ConfidenceName            = 0.5
+)
+
+// Rule names set on links
+const (
+	RuleAssociatedRefID = "associated_ref_id"
+	RuleEmail           = "email"
+	RuleAlias           = "alias"
+	RuleNoreply         = "noreply"
+	RuleLogin           = "login"
+	RuleName            = "name"
+)
+
+type compiledRules struct {
+	Rules
+	noreply []*regexp.Regexp
+	// aliases maps email or login to alias group
+	aliases map[string]int
+}
+
+func (s Rules) compile() (res compiledRules, rerr error) {
+	res.Rules = s
+	patterns := s.NoreplyPatterns
+	if len(patterns) == 0 {
+		patterns = DefaultNoreplyPatterns
+	}
+	for _, p := range patterns {
+		re, err := regexp.Compile(p)
+		if err != nil {
+			rerr = fmt.Errorf("invalid noreply pattern %v: %v", p, err)
+			return
+		}
+		if re.NumSubexp() < 1 {
+			rerr = fmt.Errorf("noreply pattern must contain a group for login: %v", p)
+			return
+		}
+		res.noreply = append(res.noreply, re)
+	}
+	res.aliases = map[string]int{}
+	if s.AliasesFile != "" {
+		res.aliases, rerr = readAliases(s.AliasesFile)
+	}
+	return
+}
+
+// noreplyLogin returns login from noreply email or empty string if email does not match any pattern
+func (s compiledRules) noreplyLogin(email string) string {
+	for _, re := range s.noreply {
+		m := re.FindStringSubmatch(email)
+		if len(m) > 1 && m[1] != "" {
+			return strings.ToLower(m[1])
+		}
+	}
+	return ""
+}
+
+func readAliases(loc string) (map[string]int, error) {
+	f, err := os.Open(loc)
+	if err != nil {
+		return nil, err
+	}
+	defer f.Close()
+	res := map[string]int{}
+	group := 0
+	sc := bufio.NewScanner(f)
+	for sc.Scan() {
+		line := strings.TrimSpace(sc.Text())
+		if line == "" || strings.HasPrefix(line, "#") {
+			continue
+		}
+		group++
+		fields := strings.FieldsFunc(line, func(r rune) bool {
+			return r == ',' || r == ' ' || r == '\t'
+		})
+		for _, f := range fields {
+			res[strings.ToLower(f)] = group
+		}
+	}
+	if err := sc.Err(); err != nil {
+		return nil, err
+	}
+	return res, nil
+}
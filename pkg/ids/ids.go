@@ -57,3 +57,27 @@ func WorkUser(customerID string, refType string, refID string) string {
 func WorkUserAssociatedRefID(customerID string, refType string, associatedRefID string) string {
 	return hash.Values(customerID, refType, associatedRefID)
 }
+
+func WorkIssueStatus(customerID string, refType string, refID string) string {
+	return work.NewIssueStatusID(customerID, refID, refType)
+}
+
+func CodeCommitFile(customerID string, refType string, repoID string, commitSHA string, path string) string {
+	return hash.Values(customerID, refType, repoID, commitSHA, path)
+}
+
+func CodeTag(customerID string, refType string, repoID string, name string) string {
+	return hash.Values(customerID, refType, repoID, "tag", name)
+}
+
+func CodeFileOwnership(customerID string, refType string, repoID string, path string) string {
+	return hash.Values(customerID, refType, repoID, "file-ownership", path)
+}
+
+func CodeDirectoryOwnership(customerID string, refType string, repoID string, path string) string {
+	return hash.Values(customerID, refType, repoID, "directory-ownership", path)
+}
+
+func CodeRelease(customerID string, refType string, repoID string, tagName string) string {
+	return hash.Values(customerID, refType, repoID, "release", tagName)
+}
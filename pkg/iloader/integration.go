@@ -76,6 +76,16 @@ func (s *Integration) RPCClient() rpcdef.Integration {
 	return s.rpcClient
 }
 
+// Exited returns true if integration process has exited
+func (s *Integration) Exited() bool {
+	return s.pluginClient.Exited()
+}
+
+// Ping checks that integration process responds to rpc calls
+func (s *Integration) Ping() error {
+	return s.rpcClientGeneric.Ping()
+}
+
 func prodIntegrationCommand(integrationsDir string, integrationName string) (*exec.Cmd, error) {
 	binName := integrationName
 	if runtime.GOOS == "windows" {
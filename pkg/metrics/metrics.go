@@ -0,0 +1,284 @@
+// Package metrics collects counters, gauges and histograms and writes them in Prometheus text format.
+//
+// Integrations and export command run in separate processes from the service. Each process records into its own Default registry, and sends the Snapshot to the parent process where it is merged into parent registry. Integrations send snapshots over rpc (rpcdef.Agent SendMetrics), export command writes them to a file in temp dir that is read by the service.
+package metrics
+
+import (
+	"errors"
+	"sort"
+	"strings"
+	"sync"
+)
+
+// Kind is the metric type
+type Kind string
+
+const (
+	KindCounter   Kind = "counter"
+	KindGauge     Kind = "gauge"
+	KindHistogram Kind = "histogram"
+)
+
+// Labels are the label names and values of one series
+type Labels map[string]string
+
+func (s Labels) key() string {
+	var keys []string
+	for k := range s {
+		keys = append(keys, k)
+	}
+	sort.Strings(keys)
+	var res []string
+	for _, k := range keys {
+		res = append(res, k+"="+s[k])
+	}
+	return strings.Join(res, ",")
+}
+
+func (s Labels) with(extra Labels) Labels {
+	res := Labels{}
+	for k, v := range s {
+		res[k] = v
+	}
+	for k, v := range extra {
+		res[k] = v
+	}
+	return res
+}
+
+// DefBuckets are the default histogram buckets for http request latencies in seconds
+var DefBuckets = []float64{.005, .01, .025, .05, .1, .25, .5, 1, 2.5, 5, 10, 30}
+
+// LongBuckets are histogram buckets for long operations such as exports and git clones in seconds
+var LongBuckets = []float64{1, 5, 10, 30, 60, 300, 600, 1800, 3600, 3 * 3600, 12 * 3600}
+
+type family struct {
+	Family
+	series map[string]*Series
+}
+
+// Registry keeps metric values. Safe for concurrent use.
+type Registry struct {
+	mu       sync.Mutex
+	families map[string]*family
+}
+
+// Default is the registry used by the current process
+var Default = NewRegistry()
+
+// NewRegistry creates an empty registry
+func NewRegistry() *Registry {
+	s := &Registry{}
+	s.families = map[string]*family{}
+	return s
+}
+
+func (s *Registry) register(name, help string, kind Kind, buckets []float64) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	_, err := s.registerNoLock(Family{Name: name, Help: help, Kind: kind, Buckets: buckets})
+	if err != nil {
+		panic(err)
+	}
+}
+
+func (s *Registry) registerNoLock(def Family) (*family, error) {
+	f := s.families[def.Name]
+	if f != nil {
+		if f.Kind != def.Kind || !bucketsEqual(f.Buckets, def.Buckets) {
+			return nil, errors.New("metric registered with different type or buckets: " + def.Name)
+		}
+		return f, nil
+	}
+	f = &family{}
+	f.Name = def.Name
+	f.Help = def.Help
+	f.Kind = def.Kind
+	f.Buckets = def.Buckets
+	f.series = map[string]*Series{}
+	s.families[def.Name] = f
+	return f, nil
+}
+
+func bucketsEqual(a, b []float64) bool {
+	if len(a) != len(b) {
+		return false
+	}
+	for i := range a {
+		if a[i] != b[i] {
+			return false
+		}
+	}
+	return true
+}
+
+func (s *family) get(labels Labels) *Series {
+	k := labels.key()
+	res := s.series[k]
+	if res == nil {
+		res = &Series{Labels: labels.with(nil)}
+		if s.Kind == KindHistogram {
+			res.BucketCounts = make([]uint64, len(s.Buckets))
+		}
+		s.series[k] = res
+	}
+	return res
+}
+
+func (s *Registry) update(name string, labels Labels, fn func(f *family, se *Series)) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	f := s.families[name]
+	if f == nil {
+		panic("metric is not registered: " + name)
+	}
+	fn(f, f.get(labels))
+}
+
+// Snapshot returns a copy of all metrics
+func (s *Registry) Snapshot() Snapshot {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	return s.snapshotNoLock()
+}
+
+func (s *Registry) snapshotNoLock() (res Snapshot) {
+	for _, f := range s.families {
+		f2 := f.Family
+		f2.Series = nil
+		for _, se := range f.series {
+			se2 := *se
+			se2.Labels = se.Labels.with(nil)
+			se2.BucketCounts = append([]uint64(nil), se.BucketCounts...)
+			f2.Series = append(f2.Series, se2)
+		}
+		res = append(res, f2)
+	}
+	res.sort()
+	return
+}
+
+// Drain returns a copy of all metrics and resets values. Used to send only the changes since the last call to the parent process.
+func (s *Registry) Drain() Snapshot {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	res := s.snapshotNoLock()
+	for _, f := range s.families {
+		f.series = map[string]*Series{}
+	}
+	return res
+}
+
+// Merge adds the values from snapshot, adding extra labels to all series. Counters and histograms are summed, gauges are replaced.
+func (s *Registry) Merge(data Snapshot, extra Labels) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	for _, f0 := range data {
+		f, err := s.registerNoLock(f0)
+		if err != nil {
+			return err
+		}
+		for _, se0 := range f0.Series {
+			se := f.get(se0.Labels.with(extra))
+			switch f.Kind {
+			case KindCounter:
+				se.Value += se0.Value
+			case KindGauge:
+				se.Value = se0.Value
+			case KindHistogram:
+				if len(se0.BucketCounts) != len(se.BucketCounts) {
+					return errors.New("invalid bucket counts for histogram: " + f.Name)
+				}
+				for i, c := range se0.BucketCounts {
+					se.BucketCounts[i] += c
+				}
+				se.Count += se0.Count
+				se.Sum += se0.Sum
+			}
+		}
+	}
+	return nil
+}
+
+// Counter is a value that only increases
+type Counter struct {
+	r    *Registry
+	name string
+}
+
+// NewCounter registers counter in Default registry
+func NewCounter(name, help string) *Counter {
+	return Default.Counter(name, help)
+}
+
+// Counter registers counter
+func (s *Registry) Counter(name, help string) *Counter {
+	s.register(name, help, KindCounter, nil)
+	return &Counter{r: s, name: name}
+}
+
+// Inc adds 1 to counter
+func (s *Counter) Inc(labels Labels) {
+	s.Add(1, labels)
+}
+
+// Add adds v to counter
+func (s *Counter) Add(v float64, labels Labels) {
+	s.r.update(s.name, labels, func(f *family, se *Series) {
+		se.Value += v
+	})
+}
+
+// Gauge is a value that could go up and down
+type Gauge struct {
+	r    *Registry
+	name string
+}
+
+// NewGauge registers gauge in Default registry
+func NewGauge(name, help string) *Gauge {
+	return Default.Gauge(name, help)
+}
+
+// Gauge registers gauge
+func (s *Registry) Gauge(name, help string) *Gauge {
+	s.register(name, help, KindGauge, nil)
+	return &Gauge{r: s, name: name}
+}
+
+// Set sets gauge value
+func (s *Gauge) Set(v float64, labels Labels) {
+	s.r.update(s.name, labels, func(f *family, se *Series) {
+		se.Value = v
+	})
+}
+
+// Histogram counts observed values in buckets
+type Histogram struct {
+	r    *Registry
+	name string
+}
+
+// NewHistogram registers histogram in Default registry
+func NewHistogram(name, help string, buckets []float64) *Histogram {
+	return Default.Histogram(name, help, buckets)
+}
+
+// Histogram registers histogram. Buckets are the upper bounds, sorted ascending.
+func (s *Registry) Histogram(name, help string, buckets []float64) *Histogram {
+	s.register(name, help, KindHistogram, buckets)
+	return &Histogram{r: s, name: name}
+}
+
+// Observe adds value to histogram
+func (s *Histogram) Observe(v float64, labels Labels) {
+	s.r.update(s.name, labels, func(f *family, se *Series) {
+		for i, b := range f.Buckets {
+			if v <= b {
+				se.BucketCounts[i]++
+			}
+		}
+		se.Count++
+		se.Sum += v
+	})
+}
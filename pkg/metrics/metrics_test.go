@@ -0,0 +1,109 @@
+package metrics
+
+import (
+	"bytes"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestWriteText(t *testing.T) {
+	r := NewRegistry()
+	c := r.Counter("requests_total", "Number of requests")
+	c.Inc(Labels{"code": "200"})
+	c.Add(2, Labels{"code": "200"})
+	c.Inc(Labels{"code": `5"0`})
+	g := r.Gauge("queue_depth", "Queue depth")
+	g.Set(3, nil)
+	h := r.Histogram("duration_seconds", "Duration", []float64{1, 5})
+	h.Observe(0.5, nil)
+	h.Observe(2, nil)
+	h.Observe(10, nil)
+	r.Counter("unused_total", "Not used")
+
+	buf := &bytes.Buffer{}
+	err := r.Snapshot().WriteText(buf)
+	if err != nil {
+		t.Fatal(err)
+	}
+	want := `# HELP duration_seconds Duration
+# TYPE duration_seconds histogram
+duration_seconds_bucket{le="1"} 1
+duration_seconds_bucket{le="5"} 2
+duration_seconds_bucket{le="+Inf"} 3
+duration_seconds_sum 12.5
+duration_seconds_count 3
+# HELP queue_depth Queue depth
+# TYPE queue_depth gauge
+queue_depth 3
+# HELP requests_total Number of requests
+# TYPE requests_total counter
+requests_total{code="200"} 3
+requests_total{code="5\"0"} 1
+`
+	assert.Equal(t, want, buf.String())
+}
+
+func TestMergeAndDrain(t *testing.T) {
+	assert := assert.New(t)
+
+	child := NewRegistry()
+	c := child.Counter("requests_total", "Number of requests")
+	h := child.Histogram("duration_seconds", "Duration", []float64{1})
+	c.Inc(Labels{"code": "200"})
+	h.Observe(2, nil)
+
+	parent := NewRegistry()
+	err := parent.Merge(child.Drain(), Labels{"integration": "github"})
+	if err != nil {
+		t.Fatal(err)
+	}
+	assert.True(child.Snapshot().Empty())
+
+	c.Inc(Labels{"code": "200"})
+	h.Observe(0.5, nil)
+	err = parent.Merge(child.Drain(), Labels{"integration": "github"})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	snap := parent.Snapshot()
+	assert.Len(snap, 2)
+	assert.Equal("duration_seconds", snap[0].Name)
+	assert.Equal([]Series{{Labels: Labels{"integration": "github"}, BucketCounts: []uint64{1}, Count: 2, Sum: 2.5}}, snap[0].Series)
+	assert.Equal([]Series{{Labels: Labels{"code": "200", "integration": "github"}, Value: 2}}, snap[1].Series)
+
+	other := NewRegistry()
+	other.Histogram("duration_seconds", "Duration", []float64{1, 2})
+	assert.Error(other.Merge(snap, nil))
+}
+
+func TestFile(t *testing.T) {
+	dir, err := ioutil.TempDir("", "metrics")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(dir)
+	loc := filepath.Join(dir, "metrics.json")
+
+	res, err := ReadFile(loc)
+	if err != nil {
+		t.Fatal(err)
+	}
+	assert.Nil(t, res)
+
+	r := NewRegistry()
+	r.Counter("requests_total", "Number of requests").Inc(nil)
+	err = r.Snapshot().WriteFile(loc)
+	if err != nil {
+		t.Fatal(err)
+	}
+	res, err = ReadFile(loc)
+	if err != nil {
+		t.Fatal(err)
+	}
+	assert.Equal(t, r.Snapshot(), res)
+}
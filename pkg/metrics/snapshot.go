@@ -0,0 +1,158 @@
+package metrics
+
+import (
+	"bufio"
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"io"
+	"io/ioutil"
+	"math"
+	"os"
+	"path/filepath"
+	"sort"
+	"strconv"
+	"strings"
+
+	"github.com/pinpt/agent/pkg/fs"
+)
+
+// Snapshot is a copy of registry values that could be serialized and merged into other registry
+type Snapshot []Family
+
+// Family is the metric with all its series
+type Family struct {
+	Name    string    `json:"name"`
+	Help    string    `json:"help"`
+	Kind    Kind      `json:"kind"`
+	Buckets []float64 `json:"buckets,omitempty"`
+	Series  []Series  `json:"series"`
+}
+
+// Series is the value of metric for one set of labels
+type Series struct {
+	Labels Labels `json:"labels"`
+	// Value is used for counters and gauges
+	Value float64 `json:"value,omitempty"`
+	// BucketCounts, Count and Sum are used for histograms. BucketCounts are cumulative, same as in Prometheus format.
+	BucketCounts []uint64 `json:"bucket_counts,omitempty"`
+	Count        uint64   `json:"count,omitempty"`
+	Sum          float64  `json:"sum,omitempty"`
+}
+
+func (s Snapshot) sort() {
+	sort.Slice(s, func(i, j int) bool {
+		return s[i].Name < s[j].Name
+	})
+	for _, f := range s {
+		sort.Slice(f.Series, func(i, j int) bool {
+			return f.Series[i].Labels.key() < f.Series[j].Labels.key()
+		})
+	}
+}
+
+// Empty returns true if snapshot has no series
+func (s Snapshot) Empty() bool {
+	for _, f := range s {
+		if len(f.Series) != 0 {
+			return false
+		}
+	}
+	return true
+}
+
+// ContentType is the content type of Prometheus text format
+const ContentType = "text/plain; version=0.0.4; charset=utf-8"
+
+// WriteText writes snapshot in Prometheus text format. Families without series are skipped.
+func (s Snapshot) WriteText(w io.Writer) error {
+	bw := bufio.NewWriter(w)
+	for _, f := range s {
+		if len(f.Series) == 0 {
+			continue
+		}
+		fmt.Fprintf(bw, "# HELP %s %s\n", f.Name, escapeHelp(f.Help))
+		fmt.Fprintf(bw, "# TYPE %s %s\n", f.Name, f.Kind)
+		for _, se := range f.Series {
+			switch f.Kind {
+			case KindHistogram:
+				for i, b := range f.Buckets {
+					writeSample(bw, f.Name+"_bucket", se.Labels.with(Labels{"le": formatFloat(b)}), float64(se.BucketCounts[i]))
+				}
+				writeSample(bw, f.Name+"_bucket", se.Labels.with(Labels{"le": "+Inf"}), float64(se.Count))
+				writeSample(bw, f.Name+"_sum", se.Labels, se.Sum)
+				writeSample(bw, f.Name+"_count", se.Labels, float64(se.Count))
+			default:
+				writeSample(bw, f.Name, se.Labels, se.Value)
+			}
+		}
+	}
+	return bw.Flush()
+}
+
+func writeSample(w io.Writer, name string, labels Labels, v float64) {
+	fmt.Fprintf(w, "%s%s %s\n", name, formatLabels(labels), formatFloat(v))
+}
+
+func formatLabels(labels Labels) string {
+	if len(labels) == 0 {
+		return ""
+	}
+	var keys []string
+	for k := range labels {
+		keys = append(keys, k)
+	}
+	sort.Strings(keys)
+	var res []string
+	for _, k := range keys {
+		res = append(res, k+`="`+escapeLabel(labels[k])+`"`)
+	}
+	return "{" + strings.Join(res, ",") + "}"
+}
+
+var labelEscaper = strings.NewReplacer(`\`, `\\`, "\n", `\n`, `"`, `\"`)
+var helpEscaper = strings.NewReplacer(`\`, `\\`, "\n", `\n`)
+
+func escapeLabel(v string) string {
+	return labelEscaper.Replace(v)
+}
+
+func escapeHelp(v string) string {
+	return helpEscaper.Replace(v)
+}
+
+func formatFloat(v float64) string {
+	switch {
+	case math.IsInf(v, 1):
+		return "+Inf"
+	case math.IsInf(v, -1):
+		return "-Inf"
+	}
+	return strconv.FormatFloat(v, 'g', -1, 64)
+}
+
+// WriteFile saves snapshot as json
+func (s Snapshot) WriteFile(loc string) error {
+	b, err := json.Marshal(s)
+	if err != nil {
+		return err
+	}
+	err = os.MkdirAll(filepath.Dir(loc), 0777)
+	if err != nil {
+		return err
+	}
+	return fs.WriteToTempAndRename(bytes.NewReader(b), loc)
+}
+
+// ReadFile reads snapshot saved with WriteFile. Returns nil if file does not exist.
+func ReadFile(loc string) (res Snapshot, _ error) {
+	b, err := ioutil.ReadFile(loc)
+	if err != nil {
+		if os.IsNotExist(err) {
+			return nil, nil
+		}
+		return nil, err
+	}
+	err = json.Unmarshal(b, &res)
+	return res, err
+}
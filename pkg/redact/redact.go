@@ -0,0 +1,314 @@
+// Package redact removes or pseudonymizes personal data in exported objects before they are written, using policies from agent config.
+package redact
+
+import (
+	"crypto/hmac"
+	"crypto/sha256"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"regexp"
+	"sort"
+	"strings"
+	"sync"
+	"unicode/utf8"
+)
+
+// Action is applied to fields matching policy
+type Action string
+
+const (
+	// ActionDrop removes the field from object
+	ActionDrop Action = "drop"
+	// ActionHash replaces the value with HMAC-SHA256 of it using customer salt, so the same values could still be matched
+	ActionHash Action = "hash"
+	// ActionTruncate shortens strings to MaxLength characters
+	ActionTruncate Action = "truncate"
+	// ActionScrub replaces parts of strings matching Patterns with Replacement
+	ActionScrub Action = "scrub"
+	// ActionKeep leaves the field as is, use it to exclude fields from later policies
+	ActionKeep Action = "keep"
+)
+
+// Builtin scrub patterns, use these names in Patterns instead of regexps
+var BuiltinPatterns = map[string]string{
+	"email": `[A-Za-z0-9._%+\-]+@[A-Za-z0-9.\-]+\.[A-Za-z]{2,}`,
+	"url":   `https?://[^\s"'<>]+`,
+	"token": `(?:gh[pousr]_[A-Za-z0-9]{36,}|glpat-[A-Za-z0-9_\-]{20,}|xox[abprs]-[A-Za-z0-9\-]{10,}|AKIA[0-9A-Z]{16}|eyJ[A-Za-z0-9_\-]+\.[A-Za-z0-9_\-]+\.[A-Za-z0-9_\-]+)`,
+}
+
+// DefaultReplacement is used for scrub action when Replacement is not set
+const DefaultReplacement = "[redacted]"
+
+// Config contains redaction policies. Policies are applied in order, each field is changed by the first matching policy only.
+type Config struct {
+	// Salt is required for hash action
+	Salt     string   `json:"salt"`
+	Policies []Policy `json:"policies"`
+}
+
+// Policy applies action to a field of model
+type Policy struct {
+	// Path is model name followed by field path, for example sourcecode.PullRequestComment.body or sourcecode.Commit.author.email. Use * instead of model name to match all models, for example *.email. Fields in arrays of objects are matched as well.
+	Path   string `json:"path"`
+	Action Action `json:"action"`
+	// MaxLength is used by truncate action
+	MaxLength int `json:"max_length"`
+	// Patterns are used by scrub action, either builtin pattern names (email, url, token) or regexps
+	Patterns []string `json:"patterns"`
+	// Replacement is used by scrub action, defaults to [redacted]
+	Replacement string `json:"replacement"`
+}
+
+type policy struct {
+	Policy
+	model    string
+	field    []string
+	patterns []*regexp.Regexp
+}
+
+// Engine applies policies to objects and counts changed fields. Safe for concurrent use.
+type Engine struct {
+	salt     []byte
+	policies []policy
+
+	mu      sync.Mutex
+	changed map[string]map[string]int
+}
+
+// New creates engine from config, returns error if config is not valid
+func New(conf Config) (*Engine, error) {
+	s := &Engine{}
+	s.salt = []byte(conf.Salt)
+	s.changed = map[string]map[string]int{}
+	for i, p := range conf.Policies {
+		cp, err := compilePolicy(p)
+		if err != nil {
+			return nil, fmt.Errorf("invalid redaction policy %v: %v", i, err)
+		}
+		if p.Action == ActionHash && conf.Salt == "" {
+			return nil, fmt.Errorf("invalid redaction policy %v: salt is required for hash action", i)
+		}
+		s.policies = append(s.policies, cp)
+	}
+	return s, nil
+}
+
+func compilePolicy(p Policy) (res policy, rerr error) {
+	res.Policy = p
+	path := p.Path
+	if strings.HasPrefix(path, "*.") {
+		res.model = "*"
+		path = path[2:]
+	} else {
+		parts := strings.SplitN(path, ".", 3)
+		if len(parts) != 3 || parts[0] == "" || parts[1] == "" {
+			rerr = fmt.Errorf("path must be model name followed by field, got: %v", p.Path)
+			return
+		}
+		res.model = parts[0] + "." + parts[1]
+		path = parts[2]
+	}
+	res.field = strings.Split(path, ".")
+	for _, f := range res.field {
+		if f == "" {
+			rerr = fmt.Errorf("empty field in path: %v", p.Path)
+			return
+		}
+	}
+	switch p.Action {
+	case ActionDrop, ActionHash, ActionKeep:
+	case ActionTruncate:
+		if p.MaxLength <= 0 {
+			rerr = errors.New("max_length is required for truncate action")
+			return
+		}
+	case ActionScrub:
+		if len(p.Patterns) == 0 {
+			rerr = errors.New("patterns are required for scrub action")
+			return
+		}
+		for _, pat := range p.Patterns {
+			if v, ok := BuiltinPatterns[pat]; ok {
+				pat = v
+			}
+			re, err := regexp.Compile(pat)
+			if err != nil {
+				rerr = fmt.Errorf("invalid pattern %v: %v", pat, err)
+				return
+			}
+			res.patterns = append(res.patterns, re)
+		}
+		if res.Replacement == "" {
+			res.Replacement = DefaultReplacement
+		}
+	default:
+		rerr = fmt.Errorf("unknown action: %v", p.Action)
+		return
+	}
+	return
+}
+
+// Enabled returns true if there are any policies
+func (s *Engine) Enabled() bool {
+	return len(s.policies) != 0
+}
+
+// Apply changes obj in place according to policies for model
+func (s *Engine) Apply(modelName string, obj map[string]interface{}) {
+	// fields already handled by earlier policy
+	handled := map[string]bool{}
+	for _, p := range s.policies {
+		if p.model != "*" && p.model != modelName {
+			continue
+		}
+		fieldPath := strings.Join(p.field, ".")
+		if handled[fieldPath] {
+			continue
+		}
+		found, changed := s.applyPath(p, obj, p.field)
+		if !found {
+			continue
+		}
+		handled[fieldPath] = true
+		if changed != 0 {
+			s.count(modelName, fieldPath, changed)
+		}
+	}
+}
+
+func (s *Engine) count(modelName, fieldPath string, n int) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if s.changed[modelName] == nil {
+		s.changed[modelName] = map[string]int{}
+	}
+	s.changed[modelName][fieldPath] += n
+}
+
+// applyPath applies policy to field at path in obj, returns if field was found and the number of changed values
+func (s *Engine) applyPath(p policy, obj map[string]interface{}, path []string) (found bool, changed int) {
+	v, ok := obj[path[0]]
+	if !ok {
+		return false, 0
+	}
+	if len(path) == 1 {
+		if p.Action == ActionDrop {
+			delete(obj, path[0])
+			return true, 1
+		}
+		res, n := s.applyValue(p, v)
+		obj[path[0]] = res
+		return true, n
+	}
+	switch v := v.(type) {
+	case map[string]interface{}:
+		return s.applyPath(p, v, path[1:])
+	case []interface{}:
+		for _, item := range v {
+			m, ok := item.(map[string]interface{})
+			if !ok {
+				continue
+			}
+			f, n := s.applyPath(p, m, path[1:])
+			found = found || f
+			changed += n
+		}
+	}
+	return
+}
+
+func (s *Engine) applyValue(p policy, v interface{}) (_ interface{}, changed int) {
+	switch v := v.(type) {
+	case nil:
+		return nil, 0
+	case string:
+		res := s.applyString(p, v)
+		if res != v {
+			changed = 1
+		}
+		return res, changed
+	case *string:
+		if v == nil {
+			return v, 0
+		}
+		return s.applyValue(p, *v)
+	case []interface{}:
+		for i, item := range v {
+			res, n := s.applyValue(p, item)
+			v[i] = res
+			changed += n
+		}
+		return v, changed
+	case []string:
+		for i, item := range v {
+			res := s.applyString(p, item)
+			if res != item {
+				v[i] = res
+				changed++
+			}
+		}
+		return v, changed
+	}
+	if p.Action == ActionHash {
+		return s.hash(fmt.Sprint(v)), 1
+	}
+	// truncate and scrub only change strings
+	return v, 0
+}
+
+func (s *Engine) applyString(p policy, v string) string {
+	switch p.Action {
+	case ActionHash:
+		if v == "" {
+			return v
+		}
+		return s.hash(v)
+	case ActionTruncate:
+		return truncate(v, p.MaxLength)
+	case ActionScrub:
+		for _, re := range p.patterns {
+			v = re.ReplaceAllLiteralString(v, p.Replacement)
+		}
+		return v
+	}
+	return v
+}
+
+func (s *Engine) hash(v string) string {
+	h := hmac.New(sha256.New, s.salt)
+	h.Write([]byte(v))
+	return hex.EncodeToString(h.Sum(nil))
+}
+
+func truncate(v string, maxLength int) string {
+	if utf8.RuneCountInString(v) <= maxLength {
+		return v
+	}
+	return string([]rune(v)[:maxLength])
+}
+
+// Report returns the number of changed fields by model name and field path
+func (s *Engine) Report() map[string]map[string]int {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	res := map[string]map[string]int{}
+	for m, fields := range s.changed {
+		res[m] = map[string]int{}
+		for f, n := range fields {
+			res[m][f] = n
+		}
+	}
+	return res
+}
+
+// ReportLines returns report as sorted lines in model.field=count format, used for logging
+func (s *Engine) ReportLines() (res []string) {
+	for m, fields := range s.Report() {
+		for f, n := range fields {
+			res = append(res, fmt.Sprintf("%v.%v=%v", m, f, n))
+		}
+	}
+	sort.Strings(res)
+	return
+}
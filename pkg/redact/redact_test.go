@@ -0,0 +1,83 @@
+package redact
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestApply(t *testing.T) {
+	assert := assert.New(t)
+	e, err := New(Config{
+		Salt: "s1",
+		Policies: []Policy{
+			{Path: "sourcecode.Commit.author.name", Action: ActionKeep},
+			{Path: "sourcecode.Commit.message", Action: ActionTruncate, MaxLength: 5},
+			{Path: "sourcecode.PullRequestComment.body", Action: ActionScrub, Patterns: []string{"email", "url"}},
+			{Path: "*.email", Action: ActionHash},
+			{Path: "*.name", Action: ActionDrop},
+			{Path: "work.Issue.attachments.url", Action: ActionDrop},
+		},
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	commit := map[string]interface{}{
+		"message": "fix bug in parser",
+		"author":  map[string]interface{}{"name": "John", "email": "j@example.com"},
+		"name":    "x",
+	}
+	e.Apply("sourcecode.Commit", commit)
+	assert.Equal("fix b", commit["message"])
+	assert.Equal("John", commit["author"].(map[string]interface{})["name"])
+	assert.NotContains(commit, "name")
+
+	user := map[string]interface{}{"email": "j@example.com", "name": "John"}
+	e.Apply("sourcecode.User", user)
+	assert.Len(user["email"], 64)
+	assert.NotContains(user, "name")
+
+	user2 := map[string]interface{}{"email": "j@example.com"}
+	e.Apply("work.User", user2)
+	assert.Equal(user["email"], user2["email"], "hash should be stable")
+
+	comment := map[string]interface{}{"body": "ping me at j@example.com or see https://example.com/x"}
+	e.Apply("sourcecode.PullRequestComment", comment)
+	assert.Equal("ping me at [redacted] or see [redacted]", comment["body"])
+
+	issue := map[string]interface{}{"attachments": []interface{}{
+		map[string]interface{}{"url": "u1", "name": "a"},
+		map[string]interface{}{"url": "u2"},
+	}}
+	e.Apply("work.Issue", issue)
+	assert.Equal([]interface{}{
+		map[string]interface{}{"name": "a"},
+		map[string]interface{}{},
+	}, issue["attachments"])
+
+	assert.Equal(map[string]map[string]int{
+		"sourcecode.Commit":             {"message": 1, "name": 1},
+		"sourcecode.User":               {"email": 1, "name": 1},
+		"work.User":                     {"email": 1},
+		"sourcecode.PullRequestComment": {"body": 1},
+		"work.Issue":                    {"attachments.url": 2},
+	}, e.Report())
+}
+
+func TestNewInvalid(t *testing.T) {
+	cases := []struct {
+		Label string
+		In    Config
+	}{
+		{"no salt", Config{Policies: []Policy{{Path: "*.email", Action: ActionHash}}}},
+		{"no field", Config{Policies: []Policy{{Path: "sourcecode.Commit", Action: ActionDrop}}}},
+		{"unknown action", Config{Policies: []Policy{{Path: "*.email", Action: "x"}}}},
+		{"truncate without length", Config{Policies: []Policy{{Path: "*.body", Action: ActionTruncate}}}},
+		{"invalid regexp", Config{Policies: []Policy{{Path: "*.body", Action: ActionScrub, Patterns: []string{"("}}}}},
+	}
+	for _, c := range cases {
+		_, err := New(c.In)
+		assert.Error(t, err, c.Label)
+	}
+}
@@ -0,0 +1,37 @@
+package redact
+
+import (
+	hclog "github.com/hashicorp/go-hclog"
+	"github.com/pinpt/agent/pkg/expsessions"
+)
+
+// Writer applies redaction policies to objects before passing them to wrapped writer
+type Writer struct {
+	wr        expsessions.Writer
+	engine    *Engine
+	modelName string
+}
+
+// NewWriter creates Writer
+func NewWriter(wr expsessions.Writer, engine *Engine, modelName string) *Writer {
+	s := &Writer{}
+	s.wr = wr
+	s.engine = engine
+	s.modelName = modelName
+	return s
+}
+
+func (s *Writer) Write(logger hclog.Logger, objs []map[string]interface{}) error {
+	for _, obj := range objs {
+		s.engine.Apply(s.modelName, obj)
+	}
+	return s.wr.Write(logger, objs)
+}
+
+func (s *Writer) Close() error {
+	return s.wr.Close()
+}
+
+func (s *Writer) Rollback() error {
+	return s.wr.Rollback()
+}
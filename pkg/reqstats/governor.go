@@ -0,0 +1,421 @@
+package reqstats
+
+import (
+	"context"
+	"crypto/sha256"
+	"encoding/hex"
+	"fmt"
+	"io"
+	"io/ioutil"
+	"math"
+	"net/http"
+	"strconv"
+	"sync"
+	"time"
+
+	"github.com/hashicorp/go-hclog"
+	"github.com/pinpt/agent/pkg/metrics"
+)
+
+var rateLimitedTotal = metrics.NewCounter("pinpoint_agent_http_rate_limited_total", "Number of http responses that were rate limited by the server")
+
+// Pauser is notified when requests are paused because of rate limits. Implemented by rpcdef.Agent.
+type Pauser interface {
+	SendPauseEvent(msg string, resumeDate time.Time) error
+	SendResumeEvent(msg string) error
+}
+
+// GovernorOpts are options for NewGovernor
+type GovernorOpts struct {
+	Logger hclog.Logger
+	// MaxRequestsPerSecond limits the request rate per host and credential. No limit if 0.
+	MaxRequestsPerSecond float64
+	// ReserveQuota is the fraction of rate limit quota to leave unused, for example 0.2. When remaining quota reported by server falls below it, requests are paused until quota reset.
+	ReserveQuota float64
+	// Pauser receives pause and resume events. Optional.
+	Pauser Pauser
+	// MaxRetries is the number of times rate limited request is retried. Default 5.
+	MaxRetries int
+	// DefaultWait is the wait before retry when server does not say when the limit resets. Doubled on every retry, up to MaxBackoff. Default 1 minute.
+	DefaultWait time.Duration
+	// MaxBackoff is the max wait before one retry when server does not say when the limit resets. Default 10 minutes.
+	MaxBackoff time.Duration
+	// MaxWait is the max time to wait for one limit reset. Default 1 hour.
+	MaxWait time.Duration
+}
+
+func (s GovernorOpts) withDefaults() GovernorOpts {
+	if s.MaxRetries == 0 {
+		s.MaxRetries = 5
+	}
+	if s.DefaultWait == 0 {
+		s.DefaultWait = time.Minute
+	}
+	if s.MaxWait == 0 {
+		s.MaxWait = time.Hour
+	}
+	if s.MaxBackoff == 0 {
+		s.MaxBackoff = 10 * time.Minute
+	}
+	return s
+}
+
+// Governor throttles requests of integration. It keeps a token bucket per host and credential, waits when server responds with 429 or 503 or reports that the quota is used up, and retries rate limited requests. Supports Retry-After, GitHub and Atlassian X-RateLimit-* and GitLab RateLimit-* headers.
+type Governor struct {
+	opts   GovernorOpts
+	logger hclog.Logger
+
+	mu         sync.Mutex
+	limits     map[limitKey]*limit
+	pausedKeys int
+	pausedAt   time.Time
+}
+
+type limitKey struct {
+	Host string
+	// Credential is the hash of auth headers, so that integrations using multiple tokens get separate limits
+	Credential string
+}
+
+type limit struct {
+	tokens      float64
+	last        time.Time
+	pausedUntil time.Time
+	announced   bool
+}
+
+// NewGovernor creates a governor. Use RoundTripper to apply it to http client.
+func NewGovernor(opts GovernorOpts) *Governor {
+	if opts.Logger == nil {
+		panic("provide logger")
+	}
+	s := &Governor{}
+	s.opts = opts.withDefaults()
+	s.logger = opts.Logger.Named("governor")
+	s.limits = map[limitKey]*limit{}
+	return s
+}
+
+// RoundTripper wraps rt, throttling and retrying requests. Requests with body are only retried if GetBody is set, which http.NewRequest does for in-memory bodies.
+func (s *Governor) RoundTripper(rt http.RoundTripper) http.RoundTripper {
+	return governedRoundTripper{governor: s, rt: rt}
+}
+
+type governedRoundTripper struct {
+	governor *Governor
+	rt       http.RoundTripper
+}
+
+func (s governedRoundTripper) RoundTrip(req *http.Request) (*http.Response, error) {
+	return s.governor.roundTrip(s.rt, req)
+}
+
+// Governed returns true if client uses Governor, which waits and retries rate limited requests
+func Governed(client *http.Client) bool {
+	_, ok := client.Transport.(governedRoundTripper)
+	return ok
+}
+
+func (s *Governor) roundTrip(rt http.RoundTripper, req *http.Request) (*http.Response, error) {
+	key := keyForRequest(req)
+	for attempt := 0; ; attempt++ {
+		err := s.wait(req.Context(), key)
+		if err != nil {
+			return nil, err
+		}
+		req2 := req
+		if attempt != 0 {
+			req2, err = rewind(req)
+			if err != nil {
+				return nil, err
+			}
+		}
+		resp, err := rt.RoundTrip(req2)
+		if err != nil {
+			return resp, err
+		}
+		now := time.Now()
+		retryAt, limited := rateLimited(resp, now)
+		if !limited {
+			s.checkQuota(key, resp.Header, now)
+			return resp, nil
+		}
+		rateLimitedTotal.Inc(nil)
+		if attempt >= s.opts.MaxRetries || !canRewind(req) {
+			s.logger.Warn("request rate limited, not retrying", "url", req.URL.String(), "code", resp.StatusCode, "attempts", attempt+1)
+			return resp, nil
+		}
+		if retryAt.IsZero() {
+			retryAt = now.Add(s.backoff(attempt))
+		}
+		s.logger.Warn("request rate limited, will wait and retry", "url", req.URL.String(), "code", resp.StatusCode, "retry_at", retryAt, "attempt", attempt+1)
+		io.Copy(ioutil.Discard, resp.Body)
+		resp.Body.Close()
+		s.pause(key, retryAt, now)
+	}
+}
+
+func (s *Governor) backoff(attempt int) time.Duration {
+	res := s.opts.DefaultWait
+	for i := 0; i < attempt && res < s.opts.MaxBackoff; i++ {
+		res *= 2
+	}
+	if res > s.opts.MaxBackoff {
+		res = s.opts.MaxBackoff
+	}
+	return res
+}
+
+// checkQuota pauses requests until reset when the remaining quota is used up or falls below ReserveQuota
+func (s *Governor) checkQuota(key limitKey, header http.Header, now time.Time) {
+	remaining, ok := headerInt(header, "X-RateLimit-Remaining", "RateLimit-Remaining")
+	if !ok {
+		return
+	}
+	reset := resetTime(header, now)
+	if !reset.After(now) {
+		return
+	}
+	if remaining > 0 {
+		max, ok := headerInt(header, "X-RateLimit-Limit", "RateLimit-Limit")
+		if !ok || max <= 0 || float64(remaining)/float64(max) > s.opts.ReserveQuota {
+			return
+		}
+	}
+	s.pause(key, reset, now)
+}
+
+func (s *Governor) pause(key limitKey, until time.Time, now time.Time) {
+	if until.Sub(now) > s.opts.MaxWait {
+		until = now.Add(s.opts.MaxWait)
+	}
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	l := s.limit(key, now)
+	if until.After(l.pausedUntil) {
+		l.pausedUntil = until
+	}
+}
+
+func (s *Governor) limit(key limitKey, now time.Time) *limit {
+	l := s.limits[key]
+	if l == nil {
+		l = &limit{}
+		l.tokens = s.burst()
+		l.last = now
+		s.limits[key] = l
+	}
+	return l
+}
+
+func (s *Governor) burst() float64 {
+	return math.Max(1, s.opts.MaxRequestsPerSecond)
+}
+
+// wait blocks until request to key is allowed
+func (s *Governor) wait(ctx context.Context, key limitKey) error {
+	for {
+		d, notify := s.reserve(key)
+		if notify != nil {
+			notify()
+		}
+		if d <= 0 {
+			return nil
+		}
+		t := time.NewTimer(d)
+		select {
+		case <-ctx.Done():
+			t.Stop()
+			return ctx.Err()
+		case <-t.C:
+		}
+	}
+}
+
+// reserve takes a token for request to key. Returns the time to wait if request is not allowed yet and the pause or resume event to send, if any.
+func (s *Governor) reserve(key limitKey) (_ time.Duration, notify func()) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	now := time.Now()
+	l := s.limit(key, now)
+	if now.Before(l.pausedUntil) {
+		if !l.announced {
+			l.announced = true
+			s.pausedKeys++
+			if s.pausedKeys == 1 {
+				s.pausedAt = now
+				until := l.pausedUntil
+				notify = func() { s.sendPause(key, until) }
+			}
+		}
+		return l.pausedUntil.Sub(now), notify
+	}
+	if l.announced {
+		l.announced = false
+		s.pausedKeys--
+		if s.pausedKeys == 0 {
+			paused := now.Sub(s.pausedAt)
+			notify = func() { s.sendResume(paused) }
+		}
+	}
+	rate := s.opts.MaxRequestsPerSecond
+	if rate <= 0 {
+		return 0, notify
+	}
+	l.tokens = math.Min(s.burst(), l.tokens+now.Sub(l.last).Seconds()*rate)
+	l.last = now
+	if l.tokens >= 1 {
+		l.tokens--
+		return 0, notify
+	}
+	return time.Duration((1 - l.tokens) / rate * float64(time.Second)), notify
+}
+
+func (s *Governor) sendPause(key limitKey, until time.Time) {
+	s.logger.Warn("rate limit reached, pausing requests", "host", key.Host, "resume_date", until)
+	if s.opts.Pauser == nil {
+		return
+	}
+	msg := fmt.Sprintf("rate limit reached for %v, paused until %v", key.Host, until.Format(time.RFC3339))
+	err := s.opts.Pauser.SendPauseEvent(msg, until)
+	if err != nil {
+		s.logger.Error("could not send pause event", "err", err)
+	}
+}
+
+func (s *Governor) sendResume(paused time.Duration) {
+	s.logger.Info("rate limit reset, resuming requests", "paused", paused.String())
+	if s.opts.Pauser == nil {
+		return
+	}
+	msg := fmt.Sprintf("rate limit reset, resumed after %v", paused.Round(time.Second))
+	err := s.opts.Pauser.SendResumeEvent(msg)
+	if err != nil {
+		s.logger.Error("could not send resume event", "err", err)
+	}
+}
+
+func keyForRequest(req *http.Request) limitKey {
+	h := sha256.New()
+	for _, k := range []string{"Authorization", "Private-Token"} {
+		h.Write([]byte(req.Header.Get(k)))
+		h.Write([]byte{0})
+	}
+	q := req.URL.Query()
+	for _, k := range []string{"access_token", "private_token"} {
+		h.Write([]byte(q.Get(k)))
+		h.Write([]byte{0})
+	}
+	return limitKey{
+		Host:       req.URL.Host,
+		Credential: hex.EncodeToString(h.Sum(nil)[:8]),
+	}
+}
+
+func canRewind(req *http.Request) bool {
+	return req.Body == nil || req.Body == http.NoBody || req.GetBody != nil
+}
+
+func rewind(req *http.Request) (*http.Request, error) {
+	res := req.Clone(req.Context())
+	if req.GetBody != nil {
+		body, err := req.GetBody()
+		if err != nil {
+			return nil, err
+		}
+		res.Body = body
+	}
+	return res, nil
+}
+
+// rateLimited checks if response is rate limited and returns the time after which request could be retried. retryAt is zero if server did not specify it.
+func rateLimited(resp *http.Response, now time.Time) (retryAt time.Time, limited bool) {
+	h := resp.Header
+	retryAfter := retryAfterTime(h, now)
+	switch resp.StatusCode {
+	case http.StatusTooManyRequests:
+	case http.StatusServiceUnavailable:
+		// 503 is also returned on outages, only treat it as rate limit when server says so
+		if retryAfter.IsZero() && !hasRateLimitHeaders(h) {
+			return
+		}
+	case http.StatusForbidden:
+		// GitHub returns 403 when quota is used up, or with Retry-After for secondary rate limits
+		remaining, ok := headerInt(h, "X-RateLimit-Remaining", "RateLimit-Remaining")
+		if retryAfter.IsZero() && !(ok && remaining == 0) {
+			return
+		}
+	default:
+		return
+	}
+	limited = true
+	if !retryAfter.IsZero() {
+		retryAt = retryAfter
+		return
+	}
+	if reset := resetTime(h, now); reset.After(now) {
+		retryAt = reset
+	}
+	return
+}
+
+func hasRateLimitHeaders(h http.Header) bool {
+	for _, k := range []string{"X-RateLimit-Remaining", "RateLimit-Remaining", "X-RateLimit-Reset", "RateLimit-Reset"} {
+		if h.Get(k) != "" {
+			return true
+		}
+	}
+	return false
+}
+
+// retryAfterTime parses Retry-After header in seconds or http date format
+func retryAfterTime(h http.Header, now time.Time) time.Time {
+	v := h.Get("Retry-After")
+	if v == "" {
+		return time.Time{}
+	}
+	if sec, err := strconv.Atoi(v); err == nil {
+		return now.Add(time.Duration(sec) * time.Second)
+	}
+	if t, err := http.ParseTime(v); err == nil {
+		return t
+	}
+	return time.Time{}
+}
+
+// resetTime parses the quota reset time. GitHub and GitLab use unix timestamp, Atlassian uses ISO 8601 date, some servers use seconds until reset.
+func resetTime(h http.Header, now time.Time) time.Time {
+	for _, k := range []string{"X-RateLimit-Reset", "RateLimit-Reset"} {
+		v := h.Get(k)
+		if v == "" {
+			continue
+		}
+		if n, err := strconv.ParseInt(v, 10, 64); err == nil {
+			if n > 1000000000 {
+				return time.Unix(n, 0)
+			}
+			return now.Add(time.Duration(n) * time.Second)
+		}
+		for _, layout := range []string{time.RFC3339, "2006-01-02T15:04Z07:00"} {
+			if t, err := time.Parse(layout, v); err == nil {
+				return t
+			}
+		}
+	}
+	return time.Time{}
+}
+
+func headerInt(h http.Header, keys ...string) (int, bool) {
+	for _, k := range keys {
+		v := h.Get(k)
+		if v == "" {
+			continue
+		}
+		n, err := strconv.Atoi(v)
+		if err != nil {
+			continue
+		}
+		return n, true
+	}
+	return 0, false
+}
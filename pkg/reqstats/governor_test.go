@@ -0,0 +1,193 @@
+package reqstats
+
+import (
+	"bytes"
+	"io/ioutil"
+	"net/http"
+	"net/http/httptest"
+	"strconv"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/hashicorp/go-hclog"
+	"github.com/stretchr/testify/assert"
+)
+
+type pauserMock struct {
+	mu     sync.Mutex
+	events []string
+}
+
+func (s *pauserMock) SendPauseEvent(msg string, resumeDate time.Time) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.events = append(s.events, "pause")
+	return nil
+}
+
+func (s *pauserMock) SendResumeEvent(msg string) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.events = append(s.events, "resume")
+	return nil
+}
+
+func TestRateLimited(t *testing.T) {
+	now := time.Date(2020, 5, 1, 10, 0, 0, 0, time.UTC)
+	resp := func(code int, header map[string]string) *http.Response {
+		res := &http.Response{StatusCode: code, Header: http.Header{}}
+		for k, v := range header {
+			res.Header.Set(k, v)
+		}
+		return res
+	}
+	cases := []struct {
+		Label   string
+		Resp    *http.Response
+		Limited bool
+		RetryAt time.Time
+	}{
+		{"ok", resp(200, nil), false, time.Time{}},
+		{"429 no headers", resp(429, nil), true, time.Time{}},
+		{"503 outage", resp(503, nil), false, time.Time{}},
+		{"503 retry-after", resp(503, map[string]string{"Retry-After": "30"}), true, now.Add(30 * time.Second)},
+		{"503 quota", resp(503, map[string]string{"X-RateLimit-Remaining": "0"}), true, time.Time{}},
+		{"retry-after seconds", resp(429, map[string]string{"Retry-After": "30"}), true, now.Add(30 * time.Second)},
+		{"retry-after date", resp(429, map[string]string{"Retry-After": "Fri, 01 May 2020 10:02:00 GMT"}), true, now.Add(2 * time.Minute)},
+		{"github quota", resp(403, map[string]string{"X-RateLimit-Remaining": "0", "X-RateLimit-Reset": strconv.FormatInt(now.Add(time.Hour).Unix(), 10)}), true, now.Add(time.Hour)},
+		{"github secondary", resp(403, map[string]string{"Retry-After": "60"}), true, now.Add(time.Minute)},
+		{"forbidden", resp(403, map[string]string{"X-RateLimit-Remaining": "10"}), false, time.Time{}},
+		{"gitlab", resp(429, map[string]string{"RateLimit-Reset": strconv.FormatInt(now.Add(time.Minute).Unix(), 10)}), true, now.Add(time.Minute)},
+		{"atlassian", resp(429, map[string]string{"X-RateLimit-Reset": "2020-05-01T10:05Z"}), true, now.Add(5 * time.Minute)},
+	}
+	for _, c := range cases {
+		retryAt, limited := rateLimited(c.Resp, now)
+		assert.Equal(t, c.Limited, limited, c.Label)
+		assert.True(t, c.RetryAt.Equal(retryAt), "%v: %v", c.Label, retryAt)
+	}
+}
+
+func TestGovernorRetry(t *testing.T) {
+	var mu sync.Mutex
+	var bodies []string
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		b, _ := ioutil.ReadAll(r.Body)
+		mu.Lock()
+		bodies = append(bodies, string(b))
+		n := len(bodies)
+		mu.Unlock()
+		if n <= 2 {
+			w.WriteHeader(http.StatusTooManyRequests)
+			return
+		}
+		w.Write([]byte("ok"))
+	}))
+	defer srv.Close()
+
+	pauser := &pauserMock{}
+	gov := NewGovernor(GovernorOpts{
+		Logger:      hclog.NewNullLogger(),
+		Pauser:      pauser,
+		DefaultWait: 10 * time.Millisecond,
+	})
+	client := &http.Client{Transport: gov.RoundTripper(http.DefaultTransport)}
+	resp, err := client.Post(srv.URL, "text/plain", bytes.NewReader([]byte("body")))
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer resp.Body.Close()
+	assert.Equal(t, 200, resp.StatusCode)
+	assert.Equal(t, []string{"body", "body", "body"}, bodies)
+	// every rate limited response pauses requests until retry
+	assert.Equal(t, []string{"pause", "resume", "pause", "resume"}, pauser.events)
+	assert.True(t, Governed(client))
+	assert.False(t, Governed(http.DefaultClient))
+}
+
+func TestGovernorMaxRetries(t *testing.T) {
+	calls := 0
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		calls++
+		w.Header().Set("Retry-After", "0")
+		w.WriteHeader(http.StatusTooManyRequests)
+	}))
+	defer srv.Close()
+
+	gov := NewGovernor(GovernorOpts{
+		Logger:     hclog.NewNullLogger(),
+		MaxRetries: 2,
+	})
+	client := &http.Client{Transport: gov.RoundTripper(http.DefaultTransport)}
+	resp, err := client.Get(srv.URL)
+	if err != nil {
+		t.Fatal(err)
+	}
+	resp.Body.Close()
+	assert.Equal(t, http.StatusTooManyRequests, resp.StatusCode)
+	assert.Equal(t, 3, calls)
+}
+
+func TestGovernorBackoff(t *testing.T) {
+	gov := NewGovernor(GovernorOpts{
+		Logger:      hclog.NewNullLogger(),
+		DefaultWait: time.Minute,
+		MaxBackoff:  5 * time.Minute,
+	})
+	var res []time.Duration
+	for attempt := 0; attempt < 5; attempt++ {
+		res = append(res, gov.backoff(attempt))
+	}
+	assert.Equal(t, []time.Duration{time.Minute, 2 * time.Minute, 4 * time.Minute, 5 * time.Minute, 5 * time.Minute}, res)
+}
+
+func TestGovernorQuotaUsedUp(t *testing.T) {
+	var reset time.Time
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		reset = time.Now().Add(200 * time.Millisecond)
+		w.Header().Set("X-RateLimit-Limit", "10")
+		w.Header().Set("X-RateLimit-Remaining", "1")
+		w.Header().Set("X-RateLimit-Reset", reset.Format(time.RFC3339Nano))
+	}))
+	defer srv.Close()
+
+	gov := NewGovernor(GovernorOpts{
+		Logger:       hclog.NewNullLogger(),
+		ReserveQuota: 0.2,
+	})
+	client := &http.Client{Transport: gov.RoundTripper(http.DefaultTransport)}
+	resp, err := client.Get(srv.URL)
+	if err != nil {
+		t.Fatal(err)
+	}
+	resp.Body.Close()
+	firstReset := reset
+	resp, err = client.Get(srv.URL)
+	if err != nil {
+		t.Fatal(err)
+	}
+	resp.Body.Close()
+	// second request waits for reset, since remaining quota is below reserved 20%
+	assert.False(t, time.Now().Before(firstReset))
+}
+
+func TestGovernorTokenBucket(t *testing.T) {
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {}))
+	defer srv.Close()
+
+	gov := NewGovernor(GovernorOpts{
+		Logger:               hclog.NewNullLogger(),
+		MaxRequestsPerSecond: 50,
+	})
+	client := &http.Client{Transport: gov.RoundTripper(http.DefaultTransport)}
+	started := time.Now()
+	// first 50 use the initial burst, next 10 wait for 20ms each
+	for i := 0; i < 60; i++ {
+		resp, err := client.Get(srv.URL)
+		if err != nil {
+			t.Fatal(err)
+		}
+		resp.Body.Close()
+	}
+	assert.True(t, time.Since(started) >= 180*time.Millisecond, time.Since(started).String())
+}
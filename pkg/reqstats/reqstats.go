@@ -6,14 +6,21 @@ import (
 	"fmt"
 	"net/http"
 	"os"
+	"strconv"
 	"strings"
 	"sync/atomic"
 	"time"
 
 	"github.com/hashicorp/go-hclog"
+	"github.com/pinpt/agent/pkg/metrics"
 	"github.com/pinpt/go-common/v10/httpdefaults"
 )
 
+var (
+	requestsTotal   = metrics.NewCounter("pinpoint_agent_http_requests_total", "Number of http requests made by integration by status code, code is error when no response was received")
+	requestDuration = metrics.NewHistogram("pinpoint_agent_http_request_duration_seconds", "Duration of http requests made by integration", metrics.DefBuckets)
+)
+
 type Clients struct {
 	Default     *http.Client
 	TLSInsecure *http.Client
@@ -27,6 +34,13 @@ type Opts struct {
 	OAuth1ConsumerKey     string
 	OAuth1Token           string
 	OAuth1URL             string
+
+	// MaxRequestsPerSecond limits the request rate per host and credential. No limit if 0.
+	MaxRequestsPerSecond float64
+	// ReserveQuota is the fraction of server rate limit quota to leave unused. Optional.
+	ReserveQuota float64
+	// Pauser receives pause and resume events when requests wait for rate limit reset. Pass rpcdef.Agent here.
+	Pauser Pauser
 }
 
 type ClientManager struct {
@@ -34,6 +48,8 @@ type ClientManager struct {
 	logger hclog.Logger
 
 	Clients Clients
+	// Governor is shared by all clients, so that rate limits apply to all requests of integration
+	Governor *Governor
 
 	totalRequests *int64
 }
@@ -52,11 +68,17 @@ func New(opts Opts) (*ClientManager, error) {
 	s.opts = opts
 	s.logger = opts.Logger.Named("reqstats")
 	s.totalRequests = int64p()
+	s.Governor = NewGovernor(GovernorOpts{
+		Logger:               s.logger,
+		MaxRequestsPerSecond: opts.MaxRequestsPerSecond,
+		ReserveQuota:         opts.ReserveQuota,
+		Pauser:               opts.Pauser,
+	})
 
 	{
 		c := &http.Client{}
 		transport := httpdefaults.DefaultTransport()
-		c.Transport = s.wrapRoundTripper(transport)
+		c.Transport = s.Governor.RoundTripper(s.wrapRoundTripper(transport))
 		s.Clients.Default = c
 	}
 
@@ -66,7 +88,7 @@ func New(opts Opts) (*ClientManager, error) {
 		c := &http.Client{}
 		transport := httpdefaults.DefaultTransport()
 		transport.TLSClientConfig = &tls.Config{InsecureSkipVerify: true}
-		c.Transport = s.wrapRoundTripper(transport)
+		c.Transport = s.Governor.RoundTripper(s.wrapRoundTripper(transport))
 		s.Clients.TLSInsecure = c
 	}
 
@@ -75,6 +97,7 @@ func New(opts Opts) (*ClientManager, error) {
 		if err != nil {
 			return nil, err
 		}
+		oauthClient.Transport = s.Governor.RoundTripper(oauthClient.Transport)
 		s.Clients.OAuth1 = oauthClient
 	}
 
@@ -102,6 +125,12 @@ func (s *ClientManager) wrapRoundTripper(rt http.RoundTripper) http.RoundTripper
 		}
 		//l.Debug("req start")
 		res, err := rt.RoundTrip(req)
+		code := "error"
+		if err == nil {
+			code = strconv.Itoa(res.StatusCode)
+		}
+		requestsTotal.Inc(metrics.Labels{"code": code})
+		requestDuration.Observe(time.Since(start).Seconds(), nil)
 		sec := fmt.Sprintf("%.1f", time.Since(start).Seconds())
 		if err != nil {
 			l.Debug("req end with err", "err", err, "sec", sec)
@@ -13,8 +13,12 @@ import (
 	"time"
 
 	"github.com/hashicorp/go-hclog"
+	"github.com/pinpt/agent/pkg/metrics"
+	"github.com/pinpt/agent/pkg/reqstats"
 )
 
+var retriesTotal = metrics.NewCounter("pinpoint_agent_http_retries_total", "Number of http requests retried by integration")
+
 type Request struct {
 	Method string
 	URL    string
@@ -121,6 +125,8 @@ func (opts Requests) retryDo(ctx context.Context, req Request) (resp *http.Respo
 	retry := opts.Retryable
 	retries := retry.MaxAttempts
 	count := 0
+	// rate limits are handled by governor in reqstats clients, which waits for reset and retries
+	governed := reqstats.Governed(opts.Client)
 
 	for time.Since(started) < retry.MaxDuration {
 		req2, err := req.Request()
@@ -143,7 +149,8 @@ func (opts Requests) retryDo(ctx context.Context, req Request) (resp *http.Respo
 			resp.StatusCode == http.StatusTemporaryRedirect || resp.StatusCode == http.StatusConflict ||
 			resp.StatusCode == http.StatusRequestEntityTooLarge || resp.StatusCode == http.StatusRequestedRangeNotSatisfiable ||
 			resp.StatusCode == http.StatusRequestHeaderFieldsTooLarge || resp.StatusCode == http.StatusBadRequest ||
-			resp.StatusCode == http.StatusUnprocessableEntity || resp.StatusCode == http.StatusInternalServerError {
+			resp.StatusCode == http.StatusUnprocessableEntity || resp.StatusCode == http.StatusInternalServerError ||
+			(governed && resp.StatusCode == http.StatusTooManyRequests) {
 			return
 		}
 
@@ -167,6 +174,7 @@ func (opts Requests) retryDo(ctx context.Context, req Request) (resp *http.Respo
 			return
 		}
 		count++
+		retriesTotal.Inc(nil)
 		opts.Logger.Info("request failed, will retry", "count", count, "url", req2.URL.String())
 	}
 	return
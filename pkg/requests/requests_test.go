@@ -1,10 +1,12 @@
 package requests
 
 import (
+	"context"
 	"fmt"
 	"net/http"
 	"net/http/httptest"
 	"testing"
+	"time"
 
 	"github.com/stretchr/testify/assert"
 
@@ -40,3 +42,26 @@ func TestJSON(t *testing.T) {
 	}
 	assert.Equal(t, []obj{{A: 1}, {A: 2}}, res)
 }
+
+func TestRetryTooManyRequests(t *testing.T) {
+	calls := 0
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		calls++
+		if calls == 1 {
+			w.WriteHeader(http.StatusTooManyRequests)
+			return
+		}
+		fmt.Fprint(w, "ok")
+	}))
+	defer server.Close()
+
+	// client without governor, 429 is retried here
+	r := New(hclog.NewNullLogger(), http.DefaultClient)
+	r.Retryable = RetryableRequest{MaxAttempts: 3, MaxDuration: time.Minute, RetryDelay: time.Millisecond}
+	res, err := r.Do(context.Background(), Request{URL: server.URL})
+	if err != nil {
+		t.Fatal(err)
+	}
+	assert.Equal(t, http.StatusOK, res.Resp.StatusCode)
+	assert.Equal(t, 2, calls)
+}
@@ -0,0 +1,243 @@
+// Package secrets detects api tokens, keys and passwords in exported objects and redacts them before objects are written.
+package secrets
+
+import (
+	"math"
+	"regexp"
+	"sort"
+	"strings"
+	"sync"
+)
+
+// Config configures secret scanning, it is enabled by default
+type Config struct {
+	// Disable turns off secret scanning
+	Disable bool `json:"disable"`
+	// HighEntropyFields are the field names where random looking strings are redacted, for example description or body. Matched case insensitively against the last part of field path. Disabled when empty, since this rule has more false positives than the others.
+	HighEntropyFields []string `json:"high_entropy_fields"`
+}
+
+// Rule detects one type of secret. If regexp has a group, only the group is redacted.
+type Rule struct {
+	Name string
+	Re   *regexp.Regexp
+}
+
+// Rules are token formats detected in all string fields
+var Rules = []Rule{
+	{"private_key", regexp.MustCompile(`-----BEGIN [A-Z ]*PRIVATE KEY-----[\s\S]*?(?:-----END [A-Z ]*PRIVATE KEY-----|$)`)},
+	{"github_token", regexp.MustCompile(`\b(?:gh[pousr]_[A-Za-z0-9]{36,}|github_pat_[A-Za-z0-9_]{22,})`)},
+	{"gitlab_token", regexp.MustCompile(`\bglpat-[A-Za-z0-9_\-]{20,}`)},
+	{"aws_access_key", regexp.MustCompile(`\b(?:AKIA|ASIA)[0-9A-Z]{16}\b`)},
+	{"aws_secret_key", regexp.MustCompile(`(?i)aws.{0,20}?(?:secret|key).{0,20}?[=:"'\s]([A-Za-z0-9/+]{40})\b`)},
+	{"slack_token", regexp.MustCompile(`\bxox[abprs]-[A-Za-z0-9\-]{10,}`)},
+	{"slack_webhook", regexp.MustCompile(`https://hooks\.slack\.com/services/[A-Za-z0-9/]+`)},
+	{"password", regexp.MustCompile(`(?i)\b(?:password|passwd|pwd)\s*[:=]\s*["']?([^\s"'<>]{6,})`)},
+}
+
+// HighEntropyRule is the name of rule for random looking strings
+const HighEntropyRule = "high_entropy"
+
+var highEntropyCandidate = regexp.MustCompile(`[A-Za-z0-9+/=_\-]{32,}`)
+
+// minEntropy is above the entropy of hex strings, such as commit shas
+const minEntropy = 4.5
+
+// Finding is a detected secret. It does not contain the secret itself, so it is safe to log and save.
+type Finding struct {
+	Model    string `json:"model"`
+	ObjectID string `json:"object_id"`
+	Field    string `json:"field"`
+	Rule     string `json:"rule"`
+}
+
+// maxFindings limits the findings kept in memory, counts are still updated after that
+const maxFindings = 10000
+
+// Scanner redacts secrets in objects and records findings. Safe for concurrent use.
+type Scanner struct {
+	conf Config
+
+	mu       sync.Mutex
+	findings []Finding
+	byRule   map[string]int
+	total    int
+}
+
+// New creates Scanner
+func New(conf Config) *Scanner {
+	s := &Scanner{}
+	s.conf = conf
+	s.byRule = map[string]int{}
+	return s
+}
+
+// Redacted returns the replacement for secret found by rule
+func Redacted(rule string) string {
+	return "[secret:" + rule + "]"
+}
+
+// Scan redacts secrets in obj in place, recording a finding for each changed field
+func (s *Scanner) Scan(modelName string, obj map[string]interface{}) {
+	id, _ := obj["id"].(string)
+	s.scanMap(modelName, id, "", obj)
+}
+
+func (s *Scanner) scanMap(modelName, objectID, prefix string, obj map[string]interface{}) {
+	for k, v := range obj {
+		path := k
+		if prefix != "" {
+			path = prefix + "." + k
+		}
+		obj[k] = s.scanValue(modelName, objectID, path, k, v)
+	}
+}
+
+func (s *Scanner) scanValue(modelName, objectID, path, key string, v interface{}) interface{} {
+	switch v := v.(type) {
+	case string:
+		res, rules := s.scanString(key, v)
+		for _, r := range rules {
+			s.record(Finding{Model: modelName, ObjectID: objectID, Field: path, Rule: r})
+		}
+		return res
+	case *string:
+		if v == nil {
+			return v
+		}
+		res := s.scanValue(modelName, objectID, path, key, *v)
+		return res
+	case map[string]interface{}:
+		s.scanMap(modelName, objectID, path, v)
+		return v
+	case []interface{}:
+		for i, item := range v {
+			v[i] = s.scanValue(modelName, objectID, path, key, item)
+		}
+		return v
+	case []string:
+		for i, item := range v {
+			v[i] = s.scanValue(modelName, objectID, path, key, item).(string)
+		}
+		return v
+	}
+	return v
+}
+
+// scanString returns string with secrets redacted and names of matched rules
+func (s *Scanner) scanString(key string, v string) (res string, rules []string) {
+	res = v
+	for _, rule := range Rules {
+		var found bool
+		res, found = redactRule(rule, res)
+		if found {
+			rules = append(rules, rule.Name)
+		}
+	}
+	if s.highEntropyField(key) {
+		found := false
+		res = highEntropyCandidate.ReplaceAllStringFunc(res, func(m string) string {
+			if entropy(m) < minEntropy {
+				return m
+			}
+			found = true
+			return Redacted(HighEntropyRule)
+		})
+		if found {
+			rules = append(rules, HighEntropyRule)
+		}
+	}
+	return
+}
+
+func redactRule(rule Rule, v string) (res string, found bool) {
+	locs := rule.Re.FindAllStringSubmatchIndex(v, -1)
+	if len(locs) == 0 {
+		return v, false
+	}
+	var b strings.Builder
+	last := 0
+	for _, loc := range locs {
+		start, end := loc[0], loc[1]
+		// redact only the group if regexp has one
+		if len(loc) >= 4 && loc[2] >= 0 {
+			start, end = loc[2], loc[3]
+		}
+		b.WriteString(v[last:start])
+		b.WriteString(Redacted(rule.Name))
+		last = end
+	}
+	b.WriteString(v[last:])
+	return b.String(), true
+}
+
+func (s *Scanner) highEntropyField(key string) bool {
+	for _, f := range s.conf.HighEntropyFields {
+		if strings.EqualFold(f, key) {
+			return true
+		}
+	}
+	return false
+}
+
+// entropy returns shannon entropy of string in bits per character
+func entropy(v string) float64 {
+	counts := map[rune]int{}
+	for _, r := range v {
+		counts[r]++
+	}
+	var res float64
+	l := float64(len(v))
+	for _, c := range counts {
+		p := float64(c) / l
+		res -= p * math.Log2(p)
+	}
+	return res
+}
+
+func (s *Scanner) record(f Finding) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.total++
+	s.byRule[f.Rule]++
+	if len(s.findings) < maxFindings {
+		s.findings = append(s.findings, f)
+	}
+}
+
+// Report contains findings of export
+type Report struct {
+	Total    int            `json:"total"`
+	ByRule   map[string]int `json:"by_rule"`
+	Findings []Finding      `json:"findings"`
+	// Truncated is true if there were more than 10000 findings, only first ones are included
+	Truncated bool `json:"truncated"`
+}
+
+// Report returns findings sorted by model, object id and field
+func (s *Scanner) Report() Report {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	res := Report{}
+	res.Total = s.total
+	res.ByRule = map[string]int{}
+	for k, v := range s.byRule {
+		res.ByRule[k] = v
+	}
+	res.Findings = append([]Finding{}, s.findings...)
+	sort.Slice(res.Findings, func(i, j int) bool {
+		a, b := res.Findings[i], res.Findings[j]
+		if a.Model != b.Model {
+			return a.Model < b.Model
+		}
+		if a.ObjectID != b.ObjectID {
+			return a.ObjectID < b.ObjectID
+		}
+		if a.Field != b.Field {
+			return a.Field < b.Field
+		}
+		return a.Rule < b.Rule
+	})
+	res.Truncated = s.total > len(s.findings)
+	return res
+}
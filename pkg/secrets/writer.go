@@ -0,0 +1,37 @@
+package secrets
+
+import (
+	hclog "github.com/hashicorp/go-hclog"
+	"github.com/pinpt/agent/pkg/expsessions"
+)
+
+// Writer redacts secrets in objects before passing them to wrapped writer
+type Writer struct {
+	wr        expsessions.Writer
+	scanner   *Scanner
+	modelName string
+}
+
+// NewWriter creates Writer
+func NewWriter(wr expsessions.Writer, scanner *Scanner, modelName string) *Writer {
+	s := &Writer{}
+	s.wr = wr
+	s.scanner = scanner
+	s.modelName = modelName
+	return s
+}
+
+func (s *Writer) Write(logger hclog.Logger, objs []map[string]interface{}) error {
+	for _, obj := range objs {
+		s.scanner.Scan(s.modelName, obj)
+	}
+	return s.wr.Write(logger, objs)
+}
+
+func (s *Writer) Close() error {
+	return s.wr.Close()
+}
+
+func (s *Writer) Rollback() error {
+	return s.wr.Rollback()
+}
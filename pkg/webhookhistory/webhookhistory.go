@@ -0,0 +1,217 @@
+// Package webhookhistory stores processed webhooks on disk, so that they could be inspected and replayed when webhook processing goes wrong.
+package webhookhistory
+
+import (
+	"bytes"
+	"crypto/rand"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/pinpt/agent/pkg/fs"
+)
+
+// DefaultMaxRecords is the number of records kept by default, older records are removed
+const DefaultMaxRecords = 500
+
+// Record is a processed webhook with the result of processing
+type Record struct {
+	ID            string    `json:"id"`
+	Date          time.Time `json:"date"`
+	Integration   string    `json:"integration"`
+	IntegrationID string    `json:"integration_id,omitempty"`
+	// MessageID is the id of the message or delivery set by the sender
+	MessageID string `json:"message_id,omitempty"`
+	// ReplayOf is the id of the replayed record
+	ReplayOf string `json:"replay_of,omitempty"`
+
+	Headers map[string]string `json:"headers"`
+	Body    json.RawMessage   `json:"body"`
+
+	DurationMs int64  `json:"duration_ms"`
+	Success    bool   `json:"success"`
+	Error      string `json:"error,omitempty"`
+	// MutatedObjects is the number of objects returned by webhook by model name
+	MutatedObjects map[string]int `json:"mutated_objects,omitempty"`
+}
+
+// SetMutatedObjects sets object counts from webhook result
+func (s *Record) SetMutatedObjects(objs map[string][]interface{}) {
+	if len(objs) == 0 {
+		return
+	}
+	s.MutatedObjects = map[string]int{}
+	for k, v := range objs {
+		s.MutatedObjects[k] = len(v)
+	}
+}
+
+// secretHeaders contain credentials and are not stored
+var secretHeaders = []string{"authorization", "x-gitlab-token"}
+
+const redacted = "[redacted]"
+
+// RedactHeaders returns a copy of headers with credentials replaced by placeholder
+func RedactHeaders(headers map[string]string) map[string]string {
+	res := map[string]string{}
+	for k, v := range headers {
+		for _, h := range secretHeaders {
+			if strings.EqualFold(k, h) {
+				v = redacted
+			}
+		}
+		res[k] = v
+	}
+	return res
+}
+
+// ErrNotFound is returned when record does not exist
+var ErrNotFound = errors.New("webhook record not found")
+
+// Store keeps the last records as one file per record. Safe for concurrent use.
+type Store struct {
+	dir string
+	max int
+	mu  sync.Mutex
+}
+
+// New creates a store in dir keeping max records. Uses DefaultMaxRecords if max is 0.
+func New(dir string, max int) *Store {
+	if max == 0 {
+		max = DefaultMaxRecords
+	}
+	s := &Store{}
+	s.dir = dir
+	s.max = max
+	return s
+}
+
+// Add saves record, setting ID and Date if empty. Removes the oldest records above max.
+func (s *Store) Add(rec Record) (Record, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if rec.Date.IsZero() {
+		rec.Date = time.Now()
+	}
+	if rec.ID == "" {
+		var err error
+		rec.ID, err = newID(rec.Date)
+		if err != nil {
+			return rec, err
+		}
+	}
+	rec.Headers = RedactHeaders(rec.Headers)
+
+	b, err := json.Marshal(rec)
+	if err != nil {
+		return rec, err
+	}
+	err = os.MkdirAll(s.dir, 0777)
+	if err != nil {
+		return rec, err
+	}
+	err = fs.WriteToTempAndRename(bytes.NewReader(b), s.loc(rec.ID))
+	if err != nil {
+		return rec, err
+	}
+	return rec, s.rotate()
+}
+
+// newID returns sortable id based on date
+func newID(date time.Time) (string, error) {
+	b := make([]byte, 4)
+	_, err := rand.Read(b)
+	if err != nil {
+		return "", err
+	}
+	return date.UTC().Format("20060102T150405.000") + "-" + hex.EncodeToString(b), nil
+}
+
+func (s *Store) loc(id string) string {
+	return filepath.Join(s.dir, id+".json")
+}
+
+// ids returns record ids, oldest first
+func (s *Store) ids() (res []string, _ error) {
+	files, err := ioutil.ReadDir(s.dir)
+	if os.IsNotExist(err) {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+	for _, f := range files {
+		if f.IsDir() || !strings.HasSuffix(f.Name(), ".json") {
+			continue
+		}
+		res = append(res, strings.TrimSuffix(f.Name(), ".json"))
+	}
+	sort.Strings(res)
+	return res, nil
+}
+
+func (s *Store) rotate() error {
+	ids, err := s.ids()
+	if err != nil {
+		return err
+	}
+	for len(ids) > s.max {
+		err := os.Remove(s.loc(ids[0]))
+		if err != nil && !os.IsNotExist(err) {
+			return err
+		}
+		ids = ids[1:]
+	}
+	return nil
+}
+
+// List returns all records, newest first
+func (s *Store) List() (res []Record, _ error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	ids, err := s.ids()
+	if err != nil {
+		return nil, err
+	}
+	for i := len(ids) - 1; i >= 0; i-- {
+		rec, err := s.get(ids[i])
+		if err == ErrNotFound {
+			// removed by another process
+			continue
+		}
+		if err != nil {
+			return nil, err
+		}
+		res = append(res, rec)
+	}
+	return res, nil
+}
+
+// Get returns record by id
+func (s *Store) Get(id string) (Record, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	return s.get(id)
+}
+
+func (s *Store) get(id string) (res Record, _ error) {
+	if id == "" || strings.ContainsAny(id, `/\`) {
+		return res, ErrNotFound
+	}
+	b, err := ioutil.ReadFile(s.loc(id))
+	if os.IsNotExist(err) {
+		return res, ErrNotFound
+	}
+	if err != nil {
+		return res, err
+	}
+	err = json.Unmarshal(b, &res)
+	return res, err
+}
@@ -0,0 +1,75 @@
+package webhookhistory
+
+import (
+	"encoding/json"
+	"io/ioutil"
+	"os"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestAddGet(t *testing.T) {
+	assert := assert.New(t)
+	dir, err := ioutil.TempDir("", "webhookhistory")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(dir)
+
+	s := New(dir, 0)
+	rec := Record{}
+	rec.Integration = "gitlab"
+	rec.Headers = map[string]string{"x-gitlab-event": "Push Hook", "X-Gitlab-Token": "s1"}
+	rec.Body = json.RawMessage(`{"a":1}`)
+	rec.SetMutatedObjects(map[string][]interface{}{"sourcecode.Commit": {1, 2}})
+	rec, err = s.Add(rec)
+	if err != nil {
+		t.Fatal(err)
+	}
+	assert.NotEmpty(rec.ID)
+
+	got, err := s.Get(rec.ID)
+	if err != nil {
+		t.Fatal(err)
+	}
+	assert.Equal("gitlab", got.Integration)
+	assert.Equal("Push Hook", got.Headers["x-gitlab-event"])
+	assert.Equal("[redacted]", got.Headers["X-Gitlab-Token"])
+	assert.Equal(`{"a":1}`, string(got.Body))
+	assert.Equal(map[string]int{"sourcecode.Commit": 2}, got.MutatedObjects)
+
+	_, err = s.Get("../" + rec.ID)
+	assert.Equal(ErrNotFound, err)
+	_, err = s.Get("x")
+	assert.Equal(ErrNotFound, err)
+}
+
+func TestRotate(t *testing.T) {
+	assert := assert.New(t)
+	dir, err := ioutil.TempDir("", "webhookhistory")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(dir)
+
+	s := New(dir, 2)
+	date := time.Date(2020, 5, 1, 0, 0, 0, 0, time.UTC)
+	for i := 0; i < 3; i++ {
+		_, err := s.Add(Record{Integration: "github", Date: date.Add(time.Duration(i) * time.Minute), Body: json.RawMessage(`{}`)})
+		if err != nil {
+			t.Fatal(err)
+		}
+	}
+	res, err := s.List()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !assert.Len(res, 2) {
+		return
+	}
+	// newest first
+	assert.Equal(date.Add(2*time.Minute), res[0].Date.UTC())
+	assert.Equal(date.Add(time.Minute), res[1].Date.UTC())
+}
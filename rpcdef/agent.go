@@ -7,6 +7,7 @@ import (
 	"strings"
 	"time"
 
+	"github.com/pinpt/agent/pkg/metrics"
 	"github.com/pinpt/agent/rpcdef/proto"
 )
 
@@ -33,6 +34,7 @@ type Agent interface {
 	// parentSessionID - parent session. Can be 0 for root sessions.
 	// parentObjectID - id of the parent object. To show in progress logs.
 	// parentObjectName - name of the parent object
+	// lastProcessed is the value passed to ExportDone in the last complete export. For child sessions it also includes values from the previous interrupted export, so integration can skip already exported objects.
 	SessionStart(isTracking bool, name string, parentSessionID int, parentObjectID, parentObjectName string) (sessionID int, lastProcessed interface{}, _ error)
 
 	// SessionProgress updates progress for a session
@@ -54,6 +56,9 @@ type Agent interface {
 	SendResumeEvent(msg string) error
 
 	GetWebhookURL() (url string, _ error)
+
+	// SendMetrics forwards metrics recorded in integration process since the last call. Called automatically by IntegrationServer, integrations only need to record into metrics.Default.
+	SendMetrics(data metrics.Snapshot) error
 }
 
 type ExportObj struct {
@@ -68,6 +73,15 @@ type GitRepoFetch struct {
 	CommitURLTemplate string
 	BranchURLTemplate string
 	PRs               []GitRepoFetchPR
+
+	// AccessMode selects how credentials are passed to git, see gitclone.AccessMode. When empty credentials are included in URL.
+	AccessMode string
+	// Username and Password are used with askpass and header access modes
+	Username string
+	Password string
+	// SSHKey and SSHKnownHosts are used with ssh access mode
+	SSHKey        string
+	SSHKnownHosts string
 }
 
 func (s GitRepoFetch) Validate() error {
@@ -202,6 +216,11 @@ func (s *AgentServer) ExportGitRepo(ctx context.Context, req *proto.ExportGitRep
 	fetch.URL = req.Url
 	fetch.CommitURLTemplate = req.CommitUrlTemplate
 	fetch.BranchURLTemplate = req.BranchUrlTemplate
+	fetch.AccessMode = req.AccessMode
+	fetch.Username = req.Username
+	fetch.Password = req.Password
+	fetch.SSHKey = req.SshKey
+	fetch.SSHKnownHosts = req.SshKnownHosts
 	for _, pr := range req.Prs {
 		pr2 := GitRepoFetchPR{}
 		pr2.ID = pr.Id
@@ -284,6 +303,16 @@ func (s *AgentServer) GetWebhookURL(ctx context.Context, req *proto.Empty) (*pro
 	return resp, err
 }
 
+func (s *AgentServer) SendMetrics(ctx context.Context, req *proto.SendMetricsReq) (*proto.Empty, error) {
+	var data metrics.Snapshot
+	err := json.Unmarshal(req.SnapshotJson, &data)
+	if err != nil {
+		return nil, err
+	}
+	err = s.Impl.SendMetrics(data)
+	return &proto.Empty{}, err
+}
+
 type AgentClient struct {
 	client proto.AgentClient
 }
@@ -341,6 +370,11 @@ func (s *AgentClient) ExportGitRepo(fetch GitRepoFetch) error {
 	args.Url = fetch.URL
 	args.CommitUrlTemplate = fetch.CommitURLTemplate
 	args.BranchUrlTemplate = fetch.BranchURLTemplate
+	args.AccessMode = fetch.AccessMode
+	args.Username = fetch.Username
+	args.Password = fetch.Password
+	args.SshKey = fetch.SSHKey
+	args.SshKnownHosts = fetch.SSHKnownHosts
 	for _, pr := range fetch.PRs {
 		pr2 := &proto.ExportGitRepoPR{}
 		pr2.Id = pr.ID
@@ -445,3 +479,17 @@ func (s *AgentClient) GetWebhookURL() (token string, _ error) {
 	}
 	return resp.Url, nil
 }
+
+func (s *AgentClient) SendMetrics(data metrics.Snapshot) error {
+	b, err := json.Marshal(data)
+	if err != nil {
+		return err
+	}
+	args := &proto.SendMetricsReq{}
+	args.SnapshotJson = b
+	_, err = s.client.SendMetrics(context.Background(), args)
+	if err != nil {
+		return err
+	}
+	return nil
+}
@@ -5,8 +5,10 @@ import (
 	"encoding/json"
 	"errors"
 	"fmt"
+	"time"
 
 	"github.com/pinpt/agent/cmd/cmdrunnorestarts/inconfig"
+	"github.com/pinpt/agent/pkg/metrics"
 
 	"github.com/pinpt/agent/rpcdef/proto"
 	"google.golang.org/grpc"
@@ -109,6 +111,8 @@ const (
 	OnboardExportTypeProjects                     = "projects"
 	OnboardExportTypeWorkConfig                   = "workconfig"
 	OnboardExportTypeCalendar                     = "calendars"
+	// OnboardExportTypePlan returns repoprojects.Plan describing what export would do, without exporting any data
+	OnboardExportTypePlan = "plan"
 )
 
 func onboardExportTypeFromProto(k proto.IntegrationOnboardExportReq_Kind) (res OnboardExportType) {
@@ -123,6 +127,8 @@ func onboardExportTypeFromProto(k proto.IntegrationOnboardExportReq_Kind) (res O
 		return OnboardExportTypeWorkConfig
 	case proto.IntegrationOnboardExportReq_CALENDARS:
 		return OnboardExportTypeCalendar
+	case proto.IntegrationOnboardExportReq_PLAN:
+		return OnboardExportTypePlan
 	default:
 		panic(fmt.Errorf("unsupported object type: %v", k))
 	}
@@ -140,6 +146,8 @@ func (s OnboardExportType) proto() proto.IntegrationOnboardExportReq_Kind {
 		return proto.IntegrationOnboardExportReq_WORKCONFIG
 	case OnboardExportTypeCalendar:
 		return proto.IntegrationOnboardExportReq_CALENDARS
+	case OnboardExportTypePlan:
+		return proto.IntegrationOnboardExportReq_PLAN
 	default:
 		panic(fmt.Errorf("unsupported object type: %v", s))
 	}
@@ -302,7 +310,8 @@ type IntegrationServer struct {
 	Impl   Integration
 	broker *plugin.GRPCBroker
 
-	conn *grpc.ClientConn
+	conn  *grpc.ClientConn
+	agent *AgentClient
 }
 
 func NewIntegrationServer(impl Integration, broker *plugin.GRPCBroker) *IntegrationServer {
@@ -322,12 +331,55 @@ func (s *IntegrationServer) Init(ctx context.Context, req *proto.IntegrationInit
 		return nil, err
 	}
 	as := &AgentClient{proto.NewAgentClient(conn)}
+	s.agent = as
 	err = s.Impl.Init(as)
 	return &proto.Empty{}, err
 }
 
+// metricsInterval defines how often metrics are sent to agent during export
+const metricsInterval = 30 * time.Second
+
+// sendMetrics sends metrics recorded since the last call to agent
+func (s *IntegrationServer) sendMetrics() {
+	if s.agent == nil {
+		return
+	}
+	data := metrics.Default.Drain()
+	if data.Empty() {
+		return
+	}
+	err := s.agent.SendMetrics(data)
+	if err != nil {
+		// put back to send with the next call
+		metrics.Default.Merge(data, nil)
+	}
+}
+
+// sendMetricsPeriodically sends metrics in background until returned func is called
+func (s *IntegrationServer) sendMetricsPeriodically() (stop func()) {
+	done := make(chan bool)
+	go func() {
+		ticker := time.NewTicker(metricsInterval)
+		defer ticker.Stop()
+		for {
+			select {
+			case <-done:
+				return
+			case <-ticker.C:
+				s.sendMetrics()
+			}
+		}
+	}()
+	return func() {
+		close(done)
+	}
+}
+
 func (s *IntegrationServer) Export(ctx context.Context, req *proto.IntegrationExportReq) (res *proto.IntegrationExportResp, _ error) {
 	res = &proto.IntegrationExportResp{}
+	defer s.sendMetrics()
+	stopMetrics := s.sendMetricsPeriodically()
+	defer stopMetrics()
 
 	config, err := exportConfigFromProto(req.Config)
 	if err != nil {
@@ -350,6 +402,7 @@ func (s *IntegrationServer) Export(ctx context.Context, req *proto.IntegrationEx
 
 func (s *IntegrationServer) ValidateConfig(ctx context.Context, req *proto.IntegrationValidateConfigReq) (res *proto.IntegrationValidateConfigResp, _ error) {
 	res = &proto.IntegrationValidateConfigResp{}
+	defer s.sendMetrics()
 
 	config, err := exportConfigFromProto(req.Config)
 	if err != nil {
@@ -367,6 +420,7 @@ func (s *IntegrationServer) ValidateConfig(ctx context.Context, req *proto.Integ
 
 func (s *IntegrationServer) OnboardExport(ctx context.Context, req *proto.IntegrationOnboardExportReq) (res *proto.IntegrationOnboardExportResp, _ error) {
 	res = &proto.IntegrationOnboardExportResp{}
+	defer s.sendMetrics()
 
 	config, err := exportConfigFromProto(req.Config)
 	if err != nil {
@@ -394,6 +448,7 @@ func (s *IntegrationServer) OnboardExport(ctx context.Context, req *proto.Integr
 
 func (s *IntegrationServer) Mutate(ctx context.Context, req *proto.IntegrationMutateReq) (res *proto.IntegrationMutateResp, _ error) {
 	res = &proto.IntegrationMutateResp{}
+	defer s.sendMetrics()
 
 	config, err := exportConfigFromProto(req.Config)
 	if err != nil {
@@ -414,6 +469,7 @@ func (s *IntegrationServer) Mutate(ctx context.Context, req *proto.IntegrationMu
 
 func (s *IntegrationServer) Webhook(ctx context.Context, req *proto.IntegrationWebhookReq) (res *proto.IntegrationWebhookResp, _ error) {
 	res = &proto.IntegrationWebhookResp{}
+	defer s.sendMetrics()
 
 	config, err := exportConfigFromProto(req.Config)
 	if err != nil {
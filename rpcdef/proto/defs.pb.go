@@ -32,6 +32,7 @@ const (
 	IntegrationOnboardExportReq_PROJECTS   IntegrationOnboardExportReq_Kind = 2
 	IntegrationOnboardExportReq_WORKCONFIG IntegrationOnboardExportReq_Kind = 3
 	IntegrationOnboardExportReq_CALENDARS  IntegrationOnboardExportReq_Kind = 4
+	IntegrationOnboardExportReq_PLAN       IntegrationOnboardExportReq_Kind = 5
 )
 
 var IntegrationOnboardExportReq_Kind_name = map[int32]string{
@@ -40,6 +41,7 @@ var IntegrationOnboardExportReq_Kind_name = map[int32]string{
 	2: "PROJECTS",
 	3: "WORKCONFIG",
 	4: "CALENDARS",
+	5: "PLAN",
 }
 
 var IntegrationOnboardExportReq_Kind_value = map[string]int32{
@@ -48,6 +50,7 @@ var IntegrationOnboardExportReq_Kind_value = map[string]int32{
 	"PROJECTS":   2,
 	"WORKCONFIG": 3,
 	"CALENDARS":  4,
+	"PLAN":       5,
 }
 
 func (x IntegrationOnboardExportReq_Kind) String() string {
@@ -1060,6 +1063,11 @@ type ExportGitRepoReq struct {
 	CommitUrlTemplate    string             `protobuf:"bytes,5,opt,name=commit_url_template,json=commitUrlTemplate,proto3" json:"commit_url_template,omitempty"`
 	BranchUrlTemplate    string             `protobuf:"bytes,6,opt,name=branch_url_template,json=branchUrlTemplate,proto3" json:"branch_url_template,omitempty"`
 	Prs                  []*ExportGitRepoPR `protobuf:"bytes,7,rep,name=prs,proto3" json:"prs,omitempty"`
+	AccessMode           string             `protobuf:"bytes,8,opt,name=access_mode,json=accessMode,proto3" json:"access_mode,omitempty"`
+	Username             string             `protobuf:"bytes,9,opt,name=username,proto3" json:"username,omitempty"`
+	Password             string             `protobuf:"bytes,10,opt,name=password,proto3" json:"password,omitempty"`
+	SshKey               string             `protobuf:"bytes,11,opt,name=ssh_key,json=sshKey,proto3" json:"ssh_key,omitempty"`
+	SshKnownHosts        string             `protobuf:"bytes,12,opt,name=ssh_known_hosts,json=sshKnownHosts,proto3" json:"ssh_known_hosts,omitempty"`
 	XXX_NoUnkeyedLiteral struct{}           `json:"-"`
 	XXX_unrecognized     []byte             `json:"-"`
 	XXX_sizecache        int32              `json:"-"`
@@ -1139,6 +1147,41 @@ func (m *ExportGitRepoReq) GetPrs() []*ExportGitRepoPR {
 	return nil
 }
 
+func (m *ExportGitRepoReq) GetAccessMode() string {
+	if m != nil {
+		return m.AccessMode
+	}
+	return ""
+}
+
+func (m *ExportGitRepoReq) GetUsername() string {
+	if m != nil {
+		return m.Username
+	}
+	return ""
+}
+
+func (m *ExportGitRepoReq) GetPassword() string {
+	if m != nil {
+		return m.Password
+	}
+	return ""
+}
+
+func (m *ExportGitRepoReq) GetSshKey() string {
+	if m != nil {
+		return m.SshKey
+	}
+	return ""
+}
+
+func (m *ExportGitRepoReq) GetSshKnownHosts() string {
+	if m != nil {
+		return m.SshKnownHosts
+	}
+	return ""
+}
+
 type ExportGitRepoPR struct {
 	Id                   string   `protobuf:"bytes,1,opt,name=id,proto3" json:"id,omitempty"`
 	RefId                string   `protobuf:"bytes,2,opt,name=ref_id,json=refId,proto3" json:"ref_id,omitempty"`
@@ -1635,6 +1678,45 @@ func (m *GetWebhookURLResp) GetUrl() string {
 	return ""
 }
 
+type SendMetricsReq struct {
+	SnapshotJson         []byte   `protobuf:"bytes,1,opt,name=snapshot_json,json=snapshotJson,proto3" json:"snapshot_json,omitempty"`
+	XXX_NoUnkeyedLiteral struct{} `json:"-"`
+	XXX_unrecognized     []byte   `json:"-"`
+	XXX_sizecache        int32    `json:"-"`
+}
+
+func (m *SendMetricsReq) Reset()         { *m = SendMetricsReq{} }
+func (m *SendMetricsReq) String() string { return proto.CompactTextString(m) }
+func (*SendMetricsReq) ProtoMessage()    {}
+func (*SendMetricsReq) Descriptor() ([]byte, []int) {
+	return fileDescriptor_bf10f51bd2cb5547, []int{32}
+}
+
+func (m *SendMetricsReq) XXX_Unmarshal(b []byte) error {
+	return xxx_messageInfo_SendMetricsReq.Unmarshal(m, b)
+}
+func (m *SendMetricsReq) XXX_Marshal(b []byte, deterministic bool) ([]byte, error) {
+	return xxx_messageInfo_SendMetricsReq.Marshal(b, m, deterministic)
+}
+func (m *SendMetricsReq) XXX_Merge(src proto.Message) {
+	xxx_messageInfo_SendMetricsReq.Merge(m, src)
+}
+func (m *SendMetricsReq) XXX_Size() int {
+	return xxx_messageInfo_SendMetricsReq.Size(m)
+}
+func (m *SendMetricsReq) XXX_DiscardUnknown() {
+	xxx_messageInfo_SendMetricsReq.DiscardUnknown(m)
+}
+
+var xxx_messageInfo_SendMetricsReq proto.InternalMessageInfo
+
+func (m *SendMetricsReq) GetSnapshotJson() []byte {
+	if m != nil {
+		return m.SnapshotJson
+	}
+	return nil
+}
+
 func init() {
 	proto.RegisterEnum("proto.IntegrationOnboardExportReq_Kind", IntegrationOnboardExportReq_Kind_name, IntegrationOnboardExportReq_Kind_value)
 	proto.RegisterEnum("proto.IntegrationOnboardExportResp_Error", IntegrationOnboardExportResp_Error_name, IntegrationOnboardExportResp_Error_value)
@@ -1672,115 +1754,122 @@ func init() {
 	proto.RegisterType((*SendPauseEventReq)(nil), "proto.SendPauseEventReq")
 	proto.RegisterType((*SendResumeEventReq)(nil), "proto.SendResumeEventReq")
 	proto.RegisterType((*GetWebhookURLResp)(nil), "proto.GetWebhookURLResp")
+	proto.RegisterType((*SendMetricsReq)(nil), "proto.SendMetricsReq")
 }
 
 func init() { proto.RegisterFile("defs.proto", fileDescriptor_bf10f51bd2cb5547) }
 
 var fileDescriptor_bf10f51bd2cb5547 = []byte{
-	// 1633 bytes of a gzipped FileDescriptorProto
-	0x1f, 0x8b, 0x08, 0x00, 0x00, 0x00, 0x00, 0x00, 0x02, 0xff, 0xb4, 0x57, 0xdb, 0x72, 0x1a, 0xcd,
-	0x11, 0xf6, 0xb2, 0x1c, 0x5b, 0x02, 0xa1, 0xf9, 0x65, 0x0b, 0x23, 0x2b, 0x56, 0xad, 0x65, 0x47,
-	0x71, 0x1c, 0x1c, 0x4b, 0x29, 0xc5, 0x87, 0x0b, 0x47, 0x91, 0x90, 0x82, 0xed, 0x00, 0xb5, 0x20,
-	0xd9, 0x55, 0xb9, 0xa0, 0x06, 0x76, 0x10, 0x08, 0xd8, 0x5d, 0xcf, 0x0c, 0x4a, 0x54, 0x95, 0xbb,
-	0xdc, 0xa6, 0x72, 0x93, 0x37, 0xc8, 0x13, 0xe4, 0x2d, 0x52, 0x95, 0x77, 0xc8, 0x5d, 0x1e, 0x24,
-	0x35, 0x87, 0x85, 0x5d, 0x40, 0x87, 0x2a, 0xe5, 0xbf, 0x62, 0xbb, 0xa7, 0x7b, 0xfa, 0x30, 0xdd,
-	0x5f, 0x37, 0x00, 0x0e, 0xe9, 0xb2, 0x92, 0x4f, 0x3d, 0xee, 0xa1, 0x84, 0xfc, 0xb1, 0x52, 0x90,
-	0x28, 0x8f, 0x7c, 0x7e, 0x65, 0xbd, 0x01, 0x54, 0x71, 0x39, 0x39, 0xa7, 0x98, 0xf7, 0x3d, 0xb7,
-	0xe2, 0xf6, 0xb9, 0x4d, 0xbe, 0xa3, 0x0d, 0xc8, 0x30, 0x42, 0x2f, 0x09, 0x6d, 0xf5, 0x9d, 0x82,
-	0xb1, 0x65, 0xec, 0x64, 0xed, 0xb4, 0x62, 0x54, 0x1c, 0xab, 0x0a, 0x6b, 0x21, 0x95, 0xf2, 0x9f,
-	0x7c, 0x8f, 0x4a, 0xa5, 0x7d, 0x48, 0x76, 0x3c, 0xb7, 0xdb, 0x3f, 0x97, 0x1a, 0x4b, 0xbb, 0x3f,
-	0x51, 0x26, 0x4b, 0x73, 0xc2, 0x87, 0x52, 0xca, 0xd6, 0xd2, 0xd6, 0x3f, 0x0d, 0x58, 0xbf, 0x46,
-	0x06, 0xed, 0xc3, 0x7a, 0x7f, 0x7a, 0xd4, 0x52, 0x1a, 0xad, 0x0b, 0xe6, 0xb9, 0xd2, 0xc8, 0xb2,
-	0xfd, 0x30, 0x74, 0xac, 0x74, 0x3e, 0x31, 0xcf, 0x45, 0xbf, 0x81, 0x65, 0x7c, 0x4e, 0x5c, 0xae,
-	0x35, 0x0a, 0x31, 0xe9, 0xd1, 0xe6, 0xbc, 0x47, 0x07, 0x42, 0x4a, 0x3b, 0xb4, 0x84, 0xa7, 0x84,
-	0x48, 0xc1, 0x98, 0x91, 0x96, 0x87, 0xc7, 0xbc, 0x57, 0x30, 0xb7, 0x8c, 0x9d, 0xb4, 0x9d, 0x1e,
-	0x33, 0x52, 0x13, 0xb4, 0xf5, 0x0e, 0x1e, 0x2d, 0xbe, 0x03, 0x3d, 0x85, 0xa5, 0xce, 0x98, 0x71,
-	0x6f, 0x34, 0xcd, 0x5d, 0xc6, 0x86, 0x80, 0x55, 0x71, 0xac, 0x6f, 0xf0, 0x70, 0x41, 0xf6, 0x98,
-	0x8f, 0x3e, 0x42, 0xda, 0xa7, 0xde, 0x05, 0xe9, 0x70, 0x56, 0x30, 0xb7, 0xcc, 0x9d, 0xa5, 0xdd,
-	0x67, 0xd7, 0x25, 0x50, 0xc8, 0xd7, 0x95, 0xac, 0x3d, 0x51, 0xb2, 0xfe, 0x0c, 0x4f, 0x6e, 0x92,
-	0x44, 0x39, 0x88, 0x4d, 0x3c, 0x8a, 0xf5, 0x1d, 0xf4, 0x10, 0x92, 0x94, 0x74, 0x85, 0x97, 0x31,
-	0xc9, 0x4b, 0x50, 0xd2, 0xad, 0x38, 0x22, 0x02, 0x4a, 0xb0, 0x83, 0xdb, 0x43, 0x22, 0xce, 0x4c,
-	0x15, 0x41, 0xc0, 0xaa, 0x38, 0x68, 0x0d, 0x12, 0x84, 0x52, 0x8f, 0x16, 0xe2, 0x4a, 0x4d, 0x12,
-	0xd6, 0x59, 0xc4, 0xfa, 0x19, 0x1e, 0xf6, 0x1d, 0xcc, 0x89, 0xce, 0xec, 0x3d, 0xaa, 0xe3, 0x0a,
-	0x36, 0x6f, 0xb8, 0x97, 0xf9, 0xe8, 0x11, 0x24, 0xa5, 0x07, 0xac, 0x60, 0x6c, 0x99, 0x3b, 0x19,
-	0x5b, 0x53, 0xe8, 0x31, 0xa4, 0x29, 0xf1, 0xbd, 0xd6, 0x98, 0x0e, 0x75, 0x80, 0x29, 0x41, 0x9f,
-	0xd2, 0x21, 0x7a, 0x0e, 0x39, 0x5d, 0xde, 0x97, 0x84, 0xb2, 0xbe, 0xe7, 0xea, 0x28, 0xb3, 0x8a,
-	0x7b, 0xa6, 0x98, 0xd6, 0x7f, 0x0c, 0xd8, 0x08, 0xd9, 0xae, 0xb9, 0x6d, 0x0f, 0x53, 0xe7, 0xde,
-	0x05, 0x8f, 0x3e, 0x40, 0x7c, 0xd0, 0x77, 0x55, 0xda, 0x73, 0xbb, 0x3f, 0x9d, 0xd7, 0x9a, 0xb5,
-	0x54, 0xfa, 0xdc, 0x77, 0x1d, 0x5b, 0x2a, 0x59, 0x15, 0x88, 0x0b, 0x0a, 0x65, 0x20, 0x71, 0xda,
-	0x28, 0xdb, 0x8d, 0xfc, 0x03, 0xf1, 0x69, 0x97, 0xeb, 0xb5, 0x46, 0xde, 0x40, 0xcb, 0x90, 0xae,
-	0xdb, 0xb5, 0x4f, 0xe5, 0xc3, 0x66, 0x23, 0x1f, 0x43, 0x39, 0x80, 0xaf, 0x35, 0xfb, 0xf3, 0x61,
-	0xad, 0x7a, 0x5c, 0x39, 0xc9, 0x9b, 0x28, 0x0b, 0x99, 0xc3, 0x83, 0x2f, 0xe5, 0xea, 0xd1, 0x81,
-	0xdd, 0xc8, 0xc7, 0xad, 0x7f, 0x18, 0x91, 0x37, 0x9b, 0xb1, 0x2a, 0x4b, 0x52, 0xbf, 0xb4, 0x21,
-	0x3d, 0xfd, 0xd9, 0xad, 0x9e, 0x32, 0xbf, 0x54, 0x16, 0x0a, 0xba, 0x28, 0x44, 0x13, 0x39, 0x98,
-	0x63, 0xd5, 0xb0, 0x31, 0xd9, 0xb0, 0x69, 0xc1, 0x10, 0x3d, 0x6a, 0x6d, 0x43, 0x42, 0x0a, 0xa3,
-	0x34, 0xc4, 0xab, 0xb5, 0x6a, 0x39, 0xff, 0x00, 0xad, 0x42, 0xb6, 0x5a, 0x6b, 0xb6, 0x1a, 0xa7,
-	0xf5, 0x7a, 0xcd, 0x6e, 0x96, 0x8f, 0xf2, 0x86, 0xf5, 0x57, 0x23, 0x02, 0x37, 0xbf, 0x1f, 0x73,
-	0xcc, 0xc9, 0x7d, 0xb2, 0xbf, 0x01, 0x99, 0x91, 0xbc, 0xa4, 0xd5, 0x75, 0x75, 0x61, 0xa4, 0x15,
-	0xe3, 0xd8, 0x15, 0xc5, 0xaf, 0x0f, 0x85, 0x9b, 0x41, 0xf1, 0x2b, 0xd6, 0x11, 0xe6, 0xd8, 0xfa,
-	0x79, 0xa4, 0x7d, 0x03, 0x6f, 0x98, 0x8f, 0x10, 0xc4, 0x27, 0xb0, 0x94, 0xb1, 0xe5, 0xb7, 0xf5,
-	0x5f, 0x23, 0x22, 0xfd, 0x95, 0xb4, 0x7b, 0x9e, 0x37, 0xb8, 0x8f, 0xf3, 0x87, 0x90, 0xea, 0x11,
-	0xec, 0x10, 0xca, 0x0a, 0x31, 0x89, 0x11, 0x0b, 0xde, 0x64, 0x6a, 0xa6, 0xf4, 0x3b, 0x25, 0x5b,
-	0x76, 0x39, 0xbd, 0xb2, 0x03, 0x4d, 0xe1, 0x6a, 0xdb, 0x73, 0xae, 0x74, 0x74, 0xf2, 0xbb, 0xf8,
-	0x1e, 0x96, 0xc3, 0xc2, 0x28, 0x0f, 0xe6, 0x80, 0x5c, 0xe9, 0x68, 0xc4, 0xa7, 0x68, 0xfb, 0x4b,
-	0x3c, 0x1c, 0x93, 0x00, 0x2d, 0x24, 0xf1, 0x3e, 0xf6, 0xd6, 0xb0, 0x5e, 0x45, 0xd0, 0x70, 0x62,
-	0xfe, 0x9a, 0xa4, 0xbc, 0x84, 0xec, 0x17, 0xcc, 0x78, 0x9d, 0x7a, 0x1d, 0xc2, 0x18, 0x71, 0x44,
-	0xa3, 0xca, 0x22, 0x61, 0x9c, 0x6a, 0xc1, 0x94, 0xa0, 0x1b, 0x9c, 0x5a, 0x6f, 0x20, 0xaf, 0xd2,
-	0xd0, 0xe0, 0x98, 0x72, 0xe2, 0x88, 0xd4, 0x6d, 0x02, 0x8c, 0x3c, 0x87, 0x0c, 0x5b, 0xfc, 0xca,
-	0x27, 0x5a, 0x21, 0x23, 0x39, 0xcd, 0x2b, 0x9f, 0x58, 0x1e, 0xac, 0xce, 0xa8, 0x30, 0x5f, 0xe8,
-	0x30, 0xc2, 0x44, 0x53, 0x4f, 0x41, 0x39, 0xa3, 0x39, 0x15, 0x07, 0x7d, 0x80, 0xdc, 0x10, 0x33,
-	0xde, 0xf2, 0x03, 0x9f, 0xf4, 0xbc, 0x58, 0xd3, 0xc9, 0x8d, 0xf8, 0x6b, 0x67, 0x87, 0x61, 0xd2,
-	0x1a, 0x40, 0x56, 0x19, 0x3c, 0xf2, 0x5c, 0xa2, 0x1d, 0xfc, 0xd1, 0x8c, 0x9d, 0xc1, 0x4a, 0x83,
-	0xb8, 0xba, 0xdf, 0x26, 0xf9, 0xb8, 0xc9, 0xdc, 0x36, 0xc4, 0xbd, 0xf6, 0x45, 0x30, 0x52, 0xf2,
-	0xda, 0x88, 0xba, 0xa0, 0xd6, 0xbe, 0xb0, 0xe5, 0xa9, 0x35, 0x82, 0xcc, 0x84, 0x85, 0xf6, 0x75,
-	0xd7, 0x4e, 0x12, 0x9c, 0xdb, 0x7d, 0x3c, 0xab, 0x57, 0x12, 0xdd, 0x20, 0x12, 0xae, 0x1a, 0x5a,
-	0x7c, 0x89, 0xd7, 0x96, 0x5d, 0xa3, 0x1a, 0x5d, 0x7e, 0x5b, 0x6b, 0x90, 0x0e, 0x24, 0x45, 0x9f,
-	0x7f, 0x6a, 0xd4, 0xaa, 0xf9, 0x07, 0xd6, 0x5f, 0x62, 0xc1, 0xc3, 0x9e, 0x88, 0x85, 0xc3, 0xf7,
-	0x44, 0x20, 0xeb, 0x20, 0x01, 0x7a, 0x1a, 0x45, 0x52, 0x90, 0x6a, 0x22, 0x8d, 0xdd, 0xfe, 0xf7,
-	0x31, 0x69, 0xb9, 0x78, 0x14, 0xd4, 0x1f, 0x28, 0x56, 0x15, 0x8f, 0x88, 0x82, 0xfa, 0xae, 0xf2,
-	0xd7, 0x0c, 0xa0, 0xbe, 0x2b, 0x6d, 0xe6, 0xc1, 0x14, 0x03, 0x40, 0x8d, 0x2a, 0xf1, 0x89, 0x4a,
-	0xf0, 0x43, 0xc7, 0x1b, 0x8d, 0xfa, 0x5c, 0x4c, 0x86, 0x16, 0x27, 0x23, 0x7f, 0x88, 0x39, 0x29,
-	0x24, 0xa4, 0xc4, 0xaa, 0x3a, 0x3a, 0xa5, 0xc3, 0xa6, 0x3e, 0x10, 0xf2, 0x6d, 0x8a, 0xdd, 0x4e,
-	0x2f, 0x2a, 0x9f, 0x54, 0xf2, 0xea, 0x28, 0x2c, 0xbf, 0x03, 0xa6, 0x4f, 0x59, 0x21, 0x25, 0xf3,
-	0xfd, 0x28, 0x92, 0x37, 0x1d, 0x6c, 0xdd, 0xb6, 0x85, 0x88, 0xf5, 0x77, 0x03, 0x56, 0x66, 0x0e,
-	0xee, 0x3a, 0xa4, 0x75, 0x58, 0xe6, 0x34, 0xac, 0xa7, 0xb0, 0xa4, 0xdd, 0x94, 0x49, 0x52, 0x01,
-	0x83, 0x62, 0xc9, 0x24, 0xbd, 0x80, 0x15, 0x59, 0x77, 0x3a, 0x78, 0xd6, 0xc3, 0x3a, 0x66, 0x59,
-	0x62, 0x87, 0x92, 0xdb, 0xe8, 0x61, 0xeb, 0xdf, 0x86, 0xa8, 0x31, 0x59, 0x3e, 0xb2, 0x85, 0xc4,
-	0xd3, 0x3c, 0x85, 0xa5, 0x3e, 0x6b, 0x71, 0x8a, 0x3b, 0x83, 0xbe, 0xab, 0x30, 0x2b, 0x6d, 0x43,
-	0x9f, 0x35, 0x35, 0x47, 0x3c, 0x7d, 0xe8, 0x6d, 0xe4, 0x37, 0x7a, 0x09, 0xab, 0x3e, 0xa6, 0x62,
-	0x09, 0x0b, 0xd5, 0xa7, 0xf0, 0xd8, 0xb4, 0x57, 0xd4, 0x41, 0x63, 0x52, 0xa5, 0x3b, 0x90, 0xd7,
-	0xb2, 0x5e, 0x5b, 0x2c, 0x2b, 0x42, 0x54, 0x85, 0x90, 0x53, 0xfc, 0x9a, 0x64, 0x57, 0x1c, 0xf4,
-	0x0a, 0x50, 0x54, 0x52, 0xda, 0x55, 0x91, 0xe4, 0xc3, 0xb2, 0x22, 0x68, 0xcb, 0x85, 0x7c, 0x34,
-	0x96, 0x85, 0x60, 0x60, 0xfe, 0xdf, 0xfa, 0xb3, 0x09, 0x48, 0xdb, 0xab, 0x53, 0xef, 0x9c, 0x12,
-	0xc6, 0x44, 0xfa, 0xa6, 0x8f, 0x6a, 0xca, 0x47, 0x2d, 0x40, 0xaa, 0x33, 0xa6, 0xc2, 0x55, 0x79,
-	0xb7, 0x69, 0x07, 0xa4, 0x00, 0x59, 0xee, 0x71, 0x3c, 0xd4, 0x79, 0x52, 0x84, 0xb5, 0x3d, 0xb9,
-	0xd5, 0xf6, 0x86, 0xc3, 0x36, 0xee, 0x0c, 0x16, 0xdc, 0x6a, 0xd9, 0xf0, 0xa2, 0x76, 0x30, 0xe6,
-	0xbd, 0x2a, 0xf9, 0xe3, 0x41, 0x47, 0xf8, 0xd3, 0xf4, 0x06, 0xc4, 0x3d, 0xa6, 0xde, 0xc8, 0x26,
-	0x5d, 0x4a, 0x58, 0x4f, 0xd2, 0x42, 0x33, 0x78, 0x2d, 0x23, 0xf4, 0x5a, 0xd2, 0xf2, 0x80, 0x04,
-	0x23, 0x51, 0x11, 0xd6, 0x6b, 0x58, 0x5f, 0x70, 0xa7, 0x4c, 0xe3, 0x44, 0xc1, 0x08, 0x2b, 0x9c,
-	0xc0, 0xaa, 0x00, 0xa8, 0x3a, 0x1e, 0x33, 0x52, 0xbe, 0x24, 0xae, 0x2c, 0x9f, 0x02, 0xa4, 0x46,
-	0x84, 0x31, 0x7c, 0x1e, 0x98, 0x0c, 0x48, 0x71, 0x42, 0xbb, 0x9d, 0xbd, 0xbd, 0xbd, 0x77, 0x93,
-	0x1d, 0x4d, 0x91, 0x56, 0x49, 0xc4, 0xec, 0x0a, 0xf8, 0x1e, 0x8f, 0xee, 0x70, 0x93, 0xf5, 0x1c,
-	0x56, 0x4f, 0x08, 0xd7, 0xc3, 0xe7, 0xd4, 0xfe, 0x22, 0x7d, 0xd4, 0x6d, 0x62, 0x4c, 0xda, 0x64,
-	0xf7, 0x5f, 0x26, 0x2c, 0x85, 0x86, 0x15, 0x7a, 0x0d, 0x71, 0xf1, 0xa7, 0x07, 0x3d, 0x9e, 0x9f,
-	0xa3, 0xfa, 0xcf, 0x50, 0x71, 0x39, 0xe8, 0x61, 0xf1, 0x87, 0x09, 0x1d, 0x42, 0x52, 0xf5, 0x2c,
-	0xda, 0xb8, 0x7e, 0x3d, 0xff, 0x5e, 0x7c, 0x72, 0xd3, 0xee, 0x8e, 0xfe, 0x00, 0xb9, 0xe8, 0x26,
-	0x8b, 0x16, 0xec, 0xfa, 0x73, 0x3b, 0x74, 0x71, 0xfb, 0x76, 0x21, 0xe6, 0xa3, 0x6f, 0x90, 0x8d,
-	0xac, 0x65, 0xc8, 0xba, 0x7d, 0xc3, 0x2c, 0x3e, 0xbb, 0xc3, 0x6e, 0x27, 0x62, 0x57, 0x1b, 0xcf,
-	0xa2, 0xd8, 0x27, 0x9b, 0xd9, 0xa2, 0xd8, 0x43, 0x8b, 0xd2, 0x31, 0xa4, 0xf4, 0x2b, 0xa1, 0x27,
-	0x37, 0x2d, 0x2f, 0xc5, 0xcd, 0x1b, 0x4e, 0x99, 0xbf, 0xfb, 0xb7, 0x24, 0x24, 0xe4, 0x3f, 0x2f,
-	0xf4, 0xdb, 0x60, 0x02, 0xeb, 0x91, 0x8f, 0xd6, 0x23, 0xa8, 0x3b, 0xdd, 0x1d, 0x8a, 0x85, 0xc5,
-	0x07, 0xcc, 0x47, 0xbf, 0x04, 0x98, 0x4e, 0x71, 0xb4, 0x16, 0x91, 0xd3, 0x83, 0x7d, 0xa6, 0x10,
-	0x7e, 0x05, 0xcb, 0xe1, 0x51, 0x8c, 0x02, 0xa8, 0x9f, 0x99, 0xcf, 0x33, 0x5a, 0xfb, 0x81, 0xaf,
-	0x1a, 0xf2, 0x67, 0x7c, 0x9d, 0x8e, 0xc3, 0x19, 0xbd, 0x8f, 0xc2, 0xda, 0x14, 0xc8, 0x42, 0xd6,
-	0x22, 0x48, 0x5d, 0x5c, 0x5f, 0xc8, 0x67, 0x3e, 0x7a, 0x3f, 0x41, 0xf5, 0x00, 0x99, 0x26, 0x35,
-	0x3f, 0x8f, 0x58, 0x33, 0xc6, 0xa7, 0xba, 0x01, 0xfe, 0xcc, 0xea, 0x86, 0x70, 0x69, 0xae, 0x5f,
-	0x7e, 0x58, 0x80, 0x20, 0x28, 0x22, 0x54, 0x0c, 0xd6, 0xdf, 0xeb, 0xb0, 0x86, 0xc3, 0xb3, 0x3b,
-	0x40, 0x1b, 0xfa, 0xc5, 0xf5, 0xd7, 0x2c, 0x80, 0xc1, 0x5b, 0xad, 0xbe, 0x85, 0x5c, 0x14, 0xcb,
-	0x50, 0x21, 0xf4, 0xc6, 0x11, 0x88, 0x5b, 0x94, 0xb0, 0x08, 0x78, 0x85, 0x12, 0x36, 0x0b, 0x6a,
-	0x33, 0xba, 0xbf, 0x86, 0x6c, 0x04, 0xc8, 0x66, 0x52, 0x15, 0xb8, 0x30, 0x07, 0x76, 0xed, 0xa4,
-	0x3c, 0xd8, 0xfb, 0x5f, 0x00, 0x00, 0x00, 0xff, 0xff, 0x31, 0xeb, 0x1e, 0x0e, 0xef, 0x11, 0x00,
-	0x00,
+	// 1741 bytes of a gzipped FileDescriptorProto
+	0x1f, 0x8b, 0x08, 0x00, 0x00, 0x00, 0x00, 0x00, 0x02, 0xff, 0xb5, 0x17, 0xcb, 0x72, 0x1a, 0x47,
+	0xd0, 0x2b, 0x1e, 0x82, 0x16, 0x48, 0x68, 0x2c, 0x59, 0x18, 0xdb, 0x49, 0x6a, 0x65, 0x3b, 0x8e,
+	0xe3, 0xe0, 0x44, 0x4a, 0x1c, 0x3f, 0x0e, 0x8e, 0x22, 0x21, 0x59, 0xb6, 0x0c, 0xd4, 0x82, 0x64,
+	0x57, 0xe5, 0x40, 0x2d, 0x30, 0x08, 0x24, 0xd8, 0xc5, 0xbb, 0x8b, 0x1d, 0x55, 0xe5, 0x13, 0x72,
+	0xcb, 0x39, 0x97, 0x7c, 0x41, 0xbe, 0x21, 0x97, 0x54, 0xe5, 0x3b, 0x52, 0x95, 0xdf, 0x48, 0xf7,
+	0xcc, 0x2c, 0xec, 0x02, 0x7a, 0x54, 0x29, 0x39, 0x50, 0x6c, 0xf7, 0x74, 0x4f, 0xbf, 0x1f, 0x03,
+	0xd0, 0xe4, 0x2d, 0x37, 0xdf, 0x77, 0x6c, 0xcf, 0x66, 0x31, 0xf1, 0xa7, 0xcf, 0x42, 0xac, 0xd0,
+	0xeb, 0x7b, 0x27, 0xfa, 0x57, 0xc0, 0x76, 0x2d, 0x8f, 0x1f, 0x3a, 0xa6, 0xd7, 0xb1, 0xad, 0x5d,
+	0xab, 0xe3, 0x19, 0xfc, 0x1d, 0xbb, 0x01, 0x49, 0x97, 0x3b, 0xef, 0xb9, 0x53, 0xeb, 0x34, 0xb3,
+	0xda, 0x27, 0xda, 0xbd, 0xb4, 0x91, 0x90, 0x88, 0xdd, 0xa6, 0x5e, 0x84, 0xa5, 0x00, 0x4b, 0xe1,
+	0xc7, 0xbe, 0xed, 0x08, 0xa6, 0x47, 0x10, 0x6f, 0xd8, 0x56, 0xab, 0x73, 0x28, 0x38, 0xe6, 0xd6,
+	0x3e, 0x92, 0x22, 0xf3, 0x13, 0xc4, 0x9b, 0x82, 0xca, 0x50, 0xd4, 0xfa, 0xef, 0x1a, 0xac, 0x9c,
+	0x42, 0x83, 0x77, 0xae, 0x74, 0x46, 0x47, 0x35, 0xc9, 0x51, 0x3b, 0x72, 0x6d, 0x4b, 0x08, 0x49,
+	0x19, 0xcb, 0x81, 0x63, 0xc9, 0xf3, 0x12, 0x0f, 0xd9, 0x77, 0x90, 0x32, 0x0f, 0xb9, 0xe5, 0x29,
+	0x8e, 0xec, 0x8c, 0xd0, 0xe8, 0xd6, 0xa4, 0x46, 0x1b, 0x44, 0xa5, 0x14, 0x9a, 0x33, 0x47, 0x00,
+	0xb9, 0x60, 0xe0, 0xf2, 0x9a, 0x6d, 0x0e, 0xbc, 0x76, 0x36, 0x82, 0xec, 0x09, 0x23, 0x81, 0x88,
+	0x12, 0xc1, 0xfa, 0x13, 0xb8, 0x36, 0xfd, 0x0e, 0xf6, 0x31, 0xcc, 0x35, 0x06, 0xae, 0x67, 0xf7,
+	0x46, 0xbe, 0x4b, 0x1a, 0xe0, 0xa3, 0xd0, 0x7b, 0x6f, 0x61, 0x79, 0x8a, 0xf7, 0xdc, 0x3e, 0x7b,
+	0x0e, 0x09, 0xd4, 0xee, 0x88, 0x37, 0x3c, 0x17, 0xe5, 0x45, 0x50, 0xdd, 0xd5, 0xd3, 0x1c, 0x48,
+	0xf4, 0x65, 0x49, 0x6b, 0x0c, 0x99, 0xf4, 0x9f, 0xe0, 0xe6, 0x59, 0x94, 0x6c, 0x1e, 0x66, 0x86,
+	0x1a, 0xe1, 0x17, 0x5b, 0x86, 0xb8, 0xc3, 0x5b, 0xa4, 0xe5, 0x8c, 0xc0, 0xc5, 0x10, 0xda, 0x6d,
+	0x92, 0x05, 0x0e, 0x37, 0x9b, 0x66, 0xbd, 0xcb, 0xe9, 0x2c, 0x22, 0x2d, 0xf0, 0x51, 0x48, 0xb0,
+	0x04, 0x31, 0xee, 0x38, 0xb6, 0x93, 0x8d, 0x4a, 0x36, 0x01, 0xe8, 0x07, 0x21, 0xe9, 0x07, 0x66,
+	0xb7, 0xd3, 0x34, 0x3d, 0xae, 0x3c, 0x7b, 0x89, 0xec, 0x38, 0x81, 0x5b, 0x67, 0xdc, 0x8b, 0x7e,
+	0xbb, 0x06, 0x71, 0xa1, 0x81, 0x8b, 0x17, 0x47, 0x50, 0x1f, 0x05, 0xb1, 0xeb, 0x90, 0x70, 0x78,
+	0xdf, 0xae, 0x0d, 0x9c, 0xae, 0x32, 0x70, 0x96, 0xe0, 0x7d, 0xa7, 0xcb, 0xee, 0xc0, 0xbc, 0x4a,
+	0x6f, 0xfc, 0xb9, 0x78, 0xad, 0xb2, 0x32, 0x2d, 0xb1, 0x07, 0x12, 0xa9, 0xff, 0xa3, 0xc1, 0x8d,
+	0x80, 0xec, 0x92, 0x55, 0xb7, 0x4d, 0xa7, 0x79, 0xe9, 0x84, 0x67, 0xcf, 0x20, 0x7a, 0xdc, 0xb1,
+	0xa4, 0xdb, 0xe7, 0xd7, 0x3e, 0x9d, 0xe4, 0x1a, 0x97, 0x94, 0x7f, 0x85, 0xe4, 0x86, 0x60, 0xd2,
+	0x2b, 0x10, 0x25, 0x88, 0x25, 0x21, 0xb6, 0x5f, 0x29, 0x18, 0x95, 0xcc, 0x15, 0xfa, 0x34, 0x0a,
+	0xe5, 0x52, 0x25, 0xa3, 0xb1, 0x14, 0x24, 0xca, 0x46, 0xe9, 0x65, 0x61, 0xb3, 0x5a, 0xc9, 0xcc,
+	0x60, 0xc4, 0xe1, 0x4d, 0xc9, 0x78, 0xb5, 0x59, 0x2a, 0x6e, 0xef, 0xee, 0x64, 0x22, 0x2c, 0x0d,
+	0xc9, 0xcd, 0x8d, 0xbd, 0x42, 0x71, 0x6b, 0x03, 0xf9, 0xa2, 0x2c, 0x01, 0xd1, 0xf2, 0xde, 0x46,
+	0x31, 0x13, 0xd3, 0x7f, 0xd3, 0x42, 0xd1, 0x1b, 0x93, 0x2f, 0x92, 0x53, 0xc5, 0x5c, 0x13, 0x3a,
+	0x7f, 0x76, 0xae, 0xce, 0x6e, 0x3f, 0x5f, 0x20, 0x06, 0x95, 0x1e, 0x54, 0x4e, 0x18, 0x37, 0x53,
+	0x96, 0xee, 0x8c, 0x28, 0xdd, 0x04, 0x21, 0xa8, 0x5a, 0xf5, 0xdb, 0xd8, 0x8d, 0x04, 0x15, 0x6a,
+	0x54, 0x2c, 0x15, 0x0b, 0x68, 0xd3, 0x22, 0xa4, 0x8b, 0xa5, 0x6a, 0xad, 0xb2, 0x5f, 0x2e, 0x97,
+	0x8c, 0x6a, 0x61, 0x2b, 0xa3, 0xe9, 0x3f, 0x6b, 0xa1, 0xc6, 0xf3, 0x7a, 0xe0, 0x61, 0x22, 0x5c,
+	0x26, 0x0e, 0xa8, 0x53, 0x4f, 0x5c, 0x52, 0x6b, 0x59, 0x2a, 0x45, 0x12, 0x12, 0xb1, 0x6d, 0x51,
+	0x19, 0xa8, 0x43, 0x52, 0xd3, 0x2f, 0x03, 0x89, 0xda, 0x42, 0x8c, 0xfe, 0x79, 0xa8, 0x90, 0x7d,
+	0x6d, 0xd0, 0x57, 0x0c, 0xa2, 0xc3, 0x06, 0x95, 0x34, 0xc4, 0xb7, 0xfe, 0xb7, 0x16, 0xa2, 0x7e,
+	0xc3, 0xeb, 0x6d, 0xdb, 0x3e, 0xbe, 0x8c, 0xf2, 0x9b, 0x30, 0xdb, 0xc6, 0x9a, 0xc4, 0x54, 0x45,
+	0xd5, 0xa9, 0x5b, 0x4c, 0x89, 0xc9, 0x48, 0x4c, 0xfe, 0x85, 0xa4, 0x2d, 0x58, 0x9e, 0x73, 0x62,
+	0xf8, 0x9c, 0xa4, 0x6a, 0xdd, 0x6e, 0x9e, 0x28, 0xeb, 0xc4, 0x77, 0xee, 0x29, 0xa4, 0x82, 0xc4,
+	0x2c, 0x03, 0x91, 0x63, 0x7e, 0xa2, 0xac, 0xa1, 0x4f, 0x6a, 0x00, 0xef, 0xcd, 0xee, 0x80, 0xfb,
+	0x7d, 0x43, 0x00, 0x4f, 0x67, 0x1e, 0x6b, 0xfa, 0x83, 0x50, 0x5f, 0x1c, 0x8a, 0x3f, 0xc5, 0x29,
+	0xf7, 0x21, 0xbd, 0x67, 0xba, 0x1e, 0xf6, 0xa7, 0x06, 0x77, 0x5d, 0xde, 0xa4, 0x92, 0x15, 0x49,
+	0xe2, 0x7a, 0x8e, 0x22, 0x9c, 0x25, 0xb8, 0xe2, 0x39, 0x38, 0xa7, 0x32, 0xd2, 0x0d, 0x15, 0xcf,
+	0x74, 0x3c, 0xde, 0x24, 0xd7, 0xdd, 0x02, 0xe8, 0xd9, 0x4d, 0xde, 0xad, 0x79, 0x27, 0x7d, 0xae,
+	0x18, 0x92, 0x02, 0x53, 0x45, 0x84, 0x6e, 0xc3, 0xe2, 0x18, 0x0b, 0xea, 0x81, 0x3c, 0x2e, 0x0a,
+	0xa3, 0x61, 0x32, 0x6c, 0x86, 0x49, 0x85, 0xc1, 0xde, 0xf6, 0x0c, 0xe6, 0xbb, 0xa8, 0x52, 0xad,
+	0xef, 0xeb, 0xa4, 0x26, 0xc7, 0x92, 0x72, 0x6e, 0x48, 0x5f, 0x23, 0xdd, 0x0d, 0x82, 0xfa, 0x31,
+	0xa4, 0xa5, 0xc0, 0x2d, 0xdb, 0xe2, 0x4a, 0xc1, 0xff, 0x4d, 0xd8, 0x01, 0x2c, 0x54, 0xb8, 0xa5,
+	0xea, 0x6d, 0xe8, 0x8f, 0xb3, 0xc4, 0xdd, 0x86, 0xa8, 0x5d, 0x3f, 0xf2, 0x87, 0x4b, 0x46, 0x09,
+	0x91, 0x17, 0x94, 0xea, 0x47, 0x86, 0x38, 0xd5, 0x7b, 0x90, 0x1c, 0xa2, 0x30, 0x39, 0x65, 0xd5,
+	0x0e, 0x1d, 0x3c, 0xbf, 0x76, 0x7d, 0x9c, 0x2f, 0x4f, 0xd5, 0x40, 0x0e, 0x97, 0x05, 0x4d, 0x5f,
+	0x14, 0x6d, 0x51, 0x35, 0xb2, 0xd0, 0xc5, 0xb7, 0xbe, 0x04, 0x09, 0x9f, 0x92, 0xea, 0xfc, 0x65,
+	0xa5, 0x54, 0xcc, 0x5c, 0xd1, 0x7f, 0x8d, 0xf8, 0x81, 0xdd, 0xa1, 0xd5, 0xa3, 0x6f, 0x93, 0x21,
+	0x2b, 0x20, 0x5a, 0xf5, 0xc8, 0x8a, 0x38, 0x81, 0x72, 0x36, 0x0d, 0xac, 0xce, 0xbb, 0x01, 0xaf,
+	0x59, 0x66, 0xcf, 0xcf, 0x3f, 0x90, 0xa8, 0x22, 0x62, 0x64, 0xd3, 0x6f, 0x49, 0x7d, 0x23, 0x7e,
+	0xd3, 0x6f, 0x09, 0x99, 0x98, 0xc7, 0x34, 0x0a, 0xe4, 0xd0, 0xa2, 0x4f, 0x96, 0x87, 0xab, 0x0d,
+	0xbb, 0xd7, 0xeb, 0x78, 0x34, 0x23, 0x6a, 0x1e, 0xef, 0xf5, 0xbb, 0x58, 0xc3, 0xd9, 0x98, 0xa0,
+	0x58, 0x94, 0x47, 0x38, 0x2e, 0xaa, 0xea, 0x80, 0xe8, 0xeb, 0x8e, 0x69, 0x35, 0xda, 0x61, 0xfa,
+	0xb8, 0xa4, 0x97, 0x47, 0x41, 0xfa, 0x7b, 0x10, 0xe9, 0x63, 0x79, 0xce, 0x0a, 0x7f, 0x5f, 0x0b,
+	0xf9, 0x4d, 0x19, 0x5b, 0x36, 0x0c, 0x22, 0x21, 0xbb, 0xcc, 0x06, 0x05, 0xb6, 0x46, 0xe9, 0x9b,
+	0x4d, 0x48, 0xbb, 0x24, 0xea, 0x35, 0x62, 0x58, 0x0e, 0x68, 0xf9, 0x70, 0x84, 0xd5, 0x49, 0xd9,
+	0xa9, 0x7c, 0x98, 0xce, 0xfa, 0xa6, 0xeb, 0x7e, 0xb0, 0x9d, 0x66, 0x16, 0xe4, 0x99, 0x0f, 0x93,
+	0x27, 0x5d, 0xb7, 0x5d, 0xa3, 0x02, 0x9e, 0x93, 0x9e, 0x44, 0xf0, 0x15, 0xd6, 0xf0, 0x5d, 0x58,
+	0x10, 0x07, 0x96, 0xfd, 0xc1, 0xaa, 0xb5, 0x6d, 0x17, 0x97, 0x8e, 0x94, 0x9a, 0x81, 0x48, 0x40,
+	0xd8, 0x17, 0x84, 0xd4, 0x7f, 0xd1, 0x60, 0x61, 0x4c, 0xe5, 0x8b, 0x2e, 0x12, 0xca, 0xe1, 0x91,
+	0x91, 0xc3, 0xd1, 0x4c, 0xe5, 0x40, 0x61, 0x88, 0x0c, 0x05, 0x48, 0x94, 0x08, 0x1f, 0x6a, 0x25,
+	0x2a, 0x42, 0x85, 0xc5, 0x6d, 0x9b, 0x2a, 0x1a, 0x22, 0xf9, 0x37, 0x05, 0xb6, 0xd2, 0x36, 0xf5,
+	0xbf, 0x34, 0xca, 0x7e, 0x91, 0xd8, 0xa2, 0xb8, 0x29, 0x69, 0xf0, 0xf2, 0x8e, 0x5b, 0xf3, 0x1c,
+	0xb3, 0x81, 0x73, 0x52, 0x76, 0xd3, 0x84, 0x01, 0x1d, 0xb7, 0xaa, 0x30, 0x94, 0x94, 0x81, 0xac,
+	0x11, 0xdf, 0xec, 0x3e, 0x2c, 0xf6, 0x4d, 0x87, 0x16, 0xc5, 0x40, 0xe5, 0x90, 0xc6, 0x11, 0x63,
+	0x41, 0x1e, 0x54, 0x86, 0xf5, 0x73, 0x0f, 0x32, 0x8a, 0x16, 0x0b, 0x05, 0x17, 0x2a, 0x22, 0x95,
+	0x26, 0xcc, 0x4b, 0x7c, 0x49, 0xa0, 0x91, 0xf2, 0x01, 0xb0, 0x30, 0xa5, 0x90, 0x2b, 0x2d, 0xc9,
+	0x04, 0x69, 0xc9, 0x68, 0xdd, 0x82, 0x4c, 0xd8, 0x96, 0xa9, 0x6d, 0x2a, 0xf2, 0x9f, 0x75, 0x8e,
+	0x2a, 0x30, 0x25, 0x0f, 0x71, 0x87, 0x0e, 0x7e, 0x92, 0xfb, 0x46, 0x41, 0x8d, 0x88, 0xa0, 0x66,
+	0x61, 0xb6, 0x31, 0x70, 0x48, 0x55, 0x71, 0x77, 0xc4, 0xf0, 0x41, 0x6a, 0xff, 0x9e, 0xed, 0x99,
+	0x5d, 0xe5, 0x27, 0x09, 0xe0, 0x0c, 0xf7, 0x6f, 0x35, 0xec, 0x6e, 0xb7, 0x8e, 0x3e, 0x9f, 0x72,
+	0xab, 0x6e, 0xc0, 0xdd, 0xd2, 0x06, 0x6e, 0xd0, 0x45, 0xfe, 0x61, 0x43, 0x64, 0x77, 0xd5, 0x3e,
+	0xe6, 0xd6, 0xb6, 0x63, 0xf7, 0x0c, 0xde, 0x42, 0x55, 0xda, 0x02, 0x26, 0x4e, 0x3f, 0x5a, 0x5a,
+	0x20, 0x5a, 0x42, 0x32, 0x9e, 0xfb, 0x79, 0x26, 0x00, 0xfd, 0x21, 0xac, 0x4c, 0xb9, 0x53, 0xb8,
+	0x71, 0xc8, 0xa0, 0x05, 0x19, 0x76, 0x60, 0x91, 0x5a, 0x67, 0xd9, 0xc4, 0x12, 0x2a, 0xbc, 0x47,
+	0x93, 0x48, 0x1e, 0xda, 0xdb, 0x43, 0x5e, 0x7c, 0x02, 0xf8, 0xa3, 0x47, 0x81, 0x74, 0xe2, 0xb4,
+	0x1a, 0xeb, 0xeb, 0xeb, 0x4f, 0x86, 0x7b, 0xa4, 0x04, 0xf5, 0x3c, 0xd9, 0x6c, 0xd1, 0x60, 0x19,
+	0xf4, 0x2e, 0x70, 0x93, 0x7e, 0x07, 0x16, 0x77, 0xb8, 0xa7, 0xc6, 0xe2, 0xbe, 0xb1, 0x27, 0x74,
+	0x54, 0x65, 0xa2, 0x0d, 0xcb, 0x44, 0xff, 0x06, 0xe6, 0xe9, 0xda, 0xd7, 0xdc, 0x73, 0x3a, 0x0d,
+	0x11, 0x9c, 0x55, 0x48, 0xbb, 0x96, 0xd9, 0x77, 0xdb, 0xb6, 0x17, 0x7c, 0xfc, 0xa4, 0x7c, 0x24,
+	0x6d, 0x51, 0x6b, 0x7f, 0x46, 0x60, 0x2e, 0x30, 0x7d, 0xd9, 0x43, 0x88, 0xd2, 0x7b, 0x8e, 0x5d,
+	0x9f, 0x5c, 0x0c, 0xd4, 0x3b, 0x2f, 0x97, 0xf2, 0x9b, 0x12, 0xbd, 0x05, 0x71, 0xa5, 0x88, 0xcb,
+	0x52, 0x67, 0x37, 0x4e, 0x7f, 0x79, 0xbc, 0xcb, 0xdd, 0x3c, 0xeb, 0x59, 0xc2, 0x7e, 0x80, 0xf9,
+	0xf0, 0x92, 0xce, 0xa6, 0x3c, 0x63, 0x26, 0x9e, 0x07, 0xb9, 0xdb, 0xe7, 0x13, 0xe1, 0xe5, 0x6f,
+	0x21, 0x1d, 0xda, 0x33, 0x99, 0x7e, 0xfe, 0xf2, 0x9c, 0x5b, 0xbd, 0xc0, 0xb2, 0x4a, 0xb6, 0xcb,
+	0x15, 0x6e, 0x9a, 0xed, 0xc3, 0x55, 0x73, 0x9a, 0xed, 0x81, 0xcd, 0x6f, 0x1b, 0x66, 0x55, 0x70,
+	0xd9, 0xcd, 0xb3, 0xb6, 0xb1, 0xdc, 0xad, 0x33, 0x4e, 0xdd, 0xfe, 0xda, 0x1f, 0x71, 0x88, 0x89,
+	0x47, 0x25, 0xfb, 0xde, 0x5f, 0x29, 0xd4, 0x0e, 0xc3, 0x56, 0x42, 0x63, 0x64, 0xb4, 0x0c, 0xe5,
+	0xb2, 0xd3, 0x0f, 0x50, 0xab, 0x2f, 0x01, 0x46, 0x6b, 0x09, 0x5b, 0x0a, 0xd1, 0xa9, 0x4d, 0x65,
+	0x2c, 0x11, 0xbe, 0x86, 0x54, 0x70, 0xb7, 0x60, 0xfe, 0xec, 0x1a, 0x5b, 0x38, 0xc6, 0xb8, 0x1e,
+	0xf9, 0xba, 0xaa, 0x49, 0x31, 0xa6, 0xeb, 0x68, 0xbe, 0x8f, 0xf1, 0x3d, 0x27, 0x69, 0xa3, 0xfe,
+	0x17, 0x90, 0x16, 0x6a, 0xf0, 0xb9, 0x95, 0xa9, 0x78, 0x34, 0xf0, 0xe9, 0x70, 0x18, 0xf8, 0x0d,
+	0x6d, 0x98, 0xf3, 0x93, 0x8d, 0x6e, 0x4c, 0xf8, 0x88, 0xd7, 0x6f, 0x5b, 0xe3, 0xbc, 0x81, 0x76,
+	0x36, 0x51, 0x2f, 0x57, 0xa7, 0x34, 0x1e, 0x16, 0x22, 0xca, 0xf9, 0xfb, 0xfc, 0x69, 0x2d, 0xca,
+	0x83, 0xd5, 0x0b, 0x74, 0x44, 0xf6, 0xc5, 0xe9, 0xd7, 0x4c, 0xe9, 0x9e, 0xe7, 0x4a, 0x7d, 0x2c,
+	0x5b, 0xcc, 0xa8, 0x05, 0xb2, 0x6c, 0x20, 0xc6, 0xa1, 0xce, 0x38, 0xcd, 0x61, 0xa1, 0x9e, 0x17,
+	0x70, 0xd8, 0x78, 0x2f, 0x1c, 0xe3, 0xfd, 0x16, 0xd2, 0xa1, 0xfe, 0x37, 0xe6, 0x2a, 0x5f, 0x85,
+	0xc9, 0x1e, 0xb9, 0x06, 0x73, 0x81, 0x8e, 0xc8, 0x96, 0x03, 0x02, 0x47, 0x5d, 0x32, 0x2c, 0xac,
+	0x1e, 0x17, 0xc0, 0xfa, 0xbf, 0x28, 0x63, 0xec, 0xc7, 0xfe, 0x12, 0x00, 0x00,
 }
 
 // Reference imports to suppress errors if they are not otherwise used.
@@ -2060,6 +2149,7 @@ type AgentClient interface {
 	SendPauseEvent(ctx context.Context, in *SendPauseEventReq, opts ...grpc.CallOption) (*Empty, error)
 	SendResumeEvent(ctx context.Context, in *SendResumeEventReq, opts ...grpc.CallOption) (*Empty, error)
 	GetWebhookURL(ctx context.Context, in *Empty, opts ...grpc.CallOption) (*GetWebhookURLResp, error)
+	SendMetrics(ctx context.Context, in *SendMetricsReq, opts ...grpc.CallOption) (*Empty, error)
 }
 
 type agentClient struct {
@@ -2178,6 +2268,15 @@ func (c *agentClient) GetWebhookURL(ctx context.Context, in *Empty, opts ...grpc
 	return out, nil
 }
 
+func (c *agentClient) SendMetrics(ctx context.Context, in *SendMetricsReq, opts ...grpc.CallOption) (*Empty, error) {
+	out := new(Empty)
+	err := c.cc.Invoke(ctx, "/proto.Agent/SendMetrics", in, out, opts...)
+	if err != nil {
+		return nil, err
+	}
+	return out, nil
+}
+
 // AgentServer is the server API for Agent service.
 type AgentServer interface {
 	ExportStarted(context.Context, *ExportStartedReq) (*ExportStartedResp, error)
@@ -2193,6 +2292,7 @@ type AgentServer interface {
 	SendPauseEvent(context.Context, *SendPauseEventReq) (*Empty, error)
 	SendResumeEvent(context.Context, *SendResumeEventReq) (*Empty, error)
 	GetWebhookURL(context.Context, *Empty) (*GetWebhookURLResp, error)
+	SendMetrics(context.Context, *SendMetricsReq) (*Empty, error)
 }
 
 // UnimplementedAgentServer can be embedded to have forward compatible implementations.
@@ -2235,6 +2335,9 @@ func (*UnimplementedAgentServer) SendResumeEvent(ctx context.Context, req *SendR
 func (*UnimplementedAgentServer) GetWebhookURL(ctx context.Context, req *Empty) (*GetWebhookURLResp, error) {
 	return nil, status.Errorf(codes.Unimplemented, "method GetWebhookURL not implemented")
 }
+func (*UnimplementedAgentServer) SendMetrics(ctx context.Context, req *SendMetricsReq) (*Empty, error) {
+	return nil, status.Errorf(codes.Unimplemented, "method SendMetrics not implemented")
+}
 
 func RegisterAgentServer(s *grpc.Server, srv AgentServer) {
 	s.RegisterService(&_Agent_serviceDesc, srv)
@@ -2456,6 +2559,24 @@ func _Agent_GetWebhookURL_Handler(srv interface{}, ctx context.Context, dec func
 	return interceptor(ctx, in, info, handler)
 }
 
+func _Agent_SendMetrics_Handler(srv interface{}, ctx context.Context, dec func(interface{}) error, interceptor grpc.UnaryServerInterceptor) (interface{}, error) {
+	in := new(SendMetricsReq)
+	if err := dec(in); err != nil {
+		return nil, err
+	}
+	if interceptor == nil {
+		return srv.(AgentServer).SendMetrics(ctx, in)
+	}
+	info := &grpc.UnaryServerInfo{
+		Server:     srv,
+		FullMethod: "/proto.Agent/SendMetrics",
+	}
+	handler := func(ctx context.Context, req interface{}) (interface{}, error) {
+		return srv.(AgentServer).SendMetrics(ctx, req.(*SendMetricsReq))
+	}
+	return interceptor(ctx, in, info, handler)
+}
+
 var _Agent_serviceDesc = grpc.ServiceDesc{
 	ServiceName: "proto.Agent",
 	HandlerType: (*AgentServer)(nil),
@@ -2508,6 +2629,10 @@ var _Agent_serviceDesc = grpc.ServiceDesc{
 			MethodName: "GetWebhookURL",
 			Handler:    _Agent_GetWebhookURL_Handler,
 		},
+		{
+			MethodName: "SendMetrics",
+			Handler:    _Agent_SendMetrics_Handler,
+		},
 	},
 	Streams:  []grpc.StreamDesc{},
 	Metadata: "defs.proto",
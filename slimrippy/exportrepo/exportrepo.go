@@ -3,8 +3,11 @@ package exportrepo
 import (
 	"bytes"
 	"context"
+	"crypto/sha1"
+	"encoding/hex"
 	"errors"
 	"fmt"
+	"io/ioutil"
 	"os"
 	"os/exec"
 	"path/filepath"
@@ -12,9 +15,12 @@ import (
 	"strings"
 	"time"
 
+	"github.com/pinpt/agent/pkg/codeownership"
+	"github.com/pinpt/agent/pkg/commitfiles"
 	"github.com/pinpt/agent/pkg/commitusers"
 	"github.com/pinpt/agent/pkg/date"
 	"github.com/pinpt/agent/pkg/filestore"
+	"github.com/pinpt/agent/pkg/gittags"
 
 	"github.com/pinpt/agent/cmd/cmdexport/process"
 	"github.com/pinpt/agent/slimrippy/slimrippy"
@@ -64,6 +70,22 @@ type Opts struct {
 	PRs []PR
 
 	CommitUsers *process.CommitUsers
+
+	// CommitFiles enables export of per file stats for commits as sourcecode.CommitFile
+	CommitFiles bool
+
+	// Tags enables export of tags and releases as sourcecode.Tag and sourcecode.Release
+	Tags bool
+
+	// Mailmap enables mapping of commit authors using .mailmap file from the repo
+	Mailmap bool
+	// AliasFile is an optional file in .mailmap format with agent level author aliases
+	AliasFile string
+
+	// Ownership enables export of blame based code ownership snapshot of default branch as sourcecode.FileOwnership and sourcecode.DirectoryOwnership
+	Ownership bool
+	// OwnershipInterval is the minimum time between ownership snapshots
+	OwnershipInterval time.Duration
 }
 
 type SessionManger interface {
@@ -138,7 +160,7 @@ func (s *Export) Run(ctx context.Context) (res Result) {
 	s.logger = s.logger.With("repo", s.repoNameUsedInCacheDir)
 	s.lastProcessedKey = []string{"ripsrc-v3", s.repoNameUsedInCacheDir}
 
-	s.sessions = newSessions(s.opts.Sessions, s.opts.SessionRootID, s.repoNameUsedInCacheDir)
+	s.sessions = newSessions(s.opts.Sessions, s.opts.SessionRootID, s.repoNameUsedInCacheDir, s.opts.CommitFiles, s.opts.Tags, s.opts.Ownership)
 
 	err := s.sessions.Open()
 	if err != nil {
@@ -225,6 +247,15 @@ func (s *Export) run(ctx context.Context) (duration ExportDuration, rerr error)
 		prsStr = append(prsStr, pr.LastCommitSHA)
 	}
 	opts.PullRequestSHAs = prsStr
+	opts.CommitFiles = s.opts.CommitFiles
+	opts.Tags = s.opts.Tags
+	opts.Mailmap = s.opts.Mailmap
+	opts.AliasFile = s.opts.AliasFile
+	opts.Ownership = s.opts.Ownership
+	opts.OwnershipInterval = s.opts.OwnershipInterval
+	opts.OwnershipCallback = s.ownership
+	opts.TagCallback = s.tag
+	opts.ReleaseCallback = s.release
 	opts.BranchCallback = s.branch
 	opts.CommitCallback = s.commit
 	s.logger.Debug("will export n PRs in exportrepo", "len(pr)", len(s.opts.PRs))
@@ -288,6 +319,16 @@ type skipRipsrcData struct {
 	Branches map[string]slimrippy.BranchLastCommit
 	// map[pr.ID]pr.Commit
 	PRCommits map[string]string
+	// CommitFiles is the value of Opts.CommitFiles used in the last run, all commits are processed again when it is enabled
+	CommitFiles bool
+	// Tags maps tag name to ref hash, only set when Opts.Tags is enabled
+	Tags map[string]string
+	// Mailmap is the value of Opts.Mailmap used in the last run
+	Mailmap bool
+	// AliasFile is the hash of alias file content used in the last run
+	AliasFile string
+	// Ownership is the value of Opts.Ownership used in the last run
+	Ownership bool
 }
 
 func (s *Export) skipRipsrc(ctx context.Context, checkoutDir string, prs []PR) (skip bool, newData skipRipsrcData, rerr error) {
@@ -295,6 +336,17 @@ func (s *Export) skipRipsrc(ctx context.Context, checkoutDir string, prs []PR) (
 	data := skipRipsrcData{}
 
 	getNewData := func() error {
+		newData.CommitFiles = s.opts.CommitFiles
+		newData.Mailmap = s.opts.Mailmap
+		newData.Ownership = s.opts.Ownership
+		if s.opts.AliasFile != "" {
+			b, err := ioutil.ReadFile(s.opts.AliasFile)
+			if err != nil {
+				return fmt.Errorf("could not read alias file: %v", err)
+			}
+			h := sha1.Sum(b)
+			newData.AliasFile = hex.EncodeToString(h[:])
+		}
 		newData.Branches = map[string]slimrippy.BranchLastCommit{}
 		br, err := slimrippy.GetBranchesWithLastCommit(ctx, checkoutDir)
 		if err != nil {
@@ -310,6 +362,12 @@ func (s *Export) skipRipsrc(ctx context.Context, checkoutDir string, prs []PR) (
 		for _, pr := range prs {
 			newData.PRCommits[pr.ID] = pr.LastCommitSHA
 		}
+		if s.opts.Tags {
+			newData.Tags, err = slimrippy.GetTagRefs(ctx, checkoutDir)
+			if err != nil {
+				return fmt.Errorf("slimrippy.GetTagRefs %v", err)
+			}
+		}
 		return nil
 	}
 
@@ -336,6 +394,22 @@ func (s *Export) skipRipsrc(ctx context.Context, checkoutDir string, prs []PR) (
 		s.logger.Debug("not skipping ripsrc, because not branches were found in repo")
 		return
 	}
+	if data.CommitFiles != newData.CommitFiles {
+		s.logger.Debug("not skipping ripsrc, commit files option changed")
+		return
+	}
+	if data.Mailmap != newData.Mailmap || data.AliasFile != newData.AliasFile {
+		s.logger.Debug("not skipping ripsrc, mailmap options or alias file changed")
+		return
+	}
+	if data.Ownership != newData.Ownership {
+		s.logger.Debug("not skipping ripsrc, ownership option changed")
+		return
+	}
+	if s.opts.Tags && !reflect.DeepEqual(data.Tags, newData.Tags) {
+		s.logger.Debug("not skipping ripsrc, tags changed")
+		return
+	}
 	branchesSame := reflect.DeepEqual(data.Branches, newData.Branches)
 	if !branchesSame {
 		s.logger.Debug("not skipping ripsrc, branches-commits are not the same as before")
@@ -499,11 +573,20 @@ func (s *Export) commit(commit slimrippy.Commit) error {
 		return err
 	}
 
+	if s.opts.CommitFiles {
+		err := s.writeCommitFiles(commit)
+		if err != nil {
+			return err
+		}
+	}
+
 	if commit.Authored.Email != "" {
 		author := commitusers.CommitUser{}
 		author.CustomerID = customerID
 		author.Email = commit.Authored.Email
 		author.Name = commit.Authored.Name
+		author.OriginalEmail = commit.Authored.OriginalEmail
+		author.OriginalName = commit.Authored.OriginalName
 		err := writeCommitUser(author)
 		if err != nil {
 			return err
@@ -515,6 +598,8 @@ func (s *Export) commit(commit slimrippy.Commit) error {
 		author.CustomerID = customerID
 		author.Email = commit.Committed.Email
 		author.Name = commit.Committed.Name
+		author.OriginalEmail = commit.Committed.OriginalEmail
+		author.OriginalName = commit.Committed.OriginalName
 		err := writeCommitUser(author)
 		if err != nil {
 			return err
@@ -524,6 +609,166 @@ func (s *Export) commit(commit slimrippy.Commit) error {
 	return nil
 }
 
+func (s *Export) writeCommitFiles(commit slimrippy.Commit) error {
+	if len(commit.Files) == 0 {
+		return nil
+	}
+	commitID := s.commitID(commit.SHA)
+	var objs []map[string]interface{}
+	for _, f := range commit.Files {
+		obj := commitfiles.CommitFile{
+			ID:           ids.CodeCommitFile(s.opts.CustomerID, s.opts.RefType, s.opts.RepoID, commit.SHA, f.Path),
+			CustomerID:   s.opts.CustomerID,
+			RefType:      s.opts.RefType,
+			RepoID:       s.opts.RepoID,
+			CommitID:     commitID,
+			CommitSha:    commit.SHA,
+			Path:         f.Path,
+			PreviousPath: f.PreviousPath,
+			Status:       string(f.Status),
+			Additions:    f.Additions,
+			Deletions:    f.Deletions,
+			Binary:       f.Binary,
+			Language:     f.Language,
+			Generated:    f.Generated,
+			Vendored:     f.Vendored,
+		}
+		objs = append(objs, obj.ToMap())
+	}
+	return s.opts.Sessions.Write(s.sessions.CommitFile, objs)
+}
+
+func (s *Export) tag(data slimrippy.Tag) error {
+	obj := gittags.Tag{
+		ID:         ids.CodeTag(s.opts.CustomerID, s.opts.RefType, s.opts.RepoID, data.Name),
+		CustomerID: s.opts.CustomerID,
+		RefType:    s.opts.RefType,
+		RepoID:     s.opts.RepoID,
+		Name:       data.Name,
+	}
+	if data.Status == slimrippy.TagDeleted {
+		obj.Deleted = true
+	} else {
+		obj.CommitSha = data.Commit
+		obj.CommitID = s.commitID(data.Commit)
+		obj.Annotated = data.Annotated
+		obj.TagSha = data.TagSHA
+		if data.Tagger.Email != "" {
+			obj.TaggerRefID = ids.CodeCommitEmail(s.opts.CustomerID, data.Tagger.Email)
+		}
+		obj.TaggerName = data.Tagger.Name
+		obj.TaggerEmail = data.Tagger.Email
+		obj.Message = data.Message
+		date.ConvertToModel(data.Date, &obj.CreatedDate)
+		if v := data.Semver; v != nil {
+			obj.Semver = v.String()
+			obj.SemverMajor = v.Major
+			obj.SemverMinor = v.Minor
+			obj.SemverPatch = v.Patch
+			obj.SemverPrerelease = v.Prerelease
+		}
+	}
+	return s.opts.Sessions.Write(s.sessions.Tag, []map[string]interface{}{
+		obj.ToMap(),
+	})
+}
+
+func (s *Export) release(data slimrippy.Release) error {
+	tagID := func(name string) string {
+		if name == "" {
+			return ""
+		}
+		return ids.CodeTag(s.opts.CustomerID, s.opts.RefType, s.opts.RepoID, name)
+	}
+	obj := gittags.Release{
+		ID:         ids.CodeRelease(s.opts.CustomerID, s.opts.RefType, s.opts.RepoID, data.Tag),
+		CustomerID: s.opts.CustomerID,
+		RefType:    s.opts.RefType,
+		RepoID:     s.opts.RepoID,
+		TagName:    data.Tag,
+		TagID:      tagID(data.Tag),
+		Deleted:    data.Deleted,
+	}
+	if !data.Deleted {
+		obj.Version = data.Semver.String()
+		obj.CommitSha = data.Commit
+		obj.CommitID = s.commitID(data.Commit)
+		obj.PreviousTagName = data.PreviousTag
+		obj.PreviousTagID = tagID(data.PreviousTag)
+		date.ConvertToModel(data.Date, &obj.ReleasedDate)
+		obj.CommitShas = data.Commits
+		obj.CommitIds = s.commitIDs(data.Commits)
+	}
+	return s.opts.Sessions.Write(s.sessions.Release, []map[string]interface{}{
+		obj.ToMap(),
+	})
+}
+
+func (s *Export) ownership(data slimrippy.Ownership) error {
+	commitSha := data.State.Commit
+	authors := func(authors []slimrippy.OwnershipAuthor) (res []codeownership.Author) {
+		for _, a := range authors {
+			res = append(res, codeownership.Author{
+				RefID: ids.CodeCommitEmail(s.opts.CustomerID, a.Email),
+				Name:  a.Name,
+				Email: a.Email,
+				Lines: a.Lines,
+			})
+		}
+		return
+	}
+	var files []map[string]interface{}
+	for _, f := range data.Files {
+		obj := codeownership.File{
+			ID:         ids.CodeFileOwnership(s.opts.CustomerID, s.opts.RefType, s.opts.RepoID, f.Path),
+			CustomerID: s.opts.CustomerID,
+			RefType:    s.opts.RefType,
+			RepoID:     s.opts.RepoID,
+			Path:       f.Path,
+			Deleted:    f.Deleted,
+		}
+		if !f.Deleted {
+			obj.CommitSha = commitSha
+			obj.CommitID = s.commitID(commitSha)
+			obj.Lines = f.Lines
+			obj.Authors = authors(f.Authors)
+			obj.DeclaredOwners = f.Owners
+			date.ConvertToModel(data.State.Date, &obj.SnapshotDate)
+		}
+		files = append(files, obj.ToMap())
+	}
+	if len(files) != 0 {
+		err := s.opts.Sessions.Write(s.sessions.FileOwnership, files)
+		if err != nil {
+			return err
+		}
+	}
+	var dirs []map[string]interface{}
+	for _, d := range data.Directories {
+		obj := codeownership.Directory{
+			ID:         ids.CodeDirectoryOwnership(s.opts.CustomerID, s.opts.RefType, s.opts.RepoID, d.Path),
+			CustomerID: s.opts.CustomerID,
+			RefType:    s.opts.RefType,
+			RepoID:     s.opts.RepoID,
+			Path:       d.Path,
+			Deleted:    d.Deleted,
+		}
+		if !d.Deleted {
+			obj.CommitSha = commitSha
+			obj.CommitID = s.commitID(commitSha)
+			obj.Files = d.Files
+			obj.Lines = d.Lines
+			obj.Authors = authors(d.Authors)
+			date.ConvertToModel(data.State.Date, &obj.SnapshotDate)
+		}
+		dirs = append(dirs, obj.ToMap())
+	}
+	if len(dirs) == 0 {
+		return nil
+	}
+	return s.opts.Sessions.Write(s.sessions.DirectoryOwnership, dirs)
+}
+
 func commitURL(commitURLTemplate, sha string) string {
 	return strings.ReplaceAll(commitURLTemplate, "@@@sha@@@", sha)
 }
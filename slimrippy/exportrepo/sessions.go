@@ -3,8 +3,11 @@ package exportrepo
 import (
 	"github.com/pinpt/integration-sdk/sourcecode"
 
+	"github.com/pinpt/agent/pkg/codeownership"
+	"github.com/pinpt/agent/pkg/commitfiles"
 	"github.com/pinpt/agent/pkg/commitusers"
 	"github.com/pinpt/agent/pkg/expsessions"
+	"github.com/pinpt/agent/pkg/gittags"
 )
 
 type sessions struct {
@@ -12,14 +15,28 @@ type sessions struct {
 	PRBranch   expsessions.ID
 	Commit     expsessions.ID
 	CommitUser expsessions.ID
+	// CommitFile is only opened when commit files are enabled
+	CommitFile expsessions.ID
+	// Tag and Release are only opened when tags are enabled
+	Tag     expsessions.ID
+	Release expsessions.ID
+	// FileOwnership and DirectoryOwnership are only opened when ownership is enabled
+	FileOwnership      expsessions.ID
+	DirectoryOwnership expsessions.ID
 
+	commitFiles            bool
+	tags                   bool
+	ownership              bool
 	sessionManager         SessionManger
 	sessionRootID          expsessions.ID
 	repoNameUsedInCacheDir string
 }
 
-func newSessions(sessionManager SessionManger, sessionRootID expsessions.ID, repoNameUsedInCacheDir string) *sessions {
+func newSessions(sessionManager SessionManger, sessionRootID expsessions.ID, repoNameUsedInCacheDir string, commitFiles bool, tags bool, ownership bool) *sessions {
 	s := &sessions{}
+	s.commitFiles = commitFiles
+	s.tags = tags
+	s.ownership = ownership
 	s.sessionManager = sessionManager
 	s.sessionRootID = sessionRootID
 	s.repoNameUsedInCacheDir = repoNameUsedInCacheDir
@@ -44,6 +61,32 @@ func (s *sessions) Open() error {
 	if err != nil {
 		return err
 	}
+	if s.commitFiles {
+		s.CommitFile, err = s.session(commitfiles.TableName)
+		if err != nil {
+			return err
+		}
+	}
+	if s.tags {
+		s.Tag, err = s.session(gittags.TagTableName)
+		if err != nil {
+			return err
+		}
+		s.Release, err = s.session(gittags.ReleaseTableName)
+		if err != nil {
+			return err
+		}
+	}
+	if s.ownership {
+		s.FileOwnership, err = s.session(codeownership.FileTableName)
+		if err != nil {
+			return err
+		}
+		s.DirectoryOwnership, err = s.session(codeownership.DirectoryTableName)
+		if err != nil {
+			return err
+		}
+	}
 	return nil
 }
 
@@ -64,6 +107,32 @@ func (s *sessions) Close() error {
 	if err != nil {
 		return err
 	}
+	if s.commitFiles {
+		err = s.sessionManager.Done(s.CommitFile, nil)
+		if err != nil {
+			return err
+		}
+	}
+	if s.tags {
+		err = s.sessionManager.Done(s.Tag, nil)
+		if err != nil {
+			return err
+		}
+		err = s.sessionManager.Done(s.Release, nil)
+		if err != nil {
+			return err
+		}
+	}
+	if s.ownership {
+		err = s.sessionManager.Done(s.FileOwnership, nil)
+		if err != nil {
+			return err
+		}
+		err = s.sessionManager.Done(s.DirectoryOwnership, nil)
+		if err != nil {
+			return err
+		}
+	}
 	return nil
 }
 
@@ -8,6 +8,7 @@ import (
 	"strings"
 	"time"
 
+	"github.com/pinpt/agent/slimrippy/internal/filestats"
 	"github.com/pinpt/agent/slimrippy/internal/repoutil"
 
 	"gopkg.in/src-d/go-git.v4"
@@ -22,6 +23,10 @@ type Opts struct {
 
 type State struct {
 	CommitsSeen CommitsSeen
+	// Files is true when commits in CommitsSeen were processed with file stats
+	Files bool
+	// Mailmap is the hash of mailmap used to process commits in CommitsSeen
+	Mailmap string
 }
 
 type CommitsSeen map[Hash]bool
@@ -58,6 +63,21 @@ type UserAction struct {
 	Email string
 	Name  string
 	Date  time.Time
+	// OriginalEmail and OriginalName are the values from git object, only set when they were changed using mailmap
+	OriginalEmail string
+	OriginalName  string
+}
+
+// Remap replaces name and email using mapping func, keeping the original values if they changed
+func (s *UserAction) Remap(mapping func(name, email string) (string, string)) {
+	name, email := mapping(s.Name, s.Email)
+	if name == s.Name && email == s.Email {
+		return
+	}
+	s.OriginalName = s.Name
+	s.OriginalEmail = s.Email
+	s.Name = name
+	s.Email = email
 }
 
 type Commit struct {
@@ -65,6 +85,8 @@ type Commit struct {
 	Authored  UserAction
 	Committed UserAction
 	Message   string
+	// Files is only set when file stats are enabled
+	Files []filestats.File
 }
 
 func Commits(ctx context.Context, opts Opts, res chan *object.Commit) (_ State, rerr error) {
@@ -0,0 +1,165 @@
+package filestats
+
+import (
+	"bytes"
+	"path"
+	"regexp"
+	"strings"
+)
+
+// Class is the classification of the file based on its path
+type Class struct {
+	Language  string
+	Generated bool
+	Vendored  bool
+}
+
+// languageByExt maps lowercase file extension to language
+var languageByExt = map[string]string{
+	".go":         "Go",
+	".c":          "C",
+	".h":          "C",
+	".cc":         "C++",
+	".cpp":        "C++",
+	".cxx":        "C++",
+	".hpp":        "C++",
+	".cs":         "C#",
+	".java":       "Java",
+	".kt":         "Kotlin",
+	".kts":        "Kotlin",
+	".scala":      "Scala",
+	".groovy":     "Groovy",
+	".gradle":     "Groovy",
+	".clj":        "Clojure",
+	".js":         "JavaScript",
+	".jsx":        "JavaScript",
+	".mjs":        "JavaScript",
+	".ts":         "TypeScript",
+	".tsx":        "TypeScript",
+	".vue":        "Vue",
+	".py":         "Python",
+	".rb":         "Ruby",
+	".php":        "PHP",
+	".pl":         "Perl",
+	".pm":         "Perl",
+	".rs":         "Rust",
+	".swift":      "Swift",
+	".m":          "Objective-C",
+	".mm":         "Objective-C",
+	".dart":       "Dart",
+	".ex":         "Elixir",
+	".exs":        "Elixir",
+	".erl":        "Erlang",
+	".hs":         "Haskell",
+	".lua":        "Lua",
+	".r":          "R",
+	".sh":         "Shell",
+	".bash":       "Shell",
+	".zsh":        "Shell",
+	".ps1":        "PowerShell",
+	".sql":        "SQL",
+	".html":       "HTML",
+	".htm":        "HTML",
+	".css":        "CSS",
+	".scss":       "SCSS",
+	".less":       "Less",
+	".json":       "JSON",
+	".yml":        "YAML",
+	".yaml":       "YAML",
+	".toml":       "TOML",
+	".xml":        "XML",
+	".proto":      "Protocol Buffer",
+	".graphql":    "GraphQL",
+	".tf":         "HCL",
+	".md":         "Markdown",
+	".markdown":   "Markdown",
+	".rst":        "reStructuredText",
+	".dockerfile": "Dockerfile",
+}
+
+// languageByName maps file name to language for files usually without extension
+var languageByName = map[string]string{
+	"Dockerfile":  "Dockerfile",
+	"Makefile":    "Makefile",
+	"makefile":    "Makefile",
+	"GNUmakefile": "Makefile",
+	"Rakefile":    "Ruby",
+	"Gemfile":     "Ruby",
+	"Jenkinsfile": "Groovy",
+}
+
+// vendoredDirs are directory names containing third party code, matched at any depth
+var vendoredDirs = map[string]bool{
+	"vendor":           true,
+	"node_modules":     true,
+	"bower_components": true,
+	"third_party":      true,
+	"thirdparty":       true,
+	"Godeps":           true,
+	"Pods":             true,
+	"Carthage":         true,
+}
+
+// generatedNames are patterns matched against file name
+var generatedNames = []string{
+	"*.pb.go",
+	"*.pb.gw.go",
+	"*_pb2.py",
+	"*_pb2_grpc.py",
+	"*.pb.cc",
+	"*.pb.h",
+	"*_generated.go",
+	"*.generated.*",
+	"*.designer.cs",
+	"*.min.js",
+	"*.min.css",
+	"*.js.map",
+	"*.css.map",
+	"package-lock.json",
+	"yarn.lock",
+	"npm-shrinkwrap.json",
+	"go.sum",
+	"Gopkg.lock",
+	"Gemfile.lock",
+	"Cargo.lock",
+	"composer.lock",
+	"poetry.lock",
+	"Pipfile.lock",
+}
+
+// Classify returns the language of file and whether it is generated or vendored based on built-in rules
+func Classify(filePath string) (res Class) {
+	name := path.Base(filePath)
+	res.Language = languageByName[name]
+	if res.Language == "" {
+		res.Language = languageByExt[strings.ToLower(path.Ext(name))]
+	}
+	for _, p := range generatedNames {
+		if ok, _ := path.Match(p, name); ok {
+			res.Generated = true
+			break
+		}
+	}
+	for _, dir := range strings.Split(path.Dir(filePath), "/") {
+		if vendoredDirs[dir] {
+			res.Vendored = true
+			break
+		}
+	}
+	return
+}
+
+// generatedHeader matches the comments used by code generators, for example in go https://golang.org/s/generatedcode
+var generatedHeader = regexp.MustCompile(`(?i)(code generated .* do not edit|@generated|<auto-generated)`)
+
+// generatedContent checks the beginning of the file for generated code marker
+func generatedContent(b []byte) bool {
+	const maxHeader = 1024
+	if len(b) > maxHeader {
+		b = b[:maxHeader]
+	}
+	if i := bytes.LastIndexByte(b, '\n'); i != -1 && len(b) == maxHeader {
+		b = b[:i]
+	}
+	return generatedHeader.Match(b)
+}
@@ -0,0 +1,384 @@
+// Package filestats calculates per file changes of a commit compared to its first parent.
+package filestats
+
+import (
+	"context"
+	"sort"
+	"strings"
+
+	"gopkg.in/src-d/go-git.v4/plumbing"
+	"gopkg.in/src-d/go-git.v4/plumbing/object"
+	"gopkg.in/src-d/go-git.v4/utils/diff"
+	"gopkg.in/src-d/go-git.v4/utils/merkletrie"
+
+	"github.com/sergi/go-diff/diffmatchpatch"
+)
+
+type Status string
+
+const (
+	Added    Status = "added"
+	Modified Status = "modified"
+	Removed  Status = "removed"
+	Renamed  Status = "renamed"
+)
+
+// File is a file changed in commit
+type File struct {
+	Path string
+	// PreviousPath is set for renamed files
+	PreviousPath string
+	Status       Status
+	Additions    int
+	Deletions    int
+	// Binary is true for binary files, additions and deletions are 0 for these
+	Binary bool
+	Class
+}
+
+// MaxFileSize is the size of the file above which line stats are not calculated
+const MaxFileSize = 1 << 20
+
+// RenameLimit is the max number of added or removed files for which we check content similarity to detect renames. Exact renames are always detected.
+const RenameLimit = 200
+
+// renameMinSimilarity is the share of lines that need to be the same for file to be considered renamed, the same as git default
+const renameMinSimilarity = 0.5
+
+// Commit returns the files changed in commit compared to the first parent. For the first commit all files are returned as added.
+//
+// For merge commits only the files that are different from all parents are returned, which are the changes done when resolving conflicts. Files that were taken from one of the parents without changes are skipped, since they are already counted in commits on that branch.
+func Commit(ctx context.Context, commit *object.Commit) (res []File, rerr error) {
+	tree, err := commit.Tree()
+	if err != nil {
+		rerr = err
+		return
+	}
+	var parentTree *object.Tree
+	var otherParents []*object.Tree
+	for i := 0; i < commit.NumParents(); i++ {
+		p, err := commit.Parent(i)
+		if err != nil {
+			rerr = err
+			return
+		}
+		t, err := p.Tree()
+		if err != nil {
+			rerr = err
+			return
+		}
+		if i == 0 {
+			parentTree = t
+		} else {
+			otherParents = append(otherParents, t)
+		}
+	}
+	changes, err := object.DiffTreeContext(ctx, parentTree, tree)
+	if err != nil {
+		rerr = err
+		return
+	}
+	var added, removed, modified []*change
+	for _, ch := range changes {
+		if len(otherParents) != 0 && sameInAnyTree(ch, otherParents) {
+			continue
+		}
+		c, err := newChange(ch)
+		if err != nil {
+			rerr = err
+			return
+		}
+		if c == nil {
+			continue
+		}
+		switch c.Status {
+		case Added:
+			added = append(added, c)
+		case Removed:
+			removed = append(removed, c)
+		default:
+			modified = append(modified, c)
+		}
+	}
+	renamed, added, removed, err := detectRenames(added, removed)
+	if err != nil {
+		rerr = err
+		return
+	}
+	for _, cs := range [][]*change{modified, renamed, added, removed} {
+		for _, c := range cs {
+			f, err := c.stats()
+			if err != nil {
+				rerr = err
+				return
+			}
+			res = append(res, f)
+		}
+	}
+	sort.Slice(res, func(i, j int) bool {
+		return res[i].Path < res[j].Path
+	})
+	return
+}
+
+// sameInAnyTree returns true if file after change is the same as in one of the trees
+func sameInAnyTree(ch *object.Change, trees []*object.Tree) bool {
+	name := ch.To.Name
+	if name == "" {
+		name = ch.From.Name
+	}
+	for _, t := range trees {
+		e, err := t.FindEntry(name)
+		if err != nil {
+			// file removed in merge and not present in other parent
+			if ch.To.Name == "" {
+				return true
+			}
+			continue
+		}
+		if ch.To.Name != "" && e.Hash == ch.To.TreeEntry.Hash && e.Mode == ch.To.TreeEntry.Mode {
+			return true
+		}
+	}
+	return false
+}
+
+type change struct {
+	Status Status
+	From   *object.File
+	To     *object.File
+	// object.File.Name only contains the base name, so we keep full paths separately
+	FromPath string
+	ToPath   string
+}
+
+func newChange(ch *object.Change) (*change, error) {
+	action, err := ch.Action()
+	if err != nil {
+		return nil, err
+	}
+	from, to, err := ch.Files()
+	if err != nil {
+		return nil, err
+	}
+	if from == nil && to == nil {
+		// submodules
+		return nil, nil
+	}
+	res := &change{From: from, To: to, FromPath: ch.From.Name, ToPath: ch.To.Name}
+	switch action {
+	case merkletrie.Insert:
+		res.Status = Added
+	case merkletrie.Delete:
+		res.Status = Removed
+	default:
+		res.Status = Modified
+	}
+	return res, nil
+}
+
+func (s *change) path() string {
+	if s.To != nil {
+		return s.ToPath
+	}
+	return s.FromPath
+}
+
+func (s *change) stats() (res File, rerr error) {
+	res.Path = s.path()
+	res.Status = s.Status
+	if s.Status == Renamed {
+		res.PreviousPath = s.FromPath
+	}
+	res.Class = Classify(res.Path)
+	var fromContent, toContent string
+	for _, f := range []*object.File{s.From, s.To} {
+		if f == nil {
+			continue
+		}
+		bin, err := f.IsBinary()
+		if err != nil {
+			rerr = err
+			return
+		}
+		if bin {
+			res.Binary = true
+			return
+		}
+	}
+	if s.From != nil && s.From.Size > MaxFileSize || s.To != nil && s.To.Size > MaxFileSize {
+		return
+	}
+	var err error
+	if s.From != nil {
+		fromContent, err = s.From.Contents()
+		if err != nil {
+			rerr = err
+			return
+		}
+	}
+	if s.To != nil {
+		toContent, err = s.To.Contents()
+		if err != nil {
+			rerr = err
+			return
+		}
+		if !res.Generated {
+			res.Generated = generatedContent([]byte(toContent))
+		}
+	}
+	res.Additions, res.Deletions = lineStats(fromContent, toContent)
+	return
+}
+
+func lineStats(from, to string) (additions, deletions int) {
+	if from == to {
+		return
+	}
+	for _, d := range diff.Do(from, to) {
+		switch d.Type {
+		case diffmatchpatch.DiffInsert:
+			additions += countLines(d.Text)
+		case diffmatchpatch.DiffDelete:
+			deletions += countLines(d.Text)
+		}
+	}
+	return
+}
+
+func countLines(s string) int {
+	if s == "" {
+		return 0
+	}
+	n := strings.Count(s, "\n")
+	if !strings.HasSuffix(s, "\n") {
+		n++
+	}
+	return n
+}
+
+// detectRenames matches removed and added files with the same content. When the number of files is below RenameLimit also files with similar content are matched.
+func detectRenames(added, removed []*change) (renamed, addedRes, removedRes []*change, rerr error) {
+	if len(added) == 0 || len(removed) == 0 {
+		return nil, added, removed, nil
+	}
+	used := map[*change]bool{}
+	rename := func(from, to *change) {
+		used[from] = true
+		used[to] = true
+		renamed = append(renamed, &change{Status: Renamed, From: from.From, To: to.To, FromPath: from.FromPath, ToPath: to.ToPath})
+	}
+	byHash := map[plumbing.Hash][]*change{}
+	for _, c := range removed {
+		byHash[c.From.Hash] = append(byHash[c.From.Hash], c)
+	}
+	for _, c := range added {
+		for _, r := range byHash[c.To.Hash] {
+			if used[r] {
+				continue
+			}
+			rename(r, c)
+			break
+		}
+	}
+	if len(added) <= RenameLimit && len(removed) <= RenameLimit {
+		err := detectSimilar(added, removed, used, rename)
+		if err != nil {
+			rerr = err
+			return
+		}
+	}
+	for _, c := range added {
+		if !used[c] {
+			addedRes = append(addedRes, c)
+		}
+	}
+	for _, c := range removed {
+		if !used[c] {
+			removedRes = append(removedRes, c)
+		}
+	}
+	return
+}
+
+func detectSimilar(added, removed []*change, used map[*change]bool, rename func(from, to *change)) error {
+	lines := map[*object.File]map[string]int{}
+	getLines := func(f *object.File) (map[string]int, error) {
+		if res, ok := lines[f]; ok {
+			return res, nil
+		}
+		res := map[string]int{}
+		lines[f] = res
+		if f.Size > MaxFileSize {
+			return res, nil
+		}
+		bin, err := f.IsBinary()
+		if err != nil || bin {
+			return res, err
+		}
+		all, err := f.Lines()
+		if err != nil {
+			return nil, err
+		}
+		for _, l := range all {
+			res[l]++
+		}
+		return res, nil
+	}
+	for _, a := range added {
+		if used[a] {
+			continue
+		}
+		aLines, err := getLines(a.To)
+		if err != nil {
+			return err
+		}
+		var best *change
+		bestScore := 0.0
+		for _, r := range removed {
+			if used[r] {
+				continue
+			}
+			rLines, err := getLines(r.From)
+			if err != nil {
+				return err
+			}
+			score := similarity(aLines, rLines)
+			if score >= renameMinSimilarity && score > bestScore {
+				best = r
+				bestScore = score
+			}
+		}
+		if best != nil {
+			rename(best, a)
+		}
+	}
+	return nil
+}
+
+// similarity returns the share of common lines compared to the larger file
+func similarity(a, b map[string]int) float64 {
+	total := func(m map[string]int) (res int) {
+		for _, n := range m {
+			res += n
+		}
+		return
+	}
+	ta, tb := total(a), total(b)
+	max := ta
+	if tb > max {
+		max = tb
+	}
+	if max == 0 {
+		return 0
+	}
+	common := 0
+	for l, n := range a {
+		if n2 := b[l]; n2 < n {
+			common += n2
+		} else {
+			common += n
+		}
+	}
+	return float64(common) / float64(max)
+}
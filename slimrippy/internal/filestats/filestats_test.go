@@ -0,0 +1,78 @@
+package filestats
+
+import (
+	"context"
+	"testing"
+
+	"github.com/pinpt/agent/slimrippy/testutil"
+	"github.com/stretchr/testify/assert"
+	"gopkg.in/src-d/go-git.v4"
+	"gopkg.in/src-d/go-git.v4/plumbing"
+)
+
+func TestCommit(t *testing.T) {
+	dirs := testutil.UnzipTestRepo("files")
+	defer dirs.Remove()
+
+	repo, err := git.PlainOpen(dirs.RepoDir)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	get := func(sha string) []File {
+		t.Helper()
+		c, err := repo.CommitObject(plumbing.NewHash(sha))
+		if err != nil {
+			t.Fatal(err)
+		}
+		res, err := Commit(context.Background(), c)
+		if err != nil {
+			t.Fatal(err)
+		}
+		return res
+	}
+
+	goFile := Class{Language: "Go"}
+
+	// first commit
+	assert.Equal(t, []File{
+		{Path: "README.md", Status: Added, Additions: 2, Class: Class{Language: "Markdown"}},
+		{Path: "a.txt", Status: Added, Additions: 10},
+		{Path: "api.go", Status: Added, Additions: 2, Class: Class{Language: "Go", Generated: true}},
+		{Path: "logo.png", Status: Added, Binary: true},
+		{Path: "main.go", Status: Added, Additions: 3, Class: goFile},
+		{Path: "vendor/lib/lib.go", Status: Added, Additions: 1, Class: Class{Language: "Go", Vendored: true}},
+	}, get("0fbaf7219ca4c17e4b3137e659ad463992aebd99"))
+
+	// modify, exact and similar renames, binary removal
+	assert.Equal(t, []File{
+		{Path: "b.txt", PreviousPath: "a.txt", Status: Renamed, Additions: 1, Deletions: 1},
+		{Path: "docs/README.md", PreviousPath: "README.md", Status: Renamed, Class: Class{Language: "Markdown"}},
+		{Path: "logo.png", Status: Removed, Binary: true},
+		{Path: "main.go", Status: Modified, Additions: 2, Deletions: 1, Class: goFile},
+	}, get("3433ab46136429a3ef1b77b35d2d2316b4c83ff5"))
+
+	// merge commit only includes main.go changed when merging, f.go is the same as in the merged branch
+	assert.Equal(t, []File{
+		{Path: "main.go", Status: Modified, Additions: 1, Class: goFile},
+	}, get("17a70d3ea6a8f86c7ba0ee96f6e02eee9648eaca"))
+}
+
+func TestClassify(t *testing.T) {
+	cases := map[string]Class{
+		"main.go":                      {Language: "Go"},
+		"web/src/App.TSX":              {Language: "TypeScript"},
+		"Dockerfile":                   {Language: "Dockerfile"},
+		"api/api.pb.go":                {Language: "Go", Generated: true},
+		"web/package-lock.json":        {Language: "JSON", Generated: true},
+		"dist/app.min.js":              {Language: "JavaScript", Generated: true},
+		"vendor/github.com/a/b/b.go":   {Language: "Go", Vendored: true},
+		"web/node_modules/x/index.js":  {Language: "JavaScript", Vendored: true},
+		"third_party/x.pb.go":          {Language: "Go", Generated: true, Vendored: true},
+		"docs/image.png":               {},
+		"src/vendors/not_vendored.txt": {},
+	}
+	for path, want := range cases {
+		assert.Equal(t, want, Classify(path), path)
+	}
+}
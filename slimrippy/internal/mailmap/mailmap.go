@@ -0,0 +1,220 @@
+// Package mailmap maps commit author names and emails to canonical ones using git .mailmap format.
+//
+// See https://git-scm.com/docs/gitmailmap. Supported entry formats:
+//
+//	Proper Name <commit@email.xx>
+//	<proper@email.xx> <commit@email.xx>
+//	Proper Name <proper@email.xx> <commit@email.xx>
+//	Proper Name <proper@email.xx> Commit Name <commit@email.xx>
+package mailmap
+
+import (
+	"bufio"
+	"crypto/sha1"
+	"encoding/hex"
+	"fmt"
+	"io"
+	"io/ioutil"
+	"os"
+	"sort"
+	"strings"
+
+	"gopkg.in/src-d/go-git.v4"
+	"gopkg.in/src-d/go-git.v4/plumbing/object"
+)
+
+type entry struct {
+	Name  string
+	Email string
+}
+
+// Mailmap contains parsed mailmap entries. Emails and commit names are matched case-insensitively, the same as in git.
+type Mailmap struct {
+	// byEmail are entries matching only by commit email
+	byEmail map[string]entry
+	// byNameEmail are entries matching commit name and email, key is name + "\x00" + email
+	byNameEmail map[string]entry
+}
+
+func New() *Mailmap {
+	s := &Mailmap{}
+	s.byEmail = map[string]entry{}
+	s.byNameEmail = map[string]entry{}
+	return s
+}
+
+// Parse reads entries from r and adds them to mailmap. Later entries override earlier ones.
+func (s *Mailmap) Parse(r io.Reader) error {
+	sc := bufio.NewScanner(r)
+	n := 0
+	for sc.Scan() {
+		n++
+		line := sc.Text()
+		if i := strings.Index(line, "#"); i != -1 {
+			line = line[:i]
+		}
+		line = strings.TrimSpace(line)
+		if line == "" {
+			continue
+		}
+		err := s.parseLine(line)
+		if err != nil {
+			return fmt.Errorf("invalid mailmap line %v: %v", n, err)
+		}
+	}
+	return sc.Err()
+}
+
+func (s *Mailmap) parseLine(line string) error {
+	var names, emails []string
+	for line != "" {
+		start := strings.Index(line, "<")
+		if start == -1 {
+			break
+		}
+		end := strings.Index(line[start:], ">")
+		if end == -1 {
+			return fmt.Errorf("missing >")
+		}
+		end += start
+		names = append(names, strings.TrimSpace(line[:start]))
+		emails = append(emails, strings.TrimSpace(line[start+1:end]))
+		line = strings.TrimSpace(line[end+1:])
+	}
+	switch len(emails) {
+	case 1:
+		// Proper Name <commit@email.xx>
+		if names[0] == "" {
+			return fmt.Errorf("name is required when only one email is set")
+		}
+		s.add(entry{Name: names[0]}, "", emails[0])
+	case 2:
+		s.add(entry{Name: names[0], Email: emails[0]}, names[1], emails[1])
+	default:
+		return fmt.Errorf("expected 1 or 2 emails, got %v", len(emails))
+	}
+	return nil
+}
+
+func (s *Mailmap) add(proper entry, commitName, commitEmail string) {
+	email := strings.ToLower(commitEmail)
+	if commitName == "" {
+		prev := s.byEmail[email]
+		// git merges name only and email only entries for the same commit email
+		if proper.Name == "" {
+			proper.Name = prev.Name
+		}
+		if proper.Email == "" {
+			proper.Email = prev.Email
+		}
+		s.byEmail[email] = proper
+		return
+	}
+	s.byNameEmail[strings.ToLower(commitName)+"\x00"+email] = proper
+}
+
+// Len returns the number of entries
+func (s *Mailmap) Len() int {
+	return len(s.byEmail) + len(s.byNameEmail)
+}
+
+// Hash returns a hash of all entries, used to detect mailmap changes between runs. Returns empty string for empty mailmap.
+func (s *Mailmap) Hash() string {
+	if s == nil || s.Len() == 0 {
+		return ""
+	}
+	var lines []string
+	for k, e := range s.byEmail {
+		lines = append(lines, "\x00"+k+"\x00"+e.Name+"\x00"+e.Email)
+	}
+	for k, e := range s.byNameEmail {
+		lines = append(lines, k+"\x00"+e.Name+"\x00"+e.Email)
+	}
+	sort.Strings(lines)
+	h := sha1.New()
+	for _, l := range lines {
+		h.Write([]byte(l + "\n"))
+	}
+	return hex.EncodeToString(h.Sum(nil))
+}
+
+// Map returns the canonical name and email. Returns passed values if there is no matching entry.
+func (s *Mailmap) Map(name, email string) (string, string) {
+	if s == nil {
+		return name, email
+	}
+	e, ok := s.byNameEmail[strings.ToLower(name)+"\x00"+strings.ToLower(email)]
+	if !ok {
+		e, ok = s.byEmail[strings.ToLower(email)]
+	}
+	if !ok {
+		return name, email
+	}
+	if e.Name != "" {
+		name = e.Name
+	}
+	if e.Email != "" {
+		email = e.Email
+	}
+	return name, email
+}
+
+// ParseRepo adds entries from .mailmap file in the head commit of repo. Does nothing if repo does not have .mailmap.
+func (s *Mailmap) ParseRepo(repoDir string) error {
+	b, err := repoMailmap(repoDir)
+	if err != nil {
+		return fmt.Errorf("could not read .mailmap from repo: %v", err)
+	}
+	err = s.Parse(strings.NewReader(b))
+	if err != nil {
+		return fmt.Errorf("repo .mailmap: %v", err)
+	}
+	return nil
+}
+
+// ParseFile adds entries from file in mailmap format
+func (s *Mailmap) ParseFile(loc string) error {
+	f, err := os.Open(loc)
+	if err != nil {
+		return err
+	}
+	defer f.Close()
+	err = s.Parse(f)
+	if err != nil {
+		return fmt.Errorf("%v: %v", loc, err)
+	}
+	return nil
+}
+
+// repoMailmap returns the content of .mailmap in HEAD commit. Cloned repos are bare mirrors, so we read it from git objects instead of working dir.
+func repoMailmap(repoDir string) (string, error) {
+	repo, err := git.PlainOpen(repoDir)
+	if err != nil {
+		return "", err
+	}
+	head, err := repo.Head()
+	if err != nil {
+		return "", err
+	}
+	commit, err := repo.CommitObject(head.Hash())
+	if err != nil {
+		return "", err
+	}
+	f, err := commit.File(".mailmap")
+	if err == object.ErrFileNotFound {
+		return "", nil
+	}
+	if err != nil {
+		return "", err
+	}
+	r, err := f.Reader()
+	if err != nil {
+		return "", err
+	}
+	defer r.Close()
+	b, err := ioutil.ReadAll(r)
+	if err != nil {
+		return "", err
+	}
+	return string(b), nil
+}
@@ -0,0 +1,115 @@
+package mailmap
+
+import (
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/pinpt/agent/slimrippy/testutil"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestParse(t *testing.T) {
+	m := New()
+	err := m.Parse(strings.NewReader(`
+# comment
+Proper Name <commit@example.com>
+<proper@example.com> <Commit@Example.com>
+Other Name <other@example.com> <other-old@example.com> # trailing comment
+Name Email <name-email@example.com> Commit Name <shared@example.com>
+`))
+	if err != nil {
+		t.Fatal(err)
+	}
+	assert.Equal(t, 3, m.Len())
+
+	cases := []struct {
+		Name      string
+		Email     string
+		WantName  string
+		WantEmail string
+	}{
+		// name only and email only entries for the same email are merged
+		{"commit", "commit@example.com", "Proper Name", "proper@example.com"},
+		{"commit", "COMMIT@example.com", "Proper Name", "proper@example.com"},
+		{"old", "other-old@example.com", "Other Name", "other@example.com"},
+		// name and email entry only matches when both match
+		{"Commit Name", "shared@example.com", "Name Email", "name-email@example.com"},
+		{"commit name", "shared@example.com", "Name Email", "name-email@example.com"},
+		{"Another Name", "shared@example.com", "Another Name", "shared@example.com"},
+		{"unknown", "unknown@example.com", "unknown", "unknown@example.com"},
+	}
+	for _, c := range cases {
+		name, email := m.Map(c.Name, c.Email)
+		assert.Equal(t, c.WantName, name, c.Email)
+		assert.Equal(t, c.WantEmail, email, c.Email)
+	}
+}
+
+func TestParseInvalid(t *testing.T) {
+	for _, line := range []string{
+		"<commit@example.com>",
+		"Name <commit@example.com",
+		"Name",
+		"A <a@example.com> B <b@example.com> C <c@example.com>",
+	} {
+		err := New().Parse(strings.NewReader(line))
+		assert.Error(t, err, line)
+	}
+}
+
+func TestMapNil(t *testing.T) {
+	var m *Mailmap
+	name, email := m.Map("n", "e")
+	assert.Equal(t, "n", name)
+	assert.Equal(t, "e", email)
+	assert.Equal(t, "", m.Hash())
+}
+
+func TestHash(t *testing.T) {
+	m1 := New()
+	m1.Parse(strings.NewReader("A <a@example.com>\nB <b@example.com>"))
+	m2 := New()
+	m2.Parse(strings.NewReader("B <b@example.com>\nA <A@example.com>"))
+	assert.Equal(t, m1.Hash(), m2.Hash())
+	m2.Parse(strings.NewReader("C <c@example.com>"))
+	assert.NotEqual(t, m1.Hash(), m2.Hash())
+	assert.Equal(t, "", New().Hash())
+}
+
+func TestParseRepo(t *testing.T) {
+	dirs := testutil.UnzipTestRepo("mailmap")
+	defer dirs.Remove()
+
+	m := New()
+	err := m.ParseRepo(dirs.RepoDir)
+	if err != nil {
+		t.Fatal(err)
+	}
+	assert.Equal(t, 1, m.Len())
+	name, email := m.Map("user one", "User1@Old.example.com")
+	assert.Equal(t, "User1", name)
+	assert.Equal(t, "user1@example.com", email)
+
+	// alias file entries are applied after repo entries
+	loc := filepath.Join(dirs.TempWrapper, "aliases")
+	err = ioutil.WriteFile(loc, []byte("User One <user1@example.com> <user1@old.example.com>\nUser Two <user2@example.com>\n"), 0666)
+	if err != nil {
+		t.Fatal(err)
+	}
+	err = m.ParseFile(loc)
+	if err != nil {
+		t.Fatal(err)
+	}
+	name, email = m.Map("user one", "User1@Old.example.com")
+	assert.Equal(t, "User One", name)
+	assert.Equal(t, "user1@example.com", email)
+	name, email = m.Map("User2", "user2@example.com")
+	assert.Equal(t, "User Two", name)
+	assert.Equal(t, "user2@example.com", email)
+
+	err = m.ParseFile(filepath.Join(dirs.TempWrapper, "missing"))
+	assert.True(t, os.IsNotExist(err))
+}
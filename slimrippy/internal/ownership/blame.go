@@ -0,0 +1,134 @@
+package ownership
+
+import (
+	"bufio"
+	"bytes"
+	"context"
+	"fmt"
+	"os/exec"
+	"sort"
+	"strings"
+)
+
+// AuthorLines is the number of lines in the file or directory last changed by author
+type AuthorLines struct {
+	Name  string
+	Email string
+	Lines int
+}
+
+// blame returns the number of lines per author for file at commit. Uses git cli, since go-git blame is too slow for large files.
+func blame(ctx context.Context, repoDir string, commit string, filePath string) (res []AuthorLines, rerr error) {
+	out, err := runGit(ctx, repoDir, nil, "blame", "--porcelain", commit, "--", filePath)
+	if err != nil {
+		rerr = fmt.Errorf("git blame failed for %v: %v", filePath, err)
+		return
+	}
+	linesByCommit, err := parseBlame(out)
+	if err != nil {
+		rerr = err
+		return
+	}
+	// git blame applies .mailmap from the work tree and has no option to disable it, so authors are read from commits instead. We apply mailmap ourselves to keep it consistent with commits.
+	authors, err := commitAuthors(ctx, repoDir, linesByCommit)
+	if err != nil {
+		rerr = fmt.Errorf("could not get commit authors for %v: %v", filePath, err)
+		return
+	}
+	byEmail := map[string]*AuthorLines{}
+	for sha, lines := range linesByCommit {
+		author := authors[sha]
+		key := strings.ToLower(author.Email)
+		a := byEmail[key]
+		if a == nil {
+			a = &AuthorLines{Name: author.Name, Email: author.Email}
+			byEmail[key] = a
+		}
+		a.Lines += lines
+	}
+	for _, a := range byEmail {
+		res = append(res, *a)
+	}
+	sortAuthors(res)
+	return
+}
+
+func runGit(ctx context.Context, repoDir string, stdin []byte, args ...string) ([]byte, error) {
+	out := bytes.NewBuffer(nil)
+	stderr := bytes.NewBuffer(nil)
+	c := exec.CommandContext(ctx, "git", args...)
+	c.Dir = repoDir
+	c.Stdin = bytes.NewReader(stdin)
+	c.Stdout = out
+	c.Stderr = stderr
+	err := c.Run()
+	if err != nil {
+		return nil, fmt.Errorf("%v %v", err, strings.TrimSpace(stderr.String()))
+	}
+	return out.Bytes(), nil
+}
+
+// commitAuthors returns the author name and email as stored in commit objects, without applying mailmap
+func commitAuthors(ctx context.Context, repoDir string, commits map[string]int) (map[string]AuthorLines, error) {
+	stdin := bytes.NewBuffer(nil)
+	for sha := range commits {
+		stdin.WriteString(sha + "\n")
+	}
+	out, err := runGit(ctx, repoDir, stdin.Bytes(), "log", "--no-walk=unsorted", "--stdin", "--no-mailmap", "--format=%H%x00%an%x00%ae")
+	if err != nil {
+		return nil, err
+	}
+	res := map[string]AuthorLines{}
+	for _, line := range strings.Split(string(out), "\n") {
+		parts := strings.Split(line, "\x00")
+		if len(parts) != 3 {
+			continue
+		}
+		res[parts[0]] = AuthorLines{Name: parts[1], Email: parts[2]}
+	}
+	for sha := range commits {
+		if _, ok := res[sha]; !ok {
+			return nil, fmt.Errorf("author not found for commit %v", sha)
+		}
+	}
+	return res, nil
+}
+
+// parseBlame parses output of git blame --porcelain, returning the number of lines per commit
+func parseBlame(b []byte) (res map[string]int, rerr error) {
+	res = map[string]int{}
+	var commit string
+	header := true
+	sc := bufio.NewScanner(bytes.NewReader(b))
+	sc.Buffer(nil, 10*1024*1024)
+	for sc.Scan() {
+		line := sc.Text()
+		if strings.HasPrefix(line, "\t") {
+			// content line ends the entry
+			res[commit]++
+			header = true
+			continue
+		}
+		if header {
+			// the first line of entry is commit sha followed by line numbers
+			commit = strings.SplitN(line, " ", 2)[0]
+			header = false
+		}
+	}
+	if err := sc.Err(); err != nil {
+		rerr = err
+		return
+	}
+	return
+}
+
+// sortAuthors sorts by number of lines desc and email
+func sortAuthors(authors []AuthorLines) {
+	sort.Slice(authors, func(i, j int) bool {
+		a, b := authors[i], authors[j]
+		if a.Lines != b.Lines {
+			return a.Lines > b.Lines
+		}
+		return a.Email < b.Email
+	})
+}
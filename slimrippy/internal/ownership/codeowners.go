@@ -0,0 +1,169 @@
+package ownership
+
+import (
+	"bufio"
+	"io"
+	"regexp"
+	"strings"
+)
+
+// CodeOwnersLocations are the paths checked for CODEOWNERS file, first existing file is used. Includes locations supported by GitHub and GitLab.
+var CodeOwnersLocations = []string{
+	".github/CODEOWNERS",
+	"CODEOWNERS",
+	"docs/CODEOWNERS",
+	".gitlab/CODEOWNERS",
+}
+
+// CodeOwners contains parsed CODEOWNERS rules. Supports GitHub syntax and GitLab sections.
+type CodeOwners struct {
+	sections []*section
+}
+
+type section struct {
+	Name string
+	// DefaultOwners are used for GitLab rules without owners in this section
+	DefaultOwners []string
+	Rules         []rule
+}
+
+type rule struct {
+	Pattern string
+	re      *regexp.Regexp
+	Owners  []string
+}
+
+// gitlabSectionRe matches GitLab section headers, such as [Section], ^[Optional Section], [Section][2] @default-owner
+var gitlabSectionRe = regexp.MustCompile(`^\^?\[([^\]]+)\](?:\[\d+\])?(.*)$`)
+
+// ParseCodeOwners parses CODEOWNERS file. Invalid patterns are skipped.
+func ParseCodeOwners(r io.Reader) (*CodeOwners, error) {
+	res := &CodeOwners{}
+	current := &section{}
+	res.sections = append(res.sections, current)
+	sc := bufio.NewScanner(r)
+	for sc.Scan() {
+		line := strings.TrimSpace(sc.Text())
+		if line == "" || strings.HasPrefix(line, "#") {
+			continue
+		}
+		if m := gitlabSectionRe.FindStringSubmatch(line); m != nil {
+			current = &section{Name: m[1], DefaultOwners: splitOwners(m[2])}
+			res.sections = append(res.sections, current)
+			continue
+		}
+		pattern, owners := splitRule(line)
+		re, err := patternToRegexp(pattern)
+		if err != nil {
+			continue
+		}
+		if len(owners) == 0 {
+			owners = current.DefaultOwners
+		}
+		current.Rules = append(current.Rules, rule{Pattern: pattern, re: re, Owners: owners})
+	}
+	if err := sc.Err(); err != nil {
+		return nil, err
+	}
+	return res, nil
+}
+
+// Owners returns the declared owners of the file. In each section the last matching rule is used, owners from all sections are combined.
+func (s *CodeOwners) Owners(filePath string) (res []string) {
+	if s == nil {
+		return nil
+	}
+	seen := map[string]bool{}
+	for _, sec := range s.sections {
+		for i := len(sec.Rules) - 1; i >= 0; i-- {
+			r := sec.Rules[i]
+			if !r.re.MatchString(filePath) {
+				continue
+			}
+			for _, o := range r.Owners {
+				if !seen[o] {
+					seen[o] = true
+					res = append(res, o)
+				}
+			}
+			break
+		}
+	}
+	return
+}
+
+// splitRule splits line into pattern and owners, handling escaped spaces in pattern
+func splitRule(line string) (pattern string, owners []string) {
+	// remove trailing comment
+	if i := strings.Index(line, " #"); i != -1 {
+		line = line[:i]
+	}
+	i := 0
+	for ; i < len(line); i++ {
+		if line[i] == '\\' {
+			i++
+			continue
+		}
+		if line[i] == ' ' || line[i] == '\t' {
+			break
+		}
+	}
+	if i > len(line) {
+		i = len(line)
+	}
+	pattern = strings.ReplaceAll(line[:i], `\ `, " ")
+	pattern = strings.ReplaceAll(pattern, `\#`, "#")
+	return pattern, splitOwners(line[i:])
+}
+
+func splitOwners(s string) []string {
+	if i := strings.Index(s, "#"); i != -1 {
+		s = s[:i]
+	}
+	return strings.Fields(s)
+}
+
+// patternToRegexp converts gitignore style pattern to regexp matching file paths relative to repo root
+func patternToRegexp(pattern string) (*regexp.Regexp, error) {
+	dirOnly := strings.HasSuffix(pattern, "/")
+	p := strings.TrimSuffix(pattern, "/")
+	// patterns with slash at the start or in the middle are relative to repo root, others match at any level
+	anchored := strings.Contains(p, "/")
+	p = strings.TrimPrefix(p, "/")
+
+	b := strings.Builder{}
+	if anchored {
+		b.WriteString("^")
+	} else {
+		b.WriteString("^(?:.*/)?")
+	}
+	for i := 0; i < len(p); i++ {
+		c := p[i]
+		switch {
+		case strings.HasPrefix(p[i:], "**/"):
+			b.WriteString("(?:.*/)?")
+			i += 2
+		case strings.HasPrefix(p[i:], "**"):
+			b.WriteString(".*")
+			i++
+		case c == '*':
+			b.WriteString("[^/]*")
+		case c == '?':
+			b.WriteString("[^/]")
+		default:
+			b.WriteString(regexp.QuoteMeta(string(c)))
+		}
+	}
+	lastSegment := p[strings.LastIndex(p, "/")+1:]
+	switch {
+	case dirOnly:
+		b.WriteString("/.*$")
+	case strings.Contains(lastSegment, "*"):
+		// docs/* matches files directly in docs, but not in subdirectories
+		b.WriteString("$")
+	default:
+		// pattern matching a directory also matches all files in it
+		b.WriteString("(?:/.*)?$")
+	}
+	return regexp.Compile(b.String())
+}
@@ -0,0 +1,75 @@
+package ownership
+
+import (
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestCodeOwnersGitHub(t *testing.T) {
+	co, err := ParseCodeOwners(strings.NewReader(`
+# comment
+*       @global-owner1 @global-owner2
+*.js    @js-owner #This is an inline comment.
+*.go docs@example.com
+/build/logs/ @doctocat
+docs/*  docs@example.com
+apps/ @octocat
+/docs/ @doctocat
+/scripts/ @doctocat @octocat
+**/logs @octocat
+/apps/github
+path\ with\ spaces/ @spaces
+`))
+	if err != nil {
+		t.Fatal(err)
+	}
+	cases := []struct {
+		Path string
+		Want []string
+	}{
+		{"README.md", []string{"@global-owner1", "@global-owner2"}},
+		{"src/index.js", []string{"@js-owner"}},
+		{"main.go", []string{"docs@example.com"}},
+		{"build/logs/out.txt", []string{"@octocat"}},
+		{"x/build/logs/out.txt", []string{"@octocat"}},
+		{"docs/getting-started.md", []string{"@doctocat"}},
+		{"docs/build-app/troubleshooting.md", []string{"@doctocat"}},
+		// patterns with slash in the middle are relative to root
+		{"other/docs/a.md", []string{"@global-owner1", "@global-owner2"}},
+		{"x/apps/a.txt", []string{"@octocat"}},
+		// rule without owners removes owners
+		{"apps/github/a.txt", nil},
+		{"scripts/run.sh", []string{"@doctocat", "@octocat"}},
+		{"path with spaces/a.txt", []string{"@spaces"}},
+	}
+	for _, c := range cases {
+		assert.Equal(t, c.Want, co.Owners(c.Path), c.Path)
+	}
+}
+
+func TestCodeOwnersGitLabSections(t *testing.T) {
+	co, err := ParseCodeOwners(strings.NewReader(`
+* @default
+[Docs] @docs-team
+*.md
+/internal/*.md @internal-docs
+^[Backend][2] @backend
+/src/
+/src/*.md @md-backend
+`))
+	if err != nil {
+		t.Fatal(err)
+	}
+	assert.Equal(t, []string{"@default", "@docs-team"}, co.Owners("README.md"))
+	assert.Equal(t, []string{"@default", "@internal-docs"}, co.Owners("internal/a.md"))
+	assert.Equal(t, []string{"@default", "@backend"}, co.Owners("src/a.go"))
+	assert.Equal(t, []string{"@default", "@docs-team", "@md-backend"}, co.Owners("src/a.md"))
+	assert.Equal(t, []string{"@default"}, co.Owners("main.go"))
+}
+
+func TestCodeOwnersNil(t *testing.T) {
+	var co *CodeOwners
+	assert.Nil(t, co.Owners("a"))
+}
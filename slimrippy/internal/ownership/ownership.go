@@ -0,0 +1,356 @@
+// Package ownership calculates blame based line ownership of files at default branch head, aggregated by directory, and declared owners from CODEOWNERS.
+package ownership
+
+import (
+	"context"
+	"errors"
+	"path"
+	"runtime"
+	"sort"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/pinpt/agent/slimrippy/internal/branchmeta"
+	"github.com/pinpt/agent/slimrippy/internal/filestats"
+
+	"gopkg.in/src-d/go-git.v4"
+	"gopkg.in/src-d/go-git.v4/plumbing"
+	"gopkg.in/src-d/go-git.v4/plumbing/object"
+)
+
+// File is line ownership of a single file
+type File struct {
+	Path    string
+	Lines   int
+	Authors []AuthorLines
+	// Owners are the owners declared in CODEOWNERS
+	Owners []string
+	// Deleted is true for files removed since the last snapshot, only Path is set in that case
+	Deleted bool
+}
+
+// Directory is line ownership of all files in directory, including subdirectories
+type Directory struct {
+	// Path is the directory path, empty for repo root
+	Path    string
+	Files   int
+	Lines   int
+	Authors []AuthorLines
+	// Deleted is true for directories removed since the last snapshot, only Path is set in that case
+	Deleted bool
+}
+
+// State contains the results of the last snapshot, used to skip blame of unchanged files
+type State struct {
+	// Date is the time of the last snapshot
+	Date time.Time
+	// Commit is the default branch head of the last snapshot
+	Commit string
+	Files  map[string]FileState
+}
+
+type FileState struct {
+	// Blob is the hash of the file content
+	Blob string
+	// Skipped is true for binary, large, generated and vendored files, these are not blamed and not exported
+	Skipped bool          `json:",omitempty"`
+	Authors []AuthorLines `json:",omitempty"`
+	Owners  []string      `json:",omitempty"`
+}
+
+func (s FileState) lines() (res int) {
+	for _, a := range s.Authors {
+		res += a.Lines
+	}
+	return
+}
+
+type Opts struct {
+	RepoDir string
+	State   State
+	// Mailmap is an optional func to map author names and emails
+	Mailmap func(name, email string) (string, string)
+	// Concurrency is the number of git blame processes to run, defaults to number of cpus
+	Concurrency int
+}
+
+type Result struct {
+	// Files are files that changed since the last snapshot, including deleted ones
+	Files []File
+	// Directories are all directories at the snapshot commit and directories deleted since the last snapshot
+	Directories []Directory
+	State       State
+}
+
+// Run creates an ownership snapshot for default branch head. Only files with content or declared owners changed since the last snapshot are blamed and returned.
+func Run(ctx context.Context, opts Opts) (res Result, rerr error) {
+	if opts.RepoDir == "" {
+		rerr = errors.New("RepoDir not set")
+		return
+	}
+	if opts.Concurrency == 0 {
+		opts.Concurrency = runtime.NumCPU()
+	}
+	head, err := branchmeta.GetDefault(ctx, opts.RepoDir)
+	if err != nil {
+		rerr = err
+		return
+	}
+	repo, err := git.PlainOpen(opts.RepoDir)
+	if err != nil {
+		rerr = err
+		return
+	}
+	commit, err := repo.CommitObject(plumbing.NewHash(head.Commit))
+	if err != nil {
+		rerr = err
+		return
+	}
+	codeOwners, err := readCodeOwners(commit)
+	if err != nil {
+		rerr = err
+		return
+	}
+
+	prev := opts.State.Files
+	newState := State{Date: time.Now(), Commit: head.Commit, Files: map[string]FileState{}}
+	var toBlame []string
+
+	iter, err := commit.Files()
+	if err != nil {
+		rerr = err
+		return
+	}
+	err = iter.ForEach(func(f *object.File) error {
+		fs := FileState{Blob: f.Hash.String()}
+		p, ok := prev[f.Name]
+		if ok && p.Blob == fs.Blob {
+			fs.Skipped = p.Skipped
+			fs.Authors = p.Authors
+		} else {
+			skip, err := skipFile(f)
+			if err != nil {
+				return err
+			}
+			fs.Skipped = skip
+			if !skip {
+				toBlame = append(toBlame, f.Name)
+			}
+		}
+		if !fs.Skipped {
+			fs.Owners = codeOwners.Owners(f.Name)
+		}
+		newState.Files[f.Name] = fs
+		return nil
+	})
+	if err != nil {
+		rerr = err
+		return
+	}
+
+	blamed, err := blameAll(ctx, opts, head.Commit, toBlame)
+	if err != nil {
+		rerr = err
+		return
+	}
+	for p, authors := range blamed {
+		fs := newState.Files[p]
+		fs.Authors = authors
+		newState.Files[p] = fs
+	}
+
+	res.Files = changedFiles(prev, newState.Files, blamed)
+	res.Directories = directories(prev, newState.Files)
+	res.State = newState
+	return
+}
+
+// skipFile returns true for files that are not useful for ownership
+func skipFile(f *object.File) (bool, error) {
+	class := filestats.Classify(f.Name)
+	if class.Generated || class.Vendored || f.Size > filestats.MaxFileSize {
+		return true, nil
+	}
+	return f.IsBinary()
+}
+
+func readCodeOwners(commit *object.Commit) (*CodeOwners, error) {
+	for _, loc := range CodeOwnersLocations {
+		f, err := commit.File(loc)
+		if err == object.ErrFileNotFound {
+			continue
+		}
+		if err != nil {
+			return nil, err
+		}
+		r, err := f.Reader()
+		if err != nil {
+			return nil, err
+		}
+		defer r.Close()
+		return ParseCodeOwners(r)
+	}
+	return nil, nil
+}
+
+func blameAll(ctx context.Context, opts Opts, commit string, files []string) (map[string][]AuthorLines, error) {
+	res := map[string][]AuthorLines{}
+	var rerr error
+	mu := sync.Mutex{}
+	ch := make(chan string)
+	wg := sync.WaitGroup{}
+	for i := 0; i < opts.Concurrency; i++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			for p := range ch {
+				authors, err := blame(ctx, opts.RepoDir, commit, p)
+				if err == nil && opts.Mailmap != nil {
+					authors = remap(authors, opts.Mailmap)
+				}
+				mu.Lock()
+				if err != nil && rerr == nil {
+					rerr = err
+				}
+				res[p] = authors
+				mu.Unlock()
+			}
+		}()
+	}
+	for _, p := range files {
+		ch <- p
+	}
+	close(ch)
+	wg.Wait()
+	if rerr != nil {
+		return nil, rerr
+	}
+	return res, nil
+}
+
+// remap applies mailmap to authors, merging authors mapped to the same email
+func remap(authors []AuthorLines, mapping func(name, email string) (string, string)) (res []AuthorLines) {
+	byEmail := map[string]int{}
+	for _, a := range authors {
+		a.Name, a.Email = mapping(a.Name, a.Email)
+		key := strings.ToLower(a.Email)
+		if i, ok := byEmail[key]; ok {
+			res[i].Lines += a.Lines
+			continue
+		}
+		byEmail[key] = len(res)
+		res = append(res, a)
+	}
+	sortAuthors(res)
+	return
+}
+
+func changedFiles(prev, files map[string]FileState, blamed map[string][]AuthorLines) (res []File) {
+	for p, fs := range files {
+		if fs.Skipped {
+			continue
+		}
+		if _, ok := blamed[p]; !ok && ownersEqual(prev[p].Owners, fs.Owners) {
+			continue
+		}
+		res = append(res, File{Path: p, Lines: fs.lines(), Authors: fs.Authors, Owners: fs.Owners})
+	}
+	for p, fs := range prev {
+		if fs.Skipped {
+			continue
+		}
+		if cur, ok := files[p]; !ok || cur.Skipped {
+			res = append(res, File{Path: p, Deleted: true})
+		}
+	}
+	sort.Slice(res, func(i, j int) bool {
+		return res[i].Path < res[j].Path
+	})
+	return
+}
+
+func ownersEqual(a, b []string) bool {
+	if len(a) != len(b) {
+		return false
+	}
+	for i := range a {
+		if a[i] != b[i] {
+			return false
+		}
+	}
+	return true
+}
+
+// directories aggregates file ownership for all parent directories of files
+func directories(prev, files map[string]FileState) (res []Directory) {
+	type dir struct {
+		Files   int
+		Lines   int
+		Authors map[string]*AuthorLines
+	}
+	dirs := map[string]*dir{}
+	for p, fs := range files {
+		if fs.Skipped {
+			continue
+		}
+		for _, d := range parentDirs(p) {
+			agg := dirs[d]
+			if agg == nil {
+				agg = &dir{Authors: map[string]*AuthorLines{}}
+				dirs[d] = agg
+			}
+			agg.Files++
+			for _, a := range fs.Authors {
+				agg.Lines += a.Lines
+				key := strings.ToLower(a.Email)
+				if v := agg.Authors[key]; v != nil {
+					v.Lines += a.Lines
+					continue
+				}
+				a2 := a
+				agg.Authors[key] = &a2
+			}
+		}
+	}
+	for p, agg := range dirs {
+		d := Directory{Path: p, Files: agg.Files, Lines: agg.Lines}
+		for _, a := range agg.Authors {
+			d.Authors = append(d.Authors, *a)
+		}
+		sortAuthors(d.Authors)
+		res = append(res, d)
+	}
+	deleted := map[string]bool{}
+	for p, fs := range prev {
+		if fs.Skipped {
+			continue
+		}
+		for _, d := range parentDirs(p) {
+			if _, ok := dirs[d]; !ok {
+				deleted[d] = true
+			}
+		}
+	}
+	for p := range deleted {
+		res = append(res, Directory{Path: p, Deleted: true})
+	}
+	sort.Slice(res, func(i, j int) bool {
+		return res[i].Path < res[j].Path
+	})
+	return
+}
+
+// parentDirs returns all parent directories of file, including repo root as empty string
+func parentDirs(filePath string) (res []string) {
+	res = append(res, "")
+	d := path.Dir(filePath)
+	if d == "." {
+		return
+	}
+	parts := strings.Split(d, "/")
+	for i := range parts {
+		res = append(res, strings.Join(parts[:i+1], "/"))
+	}
+	return
+}
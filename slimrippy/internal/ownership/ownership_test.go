@@ -0,0 +1,153 @@
+package ownership
+
+import (
+	"context"
+	"io/ioutil"
+	"path/filepath"
+	"testing"
+
+	"github.com/pinpt/agent/slimrippy/testutil"
+	"github.com/stretchr/testify/assert"
+)
+
+const head = "8b50148db1f5f6ac4d665695a65af47298099390"
+
+var (
+	user1    = AuthorLines{Name: "User1", Email: "user1@example.com"}
+	user1Old = AuthorLines{Name: "user one", Email: "user1@old.example.com"}
+	user2    = AuthorLines{Name: "User2", Email: "user2@example.com"}
+)
+
+func lines(a AuthorLines, n int) AuthorLines {
+	a.Lines = n
+	return a
+}
+
+func TestRun(t *testing.T) {
+	dirs := testutil.UnzipTestRepo("ownership")
+	defer dirs.Remove()
+
+	opts := Opts{}
+	opts.RepoDir = dirs.RepoDir
+	res, err := Run(context.Background(), opts)
+	if err != nil {
+		t.Fatal(err)
+	}
+	assert.Equal(t, head, res.State.Commit)
+
+	// vendored and binary files are skipped
+	assert.Equal(t, []File{
+		{Path: "CODEOWNERS", Lines: 4, Authors: []AuthorLines{lines(user1, 4)}, Owners: []string{"@org/all"}},
+		{Path: "README.md", Lines: 3, Authors: []AuthorLines{lines(user1, 2), lines(user1Old, 1)}, Owners: []string{"@org/docs", "user2@example.com"}},
+		{Path: "src/api/api.go", Lines: 3, Authors: []AuthorLines{lines(user2, 3)}, Owners: []string{"@org/backend"}},
+		{Path: "src/main.go", Lines: 4, Authors: []AuthorLines{lines(user1, 2), lines(user2, 2)}, Owners: []string{"@org/backend"}},
+	}, res.Files)
+
+	assert.Equal(t, []Directory{
+		{Path: "", Files: 4, Lines: 14, Authors: []AuthorLines{lines(user1, 8), lines(user2, 5), lines(user1Old, 1)}},
+		{Path: "src", Files: 2, Lines: 7, Authors: []AuthorLines{lines(user2, 5), lines(user1, 2)}},
+		{Path: "src/api", Files: 1, Lines: 3, Authors: []AuthorLines{lines(user2, 3)}},
+	}, res.Directories)
+
+	// nothing changed, directories are returned on every snapshot
+	opts.State = res.State
+	res, err = Run(context.Background(), opts)
+	if err != nil {
+		t.Fatal(err)
+	}
+	assert.Empty(t, res.Files)
+	assert.Len(t, res.Directories, 3)
+
+	// simulate main.go changed, CODEOWNERS owners changed and old/a.go deleted since the last snapshot
+	state := res.State
+	fs := state.Files["src/main.go"]
+	fs.Blob = "x"
+	fs.Authors = nil
+	state.Files["src/main.go"] = fs
+	fs = state.Files["CODEOWNERS"]
+	fs.Owners = []string{"@someone"}
+	state.Files["CODEOWNERS"] = fs
+	state.Files["old/a.go"] = FileState{Blob: "x", Authors: []AuthorLines{lines(user1, 1)}}
+	state.Files["old.png"] = FileState{Blob: "x", Skipped: true}
+	opts.State = state
+	res, err = Run(context.Background(), opts)
+	if err != nil {
+		t.Fatal(err)
+	}
+	assert.Equal(t, []File{
+		{Path: "CODEOWNERS", Lines: 4, Authors: []AuthorLines{lines(user1, 4)}, Owners: []string{"@org/all"}},
+		{Path: "old/a.go", Deleted: true},
+		{Path: "src/main.go", Lines: 4, Authors: []AuthorLines{lines(user1, 2), lines(user2, 2)}, Owners: []string{"@org/backend"}},
+	}, res.Files)
+	if assert.Len(t, res.Directories, 4) {
+		assert.Equal(t, Directory{Path: "old", Deleted: true}, res.Directories[1])
+	}
+}
+
+func TestRunMailmap(t *testing.T) {
+	dirs := testutil.UnzipTestRepo("ownership")
+	defer dirs.Remove()
+
+	opts := Opts{}
+	opts.RepoDir = dirs.RepoDir
+	opts.Mailmap = func(name, email string) (string, string) {
+		if email == "user1@old.example.com" {
+			return "User1", "user1@example.com"
+		}
+		return name, email
+	}
+	res, err := Run(context.Background(), opts)
+	if err != nil {
+		t.Fatal(err)
+	}
+	for _, f := range res.Files {
+		if f.Path == "README.md" {
+			assert.Equal(t, []AuthorLines{lines(user1, 3)}, f.Authors)
+		}
+	}
+	assert.Equal(t, []AuthorLines{lines(user1, 9), lines(user2, 5)}, res.Directories[0].Authors)
+}
+
+// git blame applies .mailmap from the work tree, it should not be used, since mailmap is applied using Opts.Mailmap
+func TestRunIgnoresGitMailmap(t *testing.T) {
+	dirs := testutil.UnzipTestRepo("ownership")
+	defer dirs.Remove()
+
+	err := ioutil.WriteFile(filepath.Join(dirs.RepoDir, ".mailmap"), []byte("User1 <user1@example.com> <user1@old.example.com>\n"), 0644)
+	if err != nil {
+		t.Fatal(err)
+	}
+	opts := Opts{}
+	opts.RepoDir = dirs.RepoDir
+	res, err := Run(context.Background(), opts)
+	if err != nil {
+		t.Fatal(err)
+	}
+	assert.Equal(t, []AuthorLines{lines(user1, 8), lines(user2, 5), lines(user1Old, 1)}, res.Directories[0].Authors)
+}
+
+func TestParseBlame(t *testing.T) {
+	out := "3afa57d29f979e5f19ff63cc786b46e16da14d73 1 1 2\n" +
+		"author user one\n" +
+		"author-mail <User1@Old.example.com>\n" +
+		"summary c2\n" +
+		"filename b.txt\n" +
+		"\tb\n" +
+		"3afa57d29f979e5f19ff63cc786b46e16da14d73 2 2\n" +
+		"\tauthor x\n" +
+		"8b50148db1f5f6ac4d665695a65af47298099390 3 3 1\n" +
+		"author User1\n" +
+		"author-mail <user1@example.com>\n" +
+		"filename b.txt\n" +
+		"\tc\n"
+	got, err := parseBlame([]byte(out))
+	if err != nil {
+		t.Fatal(err)
+	}
+	assert.Equal(t, map[string]int{"3afa57d29f979e5f19ff63cc786b46e16da14d73": 2, "8b50148db1f5f6ac4d665695a65af47298099390": 1}, got)
+}
+
+func TestParentDirs(t *testing.T) {
+	assert.Equal(t, []string{""}, parentDirs("a.go"))
+	assert.Equal(t, []string{"", "a", "a/b"}, parentDirs("a/b/c.go"))
+}
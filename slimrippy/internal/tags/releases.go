@@ -0,0 +1,163 @@
+package tags
+
+import (
+	"context"
+	"crypto/sha1"
+	"encoding/hex"
+	"errors"
+	"sort"
+	"strings"
+	"time"
+
+	"github.com/pinpt/agent/slimrippy/internal/branchmeta"
+)
+
+// Release is a version tag on the default branch. Tags with semver prerelease part are not considered releases.
+type Release struct {
+	Tag    string
+	Commit string
+	Semver Semver
+	Date   time.Time
+	// PreviousTag is the tag of the previous release, empty for the first release
+	PreviousTag string
+	// Commits are the commits included in this release that are not included in earlier releases, starting from the release commit
+	Commits []string
+	// Deleted is true if release tag was deleted since the last run, only Tag is set in that case
+	Deleted bool
+}
+
+// Releases returns releases sorted by version. Release tags are semver tags without prerelease part pointing to commits reachable from default branch head.
+//
+// Commits of the release are all commits reachable from the release commit and not reachable from earlier releases, which for releases done from the default branch are the commits between consecutive release tags.
+func Releases(tags []Tag, parents map[string][]string, defaultHead string) (res []Release) {
+	onDefault := map[string]bool{}
+	walk(parents, defaultHead, onDefault, nil)
+
+	var candidates []Tag
+	for _, tag := range tags {
+		if tag.Semver == nil || tag.Semver.IsPrerelease() || !onDefault[tag.Commit] {
+			continue
+		}
+		candidates = append(candidates, tag)
+	}
+	sort.SliceStable(candidates, func(i, j int) bool {
+		a, b := candidates[i], candidates[j]
+		if c := a.Semver.Compare(*b.Semver); c != 0 {
+			return c < 0
+		}
+		if !a.Date.Equal(b.Date) {
+			return a.Date.Before(b.Date)
+		}
+		return a.Name < b.Name
+	})
+
+	seen := map[string]bool{}
+	prev := ""
+	for _, tag := range candidates {
+		r := Release{}
+		r.Tag = tag.Name
+		r.Commit = tag.Commit
+		r.Semver = *tag.Semver
+		r.Date = tag.Date
+		r.PreviousTag = prev
+		walk(parents, tag.Commit, seen, func(sha string) {
+			r.Commits = append(r.Commits, sha)
+		})
+		res = append(res, r)
+		prev = tag.Name
+	}
+	return
+}
+
+// walk marks all commits reachable from sha as seen, calling cb for the ones not seen before
+func walk(parents map[string][]string, sha string, seen map[string]bool, cb func(sha string)) {
+	stack := []string{sha}
+	for len(stack) != 0 {
+		c := stack[len(stack)-1]
+		stack = stack[:len(stack)-1]
+		if seen[c] {
+			continue
+		}
+		seen[c] = true
+		if cb != nil {
+			cb(c)
+		}
+		ps := parents[c]
+		// push in reverse order to visit first parent first
+		for i := len(ps) - 1; i >= 0; i-- {
+			if !seen[ps[i]] {
+				stack = append(stack, ps[i])
+			}
+		}
+	}
+}
+
+func (s Release) hash() string {
+	h := sha1.New()
+	h.Write([]byte(strings.Join([]string{s.Commit, s.PreviousTag, s.Date.UTC().String()}, "\n")))
+	for _, c := range s.Commits {
+		h.Write([]byte(c))
+	}
+	return hex.EncodeToString(h.Sum(nil))
+}
+
+// ReleaseChanges returns new and changed releases and releases deleted since the last run.
+func ReleaseChanges(state State, all []Release) (res []Release, _ State) {
+	newState := State{Tags: state.Tags, Releases: map[string]string{}}
+	for _, r := range all {
+		h := r.hash()
+		newState.Releases[r.Tag] = h
+		if state.Releases[r.Tag] != h {
+			res = append(res, r)
+		}
+	}
+	var deleted []string
+	for name := range state.Releases {
+		if _, ok := newState.Releases[name]; !ok {
+			deleted = append(deleted, name)
+		}
+	}
+	sort.Strings(deleted)
+	for _, name := range deleted {
+		res = append(res, Release{Tag: name, Deleted: true})
+	}
+	return res, newState
+}
+
+type Opts struct {
+	RepoDir string
+	State   State
+	// Parents maps commit to its parents, it must contain all commits in repo
+	Parents map[string][]string
+}
+
+type Result struct {
+	// Tags are new, moved and deleted tags since the last run
+	Tags []Tag
+	// Releases are new, changed and deleted releases since the last run
+	Releases []Release
+	State    State
+}
+
+// Run returns changes to tags and releases since the last run
+func Run(ctx context.Context, opts Opts) (res Result, rerr error) {
+	if opts.RepoDir == "" {
+		rerr = errors.New("RepoDir not set")
+		return
+	}
+	all, err := GetAll(ctx, opts.RepoDir)
+	if err != nil {
+		rerr = err
+		return
+	}
+	head, err := branchmeta.GetDefault(ctx, opts.RepoDir)
+	if err != nil {
+		rerr = err
+		return
+	}
+	state := opts.State
+	res.Tags, state = Changes(state, all)
+	res.Releases, state = ReleaseChanges(state, Releases(all, opts.Parents, head.Commit))
+	res.State = state
+	return
+}
@@ -0,0 +1,106 @@
+package tags
+
+import (
+	"regexp"
+	"strconv"
+	"strings"
+)
+
+// Semver is a parsed semantic version, see https://semver.org
+type Semver struct {
+	Major      int
+	Minor      int
+	Patch      int
+	Prerelease string
+	Build      string
+}
+
+// semverRe matches semantic versions with optional v prefix. Patch can be skipped, since tags like v1.2 are common.
+var semverRe = regexp.MustCompile(`^[vV]?(0|[1-9]\d*)\.(0|[1-9]\d*)(?:\.(0|[1-9]\d*))?(?:-([0-9A-Za-z-]+(?:\.[0-9A-Za-z-]+)*))?(?:\+([0-9A-Za-z-]+(?:\.[0-9A-Za-z-]+)*))?$`)
+
+// ParseSemver parses tag name as semantic version. Returns false if it is not a valid version.
+func ParseSemver(s string) (res Semver, ok bool) {
+	m := semverRe.FindStringSubmatch(s)
+	if m == nil {
+		return
+	}
+	var err error
+	for i, p := range []*int{&res.Major, &res.Minor, &res.Patch} {
+		v := m[i+1]
+		if v == "" {
+			continue
+		}
+		*p, err = strconv.Atoi(v)
+		if err != nil {
+			// out of range
+			return
+		}
+	}
+	res.Prerelease = m[4]
+	res.Build = m[5]
+	return res, true
+}
+
+func (s Semver) String() string {
+	res := strconv.Itoa(s.Major) + "." + strconv.Itoa(s.Minor) + "." + strconv.Itoa(s.Patch)
+	if s.Prerelease != "" {
+		res += "-" + s.Prerelease
+	}
+	if s.Build != "" {
+		res += "+" + s.Build
+	}
+	return res
+}
+
+// IsPrerelease returns true for versions with prerelease part, such as 1.0.0-rc.1
+func (s Semver) IsPrerelease() bool {
+	return s.Prerelease != ""
+}
+
+// Compare returns -1, 0 or 1 if s is lower, equal or higher than b using semver precedence rules. Build metadata is ignored.
+func (s Semver) Compare(b Semver) int {
+	for _, v := range [][2]int{{s.Major, b.Major}, {s.Minor, b.Minor}, {s.Patch, b.Patch}} {
+		if v[0] != v[1] {
+			return cmpInt(v[0], v[1])
+		}
+	}
+	// version without prerelease has higher precedence
+	switch {
+	case s.Prerelease == b.Prerelease:
+		return 0
+	case s.Prerelease == "":
+		return 1
+	case b.Prerelease == "":
+		return -1
+	}
+	p1 := strings.Split(s.Prerelease, ".")
+	p2 := strings.Split(b.Prerelease, ".")
+	for i := 0; i < len(p1) && i < len(p2); i++ {
+		if p1[i] == p2[i] {
+			continue
+		}
+		n1, err1 := strconv.Atoi(p1[i])
+		n2, err2 := strconv.Atoi(p2[i])
+		switch {
+		case err1 == nil && err2 == nil:
+			return cmpInt(n1, n2)
+		case err1 == nil:
+			// numeric identifiers have lower precedence
+			return -1
+		case err2 == nil:
+			return 1
+		}
+		return strings.Compare(p1[i], p2[i])
+	}
+	return cmpInt(len(p1), len(p2))
+}
+
+func cmpInt(a, b int) int {
+	switch {
+	case a < b:
+		return -1
+	case a > b:
+		return 1
+	}
+	return 0
+}
@@ -0,0 +1,47 @@
+package tags
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestParseSemver(t *testing.T) {
+	cases := map[string]*Semver{
+		"v1.2.3":             {Major: 1, Minor: 2, Patch: 3},
+		"1.2.3":              {Major: 1, Minor: 2, Patch: 3},
+		"V10.0":              {Major: 10},
+		"1.0.0-rc.1+build.5": {Major: 1, Prerelease: "rc.1", Build: "build.5"},
+		"2.0.0-alpha-beta":   {Major: 2, Prerelease: "alpha-beta"},
+		"1":                  nil,
+		"release-1.0.0":      nil,
+		"01.2.3":             nil,
+		"1.2.3-":             nil,
+		"1.2.3.4":            nil,
+		"docs":               nil,
+	}
+	for s, want := range cases {
+		got, ok := ParseSemver(s)
+		if want == nil {
+			assert.False(t, ok, s)
+			continue
+		}
+		assert.True(t, ok, s)
+		assert.Equal(t, *want, got, s)
+	}
+}
+
+func TestSemverCompare(t *testing.T) {
+	// ordered by precedence, example from semver.org
+	versions := []string{"1.0.0-alpha", "1.0.0-alpha.1", "1.0.0-alpha.beta", "1.0.0-beta", "1.0.0-beta.2", "1.0.0-beta.11", "1.0.0-rc.1", "1.0.0", "1.0.1", "1.1.0", "2.0.0"}
+	for i := range versions {
+		for j := range versions {
+			a, _ := ParseSemver(versions[i])
+			b, _ := ParseSemver(versions[j])
+			assert.Equal(t, cmpInt(i, j), a.Compare(b), "%v %v", versions[i], versions[j])
+		}
+	}
+	a, _ := ParseSemver("v1.0.0+1")
+	b, _ := ParseSemver("1.0.0+2")
+	assert.Equal(t, 0, a.Compare(b))
+}
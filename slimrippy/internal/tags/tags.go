@@ -0,0 +1,188 @@
+// Package tags reads git tags, detects changes since the last run and calculates releases.
+package tags
+
+import (
+	"context"
+	"sort"
+	"strings"
+	"time"
+
+	"github.com/pinpt/agent/slimrippy/internal/commits"
+
+	"gopkg.in/src-d/go-git.v4"
+	"gopkg.in/src-d/go-git.v4/plumbing"
+	"gopkg.in/src-d/go-git.v4/plumbing/object"
+)
+
+type Status string
+
+const (
+	New     Status = "new"
+	Moved   Status = "moved"
+	Deleted Status = "deleted"
+)
+
+// Tag is a git tag pointing to a commit. Tags pointing to trees or blobs are skipped.
+type Tag struct {
+	Name string
+	// Commit is the sha of the target commit
+	Commit string
+	// Annotated is true for tags created with a tag object, Tagger and Message are only set for these
+	Annotated bool
+	// TagSHA is the sha of the tag object for annotated tags
+	TagSHA  string
+	Tagger  commits.UserAction
+	Message string
+	// Date is the tagger date for annotated tags and commit date for lightweight tags
+	Date time.Time
+	// Semver is set when tag name is a valid semantic version
+	Semver *Semver
+	// Status is the change since the last run. For deleted tags only Name is set.
+	Status Status
+}
+
+// refHash returns the hash the tag ref points to, used to detect moved tags
+func (s Tag) refHash() string {
+	if s.Annotated {
+		return s.TagSHA
+	}
+	return s.Commit
+}
+
+// GetAll returns all tags in repo sorted by name
+func GetAll(ctx context.Context, repoDir string) (res []Tag, rerr error) {
+	repo, err := git.PlainOpen(repoDir)
+	if err != nil {
+		rerr = err
+		return
+	}
+	iter, err := repo.Tags()
+	if err != nil {
+		rerr = err
+		return
+	}
+	err = iter.ForEach(func(ref *plumbing.Reference) error {
+		tag, ok, err := getTag(repo, ref)
+		if err != nil {
+			return err
+		}
+		if ok {
+			res = append(res, tag)
+		}
+		return nil
+	})
+	if err != nil {
+		rerr = err
+		return
+	}
+	sort.Slice(res, func(i, j int) bool {
+		return res[i].Name < res[j].Name
+	})
+	return
+}
+
+func getTag(repo *git.Repository, ref *plumbing.Reference) (res Tag, ok bool, _ error) {
+	res.Name = ref.Name().Short()
+	obj, err := repo.Object(plumbing.AnyObject, ref.Hash())
+	if err == plumbing.ErrObjectNotFound {
+		return res, false, nil
+	}
+	if err != nil {
+		return res, false, err
+	}
+	for {
+		switch o := obj.(type) {
+		case *object.Commit:
+			res.Commit = o.Hash.String()
+			if !res.Annotated {
+				res.Date = o.Committer.When
+			}
+			if v, ok := ParseSemver(res.Name); ok {
+				res.Semver = &v
+			}
+			return res, true, nil
+		case *object.Tag:
+			// tags can point to other tags, we use the data of the outer one
+			if !res.Annotated {
+				res.Annotated = true
+				res.TagSHA = o.Hash.String()
+				res.Tagger.Name = o.Tagger.Name
+				res.Tagger.Email = o.Tagger.Email
+				res.Tagger.Date = o.Tagger.When
+				res.Date = o.Tagger.When
+				res.Message = strings.TrimSpace(o.Message)
+			}
+			obj, err = o.Object()
+			if err == plumbing.ErrObjectNotFound {
+				return res, false, nil
+			}
+			if err != nil {
+				return res, false, err
+			}
+		default:
+			return res, false, nil
+		}
+	}
+}
+
+// State contains the tags and releases seen in the last run
+type State struct {
+	// Tags maps tag name to the hash of the tag object for annotated tags or commit for lightweight tags
+	Tags map[string]string
+	// Releases maps release tag name to the hash of release data, used to only return changed releases
+	Releases map[string]string
+}
+
+// Changes returns new, moved and deleted tags compared to state and the new state.
+func Changes(state State, all []Tag) (res []Tag, _ State) {
+	newState := State{Tags: map[string]string{}, Releases: state.Releases}
+	for _, tag := range all {
+		h := tag.refHash()
+		newState.Tags[tag.Name] = h
+		prev, ok := state.Tags[tag.Name]
+		switch {
+		case !ok:
+			tag.Status = New
+		case prev != h:
+			tag.Status = Moved
+		default:
+			continue
+		}
+		res = append(res, tag)
+	}
+	var deleted []string
+	for name := range state.Tags {
+		if _, ok := newState.Tags[name]; !ok {
+			deleted = append(deleted, name)
+		}
+	}
+	sort.Strings(deleted)
+	for _, name := range deleted {
+		res = append(res, Tag{Name: name, Status: Deleted})
+	}
+	return res, newState
+}
+
+// Refs returns a map of tag name to the hash tag ref points to, used to quickly check if any tags changed
+func Refs(ctx context.Context, repoDir string) (res map[string]string, rerr error) {
+	repo, err := git.PlainOpen(repoDir)
+	if err != nil {
+		rerr = err
+		return
+	}
+	iter, err := repo.Tags()
+	if err != nil {
+		rerr = err
+		return
+	}
+	res = map[string]string{}
+	err = iter.ForEach(func(ref *plumbing.Reference) error {
+		res[ref.Name().Short()] = ref.Hash().String()
+		return nil
+	})
+	if err != nil {
+		rerr = err
+		return
+	}
+	return
+}
@@ -0,0 +1,159 @@
+package tags
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/pinpt/agent/slimrippy/internal/commits"
+	"github.com/pinpt/agent/slimrippy/testutil"
+	"github.com/stretchr/testify/assert"
+	"gopkg.in/src-d/go-git.v4"
+	"gopkg.in/src-d/go-git.v4/plumbing/object"
+)
+
+const (
+	c1 = "3136798da78c4b7c3f7955d77493e2e35d9d6952"
+	c2 = "1fde402d3cd9f3e4fbda7ef0a3aa7793124944f1"
+	f1 = "9397242398ce7a3c159ba24a8ed40fe2fb014730"
+	c3 = "b8d28aab7efb37f7554f841dca13d256ee3a9dbc"
+	c4 = "e92e5c1aff4c7fbd379e1127a3e10397494d8c05"
+	c5 = "8a025935861df6f08d6f932481ca7a95fc6f78ee"
+)
+
+func parseDate(s string) time.Time {
+	d, err := time.Parse(time.RFC3339, s)
+	if err != nil {
+		panic(err)
+	}
+	return d
+}
+
+func semver(s string) *Semver {
+	v, ok := ParseSemver(s)
+	if !ok {
+		panic(s)
+	}
+	return &v
+}
+
+func repoParents(t *testing.T, repoDir string) map[string][]string {
+	repo, err := git.PlainOpen(repoDir)
+	if err != nil {
+		t.Fatal(err)
+	}
+	iter, err := repo.CommitObjects()
+	if err != nil {
+		t.Fatal(err)
+	}
+	res := map[string][]string{}
+	err = iter.ForEach(func(c *object.Commit) error {
+		res[c.Hash.String()] = nil
+		for _, p := range c.ParentHashes {
+			res[c.Hash.String()] = append(res[c.Hash.String()], p.String())
+		}
+		return nil
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	return res
+}
+
+func TestGetAll(t *testing.T) {
+	dirs := testutil.UnzipTestRepo("tags")
+	defer dirs.Remove()
+
+	got, err := GetAll(context.Background(), dirs.RepoDir)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	tagger := func(date string) commits.UserAction {
+		return commits.UserAction{Name: "User1", Email: "user1@example.com", Date: parseDate(date)}
+	}
+
+	want := []Tag{
+		{Name: "docs", Commit: c5, Date: parseDate("2019-04-06T10:00:00+01:00")},
+		{Name: "v1.0.0", Commit: c1, Annotated: true, TagSHA: "d85637c189b1c379aaae5e4be401868d756d01b2", Tagger: tagger("2019-04-01T10:00:00+01:00"), Message: "Release 1.0.0", Date: parseDate("2019-04-01T10:00:00+01:00"), Semver: semver("1.0.0")},
+		{Name: "v1.0.1", Commit: f1, Date: parseDate("2019-04-03T10:00:00+01:00"), Semver: semver("1.0.1")},
+		{Name: "v1.1.0", Commit: c3, Date: parseDate("2019-04-04T10:00:00+01:00"), Semver: semver("1.1.0")},
+		{Name: "v2.0.0", Commit: c5, Annotated: true, TagSHA: "f3dc4b2d86f4bf23498db33148d3207c4b08144d", Tagger: tagger("2019-04-06T10:00:00+01:00"), Message: "Release 2.0.0", Date: parseDate("2019-04-06T10:00:00+01:00"), Semver: semver("2.0.0")},
+		{Name: "v2.0.0-rc.1", Commit: c4, Annotated: true, TagSHA: "297a31cd313f6e2afcceb3b5b6b7123a9b160f39", Tagger: tagger("2019-04-05T10:00:00+01:00"), Message: "rc", Date: parseDate("2019-04-05T10:00:00+01:00"), Semver: semver("2.0.0-rc.1")},
+	}
+	if !assert.Len(t, got, len(want)) {
+		return
+	}
+	for i := range want {
+		// compare dates separately, since locations are different
+		assert.True(t, want[i].Date.Equal(got[i].Date), want[i].Name)
+		assert.True(t, want[i].Tagger.Date.Equal(got[i].Tagger.Date), want[i].Name)
+		want[i].Date = got[i].Date
+		want[i].Tagger.Date = got[i].Tagger.Date
+		assert.Equal(t, want[i], got[i])
+	}
+}
+
+func TestRunIncremental(t *testing.T) {
+	dirs := testutil.UnzipTestRepo("tags")
+	defer dirs.Remove()
+
+	opts := Opts{}
+	opts.RepoDir = dirs.RepoDir
+	opts.Parents = repoParents(t, dirs.RepoDir)
+	res, err := Run(context.Background(), opts)
+	if err != nil {
+		t.Fatal(err)
+	}
+	assert.Len(t, res.Tags, 6)
+	for _, tag := range res.Tags {
+		assert.Equal(t, New, tag.Status)
+	}
+
+	// v1.0.1 is not on default branch and v2.0.0-rc.1 is prerelease
+	type release struct {
+		Tag         string
+		PreviousTag string
+		Commits     []string
+	}
+	var got []release
+	for _, r := range res.Releases {
+		got = append(got, release{r.Tag, r.PreviousTag, r.Commits})
+	}
+	assert.Equal(t, []release{
+		{"v1.0.0", "", []string{c1}},
+		{"v1.1.0", "v1.0.0", []string{c3, c2}},
+		{"v2.0.0", "v1.1.0", []string{c5, c4}},
+	}, got)
+
+	// nothing changed
+	opts.State = res.State
+	res, err = Run(context.Background(), opts)
+	if err != nil {
+		t.Fatal(err)
+	}
+	assert.Empty(t, res.Tags)
+	assert.Empty(t, res.Releases)
+
+	// simulate v1.1.0 moved, v1.0.0 added after last run and old-tag deleted since
+	state := res.State
+	state.Tags["v1.1.0"] = c2
+	state.Tags["old-tag"] = c1
+	delete(state.Tags, "v1.0.0")
+	state.Releases["v1.1.0"] = "x"
+	state.Releases["old-tag"] = "x"
+	opts.State = state
+	res, err = Run(context.Background(), opts)
+	if err != nil {
+		t.Fatal(err)
+	}
+	var gotTags []string
+	for _, tag := range res.Tags {
+		gotTags = append(gotTags, tag.Name+":"+string(tag.Status))
+	}
+	assert.Equal(t, []string{"v1.0.0:new", "v1.1.0:moved", "old-tag:deleted"}, gotTags)
+	if assert.Len(t, res.Releases, 2) {
+		assert.Equal(t, "v1.1.0", res.Releases[0].Tag)
+		assert.Equal(t, Release{Tag: "old-tag", Deleted: true}, res.Releases[1])
+	}
+}
@@ -2,6 +2,7 @@ package slimrippy
 
 import (
 	"context"
+	"fmt"
 	"sync"
 	"time"
 
@@ -11,12 +12,25 @@ import (
 
 	"github.com/hashicorp/go-hclog"
 	"github.com/pinpt/agent/slimrippy/internal/commits"
+	"github.com/pinpt/agent/slimrippy/internal/filestats"
+	"github.com/pinpt/agent/slimrippy/internal/mailmap"
+	"github.com/pinpt/agent/slimrippy/internal/ownership"
 	"github.com/pinpt/agent/slimrippy/internal/parentsgraph"
+	"github.com/pinpt/agent/slimrippy/internal/tags"
 	"gopkg.in/src-d/go-git.v4/plumbing/object"
 )
 
 type Branch = branches.Branch
 type Commit = commits.Commit
+type CommitFile = filestats.File
+type Tag = tags.Tag
+type Release = tags.Release
+type Ownership = ownership.Result
+type FileOwnership = ownership.File
+type DirectoryOwnership = ownership.Directory
+type OwnershipAuthor = ownership.AuthorLines
+
+const TagDeleted = tags.Deleted
 
 type BranchLastCommit = branchmeta.Branch
 
@@ -26,9 +40,16 @@ func GetBranchesWithLastCommit(ctx context.Context, repoDir string) ([]BranchLas
 	return branchmeta.GetAll(ctx, repoDir, true)
 }
 
+// GetTagRefs returns a map of tag name to the hash tag ref points to
+func GetTagRefs(ctx context.Context, repoDir string) (map[string]string, error) {
+	return tags.Refs(ctx, repoDir)
+}
+
 type State struct {
-	Commits commits.State
-	Parents parentsgraph.State
+	Commits   commits.State
+	Parents   parentsgraph.State
+	Tags      tags.State
+	Ownership ownership.State
 }
 
 type Opts struct {
@@ -37,8 +58,27 @@ type Opts struct {
 	State           State
 	PullRequestSHAs []string
 
-	CommitCallback func(commits.Commit) error
-	BranchCallback func(branches.Branch) error
+	// CommitFiles enables calculation of per file stats for commits, returned in Commit.Files. When enabled for a repo processed before without it all commits are processed again.
+	CommitFiles bool
+
+	// Tags enables processing of tags and releases. Only tags and releases changed since the last run are passed to callbacks.
+	Tags bool
+
+	// Mailmap enables mapping of commit and tag author names and emails using .mailmap file from the repo head
+	Mailmap bool
+	// AliasFile is an optional file in .mailmap format applied after repo .mailmap, used for agent level aliases
+	AliasFile string
+
+	// Ownership enables blame based ownership snapshot of default branch head, passed to OwnershipCallback. Snapshot is created at most once per OwnershipInterval.
+	Ownership         bool
+	OwnershipInterval time.Duration
+
+	CommitCallback  func(commits.Commit) error
+	BranchCallback  func(branches.Branch) error
+	TagCallback     func(tags.Tag) error
+	ReleaseCallback func(tags.Release) error
+	// OwnershipCallback is called with files changed since the last snapshot and all directories
+	OwnershipCallback func(ownership.Result) error
 }
 
 func CommitsAndBranches(ctx context.Context, opts Opts) (_ State, rerr error) {
@@ -50,6 +90,26 @@ func CommitsAndBranches(ctx context.Context, opts Opts) (_ State, rerr error) {
 		logger.Debug("commitsAndBranches done", "duration", time.Since(started).String())
 	}()
 
+	mm, err := getMailmap(opts)
+	if err != nil {
+		rerr = err
+		return
+	}
+
+	if opts.CommitFiles && !state.Commits.Files {
+		state.Commits.CommitsSeen = nil
+	}
+	state.Commits.Files = opts.CommitFiles
+	if mm.Hash() != state.Commits.Mailmap {
+		if len(state.Commits.CommitsSeen) != 0 {
+			logger.Info("mailmap changed, processing all commits again")
+			state.Commits.CommitsSeen = nil
+		}
+		// blame results are stored with mapped authors
+		state.Ownership.Files = nil
+	}
+	state.Commits.Mailmap = mm.Hash()
+
 	commitsForParents := make(chan *object.Commit)
 
 	wg := sync.WaitGroup{}
@@ -66,7 +126,17 @@ func CommitsAndBranches(ctx context.Context, opts Opts) (_ State, rerr error) {
 			for c := range commitsChan {
 				commitsForParents <- c
 				if opts.CommitCallback != nil {
-					err := opts.CommitCallback(commits.Convert(c))
+					c2 := commits.Convert(c)
+					c2.Authored.Remap(mm.Map)
+					c2.Committed.Remap(mm.Map)
+					if opts.CommitFiles {
+						files, err := filestats.Commit(ctx, c)
+						if err != nil {
+							logger.Warn("could not get commit file stats", "sha", c2.SHA, "err", err)
+						}
+						c2.Files = files
+					}
+					err := opts.CommitCallback(c2)
 					if err != nil {
 						panic(err)
 					}
@@ -104,6 +174,58 @@ func CommitsAndBranches(ctx context.Context, opts Opts) (_ State, rerr error) {
 
 	wg.Wait()
 
+	if opts.Tags {
+		started := time.Now()
+		topts := tags.Opts{}
+		topts.RepoDir = opts.RepoDir
+		topts.State = state.Tags
+		topts.Parents = graph.Parents
+		res, err := tags.Run(ctx, topts)
+		if err != nil {
+			rerr = err
+			return
+		}
+		for _, t := range res.Tags {
+			t.Tagger.Remap(mm.Map)
+			err := opts.TagCallback(t)
+			if err != nil {
+				rerr = err
+				return
+			}
+		}
+		for _, r := range res.Releases {
+			err := opts.ReleaseCallback(r)
+			if err != nil {
+				rerr = err
+				return
+			}
+		}
+		state.Tags = res.State
+		logger.Debug("tags done", "duration", time.Since(started).String(), "tags", len(res.Tags), "releases", len(res.Releases))
+	}
+
+	if opts.Ownership && time.Since(state.Ownership.Date) >= opts.OwnershipInterval {
+		started := time.Now()
+		oopts := ownership.Opts{}
+		oopts.RepoDir = opts.RepoDir
+		oopts.State = state.Ownership
+		if mm != nil {
+			oopts.Mailmap = mm.Map
+		}
+		res, err := ownership.Run(ctx, oopts)
+		if err != nil {
+			rerr = err
+			return
+		}
+		err = opts.OwnershipCallback(res)
+		if err != nil {
+			rerr = err
+			return
+		}
+		state.Ownership = res.State
+		logger.Debug("ownership done", "duration", time.Since(started).String(), "files", len(res.Files), "dirs", len(res.Directories))
+	}
+
 	{
 		started := time.Now()
 		defer func() {
@@ -137,3 +259,24 @@ func CommitsAndBranches(ctx context.Context, opts Opts) (_ State, rerr error) {
 
 	return state, nil
 }
+
+// getMailmap returns mailmap from repo and alias file, nil if mailmap is not enabled or empty
+func getMailmap(opts Opts) (*mailmap.Mailmap, error) {
+	res := mailmap.New()
+	if opts.Mailmap {
+		err := res.ParseRepo(opts.RepoDir)
+		if err != nil {
+			return nil, err
+		}
+	}
+	if opts.AliasFile != "" {
+		err := res.ParseFile(opts.AliasFile)
+		if err != nil {
+			return nil, fmt.Errorf("could not read alias file: %v", err)
+		}
+	}
+	if res.Len() == 0 {
+		return nil, nil
+	}
+	return res, nil
+}
@@ -0,0 +1,74 @@
+package slimrippy
+
+import (
+	"context"
+	"io/ioutil"
+	"path/filepath"
+	"testing"
+
+	"github.com/hashicorp/go-hclog"
+	"github.com/pinpt/agent/slimrippy/testutil"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestMailmap(t *testing.T) {
+	dirs := testutil.UnzipTestRepoLoc(filepath.Join("..", "internal", "mailmap", "testdata", "mailmap.zip"))
+	defer dirs.Remove()
+
+	aliasFile := filepath.Join(dirs.TempWrapper, "aliases")
+	err := ioutil.WriteFile(aliasFile, []byte("User Two <user2@example.com>\n"), 0666)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	run := func(state State, mailmap bool, aliasFile string) (map[string]Commit, State) {
+		res := map[string]Commit{}
+		opts := Opts{}
+		opts.Logger = hclog.NewNullLogger()
+		opts.RepoDir = dirs.RepoDir
+		opts.State = state
+		opts.Mailmap = mailmap
+		opts.AliasFile = aliasFile
+		opts.CommitCallback = func(c Commit) error {
+			res[c.SHA] = c
+			return nil
+		}
+		opts.BranchCallback = func(Branch) error {
+			return nil
+		}
+		state, err := CommitsAndBranches(context.Background(), opts)
+		if err != nil {
+			t.Fatal(err)
+		}
+		return res, state
+	}
+
+	got, state := run(State{}, true, "")
+	assert.Len(t, got, 4)
+	c2 := got["3afa57d29f979e5f19ff63cc786b46e16da14d73"].Authored
+	assert.Equal(t, "User1", c2.Name)
+	assert.Equal(t, "user1@example.com", c2.Email)
+	assert.Equal(t, "user one", c2.OriginalName)
+	assert.Equal(t, "User1@Old.example.com", c2.OriginalEmail)
+	c1 := got["3161124121bdf5a6c1ef86a4a64d7bdbaa090cf1"].Authored
+	assert.Equal(t, "User1", c1.Name)
+	assert.Equal(t, "", c1.OriginalName)
+	assert.Equal(t, "", c1.OriginalEmail)
+
+	// nothing changed
+	got, state = run(state, true, "")
+	assert.Len(t, got, 0)
+
+	// alias file added, all commits are processed again
+	got, _ = run(state, true, aliasFile)
+	assert.Len(t, got, 4)
+	c3 := got["8b869e5ab37155e1b3e942c92261cbc69e879017"].Authored
+	assert.Equal(t, "User Two", c3.Name)
+	assert.Equal(t, "User2", c3.OriginalName)
+
+	// mailmap disabled
+	got, _ = run(State{}, false, "")
+	c2 = got["3afa57d29f979e5f19ff63cc786b46e16da14d73"].Authored
+	assert.Equal(t, "user one", c2.Name)
+	assert.Equal(t, "", c2.OriginalName)
+}